@@ -18,6 +18,7 @@ import (
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/oklog/run"
+	"github.com/oklog/ulid/v2"
 	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
@@ -30,8 +31,10 @@ import (
 	"github.com/thanos-io/objstore"
 	"github.com/thanos-io/objstore/client"
 	objstoretracing "github.com/thanos-io/objstore/tracing/opentracing"
+	"golang.org/x/sync/errgroup"
 
 	blocksAPI "github.com/thanos-io/thanos/pkg/api/blocks"
+	compactAPI "github.com/thanos-io/thanos/pkg/api/compact"
 	"github.com/thanos-io/thanos/pkg/block"
 	"github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/compact"
@@ -90,6 +93,11 @@ func (cs compactionSet) maxLevel() int {
 	return len(cs) - 1
 }
 
+// maxRange returns the block range of the max available compaction level in milliseconds.
+func (cs compactionSet) maxRange() int64 {
+	return int64(cs[cs.maxLevel()] / time.Millisecond)
+}
+
 func registerCompact(app *extkingpin.App) {
 	cmd := app.Command(component.Compact.String(), "Continuously compacts blocks in an object store bucket.")
 	conf := &compactConfig{}
@@ -382,6 +390,11 @@ func runCompact(
 		int64(conf.maxBlockIndexSize),
 		compactMetrics.blocksMarked.WithLabelValues(metadata.NoCompactMarkFilename, metadata.IndexSizeExceedingNoCompactReason),
 	)
+	if conf.splitShards > 1 {
+		level.Info(logger).Log("msg", "split compaction is enabled", "shards", conf.splitShards)
+		grouper.SetSplitShards(conf.splitShards)
+		largeIndexFilterPlanner.SetSplitShards(conf.splitShards)
+	}
 	if enableVerticalCompaction {
 		planner = compact.WithVerticalCompactionDownsampleFilter(largeIndexFilterPlanner, insBkt, compactMetrics.blocksMarked.WithLabelValues(metadata.NoCompactMarkFilename, metadata.DownsampleVerticalCompactionNoCompactReason))
 	} else {
@@ -403,29 +416,60 @@ func runCompact(
 	if err != nil {
 		return errors.Wrap(err, "create bucket compactor")
 	}
+	jobs := compact.NewJobController()
+	compactor.SetJobController(jobs)
+
+	var leaser compact.GroupLeaser
+	if conf.groupLeases {
+		owner := conf.groupLeaseOwner
+		if owner == "" {
+			if owner, err = os.Hostname(); err != nil {
+				return errors.Wrap(err, "get hostname for group lease owner")
+			}
+		}
+		if conf.groupLeaseTTL <= 0 {
+			return errors.New("--compact.group-lease-ttl must be positive")
+		}
+		level.Info(logger).Log("msg", "group leases are enabled", "owner", owner, "ttl", conf.groupLeaseTTL)
+		leaser = compact.NewBucketGroupLeaser(logger, reg, insBkt, owner, conf.groupLeaseTTL)
+		compactor.SetGroupLeaser(leaser)
+	}
+
+	ladder, err := downsample.ParseLadder(conf.downsamplingLadder)
+	if err != nil {
+		return errors.Wrap(err, "parse downsampling resolutions")
+	}
+	if err := ladder.ValidateCompactionRange(compactions.maxRange()); err != nil {
+		return errors.Wrap(err, "validate downsampling resolutions")
+	}
+	if !ladder.IsDefault() {
+		level.Info(logger).Log("msg", "custom downsampling ladder is enabled", "ladder", ladder)
+	}
 
 	retentionByResolution := map[compact.ResolutionLevel]time.Duration{
 		compact.ResolutionLevelRaw: time.Duration(conf.retentionRaw),
 		compact.ResolutionLevel5m:  time.Duration(conf.retentionFiveMin),
 		compact.ResolutionLevel1h:  time.Duration(conf.retentionOneHr),
 	}
-
-	if retentionByResolution[compact.ResolutionLevelRaw].Milliseconds() != 0 {
-		// If downsampling is enabled, error if raw retention is not sufficient for downsampling to occur (upper bound 10 days for 1h resolution)
-		if !conf.disableDownsampling && retentionByResolution[compact.ResolutionLevelRaw].Milliseconds() < downsample.ResLevel1DownsampleRange {
-			return errors.New("raw resolution must be higher than the minimum block size after which 5m resolution downsampling will occur (40 hours)")
+	for _, r := range conf.retentionByResolution {
+		res, retention, err := parseResolutionRetention(r)
+		if err != nil {
+			return err
 		}
-		level.Info(logger).Log("msg", "retention policy of raw samples is enabled", "duration", retentionByResolution[compact.ResolutionLevelRaw])
+		retentionByResolution[res] = retention
 	}
-	if retentionByResolution[compact.ResolutionLevel5m].Milliseconds() != 0 {
-		// If retention is lower than minimum downsample range, then no downsampling at this resolution will be persisted
-		if !conf.disableDownsampling && retentionByResolution[compact.ResolutionLevel5m].Milliseconds() < downsample.ResLevel2DownsampleRange {
-			return errors.New("5m resolution retention must be higher than the minimum block size after which 1h resolution downsampling will occur (10 days)")
+
+	for _, res := range ladder.Resolutions() {
+		retention := retentionByResolution[compact.ResolutionLevel(res)]
+		if retention == 0 {
+			continue
 		}
-		level.Info(logger).Log("msg", "retention policy of 5 min aggregated samples is enabled", "duration", retentionByResolution[compact.ResolutionLevel5m])
-	}
-	if retentionByResolution[compact.ResolutionLevel1h].Milliseconds() != 0 {
-		level.Info(logger).Log("msg", "retention policy of 1 hour aggregated samples is enabled", "duration", retentionByResolution[compact.ResolutionLevel1h])
+		// If retention is lower than minimum downsample range, then no downsampling of this resolution will be persisted.
+		if next, ok := ladder.Next(res); ok && !conf.disableDownsampling && retention.Milliseconds() < next.MinBlockRange {
+			return errors.Errorf("%s resolution retention must be higher than the minimum block size after which %s resolution downsampling will occur (%s)",
+				resolutionName(res), resolutionName(next.Resolution), model.Duration(time.Duration(next.MinBlockRange)*time.Millisecond))
+		}
+		level.Info(logger).Log("msg", "retention policy is enabled", "resolution", resolutionName(res), "duration", retention)
 	}
 
 	var cleanMtx sync.Mutex
@@ -454,7 +498,15 @@ func runCompact(
 				return errors.Wrap(err, "sync before first pass of downsampling")
 			}
 
-			filteredMetas := sy.Metas()
+			// With group leases, only downsample blocks of the label sets this replica could claim,
+			// for both passes.
+			claimed, release, err := claimDownsampleLeases(ctx, leaser, sy.Metas())
+			if err != nil {
+				return errors.Wrap(err, "claim downsampling leases")
+			}
+			defer release()
+
+			filteredMetas := claimed(sy.Metas())
 			noDownsampleBlocks := noDownsampleMarkerFilter.NoDownsampleMarkedBlocks()
 			for ul := range noDownsampleBlocks {
 				delete(filteredMetas, ul)
@@ -477,6 +529,8 @@ func runCompact(
 				conf.blockFilesConcurrency,
 				metadata.HashFunc(conf.hashFunc),
 				conf.acceptMalformedIndex,
+				ladder,
+				jobs,
 			); err != nil {
 				return errors.Wrap(err, "first pass of downsampling failed")
 			}
@@ -488,7 +542,7 @@ func runCompact(
 
 			// Regenerate the filtered list of blocks after the sync,
 			// to include the blocks created by the first pass.
-			filteredMetas = sy.Metas()
+			filteredMetas = claimed(sy.Metas())
 			noDownsampleBlocks = noDownsampleMarkerFilter.NoDownsampleMarkedBlocks()
 			for ul := range noDownsampleBlocks {
 				delete(filteredMetas, ul)
@@ -505,10 +559,13 @@ func runCompact(
 				conf.blockFilesConcurrency,
 				metadata.HashFunc(conf.hashFunc),
 				conf.acceptMalformedIndex,
+				ladder,
+				jobs,
 			); err != nil {
 				return errors.Wrap(err, "second pass of downsampling failed")
 			}
 
+			release()
 			level.Info(logger).Log("msg", "downsampling iterations done")
 		} else {
 			level.Info(logger).Log("msg", "downsampling was explicitly disabled")
@@ -583,6 +640,7 @@ func runCompact(
 			})}
 			logMiddleware := logging.NewHTTPServerMiddleware(logger, opts...)
 			api.Register(r.WithPrefix("/api/v1"), tracer, logger, ins, logMiddleware)
+			compactAPI.NewCompactorAPI(logger, conf.webConf.disableCORS, flagsMap, jobs).Register(r.WithPrefix("/api/v1"), tracer, logger, ins, logMiddleware)
 
 			// Separate fetcher for global view.
 			// TODO(bwplotka): Allow Bucket UI to visualize the state of the block as well.
@@ -643,6 +701,7 @@ func runCompact(
 				var ds *compact.DownsampleProgressCalculator
 				if !conf.disableDownsampling {
 					ds = compact.NewDownsampleProgressCalculator(reg)
+					ds.SetLadder(ladder)
 				}
 
 				return runutil.Repeat(conf.progressCalculateInterval, ctx.Done(), func() error {
@@ -702,6 +761,61 @@ func runCompact(
 	return nil
 }
 
+// claimDownsampleLeases claims the lease of the compaction group of all given blocks, so blocks are not
+// downsampled while another replica compacts their group. It returns a function filtering metas down to
+// blocks of claimed groups. All blocks are claimed if the leaser is nil.
+func claimDownsampleLeases(ctx context.Context, leaser compact.GroupLeaser, metas map[ulid.ULID]*metadata.Meta) (func(map[ulid.ULID]*metadata.Meta) map[ulid.ULID]*metadata.Meta, func(), error) {
+	if leaser == nil {
+		return func(m map[ulid.ULID]*metadata.Meta) map[ulid.ULID]*metadata.Meta { return m }, func() {}, nil
+	}
+
+	keys := map[string]struct{}{}
+	for _, m := range metas {
+		keys[m.Thanos.GroupKey()] = struct{}{}
+	}
+
+	var (
+		mtx      sync.Mutex
+		releases []func()
+		claimed  = map[string]struct{}{}
+		eg, ectx = errgroup.WithContext(ctx)
+	)
+	// Claiming waits for the lease to settle, so claim concurrently.
+	eg.SetLimit(32)
+	for key := range keys {
+		eg.Go(func() error {
+			_, release, ok, err := leaser.Claim(ectx, key)
+			if err != nil || !ok {
+				return err
+			}
+			mtx.Lock()
+			defer mtx.Unlock()
+			claimed[key] = struct{}{}
+			releases = append(releases, release)
+			return nil
+		})
+	}
+	releaseAll := func() {
+		for _, release := range releases {
+			release()
+		}
+	}
+	if err := eg.Wait(); err != nil {
+		releaseAll()
+		return nil, nil, err
+	}
+
+	filter := func(metas map[ulid.ULID]*metadata.Meta) map[ulid.ULID]*metadata.Meta {
+		for id, m := range metas {
+			if _, ok := claimed[m.Thanos.GroupKey()]; !ok {
+				delete(metas, id)
+			}
+		}
+		return metas
+	}
+	return filter, releaseAll, nil
+}
+
 type compactConfig struct {
 	haltOnError                                    bool
 	acceptMalformedIndex                           bool
@@ -714,6 +828,8 @@ type compactConfig struct {
 	wait                                           bool
 	waitInterval                                   time.Duration
 	disableDownsampling                            bool
+	downsamplingLadder                             string
+	retentionByResolution                          []string
 	blockListStrategy                              string
 	blockMetaFetchConcurrency                      int
 	blockFilesConcurrency                          int
@@ -737,6 +853,35 @@ type compactConfig struct {
 	progressCalculateInterval                      time.Duration
 	filterConf                                     *store.FilterConfig
 	disableAdminOperations                         bool
+	groupLeases                                    bool
+	groupLeaseOwner                                string
+	groupLeaseTTL                                  time.Duration
+	splitShards                                    uint64
+}
+
+// parseResolutionRetention parses a retention of the form <resolution>:<retention>.
+func parseResolutionRetention(s string) (compact.ResolutionLevel, time.Duration, error) {
+	res, retention, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, 0, errors.Errorf("invalid retention %q, expected <resolution>:<retention>", s)
+	}
+	r, err := model.ParseDuration(res)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "parse resolution of retention %q", s)
+	}
+	d, err := model.ParseDuration(retention)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "parse retention %q", s)
+	}
+	return compact.ResolutionLevel(time.Duration(r).Milliseconds()), time.Duration(d), nil
+}
+
+// resolutionName returns a human readable name of the given resolution in milliseconds.
+func resolutionName(res int64) string {
+	if res == downsample.ResLevel0 {
+		return "raw"
+	}
+	return model.Duration(time.Duration(res) * time.Millisecond).String()
 }
 
 func (cc *compactConfig) registerFlag(cmd extkingpin.FlagClause) {
@@ -765,6 +910,8 @@ func (cc *compactConfig) registerFlag(cmd extkingpin.FlagClause) {
 		Default("0d").SetValue(&cc.retentionFiveMin)
 	cmd.Flag("retention.resolution-1h", "How long to retain samples of resolution 2 (1 hour) in bucket. Setting this to 0d will retain samples of this resolution forever").
 		Default("0d").SetValue(&cc.retentionOneHr)
+	cmd.Flag("retention.resolution", "Experimental. How long to retain samples of a custom downsampling resolution configured with --downsampling.resolutions, as <resolution>:<retention>, e.g. 1m:30d. Samples of resolutions without retention are retained forever. May be repeated.").
+		PlaceHolder("<resolution>:<retention>").StringsVar(&cc.retentionByResolution)
 
 	// TODO(kakkoyun, pgough): https://github.com/thanos-io/thanos/issues/2266.
 	cmd.Flag("wait", "Do not exit after all compactions have been processed and wait for new work.").
@@ -775,6 +922,8 @@ func (cc *compactConfig) registerFlag(cmd extkingpin.FlagClause) {
 	cmd.Flag("downsampling.disable", "Disables downsampling. This is not recommended "+
 		"as querying long time ranges without non-downsampled data is not efficient and useful e.g it is not possible to render all samples for a human eye anyway").
 		Default("false").BoolVar(&cc.disableDownsampling)
+	cmd.Flag("downsampling.resolutions", "Experimental. Downsampling ladder as comma separated <resolution>:<min-block-range> levels, e.g. 1m:10h,15m:5d,6h:14d. Raw blocks are downsampled to the first level once they cover its min block range, blocks of each level to the next one. Already downsampled blocks keep following the ladder they were created with. Min block ranges must not exceed the largest compaction level of 14d.").
+		Default(defaultDownsamplingLadder).StringVar(&cc.downsamplingLadder)
 
 	strategies := strings.Join([]string{string(concurrentDiscovery), string(recursiveDiscovery)}, ", ")
 	cmd.Flag("block-discovery-strategy", "One of "+strategies+". When set to concurrent, stores will concurrently issue one call per directory to discover active blocks in the bucket. The recursive strategy iterates through all objects in the bucket, recursively traversing into each directory. This avoids N+1 calls at the expense of having slower bucket iterations.").
@@ -799,6 +948,13 @@ func (cc *compactConfig) registerFlag(cmd extkingpin.FlagClause) {
 	cmd.Flag("downsample.concurrency", "Number of goroutines to use when downsampling blocks.").
 		Default("1").IntVar(&cc.downsampleConcurrency)
 
+	cmd.Flag("compact.enable-group-leases", "Claim a lease in the bucket for each compaction group before compacting or downsampling it, so that multiple compactor replicas can process the same bucket concurrently without working on the same group. Groups leased by other replicas are skipped until their lease is released or expires. Leases are best effort, concurrent claims may rarely both succeed.").
+		Default("false").BoolVar(&cc.groupLeases)
+	cmd.Flag("compact.group-lease-owner", "Identity of this compactor replica in group leases. Must be unique across replicas. Defaults to the hostname.").
+		Default("").StringVar(&cc.groupLeaseOwner)
+	cmd.Flag("compact.group-lease-ttl", "Time after which a group lease of a crashed compactor replica expires. Leases are renewed every third of this duration while their group is being processed.").
+		Default("5m").DurationVar(&cc.groupLeaseTTL)
+
 	cmd.Flag("delete-delay", "Time before a block marked for deletion is deleted from bucket. "+
 		"If delete-delay is non zero, blocks will be marked for deletion and compactor component will delete blocks marked for deletion from the bucket. "+
 		"If delete-delay is 0, blocks will be deleted straight away. "+
@@ -830,6 +986,11 @@ func (cc *compactConfig) registerFlag(cmd extkingpin.FlagClause) {
 		"Default is due to https://github.com/thanos-io/thanos/issues/1424, but it's overall recommended to keeps block size to some reasonable size.").
 		Hidden().Default("64GB").BytesVar(&cc.maxBlockIndexSize)
 
+	cmd.Flag("compact.split-shards", "Experimental. Number of shards to split series of blocks into by their label hash when compacting blocks which are not sharded yet. "+
+		"Each shard is compacted separately afterwards, so very large tenants keep compacting up to the largest level instead of hitting --compact.block-max-index-size. "+
+		"The shard of a block is recorded in its meta.json. 0 or 1 disables split compaction. Changing the number only affects blocks which are not sharded yet.").
+		Default("0").Uint64Var(&cc.splitShards)
+
 	cmd.Flag("compact.skip-block-with-out-of-order-chunks", "When set to true, mark blocks containing index with out-of-order chunks for no compact instead of halting the compaction").
 		Hidden().Default("false").BoolVar(&cc.skipBlockWithOutOfOrderChunks)
 
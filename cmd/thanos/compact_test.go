@@ -0,0 +1,60 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/oklog/ulid/v2"
+
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
+)
+
+// heldGroupLeaser grants all leases except the held ones.
+type heldGroupLeaser struct {
+	held map[string]struct{}
+
+	mtx     sync.Mutex
+	claimed []string
+}
+
+func (l *heldGroupLeaser) Claim(ctx context.Context, key string) (context.Context, func(), bool, error) {
+	if _, ok := l.held[key]; ok {
+		return nil, nil, false, nil
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.claimed = append(l.claimed, key)
+	return ctx, func() {}, true, nil
+}
+
+func TestClaimDownsampleLeases(t *testing.T) {
+	meta := func(res int64, lbls map[string]string) *metadata.Meta {
+		return &metadata.Meta{Thanos: metadata.Thanos{Labels: lbls, Downsample: metadata.ThanosDownsample{Resolution: res}}}
+	}
+	var (
+		a     = ulid.MustNew(1, nil)
+		b     = ulid.MustNew(2, nil)
+		c     = ulid.MustNew(3, nil)
+		metas = map[ulid.ULID]*metadata.Meta{
+			a: meta(0, map[string]string{"ext": "1"}),
+			b: meta(downsample.ResLevel1, map[string]string{"ext": "1"}),
+			c: meta(0, map[string]string{"ext": "2"}),
+		}
+	)
+
+	// The raw group of ext="1" is being compacted by another replica.
+	leaser := &heldGroupLeaser{held: map[string]struct{}{metas[a].Thanos.GroupKey(): {}}}
+	filter, release, err := claimDownsampleLeases(context.Background(), leaser, metas)
+	testutil.Ok(t, err)
+	defer release()
+
+	testutil.Equals(t, 2, len(leaser.claimed))
+	filtered := filter(map[ulid.ULID]*metadata.Meta{a: metas[a], b: metas[b], c: metas[c]})
+	testutil.Equals(t, map[ulid.ULID]*metadata.Meta{b: metas[b], c: metas[c]}, filtered)
+}
@@ -5,8 +5,10 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"sync"
 	"time"
@@ -66,6 +68,9 @@ func newDownsampleMetrics(reg *prometheus.Registry) *DownsampleMetrics {
 	return m
 }
 
+// defaultDownsamplingLadder is the default of flags configuring downsampling.Ladder, equal to downsample.DefaultLadder.
+const defaultDownsamplingLadder = "5m:40h,1h:10d"
+
 func RunDownsample(
 	g *run.Group,
 	logger log.Logger,
@@ -80,6 +85,7 @@ func RunDownsample(
 	objStoreConfig *extflag.PathOrContent,
 	comp component.Component,
 	hashFunc metadata.HashFunc,
+	ladder downsample.Ladder,
 ) error {
 	confContentYaml, err := objStoreConfig.Content()
 	if err != nil {
@@ -119,6 +125,7 @@ func RunDownsample(
 	// Start cycle of syncing blocks from the bucket and garbage collecting the bucket.
 	{
 		ctx, cancel := context.WithCancel(context.Background())
+		jobs := compact.NewJobController()
 
 		g.Add(func() error {
 			defer runutil.CloseWithLogOnErr(logger, insBkt, "bucket client")
@@ -136,7 +143,7 @@ func RunDownsample(
 					metrics.downsamples.WithLabelValues(resolutionLabel)
 					metrics.downsampleFailures.WithLabelValues(resolutionLabel)
 				}
-				if err := downsampleBucket(ctx, logger, metrics, insBkt, metas, dataDir, downsampleConcurrency, blockFilesConcurrency, hashFunc, false); err != nil {
+				if err := downsampleBucket(ctx, logger, metrics, insBkt, metas, dataDir, downsampleConcurrency, blockFilesConcurrency, hashFunc, false, ladder, jobs); err != nil {
 					return errors.Wrap(err, "downsampling failed")
 				}
 
@@ -145,7 +152,7 @@ func RunDownsample(
 				if err != nil {
 					return errors.Wrap(err, "sync before second pass of downsampling")
 				}
-				if err := downsampleBucket(ctx, logger, metrics, insBkt, metas, dataDir, downsampleConcurrency, blockFilesConcurrency, hashFunc, false); err != nil {
+				if err := downsampleBucket(ctx, logger, metrics, insBkt, metas, dataDir, downsampleConcurrency, blockFilesConcurrency, hashFunc, false, ladder, jobs); err != nil {
 					return errors.Wrap(err, "downsampling failed")
 				}
 				return nil
@@ -187,6 +194,8 @@ func downsampleBucket(
 	blockFilesConcurrency int,
 	hashFunc metadata.HashFunc,
 	acceptMalformedIndex bool,
+	ladder downsample.Ladder,
+	jobs *compact.JobController,
 ) (rerr error) {
 	if err := os.MkdirAll(dir, 0750); err != nil {
 		return errors.Wrap(err, "create dir")
@@ -205,25 +214,7 @@ func downsampleBucket(
 
 	// mapping from a hash over all source IDs to blocks. We don't need to downsample a block
 	// if a downsampled version with the same hash already exists.
-	sources5m := map[ulid.ULID]struct{}{}
-	sources1h := map[ulid.ULID]struct{}{}
-
-	for _, m := range metas {
-		switch m.Thanos.Downsample.Resolution {
-		case downsample.ResLevel0:
-			continue
-		case downsample.ResLevel1:
-			for _, id := range m.Compaction.Sources {
-				sources5m[id] = struct{}{}
-			}
-		case downsample.ResLevel2:
-			for _, id := range m.Compaction.Sources {
-				sources1h[id] = struct{}{}
-			}
-		default:
-			return errors.Errorf("unexpected downsampling resolution %d", m.Thanos.Downsample.Resolution)
-		}
-	}
+	sources := downsample.SourcesByResolution(metas)
 
 	ignoreDirs := []string{}
 	for ulid := range metas {
@@ -256,13 +247,15 @@ func downsampleBucket(
 	for range downsampleConcurrency {
 		wg.Go(func() {
 			for m := range metaCh {
-				resolution := downsample.ResLevel1
-				errMsg := "downsampling to 5 min"
-				if m.Thanos.Downsample.Resolution == downsample.ResLevel1 {
-					resolution = downsample.ResLevel2
-					errMsg = "downsampling to 60 min"
-				}
-				if err := processDownsampling(workerCtx, logger, bkt, m, dir, resolution, hashFunc, metrics, acceptMalformedIndex, blockFilesConcurrency); err != nil {
+				blockLadder := downsample.LadderOf(m, ladder)
+				next, _ := blockLadder.Next(m.Thanos.Downsample.Resolution)
+				errMsg := fmt.Sprintf("downsampling to %s", time.Duration(next.Resolution)*time.Millisecond)
+
+				job := downsampleJob(m)
+				jobs.Start(compact.DownsampleJob, job)
+				err := processDownsampling(workerCtx, logger, bkt, m, dir, next.Resolution, blockLadder, hashFunc, metrics, acceptMalformedIndex, blockFilesConcurrency)
+				jobs.Finish(compact.DownsampleJob, job, err)
+				if err != nil {
 					metrics.downsampleFailures.WithLabelValues(m.Thanos.ResolutionString()).Inc()
 					errCh <- errors.Wrap(err, errMsg)
 
@@ -272,51 +265,35 @@ func downsampleBucket(
 		})
 	}
 
-	// Workers scheduled, distribute blocks.
-metaSendLoop:
+	var (
+		pending []*metadata.Meta
+		queued  []compact.Job
+	)
 	for _, mk := range metasULIDS {
 		m := metas[mk]
 
-		switch m.Thanos.Downsample.Resolution {
-		case downsample.ResLevel2:
+		if _, ok := downsample.NextLevel(m, ladder, sources); !ok {
 			continue
+		}
+		pending = append(pending, m)
+		queued = append(queued, downsampleJob(m))
+	}
+	jobs.Queue(compact.DownsampleJob, queued)
 
-		case downsample.ResLevel0:
-			missing := false
-			for _, id := range m.Compaction.Sources {
-				if _, ok := sources5m[id]; !ok {
-					missing = true
-					break
-				}
-			}
-			if !missing {
-				continue
-			}
-			// Only downsample blocks once we are sure to get roughly 2 chunks out of it.
-			// NOTE(fabxc): this must match with at which block size the compactor creates downsampled
-			// blocks. Otherwise we may never downsample some data.
-			if m.MaxTime-m.MinTime < downsample.ResLevel1DownsampleRange {
-				continue
-			}
-
-		case downsample.ResLevel1:
-			missing := false
-			for _, id := range m.Compaction.Sources {
-				if _, ok := sources1h[id]; !ok {
-					missing = true
-					break
-				}
-			}
-			if !missing {
-				continue
-			}
-			// Only downsample blocks once we are sure to get roughly 2 chunks out of it.
-			// NOTE(fabxc): this must match with at which block size the compactor creates downsampled
-			// blocks. Otherwise we may never downsample some data.
-			if m.MaxTime-m.MinTime < downsample.ResLevel2DownsampleRange {
-				continue
-			}
+	// Workers scheduled, distribute blocks, highest priority first.
+metaSendLoop:
+	for {
+		if err := jobs.WaitResumed(workerCtx); err != nil {
+			downsampleErrs.Add(err)
+			break
 		}
+		i := jobs.Next(compact.DownsampleJob, queued)
+		if i == -1 {
+			break
+		}
+		m := pending[i]
+		pending = slices.Delete(pending, i, i+1)
+		queued = slices.Delete(queued, i, i+1)
 
 		select {
 		case <-workerCtx.Done():
@@ -342,6 +319,16 @@ metaSendLoop:
 	return downsampleErrs.Err()
 }
 
+// downsampleJob returns the downsampling job of the given block. It belongs to the same group as compactions of the block.
+func downsampleJob(m *metadata.Meta) compact.Job {
+	return compact.Job{
+		Group:      m.Thanos.GroupKey(),
+		Labels:     m.Thanos.Labels,
+		Resolution: m.Thanos.Downsample.Resolution,
+		Blocks:     []ulid.ULID{m.ULID},
+	}
+}
+
 func processDownsampling(
 	ctx context.Context,
 	logger log.Logger,
@@ -349,6 +336,7 @@ func processDownsampling(
 	m *metadata.Meta,
 	dir string,
 	resolution int64,
+	ladder downsample.Ladder,
 	hashFunc metadata.HashFunc,
 	metrics *DownsampleMetrics,
 	acceptMalformedIndex bool,
@@ -412,6 +400,9 @@ func processDownsampling(
 	if stats.SeriesMaxSize > 0 {
 		meta.Thanos.IndexStats.SeriesMaxSize = stats.SeriesMaxSize
 	}
+	if !ladder.IsDefault() {
+		meta.Thanos.Downsample.Levels = ladder
+	}
 	if err := meta.WriteToDir(logger, resdir); err != nil {
 		return errors.Wrap(err, "write meta")
 	}
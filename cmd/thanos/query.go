@@ -49,6 +49,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/status"
 	"github.com/thanos-io/thanos/pkg/store"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
 	"github.com/thanos-io/thanos/pkg/strutil"
 	"github.com/thanos-io/thanos/pkg/targets"
 	"github.com/thanos-io/thanos/pkg/tenancy"
@@ -98,6 +99,8 @@ func registerQuery(app *extkingpin.App) {
 
 	lookbackDelta := cmd.Flag("query.lookback-delta", "The maximum lookback duration for retrieving metrics during expression evaluations. PromQL always evaluates the query for the certain timestamp (query range timestamps are deduced by step). Since scrape intervals might be different, PromQL looks back for given amount of time to get latest sample. If it exceeds the maximum lookback delta it assumes series is stale and returns none (a gap). This is why lookback delta should be set to at least 2 times of the slowest scrape interval. If unset it will use the promql default of 5m.").Duration()
 	dynamicLookbackDelta := cmd.Flag("query.dynamic-lookback-delta", "Allow for larger lookback duration for queries based on resolution.").Hidden().Default("true").Bool()
+	downsamplingResolutions := cmd.Flag("query.downsampling-resolutions", "Experimental. Comma separated downsampling resolutions of the queried blocks. Used to pick the lookback delta per resolution and the resolution raw chunks are aggregated to with --query.downsample-raw-data. Has to match the resolutions of --downsampling.resolutions of the compactor.").
+		Default("5m,1h").String()
 
 	maxConcurrentSelects := cmd.Flag("query.max-concurrent-select", "Maximum number of select requests made concurrently per a query.").
 		Default("4").Int()
@@ -118,6 +121,8 @@ func registerQuery(app *extkingpin.App) {
 	// currently, we choose the highest MinT of an engine when querying multiple engines. This flag allows to change this behavior to choose the lowest MinT.
 	queryDistributedWithOverlappingInterval := cmd.Flag("query.distributed-with-overlapping-interval", "Allow for distributed queries using an engines lowest MinT.").Hidden().Default("false").Bool()
 
+	queryDistributedCostBasedReplicaSelection := cmd.Flag("query.distributed-cost-based-replica-selection", "Experimental. When using the distributed query mode, prune leaf queriers to the TSDBs overlapping the queried time range and push queries down only to the cheapest replica of each partition, based on the time range and series count of its TSDBs and its recently observed latency. Estimated costs are reported by the query_explain endpoints. Which sub-expressions are pushed down is not affected.").Default("false").Bool()
+
 	instantDefaultMaxSourceResolution := extkingpin.ModelDuration(cmd.Flag("query.instant.default.max_source_resolution", "default value for max_source_resolution for instant queries. If not set, defaults to 0s only taking raw resolution into account. 1h can be a good value if you use instant queries over time ranges that incorporate times outside of your raw-retention.").Default("0s").Hidden())
 
 	defaultMetadataTimeRange := cmd.Flag("query.metadata.default-time-range", "The default metadata time range duration for retrieving labels through Labels and Series API when the range parameters are not specified. The zero value means range covers the time since the beginning.").Default("0s").Duration()
@@ -157,6 +162,19 @@ func registerQuery(app *extkingpin.App) {
 
 	storeResponseTimeout := extkingpin.ModelDuration(cmd.Flag("store.response-timeout", "If a Store doesn't send any data in this specified duration then a Store will be ignored and partial data will be returned if it's enabled. 0 disables timeout.").Default("0ms"))
 
+	var hedgingConfig store.HedgingConfig
+	cmd.Flag("store.hedged-requests.quantile", "Quantile of the observed Series latency of a Store after which the same request is also sent to another Store exposing identical external labels and covering the whole queried time range, e.g. a replica of a Store Gateway. The first complete response without errors is used. Only Stores with identical label sets and time ranges holding the same data should be connected when enabled. Only effective with the eager retrieval strategy. 0 disables hedging.").
+		Default("0").Float64Var(&hedgingConfig.Quantile)
+	cmd.Flag("store.adaptive-timeout.multiplier", "Multiplier applied to the latency quantile configured with --store.hedged-requests.quantile to derive a per-Store Series timeout. A Store exceeding its timeout is handled as failed. 0 disables adaptive timeouts.").
+		Default("0").Float64Var(&hedgingConfig.TimeoutMultiplier)
+	minAdaptiveTimeout := extkingpin.ModelDuration(cmd.Flag("store.adaptive-timeout.min", "Lower bound of adaptive per-Store Series timeouts.").Default("10s"))
+
+	downsampleRawData := cmd.Flag("query.downsample-raw-data", "If true, raw chunks returned by StoreAPIs are aggregated on the fly for queries allowing a downsampled resolution, e.g. with max_source_resolution or auto downsampling, when only raw data is available for their time range.").
+		Default("false").Bool()
+
+	replicaAwareSelection := cmd.Flag("store.replica-aware-selection", "When deduplication is enabled, only query one Store of each replica group, i.e. Stores whose external labels are identical once the replica labels are removed and which cover the whole queried time range. Other replicas are only queried when the selected one fails. Only effective with the eager retrieval strategy.").
+		Default("false").Bool()
+
 	storeSelectorRelabelConf := *extflag.RegisterPathOrContent(
 		cmd,
 		"selector.relabel-config",
@@ -164,6 +182,14 @@ func registerQuery(app *extkingpin.App) {
 		extflag.WithEnvSubstitution(),
 	)
 
+	resultsCacheConfig := *extflag.RegisterPathOrContent(
+		cmd,
+		"query.results-cache.config",
+		"YAML file that contains the configuration of the querier results cache, which caches Series calls and queries pushed down to remote engines for time ranges older than --query.max-cache-freshness. See format details: https://thanos.io/tip/components/query.md/#results-cache",
+		extflag.WithEnvSubstitution(),
+	)
+	resultsCacheMaxFreshness := extkingpin.ModelDuration(cmd.Flag("query.max-cache-freshness", "Most recent allowed cacheable result of the querier results cache, to prevent caching very recent results that might still be in flux.").Default("10m"))
+
 	reqLogConfig := extkingpin.RegisterRequestLoggingFlags(cmd)
 
 	alertQueryURL := cmd.Flag("alert.query-url", "The external Thanos Query URL that would be set in all alerts 'Source' field.").String()
@@ -222,6 +248,16 @@ func registerQuery(app *extkingpin.App) {
 			return errors.Wrap(err, "parse federation labels")
 		}
 
+		resolutions, err := downsample.ParseResolutions(*downsamplingResolutions)
+		if err != nil {
+			return errors.Wrap(err, "parse downsampling resolutions")
+		}
+
+		if hedgingConfig.Quantile < 0 || hedgingConfig.Quantile >= 1 {
+			return errors.Errorf("--store.hedged-requests.quantile has to be in range [0, 1), got %v", hedgingConfig.Quantile)
+		}
+		hedgingConfig.MinTimeout = time.Duration(*minAdaptiveTimeout)
+
 		for _, feature := range *featureList {
 			if feature == promqlExperimentalFunctions {
 				parser.EnableExperimentalFunctions = true
@@ -265,6 +301,18 @@ func registerQuery(app *extkingpin.App) {
 			return err
 		}
 
+		var resultsCache *query.ResultsCache
+		resultsCacheContent, err := resultsCacheConfig.Content()
+		if err != nil {
+			return errors.Wrap(err, "error while reading results cache configuration")
+		}
+		if len(resultsCacheContent) > 0 {
+			resultsCache, err = query.NewResultsCacheFromYaml(resultsCacheContent, logger, reg, time.Duration(*resultsCacheMaxFreshness))
+			if err != nil {
+				return errors.Wrap(err, "create results cache")
+			}
+		}
+
 		dialOpts, err := grpcClientConfig.dialOptions(logger, reg, tracer)
 		if err != nil {
 			return err
@@ -347,8 +395,12 @@ func registerQuery(app *extkingpin.App) {
 			time.Duration(*queryTimeout),
 			*lookbackDelta,
 			*dynamicLookbackDelta,
+			resolutions,
 			time.Duration(*defaultEvaluationInterval),
 			time.Duration(*storeResponseTimeout),
+			hedgingConfig,
+			*replicaAwareSelection,
+			*downsampleRawData,
 			*deduplicationFunc,
 			*queryReplicaLabels,
 			*queryPartitionLabels,
@@ -382,6 +434,8 @@ func registerQuery(app *extkingpin.App) {
 			*enforceTenancy,
 			*tenantLabel,
 			*queryDistributedWithOverlappingInterval,
+			*queryDistributedCostBasedReplicaSelection,
+			resultsCache,
 			*lazyRetrievalMaxBufferedResponses,
 			store.DefaultResponseBatchSize,
 		)
@@ -413,8 +467,12 @@ func runQuery(
 	queryTimeout time.Duration,
 	lookbackDelta time.Duration,
 	dynamicLookbackDelta bool,
+	downsamplingResolutions []int64,
 	defaultEvaluationInterval time.Duration,
 	storeResponseTimeout time.Duration,
+	hedgingConfig store.HedgingConfig,
+	replicaAwareSelection bool,
+	downsampleRawData bool,
 	deduplicationFunc string,
 	queryReplicaLabels []string,
 	queryPartitionLabels []string,
@@ -448,6 +506,8 @@ func runQuery(
 	enforceTenancy bool,
 	tenantLabel string,
 	queryDistributedWithOverlappingInterval bool,
+	queryDistributedCostBasedReplicaSelection bool,
+	resultsCache *query.ResultsCache,
 	lazyRetrievalMaxBufferedResponses int,
 	seriesResponseBatchSize int,
 ) error {
@@ -464,14 +524,23 @@ func runQuery(
 		store.WithTSDBSelector(tsdbSelector),
 		store.WithProxyStoreDebugLogging(debugLogging),
 		store.WithLazyRetrievalMaxBufferedResponsesForProxy(lazyRetrievalMaxBufferedResponses),
+		store.WithHedging(hedgingConfig),
+	}
+	if replicaAwareSelection {
+		options = append(options, store.WithReplicaAwareSelection())
 	}
 
 	// Parse and sanitize the provided replica labels flags.
 	queryReplicaLabels = strutil.ParseFlagLabels(queryReplicaLabels)
 
+	proxyStore := store.NewProxyStore(logger, reg, endpointSet.GetStoreClients, component.Query, selectorLset, storeResponseTimeout, store.RetrievalStrategy(grpcProxyStrategy), options...)
+	var seriesStore storepb.StoreServer = proxyStore
+	if downsampleRawData {
+		seriesStore = store.NewDownsamplingStoreServer(seriesStore, downsamplingResolutions, reg)
+	}
+
 	var (
-		proxyStore       = store.NewProxyStore(logger, reg, endpointSet.GetStoreClients, component.Query, selectorLset, storeResponseTimeout, store.RetrievalStrategy(grpcProxyStrategy), options...)
-		seriesProxy      = store.NewLimitedStoreServer(store.NewInstrumentedStoreServer(reg, proxyStore), reg, storeRateLimits)
+		seriesProxy      = store.NewLimitedStoreServer(store.NewInstrumentedStoreServer(reg, query.NewCachingStoreServer(seriesStore, resultsCache)), reg, storeRateLimits)
 		rulesProxy       = rules.NewProxy(logger, endpointSet.GetRulesClients)
 		targetsProxy     = targets.NewProxy(logger, endpointSet.GetTargetsClients)
 		metadataProxy    = metadata.NewProxy(logger, endpointSet.GetMetricMetadataClients)
@@ -493,6 +562,9 @@ func runQuery(
 			queryTimeout,
 			queryDistributedWithOverlappingInterval,
 			enableAutodownsampling,
+			queryDistributedCostBasedReplicaSelection,
+			query.NewEndpointLatencies(),
+			resultsCache,
 		)
 	)
 
@@ -523,7 +595,7 @@ func runQuery(
 		disableQueryFallback,
 	)
 
-	lookbackDeltaCreator := LookbackDeltaFactory(lookbackDelta, dynamicLookbackDelta)
+	lookbackDeltaCreator := LookbackDeltaFactory(lookbackDelta, dynamicLookbackDelta, downsamplingResolutions)
 
 	// Start query API + UI HTTP server.
 	{
@@ -695,16 +767,18 @@ func runQuery(
 	return nil
 }
 
-// LookbackDeltaFactory creates from 1 to 3 lookback deltas depending on
-// dynamicLookbackDelta and eo.LookbackDelta and returns a function
+// LookbackDeltaFactory creates one lookback delta per downsampling resolution (and one for raw data)
+// depending on dynamicLookbackDelta and eo.LookbackDelta and returns a function
 // that returns appropriate lookback delta for given maxSourceResolutionMillis.
+// The downsampling resolutions are expected in increasing order.
 func LookbackDeltaFactory(
 	lookbackDelta time.Duration,
 	dynamicLookbackDelta bool,
+	downsamplingResolutions []int64,
 ) func(int64) time.Duration {
 	resolutions := []int64{downsample.ResLevel0}
 	if dynamicLookbackDelta {
-		resolutions = []int64{downsample.ResLevel0, downsample.ResLevel1, downsample.ResLevel2}
+		resolutions = append(resolutions, downsamplingResolutions...)
 	}
 	var (
 		lds = make([]time.Duration, len(resolutions))
@@ -19,6 +19,9 @@ import (
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/thanos-io/objstore"
+	"github.com/thanos-io/objstore/client"
+	objstoretracing "github.com/thanos-io/objstore/tracing/opentracing"
 	"github.com/thanos-io/promql-engine/execution/parse"
 	"github.com/weaveworks/common/user"
 	"gopkg.in/yaml.v2"
@@ -28,6 +31,8 @@ import (
 	"github.com/thanos-io/thanos/internal/cortex/querier/queryrange"
 	cortexvalidation "github.com/thanos-io/thanos/internal/cortex/util/validation"
 	"github.com/thanos-io/thanos/pkg/api"
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
 	"github.com/thanos-io/thanos/pkg/component"
 	"github.com/thanos-io/thanos/pkg/exthttp"
 	"github.com/thanos-io/thanos/pkg/extkingpin"
@@ -48,6 +53,20 @@ type queryFrontendConfig struct {
 	http           httpConfig
 	webDisableCORS bool
 	orgIdHeaders   []string
+
+	downsamplingResolutions string
+	queryRulesConfig        *extflag.PathOrContent
+	queryRulesReloadTimer   time.Duration
+
+	tenantLimitsConfig              *extflag.PathOrContent
+	cardinalityStatsLimit           int
+	cardinalityStatsRefreshInterval time.Duration
+
+	cacheInvalidationObjStoreConfig     *extflag.PathOrContent
+	cacheInvalidationSyncInterval       time.Duration
+	cacheInvalidationLateBlockThreshold time.Duration
+	cacheInvalidationTenantLabel        string
+	cacheInvalidationEnableAPI          bool
 }
 
 func registerQueryFrontend(app *extkingpin.App) {
@@ -65,6 +84,9 @@ func registerQueryFrontend(app *extkingpin.App) {
 			LabelsConfig: queryfrontend.LabelsConfig{
 				Limits: &cortexvalidation.Limits{},
 			},
+			QueryInstantConfig: queryfrontend.QueryInstantConfig{
+				Limits: &cortexvalidation.Limits{},
+			},
 		},
 	}
 
@@ -80,6 +102,9 @@ func registerQueryFrontend(app *extkingpin.App) {
 	cmd.Flag("query-range.request-downsampled", "Make additional query for downsampled data in case of empty or incomplete response to range request.").
 		Default("true").BoolVar(&cfg.RequestDownsampled)
 
+	cmd.Flag("query-range.downsampling-resolutions", "Experimental. Comma separated downsampling resolutions requested in turn by query-range.request-downsampled. Has to match the resolutions of --downsampling.resolutions of the compactor.").
+		Default("5m,1h").StringVar(&cfg.downsamplingResolutions)
+
 	cmd.Flag("query-range.split-interval", "Split query range requests by an interval and execute in parallel, it should be greater than 0 when query-range.response-cache-config is configured.").
 		Default("24h").DurationVar(&cfg.QueryRangeConfig.SplitQueriesByInterval)
 
@@ -100,6 +125,17 @@ func registerQueryFrontend(app *extkingpin.App) {
 	cmd.Flag("query.max-retries-per-request", "Maximum number of retries for a single instant query request; beyond this, the downstream error is returned.").
 		Default("5").IntVar(&cfg.QueryInstantConfig.MaxRetries)
 
+	cmd.Flag("query.response-cache-max-freshness", "Most recent allowed cacheable result for instant queries, to prevent caching very recent results that might still be in flux.").
+		Default("1m").DurationVar((*time.Duration)(&cfg.QueryInstantConfig.Limits.MaxCacheFreshness))
+
+	cmd.Flag("query.cache-time-rounding-step", "Round down the evaluation time of cached instant queries to a multiple of this step, so that queries evaluated at close times share cache entries. 0 disables rounding.").
+		Default("0s").DurationVar(&cfg.QueryInstantConfig.CacheTimeRoundingStep)
+
+	cmd.Flag("query.split-interval", "Split instant queries of range vector functions like sum_over_time, increase and rate over ranges longer than this, optionally aggregated by sum, min or max, into queries of sub-windows of at most this length executed in parallel. 0 disables splitting.").
+		Default("0s").DurationVar(&cfg.QueryInstantConfig.SplitInterval)
+
+	cfg.QueryInstantConfig.CachePathOrContent = *extflag.RegisterPathOrContent(cmd, "query.response-cache-config", "YAML file that contains response cache configuration for instant queries.", extflag.WithEnvSubstitution())
+
 	cmd.Flag("query-frontend.enable-x-functions", "Enable experimental x- functions in query-frontend. --no-query-frontend.enable-x-functions for disabling.").
 		Default("false").BoolVar(&cfg.EnableXFunctions)
 
@@ -111,6 +147,40 @@ func registerQueryFrontend(app *extkingpin.App) {
 	cmd.Flag("query-range.max-query-parallelism", "Maximum number of query range requests will be scheduled in parallel by the Frontend.").
 		Default("14").IntVar(&cfg.QueryRangeConfig.Limits.MaxQueryParallelism)
 
+	cmd.Flag("query-range.max-estimated-series-per-query", "Reject range and instant queries estimated to select more series than this before executing them. "+
+		"The number of series is estimated from the TSDB status API of downstream queriers. 0 disables it.").
+		Default("0").IntVar(&cfg.QueryRangeConfig.Limits.MaxEstimatedSeriesPerQuery)
+
+	cmd.Flag("query-range.max-estimated-samples-per-query", "Reject range and instant queries estimated to fetch more samples than this before executing them. "+
+		"The number of samples is estimated from the number of series and the time range selected by the query. 0 disables it.").
+		Default("0").IntVar(&cfg.QueryRangeConfig.Limits.MaxEstimatedSamplesPerQuery)
+
+	cmd.Flag("query-range.max-response-size-bytes", "Reject range queries whose downstream responses, summed over their split and sharded requests, exceed this size in bytes. "+
+		"Responses are rejected while they are decoded, before they are fully read into memory. 0 disables it.").
+		Default("0").IntVar(&cfg.QueryRangeConfig.Limits.MaxResponseSizeBytes)
+
+	cmd.Flag("query-range.max-response-series", "Reject range queries whose response has more series than this. 0 disables it.").
+		Default("0").IntVar(&cfg.QueryRangeConfig.Limits.MaxResponseSeries)
+
+	cmd.Flag("query-range.incremental-merge", "Merge the responses of split range queries as they complete in time order, releasing each of them once merged, "+
+		"and write the merged response to the client series by series instead of encoding it into memory first. "+
+		"The response is not streamed while split queries complete, the merged series are held in memory until all of them are merged.").
+		Default("false").BoolVar(&cfg.QueryRangeConfig.IncrementalMerge)
+
+	cmd.Flag("query-range.downsample-expensive-queries", "Use downsampled data for queries estimated to fetch more samples than query-range.max-estimated-samples-per-query, if that brings them under the limit, instead of rejecting them.").
+		Default("false").BoolVar(&cfg.DownsampleExpensiveQueries)
+
+	cmd.Flag("query-range.cost-estimation-scrape-interval", "Interval between raw samples of series assumed when estimating the number of samples fetched by queries.").
+		Default("30s").DurationVar(&cfg.ScrapeInterval)
+
+	cmd.Flag("query-range.cost-estimation-stats-limit", "Number of metric names and label value pairs with the most series fetched from the TSDB status API of downstream queriers to estimate the number of series selected by queries.").
+		Default("1000").IntVar(&cfg.cardinalityStatsLimit)
+
+	cmd.Flag("query-range.cost-estimation-stats-refresh-interval", "Interval at which the statistics used to estimate the cost of queries are refreshed per tenant.").
+		Default("1m").DurationVar(&cfg.cardinalityStatsRefreshInterval)
+
+	cfg.tenantLimitsConfig = extflag.RegisterPathOrContent(cmd, "query-range.tenant-limits-config", "YAML file that contains per-tenant overrides of query range limits, e.g. max_estimated_series_per_query or max_response_size_bytes.", extflag.WithEnvSubstitution())
+
 	cmd.Flag("query-range.response-cache-max-freshness", "Most recent allowed cacheable result for query range requests, to prevent caching very recent results that might still be in flux.").
 		Default("1m").DurationVar((*time.Duration)(&cfg.QueryRangeConfig.Limits.MaxCacheFreshness))
 
@@ -140,6 +210,12 @@ func registerQueryFrontend(app *extkingpin.App) {
 
 	cfg.LabelsConfig.CachePathOrContent = *extflag.RegisterPathOrContent(cmd, "labels.response-cache-config", "YAML file that contains response cache configuration.", extflag.WithEnvSubstitution())
 
+	cmd.Flag("remote-read.split-interval", "Split remote read requests answered with samples by an interval and execute in parallel. Limits of range queries apply to remote read requests. 0 disables it.").
+		Default("24h").DurationVar(&cfg.RemoteReadConfig.SplitQueriesByInterval)
+
+	cmd.Flag("remote-read.max-retries-per-request", "Maximum number of retries for a single remote read request; beyond this, the downstream error is returned.").
+		Default("5").IntVar(&cfg.RemoteReadConfig.MaxRetries)
+
 	cmd.Flag("cache-compression-type", "Use compression in results cache. Supported values are: 'snappy' and '' (disable compression).").
 		Default("").StringVar(&cfg.CacheCompression)
 
@@ -148,6 +224,33 @@ func registerQueryFrontend(app *extkingpin.App) {
 
 	cfg.DownstreamTripperConfig.CachePathOrContent = *extflag.RegisterPathOrContent(cmd, "query-frontend.downstream-tripper-config", "YAML file that contains downstream tripper configuration. If your downstream URL is localhost or 127.0.0.1 then it is highly recommended to increase max_idle_conns_per_host to at least 100.", extflag.WithEnvSubstitution())
 
+	cmd.Flag("query-frontend.max-concurrent-downstream-requests", "Maximum number of requests, including split and sharded sub-queries, sent to downstream queriers at the same time. "+
+		"Further requests are queued per tenant and dequeued in round robin order across tenants. 0 disables queueing.").
+		Default("0").IntVar(&cfg.MaxConcurrentRequests)
+
+	cmd.Flag("query-frontend.max-outstanding-requests-per-tenant", "Maximum number of queued requests per tenant when queueing is enabled. Further requests of the tenant are rejected with 429.").
+		Default("100").IntVar(&cfg.MaxOutstandingPerTenant)
+
+	cfg.queryRulesConfig = extflag.RegisterPathOrContent(cmd, "query-frontend.query-rules-config", "YAML file that contains rules rejecting, throttling or rewriting range, instant and labels requests. The file is reloaded on changes.", extflag.WithEnvSubstitution())
+
+	cmd.Flag("query-frontend.query-rules-config-reload-timer", "Minimum amount of time to pass for the query rules configuration to be reloaded. Helps to avoid excessive reloads.").
+		Default("1s").Hidden().DurationVar(&cfg.queryRulesReloadTimer)
+
+	cfg.cacheInvalidationObjStoreConfig = extkingpin.RegisterCommonObjStoreFlags(cmd, "", false,
+		"If set, results cached for time ranges of blocks backfilled, rewritten or deleted in the bucket are invalidated. Invalidations caused by deleted blocks and explicit invalidations are stored in the "+queryfrontend.CacheGenerationsDir+" directory of the bucket, so that they are shared between replicas and kept across restarts.")
+
+	cmd.Flag("query-frontend.cache-invalidation.sync-interval", "Interval at which blocks in the bucket are synced to invalidate cached results of changed time ranges.").
+		Default("1m").DurationVar(&cfg.cacheInvalidationSyncInterval)
+
+	cmd.Flag("query-frontend.cache-invalidation.late-block-threshold", "Blocks uploaded later than this after their max time are considered backfilled or rewritten, invalidating results cached for their time range.").
+		Default("6h").DurationVar(&cfg.cacheInvalidationLateBlockThreshold)
+
+	cmd.Flag("query-frontend.cache-invalidation.tenant-label", "External label of blocks holding the tenant whose cached results are invalidated by changes of the blocks. Changes of blocks without it invalidate results of all tenants.").
+		Default(tenancy.DefaultTenantLabel).StringVar(&cfg.cacheInvalidationTenantLabel)
+
+	cmd.Flag("query-frontend.cache-invalidation.enable-api", "Enable the /api/v1/cache/invalidate endpoint invalidating results cached for the time range given by the start and end parameters and the tenant parameter, or all tenants if not set. Invalidations are stored in the bucket given by --objstore.config and picked up by other replicas on their next sync. Without a bucket, they are kept in memory of the receiving Query Frontend only.").
+		Default("false").BoolVar(&cfg.cacheInvalidationEnableAPI)
+
 	cmd.Flag("query-frontend.compress-responses", "Compress HTTP responses.").
 		Default("false").BoolVar(&cfg.CompressResponses)
 
@@ -181,10 +284,55 @@ func registerQueryFrontend(app *extkingpin.App) {
 			return errors.Wrap(err, "error while parsing config for request logging")
 		}
 
+		cfg.QueryRangeConfig.DownsamplingResolutions, err = downsample.ParseResolutions(cfg.downsamplingResolutions)
+		if err != nil {
+			return errors.Wrap(err, "parse downsampling resolutions")
+		}
+
 		return runQueryFrontend(g, logger, reg, tracer, httpLogOpts, cfg, comp)
 	})
 }
 
+// runCacheInvalidationSync periodically syncs blocks in the bucket to invalidate results cached for time ranges
+// whose blocks changed, and invalidations stored in the bucket by other replicas.
+func runCacheInvalidationSync(g *run.Group, logger log.Logger, reg *prometheus.Registry, confContentYaml []byte, cfg *queryFrontendConfig) error {
+	if cfg.cacheInvalidationSyncInterval <= 0 {
+		return errors.New("query-frontend.cache-invalidation.sync-interval should be greater than 0")
+	}
+	bkt, err := client.NewBucket(logger, confContentYaml, component.QueryFrontend.String(), nil)
+	if err != nil {
+		return err
+	}
+	insBkt := objstoretracing.WrapWithTraces(objstore.WrapWithMetrics(bkt, extprom.WrapRegistererWithPrefix("thanos_", reg), bkt.Name()))
+
+	logger = log.With(logger, "component", "cache-invalidation")
+	metaFetcher, err := block.NewMetaFetcher(logger, block.FetcherConcurrency, insBkt, block.NewConcurrentLister(logger, insBkt), "", extprom.WrapRegistererWithPrefix("thanos_", reg), nil)
+	if err != nil {
+		runutil.CloseWithLogOnErr(logger, insBkt, "bucket client")
+		return errors.Wrap(err, "create meta fetcher")
+	}
+	observer := queryfrontend.NewBlockChangeObserver(logger, metaFetcher, cfg.CacheGenerations, cfg.cacheInvalidationTenantLabel, cfg.cacheInvalidationLateBlockThreshold)
+	cfg.CacheGenerations.SetBucket(insBkt)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g.Add(func() error {
+		defer runutil.CloseWithLogOnErr(logger, insBkt, "bucket client")
+
+		return runutil.Repeat(cfg.cacheInvalidationSyncInterval, ctx.Done(), func() error {
+			if err := cfg.CacheGenerations.Sync(ctx); err != nil {
+				level.Warn(logger).Log("msg", "syncing stored invalidations failed, retrying in next interval", "err", err)
+			}
+			if err := observer.SyncBlocks(ctx); err != nil {
+				level.Warn(logger).Log("msg", "syncing blocks failed, retrying in next interval", "err", err)
+			}
+			return nil
+		})
+	}, func(error) {
+		cancel()
+	})
+	return nil
+}
+
 func parseTransportConfiguration(downstreamTripperConfContentYaml []byte) (*http.Transport, error) {
 	downstreamTripper := &http.Transport{
 		Proxy: http.ProxyFromEnvironment,
@@ -299,6 +447,21 @@ func runQueryFrontend(
 		}
 	}
 
+	queryInstantCacheConfContentYaml, err := cfg.QueryInstantConfig.CachePathOrContent.Content()
+	if err != nil {
+		return err
+	}
+	if len(queryInstantCacheConfContentYaml) > 0 {
+		cacheConfig, err := queryfrontend.NewCacheConfig(logger, queryInstantCacheConfContentYaml)
+		if err != nil {
+			return errors.Wrap(err, "initializing the query instant cache config")
+		}
+		cfg.QueryInstantConfig.ResultsCacheConfig = &queryrange.ResultsCacheConfig{
+			Compression: cfg.CacheCompression,
+			CacheConfig: *cacheConfig,
+		}
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return errors.Wrap(err, "error validating the config")
 	}
@@ -316,9 +479,27 @@ func runQueryFrontend(
 		}
 	}
 
-	tripperWare, err := queryfrontend.NewTripperware(cfg.Config, reg, logger)
+	queryRulesContent, err := cfg.queryRulesConfig.Content()
 	if err != nil {
-		return errors.Wrap(err, "setup tripperwares")
+		return err
+	}
+	if len(queryRulesContent) > 0 {
+		cfg.QueryRules, err = queryfrontend.NewQueryRules(cfg.queryRulesConfig, log.With(logger, "component", "query-rules"), reg, cfg.queryRulesReloadTimer)
+		if err != nil {
+			return errors.Wrap(err, "initializing query rules")
+		}
+		if cfg.QueryRules.CanReload() {
+			ctx, cancel := context.WithCancel(context.Background())
+			g.Add(func() error {
+				if err := cfg.QueryRules.StartConfigReloader(ctx); err != nil {
+					return err
+				}
+				<-ctx.Done()
+				return nil
+			}, func(error) {
+				cancel()
+			})
+		}
 	}
 
 	// Create a downstream roundtripper.
@@ -336,8 +517,50 @@ func runQueryFrontend(
 		return errors.Wrap(err, "setup downstream roundtripper")
 	}
 
+	tenantLimitsContent, err := cfg.tenantLimitsConfig.Content()
+	if err != nil {
+		return err
+	}
+	if len(tenantLimitsContent) > 0 {
+		cfg.TenantLimits, err = queryfrontend.NewTenantLimits(tenantLimitsContent, *cfg.QueryRangeConfig.Limits)
+		if err != nil {
+			return errors.Wrap(err, "initializing tenant limits")
+		}
+	}
+	// Statistics are fetched only if queries can be limited by their estimated cost. Like health checks, they are not queued.
+	if cfg.QueryRangeConfig.Limits.MaxEstimatedSeriesPerQuery > 0 || cfg.QueryRangeConfig.Limits.MaxEstimatedSamplesPerQuery > 0 || cfg.TenantLimits != nil {
+		if cfg.ScrapeInterval <= 0 || cfg.cardinalityStatsLimit <= 0 {
+			return errors.New("query-range.cost-estimation-scrape-interval and query-range.cost-estimation-stats-limit should be greater than 0 when cost limits are set")
+		}
+		cfg.CardinalityStats = queryfrontend.NewCardinalityStats(downstreamRT, cfg.cardinalityStatsLimit, cfg.cardinalityStatsRefreshInterval, reg)
+	}
+
+	cacheInvalidationObjStoreContent, err := cfg.cacheInvalidationObjStoreConfig.Content()
+	if err != nil {
+		return err
+	}
+	if len(cacheInvalidationObjStoreContent) > 0 || cfg.cacheInvalidationEnableAPI {
+		cfg.CacheGenerations = queryfrontend.NewCacheGenerations(reg)
+	}
+	if len(cacheInvalidationObjStoreContent) > 0 {
+		if err := runCacheInvalidationSync(g, logger, reg, cacheInvalidationObjStoreContent, cfg); err != nil {
+			return errors.Wrap(err, "setup cache invalidation")
+		}
+	}
+
+	tripperWare, err := queryfrontend.NewTripperware(cfg.Config, reg, logger)
+	if err != nil {
+		return errors.Wrap(err, "setup tripperwares")
+	}
+
+	// Health checks of the downstream URL below are not queued.
+	queuedRT := downstreamRT
+	if cfg.MaxConcurrentRequests > 0 {
+		queuedRT = queryfrontend.NewQueueRoundTripper(downstreamRT, cfg.QueueConfig, cfg.DefaultTenant, reg)
+	}
+
 	// Wrap the downstream RoundTripper into query frontend Tripperware.
-	roundTripper := tripperWare(downstreamRT)
+	roundTripper := tripperWare(queuedRT)
 
 	// Create the query frontend transport.
 	handler := transport.NewHandler(*cfg.CortexHandlerConfig, roundTripper, logger, nil)
@@ -385,6 +608,9 @@ func runQueryFrontend(
 			})
 			return hf
 		}
+		if cfg.cacheInvalidationEnableAPI {
+			srv.Handle("/api/v1/cache/invalidate", instr(queryfrontend.NewCacheInvalidationHandler(cfg.CacheGenerations, logger).ServeHTTP))
+		}
 		srv.Handle("/", instr(handler.ServeHTTP))
 
 		g.Add(func() error {
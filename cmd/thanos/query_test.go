@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/efficientgo/core/testutil"
+
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
 )
 
 func TestLookbackDeltaFactory(t *testing.T) {
@@ -19,9 +21,10 @@ func TestLookbackDeltaFactory(t *testing.T) {
 		minute = time.Minute.Milliseconds()
 		hour   = time.Hour.Milliseconds()
 		tData  = []struct {
-			lookbackDelta        time.Duration
-			dynamicLookbackDelta bool
-			tcs                  []testCase
+			lookbackDelta           time.Duration
+			dynamicLookbackDelta    bool
+			downsamplingResolutions []int64
+			tcs                     []testCase
 		}{
 			{
 				// Non-dynamic lookbackDelta should always return the same engine.
@@ -82,10 +85,25 @@ func TestLookbackDeltaFactory(t *testing.T) {
 					{2 * hour, time.Duration(1) * time.Hour},
 				},
 			},
+			{
+				lookbackDelta:           5 * time.Minute,
+				dynamicLookbackDelta:    true,
+				downsamplingResolutions: []int64{15 * minute, 2 * hour},
+				tcs: []testCase{
+					{5 * minute, time.Duration(5) * time.Minute},
+					{6 * minute, time.Duration(15) * time.Minute},
+					{15 * minute, time.Duration(15) * time.Minute},
+					{16 * minute, time.Duration(2) * time.Hour},
+				},
+			},
 		}
 	)
 	for _, td := range tData {
-		lookbackCreate := LookbackDeltaFactory(td.lookbackDelta, td.dynamicLookbackDelta)
+		resolutions := td.downsamplingResolutions
+		if resolutions == nil {
+			resolutions = []int64{downsample.ResLevel1, downsample.ResLevel2}
+		}
+		lookbackCreate := LookbackDeltaFactory(td.lookbackDelta, td.dynamicLookbackDelta, resolutions)
 		for _, tc := range td.tcs {
 			got := lookbackCreate(tc.stepMillis)
 			testutil.Equals(t, tc.expect, got)
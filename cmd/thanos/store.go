@@ -31,6 +31,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/block"
 	"github.com/thanos-io/thanos/pkg/block/indexheader"
 	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
 	"github.com/thanos-io/thanos/pkg/component"
 	hidden "github.com/thanos-io/thanos/pkg/extflag"
 	"github.com/thanos-io/thanos/pkg/exthttp"
@@ -50,6 +51,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/store"
 	storecache "github.com/thanos-io/thanos/pkg/store/cache"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
 	"github.com/thanos-io/thanos/pkg/tls"
 	"github.com/thanos-io/thanos/pkg/ui"
 )
@@ -102,6 +104,8 @@ type storeConfig struct {
 	lazyIndexReaderIdleTimeout    time.Duration
 	lazyExpandedPostingsEnabled   bool
 	postingGroupMaxKeySeriesRatio float64
+	downsampleRawData             bool
+	downsampleRawDataResolutions  string
 
 	indexHeaderLazyDownloadStrategy string
 
@@ -206,6 +210,12 @@ func (sc *storeConfig) registerFlag(cmd extkingpin.FlagClause) {
 	cmd.Flag("store.enable-lazy-expanded-postings", "If true, Store Gateway will estimate postings size and try to lazily expand postings if it downloads less data than expanding all postings.").
 		Default("false").BoolVar(&sc.lazyExpandedPostingsEnabled)
 
+	cmd.Flag("store.downsample-raw-data", "If true, Store Gateway will aggregate raw chunks on the fly for Series requests allowing a downsampled resolution, e.g. with max_source_resolution set, when only raw blocks are available for their time range.").
+		Default("false").BoolVar(&sc.downsampleRawData)
+
+	cmd.Flag("store.downsample-raw-data.resolutions", "Comma separated downsampling resolutions raw chunks are aggregated to with --store.downsample-raw-data. The highest resolution allowed by a request is used. Has to match the resolutions of --downsampling.resolutions of the compactor.").
+		Default("5m,1h").StringVar(&sc.downsampleRawDataResolutions)
+
 	cmd.Flag("store.posting-group-max-key-series-ratio", "Mark posting group as lazy if it fetches more keys than R * max series the query should fetch. With R set to 100, a posting group which fetches 100K keys will be marked as lazy if the current query only fetches 1000 series. thanos_bucket_store_lazy_expanded_posting_groups_total shows lazy expanded postings groups with reasons and you can tune this config accordingly. This config is only valid if lazy expanded posting is enabled. 0 disables the limit.").
 		Default("100").Float64Var(&sc.postingGroupMaxKeySeriesRatio)
 
@@ -550,7 +560,15 @@ func runStore(
 			return errors.Wrap(err, "setup gRPC server")
 		}
 
-		storeServer := store.NewInstrumentedStoreServer(reg, bs)
+		var storeServer storepb.StoreServer = bs
+		if conf.downsampleRawData {
+			resolutions, err := downsample.ParseResolutions(conf.downsampleRawDataResolutions)
+			if err != nil {
+				return errors.Wrap(err, "parse downsampling resolutions")
+			}
+			storeServer = store.NewDownsamplingStoreServer(storeServer, resolutions, reg)
+		}
+		storeServer = store.NewInstrumentedStoreServer(reg, storeServer)
 		s := grpcserver.New(logger, reg, tracer, grpcLogOpts, logFilterMethods, conf.component, grpcProbe,
 			grpcserver.WithServer(store.RegisterStoreServer(storeServer, logger)),
 			grpcserver.WithServer(info.RegisterInfoServer(infoSrv)),
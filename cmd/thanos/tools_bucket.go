@@ -19,6 +19,7 @@ import (
 	"text/template"
 	"time"
 
+	"github.com/alecthomas/units"
 	extflag "github.com/efficientgo/tools/extkingpin"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -30,10 +31,12 @@ import (
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	config_util "github.com/prometheus/common/config"
 	prommodel "github.com/prometheus/common/model"
 	"github.com/prometheus/common/route"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/storage/remote"
 	"github.com/prometheus/prometheus/tsdb"
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
 	"golang.org/x/text/language"
@@ -45,15 +48,18 @@ import (
 	objstoretracing "github.com/thanos-io/objstore/tracing/opentracing"
 
 	v1 "github.com/thanos-io/thanos/pkg/api/blocks"
+	"github.com/thanos-io/thanos/pkg/backfill"
 	"github.com/thanos-io/thanos/pkg/block"
 	"github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/compact"
 	"github.com/thanos-io/thanos/pkg/compact/downsample"
 	"github.com/thanos-io/thanos/pkg/compactv2"
 	"github.com/thanos-io/thanos/pkg/component"
+	"github.com/thanos-io/thanos/pkg/export"
 	"github.com/thanos-io/thanos/pkg/extkingpin"
 	"github.com/thanos-io/thanos/pkg/extprom"
 	extpromhttp "github.com/thanos-io/thanos/pkg/extprom/http"
+	"github.com/thanos-io/thanos/pkg/extpromql"
 	"github.com/thanos-io/thanos/pkg/logging"
 	"github.com/thanos-io/thanos/pkg/logutil"
 	"github.com/thanos-io/thanos/pkg/model"
@@ -64,6 +70,7 @@ import (
 	httpserver "github.com/thanos-io/thanos/pkg/server/http"
 	"github.com/thanos-io/thanos/pkg/shipper"
 	"github.com/thanos-io/thanos/pkg/store"
+	"github.com/thanos-io/thanos/pkg/strutil"
 	"github.com/thanos-io/thanos/pkg/ui"
 	"github.com/thanos-io/thanos/pkg/verifier"
 )
@@ -98,6 +105,28 @@ type bucketRewriteConfig struct {
 	deleteBlocks bool
 }
 
+type bucketRepairConfig struct {
+	blockIDs []string
+	tmpDir   string
+	dryRun   bool
+	labels   []string
+	hashFunc string
+}
+
+type bucketImportConfig struct {
+	tmpDir        string
+	labels        []string
+	blockDuration prommodel.Duration
+	hashFunc      string
+}
+
+type bucketExportConfig struct {
+	selector string
+	format   string
+	output   string
+	tmpDir   string
+}
+
 type bucketInspectConfig struct {
 	selector []string
 	sortBy   []string
@@ -136,6 +165,10 @@ type bucketReplicateConfig struct {
 	compactions []int
 	matcherStrs string
 	singleRun   bool
+
+	migrateVerify             bool
+	migrateVerticalCompaction bool
+	migrateCompactDir         string
 }
 
 type bucketDownsampleConfig struct {
@@ -144,6 +177,7 @@ type bucketDownsampleConfig struct {
 	blockFilesConcurrency int
 	dataDir               string
 	hashFunc              string
+	ladder                string
 }
 
 type bucketCleanupConfig struct {
@@ -158,6 +192,25 @@ type bucketRetentionConfig struct {
 	deleteDelay          time.Duration
 }
 
+type bucketCompactPlanConfig struct {
+	consistencyDelay         time.Duration
+	blockSyncConcurrency     int
+	deleteDelay              time.Duration
+	maxCompactionLevel       int
+	dedupReplicaLabels       []string
+	enableVerticalCompaction bool
+	maxBlockIndexSize        units.Base2Bytes
+	splitShards              uint64
+	disableDownsampling      bool
+	downsamplingLadder       string
+	retentionRaw             prommodel.Duration
+	retentionFiveMin         prommodel.Duration
+	retentionOneHr           prommodel.Duration
+	retentionByResolution    []string
+	output                   string
+	timeout                  time.Duration
+}
+
 type bucketMarkBlockConfig struct {
 	details      string
 	marker       string
@@ -245,6 +298,13 @@ func (tbc *bucketReplicateConfig) registerBucketReplicateFlag(cmd extkingpin.Fla
 
 	cmd.Flag("single-run", "Run replication only one time, then exit.").Default("false").BoolVar(&tbc.singleRun)
 
+	cmd.Flag("migrate.verify", "Verify sizes and hashes of replicated files in the destination bucket against meta.json before replicating meta.json of a block. Hashes are verified only for blocks uploaded with a hash function.").
+		Default("false").BoolVar(&tbc.migrateVerify)
+	cmd.Flag("migrate.vertical-compaction", "Run vertical compaction for groups of replicated blocks in the destination bucket after replication, e.g. to merge overlapping blocks of sources merged by --migrate.relabel-config. Requires --single-run. NOTE: The compactor of the destination bucket should not run at the same time.").
+		Default("false").BoolVar(&tbc.migrateVerticalCompaction)
+	cmd.Flag("migrate.compact-dir", "Working directory of vertical compaction in the destination bucket.").
+		Default(filepath.Join(os.TempDir(), "thanos-migrate")).StringVar(&tbc.migrateCompactDir)
+
 	return tbc
 }
 
@@ -258,6 +318,45 @@ func (tbc *bucketRewriteConfig) registerBucketRewriteFlag(cmd extkingpin.FlagCla
 	return tbc
 }
 
+func (tbc *bucketRepairConfig) registerBucketRepairFlag(cmd extkingpin.FlagClause) *bucketRepairConfig {
+	cmd.Flag("id", "ID (ULID) of the blocks to repair (repeated flag).").Required().StringsVar(&tbc.blockIDs)
+	cmd.Flag("tmp.dir", "Working directory for temporary files").Default(filepath.Join(os.TempDir(), "thanos-repair")).StringVar(&tbc.tmpDir)
+	cmd.Flag("dry-run", "Prints the detected issues instead of repairing them. Defaults to true, for user to double check. (: Pass --no-dry-run to skip this.").Default("true").BoolVar(&tbc.dryRun)
+	cmd.Flag("label", "External labels of blocks with missing meta.json file, used to reconstruct it (repeated).").PlaceHolder("key=\"value\"").StringsVar(&tbc.labels)
+	cmd.Flag("hash-func", "Specify which hash function to use when calculating the hashes of produced files. If no function has been specified, it does not happen. Possible values are: \"\", \"SHA256\".").
+		Default("").EnumVar(&tbc.hashFunc, "SHA256", "")
+
+	return tbc
+}
+
+func (tbc *bucketImportConfig) registerBucketImportFlag(cmd extkingpin.FlagClause) *bucketImportConfig {
+	cmd.Flag("tmp.dir", "Working directory for temporary files").Default(filepath.Join(os.TempDir(), "thanos-import")).StringVar(&tbc.tmpDir)
+	cmd.Flag("label", "External labels of the created blocks (repeated). Series labels with the same name and value are dropped, series with conflicting values are rejected.").
+		Required().PlaceHolder("key=\"value\"").StringsVar(&tbc.labels)
+	cmd.Flag("block-duration", "Time range covered by a single created block. Blocks are aligned to multiples of it. Should be one of the compaction ranges to let compactor compact them with other blocks.").
+		Default("2h").SetValue(&tbc.blockDuration)
+	cmd.Flag("hash-func", "Specify which hash function to use when calculating the hashes of produced files. If no function has been specified, it does not happen. Possible values are: \"\", \"SHA256\".").
+		Default("").EnumVar(&tbc.hashFunc, "SHA256", "")
+
+	return tbc
+}
+
+func (tbc *bucketExportConfig) registerBucketExportFlag(cmd extkingpin.FlagClause) *bucketExportConfig {
+	formats := make([]string, 0, len(export.Formats))
+	for _, f := range export.Formats {
+		formats = append(formats, string(f))
+	}
+
+	cmd.Flag("selector", "Series selector of series to export. Matchers for external label names are matched against external labels of blocks.").
+		Required().PlaceHolder("<selector>").StringVar(&tbc.selector)
+	cmd.Flag("format", fmt.Sprintf("Format of the exported file. Possible values are: %s. Native histogram samples are not exported.", strings.Join(formats, ", "))).
+		Default(string(export.OpenMetrics)).EnumVar(&tbc.format, formats...)
+	cmd.Flag("output", "Path of the exported file. Samples are written to stdout if set to -.").Default("-").StringVar(&tbc.output)
+	cmd.Flag("tmp.dir", "Working directory for temporary files").Default(filepath.Join(os.TempDir(), "thanos-export")).StringVar(&tbc.tmpDir)
+
+	return tbc
+}
+
 func (tbc *bucketDownsampleConfig) registerBucketDownsampleFlag(cmd extkingpin.FlagClause) *bucketDownsampleConfig {
 	cmd.Flag("wait-interval", "Wait interval between downsample runs.").
 		Default("5m").DurationVar(&tbc.waitInterval)
@@ -269,6 +368,8 @@ func (tbc *bucketDownsampleConfig) registerBucketDownsampleFlag(cmd extkingpin.F
 		Default("./data").StringVar(&tbc.dataDir)
 	cmd.Flag("hash-func", "Specify which hash function to use when calculating the hashes of produced files. If no function has been specified, it does not happen. This permits avoiding downloading some files twice albeit at some performance cost. Possible values are: \"\", \"SHA256\".").
 		Default("").EnumVar(&tbc.hashFunc, "SHA256", "")
+	cmd.Flag("downsampling.resolutions", "Experimental. Downsampling ladder as comma separated <resolution>:<min-block-range> levels. Raw blocks are downsampled to the first level once they cover its min block range, blocks of each level to the next one.").
+		Default(defaultDownsamplingLadder).StringVar(&tbc.ladder)
 
 	return tbc
 }
@@ -300,6 +401,40 @@ func (tbc *bucketRetentionConfig) registerBucketRetentionFlag(cmd extkingpin.Fla
 	return tbc
 }
 
+func (tbc *bucketCompactPlanConfig) registerBucketCompactPlanFlag(cmd extkingpin.FlagClause) *bucketCompactPlanConfig {
+	cmd.Flag("consistency-delay", fmt.Sprintf("Minimum age of fresh (non-compacted) blocks before they are being processed. Malformed blocks older than the maximum of consistency-delay and %v will be removed.", compact.PartialUploadThresholdAge)).
+		Default("30m").DurationVar(&tbc.consistencyDelay)
+	cmd.Flag("block-sync-concurrency", "Number of goroutines to use when syncing block metadata from object storage.").
+		Default("20").IntVar(&tbc.blockSyncConcurrency)
+	cmd.Flag("delete-delay", "Time before a block marked for deletion is deleted from bucket.").Default("48h").DurationVar(&tbc.deleteDelay)
+	cmd.Flag("debug.max-compaction-level", fmt.Sprintf("Maximum compaction level, default is %d: %s", compactions.maxLevel(), compactions.String())).
+		Hidden().Default(strconv.Itoa(compactions.maxLevel())).IntVar(&tbc.maxCompactionLevel)
+	cmd.Flag("deduplication.replica-label", "Label to treat as a replica indicator of blocks that can be deduplicated (repeated flag). Enables vertical compaction like in the compactor.").
+		StringsVar(&tbc.dedupReplicaLabels)
+	cmd.Flag("compact.enable-vertical-compaction", "Plan vertical compaction of overlapping blocks like the compactor.").
+		Default("false").BoolVar(&tbc.enableVerticalCompaction)
+	cmd.Flag("compact.block-max-index-size", "Maximum index size for the resulted block during any compaction. Blocks which would be marked for no compaction because of it are reported.").
+		Default("64GB").BytesVar(&tbc.maxBlockIndexSize)
+	cmd.Flag("compact.split-shards", "Number of shards to split series of blocks into when compacting blocks which are not sharded yet. 0 or 1 disables split compaction.").
+		Default("0").Uint64Var(&tbc.splitShards)
+	cmd.Flag("downsampling.disable", "Do not plan downsampling.").
+		Default("false").BoolVar(&tbc.disableDownsampling)
+	cmd.Flag("downsampling.resolutions", "Downsampling ladder as comma separated <resolution>:<min-block-range> levels.").
+		Default(defaultDownsamplingLadder).StringVar(&tbc.downsamplingLadder)
+	cmd.Flag("retention.resolution-raw", "How long to retain raw samples in bucket. Setting this to 0d will retain samples of this resolution forever").
+		Default("0d").SetValue(&tbc.retentionRaw)
+	cmd.Flag("retention.resolution-5m", "How long to retain samples of resolution 1 (5 minutes) in bucket. Setting this to 0d will retain samples of this resolution forever").
+		Default("0d").SetValue(&tbc.retentionFiveMin)
+	cmd.Flag("retention.resolution-1h", "How long to retain samples of resolution 2 (1 hour) in bucket. Setting this to 0d will retain samples of this resolution forever").
+		Default("0d").SetValue(&tbc.retentionOneHr)
+	cmd.Flag("retention.resolution", "How long to retain samples of a custom downsampling resolution, as <resolution>:<retention>. May be repeated.").
+		PlaceHolder("<resolution>:<retention>").StringsVar(&tbc.retentionByResolution)
+	cmd.Flag("output", "Output format for result. Currently supports table, csv, tsv, json.").
+		Default("table").EnumVar(&tbc.output, append(outputTypes, "json")...)
+	cmd.Flag("timeout", "Timeout to download metadata from remote storage and plan.").Default("10m").DurationVar(&tbc.timeout)
+	return tbc
+}
+
 func (tbc *bucketUploadBlocksConfig) registerBucketUploadBlocksFlag(cmd extkingpin.FlagClause) *bucketUploadBlocksConfig {
 	cmd.Flag("path", "Path to the directory containing blocks to upload.").Default("./data").StringVar(&tbc.path)
 	cmd.Flag("label", "External labels to add to the uploaded blocks (repeated).").PlaceHolder("key=\"value\"").StringsVar(&tbc.labels)
@@ -324,6 +459,10 @@ func registerBucket(app extkingpin.AppClause) {
 	registerBucketRewrite(cmd, objStoreConfig)
 	registerBucketRetention(cmd, objStoreConfig)
 	registerBucketUploadBlocks(cmd, objStoreConfig)
+	registerBucketCompactPlan(cmd, objStoreConfig)
+	registerBucketRepair(cmd, objStoreConfig)
+	registerBucketImport(cmd, objStoreConfig)
+	registerBucketExport(cmd, objStoreConfig)
 }
 
 func registerBucketVerify(app extkingpin.AppClause, objStoreConfig *extflag.PathOrContent) {
@@ -761,6 +900,12 @@ func registerBucketReplicate(app extkingpin.AppClause, objStoreConfig *extflag.P
 		Default("0000-01-01T00:00:00Z"))
 	maxTime := model.TimeOrDuration(cmd.Flag("max-time", "End of time range limit to replicate. Thanos Replicate will replicate only metrics, which happened earlier than this value. Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or 2h45m. Valid duration units are ms, s, m, h, d, w, y.").
 		Default("9999-12-31T23:59:59Z"))
+	migrateRelabelConf := &relabelCfg{extflag.RegisterPathOrContent(cmd, "migrate.relabel-config",
+		"YAML file with relabeling configuration applied to external labels of replicated blocks, e.g. to rename a label or to merge blocks of multiple sources. "+
+			"The meta.json in the destination bucket contains the rewritten labels. Blocks dropped by relabeling are not replicated. "+
+			"For format details see: https://thanos.io/tip/thanos/sharding.md/#relabelling",
+		extflag.WithEnvSubstitution(),
+	)}
 	ids := cmd.Flag("id", "Block to be replicated to the destination bucket. IDs will be used to match blocks and other matchers will be ignored. When specified, this command will be run only once after successful replication. Repeated field").Strings()
 	ignoreMarkedForDeletion := cmd.Flag("ignore-marked-for-deletion", "Do not replicate blocks that have deletion mark.").Bool()
 
@@ -794,6 +939,18 @@ func registerBucketReplicate(app extkingpin.AppClause, objStoreConfig *extflag.P
 			blockIDs = append(blockIDs, bid)
 		}
 
+		migrateRelabelConfig, err := migrateRelabelConf.RelabelConfig(nil)
+		if err != nil {
+			return errors.Wrap(err, "parse migrate relabel config")
+		}
+		if tbc.migrateVerticalCompaction && !tbc.singleRun && len(blockIDs) == 0 {
+			return errors.New("--migrate.vertical-compaction requires --single-run")
+		}
+		levels, err := compactions.levels(compactions.maxLevel())
+		if err != nil {
+			return errors.Wrap(err, "get compaction levels")
+		}
+
 		return replicate.RunReplicate(
 			g,
 			logger,
@@ -812,6 +969,13 @@ func registerBucketReplicate(app extkingpin.AppClause, objStoreConfig *extflag.P
 			maxTime,
 			blockIDs,
 			*ignoreMarkedForDeletion,
+			replicate.MigrateConfig{
+				RelabelConfigs:     migrateRelabelConfig,
+				Verify:             tbc.migrateVerify,
+				VerticalCompaction: tbc.migrateVerticalCompaction,
+				CompactDir:         tbc.migrateCompactDir,
+				CompactionLevels:   levels,
+			},
 		)
 	})
 }
@@ -824,8 +988,12 @@ func registerBucketDownsample(app extkingpin.AppClause, objStoreConfig *extflag.
 	tbc.registerBucketDownsampleFlag(cmd)
 
 	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, tracer opentracing.Tracer, _ <-chan struct{}, _ bool) error {
+		ladder, err := downsample.ParseLadder(tbc.ladder)
+		if err != nil {
+			return errors.Wrap(err, "parse downsampling resolutions")
+		}
 		return RunDownsample(g, logger, reg, *httpAddr, *httpTLSConfig, time.Duration(*httpGracePeriod), tbc.dataDir,
-			tbc.waitInterval, tbc.downsampleConcurrency, tbc.blockFilesConcurrency, objStoreConfig, component.Downsample, metadata.HashFunc(tbc.hashFunc))
+			tbc.waitInterval, tbc.downsampleConcurrency, tbc.blockFilesConcurrency, objStoreConfig, component.Downsample, metadata.HashFunc(tbc.hashFunc), ladder)
 	})
 }
 
@@ -1521,3 +1689,657 @@ func registerBucketUploadBlocks(app extkingpin.AppClause, objStoreConfig *extfla
 		return nil
 	})
 }
+
+func registerBucketCompactPlan(app extkingpin.AppClause, objStoreConfig *extflag.PathOrContent) {
+	cmd := app.Command("compact-plan", "Prints the work the compactor would do on the bucket with the given flags: every planned compaction with its input blocks and estimated output size, "+
+		"blocks which would be marked for no compaction, downsampled, or deleted by retention or garbage collection. The bucket is not modified.")
+
+	tbc := &bucketCompactPlanConfig{}
+	tbc.registerBucketCompactPlanFlag(cmd)
+	selectorRelabelConf := &relabelCfg{extkingpin.RegisterSelectorRelabelFlags(cmd)}
+
+	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ <-chan struct{}, _ bool) error {
+		confContentYaml, err := objStoreConfig.Content()
+		if err != nil {
+			return err
+		}
+
+		relabelConfig, err := selectorRelabelConf.RelabelConfig(block.SelectorSupportedRelabelActions)
+		if err != nil {
+			return err
+		}
+
+		ladder, err := downsample.ParseLadder(tbc.downsamplingLadder)
+		if err != nil {
+			return errors.Wrap(err, "parse downsampling resolutions")
+		}
+
+		levels, err := compactions.levels(tbc.maxCompactionLevel)
+		if err != nil {
+			return errors.Wrap(err, "get compaction levels")
+		}
+		if err := ladder.ValidateCompactionRange(compactions.maxRange()); err != nil {
+			return errors.Wrap(err, "validate downsampling resolutions")
+		}
+
+		bkt, err := client.NewBucket(logger, confContentYaml, component.Bucket.String(), nil)
+		if err != nil {
+			return err
+		}
+		insBkt := objstoretracing.WrapWithTraces(objstore.WrapWithMetrics(bkt, extprom.WrapRegistererWithPrefix("thanos_", reg), bkt.Name()))
+		// Planners mark blocks for no compaction, so make sure nothing is ever written to the bucket.
+		dryRunBkt := compact.NewDryRunBucket(insBkt)
+
+		// Dummy actor to immediately kill the group after the run function returns.
+		g.Add(func() error { return nil }, func(error) {})
+
+		defer runutil.CloseWithLogOnErr(logger, insBkt, "bucket client")
+
+		ctx, cancel := context.WithTimeout(context.Background(), tbc.timeout)
+		defer cancel()
+
+		enableVerticalCompaction := tbc.enableVerticalCompaction
+		dedupReplicaLabels := strutil.ParseFlagLabels(tbc.dedupReplicaLabels)
+		if len(dedupReplicaLabels) > 0 {
+			enableVerticalCompaction = true
+		}
+
+		// Use the same filters as the compactor.
+		ignoreDeletionMarkFilter := block.NewIgnoreDeletionMarkFilter(logger, insBkt, tbc.deleteDelay/2, tbc.blockSyncConcurrency)
+		duplicateBlocksFilter := block.NewDeduplicateFilter(tbc.blockSyncConcurrency)
+		noCompactMarkerFilter := compact.NewGatherNoCompactionMarkFilter(logger, insBkt, tbc.blockSyncConcurrency)
+		filters := []block.MetadataFilter{
+			block.NewLabelShardedMetaFilter(relabelConfig, tbc.dedupReplicaLabels...),
+			block.NewConsistencyDelayMetaFilter(logger, tbc.consistencyDelay, extprom.WrapRegistererWithPrefix(extpromPrefix, reg)),
+			ignoreDeletionMarkFilter,
+			block.NewReplicaLabelRemover(logger, dedupReplicaLabels),
+			duplicateBlocksFilter,
+			noCompactMarkerFilter,
+		}
+		noDownsampleMarkerFilter := downsample.NewGatherNoDownsampleMarkFilter(logger, insBkt, tbc.blockSyncConcurrency)
+		if !tbc.disableDownsampling {
+			filters = append(filters, noDownsampleMarkerFilter)
+		}
+		fetcher, err := block.NewMetaFetcher(logger, tbc.blockSyncConcurrency, insBkt, block.NewConcurrentLister(logger, insBkt), "", extprom.WrapRegistererWithPrefix(extpromPrefix, reg), filters)
+		if err != nil {
+			return errors.Wrap(err, "create meta fetcher")
+		}
+
+		level.Info(logger).Log("msg", "syncing blocks metadata")
+		metas, _, err := fetcher.Fetch(ctx)
+		if err != nil {
+			return errors.Wrap(err, "fetch metas")
+		}
+		level.Info(logger).Log("msg", "synced blocks", "blocks", len(metas))
+
+		stubCounter := promauto.With(nil).NewCounter(prometheus.CounterOpts{})
+		grouper := compact.NewDefaultGrouper(logger, dryRunBkt, false, enableVerticalCompaction, nil, stubCounter, stubCounter, stubCounter, metadata.NoneFunc, 1, 1)
+		largeIndexFilterPlanner := compact.WithLargeTotalIndexSizeFilter(compact.NewPlanner(logger, levels, noCompactMarkerFilter), dryRunBkt, int64(tbc.maxBlockIndexSize), stubCounter)
+		if tbc.splitShards > 1 {
+			grouper.SetSplitShards(tbc.splitShards)
+			largeIndexFilterPlanner.SetSplitShards(tbc.splitShards)
+		}
+		var planner compact.Planner = largeIndexFilterPlanner
+		if enableVerticalCompaction {
+			planner = compact.WithVerticalCompactionDownsampleFilter(largeIndexFilterPlanner, dryRunBkt, stubCounter)
+		}
+
+		var jobs []compact.SimulatedJob
+		for _, id := range duplicateBlocksFilter.DuplicateIDs() {
+			jobs = append(jobs, compact.SimulatedJob{
+				Type:    compact.SimulatedGarbageCollection,
+				Inputs:  []ulid.ULID{id},
+				Details: "data contained in other blocks",
+			})
+		}
+		retentionByResolution := map[compact.ResolutionLevel]time.Duration{
+			compact.ResolutionLevelRaw: time.Duration(tbc.retentionRaw),
+			compact.ResolutionLevel5m:  time.Duration(tbc.retentionFiveMin),
+			compact.ResolutionLevel1h:  time.Duration(tbc.retentionOneHr),
+		}
+		for _, r := range tbc.retentionByResolution {
+			res, retention, err := parseResolutionRetention(r)
+			if err != nil {
+				return err
+			}
+			retentionByResolution[res] = retention
+		}
+		planned, err := compact.SimulateCompaction(ctx, dryRunBkt, grouper, planner, metas, compact.SimulationConfig{
+			RetentionByResolution: retentionByResolution,
+			DisableDownsampling:   tbc.disableDownsampling,
+			DownsamplingLadder:    ladder,
+			NoDownsampleMarks:     noDownsampleMarkerFilter.NoDownsampleMarkedBlocks(),
+		})
+		if err != nil {
+			return errors.Wrap(err, "simulate compaction")
+		}
+		jobs = append(jobs, planned...)
+		level.Info(logger).Log("msg", "planned compactor work", "jobs", len(jobs))
+
+		if tbc.output == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "\t")
+			return enc.Encode(jobs)
+		}
+
+		var opPrinter tablePrinter
+		switch outputType(tbc.output) {
+		case TABLE:
+			opPrinter = printTable
+		case TSV:
+			opPrinter = printTSV
+		case CSV:
+			opPrinter = printCSV
+		}
+		return printSimulatedJobs(jobs, opPrinter)
+	})
+}
+
+func printSimulatedJobs(jobs []compact.SimulatedJob, printer tablePrinter) error {
+	header := []string{"TYPE", "LABELS", "RESOLUTION", "FROM", "UNTIL", "INPUTS", "OUTPUTS", "EST-SIZE", "DETAILS"}
+
+	var lines [][]string
+	for _, j := range jobs {
+		line := []string{string(j.Type), "-", "-", "-", "-", joinULIDs(j.Inputs), joinULIDs(j.Outputs), "-", j.Details}
+		if j.Group != "" {
+			line[1] = j.Labels.String()
+			line[2] = time.Duration(j.Resolution * int64(time.Millisecond)).String()
+			line[3] = time.Unix(j.MinTime/1000, 0).UTC().Format(time.RFC3339)
+			line[4] = time.Unix(j.MaxTime/1000, 0).UTC().Format(time.RFC3339)
+		}
+		if j.EstimatedSizeBytes > 0 {
+			line[7] = units.Base2Bytes(j.EstimatedSizeBytes).String()
+		}
+		lines = append(lines, line)
+	}
+	return printer(os.Stdout, Table{Header: header, Lines: lines})
+}
+
+func joinULIDs(ids []ulid.ULID) string {
+	if len(ids) == 0 {
+		return "-"
+	}
+	strs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		strs = append(strs, id.String())
+	}
+	return strings.Join(strs, ",")
+}
+
+func registerBucketRepair(app extkingpin.AppClause, objStoreConfig *extflag.PathOrContent) {
+	cmd := app.Command(component.Repair.String(), "Repair chosen blocks in the bucket. Detects out of order labels, duplicated series, "+
+		"chunks outside of the block time range, duplicated chunks, missing meta.json files and files not matching the sizes and hashes recorded in meta.json. "+
+		"Blocks with issues are rewritten into new blocks with fixed data and the original blocks are marked for deletion. "+
+		"The repaired issues are recorded in thanos.repairs section of meta.json of the new block and in the deletion mark of the original block. "+
+		"Missing meta.json files are reconstructed from the index, assuming a raw, not compacted block with external labels given by --label. "+
+		"Downsampled blocks are not supported. "+
+		"NOTE: It's recommended to turn off compactor while doing this operation.")
+
+	tbc := &bucketRepairConfig{}
+	tbc.registerBucketRepairFlag(cmd)
+
+	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ <-chan struct{}, _ bool) error {
+		confContentYaml, err := objStoreConfig.Content()
+		if err != nil {
+			return err
+		}
+
+		bkt, err := client.NewBucket(logger, confContentYaml, component.Repair.String(), nil)
+		if err != nil {
+			return err
+		}
+		insBkt := objstoretracing.WrapWithTraces(objstore.WrapWithMetrics(bkt, extprom.WrapRegistererWithPrefix("thanos_", reg), bkt.Name()))
+
+		lset, err := parseFlagLabels(tbc.labels)
+		if err != nil {
+			return errors.Wrap(err, "parse labels")
+		}
+
+		var ids []ulid.ULID
+		for _, id := range tbc.blockIDs {
+			u, err := ulid.Parse(id)
+			if err != nil {
+				return errors.Errorf("id is not a valid block ULID, got: %v", id)
+			}
+			ids = append(ids, u)
+		}
+
+		if err := os.RemoveAll(tbc.tmpDir); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(tbc.tmpDir, os.ModePerm); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(func() error {
+			defer runutil.CloseWithLogOnErr(logger, insBkt, "bucket client")
+
+			for _, id := range ids {
+				if _, err := repairBucketBlock(ctx, logger, insBkt, tbc.tmpDir, id, lset.Map(), metadata.HashFunc(tbc.hashFunc), tbc.dryRun); err != nil {
+					return errors.Wrapf(err, "repair %v", id)
+				}
+			}
+			level.Info(logger).Log("msg", "repair done", "IDs", strings.Join(tbc.blockIDs, ","))
+			return nil
+		}, func(err error) {
+			cancel()
+		})
+		return nil
+	})
+}
+
+// repairBucketBlock downloads the block with the given ID into dir and checks it for issues. Unless dryRun is set,
+// a block with issues is rewritten into a new block with fixed data, which is uploaded, and the original block is
+// marked for deletion. It returns the ID of the new block, or an empty ULID if no new block was uploaded.
+func repairBucketBlock(
+	ctx context.Context,
+	logger log.Logger,
+	bkt objstore.Bucket,
+	dir string,
+	id ulid.ULID,
+	extLset map[string]string,
+	hf metadata.HashFunc,
+	dryRun bool,
+) (ulid.ULID, error) {
+	bdir := filepath.Join(dir, id.String())
+
+	level.Info(logger).Log("msg", "downloading block", "source", id)
+	if err := objstore.DownloadDir(ctx, logger, bkt, id.String(), id.String(), bdir); err != nil {
+		return ulid.ULID{}, errors.Wrap(err, "download")
+	}
+	if _, err := os.Stat(filepath.Join(bdir, block.IndexFilename)); err != nil {
+		return ulid.ULID{}, errors.Wrap(err, "stat index file")
+	}
+	if err := os.MkdirAll(filepath.Join(bdir, block.ChunksDirname), os.ModePerm); err != nil {
+		return ulid.ULID{}, err
+	}
+	// Markers are not part of the block and should not be copied to the repaired one.
+	for _, f := range []string{metadata.DeletionMarkFilename, metadata.NoCompactMarkFilename, metadata.NoDownsampleMarkFilename} {
+		if err := os.RemoveAll(filepath.Join(bdir, f)); err != nil {
+			return ulid.ULID{}, err
+		}
+	}
+
+	var issues []string
+	meta, err := metadata.ReadFromDir(bdir)
+	if err != nil {
+		issues = append(issues, fmt.Sprintf("missing or invalid meta.json file: %v", err))
+		if len(extLset) == 0 {
+			return ulid.ULID{}, errors.Wrap(err, "read meta file; external labels are required to reconstruct it")
+		}
+		if meta, err = block.ReconstructMeta(ctx, bdir, extLset, metadata.BucketRepairSource); err != nil {
+			return ulid.ULID{}, errors.Wrap(err, "reconstruct meta file")
+		}
+		if err := meta.WriteToDir(logger, bdir); err != nil {
+			return ulid.ULID{}, err
+		}
+	} else if len(meta.Thanos.Files) > 0 {
+		if err := block.CheckFileStats(bdir, meta.Thanos.Files, logger); err != nil {
+			issues = append(issues, fmt.Sprintf("files do not match meta.json: %v", err))
+		}
+	}
+
+	stats, err := block.GatherIndexHealthStats(ctx, logger, filepath.Join(bdir, block.IndexFilename), meta.MinTime, meta.MaxTime)
+	if err != nil {
+		return ulid.ULID{}, errors.Wrap(err, "gather index issues")
+	}
+	issues = append(issues, stats.Issues()...)
+
+	if len(issues) == 0 {
+		level.Info(logger).Log("msg", "no issues found", "source", id)
+		return ulid.ULID{}, nil
+	}
+	level.Warn(logger).Log("msg", "detected issues", "source", id, "issues", strings.Join(issues, "; "))
+	if dryRun {
+		level.Info(logger).Log("msg", "dry run finished, block is not repaired", "source", id)
+		return ulid.ULID{}, nil
+	}
+
+	resid, err := block.RepairWithOptions(
+		ctx,
+		logger,
+		dir,
+		id,
+		metadata.BucketRepairSource,
+		block.RepairOptions{TrimOutsideChunks: true},
+		block.IgnoreCompleteOutsideChunk,
+		block.IgnoreDuplicateOutsideChunk,
+		block.IgnoreIssue347OutsideChunk,
+	)
+	if err != nil {
+		return ulid.ULID{}, errors.Wrap(err, "rewrite block")
+	}
+	resdir := filepath.Join(dir, resid.String())
+
+	resmeta, err := metadata.ReadFromDir(resdir)
+	if err != nil {
+		return ulid.ULID{}, errors.Wrap(err, "read repaired meta file")
+	}
+	resmeta.Thanos.Repairs = append(resmeta.Thanos.Repairs, metadata.Repair{
+		Source: id,
+		Time:   time.Now().UTC(),
+		Issues: issues,
+	})
+	if err := resmeta.WriteToDir(logger, resdir); err != nil {
+		return ulid.ULID{}, err
+	}
+
+	if err := block.VerifyIndex(ctx, logger, filepath.Join(resdir, block.IndexFilename), resmeta.MinTime, resmeta.MaxTime); err != nil {
+		return ulid.ULID{}, errors.Wrapf(err, "repaired block %v is invalid", resid)
+	}
+
+	level.Info(logger).Log("msg", "uploading repaired block", "source", id, "new", resid)
+	if err := block.Upload(ctx, logger, bkt, resdir, hf); err != nil {
+		return ulid.ULID{}, errors.Wrap(err, "upload")
+	}
+
+	details := fmt.Sprintf("repaired into %v: %s", resid, strings.Join(issues, "; "))
+	if err := block.MarkForDeletion(ctx, logger, bkt, id, details, promauto.With(nil).NewCounter(prometheus.CounterOpts{})); err != nil {
+		return ulid.ULID{}, errors.Wrap(err, "mark repaired block for deletion")
+	}
+	level.Info(logger).Log("msg", "repaired block", "source", id, "new", resid)
+	return resid, nil
+}
+
+func registerBucketImport(app extkingpin.AppClause, objStoreConfig *extflag.PathOrContent) {
+	cmd := app.Command("import", "Import series from external sources into new blocks in the bucket. "+
+		"Blocks are created with the given external labels, aligned to the block duration, and uploaded as raw, not compacted blocks.")
+
+	registerBucketImportOpenMetrics(cmd, objStoreConfig)
+	registerBucketImportRemoteRead(cmd, objStoreConfig)
+}
+
+func registerBucketImportOpenMetrics(app extkingpin.AppClause, objStoreConfig *extflag.PathOrContent) {
+	cmd := app.Command("openmetrics", "Import samples from files in the OpenMetrics text format. Every sample has to have a timestamp.")
+
+	tbc := &bucketImportConfig{}
+	tbc.registerBucketImportFlag(cmd)
+	files := cmd.Flag("file", "OpenMetrics file to import (repeated).").Required().PlaceHolder("<path>").ExistingFiles()
+
+	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ <-chan struct{}, _ bool) error {
+		ctx, cancel := context.WithCancel(context.Background())
+		return runBucketImport(g, logger, reg, objStoreConfig, tbc, func(bkt objstore.Bucket, conf backfill.Config) error {
+			for _, f := range *files {
+				input, err := os.ReadFile(f)
+				if err != nil {
+					return errors.Wrapf(err, "read %s", f)
+				}
+				src, err := backfill.NewOpenMetricsSource(input)
+				if err != nil {
+					return errors.Wrapf(err, "open %s", f)
+				}
+				mint, maxt := src.Bounds()
+				if _, err := importBlocks(ctx, logger, bkt, src, mint, maxt, conf, metadata.HashFunc(tbc.hashFunc)); err != nil {
+					return errors.Wrapf(err, "import %s", f)
+				}
+			}
+			return nil
+		}, cancel)
+	})
+}
+
+func registerBucketImportRemoteRead(app extkingpin.AppClause, objStoreConfig *extflag.PathOrContent) {
+	cmd := app.Command("remote-read", "Import samples read from a Prometheus remote read endpoint.")
+
+	tbc := &bucketImportConfig{}
+	tbc.registerBucketImportFlag(cmd)
+	readURL := cmd.Flag("url", "URL of the remote read endpoint, e.g. http://localhost:9090/api/v1/read.").Required().URL()
+	selectors := cmd.Flag("selector", "Series selector of series to import (repeated). Series matching multiple selectors are imported once.").
+		Required().PlaceHolder("<selector>").Strings()
+	timeout := extkingpin.ModelDuration(cmd.Flag("timeout", "Timeout of a single remote read request. Each block is read with a separate request per selector.").Default("5m"))
+	minTime := model.TimeOrDuration(cmd.Flag("min-time", "Start of time range to import. Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or 2h45m. Valid duration units are ms, s, m, h, d, w, y.").
+		Required())
+	maxTime := model.TimeOrDuration(cmd.Flag("max-time", "End of time range to import. Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or 2h45m. Valid duration units are ms, s, m, h, d, w, y.").
+		Default("0s"))
+
+	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ <-chan struct{}, _ bool) error {
+		var matcherSets [][]*labels.Matcher
+		for _, s := range *selectors {
+			matchers, err := extpromql.ParseMetricSelector(s)
+			if err != nil {
+				return errors.Wrapf(err, "parse selector %q", s)
+			}
+			matcherSets = append(matcherSets, matchers)
+		}
+		mint, maxt := minTime.PrometheusTimestamp(), maxTime.PrometheusTimestamp()
+		if mint >= maxt {
+			return errors.Errorf("min-time %v has to be before max-time %v", minTime, maxTime)
+		}
+
+		client, err := remote.NewReadClient(component.Bucket.String(), &remote.ClientConfig{
+			URL:              &config_util.URL{URL: *readURL},
+			Timeout:          *timeout,
+			HTTPClientConfig: config_util.DefaultHTTPClientConfig,
+		})
+		if err != nil {
+			return errors.Wrap(err, "create remote read client")
+		}
+		src := backfill.NewRemoteReadSource(client, matcherSets)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		return runBucketImport(g, logger, reg, objStoreConfig, tbc, func(bkt objstore.Bucket, conf backfill.Config) error {
+			_, err := importBlocks(ctx, logger, bkt, src, mint, maxt, conf, metadata.HashFunc(tbc.hashFunc))
+			return err
+		}, cancel)
+	})
+}
+
+// runBucketImport sets up the bucket and the working directory shared by import commands and
+// adds an actor running the import to the group.
+func runBucketImport(
+	g *run.Group,
+	logger log.Logger,
+	reg *prometheus.Registry,
+	objStoreConfig *extflag.PathOrContent,
+	tbc *bucketImportConfig,
+	importFn func(bkt objstore.Bucket, conf backfill.Config) error,
+	cancel context.CancelFunc,
+) error {
+	lset, err := parseFlagLabels(tbc.labels)
+	if err != nil {
+		return errors.Wrap(err, "parse labels")
+	}
+
+	confContentYaml, err := objStoreConfig.Content()
+	if err != nil {
+		return err
+	}
+	bkt, err := client.NewBucket(logger, confContentYaml, component.Bucket.String(), nil)
+	if err != nil {
+		return err
+	}
+	insBkt := objstoretracing.WrapWithTraces(objstore.WrapWithMetrics(bkt, extprom.WrapRegistererWithPrefix("thanos_", reg), bkt.Name()))
+
+	if err := os.RemoveAll(tbc.tmpDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(tbc.tmpDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	conf := backfill.Config{
+		Dir:           tbc.tmpDir,
+		BlockDuration: time.Duration(tbc.blockDuration).Milliseconds(),
+		Labels:        lset,
+		Source:        metadata.BucketImportSource,
+	}
+	g.Add(func() error {
+		defer runutil.CloseWithLogOnErr(logger, insBkt, "bucket client")
+
+		if err := importFn(insBkt, conf); err != nil {
+			return err
+		}
+		level.Info(logger).Log("msg", "import done")
+		return nil
+	}, func(error) {
+		cancel()
+	})
+	return nil
+}
+
+// importBlocks creates blocks from the samples of the source in [mint, maxt) and uploads them to the bucket.
+// Local copies of blocks are removed once uploaded. It returns the IDs of the uploaded blocks.
+func importBlocks(
+	ctx context.Context,
+	logger log.Logger,
+	bkt objstore.Bucket,
+	src backfill.Source,
+	mint, maxt int64,
+	conf backfill.Config,
+	hf metadata.HashFunc,
+) ([]ulid.ULID, error) {
+	ids, err := backfill.CreateBlocks(ctx, logger, src, mint, maxt, conf)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		bdir := filepath.Join(conf.Dir, id.String())
+		if err := block.Upload(ctx, logger, bkt, bdir, hf); err != nil {
+			return nil, errors.Wrapf(err, "upload %v", id)
+		}
+		if err := os.RemoveAll(bdir); err != nil {
+			return nil, errors.Wrapf(err, "remove %v", id)
+		}
+		level.Info(logger).Log("msg", "uploaded imported block", "id", id)
+	}
+	return ids, nil
+}
+
+func registerBucketExport(app extkingpin.AppClause, objStoreConfig *extflag.PathOrContent) {
+	cmd := app.Command("export", "Export samples of series matching the selector from raw blocks in the bucket into an OpenMetrics, CSV or Parquet file. "+
+		"Blocks are downloaded one at a time and series are written in block order, with external labels of the block added.")
+
+	tbc := &bucketExportConfig{}
+	tbc.registerBucketExportFlag(cmd)
+
+	minTime := model.TimeOrDuration(cmd.Flag("min-time", "Start of time range to export. Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or 2h45m. Valid duration units are ms, s, m, h, d, w, y.").
+		Default("0000-01-01T00:00:00Z"))
+	maxTime := model.TimeOrDuration(cmd.Flag("max-time", "End of time range to export. Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or 2h45m. Valid duration units are ms, s, m, h, d, w, y.").
+		Default("9999-12-31T23:59:59Z"))
+
+	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ <-chan struct{}, _ bool) error {
+		matchers, err := extpromql.ParseMetricSelector(tbc.selector)
+		if err != nil {
+			return errors.Wrap(err, "parse selector")
+		}
+
+		confContentYaml, err := objStoreConfig.Content()
+		if err != nil {
+			return err
+		}
+		bkt, err := client.NewBucket(logger, confContentYaml, component.Bucket.String(), nil)
+		if err != nil {
+			return err
+		}
+		insBkt := objstoretracing.WrapWithTraces(objstore.WrapWithMetrics(bkt, extprom.WrapRegistererWithPrefix("thanos_", reg), bkt.Name()))
+
+		baseBlockIDsFetcher := block.NewConcurrentLister(logger, insBkt)
+		fetcher, err := block.NewMetaFetcher(logger, block.FetcherConcurrency, insBkt, baseBlockIDsFetcher, "", extprom.WrapRegistererWithPrefix(extpromPrefix, reg),
+			[]block.MetadataFilter{
+				block.NewTimePartitionMetaFilter(*minTime, *maxTime),
+				block.NewIgnoreDeletionMarkFilter(logger, insBkt, 0, block.FetcherConcurrency),
+				block.NewDeduplicateFilter(block.FetcherConcurrency),
+			})
+		if err != nil {
+			return err
+		}
+
+		if err := os.RemoveAll(tbc.tmpDir); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(tbc.tmpDir, os.ModePerm); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(func() (rerr error) {
+			defer runutil.CloseWithLogOnErr(logger, insBkt, "bucket client")
+
+			metas, _, err := fetcher.Fetch(ctx)
+			if err != nil {
+				return err
+			}
+
+			out := io.Writer(os.Stdout)
+			if tbc.output != "-" {
+				f, err := os.Create(tbc.output)
+				if err != nil {
+					return errors.Wrap(err, "create output file")
+				}
+				defer runutil.CloseWithErrCapture(&rerr, f, "close output file")
+				out = f
+			}
+			w, err := export.NewWriter(export.Format(tbc.format), out)
+			if err != nil {
+				return err
+			}
+
+			blocks := make([]*metadata.Meta, 0, len(metas))
+			for _, m := range metas {
+				blocks = append(blocks, m)
+			}
+			stats, err := exportBlocks(ctx, logger, insBkt, blocks, tbc.tmpDir, minTime.PrometheusTimestamp(), maxTime.PrometheusTimestamp(), matchers, w)
+			if err != nil {
+				return err
+			}
+			if err := w.Close(); err != nil {
+				return errors.Wrap(err, "flush output")
+			}
+			level.Info(logger).Log("msg", "export done", "series", stats.Series, "samples", stats.Samples, "skippedHistogramSamples", stats.SkippedHistogramSamples)
+			return nil
+		}, func(error) {
+			cancel()
+		})
+		return nil
+	})
+}
+
+// exportBlocks writes samples in [mint, maxt] of series matching the matchers from the given blocks, merging series
+// across blocks. Downsampled blocks and blocks whose external labels don't match are skipped. All blocks are
+// downloaded into dir and removed once exported.
+func exportBlocks(
+	ctx context.Context,
+	logger log.Logger,
+	bkt objstore.Bucket,
+	metas []*metadata.Meta,
+	dir string,
+	mint, maxt int64,
+	matchers []*labels.Matcher,
+	w export.Writer,
+) (_ export.Stats, err error) {
+	sort.Slice(metas, func(i, j int) bool {
+		if metas[i].MinTime != metas[j].MinTime {
+			return metas[i].MinTime < metas[j].MinTime
+		}
+		return metas[i].ULID.Compare(metas[j].ULID) < 0
+	})
+
+	var bdirs []string
+	defer func() {
+		for _, bdir := range bdirs {
+			if rerr := os.RemoveAll(bdir); rerr != nil && err == nil {
+				err = errors.Wrapf(rerr, "remove %s", bdir)
+			}
+		}
+	}()
+	for _, m := range metas {
+		if m.Thanos.Downsample.Resolution != downsample.ResLevel0 {
+			continue
+		}
+		if m.MaxTime <= mint || m.MinTime > maxt {
+			continue
+		}
+		if _, ok := export.MatchExternalLabels(matchers, labels.FromMap(m.Thanos.Labels)); !ok {
+			continue
+		}
+
+		bdir := filepath.Join(dir, m.ULID.String())
+		bdirs = append(bdirs, bdir)
+		if err := block.Download(ctx, logger, bkt, m.ULID, bdir); err != nil {
+			return export.Stats{}, errors.Wrapf(err, "download %v", m.ULID)
+		}
+		level.Info(logger).Log("msg", "downloaded block", "id", m.ULID)
+	}
+	return export.Blocks(ctx, logger, bdirs, mint, maxt, matchers, w)
+}
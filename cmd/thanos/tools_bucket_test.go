@@ -7,21 +7,28 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/go-kit/log"
 	"github.com/oklog/run"
+	"github.com/oklog/ulid/v2"
 	"github.com/opentracing/opentracing-go"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/prometheus/model/labels"
 
 	"github.com/efficientgo/core/testutil"
+	"github.com/thanos-io/objstore"
 
+	"github.com/thanos-io/thanos/pkg/backfill"
+	"github.com/thanos-io/thanos/pkg/block"
 	"github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/compact/downsample"
+	"github.com/thanos-io/thanos/pkg/export"
 	"github.com/thanos-io/thanos/pkg/extkingpin"
 	"github.com/thanos-io/thanos/pkg/testutil/e2eutil"
 )
@@ -79,3 +86,170 @@ config:
 	testutil.Ok(t, err)
 	testutil.Equals(t, "example_tenant", meta.Thanos.Labels["tenant_id"])
 }
+
+func TestRepairBucketBlock(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+	bkt := objstore.NewInMemBucket()
+	extLset := labels.FromStrings("ext", "1")
+
+	blockDir := t.TempDir()
+	series := []labels.Labels{labels.FromStrings("a", "1"), labels.FromStrings("a", "2")}
+	createBlock := func(maxt int64) ulid.ULID {
+		id, err := e2eutil.CreateBlock(ctx, blockDir, series, 100, 0, 1000, extLset, downsample.ResLevel0, metadata.NoneFunc, nil)
+		testutil.Ok(t, err)
+
+		// Shrink the block time range so that chunks end up partially outside of it.
+		meta, err := metadata.ReadFromDir(filepath.Join(blockDir, id.String()))
+		testutil.Ok(t, err)
+		meta.MaxTime = maxt
+		testutil.Ok(t, meta.WriteToDir(logger, filepath.Join(blockDir, id.String())))
+		testutil.Ok(t, block.Upload(ctx, logger, bkt, filepath.Join(blockDir, id.String()), metadata.NoneFunc))
+		return id
+	}
+
+	t.Run("healthy block", func(t *testing.T) {
+		id := createBlock(1000)
+
+		resid, err := repairBucketBlock(ctx, logger, bkt, t.TempDir(), id, nil, metadata.NoneFunc, false)
+		testutil.Ok(t, err)
+		testutil.Equals(t, ulid.ULID{}, resid)
+	})
+
+	t.Run("chunks outside of block range", func(t *testing.T) {
+		id := createBlock(500)
+
+		resid, err := repairBucketBlock(ctx, logger, bkt, t.TempDir(), id, nil, metadata.NoneFunc, true)
+		testutil.Ok(t, err)
+		testutil.Equals(t, ulid.ULID{}, resid)
+		exists, err := bkt.Exists(ctx, path.Join(id.String(), metadata.DeletionMarkFilename))
+		testutil.Ok(t, err)
+		testutil.Assert(t, !exists, "dry run should not mark the block for deletion")
+
+		dir := t.TempDir()
+		resid, err = repairBucketBlock(ctx, logger, bkt, dir, id, nil, metadata.NoneFunc, false)
+		testutil.Ok(t, err)
+		testutil.Assert(t, resid != ulid.ULID{}, "expected a repaired block")
+
+		meta, err := block.DownloadMeta(ctx, logger, bkt, resid)
+		testutil.Ok(t, err)
+		testutil.Equals(t, metadata.BucketRepairSource, meta.Thanos.Source)
+		testutil.Equals(t, 1, len(meta.Thanos.Repairs))
+		testutil.Equals(t, id, meta.Thanos.Repairs[0].Source)
+		testutil.Equals(t, 1, len(meta.Thanos.Repairs[0].Issues))
+		testutil.Equals(t, int64(500), meta.MaxTime)
+		testutil.Assert(t, meta.Stats.NumSamples > 0 && meta.Stats.NumSamples < 200, "expected samples outside of the block range to be dropped, got %d", meta.Stats.NumSamples)
+
+		testutil.Ok(t, block.Download(ctx, logger, bkt, resid, filepath.Join(dir, "check", resid.String())))
+		testutil.Ok(t, block.VerifyIndex(ctx, logger, filepath.Join(dir, "check", resid.String(), block.IndexFilename), meta.MinTime, meta.MaxTime))
+
+		var mark metadata.DeletionMark
+		testutil.Ok(t, metadata.ReadMarker(ctx, logger, objstore.WithNoopInstr(bkt), id.String(), &mark))
+		testutil.Assert(t, strings.HasPrefix(mark.Details, "repaired into "+resid.String()), "unexpected deletion mark details %q", mark.Details)
+	})
+
+	t.Run("missing meta.json", func(t *testing.T) {
+		id := createBlock(1000)
+		orig, err := block.DownloadMeta(ctx, logger, bkt, id)
+		testutil.Ok(t, err)
+		testutil.Ok(t, bkt.Delete(ctx, path.Join(id.String(), block.MetaFilename)))
+
+		_, err = repairBucketBlock(ctx, logger, bkt, t.TempDir(), id, nil, metadata.NoneFunc, false)
+		testutil.NotOk(t, err)
+
+		resid, err := repairBucketBlock(ctx, logger, bkt, t.TempDir(), id, extLset.Map(), metadata.NoneFunc, false)
+		testutil.Ok(t, err)
+		testutil.Assert(t, resid != ulid.ULID{}, "expected a repaired block")
+
+		meta, err := block.DownloadMeta(ctx, logger, bkt, resid)
+		testutil.Ok(t, err)
+		testutil.Equals(t, extLset.Map(), meta.Thanos.Labels)
+		testutil.Equals(t, orig.MinTime, meta.MinTime)
+		testutil.Equals(t, orig.Stats.NumSeries, meta.Stats.NumSeries)
+		testutil.Equals(t, orig.Stats.NumSamples, meta.Stats.NumSamples)
+		testutil.Equals(t, id, meta.Thanos.Repairs[0].Source)
+	})
+}
+
+func TestImportBlocks(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+	bkt := objstore.NewInMemBucket()
+	dir := t.TempDir()
+
+	src, err := backfill.NewOpenMetricsSource([]byte(`# TYPE up gauge
+up{job="a"} 1 0
+up{job="a"} 1 7200
+# EOF
+`))
+	testutil.Ok(t, err)
+	mint, maxt := src.Bounds()
+
+	ids, err := importBlocks(ctx, logger, bkt, src, mint, maxt, backfill.Config{
+		Dir:           dir,
+		BlockDuration: (2 * time.Hour).Milliseconds(),
+		Labels:        labels.FromStrings("cluster", "eu"),
+		Source:        metadata.BucketImportSource,
+	}, metadata.SHA256Func)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(ids))
+
+	for _, id := range ids {
+		meta, err := block.DownloadMeta(ctx, logger, bkt, id)
+		testutil.Ok(t, err)
+		testutil.Equals(t, map[string]string{"cluster": "eu"}, meta.Thanos.Labels)
+		testutil.Equals(t, metadata.BucketImportSource, meta.Thanos.Source)
+		testutil.Equals(t, uint64(1), meta.Stats.NumSamples)
+		testutil.Assert(t, len(meta.Thanos.Files) > 0, "expected file stats in uploaded meta.json")
+
+		_, err = os.Stat(filepath.Join(dir, id.String()))
+		testutil.Assert(t, os.IsNotExist(err), "expected local block %v to be removed after upload", id)
+	}
+}
+
+func TestExportBlocks(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+	bkt := objstore.NewInMemBucket()
+
+	blockDir := t.TempDir()
+	series := []labels.Labels{labels.FromStrings("__name__", "up", "job", "a")}
+	var metas []*metadata.Meta
+	for _, b := range []struct {
+		mint, maxt int64
+		extLset    labels.Labels
+		resolution int64
+	}{
+		{mint: 1000, maxt: 2000, extLset: labels.FromStrings("cluster", "eu")},
+		{mint: 0, maxt: 1000, extLset: labels.FromStrings("cluster", "eu")},
+		{mint: 0, maxt: 1000, extLset: labels.FromStrings("cluster", "us")},
+		{mint: 0, maxt: 1000, extLset: labels.FromStrings("cluster", "eu"), resolution: downsample.ResLevel1},
+	} {
+		id, err := e2eutil.CreateBlock(ctx, blockDir, series, 9, b.mint, b.maxt, b.extLset, b.resolution, metadata.NoneFunc, nil)
+		testutil.Ok(t, err)
+		testutil.Ok(t, block.Upload(ctx, logger, bkt, filepath.Join(blockDir, id.String()), metadata.NoneFunc))
+		meta, err := metadata.ReadFromDir(filepath.Join(blockDir, id.String()))
+		testutil.Ok(t, err)
+		metas = append(metas, meta)
+	}
+
+	var buf strings.Builder
+	w, err := export.NewWriter(export.OpenMetrics, &buf)
+	testutil.Ok(t, err)
+	stats, err := exportBlocks(ctx, logger, bkt, metas, t.TempDir(), 0, 1500, []*labels.Matcher{
+		labels.MustNewMatcher(labels.MatchEqual, "__name__", "up"),
+		labels.MustNewMatcher(labels.MatchEqual, "cluster", "eu"),
+	}, w)
+	testutil.Ok(t, err)
+	testutil.Ok(t, w.Close())
+
+	// Only raw blocks of the eu cluster are exported, in time order.
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	testutil.Equals(t, stats.Samples+1, len(lines))
+	testutil.Equals(t, 2, stats.Series)
+	testutil.Equals(t, 15, stats.Samples)
+	testutil.Equals(t, `up{cluster="eu",job="a"} `, lines[0][:len(`up{cluster="eu",job="a"} `)])
+	testutil.Equals(t, "# EOF", lines[len(lines)-1])
+	testutil.Assert(t, strings.HasSuffix(lines[0], " 0"), "expected first sample at 0, got %q", lines[0])
+	testutil.Assert(t, strings.HasSuffix(lines[len(lines)-2], " 1.5"), "expected last sample at 1500, got %q", lines[len(lines)-2])
+}
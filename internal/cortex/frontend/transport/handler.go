@@ -58,10 +58,13 @@ type Handler struct {
 	roundTripper http.RoundTripper
 
 	// Metrics.
-	querySeconds *prometheus.CounterVec
-	querySeries  *prometheus.CounterVec
-	queryBytes   *prometheus.CounterVec
-	activeUsers  *util.ActiveUsersCleanupService
+	querySeconds         *prometheus.CounterVec
+	querySeries          *prometheus.CounterVec
+	queryBytes           *prometheus.CounterVec
+	queryBlocks          *prometheus.CounterVec
+	queryTouched         *prometheus.CounterVec
+	queryDownloadedBytes *prometheus.CounterVec
+	activeUsers          *util.ActiveUsersCleanupService
 }
 
 // NewHandler creates a new frontend handler.
@@ -88,10 +91,28 @@ func NewHandler(cfg HandlerConfig, roundTripper http.RoundTripper, log log.Logge
 			Help: "Size of all chunks fetched to execute a query in bytes.",
 		}, []string{"user"})
 
+		h.queryBlocks = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_query_blocks_queried_total",
+			Help: "Number of blocks queried by store gateways to execute a query.",
+		}, []string{"user"})
+
+		h.queryTouched = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_query_touched_total",
+			Help: "Number of postings, series and chunks touched by store gateways to execute a query.",
+		}, []string{"user", "type"})
+
+		h.queryDownloadedBytes = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_query_downloaded_bytes_total",
+			Help: "Size of all data downloaded from object storage by store gateways to execute a query in bytes.",
+		}, []string{"user"})
+
 		h.activeUsers = util.NewActiveUsersCleanupWithDefaultValues(func(user string) {
 			h.querySeconds.DeleteLabelValues(user)
 			h.querySeries.DeleteLabelValues(user)
 			h.queryBytes.DeleteLabelValues(user)
+			h.queryBlocks.DeleteLabelValues(user)
+			h.queryTouched.DeletePartialMatch(prometheus.Labels{"user": user})
+			h.queryDownloadedBytes.DeleteLabelValues(user)
 		})
 		// If cleaner stops or fail, we will simply not clean the metrics for inactive users.
 		_ = h.activeUsers.StartAsync(context.Background())
@@ -136,6 +157,10 @@ func (f *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		writeError(w, err)
 		return
 	}
+	// Bodies of streamed responses are written until they are closed.
+	defer func() {
+		_ = resp.Body.Close()
+	}()
 
 	hs := w.Header()
 	maps.Copy(hs, resp.Header)
@@ -243,6 +268,11 @@ func (f *Handler) reportQueryStats(r *http.Request, queryString url.Values, quer
 	f.querySeconds.WithLabelValues(userID).Add(wallTime.Seconds())
 	f.querySeries.WithLabelValues(userID).Add(float64(numSeries))
 	f.queryBytes.WithLabelValues(userID).Add(float64(numBytes))
+	f.queryBlocks.WithLabelValues(userID).Add(float64(stats.LoadBlocksQueried()))
+	f.queryTouched.WithLabelValues(userID, "postings").Add(float64(stats.LoadTouchedPostings()))
+	f.queryTouched.WithLabelValues(userID, "series").Add(float64(stats.LoadTouchedSeries()))
+	f.queryTouched.WithLabelValues(userID, "chunks").Add(float64(stats.LoadTouchedChunks()))
+	f.queryDownloadedBytes.WithLabelValues(userID).Add(float64(stats.LoadDownloadedBytes()))
 	f.activeUsers.UpdateUserTimestamp(userID, time.Now())
 
 	// Log stats.
@@ -289,6 +319,12 @@ func (f *Handler) addStatsToLogMessage(message []any, stats *querier_stats.Stats
 	if stats != nil {
 		message = append(message, "peak_samples", stats.LoadPeakSamples())
 		message = append(message, "total_samples_loaded", stats.LoadTotalSamples())
+		message = append(message, "blocks_queried", stats.LoadBlocksQueried())
+		message = append(message, "touched_postings_count", stats.LoadTouchedPostings())
+		message = append(message, "touched_series_count", stats.LoadTouchedSeries())
+		message = append(message, "touched_chunks_count", stats.LoadTouchedChunks())
+		message = append(message, "downloaded_bytes", stats.LoadDownloadedBytes())
+		message = append(message, "max_store_duration", stats.LoadMaxStoreDuration())
 	}
 
 	return message
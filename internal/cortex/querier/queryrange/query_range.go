@@ -33,6 +33,7 @@ import (
 	"github.com/thanos-io/thanos/internal/cortex/cortexpb"
 	"github.com/thanos-io/thanos/internal/cortex/util"
 	"github.com/thanos-io/thanos/internal/cortex/util/spanlogger"
+	"github.com/thanos-io/thanos/pkg/store/hintspb"
 )
 
 // StatusSuccess Prometheus success result.
@@ -721,6 +722,7 @@ func StatsMerge(resps []Response) *PrometheusResponseStats {
 	hasStats := false
 	peakSamples := int32(0)
 	totalSamples := int64(0)
+	var storeStats *hintspb.QueryStats
 	for _, resp := range resps {
 		stats := resp.GetStats()
 		if stats == nil {
@@ -728,6 +730,12 @@ func StatsMerge(resps []Response) *PrometheusResponseStats {
 		}
 
 		hasStats = true
+		if stats.Store != nil {
+			if storeStats == nil {
+				storeStats = &hintspb.QueryStats{}
+			}
+			storeStats.Merge(stats.Store)
+		}
 		if stats.Samples == nil {
 			continue
 		}
@@ -753,10 +761,13 @@ func StatsMerge(resps []Response) *PrometheusResponseStats {
 
 	slices.Sort(keys)
 
-	result := &PrometheusResponseStats{Samples: &PrometheusResponseSamplesStats{
-		PeakSamples:           peakSamples,
-		TotalQueryableSamples: totalSamples,
-	}}
+	result := &PrometheusResponseStats{
+		Samples: &PrometheusResponseSamplesStats{
+			PeakSamples:           peakSamples,
+			TotalQueryableSamples: totalSamples,
+		},
+		Store: storeStats,
+	}
 	for _, key := range keys {
 		result.Samples.TotalQueryableSamplesPerStep = append(result.Samples.TotalQueryableSamplesPerStep, output[key])
 	}
@@ -20,6 +20,7 @@ import (
 	"github.com/weaveworks/common/user"
 
 	"github.com/thanos-io/thanos/internal/cortex/cortexpb"
+	"github.com/thanos-io/thanos/pkg/store/hintspb"
 )
 
 func TestRequest(t *testing.T) {
@@ -667,6 +668,33 @@ func TestMergeAPIResponses(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "[stats] Merging of store stats.",
+			input: []Response{
+				mustParse(t, `{"status":"success","data":{"resultType":"matrix","result":[],"stats":{"samples":{"totalQueryableSamples":10},"store":{"blocks_queried":2,"data_downloaded_size_sum":100,"store_stats":[{"store":"store-1","duration":1000000000}]}}}}`),
+				mustParse(t, `{"status":"success","data":{"resultType":"matrix","result":[],"stats":{"samples":{"totalQueryableSamples":20},"store":{"blocks_queried":1,"data_downloaded_size_sum":50,"store_stats":[{"store":"store-2","duration":2000000000}]}}}}`),
+				mustParse(t, `{"status":"success","data":{"resultType":"matrix","result":[],"stats":{"samples":{"totalQueryableSamples":5}}}}`),
+			},
+			expected: &PrometheusResponse{
+				Status: StatusSuccess,
+				Data: PrometheusData{
+					ResultType: matrix,
+					Analysis:   &Analysis{},
+					Result:     []SampleStream{},
+					Stats: &PrometheusResponseStats{
+						Samples: &PrometheusResponseSamplesStats{TotalQueryableSamples: 35},
+						Store: &hintspb.QueryStats{
+							BlocksQueried:         3,
+							DataDownloadedSizeSum: 150,
+							StoreStats: []hintspb.StoreStats{
+								{Store: "store-1", Duration: time.Second},
+								{Store: "store-2", Duration: 2 * time.Second},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "[stats] Merging of samples where there is single overlap.",
 			input: []Response{
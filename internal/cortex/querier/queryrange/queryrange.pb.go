@@ -18,6 +18,8 @@ import (
 	math "math"
 	math_bits "math/bits"
 	time "time"
+
+	hintspb "github.com/thanos-io/thanos/pkg/store/hintspb"
 )
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -571,7 +573,6 @@ func (m *PrometheusInstantQueryData) GetAnalysis() *Analysis {
 
 type PrometheusInstantQueryResult struct {
 	// Types that are valid to be assigned to Result:
-	//
 	//	*PrometheusInstantQueryResult_Scalar
 	//	*PrometheusInstantQueryResult_StringSample
 	//	*PrometheusInstantQueryResult_Vector
@@ -779,10 +780,12 @@ func (m *Matrix) GetSampleStreams() []*SampleStream {
 }
 
 type PrometheusResponseStats struct {
-	Samples              *PrometheusResponseSamplesStats `protobuf:"bytes,1,opt,name=samples,proto3" json:"samples"`
-	XXX_NoUnkeyedLiteral struct{}                        `json:"-"`
-	XXX_unrecognized     []byte                          `json:"-"`
-	XXX_sizecache        int32                           `json:"-"`
+	Samples *PrometheusResponseSamplesStats `protobuf:"bytes,1,opt,name=samples,proto3" json:"samples"`
+	// Query stats of StoreAPIs, returned by Thanos queriers.
+	Store                *hintspb.QueryStats `protobuf:"bytes,2,opt,name=store,proto3" json:"store,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
 }
 
 func (m *PrometheusResponseStats) Reset()         { *m = PrometheusResponseStats{} }
@@ -825,6 +828,13 @@ func (m *PrometheusResponseStats) GetSamples() *PrometheusResponseSamplesStats {
 	return nil
 }
 
+func (m *PrometheusResponseStats) GetStore() *hintspb.QueryStats {
+	if m != nil {
+		return m.Store
+	}
+	return nil
+}
+
 type PrometheusResponseSamplesStats struct {
 	TotalQueryableSamples        int64                                             `protobuf:"varint,1,opt,name=totalQueryableSamples,proto3" json:"totalQueryableSamples"`
 	TotalQueryableSamplesPerStep []*PrometheusResponseQueryableSamplesStatsPerStep `protobuf:"bytes,2,rep,name=totalQueryableSamplesPerStep,proto3" json:"totalQueryableSamplesPerStep"`
@@ -1620,102 +1630,104 @@ func init() {
 }
 
 var fileDescriptor_9af7607b46ac39b7 = []byte{
-	// 1514 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe4, 0x58, 0xcd, 0x6f, 0x1c, 0x45,
-	0x16, 0x4f, 0xcf, 0x97, 0x67, 0xde, 0x38, 0xb6, 0x53, 0xf6, 0x26, 0x63, 0xaf, 0xd7, 0xed, 0x74,
-	0x56, 0x2b, 0x6f, 0x36, 0x19, 0x6b, 0xbd, 0xca, 0x1e, 0x22, 0xad, 0x17, 0x37, 0x31, 0x98, 0x28,
-	0x1f, 0x4e, 0x39, 0x0a, 0x12, 0x17, 0x54, 0x33, 0x53, 0x8c, 0x9b, 0xf4, 0x74, 0x77, 0xba, 0xaa,
-	0x13, 0xfb, 0xc6, 0x9f, 0x80, 0x38, 0x71, 0x04, 0x89, 0x33, 0xff, 0x06, 0x39, 0x70, 0xe0, 0x8c,
-	0x44, 0x83, 0x72, 0xec, 0x13, 0x17, 0xee, 0xa8, 0x3e, 0x7a, 0xba, 0x7a, 0x6c, 0x8f, 0x65, 0x71,
-	0x01, 0x71, 0xf1, 0x54, 0xbd, 0xf7, 0x7b, 0xaf, 0xde, 0x57, 0xbd, 0xae, 0x67, 0xb8, 0xd9, 0x0f,
-	0x63, 0x4e, 0x8f, 0x36, 0x5f, 0x24, 0x34, 0xf6, 0x68, 0x2c, 0x7f, 0x8f, 0x63, 0x12, 0x0c, 0xa9,
-	0xb1, 0xec, 0x46, 0x71, 0xc8, 0x43, 0x04, 0x05, 0x65, 0x65, 0x69, 0x18, 0x0e, 0x43, 0x49, 0xde,
-	0x14, 0x2b, 0x85, 0x58, 0x59, 0x1b, 0x86, 0xe1, 0xd0, 0xa7, 0x9b, 0x72, 0xd7, 0x4b, 0x3e, 0xda,
-	0x1c, 0x24, 0x31, 0xe1, 0x5e, 0x18, 0x68, 0xfe, 0xaa, 0x3e, 0x4d, 0xfd, 0x44, 0x3d, 0xbd, 0xd0,
-	0xdc, 0xe5, 0x49, 0x69, 0x12, 0x1c, 0x2b, 0x96, 0x73, 0x00, 0xd7, 0xf6, 0xe3, 0x70, 0x44, 0xf9,
-	0x21, 0x4d, 0x18, 0xa6, 0x2f, 0x12, 0xca, 0xf8, 0x1e, 0x25, 0x03, 0x1a, 0xa3, 0x65, 0xa8, 0x3d,
-	0x22, 0x23, 0xda, 0xb1, 0xd6, 0xad, 0x8d, 0x96, 0x5b, 0xcf, 0x52, 0xdb, 0xba, 0x8d, 0x25, 0x09,
-	0xfd, 0x0d, 0x1a, 0xcf, 0x88, 0x9f, 0x50, 0xd6, 0xa9, 0xac, 0x57, 0x0b, 0xa6, 0x26, 0x3a, 0x69,
-	0x05, 0xae, 0x9c, 0xd0, 0x8a, 0x10, 0xd4, 0x22, 0xc2, 0x0f, 0x95, 0x3e, 0x2c, 0xd7, 0x68, 0x09,
-	0xea, 0x8c, 0x93, 0x98, 0x77, 0x2a, 0xeb, 0xd6, 0x46, 0x15, 0xab, 0x0d, 0x5a, 0x80, 0x2a, 0x0d,
-	0x06, 0x9d, 0xaa, 0xa4, 0x89, 0xa5, 0x90, 0x65, 0x9c, 0x46, 0x9d, 0x9a, 0x24, 0xc9, 0x35, 0xfa,
-	0x1f, 0xcc, 0x70, 0x6f, 0x44, 0xc3, 0x84, 0x77, 0xea, 0xeb, 0xd6, 0x46, 0x7b, 0x6b, 0xb9, 0xab,
-	0xfc, 0xec, 0xe6, 0x7e, 0x76, 0xef, 0xe9, 0x28, 0xb9, 0xcd, 0xd7, 0xa9, 0x7d, 0xe9, 0xf3, 0x1f,
-	0x6d, 0x0b, 0xe7, 0x32, 0xe2, 0x68, 0x19, 0xf6, 0x4e, 0x43, 0xda, 0xa3, 0x36, 0x68, 0x0f, 0xe6,
-	0xfa, 0xa4, 0x7f, 0xe8, 0x05, 0xc3, 0xc7, 0x91, 0x90, 0x64, 0x9d, 0x19, 0xa9, 0x7b, 0xa5, 0x6b,
-	0x64, 0xed, 0xed, 0x12, 0xc2, 0xad, 0x09, 0xe5, 0x78, 0x42, 0x0e, 0xdd, 0x83, 0x19, 0x15, 0x48,
-	0xd6, 0x69, 0xae, 0x57, 0x37, 0xda, 0x5b, 0x37, 0x4c, 0x15, 0x67, 0x04, 0x3d, 0x8f, 0x64, 0x2e,
-	0xaa, 0x03, 0xc4, 0x59, 0xa7, 0xa5, 0xac, 0x94, 0x1b, 0xe7, 0x29, 0x74, 0x4c, 0x05, 0x2c, 0x0a,
-	0x03, 0x46, 0x7f, 0x73, 0xda, 0x7e, 0xa8, 0x00, 0x3a, 0xa9, 0x16, 0x39, 0xd0, 0x38, 0xe0, 0x84,
-	0x27, 0x4c, 0xab, 0x84, 0x2c, 0xb5, 0x1b, 0x4c, 0x52, 0xb0, 0xe6, 0xa0, 0x77, 0xa0, 0x76, 0x8f,
-	0x70, 0x22, 0xd3, 0x38, 0x11, 0xac, 0x42, 0xa3, 0x40, 0xb8, 0x57, 0x45, 0xb0, 0xb2, 0xd4, 0x9e,
-	0x1b, 0x10, 0x4e, 0x6e, 0x85, 0x23, 0x8f, 0xd3, 0x51, 0xc4, 0x8f, 0xb1, 0x94, 0x47, 0x77, 0xa0,
-	0xb5, 0x1b, 0xc7, 0x61, 0xfc, 0xf4, 0x38, 0xa2, 0x32, 0xff, 0x2d, 0xf7, 0x5a, 0x96, 0xda, 0x8b,
-	0x34, 0x27, 0x1a, 0x12, 0x05, 0x12, 0xfd, 0x13, 0xea, 0x72, 0x23, 0xeb, 0xa3, 0xe5, 0x2e, 0x66,
-	0xa9, 0x3d, 0x2f, 0x45, 0x0c, 0xb8, 0x42, 0xa0, 0xdd, 0x22, 0x2d, 0x75, 0x99, 0x96, 0xbf, 0x9f,
-	0x95, 0x16, 0x33, 0xaa, 0x27, 0xf2, 0xb2, 0x05, 0xcd, 0xf7, 0x49, 0x1c, 0x78, 0xc1, 0x90, 0x75,
-	0x1a, 0x32, 0x98, 0x57, 0xb3, 0xd4, 0x46, 0xaf, 0x34, 0xcd, 0x38, 0x77, 0x8c, 0x73, 0x3e, 0xab,
-	0xc0, 0x5c, 0x39, 0x1a, 0xa8, 0x0b, 0x80, 0x29, 0x4b, 0x7c, 0x2e, 0x1d, 0x56, 0xf1, 0x9d, 0xcb,
-	0x52, 0x1b, 0xe2, 0x31, 0x15, 0x1b, 0x08, 0xf4, 0x16, 0x34, 0xd4, 0x4e, 0x66, 0xb0, 0xbd, 0xd5,
-	0x31, 0x8d, 0x3f, 0x20, 0xa3, 0xc8, 0xa7, 0x07, 0x3c, 0xa6, 0x64, 0xe4, 0xce, 0xe9, 0x38, 0x37,
-	0x94, 0x26, 0xac, 0xe5, 0xd0, 0xa3, 0xbc, 0xa0, 0xaa, 0x32, 0x55, 0x37, 0xa6, 0x7b, 0x2f, 0xd2,
-	0xcb, 0x54, 0x3c, 0xa5, 0x94, 0x19, 0x4f, 0x49, 0x40, 0xdb, 0xd0, 0x24, 0x01, 0xf1, 0x8f, 0x99,
-	0xc7, 0x64, 0xf4, 0xdb, 0x5b, 0x4b, 0xa6, 0xca, 0x1d, 0xcd, 0x73, 0x67, 0xb3, 0xd4, 0x1e, 0x23,
-	0xf1, 0x78, 0xe5, 0xfc, 0x52, 0x81, 0xb5, 0xe2, 0xdc, 0xf7, 0x02, 0xc6, 0x49, 0xc0, 0x9f, 0x08,
-	0x05, 0x17, 0x2a, 0x40, 0x5c, 0x2a, 0xc0, 0x7f, 0x9c, 0xee, 0x95, 0xa9, 0xfd, 0xcf, 0x5e, 0x8c,
-	0x5f, 0x57, 0x60, 0xe5, 0xec, 0xc8, 0x5c, 0xb8, 0x30, 0xf7, 0x8d, 0xc2, 0x14, 0x19, 0xd8, 0x38,
-	0x3f, 0x03, 0x0a, 0xff, 0x87, 0x29, 0xd4, 0x9f, 0x2d, 0x58, 0x9d, 0xe6, 0x08, 0xba, 0x09, 0x0d,
-	0xd6, 0x27, 0x3e, 0x89, 0x65, 0xb8, 0xda, 0x5b, 0x0b, 0xdd, 0xfc, 0x6b, 0xac, 0x6f, 0xe6, 0xde,
-	0x25, 0xac, 0x11, 0x68, 0x1b, 0x66, 0x19, 0x8f, 0xbd, 0x60, 0xa8, 0x38, 0x3a, 0x68, 0xe5, 0xdb,
-	0x6c, 0xf0, 0xf7, 0x2e, 0xe1, 0x12, 0x1e, 0xdd, 0x82, 0xc6, 0x4b, 0xda, 0xe7, 0x61, 0xac, 0xa3,
-	0x83, 0x4c, 0xc9, 0x67, 0x92, 0x23, 0x4e, 0x53, 0x18, 0x81, 0x1e, 0x11, 0x1e, 0x7b, 0x47, 0xda,
-	0xf1, 0x12, 0xfa, 0xa1, 0xe4, 0x08, 0xb4, 0xc2, 0xb8, 0x4d, 0xd0, 0xa9, 0x70, 0xfe, 0x0b, 0x8d,
-	0x67, 0xb9, 0x86, 0x19, 0x26, 0x4f, 0x16, 0x77, 0xb0, 0x3a, 0xa9, 0x42, 0x19, 0x85, 0x73, 0x88,
-	0xb3, 0x07, 0x0d, 0xa5, 0x15, 0x6d, 0xc3, 0x65, 0x66, 0x74, 0xa5, 0x5c, 0xfa, 0xcc, 0xb6, 0x85,
-	0xcb, 0x70, 0xc7, 0x2f, 0x3f, 0x4f, 0x8c, 0x5c, 0xa3, 0x27, 0xa6, 0x49, 0xc2, 0xab, 0x9b, 0xe7,
-	0x54, 0x88, 0x02, 0xab, 0x42, 0x69, 0x67, 0xa9, 0x9d, 0x8b, 0x17, 0x76, 0x7f, 0x53, 0xea, 0x45,
-	0xa7, 0x09, 0xa2, 0xc7, 0xf0, 0x17, 0x1e, 0x72, 0xe2, 0xcb, 0xc4, 0x93, 0x9e, 0x9f, 0x73, 0xa5,
-	0x0d, 0x55, 0x77, 0x39, 0x4b, 0xed, 0xd3, 0x01, 0xf8, 0x74, 0x32, 0xfa, 0xc2, 0x82, 0xd5, 0x53,
-	0x39, 0xfb, 0x34, 0x3e, 0x10, 0x4f, 0x1e, 0xd5, 0xe8, 0xef, 0x4e, 0x77, 0x6e, 0x52, 0x58, 0x1a,
-	0xab, 0x35, 0xb8, 0xeb, 0x59, 0x6a, 0x4f, 0x3d, 0x03, 0x4f, 0xe5, 0xa2, 0x7f, 0x43, 0x3b, 0xa2,
-	0xe4, 0x79, 0xee, 0xa9, 0xa8, 0xb8, 0xba, 0x3b, 0x9f, 0xa5, 0xb6, 0x49, 0xc6, 0xe6, 0xc6, 0xf1,
-	0xe0, 0x82, 0x46, 0x8a, 0x87, 0xce, 0x4b, 0xf1, 0x0c, 0x51, 0x81, 0xc4, 0x6a, 0x83, 0xae, 0xc3,
-	0xac, 0x78, 0xaf, 0x31, 0x4e, 0x46, 0xd1, 0x87, 0x23, 0xa6, 0x9f, 0x89, 0xed, 0x31, 0xed, 0x21,
-	0x73, 0xbe, 0xac, 0xc0, 0xac, 0x59, 0x42, 0xe8, 0x13, 0x0b, 0x1a, 0x3e, 0xe9, 0x51, 0x3f, 0xaf,
-	0xb6, 0xc5, 0xe2, 0x22, 0x3e, 0x10, 0xf4, 0x7d, 0xe2, 0xc5, 0xee, 0x81, 0x68, 0x3b, 0xdf, 0xa7,
-	0xf6, 0xce, 0xd0, 0xe3, 0x87, 0x49, 0xaf, 0xdb, 0x0f, 0x47, 0x9b, 0xfc, 0x90, 0x04, 0x21, 0xbb,
-	0xed, 0x85, 0x7a, 0xb5, 0xe9, 0x05, 0x9c, 0xc6, 0x01, 0xf1, 0x37, 0x27, 0x9e, 0xd7, 0x4a, 0xcf,
-	0xce, 0x80, 0x44, 0x9c, 0xc6, 0xa2, 0x77, 0x8d, 0x28, 0x8f, 0xbd, 0x3e, 0xd6, 0xe7, 0xa2, 0xbb,
-	0x45, 0x6d, 0xaa, 0xf4, 0x9d, 0xe8, 0x05, 0x45, 0xdb, 0x93, 0x8e, 0x16, 0x45, 0x88, 0x30, 0xc0,
-	0xa1, 0xc7, 0x78, 0x38, 0x8c, 0xc5, 0x7d, 0xa9, 0x4a, 0x71, 0xfb, 0xe4, 0x7d, 0xd9, 0xcb, 0x31,
-	0xd2, 0x9b, 0x2b, 0x5a, 0x5b, 0x6b, 0x2c, 0x8a, 0x0d, 0x2d, 0xce, 0x57, 0x15, 0x68, 0xe8, 0xce,
-	0xf1, 0x3b, 0x88, 0xce, 0xbf, 0xa0, 0xad, 0x9c, 0x95, 0xef, 0x4e, 0x99, 0x53, 0xcb, 0x6d, 0x65,
-	0xa9, 0xad, 0x92, 0x8e, 0x4d, 0x2e, 0x5a, 0x85, 0xd6, 0x38, 0xdb, 0x7a, 0x22, 0x28, 0x08, 0xe8,
-	0x01, 0x14, 0x1e, 0xeb, 0xe6, 0xf6, 0xd7, 0x29, 0xb1, 0x92, 0x71, 0xb2, 0xca, 0x71, 0x2a, 0x96,
-	0xce, 0xbb, 0x30, 0x6b, 0x76, 0xdd, 0x72, 0x4d, 0xb6, 0x2e, 0x50, 0x93, 0x1c, 0x16, 0x4f, 0xc9,
-	0x52, 0xd9, 0x17, 0x6b, 0xd2, 0x97, 0xff, 0x9b, 0xbe, 0x54, 0xce, 0xf7, 0x45, 0x8d, 0x1d, 0x86,
-	0xf9, 0x11, 0xcc, 0x4f, 0x60, 0x84, 0x07, 0xfd, 0x30, 0x09, 0xb8, 0x3c, 0xcd, 0xc2, 0x6a, 0x23,
-	0xe6, 0x2b, 0x96, 0xa8, 0x33, 0x2c, 0x2c, 0x96, 0xe8, 0x0e, 0xcc, 0xf4, 0x92, 0xfe, 0x73, 0xca,
-	0xf3, 0x8a, 0x2b, 0x9d, 0x5c, 0x9c, 0x29, 0x31, 0x38, 0xc7, 0x3a, 0x0c, 0xe6, 0x27, 0x78, 0x68,
-	0x0d, 0xa0, 0x17, 0x26, 0xc1, 0x80, 0xc4, 0x9e, 0xee, 0x8a, 0x75, 0x6c, 0x50, 0x84, 0x45, 0x7e,
-	0xf8, 0x8a, 0xc6, 0xfa, 0x74, 0xb5, 0x11, 0xd4, 0x24, 0x8a, 0xa8, 0xfa, 0x9c, 0x59, 0x58, 0x6d,
-	0x0a, 0xeb, 0x6b, 0x86, 0xf5, 0xce, 0xc7, 0x30, 0x27, 0x06, 0x30, 0x3a, 0x18, 0x3f, 0x10, 0x97,
-	0xa1, 0xfa, 0x9c, 0x1e, 0xeb, 0x57, 0xca, 0x4c, 0x96, 0xda, 0x62, 0x8b, 0xc5, 0x1f, 0x31, 0x24,
-	0xd2, 0x23, 0x4e, 0x03, 0x9e, 0xdf, 0xc4, 0xd2, 0x87, 0x6b, 0x57, 0xb2, 0xdc, 0x79, 0x7d, 0x7b,
-	0x72, 0x28, 0xce, 0x17, 0xce, 0xa7, 0x16, 0x34, 0x14, 0x08, 0xd9, 0xf9, 0xa8, 0xaa, 0x3a, 0xbd,
-	0xac, 0x57, 0x49, 0xc8, 0xa7, 0xd6, 0x65, 0x35, 0xb5, 0xca, 0x72, 0x50, 0x56, 0xd0, 0x60, 0xa0,
-	0xc6, 0xd7, 0x6d, 0x68, 0xc6, 0xda, 0x58, 0x3d, 0xab, 0x2e, 0x9d, 0x98, 0x55, 0x77, 0x82, 0x63,
-	0xf5, 0xf6, 0xc8, 0x91, 0x78, 0xbc, 0xba, 0x5f, 0x6b, 0x56, 0x17, 0x6a, 0xf7, 0x6b, 0xcd, 0xda,
-	0x42, 0xdd, 0xb9, 0xa5, 0xdc, 0x37, 0x26, 0xcd, 0x15, 0x68, 0x0e, 0x3c, 0x26, 0x1a, 0xeb, 0x40,
-	0x1a, 0xd7, 0xc4, 0xe3, 0xbd, 0x13, 0x40, 0x7b, 0xf7, 0x28, 0xf2, 0x49, 0x20, 0xe7, 0x60, 0xb4,
-	0x0a, 0xb5, 0xa0, 0x18, 0x0e, 0x9b, 0x59, 0x6a, 0xcb, 0x3d, 0x96, 0x7f, 0xd1, 0x0e, 0x34, 0xfb,
-	0x87, 0x9e, 0x3f, 0x88, 0x69, 0xa0, 0xa3, 0x75, 0xad, 0x1c, 0xad, 0xb1, 0x22, 0x65, 0x69, 0x0e,
-	0xc6, 0xe3, 0x95, 0xf3, 0xad, 0x05, 0xcd, 0xfc, 0x29, 0x75, 0xce, 0x69, 0x3d, 0xb8, 0x4c, 0x8f,
-	0x68, 0x3f, 0x11, 0xfa, 0x9e, 0x7a, 0xa3, 0xfc, 0x11, 0x34, 0x65, 0x8a, 0xbf, 0xae, 0xbb, 0x52,
-	0x33, 0xa7, 0x64, 0xa9, 0x5d, 0xd6, 0x81, 0xcb, 0x5b, 0x11, 0xf8, 0xb1, 0x47, 0xaa, 0xb0, 0xa7,
-	0x3c, 0xfa, 0x4e, 0xba, 0xe3, 0x76, 0x5e, 0xbf, 0x59, 0xb3, 0xbe, 0x7b, 0xb3, 0x66, 0xfd, 0xf4,
-	0x66, 0xcd, 0xfa, 0xc0, 0xf8, 0x3f, 0x4d, 0xaf, 0x21, 0xcd, 0xfb, 0xcf, 0xaf, 0x01, 0x00, 0x00,
-	0xff, 0xff, 0x5e, 0x6a, 0x36, 0xdc, 0xe8, 0x11, 0x00, 0x00,
+	// 1546 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe4, 0x58, 0x4b, 0x6f, 0x23, 0x4b,
+	0x15, 0x9e, 0xf6, 0x2b, 0xf6, 0x71, 0x6e, 0x92, 0x5b, 0x09, 0x37, 0x9d, 0x10, 0xd2, 0xb9, 0x0d,
+	0x42, 0x61, 0x98, 0x6b, 0x8b, 0xa0, 0x61, 0x31, 0x12, 0x81, 0x34, 0x13, 0x08, 0xa3, 0x79, 0x64,
+	0x2a, 0xa3, 0x41, 0x62, 0x83, 0xca, 0x76, 0x61, 0x37, 0x63, 0x77, 0xf7, 0x54, 0x55, 0xcf, 0x24,
+	0x3b, 0x7e, 0x02, 0x62, 0xc5, 0x12, 0x24, 0x96, 0x88, 0xbf, 0xc1, 0x2c, 0x58, 0xb0, 0x46, 0xa2,
+	0x41, 0xb3, 0xec, 0x15, 0x1b, 0xf6, 0xa8, 0x1e, 0x6d, 0x57, 0xdb, 0x89, 0xa3, 0x88, 0x0d, 0x88,
+	0x4d, 0x5c, 0xe7, 0x7d, 0xce, 0x77, 0x4e, 0x55, 0x57, 0x05, 0xee, 0xf7, 0x63, 0x26, 0xe8, 0x65,
+	0xf7, 0x6d, 0x4a, 0x59, 0x48, 0x99, 0xfa, 0xbd, 0x62, 0x24, 0x1a, 0x52, 0x6b, 0xd9, 0x49, 0x58,
+	0x2c, 0x62, 0x04, 0x33, 0xce, 0xee, 0xd6, 0x30, 0x1e, 0xc6, 0x8a, 0xdd, 0x95, 0x2b, 0xad, 0xb1,
+	0xbb, 0x3f, 0x8c, 0xe3, 0xe1, 0x98, 0x76, 0x15, 0xd5, 0x4b, 0x7f, 0xde, 0x1d, 0xa4, 0x8c, 0x88,
+	0x30, 0x8e, 0x8c, 0x7c, 0xcf, 0x44, 0xd3, 0x3f, 0x49, 0xcf, 0x2c, 0x8c, 0x74, 0x67, 0xde, 0x9a,
+	0x44, 0x57, 0x85, 0x88, 0x8b, 0x98, 0xd1, 0xee, 0x28, 0x8c, 0x04, 0x4f, 0x7a, 0xfa, 0x57, 0x8b,
+	0xfc, 0x0b, 0xd8, 0x3e, 0x67, 0xf1, 0x84, 0x8a, 0x11, 0x4d, 0x39, 0xa6, 0x6f, 0x53, 0xca, 0xc5,
+	0x19, 0x25, 0x03, 0xca, 0xd0, 0x0e, 0xd4, 0x9e, 0x93, 0x09, 0x75, 0x9d, 0x03, 0xe7, 0xb0, 0x15,
+	0xd4, 0xf3, 0xcc, 0x73, 0xbe, 0xc0, 0x8a, 0x85, 0xbe, 0x02, 0x8d, 0xd7, 0x64, 0x9c, 0x52, 0xee,
+	0x56, 0x0e, 0xaa, 0x33, 0xa1, 0x61, 0xfa, 0x59, 0x05, 0x3e, 0x5d, 0xf0, 0x8a, 0x10, 0xd4, 0x12,
+	0x22, 0x46, 0xda, 0x1f, 0x56, 0x6b, 0xb4, 0x05, 0x75, 0x2e, 0x08, 0x13, 0x6e, 0xe5, 0xc0, 0x39,
+	0xac, 0x62, 0x4d, 0xa0, 0x0d, 0xa8, 0xd2, 0x68, 0xe0, 0x56, 0x15, 0x4f, 0x2e, 0xa5, 0x2d, 0x17,
+	0x34, 0x71, 0x6b, 0x8a, 0xa5, 0xd6, 0xe8, 0xbb, 0xb0, 0x22, 0xc2, 0x09, 0x8d, 0x53, 0xe1, 0xd6,
+	0x0f, 0x9c, 0xc3, 0xf6, 0xd1, 0x4e, 0x47, 0x43, 0xd0, 0x29, 0x20, 0xe8, 0x3c, 0x36, 0x00, 0x06,
+	0xcd, 0x0f, 0x99, 0x77, 0xef, 0x37, 0x7f, 0xf7, 0x1c, 0x5c, 0xd8, 0xc8, 0xd0, 0xaa, 0x23, 0x6e,
+	0x43, 0xe5, 0xa3, 0x09, 0x74, 0x06, 0x6b, 0x7d, 0xd2, 0x1f, 0x85, 0xd1, 0xf0, 0x45, 0x22, 0x2d,
+	0xb9, 0xbb, 0xa2, 0x7c, 0xef, 0x76, 0xac, 0x86, 0xfe, 0xa0, 0xa4, 0x11, 0xd4, 0xa4, 0x73, 0x3c,
+	0x67, 0x87, 0x1e, 0xc3, 0x8a, 0x06, 0x92, 0xbb, 0xcd, 0x83, 0xea, 0x61, 0xfb, 0xe8, 0xab, 0xb6,
+	0x8b, 0x1b, 0x40, 0x2f, 0x90, 0x2c, 0x4c, 0x0d, 0x40, 0x82, 0xbb, 0x2d, 0x9d, 0xa5, 0x22, 0xfc,
+	0x57, 0xe0, 0xda, 0x0e, 0x78, 0x12, 0x47, 0x9c, 0xfe, 0xc7, 0x6d, 0xfb, 0x5b, 0x05, 0xd0, 0xa2,
+	0x5b, 0xe4, 0x43, 0xe3, 0x42, 0x10, 0x91, 0x72, 0xe3, 0x12, 0xf2, 0xcc, 0x6b, 0x70, 0xc5, 0xc1,
+	0x46, 0x82, 0x7e, 0x08, 0xb5, 0xc7, 0x44, 0x10, 0xb7, 0xb2, 0x08, 0xd6, 0xcc, 0xa3, 0xd4, 0x08,
+	0x3e, 0x93, 0x60, 0xe5, 0x99, 0xb7, 0x36, 0x20, 0x82, 0x3c, 0x88, 0x27, 0xa1, 0xa0, 0x93, 0x44,
+	0x5c, 0x61, 0x65, 0x8f, 0x1e, 0x42, 0xeb, 0x94, 0xb1, 0x98, 0xbd, 0xba, 0x4a, 0xa8, 0xea, 0x7f,
+	0x2b, 0xd8, 0xce, 0x33, 0x6f, 0x93, 0x16, 0x4c, 0xcb, 0x62, 0xa6, 0x89, 0xbe, 0x01, 0x75, 0x45,
+	0xa8, 0xf9, 0x68, 0x05, 0x9b, 0x79, 0xe6, 0xad, 0x2b, 0x13, 0x4b, 0x5d, 0x6b, 0xa0, 0xd3, 0x59,
+	0x5b, 0xea, 0xaa, 0x2d, 0x5f, 0xbb, 0xa9, 0x2d, 0x36, 0xaa, 0x0b, 0x7d, 0x39, 0x82, 0xe6, 0x4f,
+	0x08, 0x8b, 0xc2, 0x68, 0xc8, 0xdd, 0x86, 0x02, 0xf3, 0xb3, 0x3c, 0xf3, 0xd0, 0x7b, 0xc3, 0xb3,
+	0xe2, 0x4e, 0xf5, 0xfc, 0x5f, 0x57, 0x60, 0xad, 0x8c, 0x06, 0xea, 0x00, 0x60, 0xca, 0xd3, 0xb1,
+	0x50, 0x05, 0x6b, 0x7c, 0xd7, 0xf2, 0xcc, 0x03, 0x36, 0xe5, 0x62, 0x4b, 0x03, 0x7d, 0x1f, 0x1a,
+	0x9a, 0x52, 0x1d, 0x6c, 0x1f, 0xb9, 0x76, 0xf2, 0x17, 0x64, 0x92, 0x8c, 0xe9, 0x85, 0x60, 0x94,
+	0x4c, 0x82, 0x35, 0x83, 0x73, 0x43, 0x7b, 0xc2, 0xc6, 0x0e, 0x3d, 0x2f, 0x06, 0xaa, 0x7a, 0xe0,
+	0x2c, 0x1b, 0x4a, 0x5d, 0xbd, 0x6c, 0x2f, 0xd7, 0x78, 0x2a, 0x2b, 0x1b, 0x4f, 0xc5, 0x40, 0xc7,
+	0xd0, 0x24, 0x11, 0x19, 0x5f, 0xf1, 0x90, 0x2b, 0xf4, 0xdb, 0x47, 0x5b, 0xb6, 0xcb, 0x13, 0x23,
+	0x0b, 0x56, 0xf3, 0xcc, 0x9b, 0x6a, 0xe2, 0xe9, 0xca, 0xff, 0x57, 0x05, 0xf6, 0x67, 0x71, 0x7f,
+	0x1c, 0x71, 0x41, 0x22, 0xf1, 0x52, 0x3a, 0xb8, 0xd3, 0x00, 0xe2, 0xd2, 0x00, 0x7e, 0xfd, 0xfa,
+	0xaa, 0x6c, 0xef, 0xff, 0xef, 0xc3, 0xf8, 0xc7, 0x0a, 0xec, 0xde, 0x8c, 0xcc, 0x9d, 0x07, 0xf3,
+	0xdc, 0x1a, 0x4c, 0xd9, 0x81, 0xc3, 0xdb, 0x3b, 0xa0, 0xf5, 0xff, 0x67, 0x06, 0xf5, 0x9f, 0x0e,
+	0xec, 0x2d, 0x2b, 0x04, 0xdd, 0x87, 0x06, 0xef, 0x93, 0x31, 0x61, 0x0a, 0xae, 0xf6, 0xd1, 0x46,
+	0xa7, 0xf8, 0x50, 0x9b, 0x9d, 0x79, 0x76, 0x0f, 0x1b, 0x0d, 0x74, 0x0c, 0xab, 0x5c, 0xb0, 0x30,
+	0x1a, 0x6a, 0x89, 0x01, 0xad, 0xbc, 0x9b, 0x2d, 0xf9, 0xd9, 0x3d, 0x5c, 0xd2, 0x47, 0x0f, 0xa0,
+	0xf1, 0x8e, 0xf6, 0x45, 0xcc, 0x0c, 0x3a, 0xc8, 0xb6, 0x7c, 0xad, 0x24, 0x32, 0x9a, 0xd6, 0x91,
+	0xda, 0x13, 0x22, 0x58, 0x78, 0xe9, 0xd6, 0x16, 0xb5, 0x9f, 0x29, 0x89, 0xd4, 0xd6, 0x3a, 0x41,
+	0x13, 0x4c, 0x2b, 0xfc, 0xef, 0x40, 0xe3, 0x75, 0xe1, 0x61, 0x85, 0xab, 0xc8, 0x72, 0x0f, 0x56,
+	0xe7, 0x5d, 0xe8, 0xa4, 0x70, 0xa1, 0xe2, 0x9f, 0x41, 0x43, 0x7b, 0x45, 0xc7, 0xf0, 0x09, 0xb7,
+	0x4e, 0xa5, 0xc2, 0xfa, 0xc6, 0x63, 0x0b, 0x97, 0xd5, 0xfd, 0x3f, 0x38, 0xb0, 0x7d, 0x43, 0xb3,
+	0xd1, 0x4b, 0x3b, 0x27, 0x59, 0xd6, 0xfd, 0x5b, 0x46, 0x44, 0x2b, 0xeb, 0x49, 0x69, 0xe7, 0x99,
+	0x57, 0x98, 0x4f, 0x13, 0x47, 0xc7, 0x72, 0xe6, 0x62, 0x56, 0xf4, 0x63, 0xb3, 0x63, 0xae, 0x4c,
+	0x1d, 0xd5, 0xe7, 0xd2, 0x8c, 0xc5, 0x8c, 0x96, 0x67, 0x2c, 0x66, 0xd4, 0xff, 0x53, 0xe9, 0x30,
+	0xbb, 0x2e, 0x30, 0x7a, 0x01, 0x5f, 0x12, 0xb1, 0x20, 0x63, 0xe5, 0x91, 0xf4, 0xc6, 0xf4, 0xc2,
+	0xaa, 0xa1, 0x1a, 0xec, 0xe4, 0x99, 0x77, 0xbd, 0x02, 0xbe, 0x9e, 0x8d, 0x7e, 0xeb, 0xc0, 0xde,
+	0xb5, 0x92, 0x73, 0xca, 0x2e, 0xe4, 0x9d, 0x49, 0x7f, 0x29, 0x1e, 0x2d, 0x07, 0x67, 0xde, 0x58,
+	0x25, 0x6b, 0x3c, 0x04, 0x07, 0x79, 0xe6, 0x2d, 0x8d, 0x81, 0x97, 0x4a, 0xd1, 0xb7, 0xa0, 0x9d,
+	0x50, 0xf2, 0xa6, 0xa8, 0x54, 0x8e, 0x6c, 0x3d, 0x58, 0xcf, 0x33, 0xcf, 0x66, 0x63, 0x9b, 0xf0,
+	0x43, 0xb8, 0x63, 0x92, 0xf2, 0xa6, 0xf4, 0x4e, 0xde, 0x63, 0x34, 0x90, 0x58, 0x13, 0xe8, 0x73,
+	0x58, 0x95, 0x17, 0x3e, 0x2e, 0xc8, 0x24, 0xf9, 0xd9, 0x84, 0x9b, 0x7b, 0x66, 0x7b, 0xca, 0x7b,
+	0xc6, 0xfd, 0xdf, 0x55, 0x60, 0xd5, 0x9e, 0x41, 0xf4, 0x4b, 0x07, 0x1a, 0x63, 0xd2, 0xa3, 0xe3,
+	0x62, 0x5c, 0x37, 0x67, 0x3b, 0xf9, 0xa9, 0xe4, 0x9f, 0x93, 0x90, 0x05, 0x17, 0xf2, 0xdc, 0xfa,
+	0x6b, 0xe6, 0x9d, 0x0c, 0x43, 0x31, 0x4a, 0x7b, 0x9d, 0x7e, 0x3c, 0xe9, 0x8a, 0x11, 0x89, 0x62,
+	0xfe, 0x45, 0x18, 0x9b, 0x55, 0x37, 0x8c, 0x04, 0x65, 0x11, 0x19, 0x77, 0xe7, 0xae, 0xee, 0xda,
+	0xcf, 0xc9, 0x80, 0x24, 0x82, 0x32, 0x79, 0xf8, 0x4d, 0xa8, 0x60, 0x61, 0x1f, 0x9b, 0xb8, 0xe8,
+	0xd1, 0x6c, 0xb6, 0x75, 0xfb, 0x16, 0x0e, 0x93, 0xd9, 0xb9, 0xa9, 0x0a, 0xb5, 0x86, 0x18, 0x03,
+	0x8c, 0x42, 0x2e, 0xe2, 0x21, 0x93, 0x1b, 0xae, 0xaa, 0xcc, 0xbd, 0xc5, 0x0d, 0x77, 0x56, 0xe8,
+	0xa8, 0x6a, 0x3e, 0x35, 0xde, 0x5a, 0x53, 0x53, 0x6c, 0x79, 0xf1, 0x7f, 0x5f, 0x81, 0x86, 0x39,
+	0x7a, 0xfe, 0x0b, 0xd0, 0xf9, 0x26, 0xb4, 0x75, 0xb1, 0xea, 0xe2, 0xaa, 0x7a, 0xea, 0x04, 0xad,
+	0x3c, 0xf3, 0x74, 0xd3, 0xb1, 0x2d, 0x45, 0x7b, 0xd0, 0x9a, 0x76, 0xdb, 0x3c, 0x29, 0x66, 0x0c,
+	0xf4, 0x14, 0x66, 0x15, 0x9b, 0xd3, 0xf1, 0xcb, 0x4b, 0xb0, 0x52, 0x38, 0x39, 0x65, 0x9c, 0x66,
+	0x4b, 0xff, 0x47, 0xb0, 0x6a, 0x1f, 0xdb, 0xe5, 0x99, 0x6c, 0xdd, 0x61, 0x26, 0x05, 0x6c, 0x5e,
+	0xd3, 0xa5, 0x72, 0x2d, 0xce, 0x7c, 0x2d, 0xdf, 0xb3, 0x6b, 0xa9, 0xdc, 0x5e, 0x8b, 0x7e, 0xb7,
+	0x58, 0xe9, 0x27, 0xb0, 0x3e, 0xa7, 0x23, 0x2b, 0xe8, 0xc7, 0x69, 0x24, 0x54, 0x34, 0x07, 0x6b,
+	0x42, 0x3e, 0xd0, 0x78, 0xaa, 0x63, 0x38, 0x58, 0x2e, 0xd1, 0x43, 0x58, 0xe9, 0xa5, 0xfd, 0x37,
+	0x54, 0x14, 0x13, 0x57, 0x8a, 0x3c, 0x8b, 0xa9, 0x74, 0x70, 0xa1, 0xeb, 0x73, 0x58, 0x9f, 0x93,
+	0xa1, 0x7d, 0x80, 0x5e, 0x9c, 0x46, 0x03, 0xc2, 0x42, 0x73, 0x2a, 0xd6, 0xb1, 0xc5, 0x91, 0x19,
+	0x8d, 0xe3, 0xf7, 0x94, 0x99, 0xe8, 0x9a, 0x90, 0xdc, 0x34, 0x49, 0xa8, 0xfe, 0x1e, 0x3a, 0x58,
+	0x13, 0xb3, 0xec, 0x6b, 0x56, 0xf6, 0xfe, 0x2f, 0x60, 0x4d, 0xbe, 0xe0, 0xe8, 0x60, 0x7a, 0xc3,
+	0xdc, 0x81, 0xea, 0x1b, 0x7a, 0x65, 0xae, 0x39, 0x2b, 0x79, 0xe6, 0x49, 0x12, 0xcb, 0x3f, 0xf2,
+	0x95, 0x49, 0x2f, 0x05, 0x8d, 0x44, 0xb1, 0x13, 0x4b, 0x5f, 0xbe, 0x53, 0x25, 0x0a, 0xd6, 0xcd,
+	0xee, 0x29, 0x54, 0x71, 0xb1, 0xf0, 0x7f, 0xe5, 0x40, 0x43, 0x2b, 0x21, 0xaf, 0x78, 0xeb, 0xea,
+	0x93, 0x5e, 0xcd, 0xab, 0x62, 0x14, 0xcf, 0xde, 0x1d, 0xfd, 0xec, 0x55, 0xe3, 0xa0, 0xb3, 0xa0,
+	0xd1, 0x40, 0xbf, 0x7f, 0x8f, 0xa1, 0xc9, 0x4c, 0xb2, 0xe6, 0xb1, 0xbb, 0xb5, 0xf0, 0xd8, 0x3d,
+	0x89, 0xae, 0xf4, 0xe5, 0xa5, 0xd0, 0xc4, 0xd3, 0xd5, 0x93, 0x5a, 0xb3, 0xba, 0x51, 0x7b, 0x52,
+	0x6b, 0xd6, 0x36, 0xea, 0xfe, 0x03, 0x5d, 0xbe, 0xf5, 0x54, 0xdd, 0x85, 0xe6, 0x20, 0xe4, 0xf2,
+	0x60, 0x1d, 0xa8, 0xe4, 0x9a, 0x78, 0x4a, 0xfb, 0x11, 0xb4, 0x4f, 0x2f, 0x93, 0x31, 0x89, 0xd4,
+	0x43, 0x1a, 0xed, 0x41, 0x2d, 0x9a, 0xbd, 0x2e, 0x9b, 0x79, 0xe6, 0x29, 0x1a, 0xab, 0xbf, 0xe8,
+	0x04, 0x9a, 0xfd, 0x51, 0x38, 0x1e, 0x30, 0x1a, 0x19, 0xb4, 0xb6, 0xcb, 0x68, 0x4d, 0x1d, 0xe9,
+	0x4c, 0x0b, 0x65, 0x3c, 0x5d, 0xf9, 0x7f, 0x76, 0xa0, 0x59, 0xdc, 0xc5, 0x6e, 0x89, 0xd6, 0x83,
+	0x4f, 0xe8, 0x25, 0xed, 0xa7, 0xd2, 0xdf, 0xab, 0x70, 0x52, 0x7c, 0xb5, 0x97, 0xfc, 0x1b, 0xe0,
+	0x73, 0x73, 0x2a, 0x35, 0x0b, 0x4e, 0x9e, 0x79, 0x65, 0x1f, 0xb8, 0x4c, 0x4a, 0xe0, 0xa7, 0x15,
+	0xe9, 0xc1, 0x5e, 0x72, 0x6b, 0x5c, 0x2c, 0x27, 0x70, 0x3f, 0x7c, 0xdc, 0x77, 0xfe, 0xf2, 0x71,
+	0xdf, 0xf9, 0xc7, 0xc7, 0x7d, 0xe7, 0xa7, 0xd6, 0xff, 0x80, 0x7a, 0x0d, 0x95, 0xde, 0xb7, 0xff,
+	0x3d, 0x00, 0xc8, 0x0f, 0xb3, 0xf0, 0x44, 0x12, 0x00, 0x00,
 }
 
 func (m *PrometheusRequestHeader) Marshal() (dAtA []byte, err error) {
@@ -2427,6 +2439,18 @@ func (m *PrometheusResponseStats) MarshalToSizedBuffer(dAtA []byte) (int, error)
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
+	if m.Store != nil {
+		{
+			size, err := m.Store.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintQueryrange(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x12
+	}
 	if m.Samples != nil {
 		{
 			size, err := m.Samples.MarshalToSizedBuffer(dAtA[:i])
@@ -3426,6 +3450,10 @@ func (m *PrometheusResponseStats) Size() (n int) {
 		l = m.Samples.Size()
 		n += 1 + l + sovQueryrange(uint64(l))
 	}
+	if m.Store != nil {
+		l = m.Store.Size()
+		n += 1 + l + sovQueryrange(uint64(l))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -5556,6 +5584,42 @@ func (m *PrometheusResponseStats) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Store", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowQueryrange
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthQueryrange
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthQueryrange
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Store == nil {
+				m.Store = &hintspb.QueryStats{}
+			}
+			if err := m.Store.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipQueryrange(dAtA[iNdEx:])
@@ -32,10 +32,13 @@ func (s statsMiddleware) Do(ctx context.Context, r Request) (Response, error) {
 		return resp, err
 	}
 
-	if resp.GetStats() != nil {
+	if respStats := resp.GetStats(); respStats != nil {
 		if sts := stats.FromContext(ctx); sts != nil {
-			sts.SetPeakSamples(max(sts.LoadPeakSamples(), resp.GetStats().Samples.PeakSamples))
-			sts.AddTotalSamples(resp.GetStats().Samples.TotalQueryableSamples)
+			if respStats.Samples != nil {
+				sts.SetPeakSamples(max(sts.LoadPeakSamples(), respStats.Samples.PeakSamples))
+				sts.AddTotalSamples(respStats.Samples.TotalQueryableSamples)
+			}
+			sts.AddStoreStats(respStats.Store)
 		}
 	}
 
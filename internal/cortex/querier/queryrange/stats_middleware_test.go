@@ -6,11 +6,13 @@ package queryrange
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/thanos-io/thanos/internal/cortex/querier/stats"
+	"github.com/thanos-io/thanos/pkg/store/hintspb"
 )
 
 func Test_statsMiddleware_AddsHeaderWithStats(t *testing.T) {
@@ -90,6 +92,43 @@ func Test_statsMiddleware_AddsHeaderWithStats(t *testing.T) {
 	}
 }
 
+func Test_statsMiddleware_AddsStoreStats(t *testing.T) {
+	t.Parallel()
+
+	fakeHandler := &fakeHandler{
+		response: &PrometheusResponse{
+			Status: "success",
+			Data: PrometheusData{
+				ResultType: "vector",
+				Result:     []SampleStream{},
+				Stats: &PrometheusResponseStats{
+					Store: &hintspb.QueryStats{
+						BlocksQueried:         4,
+						DataDownloadedSizeSum: 2048,
+						StoreStats:            []hintspb.StoreStats{{Store: "store-1", Duration: time.Second}},
+					},
+				},
+			},
+		},
+	}
+
+	qryStats, ctx := stats.ContextWithEmptyStats(context.Background())
+	_, err := NewStatsMiddleware(true).Wrap(fakeHandler).Do(ctx, &PrometheusRequest{
+		Path:  "/api/v1/query_range",
+		Start: 1536673680 * 1e3,
+		End:   1536716898 * 1e3,
+		Step:  120 * 1e3,
+		Query: "sum(container_memory_rss) by (namespace)",
+	})
+	require.NoError(t, err)
+
+	// Responses without samples stats only report store stats.
+	assert.Equal(t, int32(0), qryStats.LoadPeakSamples())
+	assert.Equal(t, uint64(4), qryStats.LoadBlocksQueried())
+	assert.Equal(t, uint64(2048), qryStats.LoadDownloadedBytes())
+	assert.Equal(t, time.Second, qryStats.LoadMaxStoreDuration())
+}
+
 type fakeHandler struct {
 	request  Request
 	response Response
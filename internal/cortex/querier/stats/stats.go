@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/thanos-io/thanos/pkg/store/hintspb"
 )
 
 type contextKey int
@@ -121,6 +123,110 @@ func (s *Stats) LoadTotalSamples() int64 {
 	return atomic.LoadInt64(&s.TotalLoadedSamples)
 }
 
+func (s *Stats) AddBlocksQueried(blocks uint64) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddUint64(&s.BlocksQueried, blocks)
+}
+
+func (s *Stats) LoadBlocksQueried() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.BlocksQueried)
+}
+
+func (s *Stats) AddTouched(postings, series, chunks uint64) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddUint64(&s.TouchedPostingsCount, postings)
+	atomic.AddUint64(&s.TouchedSeriesCount, series)
+	atomic.AddUint64(&s.TouchedChunksCount, chunks)
+}
+
+func (s *Stats) LoadTouchedPostings() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.TouchedPostingsCount)
+}
+
+func (s *Stats) LoadTouchedSeries() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.TouchedSeriesCount)
+}
+
+func (s *Stats) LoadTouchedChunks() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.TouchedChunksCount)
+}
+
+func (s *Stats) AddDownloadedBytes(bytes uint64) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddUint64(&s.DownloadedBytes, bytes)
+}
+
+func (s *Stats) LoadDownloadedBytes() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.DownloadedBytes)
+}
+
+// UpdateMaxStoreDuration sets the max store duration to d if it is longer than the current one.
+func (s *Stats) UpdateMaxStoreDuration(d time.Duration) {
+	if s == nil {
+		return
+	}
+
+	for {
+		cur := atomic.LoadInt64((*int64)(&s.MaxStoreDuration))
+		if int64(d) <= cur || atomic.CompareAndSwapInt64((*int64)(&s.MaxStoreDuration), cur, int64(d)) {
+			return
+		}
+	}
+}
+
+func (s *Stats) LoadMaxStoreDuration() time.Duration {
+	if s == nil {
+		return 0
+	}
+
+	return time.Duration(atomic.LoadInt64((*int64)(&s.MaxStoreDuration)))
+}
+
+// AddStoreStats adds the query stats reported by the StoreAPIs queried for a request.
+func (s *Stats) AddStoreStats(qs *hintspb.QueryStats) {
+	if s == nil || qs == nil {
+		return
+	}
+
+	s.AddFetchedSeries(uint64(qs.MergedSeriesCount))
+	s.AddFetchedChunkBytes(uint64(qs.ChunksFetchedSizeSum))
+	s.AddBlocksQueried(uint64(qs.BlocksQueried))
+	s.AddTouched(uint64(qs.PostingsTouched), uint64(qs.SeriesTouched), uint64(qs.ChunksTouched))
+	s.AddDownloadedBytes(uint64(qs.DataDownloadedSizeSum))
+	for _, ss := range qs.StoreStats {
+		s.UpdateMaxStoreDuration(ss.Duration)
+	}
+}
+
 // Merge the provide Stats into this one.
 func (s *Stats) Merge(other *Stats) {
 	if s == nil || other == nil {
@@ -130,6 +236,10 @@ func (s *Stats) Merge(other *Stats) {
 	s.AddWallTime(other.LoadWallTime())
 	s.AddFetchedSeries(other.LoadFetchedSeries())
 	s.AddFetchedChunkBytes(other.LoadFetchedChunkBytes())
+	s.AddBlocksQueried(other.LoadBlocksQueried())
+	s.AddTouched(other.LoadTouchedPostings(), other.LoadTouchedSeries(), other.LoadTouchedChunks())
+	s.AddDownloadedBytes(other.LoadDownloadedBytes())
+	s.UpdateMaxStoreDuration(other.LoadMaxStoreDuration())
 }
 
 func ShouldTrackHTTPGRPCResponse(r *httpgrpc.HTTPResponse) bool {
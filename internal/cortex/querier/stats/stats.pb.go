@@ -38,10 +38,20 @@ type Stats struct {
 	// The maximum number of samples loaded in a single execution window.
 	PeakLoadedSamples int32 `protobuf:"varint,4,opt,name=peak_loaded_samples,json=peakLoadedSamples,proto3" json:"peak_loaded_samples,omitempty"`
 	// The total number of samples loaded for the query
-	TotalLoadedSamples   int64    `protobuf:"varint,5,opt,name=total_loaded_samples,json=totalLoadedSamples,proto3" json:"total_loaded_samples,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	TotalLoadedSamples int64 `protobuf:"varint,5,opt,name=total_loaded_samples,json=totalLoadedSamples,proto3" json:"total_loaded_samples,omitempty"`
+	// The number of blocks queried by store gateways for the query.
+	BlocksQueried uint64 `protobuf:"varint,6,opt,name=blocks_queried,json=blocksQueried,proto3" json:"blocks_queried,omitempty"`
+	// The number of postings, series and chunks touched by store gateways for the query.
+	TouchedPostingsCount uint64 `protobuf:"varint,7,opt,name=touched_postings_count,json=touchedPostingsCount,proto3" json:"touched_postings_count,omitempty"`
+	TouchedSeriesCount   uint64 `protobuf:"varint,8,opt,name=touched_series_count,json=touchedSeriesCount,proto3" json:"touched_series_count,omitempty"`
+	TouchedChunksCount   uint64 `protobuf:"varint,9,opt,name=touched_chunks_count,json=touchedChunksCount,proto3" json:"touched_chunks_count,omitempty"`
+	// The number of bytes downloaded from object storage by store gateways for the query.
+	DownloadedBytes uint64 `protobuf:"varint,10,opt,name=downloaded_bytes,json=downloadedBytes,proto3" json:"downloaded_bytes,omitempty"`
+	// The longest time spent streaming series from a single StoreAPI for the query.
+	MaxStoreDuration     time.Duration `protobuf:"bytes,11,opt,name=max_store_duration,json=maxStoreDuration,proto3,stdduration" json:"max_store_duration"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
 }
 
 func (m *Stats) Reset()         { *m = Stats{} }
@@ -112,6 +122,48 @@ func (m *Stats) GetTotalLoadedSamples() int64 {
 	return 0
 }
 
+func (m *Stats) GetBlocksQueried() uint64 {
+	if m != nil {
+		return m.BlocksQueried
+	}
+	return 0
+}
+
+func (m *Stats) GetTouchedPostingsCount() uint64 {
+	if m != nil {
+		return m.TouchedPostingsCount
+	}
+	return 0
+}
+
+func (m *Stats) GetTouchedSeriesCount() uint64 {
+	if m != nil {
+		return m.TouchedSeriesCount
+	}
+	return 0
+}
+
+func (m *Stats) GetTouchedChunksCount() uint64 {
+	if m != nil {
+		return m.TouchedChunksCount
+	}
+	return 0
+}
+
+func (m *Stats) GetDownloadedBytes() uint64 {
+	if m != nil {
+		return m.DownloadedBytes
+	}
+	return 0
+}
+
+func (m *Stats) GetMaxStoreDuration() time.Duration {
+	if m != nil {
+		return m.MaxStoreDuration
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Stats)(nil), "stats.Stats")
 }
@@ -119,25 +171,33 @@ func init() {
 func init() { proto.RegisterFile("cortex/querier/stats/stats.proto", fileDescriptor_993e99dbe6209dce) }
 
 var fileDescriptor_993e99dbe6209dce = []byte{
-	// 288 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x5c, 0xd0, 0x31, 0x52, 0xb3, 0x40,
-	0x14, 0xc0, 0xf1, 0x6f, 0x93, 0xf0, 0x4d, 0xc4, 0x2a, 0x9b, 0x14, 0x98, 0x82, 0x30, 0x56, 0x54,
-	0xe0, 0xe8, 0x05, 0x1c, 0x62, 0x69, 0x05, 0x56, 0x36, 0xcc, 0x02, 0x1b, 0xc2, 0x64, 0x61, 0x91,
-	0x7d, 0x8c, 0x7a, 0x08, 0x7b, 0x8f, 0x94, 0xd2, 0x13, 0xa8, 0xc3, 0x49, 0x9c, 0xb7, 0x4b, 0x0a,
-	0x6d, 0x18, 0x1e, 0x3f, 0xfe, 0xc3, 0x63, 0x6d, 0x2f, 0x97, 0x1d, 0xf0, 0x97, 0xf0, 0xa9, 0xe7,
-	0x5d, 0xc5, 0xbb, 0x50, 0x01, 0x03, 0x65, 0xae, 0x41, 0xdb, 0x49, 0x90, 0xd4, 0xd2, 0xc3, 0x7a,
-	0x55, 0xca, 0x52, 0xea, 0x27, 0x21, 0xde, 0x19, 0x5c, 0xbb, 0xa5, 0x94, 0xa5, 0xe0, 0xa1, 0x9e,
-	0xb2, 0x7e, 0x17, 0x16, 0x7d, 0xc7, 0xa0, 0x92, 0x8d, 0xf1, 0xcb, 0xb7, 0x89, 0x6d, 0x25, 0xd8,
-	0xd3, 0x5b, 0xfb, 0xec, 0x99, 0x09, 0x91, 0x42, 0x55, 0x73, 0x87, 0x78, 0xc4, 0x3f, 0xbf, 0xbe,
-	0x08, 0x4c, 0x1d, 0x9c, 0xea, 0xe0, 0x6e, 0xac, 0xa3, 0xf9, 0xf1, 0x73, 0xf3, 0xef, 0xfd, 0x6b,
-	0x43, 0xe2, 0x39, 0x56, 0x0f, 0x55, 0xcd, 0xe9, 0x95, 0xbd, 0xda, 0x71, 0xc8, 0xf7, 0xbc, 0x48,
-	0x15, 0x2e, 0xab, 0xd2, 0x5c, 0xf6, 0x0d, 0x38, 0x13, 0x8f, 0xf8, 0xb3, 0x98, 0x8e, 0x96, 0x68,
-	0xda, 0xa2, 0xd0, 0xc0, 0x5e, 0x9e, 0x8a, 0x7c, 0xdf, 0x37, 0x87, 0x34, 0x7b, 0x05, 0xae, 0x9c,
-	0xa9, 0x0e, 0x16, 0x23, 0x6d, 0x51, 0x22, 0x04, 0x7c, 0xbf, 0xe5, 0xec, 0x90, 0x0a, 0xc9, 0x0a,
-	0xfc, 0x0a, 0xab, 0x5b, 0xc1, 0x95, 0x33, 0xf3, 0x88, 0x6f, 0xc5, 0x0b, 0xa4, 0x7b, 0x2d, 0x89,
-	0x01, 0xdc, 0x08, 0x24, 0x30, 0xf1, 0x37, 0xb0, 0x3c, 0xe2, 0x4f, 0x63, 0xaa, 0xed, 0x57, 0x11,
-	0x2d, 0x8f, 0x83, 0x4b, 0x3e, 0x06, 0x97, 0x7c, 0x0f, 0x2e, 0x79, 0x34, 0x47, 0x9b, 0xfd, 0xd7,
-	0xff, 0x7f, 0xf3, 0x13, 0x00, 0x00, 0xff, 0xff, 0x99, 0x96, 0x18, 0xaf, 0x8c, 0x01, 0x00, 0x00,
+	// 402 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x92, 0xcf, 0xae, 0x93, 0x40,
+	0x14, 0x87, 0x1d, 0x6f, 0xb9, 0xf6, 0xce, 0x8d, 0x7a, 0xef, 0xdc, 0xc6, 0x60, 0x17, 0x94, 0x98,
+	0x98, 0xe0, 0x06, 0x1a, 0xf5, 0x01, 0x4c, 0xeb, 0xd2, 0x85, 0x05, 0x57, 0x6e, 0x26, 0x03, 0x4c,
+	0x29, 0x29, 0x70, 0x90, 0x19, 0xd2, 0xfa, 0x26, 0x3e, 0x52, 0x17, 0x2e, 0x7c, 0x02, 0x35, 0x7d,
+	0x12, 0x33, 0x7f, 0xd0, 0xd6, 0xd5, 0xdd, 0x34, 0x9d, 0xf3, 0x9d, 0x0f, 0xce, 0xf9, 0x31, 0xd8,
+	0xcf, 0xa0, 0x93, 0x7c, 0x1f, 0x7d, 0xe9, 0x79, 0x57, 0xf2, 0x2e, 0x12, 0x92, 0x49, 0x61, 0x7e,
+	0xc3, 0xb6, 0x03, 0x09, 0xc4, 0xd1, 0x87, 0xe9, 0xa4, 0x80, 0x02, 0x74, 0x25, 0x52, 0xff, 0x0c,
+	0x9c, 0x7a, 0x05, 0x40, 0x51, 0xf1, 0x48, 0x9f, 0xd2, 0x7e, 0x1d, 0xe5, 0x7d, 0xc7, 0x64, 0x09,
+	0x8d, 0xe1, 0x2f, 0xbe, 0x8f, 0xb0, 0x93, 0x28, 0x9f, 0xbc, 0xc3, 0x57, 0x3b, 0x56, 0x55, 0x54,
+	0x96, 0x35, 0x77, 0x91, 0x8f, 0x82, 0xeb, 0xd7, 0xcf, 0x43, 0x63, 0x87, 0x83, 0x1d, 0xbe, 0xb7,
+	0xf6, 0x62, 0x7c, 0xf8, 0x39, 0x7b, 0xf0, 0xed, 0xd7, 0x0c, 0xc5, 0x63, 0x65, 0x7d, 0x2a, 0x6b,
+	0x4e, 0xe6, 0x78, 0xb2, 0xe6, 0x32, 0xdb, 0xf0, 0x9c, 0x0a, 0x35, 0xac, 0xa0, 0x19, 0xf4, 0x8d,
+	0x74, 0x1f, 0xfa, 0x28, 0x18, 0xc5, 0xc4, 0xb2, 0x44, 0xa3, 0xa5, 0x22, 0x24, 0xc4, 0x77, 0x83,
+	0x91, 0x6d, 0xfa, 0x66, 0x4b, 0xd3, 0xaf, 0x92, 0x0b, 0xf7, 0x42, 0x0b, 0xb7, 0x16, 0x2d, 0x15,
+	0x59, 0x28, 0xa0, 0xfa, 0x5b, 0xce, 0xb6, 0xb4, 0x02, 0x96, 0xab, 0xb7, 0xb0, 0xba, 0xad, 0xb8,
+	0x70, 0x47, 0x3e, 0x0a, 0x9c, 0xf8, 0x56, 0xa1, 0x0f, 0x9a, 0x24, 0x06, 0xa8, 0x89, 0x24, 0x48,
+	0x56, 0xfd, 0x2f, 0x38, 0x3e, 0x0a, 0x2e, 0x62, 0xa2, 0xd9, 0xb9, 0xf1, 0x12, 0x3f, 0x49, 0x2b,
+	0xc8, 0xb6, 0x82, 0x9a, 0xc0, 0x73, 0xf7, 0x52, 0x0f, 0xf3, 0xd8, 0x54, 0x57, 0xa6, 0x48, 0xde,
+	0xe2, 0x67, 0x12, 0x7a, 0x3d, 0x78, 0x0b, 0x42, 0x96, 0x4d, 0x31, 0x2c, 0xfb, 0x48, 0xb7, 0x4f,
+	0x2c, 0xfd, 0x68, 0xa1, 0x59, 0x77, 0x8e, 0x87, 0xfa, 0x79, 0x40, 0x63, 0x13, 0x90, 0x65, 0xa7,
+	0x01, 0x9d, 0x18, 0x3a, 0xa0, 0xc1, 0xb8, 0x3a, 0x33, 0x74, 0x42, 0xd6, 0x78, 0x85, 0x6f, 0x72,
+	0xd8, 0x35, 0x76, 0x61, 0x93, 0x27, 0xd6, 0xdd, 0x4f, 0xff, 0xd5, 0x4d, 0x9a, 0x2b, 0x4c, 0x6a,
+	0xb6, 0xa7, 0x42, 0x42, 0xc7, 0xe9, 0x70, 0x2f, 0xdc, 0xeb, 0xfb, 0x7f, 0xfa, 0x9b, 0x9a, 0xed,
+	0x13, 0x65, 0xff, 0x65, 0x77, 0x87, 0xa3, 0x87, 0x7e, 0x1c, 0x3d, 0xf4, 0xfb, 0xe8, 0xa1, 0xcf,
+	0xe6, 0x66, 0xa6, 0x97, 0xfa, 0x19, 0x6f, 0xfe, 0x0c, 0x00, 0x0b, 0xbf, 0x8d, 0x70, 0xcb, 0x02,
+	0x00, 0x00,
 }
 
 func (m *Stats) Marshal() (dAtA []byte, err error) {
@@ -164,6 +224,39 @@ func (m *Stats) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
+	n1, err1 := github_com_gogo_protobuf_types.StdDurationMarshalTo(m.MaxStoreDuration, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdDuration(m.MaxStoreDuration):])
+	if err1 != nil {
+		return 0, err1
+	}
+	i -= n1
+	i = encodeVarintStats(dAtA, i, uint64(n1))
+	i--
+	dAtA[i] = 0x5a
+	if m.DownloadedBytes != 0 {
+		i = encodeVarintStats(dAtA, i, uint64(m.DownloadedBytes))
+		i--
+		dAtA[i] = 0x50
+	}
+	if m.TouchedChunksCount != 0 {
+		i = encodeVarintStats(dAtA, i, uint64(m.TouchedChunksCount))
+		i--
+		dAtA[i] = 0x48
+	}
+	if m.TouchedSeriesCount != 0 {
+		i = encodeVarintStats(dAtA, i, uint64(m.TouchedSeriesCount))
+		i--
+		dAtA[i] = 0x40
+	}
+	if m.TouchedPostingsCount != 0 {
+		i = encodeVarintStats(dAtA, i, uint64(m.TouchedPostingsCount))
+		i--
+		dAtA[i] = 0x38
+	}
+	if m.BlocksQueried != 0 {
+		i = encodeVarintStats(dAtA, i, uint64(m.BlocksQueried))
+		i--
+		dAtA[i] = 0x30
+	}
 	if m.TotalLoadedSamples != 0 {
 		i = encodeVarintStats(dAtA, i, uint64(m.TotalLoadedSamples))
 		i--
@@ -184,12 +277,12 @@ func (m *Stats) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i--
 		dAtA[i] = 0x10
 	}
-	n1, err1 := github_com_gogo_protobuf_types.StdDurationMarshalTo(m.WallTime, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdDuration(m.WallTime):])
-	if err1 != nil {
-		return 0, err1
+	n2, err2 := github_com_gogo_protobuf_types.StdDurationMarshalTo(m.WallTime, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdDuration(m.WallTime):])
+	if err2 != nil {
+		return 0, err2
 	}
-	i -= n1
-	i = encodeVarintStats(dAtA, i, uint64(n1))
+	i -= n2
+	i = encodeVarintStats(dAtA, i, uint64(n2))
 	i--
 	dAtA[i] = 0xa
 	return len(dAtA) - i, nil
@@ -226,6 +319,23 @@ func (m *Stats) Size() (n int) {
 	if m.TotalLoadedSamples != 0 {
 		n += 1 + sovStats(uint64(m.TotalLoadedSamples))
 	}
+	if m.BlocksQueried != 0 {
+		n += 1 + sovStats(uint64(m.BlocksQueried))
+	}
+	if m.TouchedPostingsCount != 0 {
+		n += 1 + sovStats(uint64(m.TouchedPostingsCount))
+	}
+	if m.TouchedSeriesCount != 0 {
+		n += 1 + sovStats(uint64(m.TouchedSeriesCount))
+	}
+	if m.TouchedChunksCount != 0 {
+		n += 1 + sovStats(uint64(m.TouchedChunksCount))
+	}
+	if m.DownloadedBytes != 0 {
+		n += 1 + sovStats(uint64(m.DownloadedBytes))
+	}
+	l = github_com_gogo_protobuf_types.SizeOfStdDuration(m.MaxStoreDuration)
+	n += 1 + l + sovStats(uint64(l))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -376,6 +486,134 @@ func (m *Stats) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BlocksQueried", wireType)
+			}
+			m.BlocksQueried = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStats
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.BlocksQueried |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TouchedPostingsCount", wireType)
+			}
+			m.TouchedPostingsCount = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStats
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.TouchedPostingsCount |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TouchedSeriesCount", wireType)
+			}
+			m.TouchedSeriesCount = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStats
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.TouchedSeriesCount |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TouchedChunksCount", wireType)
+			}
+			m.TouchedChunksCount = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStats
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.TouchedChunksCount |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DownloadedBytes", wireType)
+			}
+			m.DownloadedBytes = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStats
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.DownloadedBytes |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 11:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxStoreDuration", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStats
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthStats
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthStats
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := github_com_gogo_protobuf_types.StdDurationUnmarshal(&m.MaxStoreDuration, dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipStats(dAtA[iNdEx:])
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/thanos-io/thanos/pkg/store/hintspb"
 )
 
 func TestStats_WallTime(t *testing.T) {
@@ -62,6 +64,46 @@ func TestStats_AddFetchedChunkBytes(t *testing.T) {
 	})
 }
 
+func TestStats_AddStoreStats(t *testing.T) {
+	t.Run("add and load store stats", func(t *testing.T) {
+		stats, _ := ContextWithEmptyStats(context.Background())
+		stats.AddStoreStats(&hintspb.QueryStats{
+			BlocksQueried:         2,
+			MergedSeriesCount:     10,
+			PostingsTouched:       3,
+			SeriesTouched:         12,
+			ChunksTouched:         30,
+			ChunksFetchedSizeSum:  1024,
+			DataDownloadedSizeSum: 4096,
+			StoreStats: []hintspb.StoreStats{
+				{Store: "store-1", Duration: time.Second},
+				{Store: "store-2", Duration: 3 * time.Second},
+			},
+		})
+		stats.AddStoreStats(&hintspb.QueryStats{
+			BlocksQueried: 1,
+			StoreStats:    []hintspb.StoreStats{{Store: "store-3", Duration: 2 * time.Second}},
+		})
+
+		assert.Equal(t, uint64(3), stats.LoadBlocksQueried())
+		assert.Equal(t, uint64(10), stats.LoadFetchedSeries())
+		assert.Equal(t, uint64(1024), stats.LoadFetchedChunkBytes())
+		assert.Equal(t, uint64(3), stats.LoadTouchedPostings())
+		assert.Equal(t, uint64(12), stats.LoadTouchedSeries())
+		assert.Equal(t, uint64(30), stats.LoadTouchedChunks())
+		assert.Equal(t, uint64(4096), stats.LoadDownloadedBytes())
+		assert.Equal(t, 3*time.Second, stats.LoadMaxStoreDuration())
+	})
+
+	t.Run("add and load store stats nil receiver", func(t *testing.T) {
+		var stats *Stats
+		stats.AddStoreStats(&hintspb.QueryStats{BlocksQueried: 1})
+
+		assert.Equal(t, uint64(0), stats.LoadBlocksQueried())
+		assert.Equal(t, time.Duration(0), stats.LoadMaxStoreDuration())
+	})
+}
+
 func TestStats_Merge(t *testing.T) {
 	t.Run("merge two stats objects", func(t *testing.T) {
 		stats1 := &Stats{}
@@ -85,6 +85,10 @@ type Limits struct {
 	CardinalityLimit             int            `yaml:"cardinality_limit" json:"cardinality_limit"`
 	MaxCacheFreshness            model.Duration `yaml:"max_cache_freshness" json:"max_cache_freshness"`
 	MaxQueriersPerTenant         int            `yaml:"max_queriers_per_tenant" json:"max_queriers_per_tenant"`
+	MaxEstimatedSeriesPerQuery   int            `yaml:"max_estimated_series_per_query" json:"max_estimated_series_per_query"`
+	MaxEstimatedSamplesPerQuery  int            `yaml:"max_estimated_samples_per_query" json:"max_estimated_samples_per_query"`
+	MaxResponseSizeBytes         int            `yaml:"max_response_size_bytes" json:"max_response_size_bytes"`
+	MaxResponseSeries            int            `yaml:"max_response_series" json:"max_response_series"`
 
 	// Ruler defaults and limits.
 	RulerEvaluationDelay        model.Duration `yaml:"ruler_evaluation_delay_duration" json:"ruler_evaluation_delay_duration"`
@@ -445,6 +449,29 @@ func (o *Overrides) MaxQueryParallelism(userID string) int {
 	return o.getOverridesForUser(userID).MaxQueryParallelism
 }
 
+// MaxEstimatedSeriesPerQuery returns the limit of series a query is estimated
+// to select before it is executed.
+func (o *Overrides) MaxEstimatedSeriesPerQuery(userID string) int {
+	return o.getOverridesForUser(userID).MaxEstimatedSeriesPerQuery
+}
+
+// MaxEstimatedSamplesPerQuery returns the limit of samples a query is estimated
+// to fetch before it is executed.
+func (o *Overrides) MaxEstimatedSamplesPerQuery(userID string) int {
+	return o.getOverridesForUser(userID).MaxEstimatedSamplesPerQuery
+}
+
+// MaxResponseSizeBytes returns the limit of the size of the downstream responses
+// decoded to answer a query.
+func (o *Overrides) MaxResponseSizeBytes(userID string) int {
+	return o.getOverridesForUser(userID).MaxResponseSizeBytes
+}
+
+// MaxResponseSeries returns the limit of series in the response of a query.
+func (o *Overrides) MaxResponseSeries(userID string) int {
+	return o.getOverridesForUser(userID).MaxResponseSeries
+}
+
 // EnforceMetricName whether to enforce the presence of a metric name.
 func (o *Overrides) EnforceMetricName(userID string) bool {
 	return o.getOverridesForUser(userID).EnforceMetricName
@@ -0,0 +1,104 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/log"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/route"
+
+	"github.com/thanos-io/thanos/pkg/api"
+	"github.com/thanos-io/thanos/pkg/compact"
+	extpromhttp "github.com/thanos-io/thanos/pkg/extprom/http"
+	"github.com/thanos-io/thanos/pkg/logging"
+)
+
+// CompactorAPI is an API listing the jobs of the compactor and allowing to pause it or to skip and prioritize groups.
+type CompactorAPI struct {
+	baseAPI                *api.BaseAPI
+	logger                 log.Logger
+	jobs                   *compact.JobController
+	disableCORS            bool
+	disableAdminOperations bool
+}
+
+// NewCompactorAPI creates a new CompactorAPI for the given JobController.
+func NewCompactorAPI(logger log.Logger, disableCORS bool, flagsMap map[string]string, jobs *compact.JobController) *CompactorAPI {
+	return &CompactorAPI{
+		baseAPI:                api.NewBaseAPI(logger, disableCORS, flagsMap),
+		logger:                 logger,
+		jobs:                   jobs,
+		disableCORS:            disableCORS,
+		disableAdminOperations: flagsMap["disable-admin-operations"] == "true",
+	}
+}
+
+// Register registers the compactor routes. It does not register the common routes of api.BaseAPI,
+// so it can be registered next to other APIs.
+func (capi *CompactorAPI) Register(r *route.Router, tracer opentracing.Tracer, logger log.Logger, ins extpromhttp.InstrumentationMiddleware, logMiddleware *logging.HTTPServerMiddleware) {
+	instr := api.GetInstr(tracer, logger, ins, logMiddleware, capi.disableCORS)
+
+	r.Get("/compactor/jobs", instr("compactor_jobs", capi.status))
+	r.Post("/compactor/pause", instr("compactor_pause", capi.admin(capi.pause)))
+	r.Post("/compactor/resume", instr("compactor_resume", capi.admin(capi.resume)))
+	r.Post("/compactor/groups/skip", instr("compactor_groups_skip", capi.admin(capi.skip)))
+	r.Post("/compactor/groups/priority", instr("compactor_groups_priority", capi.admin(capi.priority)))
+}
+
+func (capi *CompactorAPI) admin(f api.ApiFunc) api.ApiFunc {
+	return func(r *http.Request) (any, []error, *api.ApiError, func()) {
+		if capi.disableAdminOperations {
+			return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.New("Admin operations are disabled")}, func() {}
+		}
+		return f(r)
+	}
+}
+
+func (capi *CompactorAPI) status(*http.Request) (any, []error, *api.ApiError, func()) {
+	return capi.jobs.Status(), nil, nil, func() {}
+}
+
+func (capi *CompactorAPI) pause(*http.Request) (any, []error, *api.ApiError, func()) {
+	capi.jobs.Pause()
+	return capi.jobs.Status(), nil, nil, func() {}
+}
+
+func (capi *CompactorAPI) resume(*http.Request) (any, []error, *api.ApiError, func()) {
+	capi.jobs.Resume()
+	return capi.jobs.Status(), nil, nil, func() {}
+}
+
+func (capi *CompactorAPI) skip(r *http.Request) (any, []error, *api.ApiError, func()) {
+	group := r.FormValue("group")
+	if group == "" {
+		return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.New("Group cannot be empty")}, func() {}
+	}
+	skip := true
+	if s := r.FormValue("skip"); s != "" {
+		var err error
+		if skip, err = strconv.ParseBool(s); err != nil {
+			return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.Errorf("skip %q is not a boolean: %v", s, err)}, func() {}
+		}
+	}
+	capi.jobs.SetSkipped(group, skip)
+	return capi.jobs.Status(), nil, nil, func() {}
+}
+
+func (capi *CompactorAPI) priority(r *http.Request) (any, []error, *api.ApiError, func()) {
+	group := r.FormValue("group")
+	if group == "" {
+		return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.New("Group cannot be empty")}, func() {}
+	}
+	p := r.FormValue("priority")
+	priority, err := strconv.Atoi(p)
+	if err != nil {
+		return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.Errorf("priority %q is not an integer: %v", p, err)}, func() {}
+	}
+	capi.jobs.SetPriority(group, priority)
+	return capi.jobs.Status(), nil, nil, func() {}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package v1
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/go-kit/log"
+
+	baseAPI "github.com/thanos-io/thanos/pkg/api"
+	"github.com/thanos-io/thanos/pkg/compact"
+	"github.com/thanos-io/thanos/pkg/testutil/custom"
+)
+
+func TestMain(m *testing.M) {
+	custom.TolerantVerifyLeakMain(m)
+}
+
+func postForm(t *testing.T, f baseAPI.ApiFunc, form url.Values) (any, *baseAPI.ApiError) {
+	req, err := http.NewRequest(http.MethodPost, "http://example.com", strings.NewReader(form.Encode()))
+	testutil.Ok(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, _, apiErr, release := f(req)
+	release()
+	return resp, apiErr
+}
+
+func TestCompactorAPI(t *testing.T) {
+	jobs := compact.NewJobController()
+	api := NewCompactorAPI(log.NewNopLogger(), false, map[string]string{}, jobs)
+
+	resp, apiErr := postForm(t, api.admin(api.pause), nil)
+	testutil.Assert(t, apiErr == nil, "unexpected error %v", apiErr)
+	testutil.Assert(t, resp.(compact.JobsStatus).Paused, "expected compactor to be paused")
+	testutil.Assert(t, jobs.Paused(), "expected compactor to be paused")
+
+	_, apiErr = postForm(t, api.admin(api.resume), nil)
+	testutil.Assert(t, apiErr == nil, "unexpected error %v", apiErr)
+	testutil.Assert(t, !jobs.Paused(), "expected compactor to be resumed")
+
+	_, apiErr = postForm(t, api.admin(api.skip), url.Values{"group": {"0@123"}})
+	testutil.Assert(t, apiErr == nil, "unexpected error %v", apiErr)
+	testutil.Assert(t, jobs.Skipped("0@123"), "expected group to be skipped")
+
+	_, apiErr = postForm(t, api.admin(api.skip), url.Values{"group": {"0@123"}, "skip": {"false"}})
+	testutil.Assert(t, apiErr == nil, "unexpected error %v", apiErr)
+	testutil.Assert(t, !jobs.Skipped("0@123"), "expected group to not be skipped")
+
+	resp, apiErr = postForm(t, api.admin(api.priority), url.Values{"group": {"0@123"}, "priority": {"5"}})
+	testutil.Assert(t, apiErr == nil, "unexpected error %v", apiErr)
+	testutil.Equals(t, map[string]int{"0@123": 5}, resp.(compact.JobsStatus).Priorities)
+
+	for _, tc := range []struct {
+		name string
+		f    baseAPI.ApiFunc
+		form url.Values
+	}{
+		{name: "skip without group", f: api.skip, form: url.Values{}},
+		{name: "skip with invalid value", f: api.skip, form: url.Values{"group": {"0@123"}, "skip": {"maybe"}}},
+		{name: "priority without group", f: api.priority, form: url.Values{"priority": {"1"}}},
+		{name: "priority with invalid value", f: api.priority, form: url.Values{"group": {"0@123"}, "priority": {"high"}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, apiErr := postForm(t, tc.f, tc.form)
+			testutil.Assert(t, apiErr != nil, "expected error")
+			testutil.Equals(t, baseAPI.ErrorBadData, apiErr.Typ)
+		})
+	}
+
+	t.Run("admin operations disabled", func(t *testing.T) {
+		api := NewCompactorAPI(log.NewNopLogger(), false, map[string]string{"disable-admin-operations": "true"}, jobs)
+		_, apiErr := postForm(t, api.admin(api.pause), nil)
+		testutil.Assert(t, apiErr != nil, "expected error")
+		testutil.Assert(t, !jobs.Paused(), "expected compactor to not be paused")
+
+		resp, _, apiErr, _ := api.status(nil)
+		testutil.Assert(t, apiErr == nil, "unexpected error %v", apiErr)
+		testutil.Equals(t, 1, len(resp.(compact.JobsStatus).Priorities))
+	})
+}
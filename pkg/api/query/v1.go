@@ -45,6 +45,7 @@ import (
 	"github.com/prometheus/prometheus/util/annotations"
 	"github.com/prometheus/prometheus/util/stats"
 	v1 "github.com/prometheus/prometheus/web/api/v1"
+	promqlapi "github.com/thanos-io/promql-engine/api"
 	"github.com/thanos-io/promql-engine/engine"
 
 	"github.com/thanos-io/thanos/pkg/api"
@@ -64,6 +65,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/status"
 	"github.com/thanos-io/thanos/pkg/status/statuspb"
 	"github.com/thanos-io/thanos/pkg/store"
+	"github.com/thanos-io/thanos/pkg/store/hintspb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
 	"github.com/thanos-io/thanos/pkg/targets"
 	"github.com/thanos-io/thanos/pkg/targets/targetspb"
@@ -267,6 +269,19 @@ type queryData struct {
 	Warnings      []error        `json:"warnings,omitempty"`
 }
 
+// thanosQueryStats extends the query stats of the engine with the query stats of StoreAPIs.
+type thanosQueryStats struct {
+	stats.BuiltinStats
+	Store *hintspb.QueryStats `json:"store,omitempty"`
+}
+
+func newThanosQueryStats(s *stats.Statistics, storeStats *query.StoreStats) stats.QueryStats {
+	return &thanosQueryStats{
+		BuiltinStats: stats.NewQueryStats(s).Builtin(),
+		Store:        storeStats.Stats(),
+	}
+}
+
 type queryTelemetry struct {
 	// TODO(saswatamcode): Replace with engine.TrackedTelemetry once it has exported fields.
 	// TODO(saswatamcode): Add aggregate fields to enrich data.
@@ -431,9 +446,20 @@ func (qapi *QueryAPI) parseShardInfo(r *http.Request) (*storepb.ShardInfo, *api.
 	return &info, nil
 }
 
-func (qapi *QueryAPI) getQueryExplain(query promql.Query) (*engine.ExplainOutputNode, *api.ApiError) {
-	if eq, ok := query.(engine.ExplainableQuery); ok {
-		return eq.Explain(), nil
+// explainOutput is the query explanation, optionally extended with the costs the
+// distributed planner estimated for each remote engine.
+type explainOutput struct {
+	*engine.ExplainOutputNode
+	RemoteEngines []query.RemoteEngineCost `json:"remoteEngines,omitempty"`
+}
+
+func (qapi *QueryAPI) getQueryExplain(qry promql.Query, remoteEndpoints promqlapi.RemoteEndpoints) (*explainOutput, *api.ApiError) {
+	if eq, ok := qry.(engine.ExplainableQuery); ok {
+		out := &explainOutput{ExplainOutputNode: eq.Explain()}
+		if reporter, ok := remoteEndpoints.(query.RemoteEngineCostReporter); ok {
+			out.RemoteEngines = reporter.RemoteEngineCosts()
+		}
+		return out, nil
 	}
 	return nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.Errorf("Query not explainable")}
 }
@@ -560,8 +586,9 @@ func (qapi *QueryAPI) queryExplain(r *http.Request) (any, []error, *api.ApiError
 	}
 
 	var (
-		qry         promql.Query
-		seriesStats []storepb.SeriesStatsCounter
+		qry             promql.Query
+		seriesStats     []storepb.SeriesStatsCounter
+		remoteEndpoints promqlapi.RemoteEndpoints
 	)
 	if err := tracing.DoInSpanWithErr(ctx, "instant_query_create", func(ctx context.Context) error {
 		queryable := qapi.queryableCreate(
@@ -574,7 +601,7 @@ func (qapi *QueryAPI) queryExplain(r *http.Request) (any, []error, *api.ApiError
 			shardInfo,
 			query.NewAggregateStatsReporter(&seriesStats),
 		)
-		remoteEndpoints := qapi.remoteEndpointsCreate(
+		remoteEndpoints = qapi.remoteEndpointsCreate(
 			replicaLabels,
 			enablePartialResponse,
 		)
@@ -589,7 +616,7 @@ func (qapi *QueryAPI) queryExplain(r *http.Request) (any, []error, *api.ApiError
 		return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: err}, func() {}
 	}
 
-	explanation, apiErr := qapi.getQueryExplain(qry)
+	explanation, apiErr := qapi.getQueryExplain(qry, remoteEndpoints)
 	if apiErr != nil {
 		return nil, nil, apiErr, func() {}
 	}
@@ -709,6 +736,12 @@ func (qapi *QueryAPI) query(r *http.Request) (any, []error, *api.ApiError, func(
 		ctx = fanout.NewContext(ctx, fanoutTracker)
 	}
 
+	var storeStats *query.StoreStats
+	if r.FormValue(Stats) != "" {
+		storeStats = query.NewStoreStats()
+		ctx = query.NewStoreStatsContext(ctx, storeStats)
+	}
+
 	var res *promql.Result
 	tracing.DoInSpan(ctx, "instant_query_exec", func(ctx context.Context) {
 		res = qry.Exec(ctx)
@@ -745,7 +778,7 @@ func (qapi *QueryAPI) query(r *http.Request) (any, []error, *api.ApiError, func(
 	// Optional stats field in response if parameter "stats" is not empty.
 	var qs stats.QueryStats
 	if r.FormValue(Stats) != "" {
-		qs = stats.NewQueryStats(qry.Stats())
+		qs = newThanosQueryStats(qry.Stats(), storeStats)
 	}
 	return &queryData{
 		ResultType:    res.Value.Type(),
@@ -854,8 +887,9 @@ func (qapi *QueryAPI) queryRangeExplain(r *http.Request) (any, []error, *api.Api
 	}
 
 	var (
-		qry         promql.Query
-		seriesStats []storepb.SeriesStatsCounter
+		qry             promql.Query
+		seriesStats     []storepb.SeriesStatsCounter
+		remoteEndpoints promqlapi.RemoteEndpoints
 	)
 	if err := tracing.DoInSpanWithErr(ctx, "range_query_create", func(ctx context.Context) error {
 		queryable := qapi.queryableCreate(
@@ -868,7 +902,7 @@ func (qapi *QueryAPI) queryRangeExplain(r *http.Request) (any, []error, *api.Api
 			shardInfo,
 			query.NewAggregateStatsReporter(&seriesStats),
 		)
-		remoteEndpoints := qapi.remoteEndpointsCreate(
+		remoteEndpoints = qapi.remoteEndpointsCreate(
 			replicaLabels,
 			enablePartialResponse,
 		)
@@ -883,7 +917,7 @@ func (qapi *QueryAPI) queryRangeExplain(r *http.Request) (any, []error, *api.Api
 		return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: err}, func() {}
 	}
 
-	explanation, apiErr := qapi.getQueryExplain(qry)
+	explanation, apiErr := qapi.getQueryExplain(qry, remoteEndpoints)
 	if apiErr != nil {
 		return nil, nil, apiErr, func() {}
 	}
@@ -1027,6 +1061,12 @@ func (qapi *QueryAPI) queryRange(r *http.Request) (any, []error, *api.ApiError,
 		ctx = fanout.NewContext(ctx, fanoutTracker)
 	}
 
+	var storeStats *query.StoreStats
+	if r.FormValue(Stats) != "" {
+		storeStats = query.NewStoreStats()
+		ctx = query.NewStoreStatsContext(ctx, storeStats)
+	}
+
 	var res *promql.Result
 	tracing.DoInSpan(ctx, "range_query_exec", func(ctx context.Context) {
 		res = qry.Exec(ctx)
@@ -1062,7 +1102,7 @@ func (qapi *QueryAPI) queryRange(r *http.Request) (any, []error, *api.ApiError,
 	// Optional stats field in response if parameter "stats" is not empty.
 	var qs stats.QueryStats
 	if r.FormValue(Stats) != "" {
-		qs = stats.NewQueryStats(qry.Stats())
+		qs = newThanosQueryStats(qry.Stats(), storeStats)
 	}
 	return &queryData{
 		ResultType:    res.Value.Type(),
@@ -111,6 +111,9 @@ var (
 		timeout,
 		true,
 		true,
+		false,
+		nil,
+		nil,
 	)
 )
 
@@ -562,8 +565,10 @@ func TestQueryExplainEndpoints(t *testing.T) {
 				"time":   []string{"123.4"},
 				"engine": []string{"thanos"},
 			},
-			response: &engine.ExplainOutputNode{
-				OperatorName: "[numberLiteral] 2",
+			response: &explainOutput{
+				ExplainOutputNode: &engine.ExplainOutputNode{
+					OperatorName: "[numberLiteral] 2",
+				},
 			},
 		},
 		{
@@ -575,11 +580,13 @@ func TestQueryExplainEndpoints(t *testing.T) {
 				"step":   []string{"1"},
 				"engine": []string{"thanos"},
 			},
-			response: &engine.ExplainOutputNode{
-				OperatorName: "[duplicateLabelCheck]",
-				Children: []engine.ExplainOutputNode{
-					{
-						OperatorName: "[noArgFunction]",
+			response: &explainOutput{
+				ExplainOutputNode: &engine.ExplainOutputNode{
+					OperatorName: "[duplicateLabelCheck]",
+					Children: []engine.ExplainOutputNode{
+						{
+							OperatorName: "[noArgFunction]",
+						},
 					},
 				},
 			},
@@ -0,0 +1,362 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package backfill creates Thanos blocks from series read from external sources
+// like OpenMetrics files or Prometheus remote read endpoints.
+package backfill
+
+import (
+	"context"
+	"crypto/rand"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+)
+
+// samplesPerChunk is the maximum number of samples encoded in a single chunk,
+// same as the one used by the Prometheus head.
+const samplesPerChunk = 120
+
+// Appender collects the samples of a single block.
+type Appender interface {
+	// Append adds a float sample for the given series.
+	Append(lset labels.Labels, t int64, v float64) error
+	// AppendHistogram adds a native histogram sample for the given series.
+	// Exactly one of h or fh has to be set.
+	AppendHistogram(lset labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram) error
+}
+
+// Source provides the series to import.
+type Source interface {
+	// Read appends all samples with timestamps in [mint, maxt) to the appender.
+	// Samples outside of the range are ignored by the appender, so sources may
+	// append them if filtering is expensive.
+	Read(ctx context.Context, mint, maxt int64, app Appender) error
+}
+
+// Config configures how imported series are cut into blocks.
+type Config struct {
+	// Dir is the directory the blocks are written to.
+	Dir string
+	// BlockDuration is the time range in milliseconds covered by a single block.
+	// Blocks are aligned to multiples of it.
+	BlockDuration int64
+	// Labels are the external labels of the created blocks. Series labels
+	// with the same name and value are dropped, conflicting ones are rejected.
+	Labels labels.Labels
+	// Source is the source type recorded in the meta.json of the created blocks.
+	Source metadata.SourceType
+}
+
+// CreateBlocks reads all samples in [mint, maxt) from the source and writes them
+// into blocks aligned to the block duration. Time ranges without any samples are
+// skipped. It returns the IDs of the created blocks in time order.
+func CreateBlocks(ctx context.Context, logger log.Logger, src Source, mint, maxt int64, conf Config) ([]ulid.ULID, error) {
+	if conf.BlockDuration <= 0 {
+		return nil, errors.Errorf("block duration has to be positive, got %d", conf.BlockDuration)
+	}
+	if conf.Labels.IsEmpty() {
+		return nil, errors.New("external labels are required to create Thanos blocks")
+	}
+	if mint >= maxt {
+		return nil, errors.Errorf("empty time range [%d, %d)", mint, maxt)
+	}
+
+	var ids []ulid.ULID
+	for t := conf.BlockDuration * floorDiv(mint, conf.BlockDuration); t < maxt; t += conf.BlockDuration {
+		if err := ctx.Err(); err != nil {
+			return ids, err
+		}
+
+		app := newBlockAppender(max(t, mint), min(t+conf.BlockDuration, maxt), conf.Labels)
+		if err := src.Read(ctx, app.mint, app.maxt, app); err != nil {
+			return ids, errors.Wrapf(err, "read samples for [%d, %d)", app.mint, app.maxt)
+		}
+		if len(app.series) == 0 {
+			continue
+		}
+
+		id, err := app.write(ctx, logger, conf)
+		if err != nil {
+			return ids, errors.Wrapf(err, "write block for [%d, %d)", app.mint, app.maxt)
+		}
+		level.Info(logger).Log("msg", "created block", "id", id, "mint", app.mint, "maxt", app.maxt, "series", len(app.series))
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// floorDiv is integer division rounding towards negative infinity.
+func floorDiv(a, b int64) int64 {
+	if a < 0 && a%b != 0 {
+		return a/b - 1
+	}
+	return a / b
+}
+
+type sample struct {
+	t  int64
+	f  float64
+	h  *histogram.Histogram
+	fh *histogram.FloatHistogram
+}
+
+func (s sample) valueType() chunkenc.ValueType {
+	switch {
+	case s.h != nil:
+		return chunkenc.ValHistogram
+	case s.fh != nil:
+		return chunkenc.ValFloatHistogram
+	default:
+		return chunkenc.ValFloat
+	}
+}
+
+type memSeries struct {
+	lset    labels.Labels
+	samples []sample
+}
+
+// blockAppender keeps all samples of a single block in memory.
+type blockAppender struct {
+	mint, maxt int64
+	extLset    labels.Labels
+
+	builder *labels.Builder
+	series  map[string]*memSeries
+}
+
+func newBlockAppender(mint, maxt int64, extLset labels.Labels) *blockAppender {
+	return &blockAppender{
+		mint:    mint,
+		maxt:    maxt,
+		extLset: extLset,
+		builder: labels.NewBuilder(labels.EmptyLabels()),
+		series:  map[string]*memSeries{},
+	}
+}
+
+func (a *blockAppender) Append(lset labels.Labels, t int64, v float64) error {
+	return a.append(lset, sample{t: t, f: v})
+}
+
+func (a *blockAppender) AppendHistogram(lset labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram) error {
+	if (h == nil) == (fh == nil) {
+		return errors.Errorf("exactly one of histogram or float histogram has to be set for series %s", lset)
+	}
+	return a.append(lset, sample{t: t, h: h, fh: fh})
+}
+
+func (a *blockAppender) append(lset labels.Labels, s sample) error {
+	if s.t < a.mint || s.t >= a.maxt {
+		return nil
+	}
+
+	a.builder.Reset(lset)
+	var err error
+	a.extLset.Range(func(l labels.Label) {
+		v := lset.Get(l.Name)
+		if v == "" || err != nil {
+			return
+		}
+		if v != l.Value {
+			err = errors.Errorf("series %s has label %s that conflicts with external label value %q", lset, l.Name, l.Value)
+			return
+		}
+		a.builder.Del(l.Name)
+	})
+	if err != nil {
+		return err
+	}
+	lset = a.builder.Labels()
+	if lset.IsEmpty() {
+		return errors.New("series without labels other than external labels")
+	}
+
+	key := lset.String()
+	ms, ok := a.series[key]
+	if !ok {
+		ms = &memSeries{lset: lset}
+		a.series[key] = ms
+	}
+	ms.samples = append(ms.samples, s)
+	return nil
+}
+
+// write writes the collected series as a new block into the configured directory.
+func (a *blockAppender) write(ctx context.Context, logger log.Logger, conf Config) (_ ulid.ULID, err error) {
+	series := make([]*memSeries, 0, len(a.series))
+	symbols := map[string]struct{}{}
+	for _, s := range a.series {
+		series = append(series, s)
+		s.lset.Range(func(l labels.Label) {
+			symbols[l.Name] = struct{}{}
+			symbols[l.Value] = struct{}{}
+		})
+	}
+	sort.Slice(series, func(i, j int) bool { return labels.Compare(series[i].lset, series[j].lset) < 0 })
+
+	id := ulid.MustNew(ulid.Now(), rand.Reader)
+	bdir := filepath.Join(conf.Dir, id.String())
+	if err := os.MkdirAll(bdir, 0750); err != nil {
+		return id, errors.Wrap(err, "create block dir")
+	}
+	defer func() {
+		if err != nil {
+			if rerr := os.RemoveAll(bdir); rerr != nil {
+				level.Warn(logger).Log("msg", "failed to remove partially written block", "dir", bdir, "err", rerr)
+			}
+		}
+	}()
+
+	d, err := block.NewDiskWriter(ctx, logger, bdir)
+	if err != nil {
+		return id, err
+	}
+
+	sortedSymbols := make([]string, 0, len(symbols))
+	for s := range symbols {
+		sortedSymbols = append(sortedSymbols, s)
+	}
+	slices.Sort(sortedSymbols)
+	for _, s := range sortedSymbols {
+		if err := d.AddSymbol(s); err != nil {
+			return id, errors.Wrap(err, "add symbol")
+		}
+	}
+
+	var (
+		minTime int64 = math.MaxInt64
+		maxTime int64 = math.MinInt64
+	)
+	for i, s := range series {
+		chks, err := encodeChunks(s.samples)
+		if err != nil {
+			return id, errors.Wrapf(err, "encode chunks for series %s", s.lset)
+		}
+		if err := d.WriteChunks(chks...); err != nil {
+			return id, errors.Wrap(err, "write chunks")
+		}
+		if err := d.AddSeries(storage.SeriesRef(i), s.lset, chks...); err != nil {
+			return id, errors.Wrap(err, "add series")
+		}
+		minTime = min(minTime, chks[0].MinTime)
+		maxTime = max(maxTime, chks[len(chks)-1].MaxTime)
+	}
+
+	stats, err := d.Flush()
+	if err != nil {
+		return id, errors.Wrap(err, "flush")
+	}
+
+	meta := &metadata.Meta{
+		BlockMeta: tsdb.BlockMeta{
+			ULID:    id,
+			MinTime: minTime,
+			MaxTime: maxTime + 1,
+			Stats:   stats,
+			Compaction: tsdb.BlockMetaCompaction{
+				Level:   1,
+				Sources: []ulid.ULID{id},
+			},
+			Version: metadata.TSDBVersion1,
+		},
+		Thanos: metadata.Thanos{
+			Version: metadata.ThanosVersion1,
+			Labels:  conf.Labels.Map(),
+			Source:  conf.Source,
+		},
+	}
+	return id, meta.WriteToDir(logger, bdir)
+}
+
+// encodeChunks sorts the samples by timestamp, keeping the last appended sample
+// of duplicated timestamps, and encodes them into chunks. A new chunk is cut every
+// samplesPerChunk samples, on value type changes and on histogram counter resets.
+func encodeChunks(samples []sample) ([]chunks.Meta, error) {
+	slices.SortStableFunc(samples, func(a, b sample) int {
+		switch {
+		case a.t < b.t:
+			return -1
+		case a.t > b.t:
+			return 1
+		}
+		return 0
+	})
+
+	var (
+		res []chunks.Meta
+		app chunkenc.Appender
+		n   int
+	)
+	cut := func(vt chunkenc.ValueType, t int64) error {
+		c, err := chunkenc.NewEmptyChunk(vt.ChunkEncoding())
+		if err != nil {
+			return err
+		}
+		if app, err = c.Appender(); err != nil {
+			return err
+		}
+		res = append(res, chunks.Meta{Chunk: c, MinTime: t})
+		n = 0
+		return nil
+	}
+
+	for i, s := range samples {
+		if i+1 < len(samples) && samples[i+1].t == s.t {
+			continue
+		}
+
+		vt := s.valueType()
+		if len(res) == 0 || n >= samplesPerChunk || res[len(res)-1].Chunk.Encoding() != vt.ChunkEncoding() {
+			if err := cut(vt, s.t); err != nil {
+				return nil, err
+			}
+		}
+
+		var (
+			newChk  chunkenc.Chunk
+			recoded bool
+			err     error
+		)
+		switch vt {
+		case chunkenc.ValFloat:
+			app.Append(s.t, s.f)
+		case chunkenc.ValHistogram:
+			newChk, recoded, app, err = app.AppendHistogram(nil, s.t, s.h, false)
+		case chunkenc.ValFloatHistogram:
+			newChk, recoded, app, err = app.AppendFloatHistogram(nil, s.t, s.fh, false)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "append sample at %d", s.t)
+		}
+		if newChk != nil {
+			if recoded {
+				res[len(res)-1].Chunk = newChk
+			} else {
+				// Counter reset or incompatible layout, the sample was written to a new chunk.
+				res = append(res, chunks.Meta{Chunk: newChk, MinTime: s.t})
+				n = 0
+			}
+		}
+		res[len(res)-1].MaxTime = s.t
+		n++
+	}
+	return res, nil
+}
@@ -0,0 +1,296 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package backfill
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/tsdbutil"
+
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/logutil"
+)
+
+const openMetricsInput = `# TYPE http_requests counter
+http_requests_total{code="200",cluster="eu"} 1 0
+http_requests_total{code="200",cluster="eu"} 2 3600
+http_requests_total{code="200",cluster="eu"} 3 7200
+http_requests_total{code="500"} 1 7300.5
+# TYPE up gauge
+up{job="a"} 1 3600
+# EOF
+`
+
+func TestCreateBlocks_OpenMetrics(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	src, err := NewOpenMetricsSource([]byte(openMetricsInput))
+	testutil.Ok(t, err)
+	mint, maxt := src.Bounds()
+	testutil.Equals(t, int64(0), mint)
+	testutil.Equals(t, int64(7300501), maxt)
+
+	conf := Config{
+		Dir:           dir,
+		BlockDuration: time.Hour.Milliseconds(),
+		Labels:        labels.FromStrings("cluster", "eu"),
+		Source:        metadata.BucketImportSource,
+	}
+	ids, err := CreateBlocks(ctx, log.NewNopLogger(), src, mint, maxt, conf)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 3, len(ids))
+
+	for i, exp := range []struct {
+		mint, maxt int64
+		series     map[string][]float64
+	}{
+		{
+			mint: 0, maxt: 1,
+			series: map[string][]float64{`{__name__="http_requests_total", code="200"}`: {1}},
+		},
+		{
+			mint: 3600000, maxt: 3600001,
+			series: map[string][]float64{
+				`{__name__="http_requests_total", code="200"}`: {2},
+				`{__name__="up", job="a"}`:                     {1},
+			},
+		},
+		{
+			mint: 7200000, maxt: 7300501,
+			series: map[string][]float64{
+				`{__name__="http_requests_total", code="200"}`: {3},
+				`{__name__="http_requests_total", code="500"}`: {1},
+			},
+		},
+	} {
+		meta, err := metadata.ReadFromDir(filepath.Join(dir, ids[i].String()))
+		testutil.Ok(t, err)
+		testutil.Equals(t, exp.mint, meta.MinTime)
+		testutil.Equals(t, exp.maxt, meta.MaxTime)
+		testutil.Equals(t, map[string]string{"cluster": "eu"}, meta.Thanos.Labels)
+		testutil.Equals(t, metadata.BucketImportSource, meta.Thanos.Source)
+		testutil.Equals(t, []ulid.ULID{ids[i]}, meta.Compaction.Sources)
+		testutil.Equals(t, uint64(len(exp.series)), meta.Stats.NumSeries)
+
+		series := readFloats(t, filepath.Join(dir, ids[i].String()))
+		testutil.Equals(t, exp.series, series)
+	}
+
+	t.Run("conflicting external label", func(t *testing.T) {
+		conf := conf
+		conf.Dir = t.TempDir()
+		conf.Labels = labels.FromStrings("cluster", "us")
+		_, err := CreateBlocks(ctx, log.NewNopLogger(), src, mint, maxt, conf)
+		testutil.NotOk(t, err)
+	})
+	t.Run("read window", func(t *testing.T) {
+		app := newBlockAppender(3600000, 7200000, conf.Labels)
+		testutil.Ok(t, src.Read(ctx, app.mint, app.maxt, app))
+		testutil.Equals(t, 2, len(app.series))
+		testutil.Equals(t, []sample{{t: 3600000, f: 2}}, app.series[`{__name__="http_requests_total", code="200"}`].samples)
+
+		app = newBlockAppender(7300501, 7400000, conf.Labels)
+		testutil.Ok(t, src.Read(ctx, app.mint, app.maxt, app))
+		testutil.Equals(t, 0, len(app.series))
+	})
+	t.Run("missing timestamp", func(t *testing.T) {
+		_, err := NewOpenMetricsSource([]byte("up 1\n# EOF\n"))
+		testutil.NotOk(t, err)
+	})
+}
+
+func TestCreateBlocks_RemoteRead(t *testing.T) {
+	ctx := context.Background()
+
+	headOpts := tsdb.DefaultHeadOptions()
+	headOpts.ChunkDirRoot = t.TempDir()
+	head, err := tsdb.NewHead(nil, nil, nil, nil, headOpts, nil)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, head.Close()) }()
+
+	app := head.Appender(ctx)
+	for i := int64(0); i < 300; i++ {
+		_, err := app.Append(0, labels.FromStrings("__name__", "a", "replica", "0"), i*1000, float64(i))
+		testutil.Ok(t, err)
+		h := tsdbutil.GenerateTestHistogram(i % 100)
+		_, err = app.AppendHistogram(0, labels.FromStrings("__name__", "h"), i*1000, h, nil)
+		testutil.Ok(t, err)
+		_, err = app.Append(0, labels.FromStrings("__name__", "b"), i*1000, float64(i))
+		testutil.Ok(t, err)
+	}
+	testutil.Ok(t, app.Commit())
+
+	q, err := tsdb.NewBlockQuerier(tsdb.NewRangeHead(head, 0, 300000), 0, 300000)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, q.Close()) }()
+
+	src := NewRemoteReadSource(&querierReadClient{q: q}, [][]*labels.Matcher{
+		{labels.MustNewMatcher(labels.MatchRegexp, "__name__", "a|h")},
+		{labels.MustNewMatcher(labels.MatchEqual, "__name__", "h")},
+	})
+
+	dir := t.TempDir()
+	ids, err := CreateBlocks(ctx, log.NewNopLogger(), src, 0, 250000, Config{
+		Dir:           dir,
+		BlockDuration: (200 * time.Second).Milliseconds(),
+		Labels:        labels.FromStrings("replica", "0"),
+		Source:        metadata.BucketImportSource,
+	})
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(ids))
+
+	var samples, histograms int
+	for _, id := range ids {
+		b, err := tsdb.OpenBlock(logutil.GoKitLogToSlog(log.NewNopLogger()), filepath.Join(dir, id.String()), nil, tsdb.DefaultPostingsDecoderFactory)
+		testutil.Ok(t, err)
+		bq, err := tsdb.NewBlockQuerier(b, 0, 250000)
+		testutil.Ok(t, err)
+
+		ss := bq.Select(ctx, true, nil, labels.MustNewMatcher(labels.MatchRegexp, "__name__", ".+"))
+		var names []string
+		for ss.Next() {
+			s := ss.At()
+			names = append(names, s.Labels().String())
+			it := s.Iterator(nil)
+			for vt := it.Next(); vt != chunkenc.ValNone; vt = it.Next() {
+				switch vt {
+				case chunkenc.ValHistogram:
+					ts, h := it.AtHistogram(nil)
+					testutil.Assert(t, tsdbutil.GenerateTestHistogram((ts/1000)%100).Equals(h), "unexpected histogram at %d", ts)
+					histograms++
+				default:
+					ts, v := it.At()
+					testutil.Equals(t, float64(ts/1000), v)
+					samples++
+				}
+			}
+			testutil.Ok(t, it.Err())
+		}
+		testutil.Ok(t, ss.Err())
+		testutil.Equals(t, []string{`{__name__="a"}`, `{__name__="h"}`}, names)
+		testutil.Ok(t, bq.Close())
+		testutil.Ok(t, b.Close())
+	}
+	// Histogram samples are read by both selectors, but written once.
+	testutil.Equals(t, 250, samples)
+	testutil.Equals(t, 250, histograms)
+}
+
+func TestEncodeChunks(t *testing.T) {
+	var in []sample
+	for i := int64(0); i < 130; i++ {
+		in = append(in, sample{t: i, f: float64(i)})
+	}
+	// Duplicated timestamp, the last appended sample wins.
+	in = append(in, sample{t: 5, f: 50})
+	for i := int64(130); i < 140; i++ {
+		in = append(in, sample{t: i, h: tsdbutil.GenerateTestHistogram(i)})
+	}
+	// Counter reset cuts a new chunk.
+	in = append(in, sample{t: 140, h: tsdbutil.GenerateTestHistogram(0)})
+
+	chks, err := encodeChunks(in)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 4, len(chks))
+	for i, exp := range []struct {
+		mint, maxt int64
+		enc        chunkenc.Encoding
+		samples    int
+	}{
+		{mint: 0, maxt: 119, enc: chunkenc.EncXOR, samples: 120},
+		{mint: 120, maxt: 129, enc: chunkenc.EncXOR, samples: 10},
+		{mint: 130, maxt: 139, enc: chunkenc.EncHistogram, samples: 10},
+		{mint: 140, maxt: 140, enc: chunkenc.EncHistogram, samples: 1},
+	} {
+		testutil.Equals(t, exp.mint, chks[i].MinTime)
+		testutil.Equals(t, exp.maxt, chks[i].MaxTime)
+		testutil.Equals(t, exp.enc, chks[i].Chunk.Encoding())
+		testutil.Equals(t, exp.samples, chks[i].Chunk.NumSamples())
+	}
+
+	it := chks[0].Chunk.Iterator(nil)
+	for i := 0; i <= 5; i++ {
+		testutil.Equals(t, chunkenc.ValFloat, it.Next())
+	}
+	_, v := it.At()
+	testutil.Equals(t, 50.0, v)
+}
+
+func readFloats(t *testing.T, dir string) map[string][]float64 {
+	t.Helper()
+
+	b, err := tsdb.OpenBlock(logutil.GoKitLogToSlog(log.NewNopLogger()), dir, nil, tsdb.DefaultPostingsDecoderFactory)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, b.Close()) }()
+
+	q, err := tsdb.NewBlockQuerier(b, b.MinTime(), b.MaxTime())
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, q.Close()) }()
+
+	res := map[string][]float64{}
+	ss := q.Select(context.Background(), true, nil, labels.MustNewMatcher(labels.MatchRegexp, "__name__", ".+"))
+	for ss.Next() {
+		it := ss.At().Iterator(nil)
+		for it.Next() != chunkenc.ValNone {
+			_, v := it.At()
+			res[ss.At().Labels().String()] = append(res[ss.At().Labels().String()], v)
+		}
+		testutil.Ok(t, it.Err())
+	}
+	testutil.Ok(t, ss.Err())
+	return res
+}
+
+// querierReadClient serves remote read queries from a querier.
+type querierReadClient struct {
+	q storage.Querier
+}
+
+func (c *querierReadClient) Read(ctx context.Context, query *prompb.Query, sortSeries bool) (storage.SeriesSet, error) {
+	matchers, err := fromLabelMatchers(query.Matchers)
+	if err != nil {
+		return nil, err
+	}
+	// Samples outside of the queried range are ignored by the appender.
+	return c.q.Select(ctx, sortSeries, nil, matchers...), nil
+}
+
+func (c *querierReadClient) ReadMultiple(context.Context, []*prompb.Query, bool) (storage.SeriesSet, error) {
+	panic("not implemented")
+}
+
+func fromLabelMatchers(ms []*prompb.LabelMatcher) ([]*labels.Matcher, error) {
+	res := make([]*labels.Matcher, 0, len(ms))
+	for _, m := range ms {
+		var mt labels.MatchType
+		switch m.Type {
+		case prompb.LabelMatcher_EQ:
+			mt = labels.MatchEqual
+		case prompb.LabelMatcher_NEQ:
+			mt = labels.MatchNotEqual
+		case prompb.LabelMatcher_RE:
+			mt = labels.MatchRegexp
+		case prompb.LabelMatcher_NRE:
+			mt = labels.MatchNotRegexp
+		}
+		lm, err := labels.NewMatcher(mt, m.Name, m.Value)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, lm)
+	}
+	return res, nil
+}
@@ -0,0 +1,97 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package backfill
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/textparse"
+)
+
+// OpenMetricsSource reads samples from an input in the OpenMetrics text format.
+// Every sample has to have a timestamp.
+type OpenMetricsSource struct {
+	series []labels.Labels
+	// samples are sorted by timestamp, keeping the input order of equal timestamps.
+	samples []openMetricsSample
+}
+
+type openMetricsSample struct {
+	ref int
+	t   int64
+	v   float64
+}
+
+// NewOpenMetricsSource parses the input once and keeps its samples ordered by
+// timestamp, so that the samples of every block are found without parsing the
+// input again. The input is not referenced after it returns.
+func NewOpenMetricsSource(input []byte) (*OpenMetricsSource, error) {
+	var (
+		s    = &OpenMetricsSource{}
+		refs = map[string]int{}
+		p    = textparse.NewOpenMetricsParser(input, labels.NewSymbolTable())
+	)
+	for {
+		e, err := p.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "parse")
+		}
+		if e != textparse.EntrySeries {
+			continue
+		}
+
+		var lset labels.Labels
+		p.Labels(&lset)
+		_, ts, v := p.Series()
+		if ts == nil {
+			return nil, errors.Errorf("expected timestamp for series %s, got none", lset)
+		}
+
+		key := lset.String()
+		ref, ok := refs[key]
+		if !ok {
+			ref = len(s.series)
+			refs[key] = ref
+			s.series = append(s.series, lset)
+		}
+		s.samples = append(s.samples, openMetricsSample{ref: ref, t: *ts, v: v})
+	}
+	if len(s.samples) == 0 {
+		return nil, errors.New("no samples found in input")
+	}
+	slices.SortStableFunc(s.samples, func(a, b openMetricsSample) int {
+		switch {
+		case a.t < b.t:
+			return -1
+		case a.t > b.t:
+			return 1
+		}
+		return 0
+	})
+	return s, nil
+}
+
+// Bounds returns the time range [mint, maxt) of all samples in the input.
+func (s *OpenMetricsSource) Bounds() (mint, maxt int64) {
+	return s.samples[0].t, s.samples[len(s.samples)-1].t + 1
+}
+
+// Read implements Source.
+func (s *OpenMetricsSource) Read(_ context.Context, mint, maxt int64, app Appender) error {
+	i := sort.Search(len(s.samples), func(i int) bool { return s.samples[i].t >= mint })
+	for ; i < len(s.samples) && s.samples[i].t < maxt; i++ {
+		if err := app.Append(s.series[s.samples[i].ref], s.samples[i].t, s.samples[i].v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package backfill
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage/remote"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+)
+
+// RemoteReadSource reads samples from a Prometheus remote read endpoint.
+type RemoteReadSource struct {
+	client    remote.ReadClient
+	selectors [][]*labels.Matcher
+}
+
+// NewRemoteReadSource returns a source reading all series matching any of the
+// given selectors through the client.
+func NewRemoteReadSource(client remote.ReadClient, selectors [][]*labels.Matcher) *RemoteReadSource {
+	return &RemoteReadSource{client: client, selectors: selectors}
+}
+
+// Read implements Source. Every selector is read with a separate query covering
+// the whole range, samples of series matched by multiple selectors are deduplicated
+// by the appender.
+func (s *RemoteReadSource) Read(ctx context.Context, mint, maxt int64, app Appender) error {
+	for _, matchers := range s.selectors {
+		// Remote read time ranges are inclusive.
+		q, err := remote.ToQuery(mint, maxt-1, matchers, nil)
+		if err != nil {
+			return errors.Wrap(err, "create query")
+		}
+		ss, err := s.client.Read(ctx, q, false)
+		if err != nil {
+			return errors.Wrap(err, "remote read")
+		}
+
+		var it chunkenc.Iterator
+		for ss.Next() {
+			series := ss.At()
+			lset := series.Labels()
+			it = series.Iterator(it)
+			for vt := it.Next(); vt != chunkenc.ValNone; vt = it.Next() {
+				switch vt {
+				case chunkenc.ValFloat:
+					t, v := it.At()
+					err = app.Append(lset, t, v)
+				case chunkenc.ValHistogram:
+					t, h := it.AtHistogram(nil)
+					err = app.AppendHistogram(lset, t, h, nil)
+				case chunkenc.ValFloatHistogram:
+					t, fh := it.AtFloatHistogram(nil)
+					err = app.AppendHistogram(lset, t, nil, fh)
+				default:
+					err = errors.Errorf("unsupported value type %v", vt)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			if err := it.Err(); err != nil {
+				return errors.Wrapf(err, "iterate series %s", lset)
+			}
+		}
+		if err := ss.Err(); err != nil {
+			return errors.Wrap(err, "remote read series")
+		}
+	}
+	return nil
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
 	"github.com/prometheus/prometheus/tsdb/chunks"
 	"github.com/prometheus/prometheus/tsdb/index"
 
@@ -63,6 +64,8 @@ type HealthStats struct {
 	// OutOfOrderLabels represents the number of postings that contained out
 	// of order labels, a bug present in Prometheus 2.8.0 and below.
 	OutOfOrderLabels int
+	// DuplicatedSeries represents the number of series that have the same (sorted) labels as the series before them.
+	DuplicatedSeries int
 
 	// Debug Statistics.
 	SeriesMinLifeDuration time.Duration
@@ -109,6 +112,14 @@ func (i HealthStats) OutOfOrderLabelsErr() error {
 	return nil
 }
 
+// DuplicatedSeriesErr returns an error if the HealthStats object indicates series with duplicated labels.
+func (i HealthStats) DuplicatedSeriesErr() error {
+	if i.DuplicatedSeries > 0 {
+		return errors.Errorf("index contains %d duplicated series", i.DuplicatedSeries)
+	}
+	return nil
+}
+
 // Issue347OutsideChunksErr returns error if stats indicates issue347 block issue, that is repaired explicitly before compaction (on plan block).
 func (i HealthStats) Issue347OutsideChunksErr() error {
 	if i.Issue347OutsideChunks > 0 {
@@ -153,6 +164,14 @@ func (i HealthStats) CriticalErr() error {
 
 // AnyErr returns error if stats indicates any block issue.
 func (i HealthStats) AnyErr() error {
+	if issues := i.Issues(); len(issues) > 0 {
+		return errors.New(strings.Join(issues, ", "))
+	}
+	return nil
+}
+
+// Issues returns the descriptions of all block issues indicated by stats.
+func (i HealthStats) Issues() []string {
 	var errMsg []string
 
 	if err := i.CriticalErr(); err != nil {
@@ -167,15 +186,15 @@ func (i HealthStats) AnyErr() error {
 		errMsg = append(errMsg, err.Error())
 	}
 
-	if err := i.OutOfOrderChunksErr(); err != nil {
+	if err := i.DuplicatedSeriesErr(); err != nil {
 		errMsg = append(errMsg, err.Error())
 	}
 
-	if len(errMsg) > 0 {
-		return errors.New(strings.Join(errMsg, ", "))
+	if err := i.OutOfOrderChunksErr(); err != nil {
+		errMsg = append(errMsg, err.Error())
 	}
 
-	return nil
+	return errMsg
 }
 
 type minMaxSumInt64 struct {
@@ -228,9 +247,11 @@ func GatherIndexHealthStats(ctx context.Context, logger log.Logger, fn string, m
 		return stats, errors.Wrap(err, "get all postings")
 	}
 	var (
-		lset     labels.Labels
-		prevLset labels.Labels
-		builder  labels.ScratchBuilder
+		lset       labels.Labels
+		prevLset   labels.Labels
+		sorted     labels.Labels
+		prevSorted labels.Labels
+		builder    labels.ScratchBuilder
 
 		chks []chunks.Meta
 
@@ -266,6 +287,7 @@ func GatherIndexHealthStats(ctx context.Context, logger log.Logger, fn string, m
 	var prevId storage.SeriesRef
 	for p.Next() {
 		prevLset.CopyFrom(lset)
+		prevSorted.CopyFrom(sorted)
 
 		id := p.At()
 		if prevId != 0 {
@@ -282,9 +304,16 @@ func GatherIndexHealthStats(ctx context.Context, logger log.Logger, fn string, m
 		if lset.IsEmpty() {
 			return stats, errors.Errorf("empty label set detected for series %d", id)
 		}
-		if !prevLset.IsEmpty() && labels.Compare(prevLset, lset) >= 0 {
+		if !prevLset.IsEmpty() && labels.Compare(prevLset, lset) > 0 {
 			return stats, errors.Errorf("series %v out of order; previous %v", lset, prevLset)
 		}
+		// Series with out of order labels may become duplicates once their labels are sorted.
+		builder.Sort()
+		sorted = builder.Labels()
+		if !prevSorted.IsEmpty() && labels.Compare(prevSorted, sorted) == 0 {
+			stats.DuplicatedSeries++
+			level.Warn(logger).Log("msg", "duplicated series", "labelset", sorted.String(), "series", fmt.Sprintf("%d", id))
+		}
 		var l0 *labels.Label
 		lset.Range(func(l labels.Label) {
 			if l0 != nil {
@@ -413,6 +442,17 @@ type ignoreFnType func(mint, maxt int64, prev *chunks.Meta, curr *chunks.Meta) (
 // Fixable inconsistencies are resolved in the new block.
 // TODO(bplotka): https://github.com/thanos-io/thanos/issues/378.
 func Repair(ctx context.Context, logger log.Logger, dir string, id ulid.ULID, source metadata.SourceType, ignoreChkFns ...ignoreFnType) (resid ulid.ULID, err error) {
+	return RepairWithOptions(ctx, logger, dir, id, source, RepairOptions{}, ignoreChkFns...)
+}
+
+// RepairOptions specifies additional fixes applied by RepairWithOptions.
+type RepairOptions struct {
+	// TrimOutsideChunks re-encodes chunks that are partially outside of the block time range with only the samples within it.
+	TrimOutsideChunks bool
+}
+
+// RepairWithOptions works like Repair, but additionally applies the fixes enabled in opts.
+func RepairWithOptions(ctx context.Context, logger log.Logger, dir string, id ulid.ULID, source metadata.SourceType, opts RepairOptions, ignoreChkFns ...ignoreFnType) (resid ulid.ULID, err error) {
 	if len(ignoreChkFns) == 0 {
 		return resid, errors.New("no ignore chunk function specified")
 	}
@@ -468,7 +508,7 @@ func Repair(ctx context.Context, logger log.Logger, dir string, id ulid.ULID, so
 	resmeta.Stats = tsdb.BlockStats{} // Reset stats.
 	resmeta.Thanos.Source = source    // Update source.
 
-	if err := rewrite(ctx, logger, indexr, chunkr, indexw, chunkw, &resmeta, ignoreChkFns); err != nil {
+	if err := rewrite(ctx, logger, indexr, chunkr, indexw, chunkw, &resmeta, opts, ignoreChkFns); err != nil {
 		return resid, errors.Wrap(err, "rewrite block")
 	}
 	resmeta.Thanos.SegmentFiles = GetSegmentFiles(resdir)
@@ -566,6 +606,81 @@ OUTER:
 	return repl, nil
 }
 
+// trimOutsideChunks replaces chunks that are partially outside of [mint, maxt) with chunks that contain only
+// the samples within it. Chunks left without samples are dropped.
+func trimOutsideChunks(chks []chunks.Meta, mint, maxt int64) ([]chunks.Meta, error) {
+	repl := chks[:0]
+	for _, c := range chks {
+		if c.MinTime >= mint && c.MaxTime < maxt {
+			repl = append(repl, c)
+			continue
+		}
+		trimmed, err := trimChunk(c.Chunk, mint, maxt)
+		if err != nil {
+			return nil, err
+		}
+		if trimmed.Chunk.NumSamples() == 0 {
+			continue
+		}
+		repl = append(repl, trimmed)
+	}
+	return repl, nil
+}
+
+// trimChunk re-encodes the chunk with only the samples within [mint, maxt).
+func trimChunk(chk chunkenc.Chunk, mint, maxt int64) (chunks.Meta, error) {
+	res := chunks.Meta{MinTime: math.MaxInt64, MaxTime: math.MinInt64}
+
+	var err error
+	if res.Chunk, err = chunkenc.NewEmptyChunk(chk.Encoding()); err != nil {
+		return res, err
+	}
+	app, err := res.Chunk.Appender()
+	if err != nil {
+		return res, err
+	}
+
+	it := chk.Iterator(nil)
+	for vt := it.Next(); vt != chunkenc.ValNone; vt = it.Next() {
+		t := it.AtT()
+		if t < mint || t >= maxt {
+			continue
+		}
+
+		var (
+			newChk  chunkenc.Chunk
+			recoded bool
+		)
+		switch vt {
+		case chunkenc.ValFloat:
+			_, v := it.At()
+			app.Append(t, v)
+		case chunkenc.ValHistogram:
+			_, h := it.AtHistogram(nil)
+			newChk, recoded, app, err = app.AppendHistogram(nil, t, h, false)
+		case chunkenc.ValFloatHistogram:
+			_, fh := it.AtFloatHistogram(nil)
+			newChk, recoded, app, err = app.AppendFloatHistogram(nil, t, fh, false)
+		default:
+			return res, errors.Errorf("unsupported value type %v", vt)
+		}
+		if err != nil {
+			return res, errors.Wrap(err, "append sample")
+		}
+		if newChk != nil {
+			// Samples of a single chunk never require cutting a new one, only recoding the current one.
+			if !recoded {
+				return res, errors.Errorf("unexpected new chunk at %d", t)
+			}
+			res.Chunk = newChk
+		}
+
+		res.MinTime = min(res.MinTime, t)
+		res.MaxTime = max(res.MaxTime, t)
+	}
+	return res, it.Err()
+}
+
 type seriesRepair struct {
 	lset labels.Labels
 	chks []chunks.Meta
@@ -579,6 +694,7 @@ func rewrite(
 	indexr tsdb.IndexReader, chunkr tsdb.ChunkReader,
 	indexw tsdb.IndexWriter, chunkw tsdb.ChunkWriter,
 	meta *metadata.Meta,
+	opts RepairOptions,
 	ignoreChkFns []ignoreFnType,
 ) error {
 	symbols := indexr.Symbols()
@@ -629,6 +745,12 @@ func rewrite(
 		if err != nil {
 			return err
 		}
+		if opts.TrimOutsideChunks {
+			chks, err = trimOutsideChunks(chks, meta.MinTime, meta.MaxTime)
+			if err != nil {
+				return errors.Wrapf(err, "trim chunks of series %v", builder.Labels())
+			}
+		}
 
 		if len(chks) == 0 {
 			continue
@@ -650,22 +772,16 @@ func rewrite(
 		return labels.Compare(series[i].lset, series[j].lset) < 0
 	})
 
-	lastSet := labels.Labels{}
+	// The TSDB library will throw an error if we add a series with
+	// identical labels as the last series. This means that we have
+	// discovered a duplicate time series in the old block.
+	series, err = mergeDuplicateSeries(logger, series)
+	if err != nil {
+		return err
+	}
+
 	// Build a new TSDB block.
 	for _, s := range series {
-		// The TSDB library will throw an error if we add a series with
-		// identical labels as the last series. This means that we have
-		// discovered a duplicate time series in the old block. We drop
-		// all duplicate series preserving the first one.
-		// TODO: Add metric to count dropped series if repair becomes a daemon
-		// rather than a batch job.
-		if labels.Compare(lastSet, s.lset) == 0 {
-			level.Warn(logger).Log("msg",
-				"dropping duplicate series in tsdb block found",
-				"labelset", s.lset.String(),
-			)
-			continue
-		}
 		if err := chunkw.WriteChunks(s.chks...); err != nil {
 			return errors.Wrap(err, "write chunks")
 		}
@@ -690,11 +806,50 @@ func rewrite(
 		})
 		postings.Add(i, s.lset)
 		i++
-		lastSet = s.lset
 	}
 	return nil
 }
 
+// mergeDuplicateSeries merges the chunks of sorted series with identical labels into the first of them.
+// Overlapping chunks are merged sample by sample, so no samples are lost.
+func mergeDuplicateSeries(logger log.Logger, series []seriesRepair) ([]seriesRepair, error) {
+	var (
+		res    = series[:0]
+		merger = storage.NewCompactingChunkSeriesMerger(storage.ChainedSeriesMerge)
+	)
+	for i := 0; i < len(series); {
+		j := i + 1
+		for ; j < len(series) && labels.Compare(series[i].lset, series[j].lset) == 0; j++ {
+		}
+		if j == i+1 {
+			res = append(res, series[i])
+			i = j
+			continue
+		}
+
+		level.Warn(logger).Log("msg", "merging duplicate series in tsdb block found",
+			"labelset", series[i].lset.String(),
+			"duplicates", j-i,
+		)
+		dups := make([]storage.ChunkSeries, 0, j-i)
+		for _, s := range series[i:j] {
+			dups = append(dups, &storage.ChunkSeriesEntry{
+				Lset: s.lset,
+				ChunkIteratorFn: func(chunks.Iterator) chunks.Iterator {
+					return storage.NewListChunkSeriesIterator(s.chks...)
+				},
+			})
+		}
+		chks, err := storage.ExpandChunks(merger(dups...).Iterator(nil))
+		if err != nil {
+			return nil, errors.Wrapf(err, "merge duplicate series %v", series[i].lset)
+		}
+		res = append(res, seriesRepair{lset: series[i].lset, chks: chks})
+		i = j
+	}
+	return res, nil
+}
+
 type stringset map[string]struct{}
 
 func (ss stringset) set(s string) {
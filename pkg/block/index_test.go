@@ -12,7 +12,9 @@ import (
 
 	"github.com/go-kit/log"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
 	"github.com/prometheus/prometheus/tsdb/chunks"
 	"github.com/prometheus/prometheus/tsdb/index"
 
@@ -62,7 +64,7 @@ func TestRewrite(t *testing.T) {
 
 	defer cw.Close()
 
-	testutil.Ok(t, rewrite(ctx, log.NewNopLogger(), ir, cr, iw, cw, m, []ignoreFnType{func(mint, maxt int64, prev *chunks.Meta, curr *chunks.Meta) (bool, error) {
+	testutil.Ok(t, rewrite(ctx, log.NewNopLogger(), ir, cr, iw, cw, m, RepairOptions{}, []ignoreFnType{func(mint, maxt int64, prev *chunks.Meta, curr *chunks.Meta) (bool, error) {
 		return curr.MaxTime == 696, nil
 	}}))
 
@@ -96,3 +98,106 @@ func TestGatherIndexHealthStatsReturnsOutOfOrderChunksErr(t *testing.T) {
 	testutil.Equals(t, 1, stats.OutOfOrderChunks)
 	testutil.NotOk(t, stats.OutOfOrderChunksErr())
 }
+
+func TestRewrite_DuplicatedSeries(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+
+	// Second series has the same labels as the first one, but out of order.
+	var builder labels.ScratchBuilder
+	builder.Add("a", "1")
+	builder.Add("b", "1")
+	sorted := builder.Labels()
+	builder.Reset()
+	builder.Add("b", "1")
+	builder.Add("a", "1")
+	unsorted := builder.Labels()
+
+	chunk := func(start, n int) chunks.Meta {
+		chk, err := chunks.ChunkFromSamples(chunks.GenerateSamples(start, n))
+		testutil.Ok(t, err)
+		return chk
+	}
+	input := []seriesRepair{
+		{lset: sorted, chks: []chunks.Meta{chunk(0, 10), chunk(20, 10)}},
+		// Overlaps with the first chunk of the first series.
+		{lset: unsorted, chks: []chunks.Meta{chunk(5, 10)}},
+	}
+
+	srcDir := filepath.Join(tmpDir, "src")
+	cw, err := chunks.NewWriter(filepath.Join(srcDir, ChunksDirname))
+	testutil.Ok(t, err)
+	iw, err := index.NewWriter(ctx, filepath.Join(srcDir, IndexFilename))
+	testutil.Ok(t, err)
+	for _, s := range []string{"1", "a", "b"} {
+		testutil.Ok(t, iw.AddSymbol(s))
+	}
+	for i, s := range input {
+		testutil.Ok(t, cw.WriteChunks(s.chks...))
+		testutil.Ok(t, iw.AddSeries(storage.SeriesRef(i), s.lset, s.chks...))
+	}
+	testutil.Ok(t, cw.Close())
+	testutil.Ok(t, iw.Close())
+
+	ir, err := index.NewFileReader(filepath.Join(srcDir, IndexFilename), index.DecodePostingsRaw)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, ir.Close()) }()
+	cr, err := chunks.NewDirReader(filepath.Join(srcDir, ChunksDirname), nil)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, cr.Close()) }()
+
+	m := &metadata.Meta{BlockMeta: tsdb.BlockMeta{ULID: ULID(1), MinTime: 0, MaxTime: 100}}
+	resDir := filepath.Join(tmpDir, m.ULID.String())
+	cw, err = chunks.NewWriter(filepath.Join(resDir, ChunksDirname))
+	testutil.Ok(t, err)
+	iw, err = index.NewWriter(ctx, filepath.Join(resDir, IndexFilename))
+	testutil.Ok(t, err)
+
+	testutil.Ok(t, rewrite(ctx, log.NewNopLogger(), ir, cr, iw, cw, m, RepairOptions{}, []ignoreFnType{IgnoreDuplicateOutsideChunk}))
+	testutil.Ok(t, iw.Close())
+	testutil.Ok(t, cw.Close())
+
+	testutil.Equals(t, uint64(1), m.Stats.NumSeries)
+	testutil.Equals(t, uint64(25), m.Stats.NumSamples)
+
+	ir2, err := index.NewFileReader(filepath.Join(resDir, IndexFilename), index.DecodePostingsRaw)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, ir2.Close()) }()
+	cr2, err := chunks.NewDirReader(filepath.Join(resDir, ChunksDirname), nil)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, cr2.Close()) }()
+
+	key, value := index.AllPostingsKey()
+	all, err := ir2.Postings(ctx, key, value)
+	testutil.Ok(t, err)
+
+	var (
+		res  []labels.Labels
+		ts   []int64
+		chks []chunks.Meta
+	)
+	for all.Next() {
+		testutil.Ok(t, ir2.Series(all.At(), &builder, &chks))
+		res = append(res, builder.Labels())
+		for _, c := range chks {
+			chk, _, err := cr2.ChunkOrIterable(c)
+			testutil.Ok(t, err)
+			it := chk.Iterator(nil)
+			for it.Next() != chunkenc.ValNone {
+				ts = append(ts, it.AtT())
+			}
+			testutil.Ok(t, it.Err())
+		}
+	}
+	testutil.Ok(t, all.Err())
+	testutil.Equals(t, []labels.Labels{sorted}, res)
+
+	var expected []int64
+	for i := int64(0); i <= 14; i++ {
+		expected = append(expected, i)
+	}
+	for i := int64(20); i <= 29; i++ {
+		expected = append(expected, i)
+	}
+	testutil.Equals(t, expected, ts)
+}
@@ -14,6 +14,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/go-kit/log"
@@ -44,6 +45,7 @@ const (
 	BucketRepairSource    SourceType = "bucket.repair"
 	BucketRewriteSource   SourceType = "bucket.rewrite"
 	BucketUploadSource    SourceType = "bucket.upload"
+	BucketImportSource    SourceType = "bucket.import"
 	TestSource            SourceType = "test"
 )
 
@@ -83,6 +85,10 @@ type Thanos struct {
 	Labels     map[string]string `json:"labels"`
 	Downsample ThanosDownsample  `json:"downsample"`
 
+	// Shard is present when the block holds only a shard of the series of its compaction group,
+	// as produced by split compaction. Optional.
+	Shard *ThanosShard `json:"shard,omitempty"`
+
 	// Source is a real upload source of the block.
 	Source SourceType `json:"source"`
 
@@ -99,6 +105,9 @@ type Thanos struct {
 	// Rewrites is present when any rewrite (deletion, relabel etc) were applied to this block. Optional.
 	Rewrites []Rewrite `json:"rewrites,omitempty"`
 
+	// Repairs is present when the block was produced by repairing broken blocks. Optional.
+	Repairs []Repair `json:"repairs,omitempty"`
+
 	// IndexStats contains stats info related to block index.
 	IndexStats IndexStats `json:"index_stats,omitempty"`
 
@@ -110,6 +119,23 @@ type Thanos struct {
 	UploadTime time.Time `json:"upload_time,omitempty"`
 }
 
+// ThanosShard identifies a shard of series split by their label hash.
+type ThanosShard struct {
+	// Index of the shard, from 0 to Count-1.
+	Index uint64 `json:"index"`
+	// Count is the total number of shards.
+	Count uint64 `json:"count"`
+}
+
+// Contains returns true if the series with the given labels belongs to the shard.
+func (s ThanosShard) Contains(lset labels.Labels) bool {
+	return labels.StableHash(lset)%s.Count == s.Index
+}
+
+func (s ThanosShard) String() string {
+	return fmt.Sprintf("%d_of_%d", s.Index, s.Count)
+}
+
 type IndexStats struct {
 	SeriesMaxSize int64 `json:"series_max_size,omitempty"`
 	ChunkMaxSize  int64 `json:"chunk_max_size,omitempty"`
@@ -144,6 +170,16 @@ type Rewrite struct {
 	RelabelsApplied []*relabel.Config `json:"relabels_applied,omitempty"`
 }
 
+// Repair is an audit record of a repair that produced the block.
+type Repair struct {
+	// ULID of the repaired block, which is marked for deletion after the repair.
+	Source ulid.ULID `json:"source"`
+	// Time of the repair.
+	Time time.Time `json:"time"`
+	// Issues that were found in the repaired block.
+	Issues []string `json:"issues"`
+}
+
 type Matchers []*labels.Matcher
 
 func (m *Matchers) UnmarshalYAML(value *yaml.Node) (err error) {
@@ -171,6 +207,18 @@ type File struct {
 
 type ThanosDownsample struct {
 	Resolution int64 `json:"resolution"`
+	// Levels is the downsampling ladder the block was downsampled with. It is empty for raw blocks
+	// and for the default ladder of 5m and 1h. Downsampled blocks are downsampled further following their ladder.
+	Levels []DownsampleLevel `json:"levels,omitempty"`
+}
+
+// DownsampleLevel is a level of a downsampling ladder.
+type DownsampleLevel struct {
+	// Resolution of the level in milliseconds.
+	Resolution int64 `json:"resolution"`
+	// MinBlockRange is the minimum time range in milliseconds a block of the previous level has to cover
+	// before it is downsampled to this level.
+	MinBlockRange int64 `json:"min_block_range"`
 }
 
 // InjectThanos sets Thanos meta to the block meta JSON and saves it to the disk.
@@ -195,9 +243,20 @@ func InjectThanos(logger log.Logger, bdir string, meta Thanos, downsampledMeta *
 }
 
 // GroupKey returns a unique identifier for the compaction group the block belongs to.
-// It considers the downsampling resolution and the block's labels.
+// It considers the downsampling resolution, the block's labels, its downsampling ladder and its shard, if any.
 func (m *Thanos) GroupKey() string {
-	return fmt.Sprintf("%d@%v", m.Downsample.Resolution, labels.FromMap(m.Labels).Hash())
+	key := fmt.Sprintf("%d@%v", m.Downsample.Resolution, labels.FromMap(m.Labels).Hash())
+	if len(m.Downsample.Levels) > 0 {
+		levels := make([]string, 0, len(m.Downsample.Levels))
+		for _, l := range m.Downsample.Levels {
+			levels = append(levels, fmt.Sprintf("%d_%d", l.Resolution, l.MinBlockRange))
+		}
+		key += "@" + strings.Join(levels, "-")
+	}
+	if m.Shard != nil {
+		key += "@" + m.Shard.String()
+	}
+	return key
 }
 
 // ResolutionString returns a the block's resolution as a string.
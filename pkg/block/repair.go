@@ -0,0 +1,165 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package block
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/index"
+
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+// ReconstructMeta builds the meta of the block in bdir from its index and chunks, e.g. when its meta.json file is lost.
+// The block is assumed to be a raw, not compacted block with the given external labels.
+func ReconstructMeta(ctx context.Context, bdir string, extLset map[string]string, source metadata.SourceType) (_ *metadata.Meta, err error) {
+	id, err := ulid.Parse(filepath.Base(bdir))
+	if err != nil {
+		return nil, errors.Wrap(err, "not a block dir")
+	}
+
+	indexr, err := index.NewFileReader(filepath.Join(bdir, IndexFilename), index.DecodePostingsRaw)
+	if err != nil {
+		return nil, errors.Wrap(err, "open index file")
+	}
+	defer runutil.CloseWithErrCapture(&err, indexr, "reconstruct meta index reader")
+
+	chunkr, err := chunks.NewDirReader(filepath.Join(bdir, ChunksDirname), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "open chunks dir")
+	}
+	defer runutil.CloseWithErrCapture(&err, chunkr, "reconstruct meta chunk reader")
+
+	key, value := index.AllPostingsKey()
+	p, err := indexr.Postings(ctx, key, value)
+	if err != nil {
+		return nil, errors.Wrap(err, "get all postings")
+	}
+
+	var (
+		builder labels.ScratchBuilder
+		chks    []chunks.Meta
+		stats   tsdb.BlockStats
+
+		mint int64 = math.MaxInt64
+		maxt int64 = math.MinInt64
+	)
+	for p.Next() {
+		if err := indexr.Series(p.At(), &builder, &chks); err != nil {
+			return nil, errors.Wrap(err, "read series")
+		}
+		stats.NumSeries++
+
+		for _, c := range chks {
+			chk, _, err := chunkr.ChunkOrIterable(c)
+			if err != nil {
+				return nil, errors.Wrapf(err, "read chunk %d", c.Ref)
+			}
+			if !chunkenc.IsValidEncoding(chk.Encoding()) {
+				return nil, errors.Errorf("unsupported chunk encoding %v, only raw blocks are supported", chk.Encoding())
+			}
+
+			n := uint64(chk.NumSamples())
+			stats.NumChunks++
+			stats.NumSamples += n
+			if chk.Encoding() == chunkenc.EncXOR {
+				stats.NumFloatSamples += n
+			} else {
+				stats.NumHistogramSamples += n
+			}
+			mint = min(mint, c.MinTime)
+			maxt = max(maxt, c.MaxTime)
+		}
+	}
+	if err := p.Err(); err != nil {
+		return nil, errors.Wrap(err, "walk postings")
+	}
+	if stats.NumChunks == 0 {
+		return nil, errors.New("block has no chunks, cannot reconstruct its time range")
+	}
+
+	return &metadata.Meta{
+		BlockMeta: tsdb.BlockMeta{
+			ULID:    id,
+			MinTime: mint,
+			// The block max time is exclusive.
+			MaxTime: maxt + 1,
+			Stats:   stats,
+			Version: metadata.TSDBVersion1,
+			Compaction: tsdb.BlockMetaCompaction{
+				Level:   1,
+				Sources: []ulid.ULID{id},
+			},
+		},
+		Thanos: metadata.Thanos{
+			Version:      metadata.ThanosVersion1,
+			Labels:       extLset,
+			Source:       source,
+			SegmentFiles: GetSegmentFiles(bdir),
+		},
+	}, nil
+}
+
+// CheckFileStats verifies that the files of the block in bdir match the sizes and hashes recorded in its meta.
+func CheckFileStats(bdir string, files []metadata.File, logger log.Logger) error {
+	actual, err := GatherFileStats(bdir, metadata.NoneFunc, logger)
+	if err != nil {
+		return err
+	}
+	recorded := make(map[string]struct{}, len(files))
+	for _, f := range files {
+		recorded[f.RelPath] = struct{}{}
+	}
+
+	var errMsg []string
+	for _, f := range actual {
+		if _, ok := recorded[f.RelPath]; !ok {
+			errMsg = append(errMsg, "file "+f.RelPath+" is not recorded in meta")
+		}
+	}
+	for _, f := range files {
+		if f.RelPath == MetaFilename {
+			continue
+		}
+		fi, err := os.Stat(filepath.Join(bdir, f.RelPath))
+		if os.IsNotExist(err) {
+			errMsg = append(errMsg, "file "+f.RelPath+" is missing")
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "stat %v", f.RelPath)
+		}
+		if fi.Size() != f.SizeBytes {
+			errMsg = append(errMsg, "size of file "+f.RelPath+" does not match meta")
+			continue
+		}
+		if f.Hash == nil || f.Hash.Func == metadata.NoneFunc {
+			continue
+		}
+		h, err := metadata.CalculateHash(filepath.Join(bdir, f.RelPath), f.Hash.Func, logger)
+		if err != nil {
+			return errors.Wrapf(err, "calculate hash %v", f.RelPath)
+		}
+		if !f.Hash.Equal(&h) {
+			errMsg = append(errMsg, "hash of file "+f.RelPath+" does not match meta")
+		}
+	}
+
+	if len(errMsg) > 0 {
+		return errors.New(strings.Join(errMsg, ", "))
+	}
+	return nil
+}
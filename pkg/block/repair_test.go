@@ -0,0 +1,114 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package block
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/tsdbutil"
+
+	"github.com/efficientgo/core/testutil"
+
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/testutil/e2eutil"
+)
+
+func TestTrimOutsideChunks(t *testing.T) {
+	t.Parallel()
+
+	floats := chunkenc.NewXORChunk()
+	app, err := floats.Appender()
+	testutil.Ok(t, err)
+	for ts := int64(0); ts < 10; ts++ {
+		app.Append(ts*10, float64(ts))
+	}
+
+	histograms := chunkenc.NewHistogramChunk()
+	app, err = histograms.Appender()
+	testutil.Ok(t, err)
+	for i, h := range tsdbutil.GenerateTestHistograms(10) {
+		_, _, app, err = app.AppendHistogram(nil, int64(i)*10, h, true)
+		testutil.Ok(t, err)
+	}
+
+	outside := chunkenc.NewXORChunk()
+	app, err = outside.Appender()
+	testutil.Ok(t, err)
+	app.Append(0, 1)
+	app.Append(10, 2)
+
+	inside := chunks.Meta{MinTime: 30, MaxTime: 40, Chunk: chunkenc.NewXORChunk()}
+	res, err := trimOutsideChunks([]chunks.Meta{
+		{MinTime: 0, MaxTime: 90, Chunk: floats},
+		inside,
+		{MinTime: 0, MaxTime: 90, Chunk: histograms},
+		{MinTime: 0, MaxTime: 10, Chunk: outside},
+	}, 25, 65)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 3, len(res))
+
+	testutil.Equals(t, inside, res[1])
+	for _, c := range []chunks.Meta{res[0], res[2]} {
+		testutil.Equals(t, int64(30), c.MinTime)
+		testutil.Equals(t, int64(60), c.MaxTime)
+		testutil.Equals(t, 4, c.Chunk.NumSamples())
+
+		it := c.Chunk.Iterator(nil)
+		for vt := it.Next(); vt != chunkenc.ValNone; vt = it.Next() {
+			testutil.Assert(t, it.AtT() >= 30 && it.AtT() <= 60, "sample %d outside of range", it.AtT())
+		}
+		testutil.Ok(t, it.Err())
+	}
+	testutil.Equals(t, chunkenc.EncHistogram, res[2].Chunk.Encoding())
+}
+
+func TestReconstructMetaAndCheckFileStats(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	extLset := labels.FromStrings("ext", "1")
+
+	id, err := e2eutil.CreateBlock(ctx, dir, []labels.Labels{labels.FromStrings("a", "1"), labels.FromStrings("a", "2")}, 100, 0, 1000, extLset, 0, metadata.NoneFunc, nil)
+	testutil.Ok(t, err)
+	bdir := filepath.Join(dir, id.String())
+
+	orig, err := metadata.ReadFromDir(bdir)
+	testutil.Ok(t, err)
+	files, err := GatherFileStats(bdir, metadata.SHA256Func, log.NewNopLogger())
+	testutil.Ok(t, err)
+	testutil.Ok(t, CheckFileStats(bdir, files, log.NewNopLogger()))
+
+	meta, err := ReconstructMeta(ctx, bdir, extLset.Map(), metadata.BucketRepairSource)
+	testutil.Ok(t, err)
+	testutil.Equals(t, id, meta.ULID)
+	testutil.Equals(t, orig.MinTime, meta.MinTime)
+	testutil.Equals(t, orig.Stats.NumSeries, meta.Stats.NumSeries)
+	testutil.Equals(t, orig.Stats.NumChunks, meta.Stats.NumChunks)
+	testutil.Equals(t, orig.Stats.NumSamples, meta.Stats.NumSamples)
+	testutil.Equals(t, extLset.Map(), meta.Thanos.Labels)
+	testutil.Equals(t, metadata.BucketRepairSource, meta.Thanos.Source)
+
+	// Corrupt the index file.
+	f, err := os.OpenFile(filepath.Join(bdir, IndexFilename), os.O_WRONLY, 0)
+	testutil.Ok(t, err)
+	_, err = f.WriteAt([]byte{0xff}, 100)
+	testutil.Ok(t, err)
+	testutil.Ok(t, f.Close())
+	testutil.NotOk(t, CheckFileStats(bdir, files, log.NewNopLogger()))
+
+	// Add a file not recorded in meta.
+	files, err = GatherFileStats(bdir, metadata.SHA256Func, log.NewNopLogger())
+	testutil.Ok(t, err)
+	testutil.Ok(t, CheckFileStats(bdir, files, log.NewNopLogger()))
+	testutil.Ok(t, os.WriteFile(filepath.Join(bdir, ChunksDirname, "000002"), []byte{}, os.ModePerm))
+	testutil.NotOk(t, CheckFileStats(bdir, files, log.NewNopLogger()))
+}
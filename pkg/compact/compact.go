@@ -8,8 +8,11 @@ import (
 	"fmt"
 	"maps"
 	"math"
+	"math/rand"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -131,19 +134,15 @@ func NewMetaSyncerWithMetrics(logger log.Logger, metrics *SyncerMetrics, bkt obj
 }
 
 // UntilNextDownsampling calculates how long it will take until the next downsampling operation.
+// Raw blocks are assumed to be downsampled along the default ladder.
 // Returns an error if there will be no downsampling.
 func UntilNextDownsampling(m *metadata.Meta) (time.Duration, error) {
 	timeRange := time.Duration((m.MaxTime - m.MinTime) * int64(time.Millisecond))
-	switch m.Thanos.Downsample.Resolution {
-	case downsample.ResLevel2:
+	next, ok := downsample.LadderOf(m, downsample.DefaultLadder).Next(m.Thanos.Downsample.Resolution)
+	if !ok {
 		return time.Duration(0), errors.New("no downsampling")
-	case downsample.ResLevel1:
-		return time.Duration(downsample.ResLevel2DownsampleRange*time.Millisecond) - timeRange, nil
-	case downsample.ResLevel0:
-		return time.Duration(downsample.ResLevel1DownsampleRange*time.Millisecond) - timeRange, nil
-	default:
-		panic(errors.Errorf("invalid resolution %v", m.Thanos.Downsample.Resolution))
 	}
+	return time.Duration(next.MinBlockRange*int64(time.Millisecond)) - timeRange, nil
 }
 
 // SyncMetas synchronizes local state of block metas with what we have in the bucket.
@@ -274,6 +273,7 @@ type DefaultGrouper struct {
 	hashFunc                      metadata.HashFunc
 	blockFilesConcurrency         int
 	compactBlocksFetchConcurrency int
+	splitShards                   uint64
 }
 
 // NewDefaultGrouper makes a new DefaultGrouper.
@@ -361,6 +361,14 @@ func NewDefaultGrouperWithMetrics(
 	}
 }
 
+// SetSplitShards enables split compaction: series of blocks which are not sharded yet are split into the given
+// number of shards by their label hash when compacted. Each shard forms its own group afterwards, which keeps
+// the output blocks of very large groups small enough to be compacted up to the largest level.
+// Values lower than 2 disable split compaction.
+func (g *DefaultGrouper) SetSplitShards(shards uint64) {
+	g.splitShards = shards
+}
+
 // Groups returns the compaction groups for all blocks currently known to the syncer.
 // It creates all groups from the scratch on every call.
 func (g *DefaultGrouper) Groups(blocks map[ulid.ULID]*metadata.Meta) (res []*Group, err error) {
@@ -394,6 +402,11 @@ func (g *DefaultGrouper) Groups(blocks map[ulid.ULID]*metadata.Meta) (res []*Gro
 			if err != nil {
 				return nil, errors.Wrap(err, "create compaction group")
 			}
+			group.shard = m.Thanos.Shard
+			group.levels = m.Thanos.Downsample.Levels
+			if group.shard == nil {
+				group.splitShards = g.splitShards
+			}
 			groups[groupKey] = group
 			res = append(res, group)
 		}
@@ -456,6 +469,10 @@ type Group struct {
 	blockFilesConcurrency         int
 	compactBlocksFetchConcurrency int
 	extensions                    any
+	shard                         *metadata.ThanosShard
+	splitShards                   uint64
+	// levels is the downsampling ladder of the blocks in the group, see metadata.ThanosDownsample.
+	levels []metadata.DownsampleLevel
 }
 
 // NewGroup returns a new compaction group.
@@ -596,6 +613,17 @@ func (cg *Group) Resolution() int64 {
 	return cg.resolution
 }
 
+// Shard returns the shard of series the blocks in the group hold, or nil if they are not sharded.
+func (cg *Group) Shard() *metadata.ThanosShard {
+	return cg.shard
+}
+
+// downsample returns the downsampling meta of blocks compacted from the group. They keep the
+// ladder of their inputs, so that they are downsampled further along it.
+func (cg *Group) downsample() metadata.ThanosDownsample {
+	return metadata.ThanosDownsample{Resolution: cg.resolution, Levels: cg.levels}
+}
+
 func (cg *Group) Extensions() any {
 	return cg.extensions
 }
@@ -673,7 +701,7 @@ func (ps *CompactionProgressCalculator) ProgressCalculate(ctx context.Context, g
 			}
 
 			newMeta := tsdb.CompactBlockMetas(ulid.MustNew(uint64(time.Now().Unix()), nil), metas...)
-			if err := g.AppendMeta(&metadata.Meta{BlockMeta: *newMeta, Thanos: metadata.Thanos{Downsample: metadata.ThanosDownsample{Resolution: g.Resolution()}, Labels: g.Labels().Map()}}); err != nil {
+			if err := g.AppendMeta(&metadata.Meta{BlockMeta: *newMeta, Thanos: metadata.Thanos{Downsample: g.downsample(), Labels: g.Labels().Map(), Shard: g.Shard()}}); err != nil {
 				return errors.Wrapf(err, "append meta")
 			}
 			tmpGroups = append(tmpGroups, g)
@@ -701,11 +729,13 @@ type DownsampleProgressMetrics struct {
 // DownsampleProgressCalculator contains DownsampleMetrics, which are updated during the downsampling simulation process.
 type DownsampleProgressCalculator struct {
 	*DownsampleProgressMetrics
+	ladder downsample.Ladder
 }
 
 // NewDownsampleProgressCalculator creates a new DownsampleProgressCalculator.
 func NewDownsampleProgressCalculator(reg prometheus.Registerer) *DownsampleProgressCalculator {
 	return &DownsampleProgressCalculator{
+		ladder: downsample.DefaultLadder,
 		DownsampleProgressMetrics: &DownsampleProgressMetrics{
 			NumberOfBlocksDownsampled: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
 				Name: "thanos_compact_todo_downsample_blocks",
@@ -715,66 +745,25 @@ func NewDownsampleProgressCalculator(reg prometheus.Registerer) *DownsampleProgr
 	}
 }
 
+// SetLadder sets the downsampling ladder raw blocks are downsampled along. It defaults to downsample.DefaultLadder.
+func (ds *DownsampleProgressCalculator) SetLadder(ladder downsample.Ladder) {
+	ds.ladder = ladder
+}
+
 // ProgressCalculate calculates the number of blocks to be downsampled for the given groups.
 func (ds *DownsampleProgressCalculator) ProgressCalculate(ctx context.Context, groups []*Group) error {
-	sources5m := map[ulid.ULID]struct{}{}
-	sources1h := map[ulid.ULID]struct{}{}
-	groupBlocks := make(map[string]int, len(groups))
-
+	metas := map[ulid.ULID]*metadata.Meta{}
 	for _, group := range groups {
 		for _, m := range group.metasByMinTime {
-			switch m.Thanos.Downsample.Resolution {
-			case downsample.ResLevel0:
-				continue
-			case downsample.ResLevel1:
-				for _, id := range m.Compaction.Sources {
-					sources5m[id] = struct{}{}
-				}
-			case downsample.ResLevel2:
-				for _, id := range m.Compaction.Sources {
-					sources1h[id] = struct{}{}
-				}
-			default:
-				return errors.Errorf("unexpected downsampling resolution %d", m.Thanos.Downsample.Resolution)
-			}
-
+			metas[m.ULID] = m
 		}
 	}
+	sources := downsample.SourcesByResolution(metas)
 
+	groupBlocks := make(map[string]int, len(groups))
 	for _, group := range groups {
 		for _, m := range group.metasByMinTime {
-			switch m.Thanos.Downsample.Resolution {
-			case downsample.ResLevel0:
-				missing := false
-				for _, id := range m.Compaction.Sources {
-					if _, ok := sources5m[id]; !ok {
-						missing = true
-						break
-					}
-				}
-				if !missing {
-					continue
-				}
-
-				if m.MaxTime-m.MinTime < downsample.ResLevel1DownsampleRange {
-					continue
-				}
-				groupBlocks[group.key]++
-			case downsample.ResLevel1:
-				missing := false
-				for _, id := range m.Compaction.Sources {
-					if _, ok := sources1h[id]; !ok {
-						missing = true
-						break
-					}
-				}
-				if !missing {
-					continue
-				}
-
-				if m.MaxTime-m.MinTime < downsample.ResLevel2DownsampleRange {
-					continue
-				}
+			if _, ok := downsample.NextLevel(m, ds.ladder, sources); ok {
 				groupBlocks[group.key]++
 			}
 		}
@@ -1246,6 +1235,12 @@ func (cg *Group) compact(ctx context.Context, dir string, planner Planner, comp
 					return errors.Wrapf(err,
 						"block id %s, try running with --debug.accept-malformed-index", meta.ULID)
 				}
+
+				// Duplicated series are caused by out of order labels, so they are accepted together with them.
+				if err := stats.DuplicatedSeriesErr(); !cg.acceptMalformedIndex && err != nil {
+					return errors.Wrapf(err,
+						"block id %s, try running with --debug.accept-malformed-index or repairing the block with 'thanos tools bucket repair'", meta.ULID)
+				}
 				level.Debug(cg.logger).Log("msg", "verified block", "block", meta.ULID.String(), "duration", time.Since(start), "duration_ms", time.Since(start).Milliseconds())
 				return nil
 			})
@@ -1262,12 +1257,19 @@ func (cg *Group) compact(ctx context.Context, dir string, planner Planner, comp
 	level.Info(cg.logger).Log("msg", "downloaded and verified blocks; compacting blocks", "duration", time.Since(begin), "duration_ms", time.Since(begin).Milliseconds(), "plan", sourceBlockStr)
 
 	begin = time.Now()
-	var compIDs []ulid.ULID
+	var (
+		compIDs  []ulid.ULID
+		idShards map[ulid.ULID]*metadata.ThanosShard
+	)
 	if err := tracing.DoInSpanWithErr(ctx, "compaction", func(ctx context.Context) (e error) {
 		populateBlockFunc, e := compactionLifecycleCallback.GetBlockPopulator(ctx, cg.logger, cg)
 		if e != nil {
 			return e
 		}
+		if cg.splitShards > 1 {
+			compIDs, idShards, e = splitCompact(comp, dir, toCompactDirs, populateBlockFunc, cg.splitShards)
+			return e
+		}
 		compIDs, e = comp.CompactWithBlockPopulator(dir, toCompactDirs, nil, populateBlockFunc)
 		return e
 	}); err != nil {
@@ -1326,10 +1328,14 @@ func (cg *Group) compact(ctx context.Context, dir string, planner Planner, comp
 
 		thanosMeta := metadata.Thanos{
 			Labels:       cg.labels.Map(),
-			Downsample:   metadata.ThanosDownsample{Resolution: cg.resolution},
+			Downsample:   cg.downsample(),
 			Source:       metadata.CompactorSource,
 			SegmentFiles: block.GetSegmentFiles(bdir),
 			Extensions:   cg.Extensions(),
+			Shard:        cg.shard,
+		}
+		if shard, ok := idShards[compID]; ok {
+			thanosMeta.Shard = shard
 		}
 		if stats.ChunkMaxSize > 0 {
 			thanosMeta.IndexStats.ChunkMaxSize = stats.ChunkMaxSize
@@ -1413,6 +1419,8 @@ type BucketCompactor struct {
 	concurrency                    int
 	skipBlocksWithOutOfOrderChunks bool
 	blocksCleaner                  *BlocksCleaner
+	leaser                         GroupLeaser
+	jobs                           *JobController
 }
 
 // NewBucketCompactor creates a new bucket compactor.
@@ -1477,9 +1485,92 @@ func NewBucketCompactorWithCheckerAndCallback(
 		concurrency:                    concurrency,
 		skipBlocksWithOutOfOrderChunks: skipBlocksWithOutOfOrderChunks,
 		blocksCleaner:                  blocksCleaner,
+		jobs:                           NewJobController(),
 	}, nil
 }
 
+// SetGroupLeaser makes the compactor claim a lease for each group before compacting it, so multiple
+// compactor replicas can work on the same bucket. Groups leased by other replicas are skipped.
+func (c *BucketCompactor) SetGroupLeaser(l GroupLeaser) {
+	c.leaser = l
+}
+
+// SetJobController replaces the JobController through which the compactor reports its jobs and
+// which allows to pause the compactor and to skip or prioritize groups.
+func (c *BucketCompactor) SetJobController(jobs *JobController) {
+	c.jobs = jobs
+}
+
+// compactGroup compacts the group, if its lease can be claimed when leasing is enabled.
+func (c *BucketCompactor) compactGroup(ctx context.Context, dir *os.Root, g *Group) (shouldRerun bool, err error) {
+	job := groupJob(g)
+	if c.leaser != nil {
+		leaseCtx, release, ok, err := c.leaser.Claim(ctx, g.Key())
+		if err != nil {
+			err = retry(errors.Wrap(err, "claim group lease"))
+			c.jobs.Finish(CompactionJob, job, err)
+			return false, err
+		}
+		if !ok {
+			level.Debug(c.logger).Log("msg", "group is leased by another compactor, skipping", "group", g.Key())
+			c.jobs.skip(CompactionJob, job)
+			return false, nil
+		}
+		defer release()
+		ctx = leaseCtx
+
+		// The group was planned from metas synced before the lease was claimed. Another replica may have
+		// compacted it and released the lease in the meantime, so its blocks are checked again.
+		id, ok, err := removedGroupBlock(ctx, c.bkt, g.IDs())
+		if err != nil {
+			err = retry(errors.Wrap(err, "check group blocks"))
+			c.jobs.Finish(CompactionJob, job, err)
+			return false, err
+		}
+		if ok {
+			level.Info(c.logger).Log("msg", "block of group was deleted or marked for deletion since last sync, skipping group", "group", g.Key(), "block", id)
+			c.jobs.skip(CompactionJob, job)
+			return false, nil
+		}
+	}
+	c.jobs.Start(CompactionJob, job)
+	shouldRerun, _, err = g.Compact(ctx, dir, c.planner, c.comp, c.blockDeletableChecker, c.compactionLifecycleCallback)
+	c.jobs.Finish(CompactionJob, job, err)
+	return shouldRerun, err
+}
+
+// removedGroupBlock returns the first of the given blocks whose meta.json is missing or which is marked for deletion
+// in the bucket.
+func removedGroupBlock(ctx context.Context, bkt objstore.BucketReader, ids []ulid.ULID) (ulid.ULID, bool, error) {
+	for _, id := range ids {
+		ok, err := bkt.Exists(ctx, path.Join(id.String(), metadata.MetaFilename))
+		if err != nil {
+			return id, false, errors.Wrapf(err, "check meta of block %v", id)
+		}
+		if !ok {
+			return id, true, nil
+		}
+		ok, err = bkt.Exists(ctx, path.Join(id.String(), metadata.DeletionMarkFilename))
+		if err != nil {
+			return id, false, errors.Wrapf(err, "check deletion mark of block %v", id)
+		}
+		if ok {
+			return id, true, nil
+		}
+	}
+	return ulid.ULID{}, false, nil
+}
+
+// groupJob returns the compaction job of the given group.
+func groupJob(g *Group) Job {
+	return Job{
+		Group:      g.Key(),
+		Labels:     g.Labels().Map(),
+		Resolution: g.Resolution(),
+		Blocks:     g.IDs(),
+	}
+}
+
 // Compact runs compaction over bucket.
 func (c *BucketCompactor) Compact(ctx context.Context) (rerr error) {
 	if err := os.MkdirAll(c.compactDir, 0750); err != nil {
@@ -1520,7 +1611,7 @@ func (c *BucketCompactor) Compact(ctx context.Context) (rerr error) {
 		for i := 0; i < c.concurrency; i++ {
 			wg.Go(func() {
 				for g := range groupChan {
-					shouldRerunGroup, _, err := g.Compact(workCtx, dir, c.planner, c.comp, c.blockDeletableChecker, c.compactionLifecycleCallback)
+					shouldRerunGroup, err := c.compactGroup(workCtx, dir, g)
 					if err == nil {
 						if shouldRerunGroup {
 							mtx.Lock()
@@ -1599,15 +1690,41 @@ func (c *BucketCompactor) Compact(ctx context.Context) (rerr error) {
 		}
 
 		level.Info(c.logger).Log("msg", "start of compactions")
+		if c.leaser != nil {
+			// Let replicas start with different groups instead of contending for the same leases.
+			rand.Shuffle(len(groups), func(i, j int) { groups[i], groups[j] = groups[j], groups[i] })
+		}
 
-		// Send all groups found during this pass to the compaction workers.
-		var groupErrs errutil.MultiError
-	groupLoop:
+		var (
+			pending []*Group
+			queued  []Job
+		)
 		for _, g := range groups {
 			// Ignore groups with only one block because there is nothing to compact.
 			if len(g.IDs()) == 1 {
 				continue
 			}
+			pending = append(pending, g)
+			queued = append(queued, groupJob(g))
+		}
+		c.jobs.Queue(CompactionJob, queued)
+
+		// Send all groups found during this pass to the compaction workers, highest priority first.
+		var groupErrs errutil.MultiError
+	groupLoop:
+		for {
+			if err := c.jobs.WaitResumed(workCtx); err != nil {
+				groupErrs.Add(err)
+				break
+			}
+			i := c.jobs.Next(CompactionJob, queued)
+			if i == -1 {
+				break
+			}
+			g := pending[i]
+			pending = slices.Delete(pending, i, i+1)
+			queued = slices.Delete(queued, i, i+1)
+
 			select {
 			case groupErr := <-errChan:
 				groupErrs.Add(groupErr)
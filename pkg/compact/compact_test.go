@@ -134,6 +134,24 @@ func TestGroupKey(t *testing.T) {
 			},
 			expected: "0@16590761456214576373",
 		},
+		{
+			input: metadata.Thanos{
+				Labels:     map[string]string{"foo": "bar", "foo1": "bar2"},
+				Downsample: metadata.ThanosDownsample{Resolution: 0},
+				Shard:      &metadata.ThanosShard{Index: 1, Count: 4},
+			},
+			expected: "0@2124638872457683483@1_of_4",
+		},
+		{
+			input: metadata.Thanos{
+				Labels: map[string]string{"foo": "bar", "foo1": "bar2"},
+				Downsample: metadata.ThanosDownsample{
+					Resolution: 60000,
+					Levels:     []metadata.DownsampleLevel{{Resolution: 60000, MinBlockRange: 7200000}, {Resolution: 900000, MinBlockRange: 28800000}},
+				},
+			},
+			expected: "60000@2124638872457683483@60000_7200000-900000_28800000",
+		},
 	} {
 		if ok := t.Run("", func(t *testing.T) {
 			testutil.Equals(t, tcase.expected, tcase.input.GroupKey())
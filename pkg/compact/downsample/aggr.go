@@ -20,11 +20,16 @@ type AggrChunk []byte
 
 // EncodeAggrChunk encodes a new aggregate chunk from the array of chunks for each aggregate.
 // Each array entry corresponds to the respective AggrType number.
-func EncodeAggrChunk(chks [5]chunkenc.Chunk) *AggrChunk {
+// Unset aggregates after AggrCounter are omitted, so that chunks without them keep the original layout.
+func EncodeAggrChunk(chks [6]chunkenc.Chunk) *AggrChunk {
 	var b []byte
 	buf := [8]byte{}
 
-	for _, c := range chks {
+	n := len(chks)
+	for n > int(AggrCounter)+1 && chks[n-1] == nil {
+		n--
+	}
+	for _, c := range chks[:n] {
 		// Unset aggregates are marked with a zero length entry.
 		if c == nil {
 			n := binary.PutUvarint(buf[:], 0)
@@ -76,6 +81,10 @@ func (c AggrChunk) Get(t AggrType) (chunkenc.Chunk, error) {
 	var x []byte
 
 	for i := AggrType(0); i <= t; i++ {
+		// Chunks written before an aggregate was introduced do not have an entry for it.
+		if len(b) == 0 && i > AggrCounter {
+			return nil, ErrAggrNotExist
+		}
 		l, n := binary.Uvarint(b)
 		if n < 1 || len(b[n:]) < int(l)+1 {
 			return nil, errors.New("invalid size")
@@ -108,6 +117,9 @@ const (
 	AggrMin
 	AggrMax
 	AggrCounter
+	// AggrLast is the last raw sample of each window. It is only set for native histograms, where it is used
+	// to detect counter resets between chunks of the counter aggregate.
+	AggrLast
 )
 
 func (t AggrType) String() string {
@@ -122,6 +134,8 @@ func (t AggrType) String() string {
 		return "max"
 	case AggrCounter:
 		return "counter"
+	case AggrLast:
+		return "last"
 	}
 	return "<unknown>"
 }
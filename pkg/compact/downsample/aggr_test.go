@@ -20,7 +20,7 @@ func TestAggrChunk(t *testing.T) {
 	// Maximum is absent.
 	input[AggrCounter] = []sample{{t: 100, v: 5}, {t: 200, v: 10}, {t: 300, v: 10.1}, {t: 400, v: 15}, {t: 400, v: 3}}
 
-	var chks [5]chunkenc.Chunk
+	var chks [6]chunkenc.Chunk
 
 	for i, smpls := range input {
 		if len(smpls) == 0 {
@@ -58,3 +58,33 @@ func TestAggrChunk(t *testing.T) {
 	}
 	testutil.Equals(t, input, res)
 }
+
+func TestAggrChunk_Last(t *testing.T) {
+	var chks [6]chunkenc.Chunk
+	for _, at := range []AggrType{AggrCount, AggrCounter} {
+		chks[at] = chunkenc.NewXORChunk()
+		a, err := chks[at].Appender()
+		testutil.Ok(t, err)
+		a.Append(100, 1)
+	}
+
+	// Chunks without the last aggregate keep the layout of chunks written before it existed.
+	ac := EncodeAggrChunk(chks)
+	_, err := ac.Get(AggrLast)
+	testutil.Equals(t, ErrAggrNotExist, err)
+	c, err := ac.Get(AggrCounter)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, c.NumSamples())
+
+	chks[AggrLast] = chunkenc.NewXORChunk()
+	a, err := chks[AggrLast].Appender()
+	testutil.Ok(t, err)
+	a.Append(100, 2)
+
+	ac = EncodeAggrChunk(chks)
+	c, err = ac.Get(AggrLast)
+	testutil.Ok(t, err)
+	var res []sample
+	testutil.Ok(t, expandXorChunkIterator(c.Iterator(nil), &res))
+	testutil.Equals(t, []sample{{t: 100, v: 2}}, res)
+}
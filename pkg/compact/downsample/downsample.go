@@ -172,22 +172,10 @@ func Downsample(
 
 		// Raw and already downsampled data need different processing.
 		if origMeta.Thanos.Downsample.Resolution == 0 {
-			var prevEnc = chks[0].Chunk.Encoding()
-
-			for _, c := range chks {
-				if cutNewChunk(c.Chunk.Encoding(), prevEnc) {
-					resChunks = append(resChunks, DownsampleRaw(all, resolution)...)
-					all = all[:0]
-					prevEnc = c.Chunk.Encoding()
-				}
-				// TODO(bwplotka): We can optimize this further by using in WriteSeries iterators of each chunk instead of
-				// samples. Also ensure 120 sample limit, otherwise we have gigantic chunks.
-				// https://github.com/thanos-io/thanos/issues/2542.
-				if err := expandChunkIterator(c.Chunk.Iterator(reuseIt), c.Chunk.Encoding(), &all); err != nil {
-					return id, errors.Wrapf(err, "expand chunk %d, series %d", c.Ref, postings.At())
-				}
+			resChunks, err = downsampleRawChunks(chks, resolution, &all, reuseIt)
+			if err != nil {
+				return id, errors.Wrapf(err, "series %d", postings.At())
 			}
-			resChunks = append(resChunks, DownsampleRaw(all, resolution)...)
 			if err := streamedBlockWriter.WriteSeries(lset, resChunks); err != nil {
 				return id, errors.Wrapf(err, "downsample raw data, series: %d", postings.At())
 			}
@@ -216,11 +204,11 @@ func Downsample(
 					if err := expandChunkIterator(c.Chunk.Iterator(reuseIt), c.Chunk.Encoding(), &all); err != nil {
 						return id, errors.Wrapf(err, "expand chunk %d, series %d", c.Ref, postings.At())
 					}
-					aggrDataChunks := DownsampleRaw(all, ResLevel1)
+					aggrDataChunks := DownsampleRaw(all, origMeta.Thanos.Downsample.Resolution)
 					for _, cn := range aggrDataChunks {
 						_, ok = cn.Chunk.(*AggrChunk)
 						if !ok {
-							return id, errors.New("Not able to convert non-empty chunks to 5m downsampled aggregated chunks.")
+							return id, errors.Errorf("Not able to convert non-empty chunks to %d ms downsampled aggregated chunks.", origMeta.Thanos.Downsample.Resolution)
 						}
 						fixedChks = append(fixedChks, cn)
 					}
@@ -418,6 +406,7 @@ func newHistogramAggrChunkBuilder(isGaugeSamples bool) *aggrChunkBuilder {
 	b.chunks[AggrCount] = chunkenc.NewXORChunk()
 	b.chunks[AggrSum] = chunkenc.NewFloatHistogramChunk()
 	b.chunks[AggrCounter] = chunkenc.NewFloatHistogramChunk()
+	b.chunks[AggrLast] = chunkenc.NewFloatHistogramChunk()
 
 	for i, c := range b.chunks {
 		if c != nil {
@@ -480,6 +469,7 @@ func (b *aggrChunkBuilder) addHistogram(t int64, a sampleAggregator) {
 	}
 	b.appendFloatHistogram(AggrCounter, t, aggr.counter)
 	b.appendFloatHistogram(AggrSum, t, aggr.sum)
+	b.appendFloatHistogram(AggrLast, t, gaugeCopy(aggr.previous))
 	b.apps[AggrCount].Append(t, float64(aggr.count))
 
 	b.added++
@@ -503,6 +493,14 @@ func (b *aggrChunkBuilder) appendFloatHistogram(t AggrType, ts int64, fh *histog
 	b.apps[t] = cApp
 }
 
+// gaugeCopy returns a copy of the histogram marked as gauge histogram. Last samples of windows are not
+// monotonic, so they need to be appended as gauges to not cut a new chunk on counter resets.
+func gaugeCopy(fh *histogram.FloatHistogram) *histogram.FloatHistogram {
+	c := fh.Copy()
+	c.CounterResetHint = histogram.GaugeType
+	return c
+}
+
 func isGaugeSamples(samples []sample) bool {
 	if len(samples) == 0 {
 		return false
@@ -563,8 +561,8 @@ type aggrChunkBuilder struct {
 	mint, maxt int64
 	added      int
 
-	chunks [5]chunkenc.Chunk
-	apps   [5]chunkenc.Appender
+	chunks [6]chunkenc.Chunk
+	apps   [6]chunkenc.Appender
 
 	isGaugeSamples bool
 }
@@ -630,6 +628,40 @@ func (b *aggrChunkBuilder) encode() chunks.Meta {
 	}
 }
 
+// DownsampleRawChunks creates a series of aggregation chunks for the given raw chunks of a single series.
+// Chunks are expected to be ordered by time and non-overlapping.
+func DownsampleRawChunks(chks []chunks.Meta, resolution int64) ([]chunks.Meta, error) {
+	var all []sample
+	return downsampleRawChunks(chks, resolution, &all, nil)
+}
+
+func downsampleRawChunks(chks []chunks.Meta, resolution int64, all *[]sample, reuseIt chunkenc.Iterator) ([]chunks.Meta, error) {
+	if len(chks) == 0 {
+		return nil, nil
+	}
+
+	var (
+		resChunks []chunks.Meta
+		prevEnc   = chks[0].Chunk.Encoding()
+	)
+	*all = (*all)[:0]
+	for _, c := range chks {
+		if cutNewChunk(c.Chunk.Encoding(), prevEnc) {
+			resChunks = append(resChunks, DownsampleRaw(*all, resolution)...)
+			*all = (*all)[:0]
+			prevEnc = c.Chunk.Encoding()
+		}
+		// TODO(bwplotka): We can optimize this further by using in WriteSeries iterators of each chunk instead of
+		// samples. Also ensure 120 sample limit, otherwise we have gigantic chunks.
+		// https://github.com/thanos-io/thanos/issues/2542.
+		reuseIt = c.Chunk.Iterator(reuseIt)
+		if err := expandChunkIterator(reuseIt, c.Chunk.Encoding(), all); err != nil {
+			return nil, errors.Wrapf(err, "expand chunk %d", c.Ref)
+		}
+	}
+	return append(resChunks, DownsampleRaw(*all, resolution)...), nil
+}
+
 // DownsampleRaw create a series of aggregation chunks for the given sample data.
 func DownsampleRaw(data []sample, resolution int64) []chunks.Meta {
 	if len(data) == 0 {
@@ -697,10 +729,11 @@ func downsampleHistogramAggrBatch(chks []*AggrChunk, buf *[]sample, resolution i
 	mint, maxt := int64(math.MaxInt64), int64(math.MinInt64)
 	var reuseIt chunkenc.Iterator
 
-	// Native histograms support counter, sum, count types.
+	// Native histograms support counter, sum, count and last types.
 
-	// Expand all samples for the counter aggregate type.
-	*buf = (*buf)[:0]
+	// Expand all samples for the counter aggregate type. The counter of each chunk starts with the first
+	// raw sample of the chunk, so chunks are stitched together based on their last raw samples.
+	var counters, lasts []chunkenc.Iterator
 	for _, achk := range chks {
 		c, err := achk.Get(AggrCounter)
 		if err == ErrAggrNotExist {
@@ -708,9 +741,21 @@ func downsampleHistogramAggrBatch(chks []*AggrChunk, buf *[]sample, resolution i
 		} else if err != nil {
 			return chk, err
 		}
-		if err := expandFloatHistogramChunkIterator(c.Iterator(reuseIt), buf); err != nil {
+		counters = append(counters, c.Iterator(nil))
+
+		l, err := achk.Get(AggrLast)
+		if err == ErrAggrNotExist {
+			// Chunks written before the last aggregate was introduced.
+			lasts = append(lasts, nil)
+			continue
+		} else if err != nil {
 			return chk, err
 		}
+		lasts = append(lasts, l.Iterator(nil))
+	}
+	*buf = (*buf)[:0]
+	if err := expandFloatHistogramChunkIterator(NewApplyCounterResetsIteratorWithLast(counters, lasts), buf); err != nil {
+		return chk, err
 	}
 
 	ab := newHistogramAggrChunkBuilder(isGaugeSamples(*buf))
@@ -754,6 +799,29 @@ func downsampleHistogramAggrBatch(chks []*AggrChunk, buf *[]sample, resolution i
 		ab.appendFloatHistogram(AggrSum, t, mustGetHistogramAggregator(a).sum)
 	})
 
+	// Expand all last samples, using the counter of chunks written without them.
+	*buf = (*buf)[:0]
+	for _, achk := range chks {
+		c, err := achk.Get(AggrLast)
+		if err == ErrAggrNotExist {
+			c, err = achk.Get(AggrCounter)
+		}
+		if err == ErrAggrNotExist {
+			continue
+		} else if err != nil {
+			return chk, err
+		}
+		if err := expandFloatHistogramChunkIterator(c.Iterator(reuseIt), buf); err != nil {
+			return chk, err
+		}
+	}
+	if len(*buf) > 0 {
+		schema = minSchema(*buf)
+		downsampleBatch(*buf, resolution, newHistogramAggregator(schema), func(t int64, a sampleAggregator) {
+			ab.appendFloatHistogram(AggrLast, t, gaugeCopy(mustGetHistogramAggregator(a).previous))
+		})
+	}
+
 	*buf = (*buf)[:0]
 	batchMint, batchMaxt, err := genericAggregate(AggrCount, chks, buf, ab, resolution, func(a sampleAggregator) float64 {
 		aggr := mustGetFloatAggregator(a)
@@ -1123,39 +1191,64 @@ type sample struct {
 // value of the later chunk ensures that counter resets between chunks are
 // recognized and that the correct value delta is calculated.
 //
+// Native histogram counter aggregates cannot encode the last raw value this way, since a decreasing
+// histogram would cut a new chunk. Their last raw values are read from the AggrLast aggregate instead,
+// see NewApplyCounterResetsIteratorWithLast. Histograms are returned as float histograms with explicit
+// counter reset hints, so that PromQL does not detect resets between chunks again.
+//
 // It handles overlapped chunks (removes overlaps).
 // NOTE: It is important to deduplicate with care ensuring that you don't hit
 // issue https://github.com/thanos-io/thanos/issues/2401#issuecomment-621958839.
 // NOTE(bwplotka): This hides resets from PromQL engine. This means it will not work for PromQL resets function.
 type ApplyCounterResetsSeriesIterator struct {
 	chks        []chunkenc.Iterator
-	i           int     // Current chunk.
-	total       int     // Total number of processed samples.
-	lastT       int64   // Timestamp of the last sample.
-	lastV       float64 // Value of the last sample.
-	totalV      float64 // Total counter state since beginning of series.
+	lasts       []chunkenc.Iterator // Last raw histograms of each chunk, if any.
+	i           int                 // Current chunk.
+	total       int                 // Total number of processed samples.
+	lastT       int64               // Timestamp of the last sample.
+	lastV       float64             // Value of the last sample.
+	totalV      float64             // Total counter state since beginning of series.
 	lastValType chunkenc.ValueType
+
+	lastH  *histogram.FloatHistogram // Last raw histogram.
+	lastHi int                       // Chunk of the last raw histogram.
+	totalH *histogram.FloatHistogram // Total histogram counter state since beginning of series.
+	err    error
 }
 
 func NewApplyCounterResetsIterator(chks ...chunkenc.Iterator) *ApplyCounterResetsSeriesIterator {
 	return &ApplyCounterResetsSeriesIterator{chks: chks}
 }
 
-// TODO(rabenhorst): Native histogram support needs to be added, float type is hardcoded.
+// NewApplyCounterResetsIteratorWithLast is like NewApplyCounterResetsIterator, but takes the AggrLast
+// iterators of native histogram aggregate chunks, to detect counter resets between them.
+// Entries of lasts are nil for raw chunks and for aggregate chunks without last samples.
+func NewApplyCounterResetsIteratorWithLast(chks, lasts []chunkenc.Iterator) *ApplyCounterResetsSeriesIterator {
+	return &ApplyCounterResetsSeriesIterator{chks: chks, lasts: lasts}
+}
+
 func (it *ApplyCounterResetsSeriesIterator) Next() chunkenc.ValueType {
 	for {
-		if it.i >= len(it.chks) {
+		if it.i >= len(it.chks) || it.err != nil {
 			return chunkenc.ValNone
 		}
 		it.lastValType = it.chks[it.i].Next()
 		if it.lastValType == chunkenc.ValNone {
+			it.applyLastHistogram()
 			it.i++
 			// While iterators are ordered, they are not generally guaranteed to be
 			// non-overlapping. Ensure that the series does not go back in time by seeking at least
 			// to the next timestamp.
 			return it.Seek(it.lastT + 1)
 		}
-		// Counter resets do not need to be handled for non-float sample types.
+		if (it.lastValType == chunkenc.ValHistogram || it.lastValType == chunkenc.ValFloatHistogram) && it.stitchHistograms() {
+			if it.nextHistogram() {
+				it.lastValType = chunkenc.ValFloatHistogram
+				return it.lastValType
+			}
+			continue
+		}
+		// Counter resets do not need to be handled for other sample types and raw histograms.
 		if it.lastValType != chunkenc.ValFloat {
 			it.lastT = it.chks[it.i].AtT()
 			return it.lastValType
@@ -1192,6 +1285,86 @@ func (it *ApplyCounterResetsSeriesIterator) Next() chunkenc.ValueType {
 	}
 }
 
+// stitchHistograms returns true if histograms of the current chunk are stitched with the previous ones.
+// Only aggregate chunks with last samples are stitched, other histograms are returned as they are.
+func (it *ApplyCounterResetsSeriesIterator) stitchHistograms() bool {
+	return it.i < len(it.lasts) && it.lasts[it.i] != nil
+}
+
+// nextHistogram applies counter resets to the current histogram of the current chunk.
+// It returns false if the histogram has to be skipped.
+func (it *ApplyCounterResetsSeriesIterator) nextHistogram() bool {
+	var (
+		t  int64
+		fh *histogram.FloatHistogram
+	)
+	if it.lastValType == chunkenc.ValHistogram {
+		var h *histogram.Histogram
+		t, h = it.chks[it.i].AtHistogram(nil)
+		fh = h.ToFloat(nil)
+	} else {
+		t, fh = it.chks[it.i].AtFloatHistogram(nil)
+	}
+	if value.IsStaleNaN(fh.Sum) {
+		return false
+	}
+	// First histogram sets the initial counter state. Gauge histograms have no counter resets.
+	if it.lastH == nil || fh.CounterResetHint == histogram.GaugeType {
+		it.lastT, it.lastH, it.lastHi = t, fh, it.i
+		it.totalH = fh.Copy()
+		it.total++
+		return true
+	}
+	// The series went back in time, keep moving forward.
+	if t <= it.lastT {
+		return false
+	}
+
+	var (
+		err  error
+		hint = histogram.NotCounterReset
+	)
+	if fh.Schema != it.lastH.Schema && (fh.UsesCustomBuckets() || it.lastH.UsesCustomBuckets()) {
+		// Exponential and custom buckets cannot be combined, start over as after a counter reset.
+		it.totalH = fh.Copy()
+		hint = histogram.CounterReset
+	} else if fh.DetectReset(it.lastH) {
+		_, _, _, err = it.totalH.Add(fh)
+	} else {
+		var delta *histogram.FloatHistogram
+		if delta, _, _, err = fh.Copy().Sub(it.lastH); err == nil {
+			_, _, _, err = it.totalH.Add(delta)
+		}
+	}
+	if err != nil {
+		it.err = errors.Wrapf(err, "apply counter reset to histogram at %d", t)
+		return false
+	}
+	it.totalH.CounterResetHint = hint
+	it.lastT, it.lastH, it.lastHi = t, fh, it.i
+	it.total++
+	return true
+}
+
+// applyLastHistogram replaces the last raw histogram with the last sample of the AggrLast aggregate of
+// the current chunk, once all its samples were read. Counters of aggregate chunks are reset-corrected, so
+// their last value differs from the last raw one if there were counter resets within the chunk.
+func (it *ApplyCounterResetsSeriesIterator) applyLastHistogram() {
+	if it.lastH == nil || it.lastHi != it.i || it.i >= len(it.lasts) || it.lasts[it.i] == nil {
+		return
+	}
+	lastIt := it.lasts[it.i]
+	for lastIt.Next() != chunkenc.ValNone {
+		_, fh := lastIt.AtFloatHistogram(nil)
+		if !value.IsStaleNaN(fh.Sum) {
+			it.lastH = fh
+		}
+	}
+	if err := lastIt.Err(); err != nil {
+		it.err = err
+	}
+}
+
 func (it *ApplyCounterResetsSeriesIterator) At() (t int64, v float64) {
 	return it.lastT, it.totalV
 }
@@ -1201,7 +1374,14 @@ func (it *ApplyCounterResetsSeriesIterator) AtHistogram(h *histogram.Histogram)
 }
 
 func (it *ApplyCounterResetsSeriesIterator) AtFloatHistogram(fh *histogram.FloatHistogram) (int64, *histogram.FloatHistogram) {
-	return it.chks[it.i].AtFloatHistogram(fh)
+	if !it.stitchHistograms() {
+		return it.chks[it.i].AtFloatHistogram(fh)
+	}
+	if fh == nil {
+		return it.lastT, it.totalH.Copy()
+	}
+	it.totalH.CopyTo(fh)
+	return it.lastT, fh
 }
 
 func (it *ApplyCounterResetsSeriesIterator) AtT() int64 {
@@ -1222,6 +1402,9 @@ func (it *ApplyCounterResetsSeriesIterator) Seek(x int64) chunkenc.ValueType {
 }
 
 func (it *ApplyCounterResetsSeriesIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
 	if it.i >= len(it.chks) {
 		return nil
 	}
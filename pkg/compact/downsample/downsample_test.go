@@ -1943,6 +1943,16 @@ func TestApplyCounterResetsIteratorHistograms(t *testing.T) {
 		expected = append(expected, &testiters.HistogramPair{T: int64(i * 100), H: h})
 	}
 
+	// Raw histograms are returned as they are, even after counter resets.
+	var resetChunks [][]*testiters.HistogramPair
+	for i := range 2 {
+		var chunk []*testiters.HistogramPair
+		for j := range lenChunk {
+			chunk = append(chunk, &testiters.HistogramPair{T: int64(i*lenChunk+j) * 100, H: histograms[j]})
+		}
+		resetChunks = append(resetChunks, chunk)
+	}
+
 	for _, tcase := range []struct {
 		name string
 
@@ -1955,6 +1965,11 @@ func TestApplyCounterResetsIteratorHistograms(t *testing.T) {
 			chunks:   chunks,
 			expected: expected,
 		},
+		{
+			name:     "histogram series with counter reset",
+			chunks:   resetChunks,
+			expected: append(append([]*testiters.HistogramPair{}, resetChunks[0]...), resetChunks[1]...),
+		},
 	} {
 		t.Run(tcase.name, func(t *testing.T) {
 			var its []chunkenc.Iterator
@@ -1965,7 +1980,8 @@ func TestApplyCounterResetsIteratorHistograms(t *testing.T) {
 			x := NewApplyCounterResetsIterator(its...)
 
 			var res []*testiters.HistogramPair
-			for x.Next() != chunkenc.ValNone {
+			for vt := x.Next(); vt != chunkenc.ValNone; vt = x.Next() {
+				testutil.Equals(t, chunkenc.ValHistogram, vt)
 				t, h := x.AtHistogram(nil)
 				res = append(res, &testiters.HistogramPair{T: t, H: h})
 			}
@@ -1980,6 +1996,42 @@ func TestApplyCounterResetsIteratorHistograms(t *testing.T) {
 
 }
 
+func TestApplyCounterResetsIteratorWithLastCustomBuckets(t *testing.T) {
+	var (
+		exponential = tsdbutil.GenerateTestFloatHistograms(3)
+		custom      = tsdbutil.GenerateTestCustomBucketsFloatHistograms(3)
+	)
+	counter := func(start int64, fhs []*histogram.FloatHistogram) chunkenc.Chunk {
+		chk, app := newHistogramChunk(t)
+		for i, fh := range fhs {
+			_, _, _, err := app.AppendFloatHistogram(nil, start+int64(i), fh, false)
+			testutil.Ok(t, err)
+		}
+		return chk
+	}
+	last := func(t int64, fh *histogram.FloatHistogram) chunkenc.Chunk {
+		return counter(t, []*histogram.FloatHistogram{gaugeCopy(fh)})
+	}
+
+	x := NewApplyCounterResetsIteratorWithLast(
+		[]chunkenc.Iterator{counter(0, exponential).Iterator(nil), counter(3, custom).Iterator(nil)},
+		[]chunkenc.Iterator{last(2, exponential[2]).Iterator(nil), last(5, custom[2]).Iterator(nil)},
+	)
+
+	var res []*histogram.FloatHistogram
+	for x.Next() != chunkenc.ValNone {
+		_, fh := x.AtFloatHistogram(nil)
+		res = append(res, fh)
+	}
+	testutil.Ok(t, x.Err())
+	testutil.Equals(t, 6, len(res))
+
+	// Custom buckets cannot be added to exponential ones, so the counter starts over.
+	testutil.Equals(t, histogram.CounterReset, res[3].CounterResetHint)
+	testutil.Equals(t, custom[0].Count, res[3].Count)
+	testutil.Equals(t, custom[2].Count, res[5].Count)
+}
+
 func TestCounterSeriesIteratorSeek(t *testing.T) {
 	chunks := [][]sample{
 		{{t: 100, v: 10}, {t: 200, v: 20}, {t: 300, v: 10}, {t: 400, v: 20}, {t: 400, v: 5}},
@@ -2874,3 +2926,102 @@ func TestDownsampleNHCutNewChunk(t *testing.T) {
 	require.True(t, cutNewChunk(chunkenc.EncXOR, chunkenc.EncFloatHistogram))
 	require.True(t, cutNewChunk(chunkenc.EncXOR, chunkenc.EncHistogram))
 }
+
+// TestDownsampleNativeHistogramCounterEquivalence checks that the reset-corrected counter of downsampled native
+// histograms matches the raw data at the end of every window, so that rate(), increase() and histogram_quantile()
+// over downsampled data give the same results as over raw data for ranges aligned to the resolution.
+func TestDownsampleNativeHistogramCounterEquivalence(t *testing.T) {
+	// Three days of 30s scrapes with counter resets in the middle of windows and chunks.
+	samples := generateFloatHistogramSamples(0, 2003, 2999, 3638)
+	raw := make([]*histogram.FloatHistogram, 0, len(samples))
+	for _, s := range samples {
+		raw = append(raw, s.fh)
+	}
+	expected := counterResetAdjustFloatHistograms(raw)
+
+	logger := log.NewNopLogger()
+	dir := t.TempDir()
+	mb := blockFromChunks(chunksFromHistogramSamples(t, samples))
+	fakeMeta := &metadata.Meta{
+		BlockMeta: tsdb.BlockMeta{
+			MinTime: samples[0].t,
+			MaxTime: samples[len(samples)-1].t,
+		},
+	}
+
+	idResLevel1, err := Downsample(context.Background(), logger, fakeMeta, mb, dir, ResLevel1)
+	testutil.Ok(t, err)
+	meta, _, chks := GetMetaLabelsAndChunks(t, dir, idResLevel1)
+	testutil.Assert(t, len(chks[0]) > 1, "expected multiple 5m chunks, got %d", len(chks[0]))
+	assertCounterMatchesRaw(t, dir, idResLevel1.String(), chks[0], samples, expected)
+
+	blk, err := tsdb.OpenBlock(logutil.GoKitLogToSlog(logger), filepath.Join(dir, idResLevel1.String()), NewPool(), tsdb.DefaultPostingsDecoderFactory)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, blk.Close()) }()
+
+	idResLevel2, err := Downsample(context.Background(), logger, meta, blk, dir, ResLevel2)
+	testutil.Ok(t, err)
+	_, _, chks = GetMetaLabelsAndChunks(t, dir, idResLevel2)
+	assertCounterMatchesRaw(t, dir, idResLevel2.String(), chks[0], samples, expected)
+}
+
+// assertCounterMatchesRaw stitches the counter aggregate of all chunks together the way the querier does and
+// compares each resulting sample with the reset-corrected raw counter at the same time.
+func assertCounterMatchesRaw(t *testing.T, dir, blockID string, chks []chunks.Meta, samples []sample, expected []*histogram.FloatHistogram) {
+	t.Helper()
+
+	chunkr, err := chunks.NewDirReader(filepath.Join(dir, blockID, block.ChunksDirname), NewPool())
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, chunkr.Close()) }()
+
+	var counters, lasts []chunkenc.Iterator
+	for _, c := range chks {
+		chk, _, err := chunkr.ChunkOrIterable(c)
+		testutil.Ok(t, err)
+		ac, ok := chk.(*AggrChunk)
+		testutil.Assert(t, ok)
+
+		counter, err := ac.Get(AggrCounter)
+		testutil.Ok(t, err)
+		counters = append(counters, counter.Iterator(nil))
+		last, err := ac.Get(AggrLast)
+		testutil.Ok(t, err)
+		lasts = append(lasts, last.Iterator(nil))
+	}
+
+	testUtilWithOps := testutil.WithGoCmp(cmpopts.EquateApprox(1e-12, 0))
+
+	it := NewApplyCounterResetsIteratorWithLast(counters, lasts)
+	var (
+		j int
+		n int
+	)
+	for it.Next() != chunkenc.ValNone {
+		ts, fh := it.AtFloatHistogram(nil)
+		for j+1 < len(samples) && samples[j+1].t <= ts {
+			j++
+		}
+		testutil.Assert(t, samples[j].t <= ts, "sample at %d before first raw sample", ts)
+		testUtilWithOps.Equals(t, expected[j].Count, fh.Count, "count mismatch at %d", ts)
+		testUtilWithOps.Equals(t, expected[j].Sum, fh.Sum, "sum mismatch at %d", ts)
+		testUtilWithOps.Equals(t, bucketCounts(expected[j]), bucketCounts(fh), "bucket mismatch at %d", ts)
+		n++
+	}
+	testutil.Ok(t, it.Err())
+	testutil.Equals(t, len(samples)-1, j, "expected the last raw sample to be covered")
+	testutil.Assert(t, n > 1, "expected more than one sample")
+}
+
+func bucketCounts(fh *histogram.FloatHistogram) map[histogram.Bucket[float64]]float64 {
+	res := map[histogram.Bucket[float64]]float64{}
+	for it := fh.AllBucketIterator(); it.Next(); {
+		b := it.At()
+		if b.Count == 0 {
+			continue
+		}
+		count := b.Count
+		b.Count = 0
+		res[b] = count
+	}
+	return res
+}
@@ -0,0 +1,190 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package downsample
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+)
+
+// Ladder is a list of downsampling levels ordered by increasing resolution. Raw blocks are downsampled to
+// the first level, blocks of each level to the next one.
+type Ladder []metadata.DownsampleLevel
+
+// DefaultLadder is the standard downsampling ladder of Thanos: raw → 5m → 1h.
+var DefaultLadder = Ladder{
+	{Resolution: ResLevel1, MinBlockRange: ResLevel1DownsampleRange},
+	{Resolution: ResLevel2, MinBlockRange: ResLevel2DownsampleRange},
+}
+
+// ParseLadder parses a ladder in the form of comma separated <resolution>:<min-block-range> pairs
+// of Prometheus durations, e.g. "5m:40h,1h:10d" for the default ladder.
+func ParseLadder(s string) (Ladder, error) {
+	var l Ladder
+	for level := range strings.SplitSeq(s, ",") {
+		res, minRange, ok := strings.Cut(strings.TrimSpace(level), ":")
+		if !ok {
+			return nil, errors.Errorf("invalid downsampling level %q, expected <resolution>:<min-block-range>", level)
+		}
+		r, err := model.ParseDuration(res)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse resolution of downsampling level %q", level)
+		}
+		m, err := model.ParseDuration(minRange)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse min block range of downsampling level %q", level)
+		}
+		l = append(l, metadata.DownsampleLevel{
+			Resolution:    time.Duration(r).Milliseconds(),
+			MinBlockRange: time.Duration(m).Milliseconds(),
+		})
+	}
+	return l, l.Validate()
+}
+
+// ParseResolutions parses comma separated Prometheus durations into resolutions in milliseconds, ordered by
+// increasing resolution.
+func ParseResolutions(s string) ([]int64, error) {
+	var resolutions []int64
+	for res := range strings.SplitSeq(s, ",") {
+		r, err := model.ParseDuration(strings.TrimSpace(res))
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse resolution %q", res)
+		}
+		if r <= 0 {
+			return nil, errors.Errorf("resolution %q must be positive", res)
+		}
+		resolutions = append(resolutions, time.Duration(r).Milliseconds())
+	}
+	slices.Sort(resolutions)
+	return slices.Compact(resolutions), nil
+}
+
+// Validate returns an error if the ladder is empty, or if resolutions or min block ranges do not increase.
+func (l Ladder) Validate() error {
+	if len(l) == 0 {
+		return errors.New("downsampling ladder is empty")
+	}
+	prev := metadata.DownsampleLevel{}
+	for _, level := range l {
+		if level.Resolution <= prev.Resolution {
+			return errors.Errorf("resolution %s of downsampling level is not higher than the previous one", model.Duration(time.Duration(level.Resolution)*time.Millisecond))
+		}
+		if level.MinBlockRange <= prev.MinBlockRange {
+			return errors.Errorf("min block range of %s downsampling level is not higher than the previous one", model.Duration(time.Duration(level.Resolution)*time.Millisecond))
+		}
+		prev = level
+	}
+	return nil
+}
+
+// ValidateCompactionRange returns an error if a level requires blocks longer than the given largest
+// compaction block range, since blocks of the previous level never grow longer than it.
+func (l Ladder) ValidateCompactionRange(maxBlockRange int64) error {
+	for _, level := range l {
+		if level.MinBlockRange > maxBlockRange {
+			return errors.Errorf("min block range %s of %s downsampling level exceeds the largest compaction block range %s, blocks would never be downsampled to it",
+				model.Duration(time.Duration(level.MinBlockRange)*time.Millisecond),
+				model.Duration(time.Duration(level.Resolution)*time.Millisecond),
+				model.Duration(time.Duration(maxBlockRange)*time.Millisecond))
+		}
+	}
+	return nil
+}
+
+// IsDefault returns true if the ladder equals DefaultLadder.
+func (l Ladder) IsDefault() bool {
+	return slices.Equal(l, DefaultLadder)
+}
+
+// Resolutions returns all resolutions of the ladder, including the raw one.
+func (l Ladder) Resolutions() []int64 {
+	resolutions := []int64{ResLevel0}
+	for _, level := range l {
+		resolutions = append(resolutions, level.Resolution)
+	}
+	return resolutions
+}
+
+// Next returns the level blocks of the given resolution are downsampled to. It returns false for the last level
+// and for resolutions which are not part of the ladder.
+func (l Ladder) Next(resolution int64) (metadata.DownsampleLevel, bool) {
+	if resolution == ResLevel0 && len(l) > 0 {
+		return l[0], true
+	}
+	for i, level := range l[:max(len(l)-1, 0)] {
+		if level.Resolution == resolution {
+			return l[i+1], true
+		}
+	}
+	return metadata.DownsampleLevel{}, false
+}
+
+func (l Ladder) String() string {
+	levels := make([]string, 0, len(l))
+	for _, level := range l {
+		levels = append(levels, model.Duration(time.Duration(level.Resolution)*time.Millisecond).String()+":"+model.Duration(time.Duration(level.MinBlockRange)*time.Millisecond).String())
+	}
+	return strings.Join(levels, ",")
+}
+
+// LadderOf returns the ladder the given block is downsampled along. Downsampled blocks keep the ladder they were
+// created with, so that changing the ladder does not leave gaps in levels of already downsampled data. Raw blocks
+// start on the given ladder.
+func LadderOf(m *metadata.Meta, ladder Ladder) Ladder {
+	if m.Thanos.Downsample.Resolution == ResLevel0 {
+		return ladder
+	}
+	if len(m.Thanos.Downsample.Levels) > 0 {
+		return m.Thanos.Downsample.Levels
+	}
+	return DefaultLadder
+}
+
+// SourcesByResolution returns the source blocks of all given downsampled blocks by their resolution.
+func SourcesByResolution(metas map[ulid.ULID]*metadata.Meta) map[int64]map[ulid.ULID]struct{} {
+	sources := map[int64]map[ulid.ULID]struct{}{}
+	for _, m := range metas {
+		res := m.Thanos.Downsample.Resolution
+		if res == ResLevel0 {
+			continue
+		}
+		if sources[res] == nil {
+			sources[res] = map[ulid.ULID]struct{}{}
+		}
+		for _, id := range m.Compaction.Sources {
+			sources[res][id] = struct{}{}
+		}
+	}
+	return sources
+}
+
+// NextLevel returns the level the given block has to be downsampled to, if any. A block is downsampled once it
+// covers the min block range of the next level of its ladder, and as long as some of its sources were not
+// downsampled to that level yet. See SourcesByResolution.
+func NextLevel(m *metadata.Meta, ladder Ladder, sources map[int64]map[ulid.ULID]struct{}) (metadata.DownsampleLevel, bool) {
+	next, ok := LadderOf(m, ladder).Next(m.Thanos.Downsample.Resolution)
+	if !ok {
+		return next, false
+	}
+	// Only downsample blocks once we are sure to get roughly 2 chunks out of it.
+	// NOTE(fabxc): this must match with at which block size the compactor creates downsampled
+	// blocks. Otherwise we may never downsample some data.
+	if m.MaxTime-m.MinTime < next.MinBlockRange {
+		return next, false
+	}
+	for _, id := range m.Compaction.Sources {
+		if _, ok := sources[next.Resolution][id]; !ok {
+			return next, true
+		}
+	}
+	return next, false
+}
@@ -0,0 +1,130 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package downsample
+
+import (
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/oklog/ulid/v2"
+
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+)
+
+func TestParseLadder(t *testing.T) {
+	t.Parallel()
+
+	l, err := ParseLadder("5m:40h, 1h:10d")
+	testutil.Ok(t, err)
+	testutil.Equals(t, DefaultLadder, l)
+	testutil.Assert(t, l.IsDefault(), "expected default ladder")
+	testutil.Equals(t, "5m:1d16h,1h:10d", l.String())
+
+	l, err = ParseLadder("1m:4h,15m:2d,6h:60d")
+	testutil.Ok(t, err)
+	testutil.Equals(t, Ladder{
+		{Resolution: time.Minute.Milliseconds(), MinBlockRange: (4 * time.Hour).Milliseconds()},
+		{Resolution: (15 * time.Minute).Milliseconds(), MinBlockRange: (48 * time.Hour).Milliseconds()},
+		{Resolution: (6 * time.Hour).Milliseconds(), MinBlockRange: (60 * 24 * time.Hour).Milliseconds()},
+	}, l)
+	testutil.Assert(t, !l.IsDefault(), "expected custom ladder")
+	testutil.Equals(t, []int64{0, time.Minute.Milliseconds(), (15 * time.Minute).Milliseconds(), (6 * time.Hour).Milliseconds()}, l.Resolutions())
+
+	for _, s := range []string{"", "5m", "5m:abc", "1h:10d,5m:40h", "5m:10d,1h:40h", "0s:40h"} {
+		_, err := ParseLadder(s)
+		testutil.NotOk(t, err, "expected error for %q", s)
+	}
+}
+
+func TestLadder_ValidateCompactionRange(t *testing.T) {
+	t.Parallel()
+
+	maxRange := (14 * 24 * time.Hour).Milliseconds()
+	testutil.Ok(t, DefaultLadder.ValidateCompactionRange(maxRange))
+
+	l, err := ParseLadder("1m:4h,15m:2d,6h:14d")
+	testutil.Ok(t, err)
+	testutil.Ok(t, l.ValidateCompactionRange(maxRange))
+
+	// Blocks are never compacted to 60d, so they would never be downsampled to 6h.
+	l, err = ParseLadder("1m:4h,15m:2d,6h:60d")
+	testutil.Ok(t, err)
+	testutil.NotOk(t, l.ValidateCompactionRange(maxRange))
+}
+
+func TestParseResolutions(t *testing.T) {
+	t.Parallel()
+
+	res, err := ParseResolutions("1h, 5m,1h")
+	testutil.Ok(t, err)
+	testutil.Equals(t, []int64{ResLevel1, ResLevel2}, res)
+
+	_, err = ParseResolutions("0s")
+	testutil.NotOk(t, err)
+	_, err = ParseResolutions("5m,abc")
+	testutil.NotOk(t, err)
+}
+
+func TestLadder_Next(t *testing.T) {
+	t.Parallel()
+
+	next, ok := DefaultLadder.Next(ResLevel0)
+	testutil.Assert(t, ok, "expected next level for raw blocks")
+	testutil.Equals(t, ResLevel1, next.Resolution)
+
+	next, ok = DefaultLadder.Next(ResLevel1)
+	testutil.Assert(t, ok, "expected next level for 5m blocks")
+	testutil.Equals(t, ResLevel2, next.Resolution)
+
+	_, ok = DefaultLadder.Next(ResLevel2)
+	testutil.Assert(t, !ok, "expected no level after the last one")
+	_, ok = DefaultLadder.Next(42)
+	testutil.Assert(t, !ok, "expected no level for unknown resolution")
+	_, ok = Ladder{}.Next(ResLevel0)
+	testutil.Assert(t, !ok, "expected no level for empty ladder")
+}
+
+func TestNextLevel(t *testing.T) {
+	t.Parallel()
+
+	custom, err := ParseLadder("15m:1d,6h:30d")
+	testutil.Ok(t, err)
+
+	meta := func(id uint64, res, rangeMillis int64, levels Ladder) *metadata.Meta {
+		m := &metadata.Meta{}
+		m.ULID = ulid.MustNew(id, nil)
+		m.MaxTime = rangeMillis
+		m.Compaction.Sources = []ulid.ULID{ulid.MustNew(id, nil)}
+		m.Thanos.Downsample.Resolution = res
+		m.Thanos.Downsample.Levels = levels
+		return m
+	}
+
+	// Raw blocks follow the given ladder.
+	next, ok := NextLevel(meta(1, ResLevel0, (24*time.Hour).Milliseconds(), nil), custom, nil)
+	testutil.Assert(t, ok, "expected raw block to be downsampled")
+	testutil.Equals(t, custom[0], next)
+	_, ok = NextLevel(meta(1, ResLevel0, (23*time.Hour).Milliseconds(), nil), custom, nil)
+	testutil.Assert(t, !ok, "expected raw block below the min block range to not be downsampled")
+
+	// Downsampled blocks follow the ladder they were created with.
+	next, ok = NextLevel(meta(2, ResLevel1, ResLevel2DownsampleRange, nil), custom, nil)
+	testutil.Assert(t, ok, "expected 5m block to be downsampled along the default ladder")
+	testutil.Equals(t, DefaultLadder[1], next)
+	next, ok = NextLevel(meta(3, custom[0].Resolution, custom[1].MinBlockRange, custom), DefaultLadder, nil)
+	testutil.Assert(t, ok, "expected 15m block to be downsampled along its ladder")
+	testutil.Equals(t, custom[1], next)
+
+	// Blocks whose sources are all downsampled already are skipped.
+	metas := map[ulid.ULID]*metadata.Meta{}
+	for _, m := range []*metadata.Meta{meta(4, custom[0].Resolution, 0, custom), meta(5, ResLevel1, 0, nil)} {
+		metas[m.ULID] = m
+	}
+	sources := SourcesByResolution(metas)
+	_, ok = NextLevel(meta(4, ResLevel0, (24*time.Hour).Milliseconds(), nil), custom, sources)
+	testutil.Assert(t, !ok, "expected already downsampled block to be skipped")
+	_, ok = NextLevel(meta(4, ResLevel0, (40*time.Hour).Milliseconds(), nil), DefaultLadder, sources)
+	testutil.Assert(t, ok, "expected block downsampled to another resolution to be downsampled")
+}
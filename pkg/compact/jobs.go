@@ -0,0 +1,305 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package compact
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// JobType is the kind of work done by a compactor job.
+type JobType string
+
+const (
+	CompactionJob JobType = "compaction"
+	DownsampleJob JobType = "downsample"
+)
+
+// JobState is the state of a compactor job.
+type JobState string
+
+const (
+	JobQueued  JobState = "queued"
+	JobRunning JobState = "running"
+	JobDone    JobState = "done"
+	JobFailed  JobState = "failed"
+	JobSkipped JobState = "skipped"
+)
+
+// Job is a compaction or downsampling job of the current compactor iteration.
+type Job struct {
+	Type       JobType           `json:"type"`
+	Group      string            `json:"group"`
+	Labels     map[string]string `json:"labels"`
+	Resolution int64             `json:"resolution"`
+	Blocks     []ulid.ULID       `json:"blocks"`
+	State      JobState          `json:"state"`
+	Priority   int               `json:"priority"`
+	QueuedAt   time.Time         `json:"queuedAt"`
+	StartedAt  *time.Time        `json:"startedAt,omitempty"`
+	FinishedAt *time.Time        `json:"finishedAt,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// JobsStatus is a snapshot of the jobs of a JobController and its overrides.
+type JobsStatus struct {
+	Paused bool `json:"paused"`
+	// Jobs are ordered by type, then by the order in which they are picked up.
+	Jobs          []Job          `json:"jobs"`
+	SkippedGroups []string       `json:"skippedGroups"`
+	Priorities    map[string]int `json:"priorities"`
+}
+
+// JobController tracks the compaction and downsampling jobs of the compactor and allows operators
+// to pause and resume the compactor, skip groups and change the order in which groups are worked on.
+// Overrides are kept in memory only and reset on restart.
+type JobController struct {
+	mtx sync.Mutex
+	// resumed is closed when the controller is not paused.
+	resumed    chan struct{}
+	skipped    map[string]struct{}
+	priorities map[string]int
+	jobs       map[JobType][]*Job
+}
+
+// NewJobController creates a new JobController which is not paused.
+func NewJobController() *JobController {
+	resumed := make(chan struct{})
+	close(resumed)
+	return &JobController{
+		resumed:    resumed,
+		skipped:    map[string]struct{}{},
+		priorities: map[string]int{},
+		jobs:       map[JobType][]*Job{},
+	}
+}
+
+// Pause stops the compactor from starting new jobs. Running jobs are finished.
+func (c *JobController) Pause() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	select {
+	case <-c.resumed:
+		c.resumed = make(chan struct{})
+	default:
+	}
+}
+
+// Resume lets the compactor start new jobs again.
+func (c *JobController) Resume() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	select {
+	case <-c.resumed:
+	default:
+		close(c.resumed)
+	}
+}
+
+// Paused returns true if the compactor is paused.
+func (c *JobController) Paused() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	select {
+	case <-c.resumed:
+		return false
+	default:
+		return true
+	}
+}
+
+// WaitResumed blocks while the compactor is paused.
+func (c *JobController) WaitResumed(ctx context.Context) error {
+	c.mtx.Lock()
+	resumed := c.resumed
+	c.mtx.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-resumed:
+		return nil
+	}
+}
+
+// SetSkipped sets whether jobs of the given group are skipped. Running jobs of the group are not interrupted.
+func (c *JobController) SetSkipped(group string, skip bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if skip {
+		c.skipped[group] = struct{}{}
+		return
+	}
+	delete(c.skipped, group)
+}
+
+// Skipped returns true if jobs of the given group are skipped.
+func (c *JobController) Skipped(group string) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	_, ok := c.skipped[group]
+	return ok
+}
+
+// SetPriority sets the priority of the given group. Queued jobs of groups with higher priority are started first,
+// the default priority is 0.
+func (c *JobController) SetPriority(group string, priority int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if priority == 0 {
+		delete(c.priorities, group)
+	} else {
+		c.priorities[group] = priority
+	}
+	for _, jobs := range c.jobs {
+		for _, j := range jobs {
+			if j.Group == group {
+				j.Priority = priority
+			}
+		}
+	}
+}
+
+// Priority returns the priority of the given group.
+func (c *JobController) Priority(group string) int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.priorities[group]
+}
+
+// Queue replaces all jobs of the given type with the given jobs in queued state.
+func (c *JobController) Queue(typ JobType, jobs []Job) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	now := time.Now()
+	queued := make([]*Job, 0, len(jobs))
+	for _, j := range jobs {
+		j.Type = typ
+		j.State = JobQueued
+		j.Priority = c.priorities[j.Group]
+		j.QueuedAt = now
+		queued = append(queued, &j)
+	}
+	c.jobs[typ] = queued
+}
+
+// Next returns the index of the job among the given queued ones which should be started next, i.e. the first one
+// of the highest priority. Skipped jobs are marked as such and never returned. If there is none, -1 is returned.
+func (c *JobController) Next(typ JobType, queued []Job) int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	next := -1
+	for i, j := range queued {
+		if _, ok := c.skipped[j.Group]; ok {
+			c.setState(typ, j, JobSkipped, nil)
+			continue
+		}
+		if next == -1 || c.priorities[j.Group] > c.priorities[queued[next].Group] {
+			next = i
+		}
+	}
+	return next
+}
+
+// Start marks the job as running.
+func (c *JobController) Start(typ JobType, job Job) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.setState(typ, job, JobRunning, nil)
+}
+
+// Finish marks the job as done, or as failed if err is not nil.
+func (c *JobController) Finish(typ JobType, job Job, err error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if err != nil {
+		c.setState(typ, job, JobFailed, err)
+		return
+	}
+	c.setState(typ, job, JobDone, nil)
+}
+
+// skip marks the job as skipped, e.g. because it was claimed by another compactor replica.
+func (c *JobController) skip(typ JobType, job Job) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.setState(typ, job, JobSkipped, nil)
+}
+
+func (c *JobController) setState(typ JobType, job Job, state JobState, err error) {
+	for _, j := range c.jobs[typ] {
+		if j.Group != job.Group || !slices.Equal(j.Blocks, job.Blocks) {
+			continue
+		}
+		if state == JobSkipped && j.State == JobSkipped {
+			return
+		}
+		now := time.Now()
+		j.State = state
+		switch state {
+		case JobRunning:
+			j.StartedAt = &now
+		case JobDone, JobFailed, JobSkipped:
+			j.FinishedAt = &now
+		}
+		if err != nil {
+			j.Error = err.Error()
+		}
+		return
+	}
+}
+
+// Status returns a snapshot of all jobs and overrides.
+func (c *JobController) Status() JobsStatus {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	status := JobsStatus{
+		Jobs:          []Job{},
+		SkippedGroups: make([]string, 0, len(c.skipped)),
+		Priorities:    make(map[string]int, len(c.priorities)),
+	}
+	select {
+	case <-c.resumed:
+	default:
+		status.Paused = true
+	}
+	for _, typ := range []JobType{CompactionJob, DownsampleJob} {
+		jobs := make([]Job, 0, len(c.jobs[typ]))
+		for _, j := range c.jobs[typ] {
+			jobs = append(jobs, *j)
+		}
+		// Queued jobs are picked up by priority, so list them in that order.
+		sort.SliceStable(jobs, func(i, j int) bool {
+			return jobs[i].State != JobQueued && jobs[j].State == JobQueued ||
+				jobs[i].State == JobQueued && jobs[j].State == JobQueued && jobs[i].Priority > jobs[j].Priority
+		})
+		status.Jobs = append(status.Jobs, jobs...)
+	}
+	for g := range c.skipped {
+		status.SkippedGroups = append(status.SkippedGroups, g)
+	}
+	sort.Strings(status.SkippedGroups)
+	for g, p := range c.priorities {
+		status.Priorities[g] = p
+	}
+	return status
+}
@@ -0,0 +1,102 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package compact
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+)
+
+func TestJobController_PauseResume(t *testing.T) {
+	t.Parallel()
+
+	c := NewJobController()
+	testutil.Assert(t, !c.Paused(), "expected controller to not be paused initially")
+	testutil.Ok(t, c.WaitResumed(context.Background()))
+
+	c.Pause()
+	c.Pause()
+	testutil.Assert(t, c.Paused(), "expected controller to be paused")
+	testutil.Assert(t, c.Status().Paused, "expected status to be paused")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	testutil.Equals(t, context.DeadlineExceeded, c.WaitResumed(ctx))
+
+	done := make(chan error)
+	go func() { done <- c.WaitResumed(context.Background()) }()
+	c.Resume()
+	c.Resume()
+	testutil.Ok(t, <-done)
+	testutil.Assert(t, !c.Paused(), "expected controller to be resumed")
+}
+
+func TestJobController_Next(t *testing.T) {
+	t.Parallel()
+
+	c := NewJobController()
+	queued := []Job{
+		{Group: "a", Blocks: []ulid.ULID{ulid.MustNew(1, nil)}},
+		{Group: "b", Blocks: []ulid.ULID{ulid.MustNew(2, nil)}},
+		{Group: "c", Blocks: []ulid.ULID{ulid.MustNew(3, nil)}},
+	}
+	c.Queue(CompactionJob, queued)
+	testutil.Equals(t, 0, c.Next(CompactionJob, queued))
+
+	c.SetPriority("c", 10)
+	c.SetSkipped("a", true)
+	testutil.Equals(t, 2, c.Next(CompactionJob, queued))
+
+	status := c.Status()
+	testutil.Equals(t, []string{"a"}, status.SkippedGroups)
+	testutil.Equals(t, map[string]int{"c": 10}, status.Priorities)
+	// Skipped jobs are listed first, queued ones by priority.
+	testutil.Equals(t, []string{"a", "c", "b"}, jobGroups(status.Jobs))
+	testutil.Equals(t, []JobState{JobSkipped, JobQueued, JobQueued}, jobStates(status.Jobs))
+	testutil.Equals(t, 10, status.Jobs[1].Priority)
+
+	c.Start(CompactionJob, queued[2])
+	c.Finish(CompactionJob, queued[2], errors.New("boom"))
+	testutil.Equals(t, 1, c.Next(CompactionJob, queued[:2]))
+	c.Start(CompactionJob, queued[1])
+
+	status = c.Status()
+	testutil.Equals(t, []string{"a", "b", "c"}, jobGroups(status.Jobs))
+	testutil.Equals(t, []JobState{JobSkipped, JobRunning, JobFailed}, jobStates(status.Jobs))
+	testutil.Assert(t, status.Jobs[1].StartedAt != nil, "expected running job to have a start time")
+	testutil.Equals(t, "boom", status.Jobs[2].Error)
+
+	// Only skipped jobs are left.
+	testutil.Equals(t, -1, c.Next(CompactionJob, queued[:1]))
+	c.SetSkipped("a", false)
+	testutil.Equals(t, 0, c.Next(CompactionJob, queued[:1]))
+
+	// Queueing replaces jobs of the same type only.
+	c.Queue(DownsampleJob, queued[:1])
+	c.Queue(CompactionJob, nil)
+	status = c.Status()
+	testutil.Equals(t, 1, len(status.Jobs))
+	testutil.Equals(t, DownsampleJob, status.Jobs[0].Type)
+}
+
+func jobGroups(jobs []Job) []string {
+	var groups []string
+	for _, j := range jobs {
+		groups = append(groups, j.Group)
+	}
+	return groups
+}
+
+func jobStates(jobs []Job) []JobState {
+	var states []JobState
+	for _, j := range jobs {
+		states = append(states, j.State)
+	}
+	return states
+}
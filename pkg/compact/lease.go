@@ -0,0 +1,190 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package compact
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/objstore"
+
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+// GroupLeasesDir is the directory in the bucket holding leases of compactor replicas.
+const GroupLeasesDir = "compactor-leases"
+
+// GroupLeaser coordinates work on compaction groups between multiple compactor replicas,
+// so that a group is not compacted or downsampled by more than one replica at a time.
+// Keys are compaction group keys, see metadata.Thanos.GroupKey.
+type GroupLeaser interface {
+	// Claim tries to acquire the lease for the given key. If it is held by another replica, ok is false.
+	// Otherwise the lease is renewed until release is called, and the returned context is canceled
+	// once the lease is lost, e.g. because it could not be renewed in time.
+	Claim(ctx context.Context, key string) (leaseCtx context.Context, release func(), ok bool, err error)
+}
+
+// groupLease is the content of a lease object in the bucket.
+type groupLease struct {
+	Owner   string    `json:"owner"`
+	Expires time.Time `json:"expires"`
+}
+
+// BucketGroupLeaser is a GroupLeaser storing leases as objects in the bucket.
+// A lease is claimed by writing it and reading it back after a settle delay, so that
+// of concurrent claims usually only the last written one wins. Buckets provide no
+// conditional writes, so this is best effort rather than mutual exclusion: claims
+// whose writes are further apart than the settle delay can both succeed. This relies
+// on the strong read-after-write consistency provided by all major object storages.
+// Leases of crashed replicas expire after the TTL and can be claimed by others.
+type BucketGroupLeaser struct {
+	logger      log.Logger
+	bkt         objstore.Bucket
+	owner       string
+	ttl         time.Duration
+	settleDelay time.Duration
+
+	claims *prometheus.CounterVec
+}
+
+// NewBucketGroupLeaser creates a new BucketGroupLeaser claiming leases as the given owner.
+func NewBucketGroupLeaser(logger log.Logger, reg prometheus.Registerer, bkt objstore.Bucket, owner string, ttl time.Duration) *BucketGroupLeaser {
+	return &BucketGroupLeaser{
+		logger:      logger,
+		bkt:         bkt,
+		owner:       owner,
+		ttl:         ttl,
+		settleDelay: 2 * time.Second,
+		claims: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_compact_group_lease_claims_total",
+			Help: "Total number of attempts to claim a group lease by result.",
+		}, []string{"result"}),
+	}
+}
+
+func (l *BucketGroupLeaser) Claim(ctx context.Context, key string) (context.Context, func(), bool, error) {
+	cur, ok, err := l.read(ctx, key)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if ok && cur.Owner != l.owner && time.Now().Before(cur.Expires) {
+		l.claims.WithLabelValues("held").Inc()
+		return nil, nil, false, nil
+	}
+
+	if err := l.write(ctx, key); err != nil {
+		return nil, nil, false, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, nil, false, ctx.Err()
+	case <-time.After(l.settleDelay):
+	}
+	cur, ok, err = l.read(ctx, key)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if !ok || cur.Owner != l.owner {
+		l.claims.WithLabelValues("held").Inc()
+		return nil, nil, false, nil
+	}
+	l.claims.WithLabelValues("claimed").Inc()
+
+	leaseCtx, cancel := context.WithCancel(ctx)
+	var (
+		done = make(chan struct{})
+		wg   sync.WaitGroup
+	)
+	wg.Go(func() {
+		defer cancel()
+		ticker := time.NewTicker(l.ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-leaseCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := l.renew(leaseCtx, key); err != nil {
+				level.Warn(l.logger).Log("msg", "lost group lease, aborting work on group", "key", key, "err", err)
+				return
+			}
+		}
+	})
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+			cancel()
+
+			// The parent context may already be canceled, still try to release the lease for others.
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if cur, ok, err := l.read(ctx, key); err == nil && ok && cur.Owner == l.owner {
+				if err := l.bkt.Delete(ctx, leasePath(key)); err != nil {
+					level.Warn(l.logger).Log("msg", "failed to release group lease", "key", key, "err", err)
+				}
+			}
+		})
+	}
+	return leaseCtx, release, true, nil
+}
+
+func (l *BucketGroupLeaser) renew(ctx context.Context, key string) error {
+	cur, ok, err := l.read(ctx, key)
+	if err != nil {
+		return err
+	}
+	if ok && cur.Owner != l.owner {
+		return errors.Errorf("lease taken over by %s", cur.Owner)
+	}
+	return l.write(ctx, key)
+}
+
+func (l *BucketGroupLeaser) read(ctx context.Context, key string) (lease groupLease, ok bool, err error) {
+	r, err := l.bkt.Get(ctx, leasePath(key))
+	if l.bkt.IsObjNotFoundErr(err) {
+		return lease, false, nil
+	}
+	if err != nil {
+		return lease, false, errors.Wrapf(err, "get group lease %s", key)
+	}
+	defer runutil.CloseWithLogOnErr(l.logger, r, "group lease reader")
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return lease, false, errors.Wrapf(err, "read group lease %s", key)
+	}
+	if err := json.Unmarshal(b, &lease); err != nil {
+		return lease, false, errors.Wrapf(err, "unmarshal group lease %s", key)
+	}
+	return lease, true, nil
+}
+
+func (l *BucketGroupLeaser) write(ctx context.Context, key string) error {
+	b, err := json.Marshal(groupLease{Owner: l.owner, Expires: time.Now().Add(l.ttl)})
+	if err != nil {
+		return errors.Wrap(err, "marshal group lease")
+	}
+	return errors.Wrapf(l.bkt.Upload(ctx, leasePath(key), bytes.NewReader(b)), "upload group lease %s", key)
+}
+
+func leasePath(key string) string {
+	return path.Join(GroupLeasesDir, key+".json")
+}
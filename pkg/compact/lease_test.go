@@ -0,0 +1,138 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package compact
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/thanos-io/objstore"
+
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+)
+
+func newTestGroupLeaser(bkt objstore.Bucket, owner string, ttl time.Duration) *BucketGroupLeaser {
+	l := NewBucketGroupLeaser(log.NewNopLogger(), prometheus.NewRegistry(), bkt, owner, ttl)
+	l.settleDelay = 0
+	return l
+}
+
+func TestBucketGroupLeaser_Claim(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	a := newTestGroupLeaser(bkt, "a", time.Minute)
+	b := newTestGroupLeaser(bkt, "b", time.Minute)
+
+	_, releaseA, ok, err := a.Claim(ctx, "0@1")
+	testutil.Ok(t, err)
+	testutil.Assert(t, ok, "expected a to claim free lease")
+
+	_, _, ok, err = b.Claim(ctx, "0@1")
+	testutil.Ok(t, err)
+	testutil.Assert(t, !ok, "expected b not to claim lease held by a")
+	testutil.Equals(t, 1.0, promtest.ToFloat64(b.claims.WithLabelValues("held")))
+
+	// Other groups are not affected.
+	_, releaseB, ok, err := b.Claim(ctx, "0@2")
+	testutil.Ok(t, err)
+	testutil.Assert(t, ok, "expected b to claim free lease")
+	releaseB()
+
+	releaseA()
+	exists, err := bkt.Exists(ctx, leasePath("0@1"))
+	testutil.Ok(t, err)
+	testutil.Assert(t, !exists, "expected lease to be deleted on release")
+
+	_, releaseB, ok, err = b.Claim(ctx, "0@1")
+	testutil.Ok(t, err)
+	testutil.Assert(t, ok, "expected b to claim released lease")
+	releaseB()
+}
+
+func TestBucketGroupLeaser_ClaimExpired(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+
+	// A lease left behind by a crashed replica.
+	b, err := json.Marshal(groupLease{Owner: "crashed", Expires: time.Now().Add(-time.Second)})
+	testutil.Ok(t, err)
+	testutil.Ok(t, bkt.Upload(ctx, leasePath("0@1"), bytes.NewReader(b)))
+
+	l := newTestGroupLeaser(bkt, "a", time.Minute)
+	_, release, ok, err := l.Claim(ctx, "0@1")
+	testutil.Ok(t, err)
+	testutil.Assert(t, ok, "expected expired lease to be claimed")
+	release()
+}
+
+func TestBucketGroupLeaser_LostLease(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	l := newTestGroupLeaser(bkt, "a", 30*time.Millisecond)
+
+	leaseCtx, release, ok, err := l.Claim(ctx, "0@1")
+	testutil.Ok(t, err)
+	testutil.Assert(t, ok, "expected a to claim free lease")
+	defer release()
+
+	// Another replica takes over, e.g. because renewals were delayed beyond the TTL.
+	b, err := json.Marshal(groupLease{Owner: "b", Expires: time.Now().Add(time.Minute)})
+	testutil.Ok(t, err)
+	testutil.Ok(t, bkt.Upload(ctx, leasePath("0@1"), bytes.NewReader(b)))
+
+	select {
+	case <-leaseCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected lease context to be canceled after losing the lease")
+	}
+
+	// The lease of the new owner is kept on release.
+	release()
+	exists, err := bkt.Exists(ctx, leasePath("0@1"))
+	testutil.Ok(t, err)
+	testutil.Assert(t, exists, "expected lease of new owner to be kept")
+}
+
+func TestRemovedGroupBlock(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	a, b := ulid.MustNew(1, nil), ulid.MustNew(2, nil)
+	for _, id := range []ulid.ULID{a, b} {
+		testutil.Ok(t, bkt.Upload(ctx, path.Join(id.String(), metadata.MetaFilename), bytes.NewReader([]byte("{}"))))
+	}
+
+	_, ok, err := removedGroupBlock(ctx, bkt, []ulid.ULID{a, b})
+	testutil.Ok(t, err)
+	testutil.Assert(t, !ok, "expected no removed block")
+
+	// Blocks compacted by another replica are marked for deletion first and deleted later.
+	testutil.Ok(t, bkt.Upload(ctx, path.Join(b.String(), metadata.DeletionMarkFilename), bytes.NewReader([]byte("{}"))))
+	id, ok, err := removedGroupBlock(ctx, bkt, []ulid.ULID{a, b})
+	testutil.Ok(t, err)
+	testutil.Assert(t, ok, "expected block marked for deletion to be removed")
+	testutil.Equals(t, b, id)
+
+	testutil.Ok(t, bkt.Delete(ctx, path.Join(a.String(), metadata.MetaFilename)))
+	id, ok, err = removedGroupBlock(ctx, bkt, []ulid.ULID{a, b})
+	testutil.Ok(t, err)
+	testutil.Assert(t, ok, "expected deleted block to be removed")
+	testutil.Equals(t, a, id)
+}
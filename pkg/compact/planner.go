@@ -231,6 +231,7 @@ type largeTotalIndexSizeFilter struct {
 	bkt                    objstore.Bucket
 	markedForNoCompact     prometheus.Counter
 	totalMaxIndexSizeBytes int64
+	splitShards            uint64
 }
 
 var _ Planner = &largeTotalIndexSizeFilter{}
@@ -305,6 +306,12 @@ func WithLargeTotalIndexSizeFilter(with *tsdbBasedPlanner, bkt objstore.Bucket,
 	return &largeTotalIndexSizeFilter{tsdbBasedPlanner: with, bkt: bkt, totalMaxIndexSizeBytes: totalMaxIndexSizeBytes, markedForNoCompact: markedForNoCompact}
 }
 
+// SetSplitShards makes the filter take split compaction into account: blocks which are not sharded yet are split
+// into the given number of shards when compacted, so each resulting block only gets a part of their index.
+func (t *largeTotalIndexSizeFilter) SetSplitShards(shards uint64) {
+	t.splitShards = shards
+}
+
 func (t *largeTotalIndexSizeFilter) plan(ctx context.Context, extraNoCompactMarked map[ulid.ULID]*metadata.NoCompactMark, metasByMinTime []*metadata.Meta) ([]*metadata.Meta, error) {
 	noCompactMarked := t.noCompBlocksFunc()
 	copiedNoCompactMarked := make(map[ulid.ULID]*metadata.NoCompactMark, len(noCompactMarked)+len(extraNoCompactMarked))
@@ -334,6 +341,9 @@ PlanLoop:
 				}
 				indexSize = attr.Size
 			}
+			if t.splitShards > 1 && p.Thanos.Shard == nil {
+				indexSize /= int64(t.splitShards)
+			}
 
 			if maxIndexSize < indexSize {
 				maxIndexSize = indexSize
@@ -0,0 +1,510 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package compact
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/thanos-io/objstore"
+
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
+)
+
+// SimulatedJobType is the type of work the compactor would do in a SimulatedJob.
+type SimulatedJobType string
+
+const (
+	// SimulatedCompaction compacts the input blocks into the output blocks.
+	SimulatedCompaction SimulatedJobType = "compact"
+	// SimulatedDownsampling downsamples the input block into the output block.
+	SimulatedDownsampling SimulatedJobType = "downsample"
+	// SimulatedRetention marks the input block for deletion because it exceeds the retention.
+	SimulatedRetention SimulatedJobType = "retention"
+	// SimulatedGarbageCollection marks the input block for deletion because its data is contained in other blocks.
+	SimulatedGarbageCollection SimulatedJobType = "garbage-collect"
+	// SimulatedNoCompactMark marks the input block for no compaction, e.g. because the resulting index would be too large.
+	SimulatedNoCompactMark SimulatedJobType = "no-compact"
+)
+
+// SimulatedJob is a single step the compactor would take on the bucket.
+type SimulatedJob struct {
+	Type       SimulatedJobType `json:"type"`
+	Group      string           `json:"group"`
+	Labels     labels.Labels    `json:"labels"`
+	Resolution int64            `json:"resolution"`
+	// Inputs are the blocks the job reads or marks. They might be outputs of earlier jobs.
+	Inputs []ulid.ULID `json:"inputs"`
+	// Outputs are the blocks the job would upload. Their IDs are made up and only used to refer to them in later jobs.
+	Outputs []ulid.ULID `json:"outputs,omitempty"`
+	MinTime int64       `json:"min_time"`
+	MaxTime int64       `json:"max_time"`
+	// EstimatedSizeBytes is the estimated total size of the output blocks, or of the input blocks for deletions.
+	// It is zero if unknown. As for the index size limit, it assumes inputs share no data, so it is an upper bound.
+	EstimatedSizeBytes int64  `json:"estimated_size_bytes,omitempty"`
+	Details            string `json:"details,omitempty"`
+}
+
+// SimulationConfig configures SimulateCompaction.
+type SimulationConfig struct {
+	// RetentionByResolution is the retention applied after compaction and downsampling. Zero disables it for a resolution.
+	RetentionByResolution map[ResolutionLevel]time.Duration
+	// DisableDownsampling disables simulation of downsampling.
+	DisableDownsampling bool
+	// DownsamplingLadder is the ladder raw blocks are downsampled along. Defaults to downsample.DefaultLadder.
+	DownsamplingLadder downsample.Ladder
+	// NoDownsampleMarks are the no-downsample marks of the given blocks, e.g. gathered by
+	// downsample.GatherNoDownsampleMarkFilter. Marked blocks are not downsampled.
+	NoDownsampleMarks map[ulid.ULID]*metadata.NoDownsampleMark
+}
+
+// SimulateCompaction plans the work the compactor would do on the given blocks until no more work is left, without
+// touching any block data: it groups and plans blocks with the given grouper and planner, replacing planned blocks
+// with estimated outputs, downsamples blocks which are eligible and finally applies the retention.
+// The planner must write to the given DryRunBucket, so that blocks it marks for no compaction are reported as jobs.
+// The bucket is also used to read index sizes of blocks which do not list their files in their meta.
+func SimulateCompaction(ctx context.Context, bkt *DryRunBucket, grouper Grouper, planner Planner, metas map[ulid.ULID]*metadata.Meta, conf SimulationConfig) ([]SimulatedJob, error) {
+	s := &simulation{
+		bkt:          bkt,
+		metas:        make(map[ulid.ULID]*metadata.Meta, len(metas)),
+		noCompact:    map[ulid.ULID]struct{}{},
+		noDownsample: make(map[ulid.ULID]struct{}, len(conf.NoDownsampleMarks)),
+	}
+	for id := range conf.NoDownsampleMarks {
+		s.noDownsample[id] = struct{}{}
+	}
+	for id, m := range metas {
+		// Metas are modified during the simulation.
+		mc := *m
+		s.metas[id] = &mc
+	}
+
+	ladder := conf.DownsamplingLadder
+	if len(ladder) == 0 {
+		ladder = downsample.DefaultLadder
+	}
+	for {
+		if err := s.compact(ctx, grouper, planner); err != nil {
+			return nil, err
+		}
+		if conf.DisableDownsampling {
+			break
+		}
+		// The compactor runs downsampling twice, so that blocks downsampled to 5m can be downsampled to 1h right away.
+		downsampled, err := s.downsample(ctx, ladder)
+		if err != nil {
+			return nil, err
+		}
+		again, err := s.downsample(ctx, ladder)
+		if err != nil {
+			return nil, err
+		}
+		// Newly downsampled blocks are compacted in the next compactor iteration.
+		if downsampled+again == 0 {
+			break
+		}
+	}
+	if err := s.applyRetention(ctx, conf.RetentionByResolution); err != nil {
+		return nil, err
+	}
+	return s.jobs, nil
+}
+
+type simulation struct {
+	bkt          *DryRunBucket
+	metas        map[ulid.ULID]*metadata.Meta
+	noCompact    map[ulid.ULID]struct{}
+	noDownsample map[ulid.ULID]struct{}
+	jobs         []SimulatedJob
+}
+
+func (s *simulation) compact(ctx context.Context, grouper Grouper, planner Planner) error {
+	for {
+		// Blocks marked for no compaction during the simulation are excluded like the compactor would after its next sync.
+		compactable := make(map[ulid.ULID]*metadata.Meta, len(s.metas))
+		for id, m := range s.metas {
+			if _, ok := s.noCompact[id]; !ok {
+				compactable[id] = m
+			}
+		}
+		groups, err := grouper.Groups(compactable)
+		if err != nil {
+			return errors.Wrap(err, "group blocks")
+		}
+
+		planned := false
+		for _, g := range groups {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			toCompact, err := planner.Plan(ctx, g.metasByMinTime, nil, g.Extensions())
+			if err != nil {
+				return errors.Wrapf(err, "plan group %s", g.Key())
+			}
+			if err := s.addNoCompactMarks(g); err != nil {
+				return err
+			}
+			if len(toCompact) == 0 {
+				continue
+			}
+			planned = true
+
+			if err := s.addCompaction(ctx, g, toCompact); err != nil {
+				return err
+			}
+		}
+		if !planned {
+			return nil
+		}
+	}
+}
+
+func (s *simulation) addCompaction(ctx context.Context, g *Group, toCompact []*metadata.Meta) error {
+	var (
+		inputs      = make([]ulid.ULID, 0, len(toCompact))
+		blockMetas  = make([]*tsdb.BlockMeta, 0, len(toCompact))
+		indexSize   int64
+		chunksSize  int64
+		overlapping = len(selectOverlappingMetas(toCompact)) > 0
+	)
+	for _, m := range toCompact {
+		inputs = append(inputs, m.ULID)
+		blockMetas = append(blockMetas, &m.BlockMeta)
+
+		idx, chks, err := s.blockSize(ctx, m)
+		if err != nil {
+			return err
+		}
+		indexSize += idx
+		chunksSize += chks
+		delete(s.metas, m.ULID)
+	}
+
+	shards := []*metadata.ThanosShard{g.Shard()}
+	if g.splitShards > 1 {
+		shards = shards[:0]
+		for i := range g.splitShards {
+			shards = append(shards, &metadata.ThanosShard{Index: i, Count: g.splitShards})
+		}
+	}
+
+	job := SimulatedJob{
+		Type:               SimulatedCompaction,
+		Group:              g.Key(),
+		Labels:             g.Labels(),
+		Resolution:         g.Resolution(),
+		Inputs:             inputs,
+		EstimatedSizeBytes: indexSize + chunksSize,
+	}
+	var details []string
+	if overlapping {
+		details = append(details, "vertical compaction")
+	}
+	if len(shards) > 1 {
+		details = append(details, fmt.Sprintf("split into %d shards", len(shards)))
+	}
+	job.Details = strings.Join(details, ", ")
+
+	for _, shard := range shards {
+		out := &metadata.Meta{
+			BlockMeta: *tsdb.CompactBlockMetas(ulid.Make(), blockMetas...),
+			Thanos: metadata.Thanos{
+				Labels:     g.Labels().Map(),
+				Downsample: g.downsample(),
+				Source:     metadata.CompactorSource,
+				Shard:      shard,
+				Extensions: g.Extensions(),
+				Files:      estimatedFiles(indexSize/int64(len(shards)), chunksSize/int64(len(shards))),
+			},
+		}
+		s.metas[out.ULID] = out
+		job.Outputs = append(job.Outputs, out.ULID)
+		job.MinTime, job.MaxTime = out.MinTime, out.MaxTime
+	}
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+// addNoCompactMarks adds jobs for blocks of the group the planner marked for no compaction.
+func (s *simulation) addNoCompactMarks(g *Group) error {
+	marks, err := s.bkt.NoCompactMarks()
+	if err != nil {
+		return err
+	}
+	for _, mark := range marks {
+		if _, ok := s.noCompact[mark.ID]; ok {
+			continue
+		}
+		m, ok := s.metas[mark.ID]
+		if !ok {
+			continue
+		}
+		s.noCompact[mark.ID] = struct{}{}
+		s.jobs = append(s.jobs, SimulatedJob{
+			Type:       SimulatedNoCompactMark,
+			Group:      g.Key(),
+			Labels:     g.Labels(),
+			Resolution: g.Resolution(),
+			Inputs:     []ulid.ULID{mark.ID},
+			MinTime:    m.MinTime,
+			MaxTime:    m.MaxTime,
+			Details:    fmt.Sprintf("%s: %s", mark.Reason, mark.Details),
+		})
+	}
+	return nil
+}
+
+// addNoDownsampleMarks adds blocks marked for no downsampling during the simulation to the marked ones.
+func (s *simulation) addNoDownsampleMarks() error {
+	marks, err := s.bkt.NoDownsampleMarks()
+	if err != nil {
+		return err
+	}
+	for _, mark := range marks {
+		s.noDownsample[mark.ID] = struct{}{}
+	}
+	return nil
+}
+
+// downsample simulates a downsampling pass of the compactor and returns the number of downsampled blocks.
+// Like in the compactor, blocks marked for no downsampling are skipped.
+func (s *simulation) downsample(ctx context.Context, ladder downsample.Ladder) (int, error) {
+	if err := s.addNoDownsampleMarks(); err != nil {
+		return 0, err
+	}
+	sources := downsample.SourcesByResolution(s.metas)
+
+	var toDownsample []*metadata.Meta
+	for id, m := range s.metas {
+		if _, ok := s.noDownsample[id]; ok {
+			continue
+		}
+		if _, ok := downsample.NextLevel(m, ladder, sources); ok {
+			toDownsample = append(toDownsample, m)
+		}
+	}
+	sort.Slice(toDownsample, func(i, j int) bool {
+		return toDownsample[i].ULID.Compare(toDownsample[j].ULID) < 0
+	})
+
+	for _, m := range toDownsample {
+		blockLadder := downsample.LadderOf(m, ladder)
+		next, _ := blockLadder.Next(m.Thanos.Downsample.Resolution)
+		resolution := next.Resolution
+		indexSize, _, err := s.blockSize(ctx, m)
+		if err != nil {
+			return 0, err
+		}
+
+		out := *m
+		out.ULID = ulid.Make()
+		out.Thanos.Downsample.Resolution = resolution
+		if !blockLadder.IsDefault() {
+			out.Thanos.Downsample.Levels = blockLadder
+		}
+		out.Thanos.Source = metadata.CompactorSource
+		// Downsampled blocks hold the same series, but the size of their chunks cannot be estimated.
+		out.Thanos.Files = estimatedFiles(indexSize, 0)
+		s.metas[out.ULID] = &out
+
+		s.jobs = append(s.jobs, SimulatedJob{
+			Type:       SimulatedDownsampling,
+			Group:      m.Thanos.GroupKey(),
+			Labels:     labels.FromMap(m.Thanos.Labels),
+			Resolution: m.Thanos.Downsample.Resolution,
+			Inputs:     []ulid.ULID{m.ULID},
+			Outputs:    []ulid.ULID{out.ULID},
+			MinTime:    m.MinTime,
+			MaxTime:    m.MaxTime,
+			Details:    fmt.Sprintf("to resolution %s", time.Duration(resolution)*time.Millisecond),
+		})
+	}
+	return len(toDownsample), nil
+}
+
+func (s *simulation) applyRetention(ctx context.Context, retentionByResolution map[ResolutionLevel]time.Duration) error {
+	ids := make([]ulid.ULID, 0, len(s.metas))
+	for id := range s.metas {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].Compare(ids[j]) < 0
+	})
+
+	for _, id := range ids {
+		m := s.metas[id]
+		retention := retentionByResolution[ResolutionLevel(m.Thanos.Downsample.Resolution)]
+		if retention.Seconds() == 0 || !time.Now().After(time.Unix(m.MaxTime/1000, 0).Add(retention)) {
+			continue
+		}
+		indexSize, chunksSize, err := s.blockSize(ctx, m)
+		if err != nil {
+			return err
+		}
+		s.jobs = append(s.jobs, SimulatedJob{
+			Type:               SimulatedRetention,
+			Group:              m.Thanos.GroupKey(),
+			Labels:             labels.FromMap(m.Thanos.Labels),
+			Resolution:         m.Thanos.Downsample.Resolution,
+			Inputs:             []ulid.ULID{id},
+			MinTime:            m.MinTime,
+			MaxTime:            m.MaxTime,
+			EstimatedSizeBytes: indexSize + chunksSize,
+			Details:            fmt.Sprintf("exceeds retention of %v", retention),
+		})
+		delete(s.metas, id)
+	}
+	return nil
+}
+
+// blockSize returns the index and chunks size of the block from its meta, falling back to the
+// size of the index in the bucket if the meta does not list files.
+func (s *simulation) blockSize(ctx context.Context, m *metadata.Meta) (index, chunks int64, err error) {
+	index = -1
+	for _, f := range m.Thanos.Files {
+		switch {
+		case f.RelPath == block.IndexFilename:
+			index = f.SizeBytes
+		case strings.HasPrefix(f.RelPath, block.ChunksDirname+"/"):
+			chunks += f.SizeBytes
+		}
+	}
+	if index >= 0 {
+		return index, chunks, nil
+	}
+	attr, err := s.bkt.Attributes(ctx, filepath.Join(m.ULID.String(), block.IndexFilename))
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "get attr of %v", filepath.Join(m.ULID.String(), block.IndexFilename))
+	}
+	// Cache the size, as planners would look it up in the bucket for every plan otherwise.
+	m.Thanos.Files = append(m.Thanos.Files, metadata.File{RelPath: block.IndexFilename, SizeBytes: attr.Size})
+	return attr.Size, chunks, nil
+}
+
+func estimatedFiles(indexSize, chunksSize int64) []metadata.File {
+	files := []metadata.File{{RelPath: block.IndexFilename, SizeBytes: indexSize}}
+	if chunksSize > 0 {
+		files = append(files, metadata.File{RelPath: path.Join(block.ChunksDirname, "000001"), SizeBytes: chunksSize})
+	}
+	return files
+}
+
+// DryRunBucket is a bucket which records uploads and deletions instead of executing them, so that
+// components writing markers, like planners, can be run against a live bucket without modifying it.
+// Recorded objects are visible to Exists, Get and Attributes calls on the DryRunBucket.
+type DryRunBucket struct {
+	objstore.Bucket
+
+	mtx      sync.Mutex
+	uploaded map[string][]byte
+	deleted  map[string]struct{}
+}
+
+// NewDryRunBucket returns a DryRunBucket reading from the given bucket.
+func NewDryRunBucket(bkt objstore.Bucket) *DryRunBucket {
+	return &DryRunBucket{Bucket: bkt, uploaded: map[string][]byte{}, deleted: map[string]struct{}{}}
+}
+
+func (b *DryRunBucket) Upload(_ context.Context, name string, r io.Reader, _ ...objstore.ObjectUploadOption) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.uploaded[name] = content
+	delete(b.deleted, name)
+	return nil
+}
+
+func (b *DryRunBucket) Delete(_ context.Context, name string) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	delete(b.uploaded, name)
+	b.deleted[name] = struct{}{}
+	return nil
+}
+
+func (b *DryRunBucket) Exists(ctx context.Context, name string) (bool, error) {
+	b.mtx.Lock()
+	_, uploaded := b.uploaded[name]
+	_, deleted := b.deleted[name]
+	b.mtx.Unlock()
+
+	if uploaded || deleted {
+		return uploaded, nil
+	}
+	return b.Bucket.Exists(ctx, name)
+}
+
+func (b *DryRunBucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	b.mtx.Lock()
+	content, uploaded := b.uploaded[name]
+	b.mtx.Unlock()
+
+	if uploaded {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	}
+	return b.Bucket.Get(ctx, name)
+}
+
+func (b *DryRunBucket) Attributes(ctx context.Context, name string) (objstore.ObjectAttributes, error) {
+	b.mtx.Lock()
+	content, uploaded := b.uploaded[name]
+	b.mtx.Unlock()
+
+	if uploaded {
+		return objstore.ObjectAttributes{Size: int64(len(content)), LastModified: time.Now()}, nil
+	}
+	return b.Bucket.Attributes(ctx, name)
+}
+
+// NoCompactMarks returns the no-compact marks which would have been uploaded to the bucket.
+func (b *DryRunBucket) NoCompactMarks() ([]metadata.NoCompactMark, error) {
+	return uploadedMarks(b, metadata.NoCompactMarkFilename, func(m metadata.NoCompactMark) ulid.ULID { return m.ID })
+}
+
+// NoDownsampleMarks returns the no-downsample marks which would have been uploaded to the bucket.
+func (b *DryRunBucket) NoDownsampleMarks() ([]metadata.NoDownsampleMark, error) {
+	return uploadedMarks(b, metadata.NoDownsampleMarkFilename, func(m metadata.NoDownsampleMark) ulid.ULID { return m.ID })
+}
+
+// uploadedMarks returns the marks with the given file name which would have been uploaded to the bucket, ordered by block.
+func uploadedMarks[M any](b *DryRunBucket, filename string, id func(M) ulid.ULID) ([]M, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	var marks []M
+	for name, content := range b.uploaded {
+		if path.Base(name) != filename {
+			continue
+		}
+		var m M
+		if err := json.Unmarshal(content, &m); err != nil {
+			return nil, errors.Wrapf(err, "unmarshal %s", name)
+		}
+		marks = append(marks, m)
+	}
+	sort.Slice(marks, func(i, j int) bool {
+		return id(marks[i]).Compare(id(marks[j])) < 0
+	})
+	return marks, nil
+}
@@ -0,0 +1,213 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package compact
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/objstore"
+
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
+)
+
+func TestSimulateCompaction(t *testing.T) {
+	t.Parallel()
+
+	const h = int64(time.Hour / time.Millisecond)
+	sizedMeta := func(id uint64, minTime, maxTime int64, lbls map[string]string, indexSize int64) *metadata.Meta {
+		m := createBlockMeta(id, minTime, maxTime, lbls, 0, []uint64{id})
+		m.Thanos.Files = []metadata.File{
+			{RelPath: block.IndexFilename, SizeBytes: indexSize},
+			{RelPath: "chunks/000001", SizeBytes: 2 * indexSize},
+		}
+		return m
+	}
+	metas := map[ulid.ULID]*metadata.Meta{}
+	for i := range uint64(5) {
+		m := sizedMeta(i+1, int64(i)*2*h, int64(i+1)*2*h, map[string]string{"a": "1"}, 10)
+		metas[m.ULID] = m
+	}
+	old := sizedMeta(10, 0, 48*h, map[string]string{"a": "2"}, 10)
+	metas[old.ULID] = old
+
+	for _, tc := range []struct {
+		name          string
+		maxIndexSize  int64
+		splitShards   uint64
+		retention     map[ResolutionLevel]time.Duration
+		noDownsample  map[ulid.ULID]*metadata.NoDownsampleMark
+		expectedTypes []SimulatedJobType
+		expectedSizes []int64
+		// Number of input blocks and start of the planned compaction.
+		expectedInputs  int
+		expectedMinTime int64
+	}{
+		{
+			name:           "compaction and downsampling",
+			maxIndexSize:   1000,
+			expectedTypes:  []SimulatedJobType{SimulatedCompaction, SimulatedDownsampling},
+			expectedSizes:  []int64{4 * 30, 0},
+			expectedInputs: 4,
+		},
+		{
+			name:           "retention of downsampled blocks",
+			maxIndexSize:   1000,
+			retention:      map[ResolutionLevel]time.Duration{ResolutionLevel5m: time.Hour},
+			expectedTypes:  []SimulatedJobType{SimulatedCompaction, SimulatedDownsampling, SimulatedRetention},
+			expectedSizes:  []int64{4 * 30, 0, 10},
+			expectedInputs: 4,
+		},
+		{
+			name:         "index size limit",
+			maxIndexSize: 40,
+			// The first block is marked, the others fit into the limit.
+			expectedTypes:   []SimulatedJobType{SimulatedNoCompactMark, SimulatedCompaction, SimulatedDownsampling},
+			expectedSizes:   []int64{0, 3 * 30, 0},
+			expectedInputs:  3,
+			expectedMinTime: 2 * h,
+		},
+		{
+			name:           "no-downsample mark",
+			maxIndexSize:   1000,
+			noDownsample:   map[ulid.ULID]*metadata.NoDownsampleMark{old.ULID: {ID: old.ULID}},
+			expectedTypes:  []SimulatedJobType{SimulatedCompaction},
+			expectedSizes:  []int64{4 * 30},
+			expectedInputs: 4,
+		},
+		{
+			name:           "split compaction",
+			maxIndexSize:   40,
+			splitShards:    2,
+			expectedTypes:  []SimulatedJobType{SimulatedCompaction, SimulatedDownsampling},
+			expectedSizes:  []int64{4 * 30, 0},
+			expectedInputs: 4,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bkt := objstore.NewInMemBucket()
+			dryRunBkt := NewDryRunBucket(bkt)
+			counter := promauto.With(nil).NewCounter(prometheus.CounterOpts{})
+
+			grouper := NewDefaultGrouper(nil, dryRunBkt, false, false, nil, counter, counter, counter, metadata.NoneFunc, 1, 1)
+			planner := WithLargeTotalIndexSizeFilter(NewPlanner(log.NewNopLogger(), []int64{2 * h, 8 * h}, &GatherNoCompactionMarkFilter{}), dryRunBkt, tc.maxIndexSize, counter)
+			grouper.SetSplitShards(tc.splitShards)
+			planner.SetSplitShards(tc.splitShards)
+
+			jobs, err := SimulateCompaction(context.Background(), dryRunBkt, grouper, planner, metas, SimulationConfig{RetentionByResolution: tc.retention, NoDownsampleMarks: tc.noDownsample})
+			testutil.Ok(t, err)
+
+			var (
+				types []SimulatedJobType
+				sizes []int64
+			)
+			for _, j := range jobs {
+				types = append(types, j.Type)
+				sizes = append(sizes, j.EstimatedSizeBytes)
+			}
+			testutil.Equals(t, tc.expectedTypes, types)
+			testutil.Equals(t, tc.expectedSizes, sizes)
+
+			for _, j := range jobs {
+				switch j.Type {
+				case SimulatedCompaction:
+					testutil.Equals(t, tc.expectedInputs, len(j.Inputs))
+					testutil.Equals(t, tc.expectedMinTime, j.MinTime)
+					testutil.Equals(t, 8*h, j.MaxTime)
+					testutil.Equals(t, max(1, int(tc.splitShards)), len(j.Outputs))
+				case SimulatedDownsampling:
+					testutil.Equals(t, []ulid.ULID{old.ULID}, j.Inputs)
+					testutil.Equals(t, downsample.ResLevel0, j.Resolution)
+				case SimulatedNoCompactMark:
+					testutil.Equals(t, []ulid.ULID{ulid.MustNew(1, nil)}, j.Inputs)
+				}
+			}
+
+			// Nothing is written to the bucket.
+			testutil.Equals(t, 0, len(bkt.Objects()))
+			// Given metas are not modified.
+			testutil.Equals(t, 6, len(metas))
+		})
+	}
+}
+
+func TestSimulateCompaction_CustomLadder(t *testing.T) {
+	t.Parallel()
+
+	const h = int64(time.Hour / time.Millisecond)
+	ladder := downsample.Ladder{
+		{Resolution: time.Minute.Milliseconds(), MinBlockRange: 2 * h},
+		{Resolution: (15 * time.Minute).Milliseconds(), MinBlockRange: 8 * h},
+	}
+	// Blocks downsampled to 1m along the custom ladder, which have to be compacted before reaching the next level.
+	// The last one is not compacted yet, like in TestSimulateCompaction.
+	metas := map[ulid.ULID]*metadata.Meta{}
+	for i := range uint64(5) {
+		m := createBlockMeta(i+1, int64(i)*2*h, int64(i+1)*2*h, map[string]string{"a": "1"}, time.Minute.Milliseconds(), []uint64{i + 1})
+		m.Thanos.Downsample.Levels = ladder
+		m.Thanos.Files = []metadata.File{{RelPath: block.IndexFilename, SizeBytes: 10}}
+		metas[m.ULID] = m
+	}
+
+	dryRunBkt := NewDryRunBucket(objstore.NewInMemBucket())
+	counter := promauto.With(nil).NewCounter(prometheus.CounterOpts{})
+	grouper := NewDefaultGrouper(nil, dryRunBkt, false, false, nil, counter, counter, counter, metadata.NoneFunc, 1, 1)
+	planner := NewPlanner(log.NewNopLogger(), []int64{2 * h, 8 * h}, &GatherNoCompactionMarkFilter{})
+
+	jobs, err := SimulateCompaction(context.Background(), dryRunBkt, grouper, planner, metas, SimulationConfig{})
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(jobs))
+	testutil.Equals(t, SimulatedCompaction, jobs[0].Type)
+
+	// The compacted block keeps the ladder and is downsampled further along it.
+	testutil.Equals(t, SimulatedDownsampling, jobs[1].Type)
+	testutil.Equals(t, jobs[0].Outputs, jobs[1].Inputs)
+	testutil.Equals(t, "to resolution 15m0s", jobs[1].Details)
+}
+
+func TestDryRunBucket(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	testutil.Ok(t, bkt.Upload(ctx, "existing", strings.NewReader("test")))
+
+	dryRunBkt := NewDryRunBucket(bkt)
+	id := ulid.MustNew(1, nil)
+	testutil.Ok(t, block.MarkForNoCompact(ctx, log.NewNopLogger(), dryRunBkt, id, metadata.IndexSizeExceedingNoCompactReason, "test", promauto.With(nil).NewCounter(prometheus.CounterOpts{})))
+	testutil.Ok(t, dryRunBkt.Delete(ctx, "existing"))
+
+	ok, err := dryRunBkt.Exists(ctx, "existing")
+	testutil.Ok(t, err)
+	testutil.Assert(t, !ok, "expected deleted object to not exist")
+	ok, err = dryRunBkt.Exists(ctx, id.String()+"/"+metadata.NoCompactMarkFilename)
+	testutil.Ok(t, err)
+	testutil.Assert(t, ok, "expected uploaded mark to exist")
+
+	marks, err := dryRunBkt.NoCompactMarks()
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(marks))
+	testutil.Equals(t, id, marks[0].ID)
+	testutil.Equals(t, metadata.NoCompactReason(metadata.IndexSizeExceedingNoCompactReason), marks[0].Reason)
+
+	testutil.Ok(t, block.MarkForNoDownsample(ctx, log.NewNopLogger(), dryRunBkt, id, metadata.ManualNoDownsampleReason, "test", promauto.With(nil).NewCounter(prometheus.CounterOpts{})))
+	noDownsampleMarks, err := dryRunBkt.NoDownsampleMarks()
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(noDownsampleMarks))
+	testutil.Equals(t, id, noDownsampleMarks[0].ID)
+
+	// The underlying bucket is untouched.
+	testutil.Equals(t, 1, len(bkt.Objects()))
+	_, ok = bkt.Objects()["existing"]
+	testutil.Assert(t, ok, "expected object to be kept")
+}
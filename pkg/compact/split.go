@@ -0,0 +1,224 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package compact
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/index"
+
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+)
+
+// splitCompact compacts the given blocks into one block per shard, each holding only the series
+// of its shard. Shards without any series do not produce a block.
+func splitCompact(comp Compactor, dest string, dirs []string, populator tsdb.BlockPopulator, shards uint64) ([]ulid.ULID, map[ulid.ULID]*metadata.ThanosShard, error) {
+	if populator == nil {
+		populator = tsdb.DefaultBlockPopulator{}
+	}
+	var (
+		ids      []ulid.ULID
+		idShards = make(map[ulid.ULID]*metadata.ThanosShard, shards)
+		symbols  = newShardSymbols(shards)
+	)
+	for i := range shards {
+		shard := &metadata.ThanosShard{Index: i, Count: shards}
+		res, err := comp.CompactWithBlockPopulator(dest, dirs, nil, &shardBlockPopulator{BlockPopulator: populator, shard: *shard, symbols: symbols})
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "compact shard %s", shard)
+		}
+		for _, id := range res {
+			ids = append(ids, id)
+			idShards[id] = shard
+		}
+	}
+	return ids, idShards, nil
+}
+
+// shardBlockPopulator is a tsdb.BlockPopulator which only populates the block with series of the given shard.
+type shardBlockPopulator struct {
+	tsdb.BlockPopulator
+
+	shard   metadata.ThanosShard
+	symbols *shardSymbols
+}
+
+func (p *shardBlockPopulator) PopulateBlock(ctx context.Context, metrics *tsdb.CompactorMetrics, logger *slog.Logger, chunkPool chunkenc.Pool, mergeFunc storage.VerticalChunkSeriesMergeFunc, blocks []tsdb.BlockReader, meta *tsdb.BlockMeta, indexw tsdb.IndexWriter, chunkw tsdb.ChunkWriter, postingsFunc tsdb.IndexReaderPostingsFunc) error {
+	shardBlocks := make([]tsdb.BlockReader, 0, len(blocks))
+	for _, b := range blocks {
+		shardBlocks = append(shardBlocks, &shardBlockReader{BlockReader: b, shard: p.shard, symbols: p.symbols})
+	}
+	return p.BlockPopulator.PopulateBlock(ctx, metrics, logger, chunkPool, mergeFunc, shardBlocks, meta, indexw, chunkw, func(ctx context.Context, r tsdb.IndexReader) index.Postings {
+		return &shardPostings{Postings: postingsFunc(ctx, r), r: r, shard: p.shard}
+	})
+}
+
+// shardBlockReader is a tsdb.BlockReader whose index only exposes the symbols used by series of the given shard,
+// so that blocks of a shard do not carry the symbols of all other shards.
+type shardBlockReader struct {
+	tsdb.BlockReader
+
+	shard   metadata.ThanosShard
+	symbols *shardSymbols
+}
+
+func (b *shardBlockReader) Index() (tsdb.IndexReader, error) {
+	r, err := b.BlockReader.Index()
+	if err != nil {
+		return nil, err
+	}
+	return &shardIndexReader{IndexReader: r, block: b.Meta().ULID, shard: b.shard, symbols: b.symbols}, nil
+}
+
+type shardIndexReader struct {
+	tsdb.IndexReader
+
+	block   ulid.ULID
+	shard   metadata.ThanosShard
+	symbols *shardSymbols
+}
+
+func (r *shardIndexReader) Symbols() index.StringIter {
+	symbols, err := r.symbols.get(r.block, r.IndexReader)
+	if err != nil {
+		return errStringIter{err: err}
+	}
+	return index.NewStringListIter(symbols[r.shard.Index])
+}
+
+// shardSymbols holds the symbols used by series of each shard per block. They are computed in a single
+// pass over the series of a block when the first shard is compacted, and reused by all other shards.
+type shardSymbols struct {
+	shards uint64
+
+	mtx    sync.Mutex
+	blocks map[ulid.ULID]*blockShardSymbols
+}
+
+type blockShardSymbols struct {
+	once    sync.Once
+	symbols [][]string
+	err     error
+}
+
+func newShardSymbols(shards uint64) *shardSymbols {
+	return &shardSymbols{shards: shards, blocks: map[ulid.ULID]*blockShardSymbols{}}
+}
+
+// get returns the sorted symbols of each shard of the given block, reading them from r if needed.
+func (s *shardSymbols) get(block ulid.ULID, r tsdb.IndexReader) ([][]string, error) {
+	s.mtx.Lock()
+	b, ok := s.blocks[block]
+	if !ok {
+		b = &blockShardSymbols{}
+		s.blocks[block] = b
+	}
+	s.mtx.Unlock()
+
+	b.once.Do(func() {
+		b.symbols, b.err = s.read(r)
+	})
+	return b.symbols, b.err
+}
+
+func (s *shardSymbols) read(r tsdb.IndexReader) ([][]string, error) {
+	k, v := index.AllPostingsKey()
+	p, err := r.Postings(context.Background(), k, v)
+	if err != nil {
+		return nil, errors.Wrap(err, "get all postings")
+	}
+
+	var (
+		builder labels.ScratchBuilder
+		sets    = make([]map[string]struct{}, s.shards)
+	)
+	for i := range sets {
+		sets[i] = map[string]struct{}{}
+	}
+	for p.Next() {
+		if err := r.Series(p.At(), &builder, nil); err != nil {
+			return nil, errors.Wrapf(err, "get series %d", p.At())
+		}
+		lset := builder.Labels()
+		set := sets[labels.StableHash(lset)%s.shards]
+		lset.Range(func(l labels.Label) {
+			set[l.Name] = struct{}{}
+			set[l.Value] = struct{}{}
+		})
+	}
+	if err := p.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate all postings")
+	}
+
+	symbols := make([][]string, s.shards)
+	for i, set := range sets {
+		symbols[i] = make([]string, 0, len(set))
+		for sym := range set {
+			symbols[i] = append(symbols[i], sym)
+		}
+		slices.Sort(symbols[i])
+	}
+	return symbols, nil
+}
+
+type errStringIter struct {
+	err error
+}
+
+func (errStringIter) Next() bool   { return false }
+func (errStringIter) At() string   { return "" }
+func (s errStringIter) Err() error { return s.err }
+
+// shardPostings filters postings down to the series of the given shard.
+type shardPostings struct {
+	index.Postings
+
+	r       tsdb.IndexReader
+	shard   metadata.ThanosShard
+	builder labels.ScratchBuilder
+	err     error
+}
+
+func (p *shardPostings) Next() bool {
+	for p.err == nil && p.Postings.Next() {
+		if p.contains(p.Postings.At()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *shardPostings) Seek(v storage.SeriesRef) bool {
+	if p.err != nil || !p.Postings.Seek(v) {
+		return false
+	}
+	if p.contains(p.Postings.At()) {
+		return true
+	}
+	return p.Next()
+}
+
+func (p *shardPostings) contains(ref storage.SeriesRef) bool {
+	if err := p.r.Series(ref, &p.builder, nil); err != nil {
+		p.err = errors.Wrapf(err, "get series %d", ref)
+		return false
+	}
+	return p.shard.Contains(p.builder.Labels())
+}
+
+func (p *shardPostings) Err() error {
+	if p.err != nil {
+		return p.err
+	}
+	return p.Postings.Err()
+}
@@ -0,0 +1,217 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package compact
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/index"
+	"github.com/thanos-io/objstore"
+
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/testutil/e2eutil"
+)
+
+func TestSplitCompact(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	var series []labels.Labels
+	for i := range 50 {
+		series = append(series, labels.FromStrings("__name__", "metric", "instance", fmt.Sprintf("instance-%d", i)))
+	}
+	var dirs []string
+	for _, mint := range []int64{0, 1000} {
+		id, err := e2eutil.CreateBlock(ctx, dir, series, 10, mint, mint+1000, labels.FromStrings("ext", "1"), 0, metadata.NoneFunc, nil)
+		testutil.Ok(t, err)
+		dirs = append(dirs, filepath.Join(dir, id.String()))
+	}
+
+	comp, err := tsdb.NewLeveledCompactor(ctx, nil, nil, []int64{1000, 2000}, nil, nil)
+	testutil.Ok(t, err)
+
+	const shards = 3
+	ids, idShards, err := splitCompact(comp, dir, dirs, nil, shards)
+	testutil.Ok(t, err)
+	testutil.Equals(t, shards, len(ids))
+
+	seen := map[string]struct{}{}
+	for i, id := range ids {
+		shard := idShards[id]
+		testutil.Equals(t, metadata.ThanosShard{Index: uint64(i), Count: shards}, *shard)
+
+		r, err := index.NewFileReader(filepath.Join(dir, id.String(), block.IndexFilename), index.DecodePostingsRaw)
+		testutil.Ok(t, err)
+
+		k, v := index.AllPostingsKey()
+		p, err := r.Postings(ctx, k, v)
+		testutil.Ok(t, err)
+
+		var (
+			builder labels.ScratchBuilder
+			values  = map[string]struct{}{}
+		)
+		for p.Next() {
+			testutil.Ok(t, r.Series(p.At(), &builder, nil))
+			lset := builder.Labels()
+			testutil.Assert(t, shard.Contains(lset), "series %s does not belong to shard %s", lset, shard)
+			seen[lset.String()] = struct{}{}
+			values[lset.Get("instance")] = struct{}{}
+		}
+		testutil.Ok(t, p.Err())
+
+		// Only symbols of series in the shard are kept.
+		symbols := r.Symbols()
+		var n int
+		for symbols.Next() {
+			n++
+		}
+		testutil.Ok(t, symbols.Err())
+		testutil.Equals(t, len(values)+3, n)
+		testutil.Ok(t, r.Close())
+	}
+	testutil.Equals(t, len(series), len(seen))
+}
+
+func TestDefaultGrouper_SplitShards(t *testing.T) {
+	t.Parallel()
+
+	grouper := NewDefaultGrouper(nil, objstore.NewInMemBucket(), false, false, nil, nil, nil, nil, metadata.NoneFunc, 1, 1)
+	grouper.SetSplitShards(2)
+
+	blocks := map[ulid.ULID]*metadata.Meta{}
+	for i, shard := range []*metadata.ThanosShard{nil, {Index: 0, Count: 2}, {Index: 1, Count: 2}} {
+		m := &metadata.Meta{
+			BlockMeta: tsdb.BlockMeta{ULID: ulid.MustNew(uint64(i), nil)},
+			Thanos:    metadata.Thanos{Labels: map[string]string{"ext": "1"}, Shard: shard},
+		}
+		blocks[m.ULID] = m
+	}
+
+	groups, err := grouper.Groups(blocks)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 3, len(groups))
+	for _, g := range groups {
+		testutil.Equals(t, 1, len(g.IDs()))
+		m := blocks[g.IDs()[0]]
+		testutil.Equals(t, m.Thanos.Shard, g.Shard())
+		if m.Thanos.Shard == nil {
+			testutil.Equals(t, uint64(2), g.splitShards)
+		} else {
+			testutil.Equals(t, uint64(0), g.splitShards)
+		}
+	}
+}
+
+func TestShardPostings(t *testing.T) {
+	t.Parallel()
+
+	var (
+		series []labels.Labels
+		refs   []storage.SeriesRef
+	)
+	for i := range 20 {
+		series = append(series, labels.FromStrings("a", fmt.Sprintf("%d", i)))
+		refs = append(refs, storage.SeriesRef(i))
+	}
+	r := &seriesIndexReader{series: series}
+
+	for i := range uint64(2) {
+		shard := metadata.ThanosShard{Index: i, Count: 2}
+		var expected []storage.SeriesRef
+		for _, ref := range refs {
+			if shard.Contains(series[ref]) {
+				expected = append(expected, ref)
+			}
+		}
+		p := &shardPostings{Postings: index.NewListPostings(refs), r: r, shard: shard}
+		got, err := index.ExpandPostings(p)
+		testutil.Ok(t, err)
+		testutil.Equals(t, expected, got)
+
+		// Seeking to a series of another shard moves on to the next one of this shard.
+		for _, ref := range refs {
+			if shard.Contains(series[ref]) || ref > expected[len(expected)-1] {
+				continue
+			}
+			p = &shardPostings{Postings: index.NewListPostings(refs), r: r, shard: shard}
+			testutil.Assert(t, p.Seek(ref), "expected seek to %d to succeed", ref)
+			testutil.Assert(t, p.At() > ref && shard.Contains(series[p.At()]), "unexpected series %d after seek to %d", p.At(), ref)
+		}
+	}
+}
+
+func TestShardSymbols(t *testing.T) {
+	t.Parallel()
+
+	var series []labels.Labels
+	for i := range 20 {
+		series = append(series, labels.FromStrings("a", fmt.Sprintf("%d", i)))
+	}
+	r := &seriesIndexReader{series: series}
+
+	const shards = 3
+	var (
+		symbols = newShardSymbols(shards)
+		block   = ulid.MustNew(1, nil)
+	)
+	for i := range uint64(shards) {
+		shard := metadata.ThanosShard{Index: i, Count: shards}
+		expected := []string{"a"}
+		for _, lset := range series {
+			if shard.Contains(lset) {
+				expected = append(expected, lset.Get("a"))
+			}
+		}
+		slices.Sort(expected)
+
+		it := (&shardIndexReader{IndexReader: r, block: block, shard: shard, symbols: symbols}).Symbols()
+		var got []string
+		for it.Next() {
+			got = append(got, it.At())
+		}
+		testutil.Ok(t, it.Err())
+		testutil.Equals(t, expected, got)
+	}
+	// Series are only read once for all shards.
+	testutil.Equals(t, len(series), r.seriesCalls)
+}
+
+// seriesIndexReader is a tsdb.IndexReader serving the labels of series by their position.
+type seriesIndexReader struct {
+	tsdb.IndexReader
+
+	series      []labels.Labels
+	seriesCalls int
+}
+
+func (r *seriesIndexReader) Postings(context.Context, string, ...string) (index.Postings, error) {
+	refs := make([]storage.SeriesRef, 0, len(r.series))
+	for i := range r.series {
+		refs = append(refs, storage.SeriesRef(i))
+	}
+	return index.NewListPostings(refs), nil
+}
+
+func (r *seriesIndexReader) Series(ref storage.SeriesRef, builder *labels.ScratchBuilder, _ *[]chunks.Meta) error {
+	r.seriesCalls++
+	builder.Reset()
+	r.series[ref].Range(func(l labels.Label) {
+		builder.Add(l.Name, l.Value)
+	})
+	return nil
+}
@@ -77,6 +77,7 @@ var (
 	Mark            = source{component: component{name: "mark"}}
 	Upload          = source{component: component{name: "upload"}}
 	Rewrite         = source{component: component{name: "rewrite"}}
+	Repair          = source{component: component{name: "repair"}}
 	Retention       = source{component: component{name: "retention"}}
 	Compact         = source{component: component{name: "compact"}}
 	Downsample      = source{component: component{name: "downsample"}}
@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"container/heap"
 
+	"github.com/pkg/errors"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
 	"github.com/prometheus/prometheus/tsdb/chunks"
@@ -154,7 +155,7 @@ type overlappingMerger struct {
 	xorIterators       []chunkenc.Iterator
 	histIterators      []chunkenc.Iterator
 	floatHistIterators []chunkenc.Iterator
-	aggrIterators      [5][]chunkenc.Iterator
+	aggrIterators      [6][]chunkenc.Iterator
 
 	samplesMergeFunc func(a, b chunkenc.Iterator) chunkenc.Iterator
 }
@@ -180,7 +181,7 @@ func (o *overlappingMerger) addChunk(chk chunks.Meta) {
 		o.histIterators = append(o.histIterators, chk.Chunk.Iterator(nil))
 	case downsample.ChunkEncAggr:
 		aggrChk := chk.Chunk.(*downsample.AggrChunk)
-		for i := downsample.AggrCount; i <= downsample.AggrCounter; i++ {
+		for i := downsample.AggrCount; i <= downsample.AggrLast; i++ {
 			if c, err := aggrChk.Get(i); err == nil {
 				o.aggrIterators[i] = append(o.aggrIterators[i], c.Iterator(nil))
 			}
@@ -240,8 +241,8 @@ func (o *overlappingMerger) iterator(baseChk chunks.Meta) chunks.Iterator {
 		// If Aggr encoding, each aggregated chunks need to be expanded and deduplicated,
 		// then re-encoded into Aggr chunks.
 		aggrChk := baseChk.Chunk.(*downsample.AggrChunk)
-		samplesIter := [5]chunkenc.Iterator{}
-		for i := downsample.AggrCount; i <= downsample.AggrCounter; i++ {
+		samplesIter := [6]chunkenc.Iterator{}
+		for i := downsample.AggrCount; i <= downsample.AggrLast; i++ {
 			if c, err := aggrChk.Get(i); err == nil {
 				o.aggrIterators[i] = append(o.aggrIterators[i], c.Iterator(nil))
 			}
@@ -263,14 +264,14 @@ func (o *overlappingMerger) iterator(baseChk chunks.Meta) chunks.Iterator {
 }
 
 type aggrChunkIterator struct {
-	iters        [5]chunkenc.Iterator
+	iters        [6]chunkenc.Iterator
 	curr         chunks.Meta
 	countChkIter chunks.Iterator
 
 	err error
 }
 
-func newAggrChunkIterator(iters [5]chunkenc.Iterator) chunks.Iterator {
+func newAggrChunkIterator(iters [6]chunkenc.Iterator) chunks.Iterator {
 	return &aggrChunkIterator{
 		iters: iters,
 		countChkIter: storage.NewSeriesToChunkEncoder(&storage.SeriesEntry{
@@ -294,13 +295,13 @@ func (a *aggrChunkIterator) Next() bool {
 	maxt := countChk.MaxTime
 
 	var (
-		chks [5]chunkenc.Chunk
+		chks [6]chunkenc.Chunk
 		chk  *chunks.Meta
 		err  error
 	)
 
 	chks[downsample.AggrCount] = countChk.Chunk
-	for i := downsample.AggrSum; i <= downsample.AggrCounter; i++ {
+	for i := downsample.AggrSum; i <= downsample.AggrLast; i++ {
 		chk, err = a.toChunk(i, mint, maxt)
 		if err != nil {
 			a.err = err
@@ -331,33 +332,67 @@ func (a *aggrChunkIterator) toChunk(at downsample.AggrType, minTime, maxTime int
 	if a.iters[at] == nil {
 		return nil, nil
 	}
-	c := chunkenc.NewXORChunk()
-	appender, err := c.Appender()
-	if err != nil {
-		return nil, err
-	}
-
-	it := NewBoundedSeriesIterator(a.iters[at], minTime, maxTime)
 
 	var (
+		c        chunkenc.Chunk
+		appender chunkenc.Appender
+		err      error
+
 		lastT int64
 		lastV float64
 	)
-	for it.Next() != chunkenc.ValNone {
-		lastT, lastV = it.At()
-		appender.Append(lastT, lastV)
+	it := NewBoundedSeriesIterator(a.iters[at], minTime, maxTime)
+	for vt := it.Next(); vt != chunkenc.ValNone; vt = it.Next() {
+		// Float aggregates are encoded as XOR chunks, native histogram aggregates as float histogram chunks.
+		if c == nil {
+			if vt == chunkenc.ValFloat {
+				c = chunkenc.NewXORChunk()
+			} else {
+				c = chunkenc.NewFloatHistogramChunk()
+			}
+			if appender, err = c.Appender(); err != nil {
+				return nil, err
+			}
+		}
+
+		if c.Encoding() == chunkenc.EncXOR {
+			if vt != chunkenc.ValFloat {
+				return nil, errors.Errorf("unexpected value type %v in float aggregate %v", vt, at)
+			}
+			lastT, lastV = it.At()
+			appender.Append(lastT, lastV)
+			continue
+		}
+
+		if vt == chunkenc.ValFloat {
+			return nil, errors.Errorf("unexpected float value in histogram aggregate %v", at)
+		}
+		t, fh := it.AtFloatHistogram(nil)
+		newChk, recoded, newAppender, err := appender.AppendFloatHistogram(nil, t, fh, false)
+		if err != nil {
+			return nil, err
+		}
+		if newChk != nil {
+			// Aggregates hold a single chunk each. Replicas can have different counter states, samples
+			// that would cut a new chunk are dropped, like samples of a series going back in time.
+			if !recoded {
+				continue
+			}
+			c = newChk
+		}
+		appender = newAppender
 	}
 	if err := it.Err(); err != nil {
 		return nil, err
 	}
 
 	// No sample in the required time range.
-	if lastT == 0 && lastV == 0 {
+	if c == nil {
 		return nil, nil
 	}
 
 	// Encode last sample for AggrCounter.
-	if at == downsample.AggrCounter {
+	if at == downsample.AggrCounter && c.Encoding() == chunkenc.EncXOR {
 		appender.Append(lastT, lastV)
 	}
 
@@ -12,6 +12,7 @@ import (
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
 	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/tsdbutil"
 
 	"github.com/thanos-io/thanos/pkg/compact/downsample"
 )
@@ -295,7 +296,7 @@ func TestDedupChunkSeriesMergerDownsampledChunks(t *testing.T) {
 						{sample{299999, 240000}, sample{540000, 540000}},
 						{sample{299999, 240000}, sample{299999, 240000}},
 					}
-					var chks [5]chunkenc.Chunk
+					var chks [6]chunkenc.Chunk
 					for i, s := range samples {
 						chk, err := chunks.ChunkFromSamples(s)
 						testutil.Ok(t, err)
@@ -322,6 +323,66 @@ func TestDedupChunkSeriesMergerDownsampledChunks(t *testing.T) {
 	}
 }
 
+func TestDedupChunkSeriesMergerDownsampledHistogramChunks(t *testing.T) {
+	m := NewChunkSeriesMerger()
+
+	defaultLabels := labels.FromStrings("bar", "baz")
+	histograms := tsdbutil.GenerateTestFloatHistograms(12)
+	downsampled := func(start int) []chunks.Meta {
+		// Samples are created with step 1m.
+		var samples []chunks.Sample
+		for i := start; i < start+10; i++ {
+			samples = append(samples, histoSample{t: int64(i) * 60 * 1000, fh: histograms[i]})
+		}
+		raw, err := chunks.ChunkFromSamples(samples)
+		testutil.Ok(t, err)
+		chks, err := downsample.DownsampleRawChunks([]chunks.Meta{raw}, downsample.ResLevel1)
+		testutil.Ok(t, err)
+		return chks
+	}
+
+	merged := m(
+		&storage.ChunkSeriesEntry{
+			Lset: defaultLabels,
+			ChunkIteratorFn: func(chunks.Iterator) chunks.Iterator {
+				return storage.NewListChunkSeriesIterator(downsampled(0)...)
+			},
+		},
+		// Overlapped with the first series.
+		&storage.ChunkSeriesEntry{
+			Lset: defaultLabels,
+			ChunkIteratorFn: func(chunks.Iterator) chunks.Iterator {
+				return storage.NewListChunkSeriesIterator(downsampled(2)...)
+			},
+		},
+	)
+	chks, err := storage.ExpandChunks(merged.Iterator(nil))
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(chks))
+
+	ac, ok := chks[0].Chunk.(*downsample.AggrChunk)
+	testutil.Assert(t, ok)
+	for _, at := range []downsample.AggrType{downsample.AggrSum, downsample.AggrCounter, downsample.AggrLast} {
+		c, err := ac.Get(at)
+		testutil.Ok(t, err)
+		testutil.Equals(t, chunkenc.EncFloatHistogram, c.Encoding())
+	}
+
+	// The last aggregate holds the last raw histogram of each window.
+	c, err := ac.Get(downsample.AggrLast)
+	testutil.Ok(t, err)
+	var lasts []*histogram.FloatHistogram
+	it := c.Iterator(nil)
+	for it.Next() != chunkenc.ValNone {
+		_, fh := it.AtFloatHistogram(nil)
+		lasts = append(lasts, fh)
+	}
+	testutil.Ok(t, it.Err())
+	testutil.Equals(t, 2, len(lasts))
+	testutil.Equals(t, histograms[4].Count, lasts[0].Count)
+	testutil.Equals(t, histograms[9].Count, lasts[1].Count)
+}
+
 type histoSample struct {
 	t  int64
 	f  float64
@@ -0,0 +1,242 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package export writes series from blocks into files for offline analysis.
+package export
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/logutil"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+// Stats holds statistics of an export.
+type Stats struct {
+	Series  int
+	Samples int
+	// SkippedHistogramSamples is the number of native histogram samples, which
+	// are not supported by the export formats.
+	SkippedHistogramSamples int
+}
+
+// MatchExternalLabels matches the matchers against the external labels of a block.
+// It returns false if any matcher for an external label name does not match, meaning
+// that no series of the block can match. Otherwise it returns the matchers that
+// have to be checked against series labels.
+func MatchExternalLabels(matchers []*labels.Matcher, extLset labels.Labels) ([]*labels.Matcher, bool) {
+	var res []*labels.Matcher
+	for _, m := range matchers {
+		if !extLset.Has(m.Name) {
+			res = append(res, m)
+			continue
+		}
+		if !m.Matches(extLset.Get(m.Name)) {
+			return nil, false
+		}
+	}
+	return res, true
+}
+
+// Blocks writes float samples with timestamps in [mint, maxt] of all series in the
+// block directories matching the matchers, with the external labels of their block
+// added. All blocks are read at once and series are written grouped by metric name,
+// so that samples of a metric family are never interleaved with other families.
+// Samples of series with the same labels in multiple blocks are merged in time order,
+// so block directories should be given in time order.
+func Blocks(ctx context.Context, logger log.Logger, bdirs []string, mint, maxt int64, matchers []*labels.Matcher, w Writer) (_ Stats, err error) {
+	var (
+		stats  Stats
+		blocks []*exportBlock
+	)
+	defer func() {
+		for _, b := range blocks {
+			runutil.CloseWithErrCapture(&err, b.q, "close querier")
+			runutil.CloseWithErrCapture(&err, b.b, "close block")
+		}
+	}()
+
+	names := map[string]struct{}{}
+	for _, bdir := range bdirs {
+		b, ok, err := openExportBlock(logger, bdir, mint, maxt, matchers)
+		if err != nil {
+			return stats, err
+		}
+		if !ok {
+			continue
+		}
+		blocks = append(blocks, b)
+
+		vals, _, err := b.q.LabelValues(ctx, labels.MetricName, nil, b.matchers...)
+		if err != nil {
+			return stats, errors.Wrapf(err, "get metric names of block %v", b.id)
+		}
+		for _, v := range vals {
+			names[v] = struct{}{}
+		}
+	}
+	sortedNames := make([]string, 0, len(names))
+	for n := range names {
+		sortedNames = append(sortedNames, n)
+	}
+	slices.Sort(sortedNames)
+
+	var it chunkenc.Iterator
+	for _, name := range sortedNames {
+		sets := make([]*exportSeriesSet, 0, len(blocks))
+		for _, b := range blocks {
+			ms := append(slices.Clone(b.matchers), labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, name))
+			set := &exportSeriesSet{block: b, ss: b.q.Select(ctx, true, nil, ms...), builder: labels.NewBuilder(labels.EmptyLabels())}
+			if err := set.next(); err != nil {
+				return stats, err
+			}
+			sets = append(sets, set)
+		}
+
+		for {
+			if err := ctx.Err(); err != nil {
+				return stats, err
+			}
+
+			var (
+				cur    []*exportSeriesSet
+				series []storage.Series
+			)
+			for _, set := range sets {
+				if set.cur == nil {
+					continue
+				}
+				if len(cur) > 0 {
+					c := set.compare(cur[0])
+					if c > 0 {
+						continue
+					}
+					if c < 0 {
+						cur = cur[:0]
+						series = series[:0]
+					}
+				}
+				cur = append(cur, set)
+				series = append(series, set.cur)
+			}
+			if len(cur) == 0 {
+				break
+			}
+
+			lset := cur[0].lset
+			var samples int
+			it = storage.ChainedSeriesMerge(series...).Iterator(it)
+			for vt := it.Next(); vt != chunkenc.ValNone; vt = it.Next() {
+				if vt != chunkenc.ValFloat {
+					stats.SkippedHistogramSamples++
+					continue
+				}
+				t, v := it.At()
+				if err := w.Write(lset, t, v); err != nil {
+					return stats, errors.Wrapf(err, "write sample of series %s", lset)
+				}
+				samples++
+			}
+			if err := it.Err(); err != nil {
+				return stats, errors.Wrapf(err, "iterate series %s", lset)
+			}
+			if samples > 0 {
+				stats.Series++
+				stats.Samples += samples
+			}
+
+			for _, set := range cur {
+				if err := set.next(); err != nil {
+					return stats, err
+				}
+			}
+		}
+	}
+	if stats.SkippedHistogramSamples > 0 {
+		level.Warn(logger).Log("msg", "skipped native histogram samples, which are not supported by export formats", "samples", stats.SkippedHistogramSamples)
+	}
+	return stats, nil
+}
+
+type exportBlock struct {
+	id       ulid.ULID
+	b        *tsdb.Block
+	q        storage.Querier
+	extLset  labels.Labels
+	matchers []*labels.Matcher
+}
+
+// openExportBlock opens the block and a querier of [mint, maxt]. It returns false
+// if no series of the block can match the matchers.
+func openExportBlock(logger log.Logger, bdir string, mint, maxt int64, matchers []*labels.Matcher) (*exportBlock, bool, error) {
+	meta, err := metadata.ReadFromDir(bdir)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "read meta")
+	}
+	if meta.Thanos.Downsample.Resolution != 0 {
+		return nil, false, errors.Errorf("block %v is downsampled, only raw blocks can be exported", meta.ULID)
+	}
+	extLset := labels.FromMap(meta.Thanos.Labels)
+	matchers, ok := MatchExternalLabels(matchers, extLset)
+	if !ok {
+		return nil, false, nil
+	}
+	if len(matchers) == 0 {
+		matchers = []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, ".*")}
+	}
+
+	b, err := tsdb.OpenBlock(logutil.GoKitLogToSlog(logger), bdir, nil, tsdb.DefaultPostingsDecoderFactory)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "open block %s", filepath.Base(bdir))
+	}
+	q, err := tsdb.NewBlockQuerier(b, mint, maxt)
+	if err != nil {
+		runutil.CloseWithLogOnErr(logger, b, "close block")
+		return nil, false, errors.Wrapf(err, "create querier of block %v", meta.ULID)
+	}
+	return &exportBlock{id: meta.ULID, b: b, q: q, extLset: extLset, matchers: matchers}, true, nil
+}
+
+// exportSeriesSet iterates the series of a block in the order of their labels
+// without external labels, which is kept when the same external labels are added.
+type exportSeriesSet struct {
+	block   *exportBlock
+	ss      storage.SeriesSet
+	builder *labels.Builder
+
+	cur  storage.Series
+	lset labels.Labels
+}
+
+func (s *exportSeriesSet) next() error {
+	s.cur = nil
+	if !s.ss.Next() {
+		return errors.Wrapf(s.ss.Err(), "select series of block %v", s.block.id)
+	}
+	s.cur = s.ss.At()
+	s.builder.Reset(s.cur.Labels())
+	s.block.extLset.Range(func(l labels.Label) { s.builder.Set(l.Name, l.Value) })
+	s.lset = s.builder.Labels()
+	return nil
+}
+
+// compare orders series by their labels without external labels first, which is
+// the order of series within a single block, and by external labels next.
+func (s *exportSeriesSet) compare(o *exportSeriesSet) int {
+	if c := labels.Compare(s.cur.Labels(), o.cur.Labels()); c != 0 {
+		return c
+	}
+	return labels.Compare(s.block.extLset, o.block.extLset)
+}
@@ -0,0 +1,202 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package export
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/go-kit/log"
+	"github.com/parquet-go/parquet-go"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+
+	"github.com/thanos-io/thanos/pkg/testutil/e2eutil"
+)
+
+type recordedSample struct {
+	lset string
+	t    int64
+}
+
+type recordingWriter struct {
+	samples []recordedSample
+}
+
+func (w *recordingWriter) Write(lset labels.Labels, t int64, _ float64) error {
+	w.samples = append(w.samples, recordedSample{lset: lset.String(), t: t})
+	return nil
+}
+
+func (w *recordingWriter) Close() error { return nil }
+
+func TestBlocks(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	id, err := e2eutil.CreateBlock(ctx, dir, []labels.Labels{
+		labels.FromStrings("__name__", "a", "job", "x"),
+		labels.FromStrings("__name__", "b", "job", "y"),
+	}, 10, 0, 110, labels.FromStrings("cluster", "eu"), 0, "", []chunkenc.ValueType{chunkenc.ValFloat})
+	testutil.Ok(t, err)
+	bdir := filepath.Join(dir, id.String())
+
+	for _, tcase := range []struct {
+		name       string
+		matchers   []*labels.Matcher
+		mint, maxt int64
+		expSeries  []string
+		expSamples int
+	}{
+		{
+			name:       "all series",
+			matchers:   []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, "__name__", ".+")},
+			mint:       math.MinInt64,
+			maxt:       math.MaxInt64,
+			expSeries:  []string{`{__name__="a", cluster="eu", job="x"}`, `{__name__="b", cluster="eu", job="y"}`},
+			expSamples: 20,
+		},
+		{
+			name:       "only external label matcher",
+			matchers:   []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "cluster", "eu")},
+			mint:       math.MinInt64,
+			maxt:       math.MaxInt64,
+			expSeries:  []string{`{__name__="a", cluster="eu", job="x"}`, `{__name__="b", cluster="eu", job="y"}`},
+			expSamples: 20,
+		},
+		{
+			name: "series and external label matchers with time range",
+			matchers: []*labels.Matcher{
+				labels.MustNewMatcher(labels.MatchEqual, "job", "x"),
+				labels.MustNewMatcher(labels.MatchEqual, "cluster", "eu"),
+			},
+			mint:       20,
+			maxt:       50,
+			expSeries:  []string{`{__name__="a", cluster="eu", job="x"}`},
+			expSamples: 4,
+		},
+		{
+			name:     "external label mismatch",
+			matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "cluster", "us")},
+			mint:     math.MinInt64,
+			maxt:     math.MaxInt64,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			w := &recordingWriter{}
+			stats, err := Blocks(ctx, log.NewNopLogger(), []string{bdir}, tcase.mint, tcase.maxt, tcase.matchers, w)
+			testutil.Ok(t, err)
+			testutil.Equals(t, len(tcase.expSeries), stats.Series)
+			testutil.Equals(t, tcase.expSamples, stats.Samples)
+			testutil.Equals(t, tcase.expSamples, len(w.samples))
+
+			var series []string
+			for _, s := range w.samples {
+				testutil.Assert(t, s.t >= tcase.mint && s.t <= tcase.maxt, "sample at %d outside of the time range", s.t)
+				if len(series) == 0 || series[len(series)-1] != s.lset {
+					series = append(series, s.lset)
+				}
+			}
+			testutil.Equals(t, tcase.expSeries, series)
+		})
+	}
+
+	t.Run("series merged across blocks", func(t *testing.T) {
+		next, err := e2eutil.CreateBlock(ctx, dir, []labels.Labels{
+			labels.FromStrings("__name__", "a", "job", "x"),
+			labels.FromStrings("__name__", "b", "job", "y"),
+		}, 10, 100, 210, labels.FromStrings("cluster", "eu"), 0, "", []chunkenc.ValueType{chunkenc.ValFloat})
+		testutil.Ok(t, err)
+		other, err := e2eutil.CreateBlock(ctx, dir, []labels.Labels{
+			labels.FromStrings("__name__", "a", "job", "x"),
+		}, 10, 0, 110, labels.FromStrings("cluster", "us"), 0, "", []chunkenc.ValueType{chunkenc.ValFloat})
+		testutil.Ok(t, err)
+
+		w := &recordingWriter{}
+		stats, err := Blocks(ctx, log.NewNopLogger(), []string{
+			bdir, filepath.Join(dir, next.String()), filepath.Join(dir, other.String()),
+		}, math.MinInt64, math.MaxInt64, nil, w)
+		testutil.Ok(t, err)
+		testutil.Equals(t, 3, stats.Series)
+
+		var (
+			series []string
+			counts = map[string]int{}
+			last   = map[string]int64{}
+		)
+		for _, s := range w.samples {
+			if len(series) == 0 || series[len(series)-1] != s.lset {
+				series = append(series, s.lset)
+			}
+			if n, ok := last[s.lset]; ok {
+				testutil.Assert(t, s.t > n, "sample at %d of series %s not after %d", s.t, s.lset, n)
+			}
+			last[s.lset] = s.t
+			counts[s.lset]++
+		}
+		// Every series is written once, families are not interleaved.
+		testutil.Equals(t, []string{
+			`{__name__="a", cluster="eu", job="x"}`,
+			`{__name__="a", cluster="us", job="x"}`,
+			`{__name__="b", cluster="eu", job="y"}`,
+		}, series)
+		testutil.Equals(t, stats.Samples, len(w.samples))
+		testutil.Assert(t, counts[`{__name__="a", cluster="eu", job="x"}`] > 10, "samples of both blocks expected")
+	})
+}
+
+func TestWriters(t *testing.T) {
+	samples := []struct {
+		lset labels.Labels
+		t    int64
+		v    float64
+	}{
+		{lset: labels.FromStrings("__name__", "up", "job", "a"), t: 1000, v: 1},
+		{lset: labels.FromStrings("__name__", "up", "job", "a"), t: 2500, v: 0},
+		{lset: labels.FromStrings("__name__", "http_requests_total", "code", "200", "path", `/"q"`), t: 1700000000123, v: math.Inf(1)},
+	}
+	write := func(t *testing.T, format Format) string {
+		var buf bytes.Buffer
+		w, err := NewWriter(format, &buf)
+		testutil.Ok(t, err)
+		for _, s := range samples {
+			testutil.Ok(t, w.Write(s.lset, s.t, s.v))
+		}
+		testutil.Ok(t, w.Close())
+		return buf.String()
+	}
+
+	t.Run("openmetrics", func(t *testing.T) {
+		testutil.Equals(t, `up{job="a"} 1 1
+up{job="a"} 0 2.5
+http_requests_total{code="200",path="/\"q\""} +Inf 1700000000.123
+# EOF
+`, write(t, OpenMetrics))
+	})
+	t.Run("csv", func(t *testing.T) {
+		testutil.Equals(t, `name,labels,timestamp,value
+up,"{""job"":""a""}",1000,1
+up,"{""job"":""a""}",2500,0
+http_requests_total,"{""code"":""200"",""path"":""/\""q\""""}",1700000000123,+Inf
+`, write(t, CSV))
+	})
+	t.Run("parquet", func(t *testing.T) {
+		out := write(t, Parquet)
+		rows, err := parquet.Read[parquetSample](bytes.NewReader([]byte(out)), int64(len(out)))
+		testutil.Ok(t, err)
+		testutil.Equals(t, []parquetSample{
+			{Name: "up", Labels: map[string]string{"job": "a"}, Timestamp: 1000, Value: 1},
+			{Name: "up", Labels: map[string]string{"job": "a"}, Timestamp: 2500, Value: 0},
+			{Name: "http_requests_total", Labels: map[string]string{"code": "200", "path": `/"q"`}, Timestamp: 1700000000123, Value: math.Inf(1)},
+		}, rows)
+	})
+	t.Run("unknown format", func(t *testing.T) {
+		_, err := NewWriter("xml", &bytes.Buffer{})
+		testutil.NotOk(t, err)
+	})
+}
@@ -0,0 +1,204 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// Format is the file format of exported samples.
+type Format string
+
+const (
+	// OpenMetrics writes samples in the OpenMetrics text format, with one line per sample.
+	OpenMetrics Format = "openmetrics"
+	// CSV writes samples as CSV with name, labels, timestamp and value columns.
+	CSV Format = "csv"
+	// Parquet writes samples as Parquet rows with name, labels, timestamp and value columns.
+	Parquet Format = "parquet"
+)
+
+// Formats are all supported export formats.
+var Formats = []Format{OpenMetrics, CSV, Parquet}
+
+// Writer writes exported samples.
+type Writer interface {
+	// Write writes a single float sample of the series.
+	Write(lset labels.Labels, t int64, v float64) error
+	// Close flushes all buffered samples. It does not close the underlying writer.
+	Close() error
+}
+
+// NewWriter returns a writer of the given format writing into w.
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case OpenMetrics:
+		return &openMetricsWriter{w: bufio.NewWriter(w)}, nil
+	case CSV:
+		return newCSVWriter(w)
+	case Parquet:
+		return &parquetWriter{w: parquet.NewGenericWriter[parquetSample](w)}, nil
+	default:
+		return nil, errors.Errorf("unsupported export format %q", format)
+	}
+}
+
+var openMetricsEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+type openMetricsWriter struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+func (w *openMetricsWriter) Write(lset labels.Labels, t int64, v float64) error {
+	name := lset.Get(labels.MetricName)
+	if name == "" {
+		return errors.New("series without metric name can't be written in OpenMetrics format")
+	}
+
+	b := append(w.buf[:0], name...)
+	first := true
+	lset.Range(func(l labels.Label) {
+		if l.Name == labels.MetricName {
+			return
+		}
+		if first {
+			b = append(b, '{')
+			first = false
+		} else {
+			b = append(b, ',')
+		}
+		b = append(b, l.Name...)
+		b = append(b, `="`...)
+		b = append(b, openMetricsEscaper.Replace(l.Value)...)
+		b = append(b, '"')
+	})
+	if !first {
+		b = append(b, '}')
+	}
+	b = append(b, ' ')
+	b = strconv.AppendFloat(b, v, 'g', -1, 64)
+	b = append(b, ' ')
+	b = appendSeconds(b, t)
+	b = append(b, '\n')
+	w.buf = b
+
+	_, err := w.w.Write(b)
+	return err
+}
+
+// appendSeconds appends the millisecond timestamp as seconds with exact decimal fraction.
+func appendSeconds(b []byte, t int64) []byte {
+	if t < 0 {
+		b = append(b, '-')
+		t = -t
+	}
+	b = strconv.AppendInt(b, t/1000, 10)
+	if ms := t % 1000; ms != 0 {
+		frac := strconv.AppendInt(nil, 1000+ms, 10)[1:]
+		b = append(b, '.')
+		b = append(b, strings.TrimRight(string(frac), "0")...)
+	}
+	return b
+}
+
+func (w *openMetricsWriter) Close() error {
+	if _, err := w.w.WriteString("# EOF\n"); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w), record: make([]string, 4)}
+	if err := cw.w.Write([]string{"name", "labels", "timestamp", "value"}); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// Write writes labels other than the metric name as a JSON object.
+func (w *csvWriter) Write(lset labels.Labels, t int64, v float64) error {
+	lbls, err := json.Marshal(labelsWithoutName(lset))
+	if err != nil {
+		return err
+	}
+	w.record[0] = lset.Get(labels.MetricName)
+	w.record[1] = string(lbls)
+	w.record[2] = strconv.FormatInt(t, 10)
+	w.record[3] = strconv.FormatFloat(v, 'g', -1, 64)
+	return w.w.Write(w.record)
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type parquetSample struct {
+	Name      string            `parquet:"name,dict"`
+	Labels    map[string]string `parquet:"labels"`
+	Timestamp int64             `parquet:"timestamp,timestamp(millisecond)"`
+	Value     float64           `parquet:"value"`
+}
+
+// parquetBatchSize is the number of rows buffered before they are passed to the Parquet writer.
+const parquetBatchSize = 1024
+
+type parquetWriter struct {
+	w     *parquet.GenericWriter[parquetSample]
+	batch []parquetSample
+}
+
+func (w *parquetWriter) Write(lset labels.Labels, t int64, v float64) error {
+	w.batch = append(w.batch, parquetSample{
+		Name:      lset.Get(labels.MetricName),
+		Labels:    labelsWithoutName(lset),
+		Timestamp: t,
+		Value:     v,
+	})
+	if len(w.batch) < parquetBatchSize {
+		return nil
+	}
+	return w.flush()
+}
+
+func (w *parquetWriter) flush() error {
+	if _, err := w.w.Write(w.batch); err != nil {
+		return err
+	}
+	w.batch = w.batch[:0]
+	return nil
+}
+
+func (w *parquetWriter) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	return w.w.Close()
+}
+
+func labelsWithoutName(lset labels.Labels) map[string]string {
+	m := make(map[string]string, lset.Len())
+	lset.Range(func(l labels.Label) {
+		if l.Name != labels.MetricName {
+			m[l.Name] = l.Value
+		}
+	})
+	return m
+}
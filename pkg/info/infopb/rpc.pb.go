@@ -391,6 +391,8 @@ type TSDBInfo struct {
 	Labels  labelpb.ZLabelSet `protobuf:"bytes,1,opt,name=labels,proto3" json:"labels"`
 	MinTime int64             `protobuf:"varint,2,opt,name=min_time,json=minTime,proto3" json:"min_time,omitempty"`
 	MaxTime int64             `protobuf:"varint,3,opt,name=max_time,json=maxTime,proto3" json:"max_time,omitempty"`
+	// num_series is an estimate of the number of series in the TSDB, 0 if unknown.
+	NumSeries uint64 `protobuf:"varint,4,opt,name=num_series,json=numSeries,proto3" json:"num_series,omitempty"`
 }
 
 func (m *TSDBInfo) Reset()         { *m = TSDBInfo{} }
@@ -442,47 +444,48 @@ func init() {
 func init() { proto.RegisterFile("info/infopb/rpc.proto", fileDescriptor_a1214ec45d2bf952) }
 
 var fileDescriptor_a1214ec45d2bf952 = []byte{
-	// 633 bytes of a gzipped FileDescriptorProto
+	// 647 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x94, 0x4d, 0x6f, 0xd3, 0x30,
-	0x18, 0xc7, 0x9b, 0xbe, 0x2d, 0x7d, 0xba, 0x8d, 0xcd, 0x6c, 0x90, 0x56, 0x28, 0xab, 0xa2, 0x1d,
-	0x2a, 0x81, 0x1a, 0x51, 0x24, 0x84, 0xe0, 0xc4, 0xc6, 0x24, 0x86, 0x98, 0x34, 0xd2, 0x49, 0x48,
-	0xbb, 0x44, 0x69, 0xe7, 0x75, 0x41, 0x4d, 0xec, 0xd9, 0xae, 0xd8, 0xbe, 0x05, 0x9f, 0x83, 0x4f,
-	0xb2, 0xe3, 0x8e, 0x9c, 0x10, 0x74, 0x5f, 0x04, 0xf9, 0x71, 0xd2, 0x35, 0xb0, 0x5d, 0xb8, 0xb4,
-	0x89, 0xff, 0xbf, 0xc7, 0x79, 0x5e, 0xfe, 0x36, 0x6c, 0xc6, 0xe9, 0x29, 0xf3, 0xf5, 0x0f, 0x1f,
-	0xfa, 0x82, 0x8f, 0x7a, 0x5c, 0x30, 0xc5, 0x48, 0x53, 0x9d, 0x45, 0x29, 0x93, 0x3d, 0x2d, 0xb4,
-	0x5b, 0x52, 0x31, 0x41, 0xfd, 0x49, 0x34, 0xa4, 0x13, 0x3e, 0xf4, 0xd5, 0x25, 0xa7, 0xd2, 0x70,
-	0xed, 0x8d, 0x31, 0x1b, 0x33, 0x7c, 0xf4, 0xf5, 0x93, 0x59, 0xf5, 0x56, 0xa0, 0xb9, 0x9f, 0x9e,
-	0xb2, 0x80, 0x9e, 0x4f, 0xa9, 0x54, 0xde, 0xac, 0x02, 0xcb, 0xe6, 0x5d, 0x72, 0x96, 0x4a, 0x4a,
-	0x5e, 0x02, 0xe0, 0x66, 0xa1, 0xa4, 0x4a, 0x3a, 0x56, 0xa7, 0xd2, 0x6d, 0xf6, 0xd7, 0x7b, 0xd9,
-	0x27, 0x8f, 0x3f, 0x6a, 0x69, 0x40, 0xd5, 0x4e, 0xf5, 0xea, 0xe7, 0x56, 0x29, 0x68, 0x4c, 0xb2,
-	0x77, 0x49, 0xb6, 0x61, 0x65, 0x97, 0x25, 0x9c, 0xa5, 0x34, 0x55, 0x47, 0x97, 0x9c, 0x3a, 0xe5,
-	0x8e, 0xd5, 0x6d, 0x04, 0xc5, 0x45, 0xf2, 0x0c, 0x6a, 0x98, 0xb0, 0x53, 0xe9, 0x58, 0xdd, 0x66,
-	0xff, 0x51, 0x6f, 0xa1, 0x96, 0xde, 0x40, 0x2b, 0x98, 0x8c, 0x81, 0x34, 0x2d, 0xa6, 0x13, 0x2a,
-	0x9d, 0xea, 0x1d, 0x74, 0xa0, 0x15, 0x43, 0x23, 0x44, 0xde, 0xc3, 0x83, 0x84, 0x2a, 0x11, 0x8f,
-	0xc2, 0x84, 0xaa, 0xe8, 0x24, 0x52, 0x91, 0x53, 0xc3, 0xb8, 0xad, 0x42, 0xdc, 0x01, 0x32, 0x07,
-	0x19, 0x82, 0x1b, 0xac, 0x26, 0x85, 0x35, 0xd2, 0x87, 0x25, 0x15, 0x89, 0xb1, 0x6e, 0x40, 0x1d,
-	0x77, 0x70, 0x0a, 0x3b, 0x1c, 0x19, 0x0d, 0x43, 0x73, 0x90, 0xbc, 0x82, 0x06, 0xbd, 0xa0, 0x09,
-	0x9f, 0x44, 0x42, 0x3a, 0x4b, 0x18, 0xd5, 0x2e, 0x44, 0xed, 0xe5, 0x2a, 0xc6, 0xdd, 0xc2, 0xc4,
-	0x87, 0xda, 0xf9, 0x94, 0x8a, 0x4b, 0xc7, 0xc6, 0xa8, 0x56, 0x21, 0xea, 0x93, 0x56, 0xde, 0x1e,
-	0xee, 0x9b, 0x42, 0x91, 0x23, 0x3e, 0xd4, 0xa5, 0x8a, 0xd4, 0x54, 0x3a, 0x0d, 0x8c, 0x78, 0xfc,
-	0x57, 0x17, 0xb5, 0x84, 0x7c, 0x86, 0x79, 0xdf, 0xcb, 0xd0, 0x98, 0x37, 0x97, 0xb4, 0xc0, 0x4e,
-	0xe2, 0x34, 0x54, 0x71, 0x42, 0x1d, 0xab, 0x63, 0x75, 0x2b, 0xc1, 0x52, 0x12, 0xa7, 0x47, 0x71,
-	0x42, 0x51, 0x8a, 0x2e, 0x8c, 0x54, 0xce, 0xa4, 0xe8, 0x02, 0xa5, 0xa7, 0xb0, 0x2e, 0xa7, 0x9c,
-	0x33, 0xa1, 0x64, 0x28, 0xcf, 0x22, 0x71, 0x12, 0xa7, 0x63, 0x9c, 0xa2, 0x1d, 0xac, 0xe5, 0xc2,
-	0x20, 0x5b, 0x27, 0x7b, 0xb0, 0x35, 0x87, 0xbf, 0xc6, 0xea, 0x8c, 0x4d, 0x55, 0x28, 0x28, 0x9f,
-	0xc4, 0xa3, 0x28, 0x44, 0xcb, 0x48, 0x1c, 0x8d, 0x1d, 0x3c, 0xc9, 0xb1, 0xcf, 0x86, 0x0a, 0x0c,
-	0x84, 0x36, 0x93, 0xe4, 0x35, 0x80, 0x92, 0x27, 0xc3, 0x50, 0xd7, 0xa5, 0x47, 0xa1, 0xbd, 0xb8,
-	0x59, 0x1c, 0xc5, 0xe0, 0xdd, 0x8e, 0x2e, 0x2a, 0xf7, 0xa3, 0xc6, 0xf5, 0xbb, 0x24, 0xcf, 0x61,
-	0x63, 0x9e, 0x02, 0x17, 0xec, 0x0b, 0x1d, 0xa9, 0x98, 0xa5, 0x66, 0x34, 0x76, 0xf0, 0x30, 0xd7,
-	0x0e, 0x6f, 0xa5, 0x0f, 0x55, 0xbb, 0xba, 0x56, 0xf3, 0x9a, 0xd0, 0x98, 0x5b, 0xcb, 0xdb, 0x00,
-	0xf2, 0xaf, 0x5f, 0xf4, 0x19, 0x5a, 0xf0, 0x80, 0xb7, 0x07, 0x2b, 0x85, 0xe1, 0xfe, 0x5f, 0x87,
-	0xbd, 0x55, 0x58, 0x5e, 0x9c, 0xb6, 0xb7, 0x0c, 0x70, 0x3b, 0x4b, 0xef, 0x1c, 0xec, 0xbc, 0x58,
-	0x6d, 0x80, 0xac, 0x8b, 0x16, 0x1a, 0xe0, 0xde, 0xf3, 0x99, 0x61, 0x85, 0x84, 0xca, 0xf7, 0x27,
-	0x54, 0x29, 0x24, 0xd4, 0xdf, 0x85, 0x2a, 0x7e, 0xee, 0x4d, 0xf6, 0x5f, 0x3c, 0x05, 0x0b, 0xb7,
-	0x48, 0xbb, 0x75, 0x87, 0x62, 0xee, 0x93, 0x9d, 0xed, 0xab, 0xdf, 0x6e, 0xe9, 0x6a, 0xe6, 0x5a,
-	0xd7, 0x33, 0xd7, 0xfa, 0x35, 0x73, 0xad, 0x6f, 0x37, 0x6e, 0xe9, 0xfa, 0xc6, 0x2d, 0xfd, 0xb8,
-	0x71, 0x4b, 0xc7, 0x75, 0x73, 0xbb, 0x0d, 0xeb, 0x78, 0x39, 0xbd, 0xf8, 0x13, 0x00, 0x00, 0xff,
-	0xff, 0x41, 0x05, 0x7b, 0x5e, 0xf3, 0x04, 0x00, 0x00,
+	0x18, 0xc7, 0x9b, 0xbe, 0x2d, 0x79, 0xba, 0x8d, 0xcd, 0x6c, 0x90, 0x56, 0x90, 0x55, 0xd1, 0x0e,
+	0x95, 0x40, 0x8d, 0x28, 0x12, 0x42, 0x70, 0x62, 0x63, 0x12, 0x43, 0x4c, 0x1a, 0xe9, 0x24, 0xa4,
+	0x5d, 0xa2, 0xb4, 0xf3, 0xba, 0xa0, 0x24, 0xf6, 0x6c, 0x47, 0x6c, 0xdf, 0x62, 0x9f, 0x83, 0x4f,
+	0xb2, 0xe3, 0x8e, 0x9c, 0x10, 0x74, 0x5f, 0x04, 0xd9, 0x4e, 0xda, 0x06, 0x06, 0x07, 0x2e, 0xad,
+	0xfd, 0xfc, 0x7f, 0x8f, 0xf3, 0xbc, 0xd9, 0xb0, 0x19, 0xa5, 0xa7, 0xc4, 0x93, 0x3f, 0x74, 0xe4,
+	0x31, 0x3a, 0xee, 0x53, 0x46, 0x04, 0x41, 0x2d, 0x71, 0x16, 0xa6, 0x84, 0xf7, 0xa5, 0xd0, 0x69,
+	0x73, 0x41, 0x18, 0xf6, 0xe2, 0x70, 0x84, 0x63, 0x3a, 0xf2, 0xc4, 0x25, 0xc5, 0x5c, 0x73, 0x9d,
+	0x8d, 0x09, 0x99, 0x10, 0xb5, 0xf4, 0xe4, 0x4a, 0x5b, 0xdd, 0x15, 0x68, 0xed, 0xa7, 0xa7, 0xc4,
+	0xc7, 0xe7, 0x19, 0xe6, 0xc2, 0x9d, 0xd6, 0x60, 0x59, 0xef, 0x39, 0x25, 0x29, 0xc7, 0xe8, 0x05,
+	0x80, 0x3a, 0x2c, 0xe0, 0x58, 0x70, 0xdb, 0xe8, 0xd6, 0x7a, 0xad, 0xc1, 0x7a, 0x3f, 0xff, 0xe4,
+	0xf1, 0x07, 0x29, 0x0d, 0xb1, 0xd8, 0xa9, 0x5f, 0x7f, 0xdf, 0xaa, 0xf8, 0x56, 0x9c, 0xef, 0x39,
+	0xda, 0x86, 0x95, 0x5d, 0x92, 0x50, 0x92, 0xe2, 0x54, 0x1c, 0x5d, 0x52, 0x6c, 0x57, 0xbb, 0x46,
+	0xcf, 0xf2, 0xcb, 0x46, 0xf4, 0x14, 0x1a, 0x2a, 0x60, 0xbb, 0xd6, 0x35, 0x7a, 0xad, 0xc1, 0x83,
+	0xfe, 0x42, 0x2e, 0xfd, 0xa1, 0x54, 0x54, 0x30, 0x1a, 0x92, 0x34, 0xcb, 0x62, 0xcc, 0xed, 0xfa,
+	0x1d, 0xb4, 0x2f, 0x15, 0x4d, 0x2b, 0x08, 0xbd, 0x83, 0x7b, 0x09, 0x16, 0x2c, 0x1a, 0x07, 0x09,
+	0x16, 0xe1, 0x49, 0x28, 0x42, 0xbb, 0xa1, 0xfc, 0xb6, 0x4a, 0x7e, 0x07, 0x8a, 0x39, 0xc8, 0x11,
+	0x75, 0xc0, 0x6a, 0x52, 0xb2, 0xa1, 0x01, 0x2c, 0x89, 0x90, 0x4d, 0x64, 0x01, 0x9a, 0xea, 0x04,
+	0xbb, 0x74, 0xc2, 0x91, 0xd6, 0x94, 0x6b, 0x01, 0xa2, 0x97, 0x60, 0xe1, 0x0b, 0x9c, 0xd0, 0x38,
+	0x64, 0xdc, 0x5e, 0x52, 0x5e, 0x9d, 0x92, 0xd7, 0x5e, 0xa1, 0x2a, 0xbf, 0x39, 0x8c, 0x3c, 0x68,
+	0x9c, 0x67, 0x98, 0x5d, 0xda, 0xa6, 0xf2, 0x6a, 0x97, 0xbc, 0x3e, 0x4a, 0xe5, 0xcd, 0xe1, 0xbe,
+	0x4e, 0x54, 0x71, 0xc8, 0x83, 0x26, 0x17, 0xa1, 0xc8, 0xb8, 0x6d, 0x29, 0x8f, 0x87, 0xbf, 0x55,
+	0x51, 0x4a, 0x8a, 0xcf, 0x31, 0xf7, 0x6b, 0x15, 0xac, 0x59, 0x71, 0x51, 0x1b, 0xcc, 0x24, 0x4a,
+	0x03, 0x11, 0x25, 0xd8, 0x36, 0xba, 0x46, 0xaf, 0xe6, 0x2f, 0x25, 0x51, 0x7a, 0x14, 0x25, 0x58,
+	0x49, 0xe1, 0x85, 0x96, 0xaa, 0xb9, 0x14, 0x5e, 0x28, 0xe9, 0x09, 0xac, 0xf3, 0x8c, 0x52, 0xc2,
+	0x04, 0x0f, 0xf8, 0x59, 0xc8, 0x4e, 0xa2, 0x74, 0xa2, 0xba, 0x68, 0xfa, 0x6b, 0x85, 0x30, 0xcc,
+	0xed, 0x68, 0x0f, 0xb6, 0x66, 0xf0, 0x97, 0x48, 0x9c, 0x91, 0x4c, 0x04, 0x0c, 0xd3, 0x38, 0x1a,
+	0x87, 0x81, 0x1a, 0x19, 0xae, 0x5a, 0x63, 0xfa, 0x8f, 0x0a, 0xec, 0x93, 0xa6, 0x7c, 0x0d, 0xa9,
+	0x31, 0xe3, 0xe8, 0x15, 0x80, 0xe0, 0x27, 0xa3, 0x40, 0xe6, 0x25, 0x5b, 0x21, 0x67, 0x71, 0xb3,
+	0xdc, 0x8a, 0xe1, 0xdb, 0x1d, 0x99, 0x54, 0x31, 0x8f, 0x12, 0x97, 0x7b, 0x8e, 0x9e, 0xc1, 0xc6,
+	0x2c, 0x04, 0xca, 0xc8, 0x67, 0x3c, 0x16, 0x11, 0x49, 0x75, 0x6b, 0x4c, 0xff, 0x7e, 0xa1, 0x1d,
+	0xce, 0xa5, 0xf7, 0x75, 0xb3, 0xbe, 0xd6, 0x70, 0x5b, 0x60, 0xcd, 0x46, 0xcb, 0xdd, 0x00, 0xf4,
+	0xe7, 0xbc, 0xc8, 0x3b, 0xb4, 0x30, 0x03, 0xee, 0x1e, 0xac, 0x94, 0x9a, 0xfb, 0x7f, 0x15, 0x76,
+	0x57, 0x61, 0x79, 0xb1, 0xdb, 0xee, 0x32, 0xc0, 0xbc, 0x97, 0xee, 0x95, 0x01, 0x66, 0x91, 0xad,
+	0x9c, 0x80, 0xbc, 0x8c, 0x46, 0xd7, 0xf8, 0xd7, 0x05, 0xcd, 0xb1, 0x52, 0x44, 0xd5, 0xbf, 0x47,
+	0x54, 0x2b, 0xf7, 0xfc, 0x31, 0x40, 0x9a, 0x25, 0x01, 0xc7, 0x2c, 0xca, 0x2f, 0x61, 0xdd, 0xb7,
+	0xd2, 0x2c, 0x19, 0x2a, 0xc3, 0x60, 0x17, 0xea, 0x2a, 0x9a, 0xd7, 0xf9, 0x7f, 0xf9, 0x96, 0x2c,
+	0xbc, 0x32, 0x9d, 0xf6, 0x1d, 0x8a, 0x7e, 0x6f, 0x76, 0xb6, 0xaf, 0x7f, 0x3a, 0x95, 0xeb, 0xa9,
+	0x63, 0xdc, 0x4c, 0x1d, 0xe3, 0xc7, 0xd4, 0x31, 0xae, 0x6e, 0x9d, 0xca, 0xcd, 0xad, 0x53, 0xf9,
+	0x76, 0xeb, 0x54, 0x8e, 0x9b, 0xfa, 0xf5, 0x1b, 0x35, 0xd5, 0xe3, 0xf5, 0xfc, 0xd7, 0x00, 0x0c,
+	0xc3, 0xd3, 0xc6, 0x13, 0x05, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -963,6 +966,11 @@ func (m *TSDBInfo) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.NumSeries != 0 {
+		i = encodeVarintRpc(dAtA, i, uint64(m.NumSeries))
+		i--
+		dAtA[i] = 0x20
+	}
 	if m.MaxTime != 0 {
 		i = encodeVarintRpc(dAtA, i, uint64(m.MaxTime))
 		i--
@@ -1157,6 +1165,9 @@ func (m *TSDBInfo) Size() (n int) {
 	if m.MaxTime != 0 {
 		n += 1 + sovRpc(uint64(m.MaxTime))
 	}
+	if m.NumSeries != 0 {
+		n += 1 + sovRpc(uint64(m.NumSeries))
+	}
 	return n
 }
 
@@ -2204,6 +2215,25 @@ func (m *TSDBInfo) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NumSeries", wireType)
+			}
+			m.NumSeries = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.NumSeries |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRpc(dAtA[iNdEx:])
@@ -152,6 +152,21 @@ func (s *chunkSeries) Iterator(_ chunkenc.Iterator) chunkenc.Iterator {
 	}
 
 	switch {
+	case s.aggrs[0] == storepb.Aggr_COUNTER && s.aggrs[1] == storepb.Aggr_LAST,
+		s.aggrs[0] == storepb.Aggr_LAST && s.aggrs[1] == storepb.Aggr_COUNTER:
+
+		// Only histogram aggregate chunks are stitched based on their last samples, raw chunks are kept as they are.
+		lasts := make([]chunkenc.Iterator, 0, len(s.chunks))
+		for _, c := range s.chunks {
+			its = append(its, getFirstIterator(c.Counter, c.Raw))
+			if c.Raw == nil && c.Last != nil {
+				lasts = append(lasts, getFirstIterator(c.Last))
+			} else {
+				lasts = append(lasts, nil)
+			}
+		}
+		// TODO(bwplotka): This breaks resets function. See https://github.com/thanos-io/thanos/issues/3644
+		sit = downsample.NewApplyCounterResetsIteratorWithLast(its, lasts)
 	case s.aggrs[0] == storepb.Aggr_SUM && s.aggrs[1] == storepb.Aggr_COUNT,
 		s.aggrs[0] == storepb.Aggr_COUNT && s.aggrs[1] == storepb.Aggr_SUM:
 
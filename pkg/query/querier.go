@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/gogo/protobuf/types"
 	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 
@@ -25,6 +26,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/gate"
 	"github.com/thanos-io/thanos/pkg/query/fanout"
 	"github.com/thanos-io/thanos/pkg/store"
+	"github.com/thanos-io/thanos/pkg/store/hintspb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
 	"github.com/thanos-io/thanos/pkg/tenancy"
 	"github.com/thanos-io/thanos/pkg/tracing"
@@ -219,6 +221,7 @@ type seriesServer struct {
 	seriesSet      []storepb.Series
 	seriesSetStats storepb.SeriesStatsCounter
 	warnings       annotations.Annotations
+	storeStats     *StoreStats
 }
 
 func (s *seriesServer) Send(r *storepb.SeriesResponse) error {
@@ -243,6 +246,15 @@ func (s *seriesServer) Send(r *storepb.SeriesResponse) error {
 		return nil
 	}
 
+	if r.GetHints() != nil && s.storeStats != nil {
+		hints := &hintspb.SeriesResponseHints{}
+		if err := types.UnmarshalAny(r.GetHints(), hints); err != nil {
+			return errors.Wrap(err, "unmarshal series response hints")
+		}
+		s.storeStats.Merge(hints.QueryStats)
+		return nil
+	}
+
 	// Unsupported field, skip.
 	return nil
 }
@@ -268,7 +280,8 @@ func aggrsFromFunc(f string) []storepb.Aggr {
 		return []storepb.Aggr{storepb.Aggr_SUM}
 	}
 	if f == "increase" || f == "rate" || f == "irate" || f == "resets" || f == "xincrease" || f == "xrate" {
-		return []storepb.Aggr{storepb.Aggr_COUNTER}
+		// Last samples are only stored for native histograms and are used to apply counter resets between chunks.
+		return []storepb.Aggr{storepb.Aggr_COUNTER, storepb.Aggr_LAST}
 	}
 	// In the default case, we retrieve count and sum to compute an average.
 	return []storepb.Aggr{storepb.Aggr_COUNT, storepb.Aggr_SUM}
@@ -296,6 +309,7 @@ func (q *querier) Select(ctx context.Context, _ bool, hints *storage.SelectHints
 	tenant := ctx.Value(tenancy.TenantKey)
 	opID, hasOpID := enginemodel.OperatorIDFromContext(ctx)
 	tracker := fanout.FromContext(ctx)
+	storeStats := StoreStatsFromContext(ctx)
 	// The context gets canceled as soon as query evaluation is completed by the engine.
 	// We want to prevent this from happening for the async store API calls we make while preserving tracing context.
 	// TODO(bwplotka): Does the above still is true? It feels weird to leave unfinished calls behind query API.
@@ -307,6 +321,7 @@ func (q *querier) Select(ctx context.Context, _ bool, hints *storage.SelectHints
 	if tracker != nil {
 		ctx = fanout.NewContext(ctx, tracker)
 	}
+	ctx = NewStoreStatsContext(ctx, storeStats)
 	ctx, cancel := context.WithTimeout(ctx, q.selectTimeout)
 	span, ctx := tracing.StartSpan(ctx, "querier_select", opentracing.Tags{
 		"minTime":  hints.Start,
@@ -371,7 +386,7 @@ func (q *querier) selectFn(ctx context.Context, hints *storage.SelectHints, ms .
 	// TODO(bwplotka): Use inprocess gRPC when we want to stream responses.
 	// Currently streaming won't help due to nature of the both PromQL engine which
 	// pulls all series before computations anyway.
-	resp := &seriesServer{ctx: ctx}
+	resp := &seriesServer{ctx: ctx, storeStats: StoreStatsFromContext(ctx)}
 	req := storepb.SeriesRequest{
 		MinTime:                 hints.Start,
 		MaxTime:                 hints.End,
@@ -395,6 +410,13 @@ func (q *querier) selectFn(ctx context.Context, hints *storage.SelectHints, ms .
 		// Soft ask to sort without replica labels and push them at the end of labelset.
 		req.WithoutReplicaLabels = q.replicaLabels
 	}
+	if resp.storeStats != nil {
+		hints, err := types.MarshalAny(&hintspb.SeriesRequestHints{EnableQueryStats: true})
+		if err != nil {
+			return nil, storepb.SeriesStatsCounter{}, errors.Wrap(err, "marshal series request hints")
+		}
+		req.Hints = hints
+	}
 
 	if err := q.proxy.Series(&req, resp); err != nil {
 		return nil, storepb.SeriesStatsCounter{}, errors.Wrap(err, "proxy Series()")
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"strconv"
@@ -28,11 +29,15 @@ import (
 	"github.com/prometheus/prometheus/model/value"
 	"github.com/prometheus/prometheus/promql"
 	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/tsdbutil"
 	"github.com/prometheus/prometheus/util/annotations"
 	"github.com/prometheus/prometheus/util/gate"
 	"github.com/thanos-io/thanos/pkg/logutil"
 
+	"github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/compact/downsample"
 	"github.com/thanos-io/thanos/pkg/component"
 	"github.com/thanos-io/thanos/pkg/dedup"
@@ -1409,3 +1414,166 @@ func TestMaxResolutionFromSelectHints(t *testing.T) {
 		})
 	}
 }
+
+// TestQuerier_DownsampledPromQLEquivalence checks that rate(), increase() and histogram_quantile() evaluated on a
+// 5m downsampled block give the same results as on the raw block it was created from, within a tolerance.
+func TestQuerier_DownsampledPromQLEquivalence(t *testing.T) {
+	t.Parallel()
+
+	const (
+		scrapeInterval = int64(30 * time.Second / time.Millisecond)
+		numSamples     = 24 * 60 * 2 // One day of samples.
+	)
+
+	opts := tsdb.DefaultHeadOptions()
+	opts.ChunkDirRoot = t.TempDir()
+	head, err := tsdb.NewHead(nil, nil, nil, nil, opts, nil)
+	testutil.Ok(t, err)
+	t.Cleanup(func() { testutil.Ok(t, head.Close()) })
+
+	app := head.Appender(context.Background())
+	var counter float64
+	for i := int64(0); i < numSamples; i++ {
+		ts := i * scrapeInterval
+		// Reset both counters a few times in the middle of the evaluated windows.
+		if i%1111 == 0 {
+			counter = 0
+		}
+		counter += float64(1 + i%7)
+		_, err := app.Append(0, labels.FromStrings("__name__", "c"), ts, counter)
+		testutil.Ok(t, err)
+		_, err = app.AppendHistogram(0, labels.FromStrings("__name__", "h"), ts, nil, tsdbutil.GenerateTestFloatHistogram(i%1111))
+		testutil.Ok(t, err)
+	}
+	testutil.Ok(t, app.Commit())
+
+	mint, maxt := int64(0), numSamples*scrapeInterval
+	raw := tsdb.NewRangeHead(head, mint, maxt-1)
+
+	dir := t.TempDir()
+	id, err := downsample.Downsample(context.Background(), log.NewNopLogger(), &metadata.Meta{
+		BlockMeta: tsdb.BlockMeta{MinTime: mint, MaxTime: maxt},
+	}, raw, dir, downsample.ResLevel1)
+	testutil.Ok(t, err)
+	downsampled, err := tsdb.OpenBlock(nil, filepath.Join(dir, id.String()), downsample.NewPool(), nil)
+	testutil.Ok(t, err)
+	t.Cleanup(func() { testutil.Ok(t, downsampled.Close()) })
+
+	timeout := 10 * time.Second
+	engine := promql.NewEngine(promql.EngineOpts{MaxSamples: math.MaxInt32, Timeout: timeout})
+	eval := func(b tsdb.BlockReader, metric, query string) promql.Matrix {
+		q := NewQueryableCreator(
+			nil,
+			nil,
+			newProxyStore(&testStoreServer{resps: blockSeriesResponses(t, b, metric)}),
+			2,
+			timeout,
+			dedup.AlgorithmPenalty,
+			1,
+		)(false, nil, nil, downsample.ResLevel1, false, false, nil, NoopSeriesStatsReporter)
+
+		qry, err := engine.NewRangeQuery(context.Background(), q, nil, query, timestamp.Time(2*time.Hour.Milliseconds()), timestamp.Time(22*time.Hour.Milliseconds()), 30*time.Minute)
+		testutil.Ok(t, err)
+		// Closing the query returns its points to the pool, so keep them until both results are compared.
+		t.Cleanup(qry.Close)
+		res := qry.Exec(context.Background())
+		testutil.Ok(t, res.Err)
+		m, err := res.Matrix()
+		testutil.Ok(t, err)
+		return m
+	}
+
+	for _, tcase := range []struct {
+		metric    string
+		query     string
+		tolerance float64
+	}{
+		{metric: "c", query: `rate(c[1h])`, tolerance: 0.02},
+		{metric: "c", query: `increase(c[1h])`, tolerance: 0.02},
+		{metric: "h", query: `histogram_count(rate(h[1h]))`, tolerance: 0.02},
+		{metric: "h", query: `histogram_quantile(0.9, rate(h[1h]))`, tolerance: 0.01},
+	} {
+		t.Run(tcase.query, func(t *testing.T) {
+			exp := eval(raw, tcase.metric, tcase.query)
+			got := eval(downsampled, tcase.metric, tcase.query)
+			testutil.Equals(t, 1, len(exp))
+			testutil.Equals(t, 1, len(got))
+			testutil.Equals(t, len(exp[0].Floats), len(got[0].Floats))
+			testutil.Assert(t, len(exp[0].Floats) > 0, "expected float results")
+
+			for i, e := range exp[0].Floats {
+				g := got[0].Floats[i]
+				testutil.Equals(t, e.T, g.T)
+				testutil.Assert(t, math.Abs(g.F-e.F) <= tcase.tolerance*math.Abs(e.F), "at %d: raw %v, downsampled %v", e.T, e.F, g.F)
+			}
+		})
+	}
+}
+
+// blockSeriesResponses returns the series of the given metric in the block as store responses, splitting
+// downsampled chunks into their aggregates the same way the store gateway does.
+func blockSeriesResponses(t testing.TB, b tsdb.BlockReader, metric string) []*storepb.SeriesResponse {
+	ir, err := b.Index()
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, ir.Close()) }()
+	cr, err := b.Chunks()
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, cr.Close()) }()
+
+	storeChunk := func(c chunkenc.Chunk) *storepb.Chunk {
+		enc := storepb.Chunk_XOR
+		switch c.Encoding() {
+		case chunkenc.EncHistogram:
+			enc = storepb.Chunk_HISTOGRAM
+		case chunkenc.EncFloatHistogram:
+			enc = storepb.Chunk_FLOAT_HISTOGRAM
+		}
+		return &storepb.Chunk{Type: enc, Data: append([]byte(nil), c.Bytes()...)}
+	}
+
+	p, err := ir.Postings(context.Background(), labels.MetricName, metric)
+	testutil.Ok(t, err)
+
+	var (
+		resps   []*storepb.SeriesResponse
+		builder labels.ScratchBuilder
+		chks    []chunks.Meta
+	)
+	for p.Next() {
+		testutil.Ok(t, ir.Series(p.At(), &builder, &chks))
+		s := &storepb.Series{Labels: labelpb.ZLabelsFromPromLabels(builder.Labels())}
+		for _, meta := range chks {
+			c, _, err := cr.ChunkOrIterable(meta)
+			testutil.Ok(t, err)
+
+			chk := storepb.AggrChunk{MinTime: meta.MinTime, MaxTime: meta.MaxTime}
+			if c.Encoding() != downsample.ChunkEncAggr {
+				chk.Raw = storeChunk(c)
+				s.Chunks = append(s.Chunks, chk)
+				continue
+			}
+			for _, aggr := range []struct {
+				typ downsample.AggrType
+				out **storepb.Chunk
+			}{
+				{typ: downsample.AggrCount, out: &chk.Count},
+				{typ: downsample.AggrSum, out: &chk.Sum},
+				{typ: downsample.AggrMin, out: &chk.Min},
+				{typ: downsample.AggrMax, out: &chk.Max},
+				{typ: downsample.AggrCounter, out: &chk.Counter},
+				{typ: downsample.AggrLast, out: &chk.Last},
+			} {
+				x, err := c.(*downsample.AggrChunk).Get(aggr.typ)
+				if errors.Is(err, downsample.ErrAggrNotExist) {
+					continue
+				}
+				testutil.Ok(t, err)
+				*aggr.out = storeChunk(x)
+			}
+			s.Chunks = append(s.Chunks, chk)
+		}
+		resps = append(resps, storepb.NewSeriesResponse(s))
+	}
+	testutil.Ok(t, p.Err())
+	return resps
+}
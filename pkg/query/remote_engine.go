@@ -43,6 +43,9 @@ func NewRemoteEndpointsCreator(
 	timeout time.Duration,
 	queryDistributedWithOverlappingInterval bool,
 	autoDownsample bool,
+	costBasedReplicaSelection bool,
+	latencies *EndpointLatencies,
+	resultsCache *ResultsCache,
 ) RemoteEndpointsCreator {
 	return func(
 		replicaLabels []string,
@@ -55,6 +58,9 @@ func NewRemoteEndpointsCreator(
 			PartitionLabels:                         partitionLabels,
 			Timeout:                                 timeout,
 			QueryDistributedWithOverlappingInterval: queryDistributedWithOverlappingInterval,
+			CostBasedReplicaSelection:               costBasedReplicaSelection,
+			Latencies:                               latencies,
+			ResultsCache:                            resultsCache,
 		})
 	}
 }
@@ -67,6 +73,14 @@ type Opts struct {
 	Timeout                                 time.Duration
 	PartialResponse                         bool
 	QueryDistributedWithOverlappingInterval bool
+	// CostBasedReplicaSelection prunes remote engines to the queried time range and pushes
+	// queries down only to the cheapest replica of each partition. The structure of the
+	// distributed plan is not affected.
+	CostBasedReplicaSelection bool
+	// Latencies records the latency of remote queries, used to estimate their cost.
+	Latencies *EndpointLatencies
+	// ResultsCache caches results of queries pushed down to remote engines.
+	ResultsCache *ResultsCache
 }
 
 // Client is a query client that executes PromQL queries.
@@ -95,30 +109,47 @@ type remoteEndpoints struct {
 	logger     log.Logger
 	getClients func() []Client
 	opts       Opts
+
+	mtx   sync.Mutex
+	costs []RemoteEngineCost
 }
 
 func NewRemoteEndpoints(logger log.Logger, getClients func() []Client, opts Opts) api.RemoteEndpoints {
-	return remoteEndpoints{
+	return &remoteEndpoints{
 		logger:     logger,
 		getClients: getClients,
 		opts:       opts,
 	}
 }
 
-func (r remoteEndpoints) Engines(mint, maxt int64) []api.RemoteEngine {
-	// TODO(Aleksandr Krivoshchekov):
-	//     Use mint and maxt to prune TSDBInfos.
-	//     It's safe to ignore these params for now, and return all available
-	//     engines.
-
+func (r *remoteEndpoints) Engines(mint, maxt int64) []api.RemoteEngine {
 	clients := r.getClients()
+	if r.opts.CostBasedReplicaSelection && !unboundedRange(mint, maxt) {
+		var costs []RemoteEngineCost
+		clients, costs = planClients(clients, r.opts, r.opts.Latencies, mint, maxt)
+
+		r.mtx.Lock()
+		r.costs = costs
+		r.mtx.Unlock()
+	}
+
 	engines := make([]api.RemoteEngine, len(clients))
 	for i := range clients {
-		engines[i] = NewRemoteEngine(r.logger, clients[i], r.opts)
+		client := clients[i]
+		client.QueryClient = NewCachingQueryClient(client.QueryClient, r.opts.ResultsCache)
+		engines[i] = NewRemoteEngine(r.logger, client, r.opts)
 	}
 	return engines
 }
 
+// RemoteEngineCosts returns the costs estimated while planning the last query.
+func (r *remoteEndpoints) RemoteEngineCosts() []RemoteEngineCost {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.costs
+}
+
 type remoteEngine struct {
 	opts   Opts
 	logger log.Logger
@@ -311,6 +342,7 @@ func (r *remoteQuery) Exec(ctx context.Context) *promql.Result {
 			keys = append(keys, "remote_total_samples", r.samplesStats.TotalSamples)
 		}
 		level.Debug(r.logger).Log(keys...)
+		r.opts.Latencies.Observe(r.remoteAddr, time.Since(start))
 	}()
 
 	qctx, cancel := context.WithCancel(ctx)
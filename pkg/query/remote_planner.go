@@ -0,0 +1,261 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package query
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/thanos-io/thanos/pkg/info/infopb"
+)
+
+// latencyDecay is the weight given to the most recent observation when updating
+// the moving average of remote engine latencies.
+const latencyDecay = 0.3
+
+// EndpointLatencies keeps an exponentially weighted moving average of the time
+// remote engines took to answer pushed-down queries, keyed by endpoint address.
+// It is safe for concurrent use; a nil *EndpointLatencies records nothing.
+type EndpointLatencies struct {
+	mtx       sync.RWMutex
+	latencies map[string]time.Duration
+}
+
+// NewEndpointLatencies creates an empty latency tracker.
+func NewEndpointLatencies() *EndpointLatencies {
+	return &EndpointLatencies{latencies: make(map[string]time.Duration)}
+}
+
+// Observe records the duration of a remote query executed against addr.
+func (l *EndpointLatencies) Observe(addr string, d time.Duration) {
+	if l == nil {
+		return
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	prev, ok := l.latencies[addr]
+	if !ok {
+		l.latencies[addr] = d
+		return
+	}
+	l.latencies[addr] = time.Duration(latencyDecay*float64(d) + (1-latencyDecay)*float64(prev))
+}
+
+// Latency returns the moving average latency of addr, or zero if it was never observed.
+func (l *EndpointLatencies) Latency(addr string) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+
+	return l.latencies[addr]
+}
+
+// RemoteEngineCost is the estimated cost of pushing a query down to a single remote engine.
+type RemoteEngineCost struct {
+	Address   string   `json:"address"`
+	LabelSets []string `json:"labelSets"`
+	// TSDBs is the number of TSDBs of the engine overlapping the queried time range.
+	TSDBs int `json:"tsdbs"`
+	// Coverage is the queried time range the engine can answer for, summed over its TSDBs.
+	Coverage string `json:"coverage"`
+	// Series is the number of series of the TSDBs, as reported by the engine, 0 if unknown.
+	Series  uint64  `json:"series"`
+	Latency string  `json:"latency"`
+	Cost    float64 `json:"cost"`
+	// Selected is false when the engine was skipped in favour of a cheaper replica.
+	Selected bool `json:"selected"`
+}
+
+// RemoteEngineCostReporter is implemented by remote endpoints which can report the costs
+// estimated while planning the last query.
+type RemoteEngineCostReporter interface {
+	RemoteEngineCosts() []RemoteEngineCost
+}
+
+// pruneTSDBInfos returns the TSDBs which overlap with [mint, maxt].
+func pruneTSDBInfos(infos infopb.TSDBInfos, mint, maxt int64) infopb.TSDBInfos {
+	pruned := make(infopb.TSDBInfos, 0, len(infos))
+	for _, info := range infos {
+		if info.MaxTime < mint || info.MinTime > maxt {
+			continue
+		}
+		pruned = append(pruned, info)
+	}
+	return pruned
+}
+
+// costEstimate is the estimated cost of querying the TSDBs of a remote engine.
+type costEstimate struct {
+	// seriesCost weights the part of the time range every TSDB covers by its number of series.
+	seriesCost float64
+	// rangeCost only accounts the part of the time range the TSDBs cover.
+	rangeCost float64
+	// seriesKnown is true if all TSDBs report their number of series. Sidecars don't, and their
+	// seriesCost can't be compared to the one of engines which do.
+	seriesKnown bool
+
+	coverage time.Duration
+	series   uint64
+}
+
+// cost returns the cost to compare engines by, the series cost if known.
+func (e costEstimate) cost(bySeries bool) float64 {
+	if bySeries && e.seriesKnown {
+		return e.seriesCost
+	}
+	return e.rangeCost
+}
+
+// estimateCost estimates the cost of querying [mint, maxt] from the given TSDBs. Costs are scaled
+// by the observed latency of the endpoint so that slower replicas are more expensive than faster
+// ones holding the same data.
+func estimateCost(infos infopb.TSDBInfos, mint, maxt int64, latency time.Duration) costEstimate {
+	var (
+		seriesCost, rangeCost float64
+		coverage              int64
+		series                uint64
+		seriesKnown           = true
+	)
+	for _, info := range infos {
+		start, end := max(info.MinTime, mint), min(info.MaxTime, maxt)
+		if end <= start {
+			continue
+		}
+		coverage += end - start
+		series += info.NumSeries
+		seriesKnown = seriesKnown && info.NumSeries > 0
+		seriesCost += float64(end-start) / 1000 * float64(max(info.NumSeries, 1))
+		rangeCost += float64(end-start) / 1000
+	}
+	scale := 1 + latency.Seconds()
+	return costEstimate{
+		seriesCost:  seriesCost * scale,
+		rangeCost:   rangeCost * scale,
+		seriesKnown: seriesKnown,
+		coverage:    time.Duration(coverage) * time.Millisecond,
+		series:      series,
+	}
+}
+
+type plannedClient struct {
+	client   Client
+	cost     RemoteEngineCost
+	estimate costEstimate
+
+	groupKey   string
+	mint, maxt int64
+}
+
+// planClients prunes the TSDBInfos of every client to [mint, maxt] and, for groups of clients
+// exposing the same partition label sets, keeps only the cheapest client that covers the time
+// range of all of its replicas. Replicas are compared by the series of their TSDBs only if all
+// of them report it, otherwise by the time range they cover. Skipped clients are returned without TSDBInfos so that the
+// distributed optimizer does not push any part of the query down to them.
+func planClients(clients []Client, opts Opts, latencies *EndpointLatencies, mint, maxt int64) ([]Client, []RemoteEngineCost) {
+	planned := make([]*plannedClient, 0, len(clients))
+	groups := make(map[string][]*plannedClient)
+	for _, c := range clients {
+		pruned := NewClient(c.QueryClient, c.address, pruneTSDBInfos(c.tsdbInfos, mint, maxt))
+		latency := latencies.Latency(c.address)
+		estimate := estimateCost(pruned.tsdbInfos, mint, maxt, latency)
+
+		engine := NewRemoteEngine(log.NewNopLogger(), pruned, opts)
+		partitionLabelSets := make([]string, 0, len(engine.PartitionLabelSets()))
+		for _, lset := range engine.PartitionLabelSets() {
+			partitionLabelSets = append(partitionLabelSets, lset.String())
+		}
+		sort.Strings(partitionLabelSets)
+		labelSets := make([]string, 0, len(engine.LabelSets()))
+		for _, lset := range engine.LabelSets() {
+			labelSets = append(labelSets, lset.String())
+		}
+
+		p := &plannedClient{
+			client:   pruned,
+			estimate: estimate,
+			groupKey: strings.Join(partitionLabelSets, ","),
+			mint:     max(engine.MinT(), mint),
+			maxt:     min(engine.MaxT(), maxt),
+			cost: RemoteEngineCost{
+				Address:   c.address,
+				LabelSets: labelSets,
+				TSDBs:     len(pruned.tsdbInfos),
+				Coverage:  estimate.coverage.String(),
+				Series:    estimate.series,
+				Latency:   latency.String(),
+				Cost:      estimate.cost(true),
+				Selected:  true,
+			},
+		}
+		planned = append(planned, p)
+		if len(pruned.tsdbInfos) > 0 && p.groupKey != "" {
+			groups[p.groupKey] = append(groups[p.groupKey], p)
+		}
+	}
+
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		// Replicas are only compared by their series if all of them report it.
+		bySeries := true
+		for _, p := range group {
+			bySeries = bySeries && p.estimate.seriesKnown
+		}
+		for _, p := range group {
+			p.cost.Cost = p.estimate.cost(bySeries)
+		}
+
+		var best *plannedClient
+		for _, candidate := range group {
+			if !coversGroup(candidate, group) {
+				continue
+			}
+			if best == nil || candidate.cost.Cost < best.cost.Cost {
+				best = candidate
+			}
+		}
+		if best == nil {
+			// No single replica holds everything the others do, query all of them.
+			continue
+		}
+		for _, p := range group {
+			if p == best {
+				continue
+			}
+			p.cost.Selected = false
+			p.client = NewClient(p.client.QueryClient, p.client.address, nil)
+		}
+	}
+
+	result := make([]Client, 0, len(planned))
+	costs := make([]RemoteEngineCost, 0, len(planned))
+	for _, p := range planned {
+		result = append(result, p.client)
+		costs = append(costs, p.cost)
+	}
+	return result, costs
+}
+
+func coversGroup(candidate *plannedClient, group []*plannedClient) bool {
+	for _, p := range group {
+		if candidate.mint > p.mint || candidate.maxt < p.maxt {
+			return false
+		}
+	}
+	return true
+}
+
+// unboundedRange reports whether the time range passed to Engines is unknown.
+func unboundedRange(mint, maxt int64) bool {
+	return mint == math.MinInt64 && maxt == math.MaxInt64
+}
@@ -0,0 +1,162 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package query
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/go-kit/log"
+
+	"github.com/thanos-io/thanos/pkg/info/infopb"
+)
+
+func TestEndpointLatencies(t *testing.T) {
+	t.Parallel()
+
+	l := NewEndpointLatencies()
+	testutil.Equals(t, time.Duration(0), l.Latency("a"))
+
+	l.Observe("a", 10*time.Second)
+	testutil.Equals(t, 10*time.Second, l.Latency("a"))
+
+	l.Observe("a", 0)
+	testutil.Equals(t, 7*time.Second, l.Latency("a"))
+
+	var nilLatencies *EndpointLatencies
+	nilLatencies.Observe("a", time.Second)
+	testutil.Equals(t, time.Duration(0), nilLatencies.Latency("a"))
+}
+
+func TestRemoteEndpoints_CostBasedReplicaSelection(t *testing.T) {
+	t.Parallel()
+
+	var (
+		replicaA = NewClient(nil, "replica-a", []infopb.TSDBInfo{
+			{Labels: zLabelSetFromStrings("zone", "east", "replica", "a"), MinTime: 0, MaxTime: 1000},
+		})
+		replicaB = NewClient(nil, "replica-b", []infopb.TSDBInfo{
+			{Labels: zLabelSetFromStrings("zone", "east", "replica", "b"), MinTime: 0, MaxTime: 1000},
+		})
+		partial = NewClient(nil, "partial", []infopb.TSDBInfo{
+			{Labels: zLabelSetFromStrings("zone", "west", "replica", "a"), MinTime: 500, MaxTime: 1000},
+		})
+		full = NewClient(nil, "full", []infopb.TSDBInfo{
+			{Labels: zLabelSetFromStrings("zone", "west", "replica", "b"), MinTime: 0, MaxTime: 1000},
+		})
+		old = NewClient(nil, "old", []infopb.TSDBInfo{
+			{Labels: zLabelSetFromStrings("zone", "north"), MinTime: 0, MaxTime: 100},
+		})
+	)
+
+	latencies := NewEndpointLatencies()
+	latencies.Observe("replica-a", 5*time.Second)
+	latencies.Observe("replica-b", time.Second)
+	latencies.Observe("partial", 0)
+	latencies.Observe("full", 10*time.Second)
+
+	endpoints := NewRemoteEndpoints(log.NewNopLogger(), func() []Client {
+		return []Client{replicaA, replicaB, partial, full, old}
+	}, Opts{
+		ReplicaLabels:             []string{"replica"},
+		CostBasedReplicaSelection: true,
+		Latencies:                 latencies,
+	})
+
+	engines := endpoints.Engines(200, 1000)
+	testutil.Equals(t, 5, len(engines))
+
+	selected := make(map[string]bool)
+	for _, c := range endpoints.(RemoteEngineCostReporter).RemoteEngineCosts() {
+		selected[c.Address] = c.Selected
+	}
+	testutil.Equals(t, map[string]bool{
+		// The faster replica is queried.
+		"replica-a": false,
+		"replica-b": true,
+		// Only the slower replica covers the whole range.
+		"partial": false,
+		"full":    true,
+		"old":     true,
+	}, selected)
+
+	// Skipped replicas and engines outside the time range are not pushed down to.
+	testutil.Equals(t, int64(math.MaxInt64), engines[0].MinT())
+	testutil.Equals(t, int64(0), engines[1].MinT())
+	testutil.Equals(t, int64(math.MaxInt64), engines[2].MinT())
+	testutil.Equals(t, int64(0), engines[3].MinT())
+	testutil.Equals(t, int64(math.MaxInt64), engines[4].MinT())
+}
+
+func TestRemoteEndpoints_CostBasedReplicaSelectionDisabled(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(nil, "a", []infopb.TSDBInfo{
+		{Labels: zLabelSetFromStrings("zone", "east"), MinTime: 0, MaxTime: 100},
+	})
+	endpoints := NewRemoteEndpoints(log.NewNopLogger(), func() []Client { return []Client{client} }, Opts{})
+
+	engines := endpoints.Engines(200, 1000)
+	testutil.Equals(t, 1, len(engines))
+	testutil.Equals(t, int64(0), engines[0].MinT())
+	testutil.Equals(t, 0, len(endpoints.(RemoteEngineCostReporter).RemoteEngineCosts()))
+}
+
+func TestEstimateCost(t *testing.T) {
+	t.Parallel()
+
+	infos := infopb.TSDBInfos{
+		{MinTime: 0, MaxTime: 10000, NumSeries: 100},
+		{MinTime: 10000, MaxTime: 20000, NumSeries: 50},
+		// TSDBs which don't report series count as a single series.
+		{MinTime: 20000, MaxTime: 30000},
+	}
+	estimate := estimateCost(infos, 5000, 25000, time.Second)
+	testutil.Equals(t, (5*100+10*50+5*1)*2.0, estimate.seriesCost)
+	testutil.Equals(t, 20*2.0, estimate.rangeCost)
+	testutil.Assert(t, !estimate.seriesKnown)
+	testutil.Equals(t, estimate.rangeCost, estimate.cost(true))
+	testutil.Equals(t, 20*time.Second, estimate.coverage)
+	testutil.Equals(t, uint64(150), estimate.series)
+
+	// Replicas with the same latency and time range are compared by their series.
+	small := estimateCost(infos[1:2], 10000, 20000, 0)
+	large := estimateCost(infopb.TSDBInfos{{MinTime: 10000, MaxTime: 20000, NumSeries: 80}}, 10000, 20000, 0)
+	testutil.Assert(t, small.seriesKnown && large.seriesKnown)
+	testutil.Assert(t, small.cost(true) < large.cost(true))
+	testutil.Equals(t, small.cost(false), large.cost(false))
+}
+
+func TestRemoteEndpoints_CostBasedReplicaSelectionUnknownSeries(t *testing.T) {
+	t.Parallel()
+
+	var (
+		store = NewClient(nil, "store", []infopb.TSDBInfo{
+			{Labels: zLabelSetFromStrings("zone", "east", "replica", "a"), MinTime: 0, MaxTime: 1000, NumSeries: 1000},
+		})
+		// Sidecars don't report series, which must not make them look cheaper.
+		sidecar = NewClient(nil, "sidecar", []infopb.TSDBInfo{
+			{Labels: zLabelSetFromStrings("zone", "east", "replica", "b"), MinTime: 0, MaxTime: 1000},
+		})
+	)
+	latencies := NewEndpointLatencies()
+	latencies.Observe("sidecar", time.Second)
+
+	endpoints := NewRemoteEndpoints(log.NewNopLogger(), func() []Client { return []Client{store, sidecar} }, Opts{
+		ReplicaLabels:             []string{"replica"},
+		CostBasedReplicaSelection: true,
+		Latencies:                 latencies,
+	})
+	endpoints.Engines(0, 1000)
+
+	costs := endpoints.(RemoteEngineCostReporter).RemoteEngineCosts()
+	testutil.Equals(t, 2, len(costs))
+	testutil.Equals(t, "store", costs[0].Address)
+	testutil.Assert(t, costs[0].Selected, "expected faster replica to be selected")
+	testutil.Assert(t, !costs[1].Selected, "expected slower replica to be skipped")
+	testutil.Equals(t, 1.0, costs[0].Cost)
+	testutil.Equals(t, 2.0, costs[1].Cost)
+}
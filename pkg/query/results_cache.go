@@ -0,0 +1,466 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package query
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+	"google.golang.org/grpc"
+	"gopkg.in/yaml.v2"
+
+	"github.com/thanos-io/thanos/pkg/api/query/querypb"
+	"github.com/thanos-io/thanos/pkg/cache"
+	"github.com/thanos-io/thanos/pkg/cacheutil"
+	"github.com/thanos-io/thanos/pkg/model"
+	"github.com/thanos-io/thanos/pkg/store"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"github.com/thanos-io/thanos/pkg/tenancy"
+)
+
+// ResultsCacheProvider is the type of backend used by the querier results cache.
+type ResultsCacheProvider string
+
+const (
+	InMemoryResultsCacheProvider  ResultsCacheProvider = "IN-MEMORY"
+	MemcachedResultsCacheProvider ResultsCacheProvider = "MEMCACHED"
+	RedisResultsCacheProvider     ResultsCacheProvider = "REDIS"
+)
+
+const (
+	seriesCacheType      = "series"
+	remoteQueryCacheType = "remote_query"
+)
+
+// ResultsCacheConfig is the configuration of the querier results cache.
+type ResultsCacheConfig struct {
+	Type          ResultsCacheProvider `yaml:"type"`
+	BackendConfig any                  `yaml:"config"`
+
+	// TTL of cached results. Cached data is immutable, so this only bounds how long
+	// results of deleted or rewritten blocks can be served.
+	TTL time.Duration `yaml:"ttl"`
+	// MaxEntrySize is the maximum size of the responses of a single request recorded for
+	// caching. Larger results are not recorded any further and not cached.
+	MaxEntrySize model.Bytes `yaml:"max_entry_size"`
+}
+
+// ResultsCache caches responses of Series calls and of queries pushed down to remote engines
+// for time ranges older than the maximum cache freshness, which are not expected to change anymore.
+type ResultsCache struct {
+	logger       log.Logger
+	cache        cache.Cache
+	ttl          time.Duration
+	maxFreshness time.Duration
+	maxEntrySize int
+	now          func() time.Time
+
+	requests *prometheus.CounterVec
+	hits     *prometheus.CounterVec
+}
+
+// NewResultsCacheFromYaml creates a ResultsCache from its YAML configuration.
+func NewResultsCacheFromYaml(yamlContent []byte, logger log.Logger, reg prometheus.Registerer, maxFreshness time.Duration) (*ResultsCache, error) {
+	config := &ResultsCacheConfig{TTL: 24 * time.Hour, MaxEntrySize: 16 * 1024 * 1024}
+	if err := yaml.UnmarshalStrict(yamlContent, config); err != nil {
+		return nil, errors.Wrap(err, "parsing config YAML file")
+	}
+
+	backendConfig, err := yaml.Marshal(config.BackendConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal content of cache backend configuration")
+	}
+
+	var c cache.Cache
+	switch strings.ToUpper(string(config.Type)) {
+	case string(InMemoryResultsCacheProvider):
+		c, err = cache.NewInMemoryCache("query-results", logger, reg, backendConfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create inmemory cache")
+		}
+	case string(MemcachedResultsCacheProvider):
+		memcached, err := cacheutil.NewMemcachedClient(logger, "query-results", backendConfig, reg)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create memcached client")
+		}
+		c = cache.NewMemcachedCache("query-results", logger, memcached, reg)
+	case string(RedisResultsCacheProvider):
+		redis, err := cacheutil.NewRedisClient(logger, "query-results", backendConfig, reg)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create redis client")
+		}
+		c = cache.NewRedisCache("query-results", logger, redis, reg)
+	default:
+		return nil, errors.Errorf("unsupported cache type: %s", config.Type)
+	}
+
+	return NewResultsCache(logger, reg, cache.NewTracingCache(c), config.TTL, maxFreshness, int(config.MaxEntrySize)), nil
+}
+
+// NewResultsCache creates a ResultsCache backed by the given cache. Results of a single request larger
+// than maxEntrySize bytes are not cached, 0 disables the limit.
+func NewResultsCache(logger log.Logger, reg prometheus.Registerer, c cache.Cache, ttl, maxFreshness time.Duration, maxEntrySize int) *ResultsCache {
+	return &ResultsCache{
+		logger:       logger,
+		cache:        c,
+		ttl:          ttl,
+		maxFreshness: maxFreshness,
+		maxEntrySize: maxEntrySize,
+		now:          time.Now,
+		requests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_query_results_cache_requests_total",
+			Help: "Total number of cacheable requests looked up in the querier results cache.",
+		}, []string{"type"}),
+		hits: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_query_results_cache_hits_total",
+			Help: "Total number of requests served from the querier results cache.",
+		}, []string{"type"}),
+	}
+}
+
+// cacheable reports whether data up to maxt (in milliseconds) is old enough to be cached.
+func (c *ResultsCache) cacheable(maxt int64) bool {
+	return maxt < c.now().Add(-c.maxFreshness).UnixMilli()
+}
+
+// key returns the cache key of a request. The tenant and the store matchers restricting the queried stores
+// are part of the key so that results are never shared across tenants or different sets of stores.
+func (c *ResultsCache) key(ctx context.Context, typ string, req interface{ Marshal() ([]byte, error) }) (string, bool) {
+	b, err := req.Marshal()
+	if err != nil {
+		level.Warn(c.logger).Log("msg", "failed to marshal request for results cache", "err", err)
+		return "", false
+	}
+	tenant, ok := tenancy.GetTenantFromGRPCMetadata(ctx)
+	if !ok {
+		tenant, _ = ctx.Value(tenancy.TenantKey).(string)
+	}
+	h := sha256.New()
+	_, _ = h.Write(b)
+	if storeMatchers, ok := ctx.Value(store.StoreMatcherKey).([][]*labels.Matcher); ok {
+		for _, ms := range storeMatchers {
+			_, _ = h.Write([]byte{0xff})
+			for _, m := range ms {
+				_, _ = h.Write([]byte(m.String()))
+				_, _ = h.Write([]byte{0})
+			}
+		}
+	}
+	return typ + ":" + tenant + ":" + hex.EncodeToString(h.Sum(nil)), true
+}
+
+func (c *ResultsCache) fetch(ctx context.Context, typ, key string) ([][]byte, bool) {
+	c.requests.WithLabelValues(typ).Inc()
+
+	data, ok := c.cache.Fetch(ctx, []string{key})[key]
+	if !ok {
+		return nil, false
+	}
+	msgs, err := decodeMessages(data)
+	if err != nil {
+		level.Warn(c.logger).Log("msg", "failed to decode cached results", "key", key, "err", err)
+		return nil, false
+	}
+	c.hits.WithLabelValues(typ).Inc()
+	return msgs, true
+}
+
+func (c *ResultsCache) store(key string, msgs [][]byte) {
+	c.cache.Store(map[string][]byte{key: encodeMessages(msgs)}, c.ttl)
+}
+
+func (c *ResultsCache) newRecorder() *resultsRecorder {
+	return &resultsRecorder{maxBytes: c.maxEntrySize}
+}
+
+// resultsRecorder records the marshaled responses of a request. Recording is aborted once the
+// responses exceed maxBytes, so that large results are not buffered in memory.
+type resultsRecorder struct {
+	maxBytes int
+
+	msgs    [][]byte
+	size    int
+	aborted bool
+}
+
+// record marshals and records resp. All responses are recorded, including hints and stats,
+// so that cached results are replayed like they were received.
+func (r *resultsRecorder) record(resp interface{ Marshal() ([]byte, error) }) {
+	if r.aborted {
+		return
+	}
+	b, err := resp.Marshal()
+	if err != nil {
+		r.abort()
+		return
+	}
+	r.size += len(b)
+	if r.maxBytes > 0 && r.size > r.maxBytes {
+		r.abort()
+		return
+	}
+	r.msgs = append(r.msgs, b)
+}
+
+func (r *resultsRecorder) abort() {
+	r.aborted = true
+	r.msgs = nil
+}
+
+// encodeMessages encodes marshaled protobuf messages as a sequence of length-prefixed frames.
+func encodeMessages(msgs [][]byte) []byte {
+	size := 0
+	for _, m := range msgs {
+		size += binary.MaxVarintLen64 + len(m)
+	}
+	buf := make([]byte, 0, size)
+	for _, m := range msgs {
+		buf = binary.AppendUvarint(buf, uint64(len(m)))
+		buf = append(buf, m...)
+	}
+	return buf
+}
+
+func decodeMessages(buf []byte) ([][]byte, error) {
+	var msgs [][]byte
+	for len(buf) > 0 {
+		l, n := binary.Uvarint(buf)
+		if n <= 0 || uint64(len(buf)-n) < l {
+			return nil, errors.New("corrupted cache entry")
+		}
+		msgs = append(msgs, buf[n:n+int(l)])
+		buf = buf[n+int(l):]
+	}
+	return msgs, nil
+}
+
+type cachingStoreServer struct {
+	storepb.StoreServer
+	cache *ResultsCache
+}
+
+// NewCachingStoreServer returns a StoreServer which serves Series calls for immutable time ranges
+// from the results cache. The given store is returned as is if the cache is nil.
+func NewCachingStoreServer(s storepb.StoreServer, c *ResultsCache) storepb.StoreServer {
+	if c == nil {
+		return s
+	}
+	return &cachingStoreServer{StoreServer: s, cache: c}
+}
+
+func (s *cachingStoreServer) Series(req *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+	if !s.cache.cacheable(req.MaxTime) {
+		return s.StoreServer.Series(req, srv)
+	}
+	key, ok := s.cache.key(srv.Context(), seriesCacheType, req)
+	if !ok {
+		return s.StoreServer.Series(req, srv)
+	}
+
+	if msgs, ok := s.cache.fetch(srv.Context(), seriesCacheType, key); ok {
+		for _, m := range msgs {
+			resp := &storepb.SeriesResponse{}
+			if err := resp.Unmarshal(m); err != nil {
+				return errors.Wrap(err, "unmarshal cached series response")
+			}
+			if err := srv.Send(resp); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	recorder := &recordingSeriesServer{Store_SeriesServer: srv, recorder: s.cache.newRecorder()}
+	if err := s.StoreServer.Series(req, recorder); err != nil {
+		return err
+	}
+	if !recorder.recorder.aborted {
+		s.cache.store(key, recorder.recorder.msgs)
+	}
+	return nil
+}
+
+// recordingSeriesServer records the responses sent through it. Responses with
+// warnings are partial and are never cached.
+type recordingSeriesServer struct {
+	storepb.Store_SeriesServer
+	recorder *resultsRecorder
+}
+
+func (r *recordingSeriesServer) Send(resp *storepb.SeriesResponse) error {
+	if resp.GetWarning() != "" {
+		r.recorder.abort()
+	} else {
+		r.recorder.record(resp)
+	}
+	return r.Store_SeriesServer.Send(resp)
+}
+
+type cachingQueryClient struct {
+	querypb.QueryClient
+	cache *ResultsCache
+}
+
+// NewCachingQueryClient returns a QueryClient which serves step-aligned queries for immutable
+// time ranges from the results cache. The given client is returned as is if the cache is nil.
+func NewCachingQueryClient(c querypb.QueryClient, rc *ResultsCache) querypb.QueryClient {
+	if rc == nil {
+		return c
+	}
+	return &cachingQueryClient{QueryClient: c, cache: rc}
+}
+
+func (c *cachingQueryClient) Query(ctx context.Context, req *querypb.QueryRequest, opts ...grpc.CallOption) (querypb.Query_QueryClient, error) {
+	if !c.cache.cacheable(time.Unix(req.TimeSeconds, 0).UnixMilli()) {
+		return c.QueryClient.Query(ctx, req, opts...)
+	}
+	keyReq := *req
+	keyReq.TimeoutSeconds = 0
+	key, ok := c.cache.key(ctx, remoteQueryCacheType, &keyReq)
+	if !ok {
+		return c.QueryClient.Query(ctx, req, opts...)
+	}
+
+	if msgs, ok := c.cache.fetch(ctx, remoteQueryCacheType, key); ok {
+		return &cachedQueryClient{msgs: msgs}, nil
+	}
+	qry, err := c.QueryClient.Query(ctx, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingQueryClient{Query_QueryClient: qry, cache: c.cache, key: key, recorder: c.cache.newRecorder()}, nil
+}
+
+func (c *cachingQueryClient) QueryRange(ctx context.Context, req *querypb.QueryRangeRequest, opts ...grpc.CallOption) (querypb.Query_QueryRangeClient, error) {
+	if !c.cache.cacheable(time.Unix(req.EndTimeSeconds, 0).UnixMilli()) ||
+		req.IntervalSeconds <= 0 || req.StartTimeSeconds%req.IntervalSeconds != 0 {
+		return c.QueryClient.QueryRange(ctx, req, opts...)
+	}
+	keyReq := *req
+	keyReq.TimeoutSeconds = 0
+	key, ok := c.cache.key(ctx, remoteQueryCacheType, &keyReq)
+	if !ok {
+		return c.QueryClient.QueryRange(ctx, req, opts...)
+	}
+
+	if msgs, ok := c.cache.fetch(ctx, remoteQueryCacheType, key); ok {
+		return &cachedQueryRangeClient{msgs: msgs}, nil
+	}
+	qry, err := c.QueryClient.QueryRange(ctx, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingQueryRangeClient{Query_QueryRangeClient: qry, cache: c.cache, key: key, recorder: c.cache.newRecorder()}, nil
+}
+
+// cachedQueryClient replays cached responses of an instant query.
+type cachedQueryClient struct {
+	querypb.Query_QueryClient
+	msgs [][]byte
+}
+
+func (c *cachedQueryClient) Recv() (*querypb.QueryResponse, error) {
+	if len(c.msgs) == 0 {
+		return nil, io.EOF
+	}
+	resp := &querypb.QueryResponse{}
+	if err := resp.Unmarshal(c.msgs[0]); err != nil {
+		return nil, errors.Wrap(err, "unmarshal cached query response")
+	}
+	c.msgs = c.msgs[1:]
+	return resp, nil
+}
+
+// recordingQueryClient stores the responses of an instant query in the cache once
+// the stream is fully consumed without warnings.
+type recordingQueryClient struct {
+	querypb.Query_QueryClient
+	cache    *ResultsCache
+	key      string
+	recorder *resultsRecorder
+}
+
+func (r *recordingQueryClient) Recv() (*querypb.QueryResponse, error) {
+	resp, err := r.Query_QueryClient.Recv()
+	r.record(resp, err)
+	return resp, err
+}
+
+func (r *recordingQueryClient) record(resp *querypb.QueryResponse, err error) {
+	if r.recorder.aborted {
+		return
+	}
+	if err == io.EOF {
+		r.cache.store(r.key, r.recorder.msgs)
+		// Only store once, even if Recv is called again.
+		r.recorder.abort()
+		return
+	}
+	if err != nil || resp.GetWarnings() != "" {
+		r.recorder.abort()
+		return
+	}
+	r.recorder.record(resp)
+}
+
+// cachedQueryRangeClient replays cached responses of a range query.
+type cachedQueryRangeClient struct {
+	querypb.Query_QueryRangeClient
+	msgs [][]byte
+}
+
+func (c *cachedQueryRangeClient) Recv() (*querypb.QueryRangeResponse, error) {
+	if len(c.msgs) == 0 {
+		return nil, io.EOF
+	}
+	resp := &querypb.QueryRangeResponse{}
+	if err := resp.Unmarshal(c.msgs[0]); err != nil {
+		return nil, errors.Wrap(err, "unmarshal cached query range response")
+	}
+	c.msgs = c.msgs[1:]
+	return resp, nil
+}
+
+// recordingQueryRangeClient stores the responses of a range query in the cache once
+// the stream is fully consumed without warnings.
+type recordingQueryRangeClient struct {
+	querypb.Query_QueryRangeClient
+	cache    *ResultsCache
+	key      string
+	recorder *resultsRecorder
+}
+
+func (r *recordingQueryRangeClient) Recv() (*querypb.QueryRangeResponse, error) {
+	resp, err := r.Query_QueryRangeClient.Recv()
+	r.record(resp, err)
+	return resp, err
+}
+
+func (r *recordingQueryRangeClient) record(resp *querypb.QueryRangeResponse, err error) {
+	if r.recorder.aborted {
+		return
+	}
+	if err == io.EOF {
+		r.cache.store(r.key, r.recorder.msgs)
+		// Only store once, even if Recv is called again.
+		r.recorder.abort()
+		return
+	}
+	if err != nil || resp.GetWarnings() != "" {
+		r.recorder.abort()
+		return
+	}
+	r.recorder.record(resp)
+}
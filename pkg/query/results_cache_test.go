@@ -0,0 +1,214 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package query
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/go-kit/log"
+	"github.com/gogo/protobuf/types"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"google.golang.org/grpc"
+
+	"github.com/thanos-io/thanos/pkg/api/query/querypb"
+	"github.com/thanos-io/thanos/pkg/cache"
+	"github.com/thanos-io/thanos/pkg/store"
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"github.com/thanos-io/thanos/pkg/store/storepb/prompb"
+	storetestutil "github.com/thanos-io/thanos/pkg/store/storepb/testutil"
+	"github.com/thanos-io/thanos/pkg/tenancy"
+)
+
+func newTestResultsCache(t *testing.T, maxEntrySize int) *ResultsCache {
+	c, err := cache.NewInMemoryCacheWithConfig("test", log.NewNopLogger(), nil, cache.InMemoryCacheConfig{
+		MaxSize:     1 << 20,
+		MaxItemSize: 1 << 20,
+	})
+	testutil.Ok(t, err)
+	rc := NewResultsCache(log.NewNopLogger(), prometheus.NewRegistry(), c, time.Hour, 10*time.Minute, maxEntrySize)
+	rc.now = func() time.Time { return time.Unix(3600, 0) }
+	return rc
+}
+
+type countingStoreServer struct {
+	storepb.StoreServer
+	calls    int
+	warnings bool
+}
+
+func (s *countingStoreServer) Series(_ *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+	s.calls++
+	if s.warnings {
+		if err := srv.Send(storepb.NewWarnSeriesResponse(errors.New("partial"))); err != nil {
+			return err
+		}
+	}
+	if err := srv.Send(storepb.NewSeriesResponse(&storepb.Series{
+		Labels: labelpb.ZLabelsFromPromLabels(labels.FromStrings("a", "b")),
+	})); err != nil {
+		return err
+	}
+	return srv.Send(storepb.NewHintsSeriesResponse(&types.Any{TypeUrl: "hints", Value: []byte("hints")}))
+}
+
+func TestCachingStoreServer_Series(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name          string
+		maxt          int64
+		warnings      bool
+		maxEntrySize  int
+		expectedCalls int
+	}{
+		{name: "immutable range is cached", maxt: 1000, expectedCalls: 1},
+		{name: "result larger than the max entry size is not cached", maxt: 1000, maxEntrySize: 10, expectedCalls: 2},
+		{name: "recent range is not cached", maxt: 3500 * 1000, expectedCalls: 2},
+		{name: "partial response is not cached", maxt: 1000, warnings: true, expectedCalls: 2},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			upstream := &countingStoreServer{warnings: tcase.warnings}
+			s := NewCachingStoreServer(upstream, newTestResultsCache(t, tcase.maxEntrySize))
+
+			for range 2 {
+				srv := storetestutil.NewSeriesServer(context.Background())
+				testutil.Ok(t, s.Series(&storepb.SeriesRequest{MinTime: 0, MaxTime: tcase.maxt}, srv))
+				testutil.Equals(t, 1, len(srv.SeriesSet))
+				testutil.Equals(t, labels.FromStrings("a", "b"), srv.SeriesSet[0].PromLabels())
+				// Hints are replayed from the cache as well.
+				testutil.Equals(t, []*types.Any{{TypeUrl: "hints", Value: []byte("hints")}}, srv.HintsSet)
+			}
+			testutil.Equals(t, tcase.expectedCalls, upstream.calls)
+		})
+	}
+}
+
+type countingQueryClient struct {
+	querypb.QueryClient
+	calls int
+}
+
+func (c *countingQueryClient) QueryRange(_ context.Context, _ *querypb.QueryRangeRequest, _ ...grpc.CallOption) (querypb.Query_QueryRangeClient, error) {
+	c.calls++
+	return &staticQueryRangeClient{msgs: []*querypb.QueryRangeResponse{
+		querypb.NewQueryRangeResponse(&prompb.TimeSeries{Samples: []prompb.Sample{{Value: 1, Timestamp: 0}}}),
+		querypb.NewQueryRangeStatsResponse(&querypb.QueryStats{SamplesTotal: 1}),
+	}}, nil
+}
+
+type staticQueryRangeClient struct {
+	querypb.Query_QueryRangeClient
+	msgs []*querypb.QueryRangeResponse
+}
+
+func (c *staticQueryRangeClient) Recv() (*querypb.QueryRangeResponse, error) {
+	if len(c.msgs) == 0 {
+		return nil, io.EOF
+	}
+	msg := c.msgs[0]
+	c.msgs = c.msgs[1:]
+	return msg, nil
+}
+
+func TestCachingQueryClient_QueryRange(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name          string
+		req           *querypb.QueryRangeRequest
+		expectedCalls int
+	}{
+		{
+			name:          "aligned immutable range is cached",
+			req:           &querypb.QueryRangeRequest{Query: "up", StartTimeSeconds: 60, EndTimeSeconds: 600, IntervalSeconds: 30},
+			expectedCalls: 1,
+		},
+		{
+			name:          "unaligned range is not cached",
+			req:           &querypb.QueryRangeRequest{Query: "up", StartTimeSeconds: 61, EndTimeSeconds: 600, IntervalSeconds: 30},
+			expectedCalls: 2,
+		},
+		{
+			name:          "recent range is not cached",
+			req:           &querypb.QueryRangeRequest{Query: "up", StartTimeSeconds: 60, EndTimeSeconds: 3500, IntervalSeconds: 30},
+			expectedCalls: 2,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			upstream := &countingQueryClient{}
+			c := NewCachingQueryClient(upstream, newTestResultsCache(t, 0))
+
+			for i := range 2 {
+				req := *tcase.req
+				req.TimeoutSeconds = int64(i)
+				qry, err := c.QueryRange(context.Background(), &req)
+				testutil.Ok(t, err)
+
+				var series, stats int
+				for {
+					msg, err := qry.Recv()
+					if err == io.EOF {
+						break
+					}
+					testutil.Ok(t, err)
+					if msg.GetStats() != nil {
+						// Stats are replayed from the cache as well.
+						testutil.Equals(t, int64(1), msg.GetStats().SamplesTotal)
+						stats++
+						continue
+					}
+					testutil.Equals(t, 1.0, msg.GetTimeseries().Samples[0].Value)
+					series++
+				}
+				testutil.Equals(t, 1, series)
+				testutil.Equals(t, 1, stats)
+			}
+			testutil.Equals(t, tcase.expectedCalls, upstream.calls)
+		})
+	}
+}
+
+func TestResultsCache_KeyTenant(t *testing.T) {
+	t.Parallel()
+
+	c := newTestResultsCache(t, 0)
+	req := &storepb.SeriesRequest{MaxTime: 1000}
+
+	key, ok := c.key(context.WithValue(context.Background(), tenancy.TenantKey, "team-a"), seriesCacheType, req)
+	testutil.Assert(t, ok)
+	testutil.Assert(t, strings.HasPrefix(key, seriesCacheType+":team-a:"), key)
+
+	// Tenants of an unexpected type are ignored instead of panicking.
+	key, ok = c.key(context.WithValue(context.Background(), tenancy.TenantKey, 1), seriesCacheType, req)
+	testutil.Assert(t, ok)
+	testutil.Assert(t, strings.HasPrefix(key, seriesCacheType+"::"), key)
+}
+
+func TestResultsCache_KeyStoreMatchers(t *testing.T) {
+	t.Parallel()
+
+	c := newTestResultsCache(t, 0)
+	req := &storepb.SeriesRequest{MaxTime: 1000}
+	withStoreMatchers := func(ms ...*labels.Matcher) context.Context {
+		return context.WithValue(context.Background(), store.StoreMatcherKey, [][]*labels.Matcher{ms})
+	}
+
+	unrestricted, ok := c.key(context.Background(), seriesCacheType, req)
+	testutil.Assert(t, ok)
+	restricted, ok := c.key(withStoreMatchers(labels.MustNewMatcher(labels.MatchEqual, "__address__", "a")), seriesCacheType, req)
+	testutil.Assert(t, ok)
+	other, ok := c.key(withStoreMatchers(labels.MustNewMatcher(labels.MatchEqual, "__address__", "b")), seriesCacheType, req)
+	testutil.Assert(t, ok)
+
+	testutil.Assert(t, unrestricted != restricted, "requests restricted to stores must not share results with unrestricted ones")
+	testutil.Assert(t, restricted != other, "requests restricted to different stores must not share results")
+}
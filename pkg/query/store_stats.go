@@ -0,0 +1,64 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package query
+
+import (
+	"context"
+	"sync"
+
+	"github.com/thanos-io/thanos/pkg/store/hintspb"
+)
+
+// StoreStats aggregates the query stats of all StoreAPI Series calls made to evaluate a query, e.g. bytes
+// fetched by store gateways and latencies of stores.
+type StoreStats struct {
+	mtx   sync.Mutex
+	stats hintspb.QueryStats
+}
+
+// NewStoreStats returns empty StoreStats.
+func NewStoreStats() *StoreStats {
+	return &StoreStats{}
+}
+
+// Merge adds the given stats of a Series call.
+func (s *StoreStats) Merge(stats *hintspb.QueryStats) {
+	if s == nil || stats == nil {
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.stats.Merge(stats)
+}
+
+// Stats returns the aggregated stats.
+func (s *StoreStats) Stats() *hintspb.QueryStats {
+	if s == nil {
+		return nil
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	stats := s.stats
+	stats.StoreStats = append([]hintspb.StoreStats(nil), s.stats.StoreStats...)
+	return &stats
+}
+
+type storeStatsCtxKey struct{}
+
+// NewStoreStatsContext returns a copy of ctx carrying the given StoreStats. Queriers created by QueryableCreator
+// request query stats from StoreAPIs when it is present and merge them into it.
+func NewStoreStatsContext(ctx context.Context, s *StoreStats) context.Context {
+	if s == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, storeStatsCtxKey{}, s)
+}
+
+// StoreStatsFromContext returns the StoreStats stored in ctx, or nil if none is present.
+func StoreStatsFromContext(ctx context.Context) *StoreStats {
+	s, _ := ctx.Value(storeStatsCtxKey{}).(*StoreStats)
+	return s
+}
@@ -6,6 +6,7 @@ package queryfrontend
 import (
 	"bytes"
 	"fmt"
+	"slices"
 	"sort"
 	"strconv"
 	"sync"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/thanos-io/thanos/internal/cortex/querier/queryrange"
 	"github.com/thanos-io/thanos/pkg/compact/downsample"
+	"github.com/thanos-io/thanos/pkg/extpromql"
 )
 
 const maxPooledCacheKeyBufferSize = 64 * 1024
@@ -28,27 +30,40 @@ var queryRangeCacheKeyBufferPool = sync.Pool{
 // thanosCacheKeyGenerator is a utility for using split interval when determining cache keys.
 type thanosCacheKeyGenerator struct {
 	resolutions []int64
+	// generations, if not nil, are included in keys of requests touching changed time ranges.
+	generations *CacheGenerations
 }
 
-func newThanosCacheKeyGenerator() thanosCacheKeyGenerator {
-	return thanosCacheKeyGenerator{
-		resolutions: []int64{downsample.ResLevel2, downsample.ResLevel1, downsample.ResLevel0},
+// newThanosCacheKeyGenerator creates a cache key generator for the given downsampling resolutions
+// in increasing order, the default 5m and 1h ones if empty.
+func newThanosCacheKeyGenerator(downsamplingResolutions []int64, generations *CacheGenerations) thanosCacheKeyGenerator {
+	if len(downsamplingResolutions) == 0 {
+		downsamplingResolutions = defaultResolutions
 	}
+	resolutions := []int64{downsample.ResLevel0}
+	resolutions = append(resolutions, downsamplingResolutions...)
+	slices.Reverse(resolutions)
+	return thanosCacheKeyGenerator{resolutions: resolutions, generations: generations}
 }
 
 // GenerateCacheKey generates a cache key based on the Request and interval.
 func (t thanosCacheKeyGenerator) GenerateCacheKey(userID string, r queryrange.Request) string {
+	if tr, ok := r.(*ThanosQueryInstantRequest); ok {
+		return t.generateQueryInstantCacheKey(userID, tr)
+	}
 	if sr, ok := r.(SplitRequest); ok {
 		splitInterval := sr.GetSplitInterval().Milliseconds()
 		currentInterval := r.GetStart() / splitInterval
 
 		switch tr := r.(type) {
 		case *ThanosQueryRangeRequest:
-			return t.generateQueryRangeCacheKey(userID, tr, tr.Step, splitInterval, currentInterval)
+			return t.generateQueryRangeCacheKey(userID, tr, tr.Step, splitInterval, currentInterval, t.queryRangeGeneration(userID, tr, splitInterval, currentInterval))
 		case *ThanosLabelsRequest:
-			return fmt.Sprintf("fe:%s:%s:%s:%d:%d", userID, tr.Label, tr.Matchers, splitInterval, currentInterval)
+			return fmt.Sprintf("fe:%s:%s:%s:%d:%d", userID, tr.Label, tr.Matchers, splitInterval, currentInterval) +
+				generationSuffix(t.generation(userID, "", currentInterval*splitInterval, (currentInterval+1)*splitInterval))
 		case *ThanosSeriesRequest:
-			return fmt.Sprintf("fe:%s:%s:%d:%d", userID, tr.Matchers, splitInterval, currentInterval)
+			return fmt.Sprintf("fe:%s:%s:%d:%d", userID, tr.Matchers, splitInterval, currentInterval) +
+				generationSuffix(t.generation(userID, "", currentInterval*splitInterval, (currentInterval+1)*splitInterval))
 		}
 	}
 
@@ -73,12 +88,13 @@ func (t thanosCacheKeyGenerator) GenerateCacheKeyAlternatives(userID string, r q
 		return nil
 	}
 
+	generation := t.queryRangeGeneration(userID, tr, splitInterval, currentInterval)
 	keys := make([]string, 0, len(steps))
 	for _, step := range steps {
 		if tr.Start%step != 0 {
 			continue
 		}
-		keys = append(keys, t.generateQueryRangeCacheKey(userID, tr, step, splitInterval, currentInterval))
+		keys = append(keys, t.generateQueryRangeCacheKey(userID, tr, step, splitInterval, currentInterval, generation))
 	}
 	if len(keys) == 0 {
 		return nil
@@ -87,7 +103,7 @@ func (t thanosCacheKeyGenerator) GenerateCacheKeyAlternatives(userID string, r q
 	return keys
 }
 
-func (t thanosCacheKeyGenerator) generateQueryRangeCacheKey(userID string, tr *ThanosQueryRangeRequest, step, splitInterval, currentInterval int64) string {
+func (t thanosCacheKeyGenerator) generateQueryRangeCacheKey(userID string, tr *ThanosQueryRangeRequest, step, splitInterval, currentInterval, generation int64) string {
 	i := 0
 	for ; i < len(t.resolutions) && t.resolutions[i] > tr.MaxSourceResolution; i++ {
 	}
@@ -116,6 +132,7 @@ func (t thanosCacheKeyGenerator) generateQueryRangeCacheKey(userID string, tr *T
 	buf.WriteByte(':')
 	writeCacheKeyReplicaLabels(buf, replicaLabels)
 	writeCacheKeyBool(buf, tr.Analyze)
+	writeCacheKeyGeneration(buf, generation)
 
 	cacheKey := buf.String()
 	buf.Reset()
@@ -126,6 +143,83 @@ func (t thanosCacheKeyGenerator) generateQueryRangeCacheKey(userID string, tr *T
 	return cacheKey
 }
 
+func (t thanosCacheKeyGenerator) generateQueryInstantCacheKey(userID string, tr *ThanosQueryInstantRequest) string {
+	// Auto downsampling is resolved by the querier depending on the query, so it gets a level of its own.
+	i := -1
+	if !tr.AutoDownsampling {
+		for i = 0; i < len(t.resolutions) && t.resolutions[i] > tr.MaxSourceResolution; i++ {
+		}
+	}
+	shardInfoKey := "-"
+	if tr.ShardInfo != nil {
+		shardInfoKey = fmt.Sprintf("%d:%d", tr.ShardInfo.TotalShards, tr.ShardInfo.ShardIndex)
+	}
+	replicaLabels := append([]string(nil), tr.ReplicaLabels...)
+	sort.Strings(replicaLabels)
+
+	buf := queryRangeCacheKeyBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	buf.Grow(len(userID) + len(tr.Query) + len(shardInfoKey) + len(tr.Engine) + len(tr.Stats) + cacheKeyReplicaLabelsLen(replicaLabels) + 64)
+
+	buf.WriteString("fe:instant:")
+	buf.WriteString(userID)
+	buf.WriteByte(':')
+	buf.WriteString(tr.Query)
+	writeCacheKeyInt64(buf, tr.Time)
+	writeCacheKeyInt(buf, i)
+	buf.WriteByte(':')
+	buf.WriteString(shardInfoKey)
+	writeCacheKeyInt64(buf, tr.LookbackDelta)
+	buf.WriteByte(':')
+	buf.WriteString(tr.Engine)
+	writeCacheKeyBool(buf, tr.Dedup)
+	writeCacheKeyBool(buf, tr.PartialResponse)
+	buf.WriteByte(':')
+	writeCacheKeyReplicaLabels(buf, replicaLabels)
+	writeCacheKeyBool(buf, tr.Analyze)
+	buf.WriteByte(':')
+	buf.WriteString(tr.Stats)
+	writeCacheKeyGeneration(buf, t.generation(userID, tr.Query, tr.Time-lookbackDelta(tr.LookbackDelta), tr.Time))
+
+	cacheKey := buf.String()
+	buf.Reset()
+	if buf.Cap() <= maxPooledCacheKeyBufferSize {
+		queryRangeCacheKeyBufferPool.Put(buf)
+	}
+
+	return cacheKey
+}
+
+// queryRangeGeneration returns the generation of the data selected by the range query in the given split interval.
+func (t thanosCacheKeyGenerator) queryRangeGeneration(userID string, tr *ThanosQueryRangeRequest, splitInterval, currentInterval int64) int64 {
+	return t.generation(userID, tr.Query, currentInterval*splitInterval-lookbackDelta(tr.LookbackDelta), (currentInterval+1)*splitInterval)
+}
+
+// generation returns the generation of the data in the given time range, extended back by the range selected
+// by the query if any, 0 if it didn't change.
+func (t thanosCacheKeyGenerator) generation(userID, query string, minTime, maxTime int64) int64 {
+	if t.generations == nil || t.generations.empty() {
+		return 0
+	}
+	if query != "" {
+		// Queries which can't be parsed fail downstream, so they are not cached.
+		if expr, err := extpromql.ParseExpr(query); err == nil {
+			minTime -= selectedRange(expr)
+		}
+	}
+	return t.generations.Generation(userID, minTime, maxTime)
+}
+
+// defaultLookbackDelta is the lookback delta used by queriers when requests don't set one.
+const defaultLookbackDelta = 5 * time.Minute
+
+func lookbackDelta(delta int64) int64 {
+	if delta > 0 {
+		return delta
+	}
+	return defaultLookbackDelta.Milliseconds()
+}
+
 // commonQuerySteps bounds alternative cache lookups to common dashboard query steps.
 var commonQuerySteps = []int64{
 	(12 * time.Hour).Milliseconds(),
@@ -194,6 +288,19 @@ func writeCacheKeyBool(buf *bytes.Buffer, value bool) {
 	buf.Write(strconv.AppendBool(scratch[:0], value))
 }
 
+// writeCacheKeyGeneration appends the generation to the key if the data changed, so that keys of unchanged data
+// are the same as without generations.
+func writeCacheKeyGeneration(buf *bytes.Buffer, generation int64) {
+	buf.WriteString(generationSuffix(generation))
+}
+
+func generationSuffix(generation int64) string {
+	if generation == 0 {
+		return ""
+	}
+	return ":g" + strconv.FormatInt(generation, 10)
+}
+
 func writeCacheKeyReplicaLabels(buf *bytes.Buffer, replicaLabels []string) {
 	for i, replicaLabel := range replicaLabels {
 		if i > 0 {
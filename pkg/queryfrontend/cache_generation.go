@@ -0,0 +1,425 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/thanos-io/objstore"
+
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+const (
+	// maxCacheGenerationChanges bounds the number of changes tracked by CacheGenerations.
+	maxCacheGenerationChanges = 1000
+
+	// CacheGenerationsDir is the directory in the bucket holding the stored changes of cache generations.
+	CacheGenerationsDir = "query-frontend-cache-generations"
+)
+
+// cacheChange is a change of the data stored for a time range of a tenant, or of all tenants if tenant is empty.
+type cacheChange struct {
+	tenant           string
+	minTime, maxTime int64
+	generation       int64
+}
+
+func (c cacheChange) overlaps(tenants []string, minTime, maxTime int64) bool {
+	return c.minTime <= maxTime && minTime <= c.maxTime && (c.tenant == "" || slices.Contains(tenants, c.tenant))
+}
+
+// storedCacheChange is the content of a change object in the bucket.
+type storedCacheChange struct {
+	Tenant     string `json:"tenant,omitempty"`
+	MinTime    int64  `json:"min_time"`
+	MaxTime    int64  `json:"max_time"`
+	Generation int64  `json:"generation"`
+}
+
+// CacheGenerations tracks changes of the data stored for time ranges, e.g. backfilled, rewritten or deleted blocks.
+// Cache keys of requests touching a changed time range include the generation of the latest change, so that
+// results cached before the change are not used anymore. If a bucket is set, explicit invalidations and deleted
+// blocks are stored in it, so that they are shared with other Query Frontend replicas and kept across restarts.
+type CacheGenerations struct {
+	mtx     sync.RWMutex
+	changes []cacheChange
+	last    int64
+	now     func() time.Time
+
+	bkt objstore.Bucket
+	// stored are the names of changes stored in the bucket which are already tracked.
+	stored map[string]struct{}
+
+	bumps prometheus.Counter
+}
+
+// NewCacheGenerations creates empty cache generations.
+func NewCacheGenerations(reg prometheus.Registerer) *CacheGenerations {
+	return &CacheGenerations{
+		now: time.Now,
+		bumps: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_query_frontend_cache_generation_bumps_total",
+			Help: "Total number of changes of stored data invalidating cached results.",
+		}),
+	}
+}
+
+// SetBucket makes the generations store explicit invalidations and deleted blocks in the bucket, and load the
+// changes stored by other replicas or before a restart with Sync.
+func (g *CacheGenerations) SetBucket(bkt objstore.Bucket) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	g.bkt = bkt
+	g.stored = map[string]struct{}{}
+}
+
+// Bump invalidates results cached for the given tenant, or all tenants if empty, and time range in milliseconds.
+// The change is stored in the bucket, if set.
+func (g *CacheGenerations) Bump(ctx context.Context, tenant string, minTime, maxTime int64) error {
+	return g.bumpStored(ctx, ulid.MustNew(ulid.Now(), rand.Reader).String(), tenant, minTime, maxTime)
+}
+
+// bump invalidates results cached for the given tenant and time range in this process only. It is used for changes
+// which every replica derives from the bucket on its own.
+func (g *CacheGenerations) bump(tenant string, minTime, maxTime int64) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	g.add(cacheChange{tenant: tenant, minTime: minTime, maxTime: maxTime, generation: max(g.now().UnixMilli(), g.last+1)})
+}
+
+// bumpStored invalidates results cached for the given tenant and time range and stores the change in the bucket,
+// if set, under the given name. Changes already stored under the name, e.g. by another replica, are not repeated.
+func (g *CacheGenerations) bumpStored(ctx context.Context, name, tenant string, minTime, maxTime int64) error {
+	g.mtx.Lock()
+	if _, ok := g.stored[name]; ok {
+		g.mtx.Unlock()
+		return nil
+	}
+	c := cacheChange{tenant: tenant, minTime: minTime, maxTime: maxTime, generation: max(g.now().UnixMilli(), g.last+1)}
+	g.add(c)
+	bkt := g.bkt
+	if bkt != nil {
+		g.stored[name] = struct{}{}
+	}
+	g.mtx.Unlock()
+
+	if bkt == nil {
+		return nil
+	}
+	b, err := json.Marshal(storedCacheChange{Tenant: c.tenant, MinTime: c.minTime, MaxTime: c.maxTime, Generation: c.generation})
+	if err != nil {
+		return errors.Wrap(err, "marshal cache generation change")
+	}
+	return errors.Wrap(bkt.Upload(ctx, path.Join(CacheGenerationsDir, name+".json"), bytes.NewReader(b)), "upload cache generation change")
+}
+
+// Sync loads the changes stored in the bucket which are not tracked yet.
+func (g *CacheGenerations) Sync(ctx context.Context) error {
+	g.mtx.RLock()
+	bkt := g.bkt
+	g.mtx.RUnlock()
+	if bkt == nil {
+		return nil
+	}
+
+	return bkt.Iter(ctx, CacheGenerationsDir, func(name string) error {
+		id := strings.TrimSuffix(path.Base(name), ".json")
+		g.mtx.RLock()
+		_, ok := g.stored[id]
+		g.mtx.RUnlock()
+		if ok {
+			return nil
+		}
+
+		c, err := readStoredCacheChange(ctx, bkt, name)
+		if bkt.IsObjNotFoundErr(errors.Cause(err)) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		g.mtx.Lock()
+		defer g.mtx.Unlock()
+		if _, ok := g.stored[id]; !ok {
+			g.stored[id] = struct{}{}
+			g.add(cacheChange{tenant: c.Tenant, minTime: c.MinTime, maxTime: c.MaxTime, generation: c.Generation})
+		}
+		return nil
+	})
+}
+
+func readStoredCacheChange(ctx context.Context, bkt objstore.BucketReader, name string) (_ storedCacheChange, err error) {
+	var c storedCacheChange
+	rc, err := bkt.Get(ctx, name)
+	if err != nil {
+		return c, errors.Wrapf(err, "get %s", name)
+	}
+	defer runutil.CloseWithErrCapture(&err, rc, "close %s", name)
+
+	if err := json.NewDecoder(rc).Decode(&c); err != nil {
+		return c, errors.Wrapf(err, "decode %s", name)
+	}
+	return c, nil
+}
+
+// record adds a change with the given generation, which doesn't have to be greater than the previous ones.
+// It is used for changes which are known to have happened before any results were cached.
+func (g *CacheGenerations) record(c cacheChange) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	g.add(c)
+}
+
+func (g *CacheGenerations) add(c cacheChange) {
+	g.bumps.Inc()
+	g.last = max(g.last, c.generation)
+	g.changes = append(g.changes, c)
+	if len(g.changes) <= maxCacheGenerationChanges {
+		return
+	}
+
+	// Merge the two oldest changes. The merged change covers both of them, so it can only invalidate more results.
+	oldest, next := g.changes[0], g.changes[1]
+	if oldest.tenant != next.tenant {
+		next.tenant = ""
+	}
+	next.minTime = min(oldest.minTime, next.minTime)
+	next.maxTime = max(oldest.maxTime, next.maxTime)
+	next.generation = max(oldest.generation, next.generation)
+	g.changes[1] = next
+	g.changes = g.changes[1:]
+}
+
+func (g *CacheGenerations) empty() bool {
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+
+	return len(g.changes) == 0
+}
+
+// Generation returns the generation of the latest change of the data of the given tenants, as joined by
+// tenant.JoinTenantIDs, overlapping the given time range in milliseconds, or 0 if it didn't change.
+func (g *CacheGenerations) Generation(userID string, minTime, maxTime int64) int64 {
+	tenants := strings.Split(userID, "|")
+
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+
+	var generation int64
+	for _, c := range g.changes {
+		if c.overlaps(tenants, minTime, maxTime) {
+			generation = max(generation, c.generation)
+		}
+	}
+	return generation
+}
+
+// selectedRange returns the longest range selected by the expression before the evaluation time, in milliseconds.
+func selectedRange(expr parser.Expr) int64 {
+	var rng int64
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		switch n := node.(type) {
+		case *parser.VectorSelector:
+			rng = max(rng, n.OriginalOffset.Milliseconds())
+		case *parser.MatrixSelector:
+			r := n.Range
+			if vs, ok := n.VectorSelector.(*parser.VectorSelector); ok {
+				r += vs.OriginalOffset
+			}
+			rng = max(rng, r.Milliseconds())
+		case *parser.SubqueryExpr:
+			rng = max(rng, (n.Range + n.OriginalOffset).Milliseconds())
+		}
+		return nil
+	})
+	return rng
+}
+
+// BlockChangeObserver bumps cache generations of time ranges whose blocks in object storage are added late,
+// i.e. backfilled or rewritten, or deleted, e.g. by retention. Compacted and downsampled blocks don't
+// change the stored data, so they are ignored.
+type BlockChangeObserver struct {
+	logger             log.Logger
+	fetcher            block.MetadataFetcher
+	generations        *CacheGenerations
+	tenantLabel        string
+	lateBlockThreshold time.Duration
+
+	synced  bool
+	blocks  map[ulid.ULID]*metadata.Meta
+	sources map[ulid.ULID]struct{}
+}
+
+// NewBlockChangeObserver creates an observer of the blocks fetched by the given fetcher. Blocks whose data arrived
+// later than lateBlockThreshold after their max time are considered to be backfilled. Changes of blocks with the
+// tenantLabel external label only invalidate results cached for that tenant.
+func NewBlockChangeObserver(logger log.Logger, fetcher block.MetadataFetcher, generations *CacheGenerations, tenantLabel string, lateBlockThreshold time.Duration) *BlockChangeObserver {
+	return &BlockChangeObserver{
+		logger:             logger,
+		fetcher:            fetcher,
+		generations:        generations,
+		tenantLabel:        tenantLabel,
+		lateBlockThreshold: lateBlockThreshold,
+	}
+}
+
+// SyncBlocks fetches the metadata of blocks and bumps the generations of changed time ranges.
+func (o *BlockChangeObserver) SyncBlocks(ctx context.Context) error {
+	metas, partial, err := o.fetcher.Fetch(ctx)
+	if err != nil {
+		// Blocks missing from an incomplete view of the bucket would be seen as deleted.
+		return errors.Wrap(err, "fetch metas")
+	}
+
+	sources := map[ulid.ULID]struct{}{}
+	for _, m := range metas {
+		for _, s := range m.Compaction.Sources {
+			sources[s] = struct{}{}
+		}
+	}
+
+	if !o.synced {
+		// Results cached before the start may still contain data of blocks backfilled before the start, they
+		// get the generation of their upload time so that it doesn't change on restarts.
+		for _, m := range metas {
+			if arrival, late := o.arrival(m, m.Compaction.Sources); late {
+				o.generations.record(cacheChange{tenant: o.tenant(m), minTime: m.MinTime, maxTime: m.MaxTime, generation: arrival})
+			}
+		}
+	} else {
+		for id, m := range metas {
+			if _, ok := o.blocks[id]; ok {
+				continue
+			}
+			var added []ulid.ULID
+			for _, s := range m.Compaction.Sources {
+				if _, ok := o.sources[s]; !ok {
+					added = append(added, s)
+				}
+			}
+			if _, late := o.arrival(m, added); late {
+				level.Info(o.logger).Log("msg", "invalidating cached results of backfilled block", "block", id, "mint", m.MinTime, "maxt", m.MaxTime)
+				o.generations.bump(o.tenant(m), m.MinTime, m.MaxTime)
+			}
+		}
+		for id, m := range o.blocks {
+			if _, ok := metas[id]; ok {
+				continue
+			}
+			// Blocks whose meta.json couldn't be read are most likely still there.
+			if _, ok := partial[id]; ok {
+				continue
+			}
+			if !o.covered(m, sources) {
+				level.Info(o.logger).Log("msg", "invalidating cached results of deleted block", "block", id, "mint", m.MinTime, "maxt", m.MaxTime)
+				// Deleted blocks can't be derived from the bucket anymore, so the change is stored. All replicas
+				// observing the deletion store it under the same name.
+				if err := o.generations.bumpStored(ctx, "deleted-"+id.String(), o.tenant(m), m.MinTime, m.MaxTime); err != nil {
+					level.Warn(o.logger).Log("msg", "failed to store invalidation of deleted block", "block", id, "err", err)
+				}
+			}
+		}
+	}
+
+	blocks := maps.Clone(metas)
+	for id := range partial {
+		if m, ok := o.blocks[id]; ok {
+			blocks[id] = m
+			for _, s := range m.Compaction.Sources {
+				sources[s] = struct{}{}
+			}
+		}
+	}
+	o.synced = true
+	o.blocks = blocks
+	o.sources = sources
+	return nil
+}
+
+// arrival returns the time at which the newest of the given sources of the block was created, and whether it
+// was late for the time range of the block.
+func (o *BlockChangeObserver) arrival(m *metadata.Meta, sources []ulid.ULID) (int64, bool) {
+	var arrival int64
+	for _, s := range sources {
+		arrival = max(arrival, int64(s.Time()))
+	}
+	return arrival, arrival > m.MaxTime+o.lateBlockThreshold.Milliseconds()
+}
+
+func (o *BlockChangeObserver) covered(m *metadata.Meta, sources map[ulid.ULID]struct{}) bool {
+	for _, s := range m.Compaction.Sources {
+		if _, ok := sources[s]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// tenant returns the tenant of the block, or an empty string if it's shared by all tenants.
+func (o *BlockChangeObserver) tenant(m *metadata.Meta) string {
+	if o.tenantLabel == "" {
+		return ""
+	}
+	return m.Thanos.Labels[o.tenantLabel]
+}
+
+// NewCacheInvalidationHandler returns a handler invalidating results cached for the time range given by the start
+// and end parameters, for the tenant given by the tenant parameter or all tenants if it is not set.
+func NewCacheInvalidationHandler(generations *CacheGenerations, logger log.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		start, err := parseTimeParam(r, "start", time.Unix(0, 0))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid start: %v", err), http.StatusBadRequest)
+			return
+		}
+		end, err := parseTimeParam(r, "end", time.Now())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid end: %v", err), http.StatusBadRequest)
+			return
+		}
+		if end < start {
+			http.Error(w, errEndBeforeStart.Error(), http.StatusBadRequest)
+			return
+		}
+		tenant := r.FormValue("tenant")
+
+		if err := generations.Bump(r.Context(), tenant, start, end); err != nil {
+			level.Error(logger).Log("msg", "failed to store invalidation of cached results", "tenant", tenant, "err", err)
+			http.Error(w, fmt.Sprintf("store invalidation: %v", err), http.StatusInternalServerError)
+			return
+		}
+		level.Info(logger).Log("msg", "invalidated cached results", "tenant", tenant, "start", timestamp.Time(start), "end", timestamp.Time(end))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
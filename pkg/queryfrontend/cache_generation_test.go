@@ -0,0 +1,229 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/thanos-io/objstore"
+
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+)
+
+func TestCacheGenerations(t *testing.T) {
+	now := time.UnixMilli(1000)
+	g := NewCacheGenerations(nil)
+	g.now = func() time.Time { return now }
+
+	testutil.Equals(t, int64(0), g.Generation("a", 0, 100))
+
+	testutil.Ok(t, g.Bump(context.Background(), "a", 10, 20))
+	testutil.Equals(t, int64(1000), g.Generation("a", 0, 10))
+	testutil.Equals(t, int64(1000), g.Generation("b|a", 15, 100))
+	testutil.Equals(t, int64(0), g.Generation("a", 21, 100))
+	testutil.Equals(t, int64(0), g.Generation("b", 0, 100))
+
+	// Generations increase even if the clock doesn't.
+	testutil.Ok(t, g.Bump(context.Background(), "", 30, 40))
+	testutil.Equals(t, int64(1001), g.Generation("b", 0, 100))
+	testutil.Equals(t, int64(1001), g.Generation("a", 0, 100))
+	testutil.Equals(t, int64(1000), g.Generation("a", 0, 29))
+
+	// Changes known to have happened earlier don't affect later generations.
+	g.record(cacheChange{tenant: "a", minTime: 50, maxTime: 60, generation: 500})
+	testutil.Equals(t, int64(500), g.Generation("a", 50, 50))
+	testutil.Ok(t, g.Bump(context.Background(), "a", 50, 60))
+	testutil.Equals(t, int64(1002), g.Generation("a", 50, 50))
+}
+
+func TestCacheGenerationsMerge(t *testing.T) {
+	g := NewCacheGenerations(nil)
+	for i := range int64(maxCacheGenerationChanges) {
+		g.record(cacheChange{tenant: "a", minTime: 10 * i, maxTime: 10*i + 1, generation: i + 1})
+	}
+	testutil.Equals(t, int64(0), g.Generation("a", 5, 5))
+
+	// The oldest changes are merged, which invalidates results in between them.
+	g.record(cacheChange{tenant: "b", minTime: -10, maxTime: -10, generation: 5000})
+	testutil.Equals(t, maxCacheGenerationChanges, len(g.changes))
+	testutil.Equals(t, int64(2), g.Generation("a", 5, 5))
+	testutil.Equals(t, int64(0), g.Generation("b", 5, 5))
+	testutil.Equals(t, int64(5000), g.Generation("b", -10, -10))
+}
+
+type staticMetaFetcher struct {
+	metas   map[ulid.ULID]*metadata.Meta
+	partial map[ulid.ULID]error
+}
+
+func (f *staticMetaFetcher) Fetch(context.Context) (map[ulid.ULID]*metadata.Meta, map[ulid.ULID]error, error) {
+	metas := make(map[ulid.ULID]*metadata.Meta, len(f.metas))
+	for id, m := range f.metas {
+		metas[id] = m
+	}
+	return metas, f.partial, nil
+}
+
+func (f *staticMetaFetcher) UpdateOnChange(func([]metadata.Meta, error)) {}
+
+func testBlockMeta(created time.Time, minTime, maxTime int64, tenant string, sources ...ulid.ULID) *metadata.Meta {
+	id := ulid.MustNew(ulid.Timestamp(created), rand.Reader)
+	if len(sources) == 0 {
+		sources = []ulid.ULID{id}
+	}
+	m := &metadata.Meta{
+		BlockMeta: tsdb.BlockMeta{
+			ULID:       id,
+			MinTime:    minTime,
+			MaxTime:    maxTime,
+			Compaction: tsdb.BlockMetaCompaction{Sources: sources},
+		},
+		Thanos: metadata.Thanos{Labels: map[string]string{}},
+	}
+	if tenant != "" {
+		m.Thanos.Labels["tenant_id"] = tenant
+	}
+	return m
+}
+
+func TestCacheGenerationsBucket(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	newGenerations := func(now int64) *CacheGenerations {
+		g := NewCacheGenerations(nil)
+		g.now = func() time.Time { return time.UnixMilli(now) }
+		g.SetBucket(bkt)
+		testutil.Ok(t, g.Sync(ctx))
+		return g
+	}
+	a, b := newGenerations(1000), newGenerations(2000)
+
+	// Invalidations sent to one replica apply to others once they sync.
+	testutil.Ok(t, a.Bump(ctx, "a", 10, 20))
+	testutil.Equals(t, int64(0), b.Generation("a", 10, 20))
+	testutil.Ok(t, b.Sync(ctx))
+	testutil.Equals(t, int64(1000), b.Generation("a", 10, 20))
+
+	// Deleted blocks observed by several replicas are stored once.
+	testutil.Ok(t, b.bumpStored(ctx, "deleted-block", "", 30, 40))
+	testutil.Ok(t, a.Sync(ctx))
+	testutil.Ok(t, a.bumpStored(ctx, "deleted-block", "", 30, 40))
+	testutil.Equals(t, int64(2000), a.Generation("b", 30, 40))
+	testutil.Equals(t, 2, len(a.changes))
+
+	// Restarted replicas load all stored changes.
+	restarted := newGenerations(3000)
+	testutil.Equals(t, int64(1000), restarted.Generation("a", 10, 20))
+	testutil.Equals(t, int64(2000), restarted.Generation("b", 30, 40))
+	testutil.Ok(t, restarted.Bump(ctx, "a", 10, 20))
+	testutil.Equals(t, int64(3000), restarted.Generation("a", 10, 20))
+}
+
+func TestBlockChangeObserver(t *testing.T) {
+	ctx := context.Background()
+	start := time.Unix(0, 0).Add(30 * day)
+	now := start
+	g := NewCacheGenerations(nil)
+	g.now = func() time.Time { return now }
+
+	onTime := testBlockMeta(start.Add(-22*time.Hour), start.Add(-26*time.Hour).UnixMilli(), start.Add(-24*time.Hour).UnixMilli(), "a")
+	backfilled := testBlockMeta(start.Add(-time.Hour), 0, 2*hour, "")
+	fetcher := &staticMetaFetcher{metas: map[ulid.ULID]*metadata.Meta{onTime.ULID: onTime, backfilled.ULID: backfilled}}
+	o := NewBlockChangeObserver(log.NewNopLogger(), fetcher, g, "tenant_id", 6*time.Hour)
+
+	// Blocks backfilled before the start get the generation of their upload.
+	testutil.Ok(t, o.SyncBlocks(ctx))
+	testutil.Equals(t, start.Add(-time.Hour).UnixMilli(), g.Generation("a", 0, 0))
+	testutil.Equals(t, int64(0), g.Generation("a", onTime.MinTime, onTime.MaxTime))
+
+	now = now.Add(time.Minute)
+	uploaded := testBlockMeta(now, now.Add(-2*time.Hour).UnixMilli(), now.UnixMilli(), "a")
+	compacted := testBlockMeta(now, onTime.MinTime, uploaded.MaxTime, "a", onTime.ULID, uploaded.ULID)
+	fetcher.metas = map[ulid.ULID]*metadata.Meta{backfilled.ULID: backfilled, compacted.ULID: compacted}
+	testutil.Ok(t, o.SyncBlocks(ctx))
+	fetcher.metas[uploaded.ULID] = uploaded
+	testutil.Ok(t, o.SyncBlocks(ctx))
+	delete(fetcher.metas, uploaded.ULID)
+	testutil.Ok(t, o.SyncBlocks(ctx))
+
+	// Uploaded and compacted blocks don't change data.
+	testutil.Equals(t, int64(0), g.Generation("a", onTime.MinTime, now.UnixMilli()))
+
+	now = now.Add(time.Minute)
+	rewritten := testBlockMeta(now, onTime.MinTime, onTime.MaxTime, "b")
+	fetcher.metas[rewritten.ULID] = rewritten
+	testutil.Ok(t, o.SyncBlocks(ctx))
+	testutil.Equals(t, now.UnixMilli(), g.Generation("b", onTime.MinTime, onTime.MinTime))
+	testutil.Equals(t, int64(0), g.Generation("a", onTime.MinTime, onTime.MinTime))
+
+	// Blocks whose meta.json can't be read are not considered deleted.
+	now = now.Add(time.Minute)
+	delete(fetcher.metas, compacted.ULID)
+	fetcher.partial = map[ulid.ULID]error{compacted.ULID: context.DeadlineExceeded}
+	testutil.Ok(t, o.SyncBlocks(ctx))
+	testutil.Equals(t, int64(0), g.Generation("a", onTime.MinTime, now.UnixMilli()))
+
+	fetcher.partial = nil
+	testutil.Ok(t, o.SyncBlocks(ctx))
+	testutil.Equals(t, now.UnixMilli(), g.Generation("a", onTime.MinTime, onTime.MinTime))
+}
+
+func TestGenerateCacheKeyWithGenerations(t *testing.T) {
+	g := NewCacheGenerations(nil)
+	g.now = func() time.Time { return time.UnixMilli(1000) }
+	keys := newThanosCacheKeyGenerator(nil, g)
+
+	rangeRequest := func(query string, start int64) *ThanosQueryRangeRequest {
+		return &ThanosQueryRangeRequest{Query: query, Start: start, End: start + hour, Step: 60 * seconds, SplitInterval: time.Hour}
+	}
+	instantRequest := &ThanosQueryInstantRequest{Query: "rate(up[1h])", Time: 3 * hour}
+	seriesRequest := &ThanosSeriesRequest{Start: hour, End: 2 * hour, SplitInterval: time.Hour}
+
+	testutil.Ok(t, g.Bump(context.Background(), "a", 0, hour/2))
+	testutil.Equals(t, "fe:a:up:60000:3600000:0:2:-:0::false::false:g1000", keys.GenerateCacheKey("a", rangeRequest("up", 0)))
+	testutil.Equals(t, "fe:b:up:60000:3600000:0:2:-:0::false::false", keys.GenerateCacheKey("b", rangeRequest("up", 0)))
+	testutil.Equals(t, []string{"fe:a:up:30000:3600000:0:2:-:0::false::false:g1000"}, keys.GenerateCacheKeyAlternatives("a", rangeRequest("up", 0))[:1])
+
+	// Ranges selected by queries before the split interval are taken into account.
+	testutil.Equals(t, "fe:a:up:60000:3600000:1:2:-:0::false::false", keys.GenerateCacheKey("a", rangeRequest("up", hour)))
+	testutil.Equals(t, "fe:a:rate(up[1h]):60000:3600000:1:2:-:0::false::false:g1000", keys.GenerateCacheKey("a", rangeRequest("rate(up[1h])", hour)))
+	testutil.Equals(t, "fe:instant:a:rate(up[1h]):10800000:2:-:0::false:false::false:", keys.GenerateCacheKey("a", instantRequest))
+	testutil.Equals(t, "fe:a:[]:3600000:1", keys.GenerateCacheKey("a", seriesRequest))
+
+	testutil.Ok(t, g.Bump(context.Background(), "", 2*hour, 2*hour))
+	testutil.Equals(t, "fe:instant:a:rate(up[1h]):10800000:2:-:0::false:false::false::g1001", keys.GenerateCacheKey("a", instantRequest))
+	testutil.Equals(t, "fe:a:[]:3600000:1:g1001", keys.GenerateCacheKey("a", seriesRequest))
+}
+
+func TestCacheInvalidationHandler(t *testing.T) {
+	g := NewCacheGenerations(nil)
+	g.now = func() time.Time { return time.UnixMilli(1000) }
+	h := NewCacheInvalidationHandler(g, log.NewNopLogger())
+
+	for _, tcase := range []struct {
+		method, query string
+		expected      int
+	}{
+		{method: http.MethodGet, query: "start=0&end=10", expected: http.StatusMethodNotAllowed},
+		{method: http.MethodPost, query: "start=foo", expected: http.StatusBadRequest},
+		{method: http.MethodPost, query: "start=10&end=0", expected: http.StatusBadRequest},
+		{method: http.MethodPost, query: "start=100&end=200&tenant=a", expected: http.StatusNoContent},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tcase.method, "/api/v1/cache/invalidate?"+tcase.query, nil))
+		testutil.Equals(t, tcase.expected, rec.Code)
+	}
+	testutil.Equals(t, int64(1000), g.Generation("a", 150*1000, 150*1000))
+	testutil.Equals(t, int64(0), g.Generation("b", 150*1000, 150*1000))
+	testutil.Equals(t, int64(0), g.Generation("a", 0, 99*1000))
+}
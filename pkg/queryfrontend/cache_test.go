@@ -15,7 +15,7 @@ import (
 )
 
 func TestGenerateCacheKey(t *testing.T) {
-	splitter := newThanosCacheKeyGenerator()
+	splitter := newThanosCacheKeyGenerator(nil, nil)
 
 	for _, tc := range []struct {
 		name     string
@@ -181,6 +181,38 @@ func TestGenerateCacheKey(t *testing.T) {
 			},
 			expected: `fe::up:[[foo="bar"] [baz="qux"]]:3600000:0`,
 		},
+		{
+			name: "instant query",
+			req: &ThanosQueryInstantRequest{
+				Query: "up",
+				Time:  hour,
+				Dedup: true,
+			},
+			expected: "fe:instant::up:3600000:2:-:0::true:false::false:",
+		},
+		{
+			name: "instant query, 1h downsampling resolution with partial response and replica labels",
+			req: &ThanosQueryInstantRequest{
+				Query:               "up",
+				Time:                hour,
+				MaxSourceResolution: hour,
+				Dedup:               true,
+				PartialResponse:     true,
+				ReplicaLabels:       []string{"replica", "prometheus"},
+			},
+			expected: "fe:instant::up:3600000:0:-:0::true:true:prometheus,replica:false:",
+		},
+		{
+			name: "instant query, auto downsampling with stats",
+			req: &ThanosQueryInstantRequest{
+				Query:            "up",
+				Time:             hour,
+				AutoDownsampling: true,
+				Dedup:            true,
+				Stats:            "all",
+			},
+			expected: "fe:instant::up:3600000:-1:-:0::true:false::false:all",
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			key := splitter.GenerateCacheKey("", tc.req)
@@ -189,8 +221,25 @@ func TestGenerateCacheKey(t *testing.T) {
 	}
 }
 
+func TestGenerateCacheKey_CustomResolutions(t *testing.T) {
+	splitter := newThanosCacheKeyGenerator([]int64{15 * 60 * seconds}, nil)
+	testutil.Equals(t, []int64{15 * 60 * seconds, 0}, splitter.resolutions)
+
+	req := &ThanosQueryRangeRequest{
+		Query:               "up",
+		Start:               0,
+		Step:                60 * seconds,
+		MaxSourceResolution: 60 * 60 * seconds,
+		SplitInterval:       time.Hour,
+	}
+	testutil.Equals(t, "fe::up:60000:3600000:0:0:-:0::false::false", splitter.GenerateCacheKey("", req))
+
+	req.MaxSourceResolution = 5 * 60 * seconds
+	testutil.Equals(t, "fe::up:60000:3600000:0:1:-:0::false::false", splitter.GenerateCacheKey("", req))
+}
+
 func TestGenerateCacheKey_UnsupportedRequest(t *testing.T) {
-	splitter := newThanosCacheKeyGenerator()
+	splitter := newThanosCacheKeyGenerator(nil, nil)
 
 	req := &queryrange.PrometheusRequest{
 		Query: "up",
@@ -210,7 +259,7 @@ func TestGenerateCacheKey_UnsupportedRequest(t *testing.T) {
 }
 
 func TestGenerateCacheKeyAlternatives(t *testing.T) {
-	splitter := newThanosCacheKeyGenerator()
+	splitter := newThanosCacheKeyGenerator(nil, nil)
 
 	req := &ThanosQueryRangeRequest{
 		Query:         "up",
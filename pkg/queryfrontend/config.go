@@ -200,8 +200,11 @@ type DownstreamTripperConfig struct {
 type Config struct {
 	QueryRangeConfig
 	LabelsConfig
+	RemoteReadConfig
 	DownstreamTripperConfig
 	QueryInstantConfig
+	QueueConfig
+	CostEstimationConfig
 
 	CortexHandlerConfig    *transport.HandlerConfig
 	CompressResponses      bool
@@ -215,11 +218,26 @@ type Config struct {
 	TenantCertField        string
 	EnableXFunctions       bool
 	EnableFeatures         []string
+	QueryRules             *QueryRules
+	// TenantLimits overrides the query range limits per tenant.
+	TenantLimits cortexvalidation.TenantLimits
+	// CacheGenerations, if set, invalidate cached results of time ranges whose data changed.
+	CacheGenerations *CacheGenerations
 }
 
-// QueryInstantConfig holds the config for query range tripperware.
+// QueryInstantConfig holds the config for query instant tripperware.
 type QueryInstantConfig struct {
-	MaxRetries int
+	ResultsCacheConfig *queryrange.ResultsCacheConfig
+	CachePathOrContent extflag.PathOrContent
+
+	// CacheTimeRoundingStep rounds down the evaluation time of cached instant queries
+	// to a multiple of it, 0 disables rounding.
+	CacheTimeRoundingStep time.Duration
+	// SplitInterval splits instant queries of range vector functions over longer ranges
+	// into queries of sub-windows of at most this length, 0 disables splitting.
+	SplitInterval time.Duration
+	MaxRetries    int
+	Limits        *cortexvalidation.Limits
 }
 
 // QueryRangeConfig holds the config for query range tripperware.
@@ -231,14 +249,19 @@ type QueryRangeConfig struct {
 	ResultsCacheConfig *queryrange.ResultsCacheConfig
 	CachePathOrContent extflag.PathOrContent
 
-	AlignRangeWithStep     bool
-	RequestDownsampled     bool
-	SplitQueriesByInterval time.Duration
-	MinQuerySplitInterval  time.Duration
-	MaxQuerySplitInterval  time.Duration
-	HorizontalShards       int64
-	MaxRetries             int
-	Limits                 *cortexvalidation.Limits
+	AlignRangeWithStep bool
+	RequestDownsampled bool
+	// DownsamplingResolutions are the resolutions requested by RequestDownsampled, in increasing order.
+	DownsamplingResolutions []int64
+	SplitQueriesByInterval  time.Duration
+	MinQuerySplitInterval   time.Duration
+	MaxQuerySplitInterval   time.Duration
+	HorizontalShards        int64
+	MaxRetries              int
+	// IncrementalMerge merges responses of split queries as they complete in time order
+	// and writes the merged response to the client series by series once all are merged.
+	IncrementalMerge bool
+	Limits           *cortexvalidation.Limits
 }
 
 // LabelsConfig holds the config for labels tripperware.
@@ -257,6 +280,12 @@ type LabelsConfig struct {
 	Limits *cortexvalidation.Limits
 }
 
+// RemoteReadConfig holds the config for remote read tripperware.
+type RemoteReadConfig struct {
+	SplitQueriesByInterval time.Duration
+	MaxRetries             int
+}
+
 // Validate a fully initialized config.
 func (cfg *Config) Validate() error {
 	if cfg.QueryRangeConfig.ResultsCacheConfig != nil {
@@ -293,6 +322,26 @@ func (cfg *Config) Validate() error {
 		}
 	}
 
+	if cfg.QueryInstantConfig.ResultsCacheConfig != nil {
+		if cfg.QueryInstantConfig.CacheTimeRoundingStep < 0 {
+			return errors.New("instant query cache time rounding step cannot be negative")
+		}
+		if err := cfg.QueryInstantConfig.ResultsCacheConfig.Validate(querier.Config{}); err != nil {
+			return errors.Wrap(err, "invalid ResultsCache config for query instant tripperware")
+		}
+	}
+
+	if cfg.RemoteReadConfig.SplitQueriesByInterval < 0 {
+		return errors.New("remote read split interval cannot be negative")
+	}
+
+	if cfg.MaxConcurrentRequests < 0 {
+		return errors.New("max concurrent downstream requests cannot be negative")
+	}
+	if cfg.MaxConcurrentRequests > 0 && cfg.MaxOutstandingPerTenant <= 0 {
+		return errors.New("max outstanding requests per tenant should be greater than 0 when queueing is enabled")
+	}
+
 	if cfg.DefaultTimeRange == 0 {
 		return errors.New("labels.default-time-range cannot be set to 0")
 	}
@@ -0,0 +1,420 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/user"
+
+	"github.com/thanos-io/thanos/internal/cortex/querier/queryrange"
+	"github.com/thanos-io/thanos/internal/cortex/tenant"
+	"github.com/thanos-io/thanos/internal/cortex/util/validation"
+	"github.com/thanos-io/thanos/pkg/extpromql"
+)
+
+const (
+	errEstimatedSeriesLimit  = "the query is estimated to select %d series, exceeding the limit of %d series; add label matchers to narrow down the selected series"
+	errEstimatedSamplesLimit = "the query is estimated to fetch %d samples, exceeding the limit of %d samples; reduce the time range of the query or of its range selectors, or query downsampled data"
+)
+
+// CostLimits extends queryrange.Limits with limits on the estimated cost of queries.
+type CostLimits interface {
+	queryrange.Limits
+
+	// MaxEstimatedSeriesPerQuery returns the limit of series a query is estimated to select, 0 disables the limit.
+	MaxEstimatedSeriesPerQuery(string) int
+	// MaxEstimatedSamplesPerQuery returns the limit of samples a query is estimated to fetch, 0 disables the limit.
+	MaxEstimatedSamplesPerQuery(string) int
+}
+
+// CostEstimationConfig holds the config for estimating the cost of queries before they are executed.
+type CostEstimationConfig struct {
+	// CardinalityStats provides the statistics used to estimate the number of series selected by queries,
+	// nil disables cost estimation.
+	CardinalityStats *CardinalityStats
+	// ScrapeInterval is the assumed interval between raw samples of a series.
+	ScrapeInterval time.Duration
+	// DownsampleExpensiveQueries makes queries estimated to fetch too many samples use downsampled data
+	// if that brings them under the limit, instead of rejecting them.
+	DownsampleExpensiveQueries bool
+}
+
+// tsdbStatus is the subset of the response of the TSDB status API used to estimate cardinality.
+type tsdbStatus struct {
+	HeadStats struct {
+		NumSeries uint64 `json:"numSeries"`
+	} `json:"headStats"`
+	SeriesCountByMetricName     []tsdbStat `json:"seriesCountByMetricName"`
+	SeriesCountByLabelValuePair []tsdbStat `json:"seriesCountByLabelValuePair"`
+}
+
+type tsdbStat struct {
+	Name  string `json:"name"`
+	Value uint64 `json:"value"`
+}
+
+// cardinality holds the number of series of a tenant, in total and per label value pair.
+// Only the label value pairs with the highest number of series are known.
+type cardinality struct {
+	numSeries uint64
+	// seriesByLabel holds the number of series per label value pair, including metric names as __name__ pairs.
+	seriesByLabel map[string]map[string]uint64
+	// seriesUpperBound is the number of series of label value pairs which are not known, 0 if all of them are known.
+	seriesUpperBound map[string]uint64
+}
+
+func newCardinality(status tsdbStatus, limit int) *cardinality {
+	c := &cardinality{
+		numSeries:        status.HeadStats.NumSeries,
+		seriesByLabel:    map[string]map[string]uint64{},
+		seriesUpperBound: map[string]uint64{},
+	}
+	add := func(name, value string, series uint64) {
+		if c.seriesByLabel[name] == nil {
+			c.seriesByLabel[name] = map[string]uint64{}
+		}
+		c.seriesByLabel[name][value] = series
+	}
+	for _, s := range status.SeriesCountByMetricName {
+		add(labels.MetricName, s.Name, s.Value)
+	}
+	for _, s := range status.SeriesCountByLabelValuePair {
+		if name, value, ok := strings.Cut(s.Name, "="); ok {
+			add(name, value, s.Value)
+		}
+	}
+
+	// Statistics are sorted by decreasing number of series, so truncated lists give an
+	// upper bound of the number of series of pairs which are not listed.
+	if len(status.SeriesCountByMetricName) >= limit {
+		c.seriesUpperBound[labels.MetricName] = status.SeriesCountByMetricName[len(status.SeriesCountByMetricName)-1].Value
+	}
+	if len(status.SeriesCountByLabelValuePair) >= limit {
+		c.seriesUpperBound[""] = status.SeriesCountByLabelValuePair[len(status.SeriesCountByLabelValuePair)-1].Value
+	}
+	return c
+}
+
+// seriesWithLabel returns the estimated number of series with the label value pair.
+func (c *cardinality) seriesWithLabel(name, value string) uint64 {
+	if series, ok := c.seriesByLabel[name][value]; ok {
+		return series
+	}
+	if name == labels.MetricName {
+		return c.seriesUpperBound[labels.MetricName]
+	}
+	return c.seriesUpperBound[""]
+}
+
+// estimateSeries returns the estimated number of series selected by the matchers. Only equality
+// matchers and regex matchers of a set of values narrow down the estimation.
+func (c *cardinality) estimateSeries(matchers []*labels.Matcher) uint64 {
+	estimated := c.numSeries
+	for _, m := range matchers {
+		var values []string
+		switch m.Type {
+		case labels.MatchEqual:
+			if m.Value == "" {
+				continue
+			}
+			values = []string{m.Value}
+		case labels.MatchRegexp:
+			values = m.SetMatches()
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		var series uint64
+		for _, v := range values {
+			series += c.seriesWithLabel(m.Name, v)
+		}
+		estimated = min(estimated, series)
+	}
+	return estimated
+}
+
+type cachedCardinality struct {
+	mtx       sync.Mutex
+	c         *cardinality
+	err       error
+	fetchedAt time.Time
+}
+
+// CardinalityStats provides the cardinality of the series of tenants, fetched from the TSDB status API
+// of downstream queriers and cached for the refresh interval.
+type CardinalityStats struct {
+	next            http.RoundTripper
+	limit           int
+	refreshInterval time.Duration
+
+	mtx     sync.Mutex
+	tenants map[string]*cachedCardinality
+
+	fetchFailures prometheus.Counter
+}
+
+// NewCardinalityStats creates CardinalityStats fetching statistics of the limit label value pairs
+// with the highest number of series from downstream queriers through next.
+func NewCardinalityStats(next http.RoundTripper, limit int, refreshInterval time.Duration, reg prometheus.Registerer) *CardinalityStats {
+	return &CardinalityStats{
+		next:            next,
+		limit:           limit,
+		refreshInterval: refreshInterval,
+		tenants:         map[string]*cachedCardinality{},
+		fetchFailures: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_query_frontend_cardinality_stats_fetch_failures_total",
+			Help: "Total number of failures fetching cardinality statistics from downstream queriers.",
+		}),
+	}
+}
+
+// get returns the cardinality of the tenant, fetching it with the given request headers if it is older than
+// the refresh interval. Stale statistics are returned if they can't be refreshed.
+func (s *CardinalityStats) get(ctx context.Context, tenantID string, headers []*RequestHeader) (*cardinality, error) {
+	s.mtx.Lock()
+	cached, ok := s.tenants[tenantID]
+	if !ok {
+		cached = &cachedCardinality{}
+		s.tenants[tenantID] = cached
+	}
+	s.mtx.Unlock()
+
+	// Concurrent requests of the tenant wait for a single fetch.
+	cached.mtx.Lock()
+	defer cached.mtx.Unlock()
+
+	if !cached.fetchedAt.IsZero() && time.Since(cached.fetchedAt) < s.refreshInterval {
+		return cached.c, cached.err
+	}
+	// Failed fetches are not retried before the refresh interval either, to not overload queriers.
+	c, err := s.fetch(ctx, headers)
+	if ctx.Err() != nil {
+		return nil, err
+	}
+	cached.fetchedAt = time.Now()
+	if err != nil {
+		s.fetchFailures.Inc()
+		if cached.c == nil {
+			cached.err = err
+		}
+		return cached.c, cached.err
+	}
+	cached.c, cached.err = c, nil
+	return c, nil
+}
+
+func (s *CardinalityStats) fetch(ctx context.Context, headers []*RequestHeader) (*cardinality, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/api/v1/status/tsdb?limit="+strconv.Itoa(s.limit), nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range headers {
+		for _, v := range h.Values {
+			req.Header.Add(h.Name, v)
+		}
+	}
+	if err := user.InjectOrgIDIntoHTTPRequest(ctx, req); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.next.RoundTrip(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "get TSDB status")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("get TSDB status: unexpected status code %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data tsdbStatus `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, errors.Wrap(err, "decode TSDB status")
+	}
+	return newCardinality(body.Data, s.limit), nil
+}
+
+// queryCost is the estimated cost of a query.
+type queryCost struct {
+	series  uint64
+	samples uint64
+}
+
+// costSelector is a series selector of a query with the time window it selects
+// in addition to the time range of the query.
+type costSelector struct {
+	matchers []*labels.Matcher
+	window   int64
+}
+
+// costSelectors returns the selectors of the expression. Like the query analyzer, it walks the AST,
+// adding up the ranges of matrix selectors and subqueries enclosing each selector.
+func costSelectors(expr parser.Expr) []costSelector {
+	var selectors []costSelector
+	parser.Inspect(expr, func(node parser.Node, path []parser.Node) error {
+		vs, ok := node.(*parser.VectorSelector)
+		if !ok {
+			return nil
+		}
+		s := costSelector{matchers: vs.LabelMatchers}
+		for _, p := range path {
+			switch n := p.(type) {
+			case *parser.MatrixSelector:
+				s.window += n.Range.Milliseconds()
+			case *parser.SubqueryExpr:
+				s.window += n.Range.Milliseconds()
+			}
+		}
+		selectors = append(selectors, s)
+		return nil
+	})
+	return selectors
+}
+
+// estimateCost estimates the number of series selected and the number of samples fetched by the selectors
+// over the time range, assuming one sample per interval and series.
+func estimateCost(c *cardinality, selectors []costSelector, timeRange, interval int64) queryCost {
+	var cost queryCost
+	for _, s := range selectors {
+		series := c.estimateSeries(s.matchers)
+		cost.series += series
+		cost.samples += series * uint64(max(1, (timeRange+s.window)/interval))
+	}
+	return cost
+}
+
+type costEstimation struct {
+	logger      log.Logger
+	next        queryrange.Handler
+	limits      CostLimits
+	config      CostEstimationConfig
+	resolutions []int64
+
+	rejected    *prometheus.CounterVec
+	downsampled prometheus.Counter
+}
+
+// CostEstimationMiddleware creates a Middleware estimating the cost of queries before they are executed.
+// Queries estimated to select more series or fetch more samples than the limits of the tenant are rejected,
+// or use downsampled data of the resolutions if it brings them under the limits and it is enabled.
+// Queries are executed as usual if their cost can't be estimated.
+func CostEstimationMiddleware(limits CostLimits, config CostEstimationConfig, resolutions []int64, logger log.Logger, reg prometheus.Registerer) queryrange.Middleware {
+	if len(resolutions) == 0 {
+		resolutions = defaultResolutions
+	}
+	rejected := promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_query_frontend_expensive_queries_rejected_total",
+		Help: "Total number of queries rejected because their estimated cost exceeds a limit.",
+	}, []string{"limit"})
+	downsampled := promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "thanos_query_frontend_expensive_queries_downsampled_total",
+		Help: "Total number of queries using downsampled data because their estimated cost exceeds a limit.",
+	})
+	return queryrange.MiddlewareFunc(func(next queryrange.Handler) queryrange.Handler {
+		return costEstimation{
+			logger:      logger,
+			next:        next,
+			limits:      limits,
+			config:      config,
+			resolutions: resolutions,
+			rejected:    rejected,
+			downsampled: downsampled,
+		}
+	})
+}
+
+func (c costEstimation) Do(ctx context.Context, r queryrange.Request) (queryrange.Response, error) {
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, httpgrpc.Errorf(http.StatusBadRequest, "%s", err.Error())
+	}
+	maxSeries := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, c.limits.MaxEstimatedSeriesPerQuery)
+	maxSamples := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, c.limits.MaxEstimatedSamplesPerQuery)
+	if maxSeries == 0 && maxSamples == 0 {
+		return c.next.Do(ctx, r)
+	}
+
+	var (
+		timeRange, resolution int64
+		headers               []*RequestHeader
+	)
+	switch tr := r.(type) {
+	case *ThanosQueryRangeRequest:
+		timeRange, resolution, headers = tr.End-tr.Start, tr.MaxSourceResolution, tr.Headers
+	case *ThanosQueryInstantRequest:
+		resolution, headers = tr.MaxSourceResolution, tr.Headers
+	default:
+		return c.next.Do(ctx, r)
+	}
+	// Queries which can't be parsed fail downstream.
+	expr, err := extpromql.ParseExpr(r.GetQuery())
+	if err != nil {
+		return c.next.Do(ctx, r)
+	}
+
+	card, err := c.config.CardinalityStats.get(ctx, tenant.JoinTenantIDs(tenantIDs), headers)
+	if err != nil {
+		level.Warn(c.logger).Log("msg", "failed to get cardinality statistics, not estimating query cost", "err", err)
+		return c.next.Do(ctx, r)
+	}
+
+	selectors := costSelectors(expr)
+	cost := estimateCost(card, selectors, timeRange, c.interval(resolution))
+	if maxSeries > 0 && cost.series > uint64(maxSeries) {
+		c.rejected.WithLabelValues("series").Inc()
+		return nil, httpgrpc.Errorf(http.StatusBadRequest, errEstimatedSeriesLimit, cost.series, maxSeries)
+	}
+	if maxSamples == 0 || cost.samples <= uint64(maxSamples) {
+		return c.next.Do(ctx, r)
+	}
+
+	if c.config.DownsampleExpensiveQueries {
+		for _, res := range c.resolutions {
+			if res <= resolution || estimateCost(card, selectors, timeRange, c.interval(res)).samples > uint64(maxSamples) {
+				continue
+			}
+			level.Debug(c.logger).Log("msg", "query estimated to fetch too many samples, using downsampled data", "query", r.GetQuery(), "samples", cost.samples, "resolution", res)
+			c.downsampled.Inc()
+			return c.next.Do(ctx, withMaxSourceResolution(r, res))
+		}
+	}
+	c.rejected.WithLabelValues("samples").Inc()
+	return nil, httpgrpc.Errorf(http.StatusBadRequest, errEstimatedSamplesLimit, cost.samples, maxSamples)
+}
+
+// interval returns the assumed interval between samples of series at the resolution.
+func (c costEstimation) interval(resolution int64) int64 {
+	return max(c.config.ScrapeInterval.Milliseconds(), resolution, 1)
+}
+
+func withMaxSourceResolution(r queryrange.Request, resolution int64) queryrange.Request {
+	switch tr := r.(type) {
+	case *ThanosQueryRangeRequest:
+		downsampled := *tr
+		downsampled.MaxSourceResolution, downsampled.AutoDownsampling = resolution, false
+		return &downsampled
+	case *ThanosQueryInstantRequest:
+		downsampled := *tr
+		downsampled.MaxSourceResolution, downsampled.AutoDownsampling = resolution, false
+		return &downsampled
+	}
+	return r
+}
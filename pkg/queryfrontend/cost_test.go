@@ -0,0 +1,227 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/user"
+
+	"github.com/thanos-io/thanos/internal/cortex/querier/queryrange"
+	"github.com/thanos-io/thanos/internal/cortex/util/validation"
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
+	"github.com/thanos-io/thanos/pkg/extpromql"
+)
+
+const testTSDBStatus = `{
+	"status": "success",
+	"data": {
+		"headStats": {"numSeries": 10000},
+		"seriesCountByMetricName": [
+			{"name": "http_requests_total", "value": 5000},
+			{"name": "up", "value": 100}
+		],
+		"seriesCountByLabelValuePair": [
+			{"name": "job=api", "value": 4000},
+			{"name": "job=db", "value": 500},
+			{"name": "code=500", "value": 50}
+		]
+	}
+}`
+
+func TestCardinalityEstimateSeries(t *testing.T) {
+	statsWithLimit := func(limit int) *cardinality {
+		stats := NewCardinalityStats(tsdbStatusRoundTripper(new(int), testTSDBStatus), limit, time.Minute, nil)
+		c, err := stats.get(user.InjectOrgID(context.Background(), "tenant"), "tenant", nil)
+		testutil.Ok(t, err)
+		return c
+	}
+
+	for _, tcase := range []struct {
+		name     string
+		selector string
+		limit    int
+		expected uint64
+	}{
+		{name: "all series", selector: `{__name__=~".+"}`, expected: 10000},
+		{name: "metric name", selector: `http_requests_total`, expected: 5000},
+		{name: "narrowed down by label", selector: `http_requests_total{job="db"}`, expected: 500},
+		{name: "narrowed down by smallest label", selector: `http_requests_total{job="api", code="500"}`, expected: 50},
+		{name: "set of values", selector: `{job=~"api|db"}`, expected: 4500},
+		{name: "not equal matchers are ignored", selector: `http_requests_total{job!="api"}`, expected: 5000},
+		{name: "regex matchers are ignored", selector: `up{job=~"a.*"}`, expected: 100},
+		{name: "unknown metric with complete statistics", selector: `unknown`, limit: 10, expected: 0},
+		{name: "unknown metric with truncated statistics", selector: `unknown`, limit: 2, expected: 100},
+		{name: "unknown label with truncated statistics", selector: `http_requests_total{job="unknown"}`, limit: 2, expected: 50},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			limit := tcase.limit
+			if limit == 0 {
+				limit = 10
+			}
+			matchers, err := extpromql.ParseMetricSelector(tcase.selector)
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.expected, statsWithLimit(limit).estimateSeries(matchers))
+		})
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	c := &cardinality{
+		numSeries: 1000,
+		seriesByLabel: map[string]map[string]uint64{
+			labels.MetricName: {"up": 10, "http_requests_total": 100},
+		},
+		seriesUpperBound: map[string]uint64{},
+	}
+	for _, tcase := range []struct {
+		name      string
+		query     string
+		timeRange time.Duration
+		expected  queryCost
+	}{
+		{name: "instant vector selector", query: `up`, expected: queryCost{series: 10, samples: 10}},
+		{name: "instant range selector", query: `rate(http_requests_total[1h])`, expected: queryCost{series: 100, samples: 100 * 120}},
+		{name: "range query", query: `up`, timeRange: time.Hour, expected: queryCost{series: 10, samples: 10 * 120}},
+		{name: "range query with range selector", query: `rate(up[1h])`, timeRange: time.Hour, expected: queryCost{series: 10, samples: 10 * 240}},
+		{name: "subquery", query: `max_over_time(rate(up[1h])[1d:5m])`, expected: queryCost{series: 10, samples: 10 * 25 * 120}},
+		{name: "multiple selectors", query: `up + on() group_left http_requests_total`, expected: queryCost{series: 110, samples: 110}},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			expr, err := extpromql.ParseExpr(tcase.query)
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.expected, estimateCost(c, costSelectors(expr), tcase.timeRange.Milliseconds(), (30*time.Second).Milliseconds()))
+		})
+	}
+}
+
+func tsdbStatusRoundTripper(fetches *int, body string) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		*fetches++
+		rec := httptest.NewRecorder()
+		if r.URL.Path != "/api/v1/status/tsdb" || body == "" {
+			rec.WriteHeader(http.StatusInternalServerError)
+			return rec.Result(), nil
+		}
+		_, _ = rec.WriteString(body)
+		return rec.Result(), nil
+	})
+}
+
+func TestCostEstimationMiddleware(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "tenant")
+
+	newHandler := func(t *testing.T, limits validation.Limits, tenantLimits validation.TenantLimits, downsampleExpensive bool, statsBody string) (queryrange.Handler, *recordingHandler, *int) {
+		t.Helper()
+
+		overrides, err := validation.NewOverrides(limits, tenantLimits)
+		testutil.Ok(t, err)
+		fetches := new(int)
+		reg := prometheus.NewRegistry()
+		next := &recordingHandler{reqs: make(chan queryrange.Request, 1)}
+		h := CostEstimationMiddleware(overrides, CostEstimationConfig{
+			CardinalityStats:           NewCardinalityStats(tsdbStatusRoundTripper(fetches, statsBody), 10, time.Minute, reg),
+			ScrapeInterval:             30 * time.Second,
+			DownsampleExpensiveQueries: downsampleExpensive,
+		}, nil, log.NewNopLogger(), reg).Wrap(next)
+		return h, next, fetches
+	}
+	expectBadRequest := func(t *testing.T, err error) {
+		t.Helper()
+
+		resp, ok := httpgrpc.HTTPResponseFromError(err)
+		testutil.Assert(t, ok, "expected HTTP error, got %v", err)
+		testutil.Equals(t, int32(http.StatusBadRequest), resp.Code)
+	}
+
+	t.Run("series limit", func(t *testing.T) {
+		h, next, fetches := newHandler(t, validation.Limits{MaxEstimatedSeriesPerQuery: 1000}, nil, false, testTSDBStatus)
+
+		_, err := h.Do(ctx, &ThanosQueryInstantRequest{Query: `sum(http_requests_total)`})
+		expectBadRequest(t, err)
+
+		_, err = h.Do(ctx, &ThanosQueryInstantRequest{Query: `sum(http_requests_total{job="db"})`})
+		testutil.Ok(t, err)
+		<-next.reqs
+
+		// Statistics are cached.
+		testutil.Equals(t, 1, *fetches)
+	})
+	t.Run("samples limit", func(t *testing.T) {
+		h, next, _ := newHandler(t, validation.Limits{MaxEstimatedSamplesPerQuery: 100 * 120 * 24}, nil, false, testTSDBStatus)
+
+		_, err := h.Do(ctx, &ThanosQueryRangeRequest{Query: `up`, Start: 0, End: day.Milliseconds(), Step: 60 * seconds})
+		testutil.Ok(t, err)
+		<-next.reqs
+
+		_, err = h.Do(ctx, &ThanosQueryRangeRequest{Query: `up`, Start: 0, End: 2 * day.Milliseconds(), Step: 60 * seconds})
+		expectBadRequest(t, err)
+
+		// Queries of downsampled data fetch less samples.
+		_, err = h.Do(ctx, &ThanosQueryRangeRequest{Query: `up`, Start: 0, End: 2 * day.Milliseconds(), Step: 60 * seconds, MaxSourceResolution: downsample.ResLevel1})
+		testutil.Ok(t, err)
+		<-next.reqs
+	})
+	t.Run("downsample expensive queries", func(t *testing.T) {
+		h, next, _ := newHandler(t, validation.Limits{MaxEstimatedSamplesPerQuery: 100 * 12 * 24 * 30}, nil, true, testTSDBStatus)
+
+		_, err := h.Do(ctx, &ThanosQueryRangeRequest{Query: `up`, Start: 0, End: 7 * day.Milliseconds(), Step: 60 * seconds, AutoDownsampling: true, MaxSourceResolution: 12 * seconds})
+		testutil.Ok(t, err)
+		req := (<-next.reqs).(*ThanosQueryRangeRequest)
+		testutil.Equals(t, downsample.ResLevel1, req.MaxSourceResolution)
+		testutil.Equals(t, false, req.AutoDownsampling)
+
+		_, err = h.Do(ctx, &ThanosQueryRangeRequest{Query: `up`, Start: 0, End: 300 * day.Milliseconds(), Step: 60 * 60 * seconds})
+		testutil.Ok(t, err)
+		testutil.Equals(t, downsample.ResLevel2, (<-next.reqs).(*ThanosQueryRangeRequest).MaxSourceResolution)
+
+		// Downsampled data doesn't help queries of long range selectors.
+		_, err = h.Do(ctx, &ThanosQueryInstantRequest{Query: `count_over_time({__name__=~".+"}[10y])`})
+		expectBadRequest(t, err)
+	})
+	t.Run("tenant limits", func(t *testing.T) {
+		tenantLimits, err := NewTenantLimits([]byte(`
+overrides:
+  other:
+    max_estimated_series_per_query: 10
+`), validation.Limits{MaxEstimatedSeriesPerQuery: 1000})
+		testutil.Ok(t, err)
+		h, next, _ := newHandler(t, validation.Limits{MaxEstimatedSeriesPerQuery: 1000}, tenantLimits, false, testTSDBStatus)
+
+		_, err = h.Do(ctx, &ThanosQueryInstantRequest{Query: `up`})
+		testutil.Ok(t, err)
+		<-next.reqs
+
+		_, err = h.Do(user.InjectOrgID(context.Background(), "other"), &ThanosQueryInstantRequest{Query: `up`})
+		expectBadRequest(t, err)
+	})
+	t.Run("statistics not available", func(t *testing.T) {
+		h, next, fetches := newHandler(t, validation.Limits{MaxEstimatedSeriesPerQuery: 1}, nil, false, "")
+
+		for range 2 {
+			_, err := h.Do(ctx, &ThanosQueryInstantRequest{Query: `up`})
+			testutil.Ok(t, err)
+			<-next.reqs
+		}
+		// Failed fetches are not retried before the refresh interval.
+		testutil.Equals(t, 1, *fetches)
+	})
+	t.Run("no limits", func(t *testing.T) {
+		h, next, fetches := newHandler(t, validation.Limits{}, nil, false, testTSDBStatus)
+
+		_, err := h.Do(ctx, &ThanosQueryInstantRequest{Query: `{__name__=~".+"}`})
+		testutil.Ok(t, err)
+		<-next.reqs
+		testutil.Equals(t, 0, *fetches)
+	})
+}
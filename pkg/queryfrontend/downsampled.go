@@ -16,11 +16,16 @@ import (
 
 // DownsampledMiddleware creates a new Middleware that requests downsampled data
 // should response to original request with auto max_source_resolution not contain data points.
-func DownsampledMiddleware(merger queryrange.Merger, registerer prometheus.Registerer) queryrange.Middleware {
+// Resolutions are requested in the given order, the default 5m and 1h ones if empty.
+func DownsampledMiddleware(merger queryrange.Merger, resolutions []int64, registerer prometheus.Registerer) queryrange.Middleware {
+	if len(resolutions) == 0 {
+		resolutions = defaultResolutions
+	}
 	return queryrange.MiddlewareFunc(func(next queryrange.Handler) queryrange.Handler {
 		return downsampled{
-			next:   next,
-			merger: merger,
+			next:        next,
+			merger:      merger,
+			resolutions: resolutions,
 			additionalQueriesCount: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
 				Namespace: "thanos",
 				Name:      "frontend_downsampled_extra_queries_total",
@@ -31,14 +36,15 @@ func DownsampledMiddleware(merger queryrange.Merger, registerer prometheus.Regis
 }
 
 type downsampled struct {
-	next   queryrange.Handler
-	merger queryrange.Merger
+	next        queryrange.Handler
+	merger      queryrange.Merger
+	resolutions []int64
 
 	// Metrics.
 	additionalQueriesCount prometheus.Counter
 }
 
-var resolutions = []int64{downsample.ResLevel1, downsample.ResLevel2}
+var defaultResolutions = []int64{downsample.ResLevel1, downsample.ResLevel2}
 
 func (d downsampled) Do(ctx context.Context, req queryrange.Request) (queryrange.Response, error) {
 	tqrr, ok := req.(*ThanosQueryRangeRequest)
@@ -54,7 +60,7 @@ func (d downsampled) Do(ctx context.Context, req queryrange.Request) (queryrange
 	)
 
 forLoop:
-	for i < len(resolutions) {
+	for i < len(d.resolutions) {
 		if i > 0 {
 			d.additionalQueriesCount.Inc()
 		}
@@ -65,10 +71,10 @@ forLoop:
 		}
 		resps = append(resps, resp)
 		// Set MaxSourceResolution for next request, if any.
-		for i < len(resolutions) {
-			if tqrr.MaxSourceResolution < resolutions[i] {
+		for i < len(d.resolutions) {
+			if tqrr.MaxSourceResolution < d.resolutions[i] {
 				tqrr.AutoDownsampling = false
-				tqrr.MaxSourceResolution = resolutions[i]
+				tqrr.MaxSourceResolution = d.resolutions[i]
 				break
 			}
 			i++
@@ -0,0 +1,259 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/prometheus/common/model"
+	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/thanos-io/thanos/internal/cortex/cortexpb"
+	"github.com/thanos-io/thanos/internal/cortex/querier/queryrange"
+	"github.com/thanos-io/thanos/internal/cortex/tenant"
+	"github.com/thanos-io/thanos/internal/cortex/util/validation"
+)
+
+// streamedResultKey is the key of the series of a response, which are written one by one in streamed responses.
+var streamedResultKey = []byte(`"result":[`)
+
+// incrementalMatrixMerge merges the responses of range queries split by time into a single response. Unlike
+// queryrange.PrometheusCodec.MergeResponse, it merges responses one at a time, in time order, so that every response
+// can be released as soon as it is merged. Samples of a series are grouped in responses, so no series is complete
+// before the last response is merged.
+type incrementalMatrixMerge struct {
+	series map[string]*queryrange.SampleStream
+
+	stats      []queryrange.Response
+	analyzes   []*queryrange.Analysis
+	warnings   []string
+	genNumbers []string
+}
+
+func newIncrementalMatrixMerge() *incrementalMatrixMerge {
+	return &incrementalMatrixMerge{series: map[string]*queryrange.SampleStream{}}
+}
+
+// add merges the response of the split request following the ones merged so far. It returns false, without merging
+// the response, if it is not a Prometheus response.
+func (m *incrementalMatrixMerge) add(res queryrange.Response) bool {
+	resp, ok := res.(*queryrange.PrometheusResponse)
+	if !ok {
+		return false
+	}
+	for _, stream := range resp.Data.Result {
+		key := cortexpb.FromLabelAdaptersToLabels(stream.Labels).String()
+		existing, ok := m.series[key]
+		if !ok {
+			m.series[key] = &queryrange.SampleStream{Labels: stream.Labels, Samples: stream.Samples, Histograms: stream.Histograms}
+			continue
+		}
+
+		// Samples at the boundaries of splits are part of both responses.
+		samples := stream.Samples
+		if len(existing.Samples) > 0 && len(samples) > 0 {
+			samples = queryrange.SliceSamples(samples, existing.Samples[len(existing.Samples)-1].TimestampMs)
+		}
+		histograms := stream.Histograms
+		if len(existing.Histograms) > 0 && len(histograms) > 0 {
+			histograms = queryrange.SliceHistogram(histograms, existing.Histograms[len(existing.Histograms)-1].GetTimestamp())
+		}
+		existing.Samples = append(existing.Samples, samples...)
+		existing.Histograms = append(existing.Histograms, histograms...)
+	}
+
+	if resp.Data.Stats != nil {
+		m.stats = append(m.stats, &queryrange.PrometheusResponse{Data: queryrange.PrometheusData{Stats: resp.Data.Stats}})
+	}
+	if resp.Data.Analysis != nil {
+		m.analyzes = append(m.analyzes, resp.Data.Analysis)
+	}
+	m.warnings = append(m.warnings, resp.Warnings...)
+	for _, h := range resp.Headers {
+		if h.GetName() == queryrange.ResultsCacheGenNumberHeaderName {
+			m.genNumbers = append(m.genNumbers, h.GetValues()...)
+		}
+	}
+	return true
+}
+
+// response returns the merged response, with series sorted by labels.
+func (m *incrementalMatrixMerge) response() *queryrange.PrometheusResponse {
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]queryrange.SampleStream, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *m.series[key])
+	}
+
+	resp := &queryrange.PrometheusResponse{
+		Status: queryrange.StatusSuccess,
+		Data: queryrange.PrometheusData{
+			ResultType: model.ValMatrix.String(),
+			Result:     result,
+			Stats:      queryrange.StatsMerge(m.stats),
+			Analysis:   queryrange.AnalyzesMerge(m.analyzes...),
+		},
+		Warnings: m.warnings,
+	}
+	if len(m.genNumbers) > 0 {
+		resp.Headers = []*queryrange.PrometheusResponseHeader{{
+			Name:   queryrange.ResultsCacheGenNumberHeaderName,
+			Values: m.genNumbers,
+		}}
+	}
+	return resp
+}
+
+// doRequestsInOrder executes requests split by time like queryrange.DoRequests, but merges their responses as
+// soon as all responses of earlier requests are merged. Only responses completing ahead of an earlier one are held.
+// Once a response can't be merged this way, it and all following responses are merged by merger with the ones
+// merged so far.
+func doRequestsInOrder(ctx context.Context, next queryrange.Handler, r queryrange.Request, reqs []queryrange.Request, limits queryrange.Limits, merger queryrange.Merger) (queryrange.Response, error) {
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, httpgrpc.Errorf(http.StatusBadRequest, "%s", err.Error())
+	}
+
+	// If one of the requests fail, we want to be able to cancel the rest of them.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type indexedResponse struct {
+		index int
+		resp  queryrange.Response
+		err   error
+	}
+
+	intermediate := make(chan int)
+	go func() {
+		for i := range reqs {
+			intermediate <- i
+		}
+		close(intermediate)
+	}()
+
+	resps := make(chan indexedResponse, len(reqs))
+	parallelism := min(validation.SmallestPositiveIntPerTenant(tenantIDs, limits.MaxQueryParallelism), len(reqs))
+	for range parallelism {
+		go func() {
+			for i := range intermediate {
+				resp, err := next.Do(ctx, reqs[i])
+				resps <- indexedResponse{index: i, resp: resp, err: err}
+			}
+		}()
+	}
+
+	var (
+		merge    = newIncrementalMatrixMerge()
+		pending  = map[int]queryrange.Response{}
+		merged   int
+		fallback []queryrange.Response
+		firstErr error
+	)
+	for range reqs {
+		r := <-resps
+		if r.err != nil {
+			if firstErr == nil {
+				cancel()
+				firstErr = r.err
+			}
+			continue
+		}
+		if firstErr != nil {
+			continue
+		}
+
+		pending[r.index] = r.resp
+		for resp, ok := pending[merged]; ok; resp, ok = pending[merged] {
+			if fallback == nil && !merge.add(resp) {
+				fallback = []queryrange.Response{}
+				if merged > 0 {
+					fallback = append(fallback, merge.response())
+				}
+			}
+			if fallback != nil {
+				fallback = append(fallback, resp)
+			}
+			delete(pending, merged)
+			merged++
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if fallback != nil {
+		return merger.MergeResponse(r, fallback...)
+	}
+	return merge.response(), nil
+}
+
+// encodeStreamedResponse encodes a query range response like the given codec, but writes its series one by one to
+// the body while it is read, instead of encoding the whole response into memory first.
+func encodeStreamedResponse(ctx context.Context, codec queryrange.Codec, res queryrange.Response) (*http.Response, error) {
+	a, ok := res.(*queryrange.PrometheusResponse)
+	if !ok || len(a.Data.Result) == 0 {
+		return codec.EncodeResponse(ctx, res)
+	}
+
+	// Encode the response without series, which are written between its head and tail.
+	envelope := *a
+	envelope.Data.Result = []queryrange.SampleStream{}
+	resp, err := codec.EncodeResponse(ctx, &envelope)
+	if err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, httpgrpc.Errorf(http.StatusInternalServerError, "error encoding response: %v", err)
+	}
+	// Series are the first field of the response which can hold arbitrary strings, its first match is the key.
+	i := bytes.Index(b, streamedResultKey)
+	if i < 0 {
+		return codec.EncodeResponse(ctx, res)
+	}
+	head, tail := b[:i+len(streamedResultKey)], b[i+len(streamedResultKey):]
+
+	pr, pw := io.Pipe()
+	go func() {
+		_ = pw.CloseWithError(writeStreamedResult(pw, head, a.Data.Result, tail))
+	}()
+	resp.Body = pr
+	resp.ContentLength = -1
+	return resp, nil
+}
+
+func writeStreamedResult(w io.Writer, head []byte, result []queryrange.SampleStream, tail []byte) error {
+	bw := bufio.NewWriterSize(w, 64*1024)
+	if _, err := bw.Write(head); err != nil {
+		return err
+	}
+	for i := range result {
+		if i > 0 {
+			if err := bw.WriteByte(','); err != nil {
+				return err
+			}
+		}
+		b, err := result[i].MarshalJSON()
+		if err != nil {
+			return err
+		}
+		if _, err := bw.Write(b); err != nil {
+			return err
+		}
+	}
+	if _, err := bw.Write(tail); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,229 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/weaveworks/common/user"
+
+	"github.com/thanos-io/thanos/internal/cortex/cortexpb"
+	"github.com/thanos-io/thanos/internal/cortex/frontend/transport"
+	"github.com/thanos-io/thanos/internal/cortex/querier/queryrange"
+	cortexutil "github.com/thanos-io/thanos/internal/cortex/util"
+	cortexvalidation "github.com/thanos-io/thanos/internal/cortex/util/validation"
+)
+
+// splitPromqlResults returns a handler answering range queries with numSeries series, {series="<i>"}, having
+// samples at the start and end of the query.
+func splitPromqlResults(numSeries int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start, err := cortexutil.ParseTime(r.FormValue("start"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		end, err := cortexutil.ParseTime(r.FormValue("end"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		q := queryrange.PrometheusResponse{
+			Status: "success",
+			Data: queryrange.PrometheusData{
+				ResultType: string(parser.ValueTypeMatrix),
+				Result:     []queryrange.SampleStream{},
+			},
+		}
+		for i := range numSeries {
+			q.Data.Result = append(q.Data.Result, queryrange.SampleStream{
+				Labels:  []cortexpb.LabelAdapter{{Name: "series", Value: strconv.Itoa(i)}},
+				Samples: []cortexpb.Sample{{Value: float64(i), TimestampMs: start}, {Value: float64(i), TimestampMs: end}},
+			})
+		}
+		if err := json.NewEncoder(w).Encode(q); err != nil {
+			panic(err)
+		}
+	})
+}
+
+func TestRoundTripIncrementalMerge(t *testing.T) {
+	rt, err := newFakeRoundTripper()
+	testutil.Ok(t, err)
+	defer rt.Close()
+	rt.setHandler(splitPromqlResults(3))
+
+	roundTrip := func(incrementalMerge bool) *http.Response {
+		tpw, err := NewTripperware(
+			Config{
+				QueryRangeConfig: QueryRangeConfig{
+					Limits:                 defaultLimits,
+					SplitQueriesByInterval: time.Hour,
+					IncrementalMerge:       incrementalMerge,
+				},
+				CortexHandlerConfig: &transport.HandlerConfig{},
+			}, nil, log.NewNopLogger(),
+		)
+		testutil.Ok(t, err)
+
+		ctx := user.InjectOrgID(context.Background(), "1")
+		httpReq, err := NewThanosQueryRangeCodec(true).EncodeRequest(ctx, &ThanosQueryRangeRequest{
+			Path:  "/api/v1/query_range",
+			Start: 0,
+			End:   5 * hour,
+			Step:  10 * seconds,
+			Query: "foo",
+		})
+		testutil.Ok(t, err)
+
+		resp, err := tpw(rt).RoundTrip(httpReq)
+		testutil.Ok(t, err)
+		return resp
+	}
+
+	buffered := roundTrip(false)
+	streamed := roundTrip(true)
+	testutil.Equals(t, int64(-1), streamed.ContentLength)
+
+	expected, err := io.ReadAll(buffered.Body)
+	testutil.Ok(t, err)
+	actual, err := io.ReadAll(streamed.Body)
+	testutil.Ok(t, err)
+	testutil.Equals(t, string(expected), string(actual))
+
+	resp, err := queryrange.PrometheusCodec.DecodeResponse(context.Background(), &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewReader(actual)),
+	}, nil)
+	testutil.Ok(t, err)
+	result := resp.(*queryrange.PrometheusResponse).Data.Result
+	testutil.Equals(t, 3, len(result))
+	// Samples at the start and end of every split by hour.
+	testutil.Equals(t, 10, len(result[0].Samples))
+}
+
+func TestIncrementalMatrixMerge(t *testing.T) {
+	series := func(name string, timestamps ...int64) queryrange.SampleStream {
+		s := queryrange.SampleStream{Labels: []cortexpb.LabelAdapter{{Name: "__name__", Value: name}}}
+		for _, ts := range timestamps {
+			s.Samples = append(s.Samples, cortexpb.Sample{Value: float64(ts), TimestampMs: ts})
+		}
+		return s
+	}
+	responses := func() []queryrange.Response {
+		return []queryrange.Response{
+			&queryrange.PrometheusResponse{
+				Data: queryrange.PrometheusData{
+					Result: []queryrange.SampleStream{series("b", 0, 1, 2), series("a", 1, 2)},
+					Stats:  &queryrange.PrometheusResponseStats{Samples: &queryrange.PrometheusResponseSamplesStats{TotalQueryableSamples: 5}},
+				},
+				Warnings: []string{"warning"},
+			},
+			&queryrange.PrometheusResponse{
+				Data: queryrange.PrometheusData{
+					Result: []queryrange.SampleStream{series("a", 2, 3), series("c", 3)},
+					Stats:  &queryrange.PrometheusResponseStats{Samples: &queryrange.PrometheusResponseSamplesStats{TotalQueryableSamples: 3}},
+				},
+				Headers: []*queryrange.PrometheusResponseHeader{{Name: queryrange.ResultsCacheGenNumberHeaderName, Values: []string{"1"}}},
+			},
+			&queryrange.PrometheusResponse{
+				Data: queryrange.PrometheusData{Result: []queryrange.SampleStream{series("b", 3, 4)}},
+			},
+		}
+	}
+
+	expected, err := queryrange.PrometheusCodec.MergeResponse(nil, responses()...)
+	testutil.Ok(t, err)
+
+	merge := newIncrementalMatrixMerge()
+	for _, resp := range responses() {
+		testutil.Assert(t, merge.add(resp))
+	}
+	testutil.Equals(t, expected, merge.response())
+
+	testutil.Assert(t, !merge.add(&queryrange.PrometheusInstantQueryResponse{}))
+	testutil.Equals(t, expected, merge.response())
+}
+
+// recordingMerger records the responses it merges.
+type recordingMerger struct {
+	responses []queryrange.Response
+}
+
+func (m *recordingMerger) MergeResponse(_ queryrange.Request, responses ...queryrange.Response) (queryrange.Response, error) {
+	m.responses = responses
+	return &queryrange.PrometheusResponse{}, nil
+}
+
+func TestDoRequestsInOrderFallback(t *testing.T) {
+	responses := []queryrange.Response{
+		&queryrange.PrometheusResponse{Data: queryrange.PrometheusData{Result: []queryrange.SampleStream{{
+			Labels:  []cortexpb.LabelAdapter{{Name: "a", Value: "b"}},
+			Samples: []cortexpb.Sample{{Value: 1, TimestampMs: 0}},
+		}}}},
+		// Not supported by the incremental merge.
+		&queryrange.PrometheusInstantQueryResponse{Status: "success"},
+		&queryrange.PrometheusResponse{Status: "success"},
+	}
+	var reqs []queryrange.Request
+	for i := range responses {
+		reqs = append(reqs, &ThanosQueryRangeRequest{Start: int64(i), End: int64(i)})
+	}
+	next := queryrange.HandlerFunc(func(_ context.Context, r queryrange.Request) (queryrange.Response, error) {
+		return responses[r.GetStart()], nil
+	})
+	limits, err := cortexvalidation.NewOverrides(*defaultLimits, nil)
+	testutil.Ok(t, err)
+
+	merger := &recordingMerger{}
+	_, err = doRequestsInOrder(user.InjectOrgID(context.Background(), "1"), next, &ThanosQueryRangeRequest{}, reqs, limits, merger)
+	testutil.Ok(t, err)
+
+	// Responses merged before the unsupported one are passed to the merger as a single response.
+	merge := newIncrementalMatrixMerge()
+	testutil.Assert(t, merge.add(responses[0]))
+	testutil.Equals(t, []queryrange.Response{merge.response(), responses[1], responses[2]}, merger.responses)
+}
+
+func TestEncodeStreamedResponse(t *testing.T) {
+	resp := &queryrange.PrometheusResponse{
+		Status: "success",
+		Data: queryrange.PrometheusData{
+			ResultType: string(parser.ValueTypeMatrix),
+			Stats:      &queryrange.PrometheusResponseStats{Samples: &queryrange.PrometheusResponseSamplesStats{TotalQueryableSamples: 4}},
+		},
+		Warnings: []string{`"result":[]`},
+	}
+	for i := range 100 {
+		resp.Data.Result = append(resp.Data.Result, queryrange.SampleStream{
+			// Label values are not escaped as HTML.
+			Labels:  []cortexpb.LabelAdapter{{Name: "series", Value: fmt.Sprintf("<%d>", i)}},
+			Samples: []cortexpb.Sample{{Value: 1.5, TimestampMs: 1000}, {Value: 2, TimestampMs: 2000}},
+		})
+	}
+
+	buffered, err := queryrange.PrometheusCodec.EncodeResponse(context.Background(), resp)
+	testutil.Ok(t, err)
+	streamed, err := encodeStreamedResponse(context.Background(), queryrange.PrometheusCodec, resp)
+	testutil.Ok(t, err)
+	testutil.Equals(t, buffered.Header, streamed.Header)
+
+	expected, err := io.ReadAll(buffered.Body)
+	testutil.Ok(t, err)
+	actual, err := io.ReadAll(streamed.Body)
+	testutil.Ok(t, err)
+	testutil.Equals(t, string(expected), string(actual))
+}
@@ -0,0 +1,91 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/go-kit/log"
+
+	"github.com/thanos-io/thanos/internal/cortex/frontend/transport"
+)
+
+func TestRoundTripPromQLAPI(t *testing.T) {
+	tpw, err := NewTripperware(
+		Config{
+			QueryRangeConfig: QueryRangeConfig{
+				Limits: defaultLimits,
+			},
+			CortexHandlerConfig: &transport.HandlerConfig{},
+		}, nil, log.NewNopLogger(),
+	)
+	testutil.Ok(t, err)
+
+	// Requests are answered without a downstream.
+	rt := tpw(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		t.Fatalf("unexpected downstream request %v", r.URL)
+		return nil, nil
+	}))
+
+	for _, tcase := range []struct {
+		name     string
+		method   string
+		path     string
+		query    string
+		code     int
+		expected string
+	}{
+		{
+			name:     "format query",
+			method:   http.MethodGet,
+			path:     "/api/v1/format_query",
+			query:    "sum(rate(up[5m]))by(job)",
+			code:     http.StatusOK,
+			expected: `{"data":"sum by (job) (rate(up[5m]))","status":"success"}`,
+		},
+		{
+			name:     "parse query",
+			method:   http.MethodPost,
+			path:     "/api/v1/parse_query",
+			query:    "1 + 2",
+			code:     http.StatusOK,
+			expected: `{"data":{"bool":false,"lhs":{"type":"numberLiteral","val":"1"},"matching":null,"op":"+","rhs":{"type":"numberLiteral","val":"2"},"type":"binaryExpr"},"status":"success"}`,
+		},
+		{
+			name:     "invalid query",
+			method:   http.MethodGet,
+			path:     "/api/v1/parse_query",
+			query:    "sum(",
+			code:     http.StatusBadRequest,
+			expected: `{"error":"invalid parameter \"query\": 1:5: parse error: unclosed left parenthesis","errorType":"bad_data","status":"error"}`,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			form := url.Values{"query": []string{tcase.query}}
+			var (
+				req *http.Request
+				err error
+			)
+			if tcase.method == http.MethodGet {
+				req, err = http.NewRequest(http.MethodGet, tcase.path+"?"+form.Encode(), nil)
+			} else {
+				req, err = http.NewRequest(http.MethodPost, tcase.path, strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			testutil.Ok(t, err)
+
+			resp, err := rt.RoundTrip(req)
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.code, resp.StatusCode)
+			body, err := io.ReadAll(resp.Body)
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.expected, string(body))
+		})
+	}
+}
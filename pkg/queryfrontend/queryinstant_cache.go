@@ -0,0 +1,215 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/types"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/thanos-io/thanos/internal/cortex/chunk/cache"
+	"github.com/thanos-io/thanos/internal/cortex/querier/queryrange"
+	"github.com/thanos-io/thanos/internal/cortex/tenant"
+	"github.com/thanos-io/thanos/internal/cortex/util/validation"
+	"github.com/thanos-io/thanos/pkg/extpromql"
+)
+
+var errNotCachable = errors.New("not cachable")
+
+// instantQueryCache caches whole responses of instant queries by their evaluation time.
+type instantQueryCache struct {
+	logger       log.Logger
+	next         queryrange.Handler
+	cache        cache.Cache
+	limits       queryrange.Limits
+	keyGenerator thanosCacheKeyGenerator
+	roundingStep int64
+}
+
+// newInstantQueryCacheMiddleware creates a results cache middleware for instant queries.
+// Queries are cached if their evaluation time is older than the max cache freshness of the tenant.
+// If roundingStep is not zero, the evaluation time of cachable queries is rounded down to a multiple
+// of it, so queries evaluated at slightly different times share cache entries.
+func newInstantQueryCacheMiddleware(
+	logger log.Logger,
+	cfg queryrange.ResultsCacheConfig,
+	keyGenerator thanosCacheKeyGenerator,
+	limits queryrange.Limits,
+	roundingStep time.Duration,
+	reg prometheus.Registerer,
+) (queryrange.Middleware, error) {
+	c, err := cache.New(cfg.CacheConfig, reg, logger)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Compression == "snappy" {
+		c = cache.NewSnappy(c, logger)
+	}
+
+	return queryrange.MiddlewareFunc(func(next queryrange.Handler) queryrange.Handler {
+		return instantQueryCache{
+			logger:       logger,
+			next:         next,
+			cache:        c,
+			limits:       limits,
+			keyGenerator: keyGenerator,
+			roundingStep: roundingStep.Milliseconds(),
+		}
+	}), nil
+}
+
+func (c instantQueryCache) Do(ctx context.Context, r queryrange.Request) (queryrange.Response, error) {
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, httpgrpc.Errorf(http.StatusBadRequest, "%s", err.Error())
+	}
+
+	req, ok := r.(*ThanosQueryInstantRequest)
+	// Requests without time are evaluated at the current time by the querier.
+	if !ok || req.Time == 0 || !shouldCache(req) {
+		return c.next.Do(ctx, r)
+	}
+
+	// Freshness is checked on the requested evaluation time, recent queries are not rounded.
+	maxCacheFreshness := validation.MaxDurationPerTenant(tenantIDs, c.limits.MaxCacheFreshness)
+	maxCacheTime := int64(model.Now().Add(-maxCacheFreshness))
+	if req.Time > maxCacheTime {
+		return c.next.Do(ctx, r)
+	}
+
+	if c.roundingStep > 0 {
+		rounded := *req
+		rounded.Time -= rounded.Time % c.roundingStep
+		req = &rounded
+	}
+	if !c.isQueryCachable(req, maxCacheTime) {
+		return c.next.Do(ctx, r)
+	}
+
+	key := c.keyGenerator.GenerateCacheKey(tenant.JoinTenantIDs(tenantIDs), req)
+	if resp, ok := c.get(ctx, key); ok {
+		return resp, nil
+	}
+
+	resp, err := c.next.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if instantResp, ok := resp.(*queryrange.PrometheusInstantQueryResponse); ok && c.shouldCacheResponse(instantResp) {
+		c.put(ctx, key, req.Time, instantResp)
+	}
+	return resp, nil
+}
+
+// shouldCacheResponse returns true for successful responses that are not marked as not cachable by the downstream.
+func (c instantQueryCache) shouldCacheResponse(resp *queryrange.PrometheusInstantQueryResponse) bool {
+	if resp.Status != queryrange.StatusSuccess {
+		return false
+	}
+	for _, h := range resp.Headers {
+		if h.Name == cacheControlHeader && slices.Contains(h.Values, noStoreValue) {
+			level.Debug(c.logger).Log("msg", "response is marked as not cachable, not caching the response", "header", cacheControlHeader)
+			return false
+		}
+	}
+	return true
+}
+
+// isQueryCachable returns false for queries looking into the future of the evaluation time with
+// the @ modifier or a negative offset, as their results might still change.
+func (c instantQueryCache) isQueryCachable(r *ThanosQueryInstantRequest, maxCacheTime int64) bool {
+	if !strings.Contains(r.Query, "@") && !strings.Contains(r.Query, "offset") {
+		return true
+	}
+	expr, err := extpromql.ParseExpr(r.Query)
+	if err != nil {
+		level.Warn(c.logger).Log("msg", "failed to parse query, considering it as not cachable", "query", r.Query, "err", err)
+		return false
+	}
+	// This resolves the start() and end() used with the @ modifier.
+	t := timestamp.Time(r.Time)
+	expr, err = promql.PreprocessExpr(expr, t, t, 0)
+	if err != nil {
+		level.Warn(c.logger).Log("msg", "failed to preprocess expr, considering it as not cachable", "query", r.Query, "err", err)
+		return false
+	}
+
+	cachable := true
+	check := func(ts *int64, offset time.Duration) error {
+		if offset < 0 || (ts != nil && (*ts > r.Time || *ts > maxCacheTime)) {
+			cachable = false
+			return errNotCachable
+		}
+		return nil
+	}
+	parser.Inspect(expr, func(n parser.Node, _ []parser.Node) error {
+		switch e := n.(type) {
+		case *parser.VectorSelector:
+			return check(e.Timestamp, e.OriginalOffset)
+		case *parser.SubqueryExpr:
+			return check(e.Timestamp, e.OriginalOffset)
+		}
+		return nil
+	})
+	return cachable
+}
+
+func (c instantQueryCache) get(ctx context.Context, key string) (queryrange.Response, bool) {
+	found, bufs, _ := c.cache.Fetch(ctx, []string{cache.HashKey(key)})
+	if len(found) != 1 || len(bufs) != 1 {
+		return nil, false
+	}
+
+	var cached queryrange.CachedResponse
+	if err := proto.Unmarshal(bufs[0], &cached); err != nil {
+		level.Error(c.logger).Log("msg", "error unmarshalling cached value", "err", err)
+		return nil, false
+	}
+	if cached.Key != key || len(cached.Extents) != 1 || cached.Extents[0].Response == nil {
+		return nil, false
+	}
+
+	var resp queryrange.PrometheusInstantQueryResponse
+	if err := types.UnmarshalAny(cached.Extents[0].Response, &resp); err != nil {
+		level.Error(c.logger).Log("msg", "error unmarshalling cached response", "err", err)
+		return nil, false
+	}
+	return &resp, true
+}
+
+func (c instantQueryCache) put(ctx context.Context, key string, t int64, resp *queryrange.PrometheusInstantQueryResponse) {
+	// Headers are not needed to respond from the cache.
+	withoutHeaders := *resp
+	withoutHeaders.Headers = nil
+
+	anyResp, err := types.MarshalAny(&withoutHeaders)
+	if err != nil {
+		level.Error(c.logger).Log("msg", "error marshalling response", "err", err)
+		return
+	}
+	buf, err := proto.Marshal(&queryrange.CachedResponse{
+		Key:     key,
+		Extents: []queryrange.Extent{{Start: t, End: t, Response: anyResp}},
+	})
+	if err != nil {
+		level.Error(c.logger).Log("msg", "error marshalling cached value", "err", err)
+		return
+	}
+
+	c.cache.Store(ctx, []string{cache.HashKey(key)}, [][]byte{buf})
+}
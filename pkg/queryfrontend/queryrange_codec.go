@@ -44,6 +44,9 @@ var (
 type queryRangeCodec struct {
 	queryrange.Codec
 	partialResponse bool
+	// incrementalMerge merges responses of split requests as they complete in time order and writes the merged
+	// response to the client series by series.
+	incrementalMerge bool
 }
 
 // NewThanosQueryRangeCodec initializes a queryRangeCodec.
@@ -211,6 +214,17 @@ func (c queryRangeCodec) EncodeRequest(ctx context.Context, r queryrange.Request
 	return req.WithContext(ctx), nil
 }
 
+func (c queryRangeCodec) DecodeResponse(ctx context.Context, r *http.Response, req queryrange.Request) (queryrange.Response, error) {
+	return decodeLimitedResponse(ctx, c.Codec, r, req)
+}
+
+func (c queryRangeCodec) EncodeResponse(ctx context.Context, res queryrange.Response) (*http.Response, error) {
+	if !c.incrementalMerge {
+		return c.Codec.EncodeResponse(ctx, res)
+	}
+	return encodeStreamedResponse(ctx, c.Codec, res)
+}
+
 func parseDurationMillis(s string) (int64, error) {
 	if d, err := strconv.ParseFloat(s, 64); err == nil {
 		ts := d * float64(time.Second/time.Millisecond)
@@ -0,0 +1,213 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/thanos-io/thanos/pkg/tenancy"
+)
+
+// QueueConfig holds the config for queueing requests sent to downstream queriers.
+type QueueConfig struct {
+	// MaxConcurrentRequests is the maximum number of requests sent to downstream queriers
+	// at the same time, 0 disables queueing.
+	MaxConcurrentRequests int
+	// MaxOutstandingPerTenant is the maximum number of queued requests of a single tenant.
+	// Further requests of the tenant are rejected with 429.
+	MaxOutstandingPerTenant int
+}
+
+// requestQueue holds a FIFO queue of waiting requests per tenant. Tenants with queued requests
+// are served in round robin order whenever a downstream request slot is available.
+type requestQueue struct {
+	maxConcurrent  int
+	maxOutstanding int
+
+	mtx      sync.Mutex
+	inflight int
+	queues   map[string][]chan struct{}
+	// tenants with queued requests in round robin order, next is the index of the tenant to dequeue from next.
+	tenants []string
+	next    int
+
+	queueLength *prometheus.GaugeVec
+}
+
+func newRequestQueue(maxConcurrent, maxOutstanding int, queueLength *prometheus.GaugeVec) *requestQueue {
+	return &requestQueue{
+		maxConcurrent:  maxConcurrent,
+		maxOutstanding: maxOutstanding,
+		queues:         map[string][]chan struct{}{},
+		queueLength:    queueLength,
+	}
+}
+
+// enqueue queues a request of the tenant. The returned channel is closed once the request can be sent downstream,
+// in which case the request has to release its slot once done.
+func (q *requestQueue) enqueue(tenant string) (chan struct{}, bool) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	queue := q.queues[tenant]
+	if len(queue) >= q.maxOutstanding {
+		return nil, false
+	}
+	ready := make(chan struct{})
+	if len(queue) == 0 {
+		q.tenants = append(q.tenants, tenant)
+	}
+	q.queues[tenant] = append(queue, ready)
+	q.queueLength.WithLabelValues(tenant).Inc()
+
+	q.dispatch()
+	return ready, true
+}
+
+// cancel removes a request of the tenant from the queue. If the request was already dequeued, its slot is released.
+func (q *requestQueue) cancel(tenant string, ready chan struct{}) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	queue := q.queues[tenant]
+	i := slices.Index(queue, ready)
+	if i < 0 {
+		q.inflight--
+		q.dispatch()
+		return
+	}
+	q.queues[tenant] = slices.Delete(queue, i, i+1)
+	q.queueLength.WithLabelValues(tenant).Dec()
+	if len(q.queues[tenant]) == 0 {
+		q.removeTenant(slices.Index(q.tenants, tenant))
+	}
+}
+
+// release releases the slot of a dequeued request.
+func (q *requestQueue) release() {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.inflight--
+	q.dispatch()
+}
+
+// dispatch dequeues requests while there are free slots. It has to be called with the lock held.
+func (q *requestQueue) dispatch() {
+	for q.inflight < q.maxConcurrent && len(q.tenants) > 0 {
+		if q.next >= len(q.tenants) {
+			q.next = 0
+		}
+		tenant := q.tenants[q.next]
+		queue := q.queues[tenant]
+
+		close(queue[0])
+		q.inflight++
+		q.queues[tenant] = queue[1:]
+		q.queueLength.WithLabelValues(tenant).Dec()
+
+		if len(q.queues[tenant]) == 0 {
+			q.removeTenant(q.next)
+			continue
+		}
+		q.next++
+	}
+}
+
+func (q *requestQueue) removeTenant(i int) {
+	tenant := q.tenants[i]
+	delete(q.queues, tenant)
+	q.queueLength.DeleteLabelValues(tenant)
+
+	q.tenants = slices.Delete(q.tenants, i, i+1)
+	if i < q.next {
+		q.next--
+	}
+}
+
+// queueRoundTripper queues requests per tenant before sending them to the next RoundTripper,
+// so that tenants sending many requests at once can't starve others.
+type queueRoundTripper struct {
+	next          http.RoundTripper
+	queue         *requestQueue
+	defaultTenant string
+
+	queueDuration     prometheus.Histogram
+	discardedRequests *prometheus.CounterVec
+}
+
+// NewQueueRoundTripper returns a RoundTripper which limits the number of concurrent requests sent to next.
+// Requests are queued per tenant and dequeued in round robin order across tenants. Requests without tenant header
+// are queued as defaultTenant.
+func NewQueueRoundTripper(next http.RoundTripper, cfg QueueConfig, defaultTenant string, reg prometheus.Registerer) http.RoundTripper {
+	return &queueRoundTripper{
+		next:          next,
+		defaultTenant: defaultTenant,
+		queue: newRequestQueue(cfg.MaxConcurrentRequests, cfg.MaxOutstandingPerTenant, promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "thanos_query_frontend_queue_length",
+			Help: "Number of queued requests per tenant.",
+		}, []string{"tenant"})),
+		queueDuration: promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
+			Name:    "thanos_query_frontend_queue_duration_seconds",
+			Help:    "Time spent by requests in the queue before being sent to downstream queriers.",
+			Buckets: []float64{0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60},
+		}),
+		discardedRequests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_query_frontend_discarded_requests_total",
+			Help: "Total number of requests rejected because the queue of the tenant was full.",
+		}, []string{"tenant"}),
+	}
+}
+
+func (q *queueRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	tenant := r.Header.Get(tenancy.DefaultTenantHeader)
+	if tenant == "" {
+		tenant = q.defaultTenant
+	}
+
+	start := time.Now()
+	ready, ok := q.queue.enqueue(tenant)
+	if !ok {
+		q.discardedRequests.WithLabelValues(tenant).Inc()
+		return nil, httpgrpc.Errorf(http.StatusTooManyRequests, "too many outstanding requests for tenant %s", tenant)
+	}
+
+	select {
+	case <-ready:
+	case <-r.Context().Done():
+		q.queue.cancel(tenant, ready)
+		return nil, r.Context().Err()
+	}
+	q.queueDuration.Observe(time.Since(start).Seconds())
+
+	resp, err := q.next.RoundTrip(r)
+	if err != nil {
+		q.queue.release()
+		return nil, err
+	}
+	// The slot is held until the response is read, as the downstream querier is busy until then.
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: q.queue.release}
+	return resp, nil
+}
+
+// releasingBody releases the queue slot of a request once its response body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
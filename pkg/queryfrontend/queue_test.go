@@ -0,0 +1,217 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/thanos-io/thanos/pkg/tenancy"
+)
+
+func isReady(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestRequestQueue(t *testing.T) {
+	queueLength := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "queue_length"}, []string{"tenant"})
+
+	t.Run("round robin across tenants", func(t *testing.T) {
+		q := newRequestQueue(1, 10, queueLength)
+
+		first, ok := q.enqueue("a")
+		testutil.Assert(t, ok)
+		testutil.Assert(t, isReady(first), "first request should be sent without waiting")
+
+		var (
+			names []string
+			reqs  []chan struct{}
+		)
+		for _, tenant := range []string{"a", "a", "a", "b", "c"} {
+			ch, ok := q.enqueue(tenant)
+			testutil.Assert(t, ok)
+			testutil.Assert(t, !isReady(ch), "request should be queued")
+			names = append(names, tenant)
+			reqs = append(reqs, ch)
+		}
+		testutil.Equals(t, 3.0, promtest.ToFloat64(queueLength.WithLabelValues("a")))
+
+		var order []string
+		for range reqs {
+			q.release()
+			var dequeued int
+			for i, ch := range reqs {
+				if ch != nil && isReady(ch) {
+					order = append(order, names[i])
+					reqs[i] = nil
+					dequeued++
+				}
+			}
+			testutil.Equals(t, 1, dequeued)
+		}
+		testutil.Equals(t, []string{"a", "b", "c", "a", "a"}, order)
+		testutil.Equals(t, 0, len(q.tenants))
+		testutil.Equals(t, 0, len(q.queues))
+	})
+	t.Run("max outstanding requests per tenant", func(t *testing.T) {
+		q := newRequestQueue(1, 2, queueLength)
+
+		_, ok := q.enqueue("a")
+		testutil.Assert(t, ok)
+		for range 2 {
+			_, ok := q.enqueue("a")
+			testutil.Assert(t, ok)
+		}
+		_, ok = q.enqueue("a")
+		testutil.Assert(t, !ok, "request over the limit should be rejected")
+		_, ok = q.enqueue("b")
+		testutil.Assert(t, ok, "other tenants should not be limited")
+	})
+	t.Run("cancel", func(t *testing.T) {
+		q := newRequestQueue(1, 10, queueLength)
+
+		first, _ := q.enqueue("a")
+		canceled, _ := q.enqueue("a")
+		second, _ := q.enqueue("b")
+
+		q.cancel("a", canceled)
+		testutil.Equals(t, []string{"b"}, q.tenants)
+
+		// Canceling a dequeued request releases its slot.
+		q.cancel("a", first)
+		testutil.Assert(t, isReady(second), "queued request should be dequeued")
+		testutil.Assert(t, !isReady(canceled), "canceled request should not be dequeued")
+		testutil.Equals(t, 1, q.inflight)
+	})
+}
+
+func TestQueueRoundTripper(t *testing.T) {
+	unblock := make(chan struct{})
+	received := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.URL.Path
+		<-unblock
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	rt := NewQueueRoundTripper(http.DefaultTransport, QueueConfig{MaxConcurrentRequests: 1, MaxOutstandingPerTenant: 1}, tenancy.DefaultTenant, prometheus.NewRegistry())
+	q := rt.(*queueRoundTripper).queue
+
+	type result struct {
+		path string
+		err  error
+	}
+	results := make(chan result, 10)
+	send := func(tenant, path string) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		testutil.Ok(t, err)
+		req.Header.Set(tenancy.DefaultTenantHeader, tenant)
+		go func() {
+			resp, err := rt.RoundTrip(req)
+			if err == nil {
+				_, err = io.ReadAll(resp.Body)
+				testutil.Ok(t, resp.Body.Close())
+			}
+			results <- result{path: path, err: err}
+		}()
+	}
+
+	send("a", "/a1")
+	testutil.Equals(t, "/a1", <-received)
+
+	send("a", "/a2")
+	testutil.Ok(t, waitFor(func() bool { return queued(q, "a") == 1 }))
+
+	// The queue of tenant a is full, further requests are rejected.
+	send("a", "/a3")
+	res := <-results
+	testutil.Equals(t, "/a3", res.path)
+	resp, ok := httpgrpc.HTTPResponseFromError(res.err)
+	testutil.Assert(t, ok, "expected HTTP error, got %v", res.err)
+	testutil.Equals(t, int32(http.StatusTooManyRequests), resp.Code)
+
+	send("b", "/b1")
+	testutil.Ok(t, waitFor(func() bool { return queued(q, "b") == 1 }))
+
+	// Requests are sent one by one, alternating between tenants.
+	close(unblock)
+	for _, exp := range []string{"/a1", "/a2", "/b1"} {
+		res := <-results
+		testutil.Ok(t, res.err)
+		testutil.Equals(t, exp, res.path)
+	}
+	testutil.Equals(t, "/a2", <-received)
+	testutil.Equals(t, "/b1", <-received)
+
+	t.Run("canceled while queued", func(t *testing.T) {
+		rt := NewQueueRoundTripper(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			<-r.Context().Done()
+			return nil, r.Context().Err()
+		}), QueueConfig{MaxConcurrentRequests: 1, MaxOutstandingPerTenant: 1}, "fallback", prometheus.NewRegistry())
+		q := rt.(*queueRoundTripper).queue
+
+		blockedCtx, cancelBlocked := context.WithCancel(context.Background())
+		defer cancelBlocked()
+		blocked, err := http.NewRequestWithContext(blockedCtx, http.MethodGet, "/", nil)
+		testutil.Ok(t, err)
+		go func() { _, _ = rt.RoundTrip(blocked) }()
+		testutil.Ok(t, waitFor(func() bool { return inflight(q) == 1 }))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+		testutil.Ok(t, err)
+		errs := make(chan error, 1)
+		go func() {
+			_, err := rt.RoundTrip(req)
+			errs <- err
+		}()
+		// Requests without tenant header are queued as the configured default tenant.
+		testutil.Ok(t, waitFor(func() bool { return queued(q, "fallback") == 1 }))
+
+		cancel()
+		testutil.Equals(t, context.Canceled, <-errs)
+		testutil.Equals(t, 0, queued(q, "fallback"))
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func queued(q *requestQueue, tenant string) int {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	return len(q.queues[tenant])
+}
+
+func inflight(q *requestQueue) int {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	return q.inflight
+}
+
+func waitFor(cond func() bool) error {
+	for range 100 {
+		if cond() {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return context.DeadlineExceeded
+}
@@ -0,0 +1,418 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/opentracing/opentracing-go"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/thanos-io/thanos/internal/cortex/querier/queryrange"
+	"github.com/thanos-io/thanos/internal/cortex/util/spanlogger"
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/store/storepb/prompb"
+)
+
+// ThanosRemoteReadRequest is a remote read request whose queries are answered with samples.
+// Splitting it by time restricts the time ranges of its queries, queries outside the time range
+// of the request are not sent downstream.
+type ThanosRemoteReadRequest struct {
+	Path          string
+	Start         int64
+	End           int64
+	Queries       []*prompb.Query
+	Headers       []*RequestHeader
+	SplitInterval time.Duration
+}
+
+// GetStart returns the start timestamp of the request in milliseconds.
+func (r *ThanosRemoteReadRequest) GetStart() int64 { return r.Start }
+
+// GetEnd returns the end timestamp of the request in milliseconds.
+func (r *ThanosRemoteReadRequest) GetEnd() int64 { return r.End }
+
+// GetStep returns the step of the request in milliseconds. Returns 1 like labels requests, remote read
+// requests have no step.
+func (r *ThanosRemoteReadRequest) GetStep() int64 { return 1 }
+
+// GetQuery returns the query of the request.
+func (r *ThanosRemoteReadRequest) GetQuery() string { return "" }
+
+func (r *ThanosRemoteReadRequest) GetCachingOptions() queryrange.CachingOptions {
+	return queryrange.CachingOptions{Disabled: true}
+}
+
+func (r *ThanosRemoteReadRequest) GetStats() string { return "" }
+
+func (r *ThanosRemoteReadRequest) GetSplitInterval() time.Duration { return r.SplitInterval }
+
+func (r *ThanosRemoteReadRequest) WithStats(_ string) queryrange.Request {
+	q := *r
+	return &q
+}
+
+// WithStartEnd clone the current request with different start and end timestamp, restricting the time range
+// of its queries to it.
+func (r *ThanosRemoteReadRequest) WithStartEnd(start, end int64) queryrange.Request {
+	q := *r
+	q.Start = start
+	q.End = end
+	q.Queries = make([]*prompb.Query, 0, len(r.Queries))
+	for _, query := range r.Queries {
+		clamped := *query
+		clamped.StartTimestampMs = max(query.StartTimestampMs, start)
+		clamped.EndTimestampMs = min(query.EndTimestampMs, end)
+		if query.Hints != nil {
+			hints := *query.Hints
+			hints.StartMs = max(hints.StartMs, start)
+			hints.EndMs = min(hints.EndMs, end)
+			clamped.Hints = &hints
+		}
+		q.Queries = append(q.Queries, &clamped)
+	}
+	return &q
+}
+
+// WithQuery clone the current request with a different query.
+func (r *ThanosRemoteReadRequest) WithQuery(_ string) queryrange.Request {
+	q := *r
+	return &q
+}
+
+// WithSplitInterval clones the current request with a different split interval.
+func (r *ThanosRemoteReadRequest) WithSplitInterval(interval time.Duration) queryrange.Request {
+	q := *r
+	q.SplitInterval = interval
+	return &q
+}
+
+// LogToSpan writes information about this request to an OpenTracing span.
+func (r *ThanosRemoteReadRequest) LogToSpan(sp opentracing.Span) {
+	sp.LogFields(
+		otlog.String("start", timestamp.Time(r.GetStart()).String()),
+		otlog.String("end", timestamp.Time(r.GetEnd()).String()),
+		otlog.Int("queries", len(r.Queries)),
+	)
+}
+
+// activeQueries returns the indexes of queries overlapping the time range of the request.
+func (r *ThanosRemoteReadRequest) activeQueries() []int {
+	active := make([]int, 0, len(r.Queries))
+	for i, q := range r.Queries {
+		if q.StartTimestampMs <= q.EndTimestampMs {
+			active = append(active, i)
+		}
+	}
+	return active
+}
+
+// Reset implements proto.Message interface required by queryrange.Request,
+// which is not used in thanos.
+func (r *ThanosRemoteReadRequest) Reset() {}
+
+// String implements proto.Message interface required by queryrange.Request,
+// which is not used in thanos.
+func (r *ThanosRemoteReadRequest) String() string { return "" }
+
+// ProtoMessage implements proto.Message interface required by queryrange.Request,
+// which is not used in thanos.
+func (r *ThanosRemoteReadRequest) ProtoMessage() {}
+
+// ThanosRemoteReadResponse holds the samples of every query of a remote read request.
+type ThanosRemoteReadResponse struct {
+	Results []*prompb.QueryResult
+	Headers []*ResponseHeader
+}
+
+// GetHeaders returns the HTTP headers in the response.
+func (m *ThanosRemoteReadResponse) GetHeaders() []*queryrange.PrometheusResponseHeader {
+	return headersToQueryRangeHeaders(m.Headers)
+}
+
+// GetStats returns response stats. Unimplemented for ThanosRemoteReadResponse.
+func (m *ThanosRemoteReadResponse) GetStats() *queryrange.PrometheusResponseStats { return nil }
+
+// Reset implements proto.Message interface required by queryrange.Response,
+// which is not used in thanos.
+func (m *ThanosRemoteReadResponse) Reset() {}
+
+// String implements proto.Message interface required by queryrange.Response,
+// which is not used in thanos.
+func (m *ThanosRemoteReadResponse) String() string { return "" }
+
+// ProtoMessage implements proto.Message interface required by queryrange.Response,
+// which is not used in thanos.
+func (m *ThanosRemoteReadResponse) ProtoMessage() {}
+
+// remoteReadCodec is used to encode/decode remote read requests and responses of samples.
+type remoteReadCodec struct{}
+
+// NewThanosRemoteReadCodec initializes a remoteReadCodec.
+func NewThanosRemoteReadCodec() *remoteReadCodec {
+	return &remoteReadCodec{}
+}
+
+// acceptsSamples returns whether the remote read request can be answered with samples.
+func acceptsSamples(req *prompb.ReadRequest) bool {
+	return len(req.AcceptedResponseTypes) == 0 || slices.Contains(req.AcceptedResponseTypes, prompb.ReadRequest_SAMPLES)
+}
+
+func decodeReadRequest(r *http.Request) (*prompb.ReadRequest, error) {
+	compressed, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return nil, err
+	}
+	var req prompb.ReadRequest
+	if err := proto.Unmarshal(buf, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func (c remoteReadCodec) DecodeRequest(_ context.Context, r *http.Request, forwardHeaders []string) (queryrange.Request, error) {
+	readReq, err := decodeReadRequest(r)
+	if err != nil {
+		return nil, httpgrpc.Errorf(http.StatusBadRequest, "error decoding remote read request: %v", err)
+	}
+	if len(readReq.Queries) == 0 {
+		return nil, httpgrpc.Errorf(http.StatusBadRequest, "remote read request without queries")
+	}
+
+	result := ThanosRemoteReadRequest{
+		Path:    r.URL.Path,
+		Start:   readReq.Queries[0].StartTimestampMs,
+		End:     readReq.Queries[0].EndTimestampMs,
+		Queries: readReq.Queries,
+	}
+	for _, q := range readReq.Queries {
+		result.Start = min(result.Start, q.StartTimestampMs)
+		result.End = max(result.End, q.EndTimestampMs)
+	}
+
+	// Include the specified headers from http request in remote read request.
+	for _, header := range forwardHeaders {
+		for h, hv := range r.Header {
+			if strings.EqualFold(h, header) {
+				result.Headers = append(result.Headers, &RequestHeader{Name: h, Values: hv})
+				break
+			}
+		}
+	}
+	return &result, nil
+}
+
+func (c remoteReadCodec) EncodeRequest(_ context.Context, r queryrange.Request) (*http.Request, error) {
+	readReq, ok := r.(*ThanosRemoteReadRequest)
+	if !ok {
+		return nil, httpgrpc.Errorf(http.StatusBadRequest, "invalid request format")
+	}
+
+	active := readReq.activeQueries()
+	queries := make([]*prompb.Query, 0, len(active))
+	for _, i := range active {
+		queries = append(queries, readReq.Queries[i])
+	}
+	// Samples are requested downstream so that responses of split requests can be merged.
+	b, err := proto.Marshal(&prompb.ReadRequest{
+		Queries:               queries,
+		AcceptedResponseTypes: []prompb.ReadRequest_ResponseType{prompb.ReadRequest_SAMPLES},
+	})
+	if err != nil {
+		return nil, httpgrpc.Errorf(http.StatusBadRequest, "error encoding request: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, readReq.Path, bytes.NewReader(snappy.Encode(nil, b)))
+	if err != nil {
+		return nil, httpgrpc.Errorf(http.StatusBadRequest, "error creating request: %s", err.Error())
+	}
+	for _, hv := range readReq.Headers {
+		for _, v := range hv.Values {
+			req.Header.Add(hv.Name, v)
+		}
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Read-Version", "0.1.0")
+	return req, nil
+}
+
+func (c remoteReadCodec) DecodeResponse(ctx context.Context, r *http.Response, req queryrange.Request) (queryrange.Response, error) {
+	if r.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(r.Body)
+		return nil, httpgrpc.ErrorFromHTTPResponse(&httpgrpc.HTTPResponse{
+			Code: int32(r.StatusCode),
+			Body: body,
+		})
+	}
+	readReq, ok := req.(*ThanosRemoteReadRequest)
+	if !ok {
+		return nil, httpgrpc.Errorf(http.StatusInternalServerError, "invalid request type")
+	}
+	log, _ := spanlogger.New(ctx, "ParseRemoteReadResponse") //nolint:ineffassign,staticcheck
+	defer log.Finish()
+
+	compressed, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Error(err) //nolint:errcheck
+		return nil, httpgrpc.Errorf(http.StatusInternalServerError, "error decoding response: %v", err)
+	}
+	log.LogFields(otlog.Int("bytes", len(compressed)))
+
+	buf, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return nil, httpgrpc.Errorf(http.StatusInternalServerError, "error decoding response: %v", err)
+	}
+	var readResp prompb.ReadResponse
+	if err := proto.Unmarshal(buf, &readResp); err != nil {
+		return nil, httpgrpc.Errorf(http.StatusInternalServerError, "error decoding response: %v", err)
+	}
+	active := readReq.activeQueries()
+	if len(readResp.Results) != len(active) {
+		return nil, httpgrpc.Errorf(http.StatusInternalServerError, "expected %d results in remote read response, got %d", len(active), len(readResp.Results))
+	}
+
+	// Results are indexed by queries of the request, including those which weren't sent downstream.
+	resp := ThanosRemoteReadResponse{Results: make([]*prompb.QueryResult, len(readReq.Queries))}
+	for i := range resp.Results {
+		resp.Results[i] = &prompb.QueryResult{}
+	}
+	for i, res := range readResp.Results {
+		resp.Results[active[i]] = res
+	}
+	for h, hv := range r.Header {
+		resp.Headers = append(resp.Headers, &ResponseHeader{Name: h, Values: hv})
+	}
+	return &resp, nil
+}
+
+func (c remoteReadCodec) EncodeResponse(ctx context.Context, res queryrange.Response) (*http.Response, error) {
+	sp, _ := opentracing.StartSpanFromContext(ctx, "APIResponse.ToHTTPResponse")
+	defer sp.Finish()
+
+	resp, ok := res.(*ThanosRemoteReadResponse)
+	if !ok {
+		return nil, httpgrpc.Errorf(http.StatusInternalServerError, "invalid response format")
+	}
+	b, err := proto.Marshal(&prompb.ReadResponse{Results: resp.Results})
+	if err != nil {
+		return nil, httpgrpc.Errorf(http.StatusInternalServerError, "error encoding response: %v", err)
+	}
+	b = snappy.Encode(nil, b)
+
+	sp.LogFields(otlog.Int("bytes", len(b)))
+	return &http.Response{
+		Header: http.Header{
+			"Content-Type":     []string{"application/x-protobuf"},
+			"Content-Encoding": []string{"snappy"},
+		},
+		Body:       io.NopCloser(bytes.NewBuffer(b)),
+		StatusCode: http.StatusOK,
+	}, nil
+}
+
+// MergeResponse merges the series of every query of remote read responses of split requests. Samples at the
+// boundaries of split requests are deduplicated.
+func (c remoteReadCodec) MergeResponse(req queryrange.Request, responses ...queryrange.Response) (queryrange.Response, error) {
+	readReq, ok := req.(*ThanosRemoteReadRequest)
+	if !ok {
+		return nil, httpgrpc.Errorf(http.StatusInternalServerError, "invalid request type")
+	}
+	if len(responses) == 1 {
+		return responses[0], nil
+	}
+
+	merged := &ThanosRemoteReadResponse{Results: make([]*prompb.QueryResult, len(readReq.Queries))}
+	for i := range merged.Results {
+		seriesByLabels := map[string]*prompb.TimeSeries{}
+		for _, res := range responses {
+			readResp, ok := res.(*ThanosRemoteReadResponse)
+			if !ok {
+				return nil, httpgrpc.Errorf(http.StatusInternalServerError, "invalid response format")
+			}
+			if len(readResp.Results) != len(readReq.Queries) {
+				return nil, httpgrpc.Errorf(http.StatusInternalServerError, "expected %d results in remote read response, got %d", len(readReq.Queries), len(readResp.Results))
+			}
+			for _, ts := range readResp.Results[i].Timeseries {
+				key := labelpb.ZLabelsToPromLabels(ts.Labels).String()
+				s, ok := seriesByLabels[key]
+				if !ok {
+					s = &prompb.TimeSeries{Labels: ts.Labels}
+					seriesByLabels[key] = s
+				}
+				s.Samples = append(s.Samples, ts.Samples...)
+				s.Histograms = append(s.Histograms, ts.Histograms...)
+			}
+		}
+
+		result := &prompb.QueryResult{Timeseries: make([]*prompb.TimeSeries, 0, len(seriesByLabels))}
+		for _, s := range seriesByLabels {
+			sort.SliceStable(s.Samples, func(i, j int) bool { return s.Samples[i].Timestamp < s.Samples[j].Timestamp })
+			s.Samples = slices.CompactFunc(s.Samples, func(a, b prompb.Sample) bool { return a.Timestamp == b.Timestamp })
+			sort.SliceStable(s.Histograms, func(i, j int) bool { return s.Histograms[i].Timestamp < s.Histograms[j].Timestamp })
+			s.Histograms = slices.CompactFunc(s.Histograms, func(a, b prompb.Histogram) bool { return a.Timestamp == b.Timestamp })
+			result.Timeseries = append(result.Timeseries, s)
+		}
+		sort.Slice(result.Timeseries, func(i, j int) bool {
+			return labels.Compare(labelpb.ZLabelsToPromLabels(result.Timeseries[i].Labels), labelpb.ZLabelsToPromLabels(result.Timeseries[j].Labels)) < 0
+		})
+		merged.Results[i] = result
+	}
+	return merged, nil
+}
+
+// remoteReadEmptyResponses replaces responses of other types, returned by middlewares skipping requests, e.g.
+// outside of the max query lookback, with empty results of every query of the request.
+func remoteReadEmptyResponses(codec *remoteReadCodec) queryrange.Middleware {
+	return queryrange.MiddlewareFunc(func(next queryrange.Handler) queryrange.Handler {
+		return queryrange.HandlerFunc(func(ctx context.Context, r queryrange.Request) (queryrange.Response, error) {
+			resp, err := next.Do(ctx, r)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := resp.(*ThanosRemoteReadResponse); ok {
+				return resp, nil
+			}
+			return codec.MergeResponse(r)
+		})
+	})
+}
+
+// remoteReadRoundTripper sends remote read requests which can't be answered with samples straight to next,
+// other requests are handled by the remote read tripperware.
+type remoteReadRoundTripper struct {
+	next, remoteRead http.RoundTripper
+}
+
+func (r remoteReadRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	_ = req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	readReq, err := decodeReadRequest(req.Clone(req.Context()))
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	if err == nil && !acceptsSamples(readReq) {
+		return r.next.RoundTrip(req)
+	}
+	return r.remoteRead.RoundTrip(req)
+}
@@ -0,0 +1,179 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/go-kit/log"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/weaveworks/common/user"
+
+	"github.com/thanos-io/thanos/internal/cortex/frontend/transport"
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/store/storepb/prompb"
+)
+
+func encodeReadRequest(t *testing.T, req *prompb.ReadRequest) *http.Request {
+	t.Helper()
+
+	b, err := proto.Marshal(req)
+	testutil.Ok(t, err)
+	httpReq, err := http.NewRequest(http.MethodPost, "/api/v1/read", bytes.NewReader(snappy.Encode(nil, b)))
+	testutil.Ok(t, err)
+	return httpReq.WithContext(user.InjectOrgID(context.Background(), "1"))
+}
+
+func decodeReadResponse(t *testing.T, resp *http.Response) *prompb.ReadResponse {
+	t.Helper()
+
+	compressed, err := io.ReadAll(resp.Body)
+	testutil.Ok(t, err)
+	b, err := snappy.Decode(nil, compressed)
+	testutil.Ok(t, err)
+	var readResp prompb.ReadResponse
+	testutil.Ok(t, proto.Unmarshal(b, &readResp))
+	return &readResp
+}
+
+// remoteReadResults returns a handler recording remote read requests and answering every query with samples
+// at its start and end, for the series up{query="<value of the first matcher>"}.
+func remoteReadResults() (*[]*prompb.ReadRequest, http.Handler) {
+	var (
+		lock sync.Mutex
+		reqs []*prompb.ReadRequest
+	)
+	return &reqs, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := decodeReadRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		lock.Lock()
+		reqs = append(reqs, req)
+		lock.Unlock()
+
+		var resp prompb.ReadResponse
+		for _, q := range req.Queries {
+			resp.Results = append(resp.Results, &prompb.QueryResult{Timeseries: []*prompb.TimeSeries{{
+				Labels:  []labelpb.ZLabel{{Name: "__name__", Value: "up"}, {Name: "query", Value: q.Matchers[0].Value}},
+				Samples: []prompb.Sample{{Value: 1, Timestamp: q.StartTimestampMs}, {Value: 1, Timestamp: q.EndTimestampMs}},
+			}}})
+		}
+		b, err := proto.Marshal(&resp)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		_, _ = w.Write(snappy.Encode(nil, b))
+	})
+}
+
+func TestRoundTripRemoteRead(t *testing.T) {
+	tpw, err := NewTripperware(
+		Config{
+			QueryRangeConfig: QueryRangeConfig{
+				Limits: defaultLimits,
+			},
+			RemoteReadConfig: RemoteReadConfig{
+				SplitQueriesByInterval: day,
+			},
+			CortexHandlerConfig: &transport.HandlerConfig{},
+		}, nil, log.NewNopLogger(),
+	)
+	testutil.Ok(t, err)
+
+	rt, err := newFakeRoundTripper()
+	testutil.Ok(t, err)
+	defer rt.Close()
+	reqs, handler := remoteReadResults()
+	rt.setHandler(handler)
+
+	query := func(name string, start, end int64) *prompb.Query {
+		return &prompb.Query{
+			StartTimestampMs: start,
+			EndTimestampMs:   end,
+			Matchers:         []*prompb.LabelMatcher{{Type: prompb.LabelMatcher_EQ, Name: "query", Value: name}},
+		}
+	}
+	resp, err := tpw(rt).RoundTrip(encodeReadRequest(t, &prompb.ReadRequest{Queries: []*prompb.Query{
+		query("a", 0, 3*day.Milliseconds()),
+		query("b", 2*day.Milliseconds()+hour, 2*day.Milliseconds()+2*hour),
+	}}))
+	testutil.Ok(t, err)
+	testutil.Equals(t, http.StatusOK, resp.StatusCode)
+
+	// Queries are split by day, query b is only sent with the last split.
+	testutil.Equals(t, 3, len(*reqs))
+	for _, req := range *reqs {
+		testutil.Equals(t, []prompb.ReadRequest_ResponseType{prompb.ReadRequest_SAMPLES}, req.AcceptedResponseTypes)
+		testutil.Assert(t, len(req.Queries) == 1 || req.Queries[0].StartTimestampMs == 2*day.Milliseconds(), "unexpected request %v", req)
+	}
+
+	readResp := decodeReadResponse(t, resp)
+	testutil.Equals(t, 2, len(readResp.Results))
+	testutil.Equals(t, 1, len(readResp.Results[0].Timeseries))
+	// Samples at the boundaries of splits are deduplicated.
+	testutil.Equals(t, []prompb.Sample{
+		{Value: 1, Timestamp: 0},
+		{Value: 1, Timestamp: day.Milliseconds()},
+		{Value: 1, Timestamp: 2 * day.Milliseconds()},
+		{Value: 1, Timestamp: 3 * day.Milliseconds()},
+	}, readResp.Results[0].Timeseries[0].Samples)
+	testutil.Equals(t, 1, len(readResp.Results[1].Timeseries))
+	testutil.Equals(t, []prompb.Sample{
+		{Value: 1, Timestamp: 2*day.Milliseconds() + hour},
+		{Value: 1, Timestamp: 2*day.Milliseconds() + 2*hour},
+	}, readResp.Results[1].Timeseries[0].Samples)
+
+	// Requests of streamed chunks are not split.
+	*reqs = nil
+	_, err = tpw(rt).RoundTrip(encodeReadRequest(t, &prompb.ReadRequest{
+		Queries:               []*prompb.Query{query("a", 0, 3*day.Milliseconds())},
+		AcceptedResponseTypes: []prompb.ReadRequest_ResponseType{prompb.ReadRequest_STREAMED_XOR_CHUNKS},
+	}))
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(*reqs))
+	testutil.Equals(t, []prompb.ReadRequest_ResponseType{prompb.ReadRequest_STREAMED_XOR_CHUNKS}, (*reqs)[0].AcceptedResponseTypes)
+}
+
+func TestRemoteReadMergeResponse(t *testing.T) {
+	series := func(name string, samples ...int64) *prompb.TimeSeries {
+		s := &prompb.TimeSeries{Labels: []labelpb.ZLabel{{Name: "__name__", Value: name}}}
+		for _, ts := range samples {
+			s.Samples = append(s.Samples, prompb.Sample{Value: float64(ts), Timestamp: ts})
+		}
+		return s
+	}
+	req := &ThanosRemoteReadRequest{Queries: []*prompb.Query{{}, {}}}
+
+	merged, err := NewThanosRemoteReadCodec().MergeResponse(req,
+		&ThanosRemoteReadResponse{Results: []*prompb.QueryResult{
+			{Timeseries: []*prompb.TimeSeries{series("b", 1, 2), series("a", 2)}},
+			{},
+		}},
+		&ThanosRemoteReadResponse{Results: []*prompb.QueryResult{
+			{Timeseries: []*prompb.TimeSeries{series("a", 0, 1)}},
+			{Timeseries: []*prompb.TimeSeries{series("c", 5)}},
+		}},
+	)
+	testutil.Ok(t, err)
+	testutil.Equals(t, &ThanosRemoteReadResponse{Results: []*prompb.QueryResult{
+		{Timeseries: []*prompb.TimeSeries{series("a", 0, 1, 2), series("b", 1, 2)}},
+		{Timeseries: []*prompb.TimeSeries{series("c", 5)}},
+	}}, merged)
+
+	// Empty responses, e.g. of requests outside the max query lookback, have a result for every query.
+	merged, err = NewThanosRemoteReadCodec().MergeResponse(req)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(merged.(*ThanosRemoteReadResponse).Results))
+}
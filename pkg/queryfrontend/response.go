@@ -13,7 +13,7 @@ import (
 type ThanosResponseExtractor struct{}
 
 // Extract extracts response for specific a range from a response.
-// This interface is not used for labels and series responses.
+// Labels and series responses have no timestamps, so they are returned as a whole.
 func (ThanosResponseExtractor) Extract(_, _ int64, resp queryrange.Response) queryrange.Response {
 	return resp
 }
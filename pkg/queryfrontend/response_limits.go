@@ -0,0 +1,183 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/thanos-io/thanos/internal/cortex/cortexpb"
+	"github.com/thanos-io/thanos/internal/cortex/querier/queryrange"
+	"github.com/thanos-io/thanos/internal/cortex/tenant"
+	"github.com/thanos-io/thanos/internal/cortex/util/validation"
+)
+
+const (
+	errResponseSizeLimit   = "the responses of the query exceed the limit of %d bytes; reduce the time range of the query or add label matchers to narrow down the selected series"
+	errResponseSeriesLimit = "the response of the query exceeds the limit of %d series; add label matchers or aggregations to reduce the number of series"
+)
+
+// errResponseTooLarge is returned by bodies of downstream responses read beyond the response size limit.
+var errResponseTooLarge = errors.New("response size limit exceeded")
+
+// ResponseLimits extends queryrange.Limits with limits on the responses of queries.
+type ResponseLimits interface {
+	queryrange.Limits
+
+	// MaxResponseSizeBytes returns the limit of the size of all downstream responses of a query, 0 disables the limit.
+	MaxResponseSizeBytes(string) int
+	// MaxResponseSeries returns the limit of series in the response of a query, 0 disables the limit.
+	MaxResponseSeries(string) int
+}
+
+// ResponseLimitsMiddleware creates a new Middleware enforcing the response limits of the tenant on the downstream
+// responses decoded to answer a query, including the responses of its split and sharded requests.
+func ResponseLimitsMiddleware(limits ResponseLimits) queryrange.Middleware {
+	return queryrange.MiddlewareFunc(func(next queryrange.Handler) queryrange.Handler {
+		return responseLimitsMiddleware{next: next, limits: limits}
+	})
+}
+
+type responseLimitsMiddleware struct {
+	next   queryrange.Handler
+	limits ResponseLimits
+}
+
+func (m responseLimitsMiddleware) Do(ctx context.Context, r queryrange.Request) (queryrange.Response, error) {
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, httpgrpc.Errorf(http.StatusBadRequest, "%s", err.Error())
+	}
+
+	l := &responseLimiter{
+		maxBytes:  validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, m.limits.MaxResponseSizeBytes),
+		maxSeries: validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, m.limits.MaxResponseSeries),
+	}
+	if l.maxBytes == 0 && l.maxSeries == 0 {
+		return m.next.Do(ctx, r)
+	}
+	if l.maxSeries > 0 {
+		l.series = map[uint64]struct{}{}
+	}
+	return m.next.Do(context.WithValue(ctx, responseLimiterCtxKey{}, l), r)
+}
+
+type responseLimiterCtxKey struct{}
+
+func responseLimiterFromContext(ctx context.Context) *responseLimiter {
+	l, _ := ctx.Value(responseLimiterCtxKey{}).(*responseLimiter)
+	return l
+}
+
+// responseLimiter accounts the downstream responses decoded to answer a query.
+type responseLimiter struct {
+	maxBytes, maxSeries int
+
+	mtx    sync.Mutex
+	bytes  int
+	series map[uint64]struct{}
+}
+
+func (l *responseLimiter) sizeExceeded(size int) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return l.maxBytes > 0 && l.bytes+size > l.maxBytes
+}
+
+// read accounts bytes read from a downstream response. It returns false once the size of all responses of the
+// query exceeds the limit.
+func (l *responseLimiter) read(n int) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.bytes += n
+	return l.maxBytes <= 0 || l.bytes <= l.maxBytes
+}
+
+// release removes bytes read from a downstream response which was not decoded, e.g. because reading it failed and
+// the request is retried, so that only the responses the query is answered with count towards the limit.
+func (l *responseLimiter) release(n int) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.bytes -= n
+}
+
+// addSeries accounts the series of a decoded response. Series are counted once, even if they are part of the
+// responses of several split requests.
+func (l *responseLimiter) addSeries(resp *queryrange.PrometheusResponse) error {
+	if l.maxSeries <= 0 {
+		return nil
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	for _, s := range resp.Data.Result {
+		l.series[cortexpb.FromLabelAdaptersToLabels(s.Labels).Hash()] = struct{}{}
+	}
+	if len(l.series) > l.maxSeries {
+		return httpgrpc.Errorf(http.StatusUnprocessableEntity, errResponseSeriesLimit, l.maxSeries)
+	}
+	return nil
+}
+
+// limitedBody accounts every read of a downstream response, so that responses decoded concurrently count towards
+// the limit while they are read. It fails once the size of all responses of the query exceeds the limit, without
+// reading the rest of the response into memory.
+type limitedBody struct {
+	io.ReadCloser
+
+	limiter  *responseLimiter
+	read     int
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += n
+	if !b.limiter.read(n) {
+		b.exceeded = true
+		return n, errResponseTooLarge
+	}
+	return n, err
+}
+
+// decodeLimitedResponse decodes a downstream response with the given codec, enforcing the response limits of the
+// query if any.
+func decodeLimitedResponse(ctx context.Context, codec queryrange.Codec, r *http.Response, req queryrange.Request) (queryrange.Response, error) {
+	l := responseLimiterFromContext(ctx)
+	if l == nil || r.StatusCode/100 != 2 {
+		return codec.DecodeResponse(ctx, r, req)
+	}
+	if r.ContentLength > 0 && l.sizeExceeded(int(r.ContentLength)) {
+		return nil, httpgrpc.Errorf(http.StatusUnprocessableEntity, errResponseSizeLimit, l.maxBytes)
+	}
+
+	body := &limitedBody{ReadCloser: r.Body, limiter: l}
+	limited := *r
+	limited.Body = body
+	resp, err := codec.DecodeResponse(ctx, &limited, req)
+	if body.exceeded {
+		return nil, httpgrpc.Errorf(http.StatusUnprocessableEntity, errResponseSizeLimit, l.maxBytes)
+	}
+	if err != nil {
+		// The failed attempt doesn't count towards the limit of its retries.
+		l.release(body.read)
+		return nil, err
+	}
+
+	if promResp, ok := resp.(*queryrange.PrometheusResponse); ok {
+		if err := l.addSeries(promResp); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,153 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/user"
+
+	"github.com/thanos-io/thanos/internal/cortex/frontend/transport"
+	"github.com/thanos-io/thanos/internal/cortex/querier/queryrange"
+	cortexvalidation "github.com/thanos-io/thanos/internal/cortex/util/validation"
+)
+
+func TestRoundTripResponseLimits(t *testing.T) {
+	rt, err := newFakeRoundTripper()
+	testutil.Ok(t, err)
+	defer rt.Close()
+	rt.setHandler(splitPromqlResults(3))
+
+	for _, tc := range []struct {
+		name         string
+		maxBytes     int
+		maxSeries    int
+		expectedCode int
+	}{
+		{name: "no limits"},
+		{name: "within limits", maxBytes: 10000, maxSeries: 3},
+		{name: "size limit exceeded", maxBytes: 500, expectedCode: http.StatusUnprocessableEntity},
+		// Series are counted once across splits.
+		{name: "series limit exceeded", maxSeries: 2, expectedCode: http.StatusUnprocessableEntity},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, incrementalMerge := range []bool{false, true} {
+				tpw, err := NewTripperware(
+					Config{
+						QueryRangeConfig: QueryRangeConfig{
+							Limits: &cortexvalidation.Limits{
+								MaxQueryLength:       model.Duration(7 * 24 * time.Hour),
+								MaxQueryParallelism:  14,
+								MaxResponseSizeBytes: tc.maxBytes,
+								MaxResponseSeries:    tc.maxSeries,
+							},
+							SplitQueriesByInterval: time.Hour,
+							IncrementalMerge:       incrementalMerge,
+							MaxRetries:             3,
+						},
+						CortexHandlerConfig: &transport.HandlerConfig{},
+					}, nil, log.NewNopLogger(),
+				)
+				testutil.Ok(t, err)
+
+				ctx := user.InjectOrgID(context.Background(), "1")
+				httpReq, err := NewThanosQueryRangeCodec(true).EncodeRequest(ctx, &ThanosQueryRangeRequest{
+					Path:  "/api/v1/query_range",
+					Start: 0,
+					End:   5 * hour,
+					Step:  10 * seconds,
+					Query: "foo",
+				})
+				testutil.Ok(t, err)
+
+				_, err = tpw(rt).RoundTrip(httpReq)
+				if tc.expectedCode == 0 {
+					testutil.Ok(t, err)
+					continue
+				}
+				testutil.NotOk(t, err)
+				resp, ok := httpgrpc.HTTPResponseFromError(err)
+				testutil.Assert(t, ok, "unexpected error %v", err)
+				testutil.Equals(t, int32(tc.expectedCode), resp.Code)
+			}
+		})
+	}
+}
+
+func TestResponseLimitsPerTenant(t *testing.T) {
+	defaults := cortexvalidation.Limits{MaxQueryParallelism: 14, MaxResponseSeries: 2}
+	tenantLimits, err := NewTenantLimits([]byte(`
+overrides:
+  big-tenant:
+    max_response_series: 10
+`), defaults)
+	testutil.Ok(t, err)
+	overrides, err := cortexvalidation.NewOverrides(defaults, tenantLimits)
+	testutil.Ok(t, err)
+
+	testutil.Equals(t, 2, overrides.MaxResponseSeries("tenant"))
+	testutil.Equals(t, 10, overrides.MaxResponseSeries("big-tenant"))
+}
+
+func TestDecodeLimitedResponse(t *testing.T) {
+	body := `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"a":"b"},"values":[[1,"1"],[2,"2"]]}]}}`
+	response := func() *http.Response {
+		// Streamed responses have no content length, they are limited while they are read.
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), ContentLength: -1}
+	}
+
+	l := &responseLimiter{maxBytes: 2 * len(body)}
+	ctx := context.WithValue(context.Background(), responseLimiterCtxKey{}, l)
+	for range 2 {
+		_, err := decodeLimitedResponse(ctx, queryrange.PrometheusCodec, response(), nil)
+		testutil.Ok(t, err)
+	}
+	testutil.Equals(t, 2*len(body), l.bytes)
+
+	_, err := decodeLimitedResponse(ctx, queryrange.PrometheusCodec, response(), nil)
+	resp, ok := httpgrpc.HTTPResponseFromError(err)
+	testutil.Assert(t, ok, "unexpected error %v", err)
+	testutil.Equals(t, int32(http.StatusUnprocessableEntity), resp.Code)
+
+	t.Run("responses being read", func(t *testing.T) {
+		l := &responseLimiter{maxBytes: len(body) + len(body)/2}
+		ctx := context.WithValue(context.Background(), responseLimiterCtxKey{}, l)
+
+		// Responses count towards the limit while they are read, before they are fully decoded.
+		reading := &limitedBody{ReadCloser: response().Body, limiter: l}
+		_, err := io.ReadAll(reading)
+		testutil.Ok(t, err)
+
+		_, err = decodeLimitedResponse(ctx, queryrange.PrometheusCodec, response(), nil)
+		resp, ok := httpgrpc.HTTPResponseFromError(err)
+		testutil.Assert(t, ok, "unexpected error %v", err)
+		testutil.Equals(t, int32(http.StatusUnprocessableEntity), resp.Code)
+	})
+	t.Run("failed attempts", func(t *testing.T) {
+		l := &responseLimiter{maxBytes: len(body) + len(body)/2}
+		ctx := context.WithValue(context.Background(), responseLimiterCtxKey{}, l)
+
+		// Responses failing while they are read are retried, their bytes don't count towards the limit.
+		failed := response()
+		failed.Body = io.NopCloser(io.MultiReader(strings.NewReader(body[:len(body)/2]), iotest.ErrReader(errors.New("connection reset"))))
+		_, err := decodeLimitedResponse(ctx, queryrange.PrometheusCodec, failed, nil)
+		testutil.NotOk(t, err)
+		testutil.Equals(t, 0, l.bytes)
+
+		_, err = decodeLimitedResponse(ctx, queryrange.PrometheusCodec, response(), nil)
+		testutil.Ok(t, err)
+		testutil.Equals(t, len(body), l.bytes)
+	})
+}
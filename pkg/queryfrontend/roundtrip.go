@@ -29,6 +29,9 @@ const (
 	labelNamesOp   = "label_names"
 	labelValuesOp  = "label_values"
 	seriesOp       = "series"
+	remoteReadOp   = "remote_read"
+	formatQueryOp  = "format_query"
+	parseQueryOp   = "parse_query"
 )
 
 var labelValuesPattern = regexp.MustCompile("/api/v1/label/.+/values$")
@@ -36,14 +39,16 @@ var labelValuesPattern = regexp.MustCompile("/api/v1/label/.+/values$")
 // NewTripperware returns a Tripperware which sends requests to different sub tripperwares based on the query type.
 func NewTripperware(config Config, reg prometheus.Registerer, logger log.Logger) (queryrange.Tripperware, error) {
 	var (
-		queryRangeLimits, labelsLimits queryrange.Limits
-		err                            error
+		queryRangeLimits, labelsLimits, queryInstantLimits queryrange.Limits
+		costLimits                                         CostLimits
+		err                                                error
 	)
 	if config.QueryRangeConfig.Limits != nil {
-		queryRangeLimits, err = validation.NewOverrides(*config.QueryRangeConfig.Limits, nil)
+		overrides, err := validation.NewOverrides(*config.QueryRangeConfig.Limits, config.TenantLimits)
 		if err != nil {
 			return nil, errors.Wrap(err, "initialize query range limits")
 		}
+		queryRangeLimits, costLimits = overrides, overrides
 	}
 
 	if config.LabelsConfig.Limits != nil {
@@ -53,41 +58,71 @@ func NewTripperware(config Config, reg prometheus.Registerer, logger log.Logger)
 		}
 	}
 
+	if config.QueryInstantConfig.Limits != nil {
+		queryInstantLimits, err = validation.NewOverrides(*config.QueryInstantConfig.Limits, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "initialize query instant limits")
+		}
+	} else {
+		queryInstantLimits = queryRangeLimits
+	}
+
 	queryRangeCodec := NewThanosQueryRangeCodec(config.QueryRangeConfig.PartialResponseStrategy)
+	queryRangeCodec.incrementalMerge = config.QueryRangeConfig.IncrementalMerge
 	labelsCodec := NewThanosLabelsCodec(config.LabelsConfig.PartialResponseStrategy, config.DefaultTimeRange)
 	queryInstantCodec := NewThanosQueryInstantCodec(config.QueryRangeConfig.PartialResponseStrategy)
+	remoteReadCodec := NewThanosRemoteReadCodec()
 
+	queryRangeReg := prometheus.WrapRegistererWith(prometheus.Labels{"tripperware": "query_range"}, reg)
 	queryRangeTripperware, err := newQueryRangeTripperware(
 		config.QueryRangeConfig,
 		queryRangeLimits,
 		queryRangeCodec,
 		config.NumShards,
 		config.CortexHandlerConfig.QueryStatsEnabled,
-		prometheus.WrapRegistererWith(prometheus.Labels{"tripperware": "query_range"}, reg), logger, config.ForwardHeaders)
+		config.QueryRules,
+		costEstimationMiddleware(costLimits, config.CostEstimationConfig, config.QueryRangeConfig.DownsamplingResolutions, logger, queryRangeReg),
+		config.CacheGenerations,
+		queryRangeReg, logger, config.ForwardHeaders)
 	if err != nil {
 		return nil, err
 	}
 
-	labelsTripperware, err := newLabelsTripperware(config.LabelsConfig, labelsLimits, labelsCodec,
+	labelsTripperware, err := newLabelsTripperware(config.LabelsConfig, labelsLimits, labelsCodec, config.QueryRules, config.CacheGenerations,
 		prometheus.WrapRegistererWith(prometheus.Labels{"tripperware": "labels"}, reg), logger, config.ForwardHeaders)
 	if err != nil {
 		return nil, err
 	}
-	queryInstantTripperware := newInstantQueryTripperware(
+	queryInstantReg := prometheus.WrapRegistererWith(prometheus.Labels{"tripperware": "query_instant"}, reg)
+	queryInstantTripperware, err := newInstantQueryTripperware(
 		config.NumShards,
 		queryRangeLimits,
+		queryInstantLimits,
 		queryInstantCodec,
-		prometheus.WrapRegistererWith(prometheus.Labels{"tripperware": "query_instant"}, reg),
+		queryInstantReg,
+		logger,
 		config.ForwardHeaders,
 		config.CortexHandlerConfig.QueryStatsEnabled,
 		config.QueryInstantConfig,
+		config.QueryRangeConfig.DownsamplingResolutions,
+		config.QueryRules,
+		costEstimationMiddleware(costLimits, config.CostEstimationConfig, config.QueryRangeConfig.DownsamplingResolutions, logger, queryInstantReg),
+		config.CacheGenerations,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteReadTripperware := newRemoteReadTripperware(config.RemoteReadConfig, queryRangeLimits, remoteReadCodec,
+		prometheus.WrapRegistererWith(prometheus.Labels{"tripperware": "remote_read"}, reg), logger, config.ForwardHeaders)
+
 	return func(next http.RoundTripper) http.RoundTripper {
 		tripper := newRoundTripper(
 			next,
 			queryRangeTripperware(next),
 			labelsTripperware(next),
 			queryInstantTripperware(next),
+			remoteReadRoundTripper{next: next, remoteRead: remoteReadTripperware(next)},
 			reg,
 		)
 		return tenancy.InternalTenancyConversionTripper(config.TenantHeader, config.TenantCertField, tripper)
@@ -95,17 +130,19 @@ func NewTripperware(config Config, reg prometheus.Registerer, logger log.Logger)
 }
 
 type roundTripper struct {
-	next, queryInstant, queryRange, labels http.RoundTripper
+	next, queryInstant, queryRange, labels, remoteRead, promQL http.RoundTripper
 
 	queriesCount *prometheus.CounterVec
 }
 
-func newRoundTripper(next, queryRange, metadata, queryInstant http.RoundTripper, reg prometheus.Registerer) roundTripper {
+func newRoundTripper(next, queryRange, metadata, queryInstant, remoteRead http.RoundTripper, reg prometheus.Registerer) roundTripper {
 	r := roundTripper{
 		next:         next,
 		queryInstant: queryInstant,
 		queryRange:   queryRange,
 		labels:       metadata,
+		remoteRead:   remoteRead,
+		promQL:       promQLRoundTripper{},
 		queriesCount: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
 			Name: "thanos_query_frontend_queries_total",
 			Help: "Total queries passing through query frontend",
@@ -117,6 +154,9 @@ func newRoundTripper(next, queryRange, metadata, queryInstant http.RoundTripper,
 	r.queriesCount.WithLabelValues(labelNamesOp)
 	r.queriesCount.WithLabelValues(labelValuesOp)
 	r.queriesCount.WithLabelValues(seriesOp)
+	r.queriesCount.WithLabelValues(remoteReadOp)
+	r.queriesCount.WithLabelValues(formatQueryOp)
+	r.queriesCount.WithLabelValues(parseQueryOp)
 	return r
 }
 
@@ -131,6 +171,12 @@ func (r roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	case labelNamesOp, labelValuesOp, seriesOp:
 		r.queriesCount.WithLabelValues(op).Inc()
 		return r.labels.RoundTrip(req)
+	case remoteReadOp:
+		r.queriesCount.WithLabelValues(remoteReadOp).Inc()
+		return r.remoteRead.RoundTrip(req)
+	case formatQueryOp, parseQueryOp:
+		r.queriesCount.WithLabelValues(op).Inc()
+		return r.promQL.RoundTrip(req)
 	default:
 	}
 
@@ -148,6 +194,12 @@ func getOperation(r *http.Request) string {
 			return labelNamesOp
 		case strings.HasSuffix(r.URL.Path, "/api/v1/series"):
 			return seriesOp
+		case strings.HasSuffix(r.URL.Path, "/api/v1/format_query"):
+			return formatQueryOp
+		case strings.HasSuffix(r.URL.Path, "/api/v1/parse_query"):
+			return parseQueryOp
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/api/v1/read"):
+			return remoteReadOp
 		default:
 			if labelValuesPattern.MatchString(r.URL.Path) {
 				return labelValuesOp
@@ -159,19 +211,34 @@ func getOperation(r *http.Request) string {
 }
 
 // newQueryRangeTripperware returns a Tripperware for range queries configured with middlewares of
-// limit, step align, downsampled, split by interval, cache requests and retry.
+// query rules, limit, cost estimation, step align, downsampled, split by interval, cache requests and retry.
 func newQueryRangeTripperware(
 	config QueryRangeConfig,
 	limits queryrange.Limits,
 	codec *queryRangeCodec,
 	numShards int,
 	forceStats bool,
+	rules *QueryRules,
+	costEstimation queryrange.Middleware,
+	cacheGenerations *CacheGenerations,
 	reg prometheus.Registerer,
 	logger log.Logger,
 	forwardHeaders []string,
 ) (queryrange.Tripperware, error) {
-	queryRangeMiddleware := []queryrange.Middleware{queryrange.NewLimitsMiddleware(limits)}
 	m := queryrange.NewInstrumentMiddlewareMetrics(reg)
+	queryRangeMiddleware := queryRulesMiddlewares(rules, m)
+	queryRangeMiddleware = append(queryRangeMiddleware, queryrange.NewLimitsMiddleware(limits))
+	if responseLimits, ok := limits.(ResponseLimits); ok {
+		queryRangeMiddleware = append(queryRangeMiddleware, ResponseLimitsMiddleware(responseLimits))
+	}
+
+	if costEstimation != nil {
+		queryRangeMiddleware = append(
+			queryRangeMiddleware,
+			queryrange.InstrumentMiddleware("cost_estimation", m),
+			costEstimation,
+		)
+	}
 
 	queryRangeMiddleware = append(
 		queryRangeMiddleware,
@@ -191,7 +258,7 @@ func newQueryRangeTripperware(
 		queryRangeMiddleware = append(
 			queryRangeMiddleware,
 			queryrange.InstrumentMiddleware("downsampled", m),
-			DownsampledMiddleware(codec, reg),
+			DownsampledMiddleware(codec, config.DownsamplingResolutions, reg),
 		)
 	}
 
@@ -217,7 +284,7 @@ func newQueryRangeTripperware(
 		queryCacheMiddleware, _, err := queryrange.NewResultsCacheMiddleware(
 			logger,
 			*config.ResultsCacheConfig,
-			newThanosCacheKeyGenerator(),
+			newThanosCacheKeyGenerator(config.DownsamplingResolutions, cacheGenerations),
 			limits,
 			codec,
 			queryrange.PrometheusResponseExtractor{},
@@ -275,17 +342,19 @@ func dynamicIntervalFn(config QueryRangeConfig) queryrange.IntervalFn {
 }
 
 // newLabelsTripperware returns a Tripperware for labels and series requests
-// configured with middlewares of split by interval and retry.
+// configured with middlewares of query rules, split by interval and retry.
 func newLabelsTripperware(
 	config LabelsConfig,
 	limits queryrange.Limits,
 	codec *labelsCodec,
+	rules *QueryRules,
+	cacheGenerations *CacheGenerations,
 	reg prometheus.Registerer,
 	logger log.Logger,
 	forwardHeaders []string,
 ) (queryrange.Tripperware, error) {
-	labelsMiddleware := []queryrange.Middleware{}
 	m := queryrange.NewInstrumentMiddlewareMetrics(reg)
+	labelsMiddleware := queryRulesMiddlewares(rules, m)
 
 	queryIntervalFn := func(_ queryrange.Request) time.Duration {
 		return config.SplitQueriesByInterval
@@ -303,7 +372,7 @@ func newLabelsTripperware(
 		queryCacheMiddleware, _, err := queryrange.NewResultsCacheMiddleware(
 			logger,
 			*config.ResultsCacheConfig,
-			newThanosCacheKeyGenerator(),
+			newThanosCacheKeyGenerator(nil, cacheGenerations),
 			limits,
 			codec,
 			ThanosResponseExtractor{},
@@ -337,19 +406,84 @@ func newLabelsTripperware(
 	}, nil
 }
 
+// newRemoteReadTripperware returns a Tripperware for remote read requests configured with middlewares of
+// limit, split by interval and retry.
+func newRemoteReadTripperware(
+	config RemoteReadConfig,
+	limits queryrange.Limits,
+	codec *remoteReadCodec,
+	reg prometheus.Registerer,
+	logger log.Logger,
+	forwardHeaders []string,
+) queryrange.Tripperware {
+	m := queryrange.NewInstrumentMiddlewareMetrics(reg)
+	remoteReadMiddleware := []queryrange.Middleware{
+		remoteReadEmptyResponses(codec),
+		queryrange.NewLimitsMiddleware(limits),
+	}
+
+	if config.SplitQueriesByInterval != 0 {
+		queryIntervalFn := func(_ queryrange.Request) time.Duration {
+			return config.SplitQueriesByInterval
+		}
+
+		remoteReadMiddleware = append(
+			remoteReadMiddleware,
+			queryrange.InstrumentMiddleware("split_interval", m),
+			SplitByIntervalMiddleware(queryIntervalFn, limits, codec, reg),
+		)
+	}
+
+	if config.MaxRetries > 0 {
+		remoteReadMiddleware = append(
+			remoteReadMiddleware,
+			queryrange.InstrumentMiddleware("retry", m),
+			queryrange.NewRetryMiddleware(logger, config.MaxRetries, queryrange.NewRetryMiddlewareMetrics(reg)),
+		)
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		rt := queryrange.NewRoundTripper(next, codec, forwardHeaders, remoteReadMiddleware...)
+		return queryrange.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			return rt.RoundTrip(r)
+		})
+	}
+}
+
+// newInstantQueryTripperware returns a Tripperware for instant queries configured with middlewares of
+// query rules, cost estimation, split by interval, sharding, cache requests and retry.
 func newInstantQueryTripperware(
 	numShards int,
 	limits queryrange.Limits,
+	cacheLimits queryrange.Limits,
 	codec queryrange.Codec,
 	reg prometheus.Registerer,
+	logger log.Logger,
 	forwardHeaders []string,
 	forceStats bool,
 	instantQueryConfig QueryInstantConfig,
-) queryrange.Tripperware {
-	var instantQueryMiddlewares []queryrange.Middleware
+	downsamplingResolutions []int64,
+	rules *QueryRules,
+	costEstimation queryrange.Middleware,
+	cacheGenerations *CacheGenerations,
+) (queryrange.Tripperware, error) {
 	m := queryrange.NewInstrumentMiddlewareMetrics(reg)
+	instantQueryMiddlewares := queryRulesMiddlewares(rules, m)
+	if costEstimation != nil {
+		instantQueryMiddlewares = append(
+			instantQueryMiddlewares,
+			queryrange.InstrumentMiddleware("cost_estimation", m),
+			costEstimation,
+		)
+	}
+	analyzer := querysharding.NewQueryAnalyzer()
+	if instantQueryConfig.SplitInterval > 0 {
+		instantQueryMiddlewares = append(
+			instantQueryMiddlewares,
+			queryrange.InstrumentMiddleware("split_interval", m),
+			SplitInstantQueryMiddleware(analyzer, instantQueryConfig.SplitInterval, limits, reg),
+		)
+	}
 	if numShards > 0 {
-		analyzer := querysharding.NewQueryAnalyzer()
 		instantQueryMiddlewares = append(
 			instantQueryMiddlewares,
 			queryrange.InstrumentMiddleware("sharding", m),
@@ -362,6 +496,26 @@ func newInstantQueryTripperware(
 		queryrange.NewStatsMiddleware(forceStats),
 	)
 
+	if instantQueryConfig.ResultsCacheConfig != nil {
+		queryCacheMiddleware, err := newInstantQueryCacheMiddleware(
+			logger,
+			*instantQueryConfig.ResultsCacheConfig,
+			newThanosCacheKeyGenerator(downsamplingResolutions, cacheGenerations),
+			cacheLimits,
+			instantQueryConfig.CacheTimeRoundingStep,
+			reg,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "create results cache middleware")
+		}
+
+		instantQueryMiddlewares = append(
+			instantQueryMiddlewares,
+			queryrange.InstrumentMiddleware("results_cache", m),
+			queryCacheMiddleware,
+		)
+	}
+
 	if instantQueryConfig.MaxRetries > 0 {
 		instantQueryMiddlewares = append(
 			instantQueryMiddlewares,
@@ -375,7 +529,26 @@ func newInstantQueryTripperware(
 		return queryrange.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
 			return rt.RoundTrip(r)
 		})
+	}, nil
+}
+
+// queryRulesMiddlewares returns the middlewares applying query rules, if any.
+func queryRulesMiddlewares(rules *QueryRules, m *queryrange.InstrumentMiddlewareMetrics) []queryrange.Middleware {
+	if rules == nil {
+		return nil
+	}
+	return []queryrange.Middleware{
+		queryrange.InstrumentMiddleware("query_rules", m),
+		QueryRulesMiddleware(rules),
+	}
+}
+
+// costEstimationMiddleware returns the middleware estimating the cost of queries, nil if cost estimation is disabled.
+func costEstimationMiddleware(limits CostLimits, config CostEstimationConfig, resolutions []int64, logger log.Logger, reg prometheus.Registerer) queryrange.Middleware {
+	if limits == nil || config.CardinalityStats == nil {
+		return nil
 	}
+	return CostEstimationMiddleware(limits, config, resolutions, logger, reg)
 }
 
 // shouldCache controls what kind of Thanos request should be cached.
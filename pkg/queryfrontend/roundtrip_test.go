@@ -26,6 +26,7 @@ import (
 	"github.com/thanos-io/thanos/internal/cortex/cortexpb"
 	"github.com/thanos-io/thanos/internal/cortex/frontend/transport"
 	"github.com/thanos-io/thanos/internal/cortex/querier/queryrange"
+	cortexutil "github.com/thanos-io/thanos/internal/cortex/util"
 	cortexvalidation "github.com/thanos-io/thanos/internal/cortex/util/validation"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 )
@@ -771,6 +772,74 @@ func TestRoundTripLabelsCacheMiddleware(t *testing.T) {
 	}
 }
 
+// TestRoundTripLabelsCacheSlidingWindow tests that requests of a sliding time range, like dashboard variables,
+// only fetch the latest, uncached interval.
+func TestRoundTripLabelsCacheSlidingWindow(t *testing.T) {
+	cacheConf := &queryrange.ResultsCacheConfig{
+		CacheConfig: cortexcache.Config{
+			EnableFifoCache: true,
+			Fifocache: cortexcache.FifoCacheConfig{
+				MaxSizeBytes: "1MiB",
+				MaxSizeItems: 1000,
+				Validity:     time.Hour,
+			},
+		},
+	}
+
+	tpw, err := NewTripperware(
+		Config{
+			LabelsConfig: LabelsConfig{
+				Limits:                 defaultLimits,
+				ResultsCacheConfig:     cacheConf,
+				SplitQueriesByInterval: day,
+			},
+			CortexHandlerConfig: &transport.HandlerConfig{},
+		}, nil, log.NewNopLogger(),
+	)
+	testutil.Ok(t, err)
+
+	rt, err := newFakeRoundTripper()
+	testutil.Ok(t, err)
+	defer rt.Close()
+	res, handler := labelsResults(false)
+	rt.setHandler(handler)
+
+	for _, tc := range []struct {
+		name     string
+		req      queryrange.Request
+		expected int
+	}{
+		{
+			name:     "first request split by day",
+			req:      &ThanosLabelsRequest{Path: "/api/v1/label/foo/values", Label: "foo", Start: 10 * hour, End: 3*day.Milliseconds() + 10*hour},
+			expected: 4,
+		},
+		{
+			name:     "window moved by a minute, only the latest minute is fetched",
+			req:      &ThanosLabelsRequest{Path: "/api/v1/label/foo/values", Label: "foo", Start: 10*hour + 60*seconds, End: 3*day.Milliseconds() + 10*hour + 60*seconds},
+			expected: 5,
+		},
+		{
+			name:     "same window, use cache",
+			req:      &ThanosLabelsRequest{Path: "/api/v1/label/foo/values", Label: "foo", Start: 10*hour + 60*seconds, End: 3*day.Milliseconds() + 10*hour + 60*seconds},
+			expected: 5,
+		},
+	} {
+		if !t.Run(tc.name, func(t *testing.T) {
+			ctx := user.InjectOrgID(context.Background(), "1")
+			httpReq, err := NewThanosLabelsCodec(true, 24*time.Hour).EncodeRequest(ctx, tc.req)
+			testutil.Ok(t, err)
+
+			_, err = tpw(rt).RoundTrip(httpReq)
+			testutil.Ok(t, err)
+
+			testutil.Equals(t, tc.expected, *res)
+		}) {
+			break
+		}
+	}
+}
+
 // TestRoundTripSeriesCacheMiddleware tests the cache middleware for series requests.
 func TestRoundTripSeriesCacheMiddleware(t *testing.T) {
 	testRequest := &ThanosSeriesRequest{
@@ -863,6 +932,137 @@ func TestRoundTripSeriesCacheMiddleware(t *testing.T) {
 	}
 }
 
+func TestRoundTripQueryInstantCacheMiddleware(t *testing.T) {
+	// Evaluation times are relative to now, as only queries older than the max cache freshness are cached.
+	base := (time.Now().Add(-time.Hour).UnixMilli() / (5 * 60 * seconds)) * (5 * 60 * seconds)
+	recent := time.Now().UnixMilli()
+	newRequest := func(query string, evalTime int64) *ThanosQueryInstantRequest {
+		return &ThanosQueryInstantRequest{
+			Path:  "/api/v1/query",
+			Time:  evalTime,
+			Dedup: true,
+			Query: query,
+		}
+	}
+
+	testRequestWithoutDedup := newRequest("foo", base)
+	testRequestWithoutDedup.Dedup = false
+
+	testRequestWithStoreMatchers := newRequest("foo", base)
+	testRequestWithStoreMatchers.StoreMatchers = [][]*labels.Matcher{{labels.MustNewMatcher(labels.MatchEqual, "foo", "bar")}}
+
+	testRequestHigherLevelDownsampling := newRequest("foo", base)
+	testRequestHigherLevelDownsampling.MaxSourceResolution = 1 * hour
+
+	cacheConf := &queryrange.ResultsCacheConfig{
+		CacheConfig: cortexcache.Config{
+			EnableFifoCache: true,
+			Fifocache: cortexcache.FifoCacheConfig{
+				MaxSizeBytes: "1MiB",
+				MaxSizeItems: 1000,
+				Validity:     time.Hour,
+			},
+		},
+	}
+
+	tpw, err := NewTripperware(
+		Config{
+			CortexHandlerConfig: &transport.HandlerConfig{},
+			QueryInstantConfig: QueryInstantConfig{
+				Limits:                defaultLimits,
+				ResultsCacheConfig:    cacheConf,
+				CacheTimeRoundingStep: 5 * time.Minute,
+			},
+		}, nil, log.NewNopLogger(),
+	)
+	testutil.Ok(t, err)
+
+	rt, err := newFakeRoundTripper()
+	testutil.Ok(t, err)
+	defer rt.Close()
+	res, times, handler := promqlInstantResults()
+	rt.setHandler(handler)
+
+	for _, tc := range []struct {
+		name     string
+		req      *ThanosQueryInstantRequest
+		tenant   string
+		expected int
+		// expTime is the evaluation time received by the downstream, if checked.
+		expTime int64
+	}{
+		{name: "first request", req: newRequest("foo", base), expected: 1, expTime: base},
+		{name: "same request as the first one, directly use cache", req: newRequest("foo", base), expected: 1},
+		{name: "evaluation time rounded to the same step, use cache", req: newRequest("foo", base+2*60*seconds), expected: 1},
+		{name: "evaluation time rounded to the next step", req: newRequest("foo", base+5*60*seconds+1), expected: 2, expTime: base + 5*60*seconds},
+		{name: "different tenant", req: newRequest("foo", base), tenant: "2", expected: 3},
+		{name: "dedup disabled, should not use cache", req: testRequestWithoutDedup, expected: 4},
+		{name: "dedup disabled, should not use cache again", req: testRequestWithoutDedup, expected: 5},
+		{name: "storeMatchers requests won't go to cache", req: testRequestWithStoreMatchers, expected: 6},
+		{name: "different downsampling level", req: testRequestHigherLevelDownsampling, expected: 7},
+		{name: "different downsampling level, use cache", req: testRequestHigherLevelDownsampling, expected: 7},
+		// Recent queries are not cached and are evaluated at the requested time, not rounded.
+		{name: "recent evaluation time", req: newRequest("foo", recent), expected: 8, expTime: recent},
+		{name: "recent evaluation time, not cached", req: newRequest("foo", recent), expected: 9, expTime: recent},
+		{name: "no evaluation time", req: newRequest("foo", 0), expected: 10},
+		{name: "no evaluation time, not cached", req: newRequest("foo", 0), expected: 11},
+		{name: "negative offset", req: newRequest("foo offset -1h", base), expected: 12},
+		{name: "negative offset, not cached", req: newRequest("foo offset -1h", base), expected: 13},
+		{name: "@ modifier after evaluation time", req: newRequest(fmt.Sprintf("foo @ %d", base/seconds+60), base), expected: 14},
+		{name: "@ modifier after evaluation time, not cached", req: newRequest(fmt.Sprintf("foo @ %d", base/seconds+60), base), expected: 15},
+		{name: "@ modifier before evaluation time", req: newRequest(fmt.Sprintf("foo @ %d", base/seconds-60), base), expected: 16},
+		{name: "@ modifier before evaluation time, use cache", req: newRequest(fmt.Sprintf("foo @ %d", base/seconds-60), base), expected: 16},
+	} {
+		if !t.Run(tc.name, func(t *testing.T) {
+			tenant := tc.tenant
+			if tenant == "" {
+				tenant = "1"
+			}
+			ctx := user.InjectOrgID(context.Background(), tenant)
+			httpReq, err := NewThanosQueryInstantCodec(true).EncodeRequest(ctx, tc.req)
+			testutil.Ok(t, err)
+
+			resp, err := tpw(rt).RoundTrip(httpReq)
+			testutil.Ok(t, err)
+			testutil.Equals(t, http.StatusOK, resp.StatusCode)
+
+			testutil.Equals(t, tc.expected, *res)
+			if tc.expTime != 0 {
+				testutil.Equals(t, tc.expTime, (*times)[len(*times)-1])
+			}
+		}) {
+			break
+		}
+	}
+}
+
+// promqlInstantResults is a mock handler used to test instant query cache middleware.
+// It records the evaluation time of received requests, 0 if not set.
+func promqlInstantResults() (*int, *[]int64, http.Handler) {
+	count := 0
+	var times []int64
+	var lock sync.Mutex
+	return &count, &times, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		var evalTime int64
+		if v := r.FormValue("time"); v != "" {
+			ts, err := cortexutil.ParseTime(v)
+			if err != nil {
+				panic(err)
+			}
+			evalTime = ts
+		}
+		times = append(times, evalTime)
+
+		if _, err := w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"foo"},"value":[1,"1"]}]}}`)); err != nil {
+			panic(err)
+		}
+		count++
+	})
+}
+
 // promqlResults is a mock handler used to test split and cache middleware.
 // Modified from Loki https://github.com/grafana/loki/blob/master/pkg/querier/queryrange/roundtrip_test.go#L547.
 func promqlResults(fail bool) (*int, http.Handler) {
@@ -0,0 +1,405 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/weaveworks/common/httpgrpc"
+	"gopkg.in/yaml.v2"
+
+	"github.com/thanos-io/thanos/internal/cortex/querier/queryrange"
+	"github.com/thanos-io/thanos/internal/cortex/tenant"
+	"github.com/thanos-io/thanos/pkg/extkingpin"
+	"github.com/thanos-io/thanos/pkg/extpromql"
+)
+
+// QueryRuleAction is the action taken on requests matching a query rule.
+type QueryRuleAction string
+
+const (
+	// QueryRuleReject rejects matching requests.
+	QueryRuleReject QueryRuleAction = "reject"
+	// QueryRuleThrottle limits the number of concurrent matching requests, further requests are rejected.
+	QueryRuleThrottle QueryRuleAction = "throttle"
+	// QueryRuleRewrite rewrites matching requests.
+	QueryRuleRewrite QueryRuleAction = "rewrite"
+)
+
+// QueryRulesConfig is the configuration of query rules, applied in order to every request.
+type QueryRulesConfig struct {
+	Rules []QueryRuleConfig `yaml:"rules"`
+}
+
+// QueryRuleConfig configures a single query rule. A request matches the rule if it matches all its conditions.
+type QueryRuleConfig struct {
+	Name   string          `yaml:"name"`
+	Action QueryRuleAction `yaml:"action"`
+
+	// QueryRegex matches the PromQL query of range and instant query requests.
+	QueryRegex string `yaml:"query_regex"`
+	// Matchers are series selectors, one of which has to be part of the request. A selector is part of the request
+	// if a selector of the query, or a matcher set of a labels or series request, contains all its matchers.
+	Matchers []string `yaml:"matchers"`
+	// Tenants the rule applies to, all tenants if empty.
+	Tenants []string `yaml:"tenants"`
+	// MinRange is the minimum estimated range of the request, which is the range of the request
+	// plus the longest range selected by the query.
+	MinRange model.Duration `yaml:"min_range"`
+
+	// MaxConcurrency is the maximum number of concurrent requests matching a throttle rule.
+	MaxConcurrency int `yaml:"max_concurrency"`
+	// Rewrite configures how requests matching a rewrite rule are changed.
+	Rewrite QueryRewriteConfig `yaml:"rewrite"`
+	// Message is returned to the client when a request is rejected or throttled.
+	Message string `yaml:"message"`
+}
+
+// QueryRewriteConfig configures rewriting of requests.
+type QueryRewriteConfig struct {
+	// Replacement replaces matches of the rule's query_regex in the query, with $1 style references to its groups.
+	Replacement *string `yaml:"replacement"`
+	// MaxSourceResolution forces the max source resolution of queries to at least this resolution.
+	MaxSourceResolution model.Duration `yaml:"max_source_resolution"`
+}
+
+type queryRule struct {
+	QueryRuleConfig
+
+	queryRegex *regexp.Regexp
+	matchers   [][]*labels.Matcher
+	// throttle holds a token for every request being executed for throttle rules.
+	throttle chan struct{}
+}
+
+func compileQueryRules(conf QueryRulesConfig) ([]*queryRule, error) {
+	names := map[string]struct{}{}
+	rules := make([]*queryRule, 0, len(conf.Rules))
+	for _, c := range conf.Rules {
+		if c.Name == "" {
+			return nil, errors.New("query rule without name")
+		}
+		if _, ok := names[c.Name]; ok {
+			return nil, errors.Errorf("duplicated query rule name %q", c.Name)
+		}
+		names[c.Name] = struct{}{}
+
+		r := &queryRule{QueryRuleConfig: c}
+		if c.QueryRegex != "" {
+			re, err := regexp.Compile(c.QueryRegex)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse query regex of rule %q", c.Name)
+			}
+			r.queryRegex = re
+		}
+		for _, s := range c.Matchers {
+			ms, err := extpromql.ParseMetricSelector(s)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse matchers of rule %q", c.Name)
+			}
+			r.matchers = append(r.matchers, ms)
+		}
+
+		switch c.Action {
+		case QueryRuleReject:
+		case QueryRuleThrottle:
+			if c.MaxConcurrency <= 0 {
+				return nil, errors.Errorf("max_concurrency of throttle rule %q has to be greater than 0", c.Name)
+			}
+			r.throttle = make(chan struct{}, c.MaxConcurrency)
+		case QueryRuleRewrite:
+			if c.Rewrite.Replacement != nil && r.queryRegex == nil {
+				return nil, errors.Errorf("rewrite rule %q with replacement requires query_regex", c.Name)
+			}
+			if c.Rewrite.Replacement == nil && c.Rewrite.MaxSourceResolution == 0 {
+				return nil, errors.Errorf("rewrite rule %q does not rewrite anything", c.Name)
+			}
+		default:
+			return nil, errors.Errorf("unknown action %q of rule %q", c.Action, c.Name)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// ruleRequest holds the properties of a request that rules are matched against.
+type ruleRequest struct {
+	tenants  []string
+	query    string
+	expr     parser.Expr
+	matchers [][]*labels.Matcher
+	// estimatedRange is the range of the request plus the longest range selected by the query, in milliseconds.
+	estimatedRange int64
+}
+
+func newRuleRequest(tenants []string, r queryrange.Request) ruleRequest {
+	req := ruleRequest{tenants: tenants}
+	switch tr := r.(type) {
+	case *ThanosQueryRangeRequest:
+		req.query = tr.Query
+		req.estimatedRange = tr.End - tr.Start
+	case *ThanosQueryInstantRequest:
+		req.query = tr.Query
+	case *ThanosLabelsRequest:
+		req.matchers = tr.Matchers
+		req.estimatedRange = tr.End - tr.Start
+	case *ThanosSeriesRequest:
+		req.matchers = tr.Matchers
+		req.estimatedRange = tr.End - tr.Start
+	}
+	if req.query == "" {
+		return req
+	}
+
+	// Queries which can't be parsed are not matched by selectors or ranges, they will fail downstream.
+	expr, err := extpromql.ParseExpr(req.query)
+	if err != nil {
+		return req
+	}
+	req.expr = expr
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		if vs, ok := node.(*parser.VectorSelector); ok {
+			req.matchers = append(req.matchers, vs.LabelMatchers)
+		}
+		return nil
+	})
+	req.estimatedRange += selectedRange(expr)
+	return req
+}
+
+func (r *queryRule) matches(req ruleRequest) bool {
+	if len(r.Tenants) > 0 && !slices.ContainsFunc(req.tenants, func(t string) bool { return slices.Contains(r.Tenants, t) }) {
+		return false
+	}
+	if r.queryRegex != nil && (req.query == "" || !r.queryRegex.MatchString(req.query)) {
+		return false
+	}
+	// Ranges of queries which can't be parsed are unknown.
+	if r.MinRange > 0 && ((req.query != "" && req.expr == nil) || req.estimatedRange < time.Duration(r.MinRange).Milliseconds()) {
+		return false
+	}
+	if len(r.matchers) > 0 && !slices.ContainsFunc(r.matchers, func(ms []*labels.Matcher) bool {
+		return slices.ContainsFunc(req.matchers, func(reqMatchers []*labels.Matcher) bool { return containsMatchers(reqMatchers, ms) })
+	}) {
+		return false
+	}
+	return true
+}
+
+// containsMatchers returns true if all matchers are part of the set.
+func containsMatchers(set, matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		if !slices.ContainsFunc(set, func(s *labels.Matcher) bool {
+			return s.Name == m.Name && s.Type == m.Type && s.Value == m.Value
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// rewrite returns a copy of the request with the rule's rewrites applied.
+func (r *queryRule) rewrite(req queryrange.Request) queryrange.Request {
+	switch tr := req.(type) {
+	case *ThanosQueryRangeRequest:
+		rewritten := *tr
+		r.rewriteQuery(&rewritten.Query, &rewritten.AutoDownsampling, &rewritten.MaxSourceResolution)
+		return &rewritten
+	case *ThanosQueryInstantRequest:
+		rewritten := *tr
+		r.rewriteQuery(&rewritten.Query, &rewritten.AutoDownsampling, &rewritten.MaxSourceResolution)
+		return &rewritten
+	}
+	return req
+}
+
+func (r *queryRule) rewriteQuery(query *string, autoDownsampling *bool, maxSourceResolution *int64) {
+	if r.Rewrite.Replacement != nil {
+		*query = r.queryRegex.ReplaceAllString(*query, *r.Rewrite.Replacement)
+	}
+	if res := time.Duration(r.Rewrite.MaxSourceResolution).Milliseconds(); res > 0 && (*autoDownsampling || *maxSourceResolution < res) {
+		*autoDownsampling = false
+		*maxSourceResolution = res
+	}
+}
+
+// QueryRules holds the reloadable set of rules rejecting, throttling or rewriting requests
+// in the query frontend.
+type QueryRules struct {
+	logger      log.Logger
+	configFile  fileContent
+	reloadTimer time.Duration
+
+	mtx   sync.RWMutex
+	rules []*queryRule
+
+	matches             *prometheus.CounterVec
+	rejected            *prometheus.CounterVec
+	configReloads       prometheus.Counter
+	configReloadsFailed prometheus.Counter
+}
+
+// fileContent is an interface to avoid a direct dependency on kingpin or extkingpin.
+type fileContent interface {
+	Content() ([]byte, error)
+	Path() string
+}
+
+// NewQueryRules creates query rules loaded from the given configuration.
+func NewQueryRules(configFile fileContent, logger log.Logger, reg prometheus.Registerer, reloadTimer time.Duration) (*QueryRules, error) {
+	q := &QueryRules{
+		logger:      logger,
+		configFile:  configFile,
+		reloadTimer: reloadTimer,
+		matches: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_query_frontend_query_rule_matches_total",
+			Help: "Total number of requests matching a query rule.",
+		}, []string{"rule", "action"}),
+		rejected: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_query_frontend_query_rule_rejected_requests_total",
+			Help: "Total number of requests rejected by a reject or throttle query rule.",
+		}, []string{"rule"}),
+		configReloads: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_query_frontend_query_rules_config_reload_total",
+			Help: "How many times the query rules configuration was reloaded.",
+		}),
+		configReloadsFailed: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_query_frontend_query_rules_config_reload_err_total",
+			Help: "How many times the query rules configuration failed to reload.",
+		}),
+	}
+	if err := q.loadConfig(); err != nil {
+		return nil, errors.Wrap(err, "load query rules config")
+	}
+	return q, nil
+}
+
+func (q *QueryRules) loadConfig() error {
+	content, err := q.configFile.Content()
+	if err != nil {
+		return errors.Wrap(err, "read query rules config")
+	}
+	var conf QueryRulesConfig
+	if err := yaml.UnmarshalStrict(content, &conf); err != nil {
+		return errors.Wrap(err, "parsing query rules config YAML")
+	}
+	rules, err := compileQueryRules(conf)
+	if err != nil {
+		return err
+	}
+
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	// Keep throttling state of unchanged rules, so reloads do not allow more concurrent requests.
+	for _, r := range rules {
+		for _, old := range q.rules {
+			if old.Name == r.Name && old.Action == QueryRuleThrottle && r.Action == QueryRuleThrottle && old.MaxConcurrency == r.MaxConcurrency {
+				r.throttle = old.throttle
+			}
+		}
+	}
+	q.rules = rules
+	return nil
+}
+
+// CanReload returns true if the rules are loaded from a file which can be watched for changes.
+func (q *QueryRules) CanReload() bool {
+	return q.configFile != nil && q.configFile.Path() != ""
+}
+
+// StartConfigReloader starts reloading the rules when their configuration file changes.
+func (q *QueryRules) StartConfigReloader(ctx context.Context) error {
+	if !q.CanReload() {
+		return nil
+	}
+
+	return extkingpin.PathContentReloader(ctx, q.configFile, q.logger, func() {
+		level.Info(q.logger).Log("msg", "reloading query rules config")
+		if err := q.loadConfig(); err != nil {
+			q.configReloadsFailed.Inc()
+			level.Error(q.logger).Log("msg", "error reloading query rules config", "path", q.configFile.Path(), "err", err)
+			return
+		}
+		q.configReloads.Inc()
+	}, q.reloadTimer)
+}
+
+func (q *QueryRules) getRules() []*queryRule {
+	q.mtx.RLock()
+	defer q.mtx.RUnlock()
+	return q.rules
+}
+
+// QueryRulesMiddleware applies the query rules to range, instant and labels requests.
+func QueryRulesMiddleware(rules *QueryRules) queryrange.Middleware {
+	return queryrange.MiddlewareFunc(func(next queryrange.Handler) queryrange.Handler {
+		return queryRulesHandler{rules: rules, next: next}
+	})
+}
+
+type queryRulesHandler struct {
+	rules *QueryRules
+	next  queryrange.Handler
+}
+
+func (h queryRulesHandler) Do(ctx context.Context, r queryrange.Request) (queryrange.Response, error) {
+	rules := h.rules.getRules()
+	if len(rules) == 0 {
+		return h.next.Do(ctx, r)
+	}
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, httpgrpc.Errorf(http.StatusBadRequest, "%s", err.Error())
+	}
+
+	req := newRuleRequest(tenantIDs, r)
+	for _, rule := range rules {
+		if !rule.matches(req) {
+			continue
+		}
+		h.rules.matches.WithLabelValues(rule.Name, string(rule.Action)).Inc()
+
+		switch rule.Action {
+		case QueryRuleReject:
+			h.rules.rejected.WithLabelValues(rule.Name).Inc()
+			level.Debug(h.rules.logger).Log("msg", "request rejected by query rule", "rule", rule.Name, "query", req.query)
+			return nil, httpgrpc.Errorf(http.StatusUnprocessableEntity, "%s", rule.message("request rejected by query rule %q"))
+		case QueryRuleThrottle:
+			select {
+			case rule.throttle <- struct{}{}:
+				defer func() { <-rule.throttle }()
+			default:
+				h.rules.rejected.WithLabelValues(rule.Name).Inc()
+				level.Debug(h.rules.logger).Log("msg", "request throttled by query rule", "rule", rule.Name, "query", req.query)
+				return nil, httpgrpc.Errorf(http.StatusTooManyRequests, "%s", rule.message("too many concurrent requests matching query rule %q"))
+			}
+		case QueryRuleRewrite:
+			// Following rules match the rewritten request.
+			r = rule.rewrite(r)
+			req = newRuleRequest(tenantIDs, r)
+		}
+	}
+	return h.next.Do(ctx, r)
+}
+
+func (r *queryRule) message(format string) string {
+	if r.Message != "" {
+		return r.Message
+	}
+	return fmt.Sprintf(format, r.Name)
+}
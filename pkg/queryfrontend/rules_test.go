@@ -0,0 +1,323 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/user"
+
+	"github.com/thanos-io/thanos/internal/cortex/querier/queryrange"
+	"github.com/thanos-io/thanos/pkg/extkingpin"
+)
+
+func newTestQueryRules(t *testing.T, content string) *QueryRules {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	testutil.Ok(t, os.WriteFile(path, []byte(content), 0o600))
+	configFile, err := extkingpin.NewStaticPathContent(path)
+	testutil.Ok(t, err)
+
+	rules, err := NewQueryRules(configFile, log.NewNopLogger(), prometheus.NewRegistry(), time.Second)
+	testutil.Ok(t, err)
+	return rules
+}
+
+func TestCompileQueryRules(t *testing.T) {
+	for _, tcase := range []struct {
+		name   string
+		config string
+		expErr bool
+	}{
+		{name: "empty", config: ""},
+		{name: "valid", config: `
+rules:
+- name: block-all-series
+  action: reject
+  matchers: ['{__name__=~".+"}']
+- name: throttle-long-ranges
+  action: throttle
+  min_range: 30d
+  max_concurrency: 2
+- name: rewrite
+  action: rewrite
+  query_regex: 'rate\((.*)\[1m\]\)'
+  rewrite:
+    replacement: 'rate($1[5m])'
+    max_source_resolution: 1h
+`},
+		{name: "missing name", config: "rules: [{action: reject}]", expErr: true},
+		{name: "duplicated name", config: "rules: [{name: a, action: reject}, {name: a, action: reject}]", expErr: true},
+		{name: "unknown action", config: "rules: [{name: a, action: drop}]", expErr: true},
+		{name: "unknown field", config: "rules: [{name: a, action: reject, foo: bar}]", expErr: true},
+		{name: "invalid regex", config: "rules: [{name: a, action: reject, query_regex: '('}]", expErr: true},
+		{name: "invalid matchers", config: "rules: [{name: a, action: reject, matchers: ['{']}]", expErr: true},
+		{name: "throttle without max concurrency", config: "rules: [{name: a, action: throttle}]", expErr: true},
+		{name: "rewrite without rewrites", config: "rules: [{name: a, action: rewrite}]", expErr: true},
+		{name: "rewrite replacement without regex", config: "rules: [{name: a, action: rewrite, rewrite: {replacement: b}}]", expErr: true},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.yaml")
+			testutil.Ok(t, os.WriteFile(path, []byte(tcase.config), 0o600))
+			configFile, err := extkingpin.NewStaticPathContent(path)
+			testutil.Ok(t, err)
+
+			_, err = NewQueryRules(configFile, log.NewNopLogger(), nil, time.Second)
+			if tcase.expErr {
+				testutil.NotOk(t, err)
+				return
+			}
+			testutil.Ok(t, err)
+		})
+	}
+}
+
+func TestQueryRuleMatches(t *testing.T) {
+	rules, err := compileQueryRules(QueryRulesConfig{Rules: []QueryRuleConfig{
+		{Name: "all-series", Action: QueryRuleReject, Matchers: []string{`{__name__=~".+"}`}},
+		{Name: "job-foo", Action: QueryRuleReject, Matchers: []string{`{job="foo"}`, `{job="bar", env="prod"}`}},
+		{Name: "regex", Action: QueryRuleReject, QueryRegex: `^count\(`},
+		{Name: "tenant", Action: QueryRuleReject, Tenants: []string{"team-a"}},
+		{Name: "range", Action: QueryRuleReject, MinRange: model.Duration(30 * day)},
+	}})
+	testutil.Ok(t, err)
+
+	for _, tcase := range []struct {
+		name    string
+		tenant  string
+		req     queryrange.Request
+		matches []string
+	}{
+		{
+			name:    "all series selector",
+			req:     &ThanosQueryInstantRequest{Query: `count({__name__=~".+"})`},
+			matches: []string{"all-series", "regex"},
+		},
+		{
+			name:    "selector with additional matchers",
+			req:     &ThanosQueryInstantRequest{Query: `sum(up{job="foo", instance="a"}) + sum(http_requests_total{job="bar", env="prod"})`},
+			matches: []string{"job-foo"},
+		},
+		{
+			name: "selector missing one of the matchers",
+			req:  &ThanosQueryInstantRequest{Query: `http_requests_total{job="bar"}`},
+		},
+		{
+			name:    "tenant",
+			tenant:  "team-a",
+			req:     &ThanosQueryInstantRequest{Query: `up`},
+			matches: []string{"tenant"},
+		},
+		{
+			name:    "long range query",
+			req:     &ThanosQueryRangeRequest{Query: `up`, Start: 0, End: 31 * day.Milliseconds()},
+			matches: []string{"range"},
+		},
+		{
+			name: "short range query",
+			req:  &ThanosQueryRangeRequest{Query: `up`, Start: 0, End: 29 * day.Milliseconds()},
+		},
+		{
+			name:    "short range query with long range selector",
+			req:     &ThanosQueryRangeRequest{Query: `rate(up[2d])`, Start: 0, End: 29 * day.Milliseconds()},
+			matches: []string{"range"},
+		},
+		{
+			name:    "instant query with offset",
+			req:     &ThanosQueryInstantRequest{Query: `up offset 30d`},
+			matches: []string{"range"},
+		},
+		{
+			name:    "instant query with subquery",
+			req:     &ThanosQueryInstantRequest{Query: `max_over_time(rate(up[5m])[31d:1h])`},
+			matches: []string{"range"},
+		},
+		{
+			name:    "series request",
+			req:     &ThanosSeriesRequest{Start: 0, End: 31 * day.Milliseconds(), Matchers: [][]*labels.Matcher{{labels.MustNewMatcher(labels.MatchRegexp, "__name__", ".+")}}},
+			matches: []string{"all-series", "range"},
+		},
+		{
+			name: "unparsable query",
+			req:  &ThanosQueryRangeRequest{Query: `up{`, Start: 0, End: 31 * day.Milliseconds()},
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			tenant := tcase.tenant
+			if tenant == "" {
+				tenant = "default-tenant"
+			}
+			req := newRuleRequest([]string{tenant}, tcase.req)
+			var matches []string
+			for _, r := range rules {
+				if r.matches(req) {
+					matches = append(matches, r.Name)
+				}
+			}
+			testutil.Equals(t, tcase.matches, matches)
+		})
+	}
+}
+
+type recordingHandler struct {
+	reqs    chan queryrange.Request
+	unblock chan struct{}
+}
+
+func (h *recordingHandler) Do(_ context.Context, r queryrange.Request) (queryrange.Response, error) {
+	h.reqs <- r
+	if h.unblock != nil {
+		<-h.unblock
+	}
+	return &queryrange.PrometheusResponse{Status: queryrange.StatusSuccess}, nil
+}
+
+func TestQueryRulesMiddleware(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "team-a")
+
+	t.Run("reject", func(t *testing.T) {
+		rules := newTestQueryRules(t, `
+rules:
+- name: block-all-series
+  action: reject
+  matchers: ['{__name__=~".+"}']
+  message: queries selecting all series are not allowed
+`)
+		next := &recordingHandler{reqs: make(chan queryrange.Request, 1)}
+		h := QueryRulesMiddleware(rules).Wrap(next)
+
+		_, err := h.Do(ctx, &ThanosQueryInstantRequest{Query: `{__name__=~".+"}`})
+		resp, ok := httpgrpc.HTTPResponseFromError(err)
+		testutil.Assert(t, ok, "expected HTTP error, got %v", err)
+		testutil.Equals(t, int32(http.StatusUnprocessableEntity), resp.Code)
+		testutil.Equals(t, "queries selecting all series are not allowed", string(resp.Body))
+		testutil.Equals(t, 1.0, promtest.ToFloat64(rules.rejected.WithLabelValues("block-all-series")))
+
+		_, err = h.Do(ctx, &ThanosQueryInstantRequest{Query: `up`})
+		testutil.Ok(t, err)
+		testutil.Equals(t, `up`, (<-next.reqs).GetQuery())
+	})
+	t.Run("throttle", func(t *testing.T) {
+		rules := newTestQueryRules(t, `
+rules:
+- name: throttle-count
+  action: throttle
+  query_regex: '^count'
+  max_concurrency: 1
+`)
+		next := &recordingHandler{reqs: make(chan queryrange.Request, 2), unblock: make(chan struct{})}
+		h := QueryRulesMiddleware(rules).Wrap(next)
+
+		errs := make(chan error)
+		go func() {
+			_, err := h.Do(ctx, &ThanosQueryInstantRequest{Query: `count(up)`})
+			errs <- err
+		}()
+		<-next.reqs
+
+		_, err := h.Do(ctx, &ThanosQueryInstantRequest{Query: `count(up)`})
+		resp, ok := httpgrpc.HTTPResponseFromError(err)
+		testutil.Assert(t, ok, "expected HTTP error, got %v", err)
+		testutil.Equals(t, int32(http.StatusTooManyRequests), resp.Code)
+
+		close(next.unblock)
+		testutil.Ok(t, <-errs)
+
+		// The slot is released once the first query is done.
+		_, err = h.Do(ctx, &ThanosQueryInstantRequest{Query: `count(up)`})
+		testutil.Ok(t, err)
+		testutil.Equals(t, 3.0, promtest.ToFloat64(rules.matches.WithLabelValues("throttle-count", "throttle")))
+		testutil.Equals(t, 1.0, promtest.ToFloat64(rules.rejected.WithLabelValues("throttle-count")))
+	})
+	t.Run("rewrite", func(t *testing.T) {
+		rules := newTestQueryRules(t, `
+rules:
+- name: downsample-long-ranges
+  action: rewrite
+  min_range: 30d
+  rewrite:
+    max_source_resolution: 1h
+- name: rate-window
+  action: rewrite
+  query_regex: 'rate\((.*)\[1m\]\)'
+  rewrite:
+    replacement: 'rate($1[5m])'
+`)
+		next := &recordingHandler{reqs: make(chan queryrange.Request, 1)}
+		h := QueryRulesMiddleware(rules).Wrap(next)
+
+		orig := &ThanosQueryRangeRequest{Query: `rate(up[1m])`, Start: 0, End: 31 * day.Milliseconds(), AutoDownsampling: true}
+		_, err := h.Do(ctx, orig)
+		testutil.Ok(t, err)
+		rewritten := (<-next.reqs).(*ThanosQueryRangeRequest)
+		testutil.Equals(t, `rate(up[5m])`, rewritten.Query)
+		testutil.Equals(t, hour, float64(rewritten.MaxSourceResolution))
+		testutil.Equals(t, false, rewritten.AutoDownsampling)
+		// The original request is not modified.
+		testutil.Equals(t, `rate(up[1m])`, orig.Query)
+
+		_, err = h.Do(ctx, &ThanosQueryRangeRequest{Query: `up`, Start: 0, End: day.Milliseconds(), MaxSourceResolution: 5 * 60 * seconds})
+		testutil.Ok(t, err)
+		testutil.Equals(t, int64(5*60*seconds), (<-next.reqs).(*ThanosQueryRangeRequest).MaxSourceResolution)
+	})
+	t.Run("rules after rewrite match rewritten query", func(t *testing.T) {
+		rules := newTestQueryRules(t, `
+rules:
+- name: rename
+  action: rewrite
+  query_regex: '^old_metric$'
+  rewrite:
+    replacement: 'new_metric'
+- name: reject-new
+  action: reject
+  matchers: ['{__name__="new_metric"}']
+`)
+		next := &recordingHandler{reqs: make(chan queryrange.Request, 1)}
+		h := QueryRulesMiddleware(rules).Wrap(next)
+
+		_, err := h.Do(ctx, &ThanosQueryInstantRequest{Query: `old_metric`})
+		resp, ok := httpgrpc.HTTPResponseFromError(err)
+		testutil.Assert(t, ok, "expected HTTP error, got %v", err)
+		testutil.Equals(t, int32(http.StatusUnprocessableEntity), resp.Code)
+	})
+	t.Run("reload", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rules.yaml")
+		testutil.Ok(t, os.WriteFile(path, []byte("rules: [{name: a, action: reject, query_regex: '^up$'}]"), 0o600))
+		configFile, err := extkingpin.NewStaticPathContent(path)
+		testutil.Ok(t, err)
+		rules, err := NewQueryRules(configFile, log.NewNopLogger(), prometheus.NewRegistry(), time.Second)
+		testutil.Ok(t, err)
+
+		next := &recordingHandler{reqs: make(chan queryrange.Request, 1)}
+		h := QueryRulesMiddleware(rules).Wrap(next)
+
+		_, err = h.Do(ctx, &ThanosQueryInstantRequest{Query: `up`})
+		testutil.NotOk(t, err)
+
+		testutil.Ok(t, configFile.Rewrite([]byte("rules: []")))
+		testutil.Ok(t, rules.loadConfig())
+		_, err = h.Do(ctx, &ThanosQueryInstantRequest{Query: `up`})
+		testutil.Ok(t, err)
+		<-next.reqs
+
+		// Invalid configuration keeps the previous rules.
+		testutil.Ok(t, configFile.Rewrite([]byte("rules: [{name: a}]")))
+		testutil.NotOk(t, rules.loadConfig())
+		_, err = h.Do(ctx, &ThanosQueryInstantRequest{Query: `up`})
+		testutil.Ok(t, err)
+	})
+}
@@ -54,6 +54,12 @@ func (s splitByInterval) Do(ctx context.Context, r queryrange.Request) (queryran
 	}
 	s.splitByCounter.Add(float64(len(reqs)))
 
+	if codec, ok := s.merger.(*queryRangeCodec); ok && codec.incrementalMerge {
+		if _, ok := r.(*ThanosQueryRangeRequest); ok {
+			return doRequestsInOrder(ctx, s.next, r, reqs, s.limits, s.merger)
+		}
+	}
+
 	reqResps, err := queryrange.DoRequests(ctx, s.next, reqs, s.limits)
 	if err != nil {
 		return nil, err
@@ -95,9 +101,14 @@ func splitQuery(r queryrange.Request, interval time.Duration) ([]queryrange.Requ
 			}
 		}
 	case SplitRequest:
+		// Requests are split at multiples of the interval, so that the cached results of every interval
+		// can be reused by requests with a different start and end, e.g. sliding windows of dashboards.
 		dur := int64(interval / time.Millisecond)
-		for start := r.GetStart(); start < r.GetEnd(); start = start + dur {
-			end := min(start+dur, r.GetEnd())
+		if r.GetStart() == r.GetEnd() {
+			reqs = append(reqs, tr.WithSplitInterval(interval).WithStartEnd(r.GetStart(), r.GetEnd()))
+		}
+		for start := r.GetStart(); start < r.GetEnd(); start = (start/dur + 1) * dur {
+			end := min((start/dur+1)*dur, r.GetEnd())
 
 			reqs = append(reqs, tr.WithSplitInterval(interval).WithStartEnd(start, end))
 		}
@@ -250,6 +250,48 @@ func TestSplitQuery(t *testing.T) {
 			},
 			interval: 3 * time.Hour,
 		},
+		{
+			input: &ThanosLabelsRequest{
+				Start: 2 * 3600 * seconds,
+				End:   7 * 3600 * seconds,
+				Label: "foo",
+			},
+			expected: []queryrange.Request{
+				&ThanosLabelsRequest{
+					Start:         2 * 3600 * seconds,
+					End:           3 * 3600 * seconds,
+					Label:         "foo",
+					SplitInterval: 3 * time.Hour,
+				},
+				&ThanosLabelsRequest{
+					Start:         3 * 3600 * seconds,
+					End:           6 * 3600 * seconds,
+					Label:         "foo",
+					SplitInterval: 3 * time.Hour,
+				},
+				&ThanosLabelsRequest{
+					Start:         6 * 3600 * seconds,
+					End:           7 * 3600 * seconds,
+					Label:         "foo",
+					SplitInterval: 3 * time.Hour,
+				},
+			},
+			interval: 3 * time.Hour,
+		},
+		{
+			input: &ThanosSeriesRequest{
+				Start: 3 * 3600 * seconds,
+				End:   5 * 3600 * seconds,
+			},
+			expected: []queryrange.Request{
+				&ThanosSeriesRequest{
+					Start:         3 * 3600 * seconds,
+					End:           5 * 3600 * seconds,
+					SplitInterval: 3 * time.Hour,
+				},
+			},
+			interval: 3 * time.Hour,
+		},
 	} {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			queries, err := splitQuery(tc.input, tc.interval)
@@ -0,0 +1,261 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/thanos-io/thanos/internal/cortex/cortexpb"
+	"github.com/thanos-io/thanos/internal/cortex/querier/queryrange"
+	"github.com/thanos-io/thanos/pkg/extpromql"
+	"github.com/thanos-io/thanos/pkg/querysharding"
+)
+
+// splitRangeFunction describes how a range vector function is evaluated over sub-windows of its range.
+type splitRangeFunction struct {
+	// subFunction is the function evaluated over every sub-window.
+	subFunction string
+	// combine is the aggregation combining the results of sub-windows, which an outer aggregation must match.
+	combine parser.ItemType
+	// perSecond divides the combined result by the range in seconds.
+	perSecond bool
+}
+
+// splitRangeFunctions are the range vector functions whose result over a range can be computed from their
+// results over sub-windows of it. increase and rate extrapolate every sub-window separately, so their results
+// differ slightly from the ones over the whole range.
+var splitRangeFunctions = map[string]splitRangeFunction{
+	"sum_over_time":   {subFunction: "sum_over_time", combine: parser.SUM},
+	"count_over_time": {subFunction: "count_over_time", combine: parser.SUM},
+	"increase":        {subFunction: "increase", combine: parser.SUM},
+	"rate":            {subFunction: "increase", combine: parser.SUM, perSecond: true},
+	"max_over_time":   {subFunction: "max_over_time", combine: parser.MAX},
+	"min_over_time":   {subFunction: "min_over_time", combine: parser.MIN},
+}
+
+// SplitInstantQueryMiddleware creates a new Middleware that splits instant queries of range vector functions over
+// ranges longer than interval, optionally aggregated, into queries of sub-windows of at most interval and combines
+// their results.
+func SplitInstantQueryMiddleware(queryAnalyzer querysharding.Analyzer, interval time.Duration, limits queryrange.Limits, registerer prometheus.Registerer) queryrange.Middleware {
+	return queryrange.MiddlewareFunc(func(next queryrange.Handler) queryrange.Handler {
+		queriesTotal := promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+			Namespace: "thanos",
+			Name:      "frontend_instant_query_split_queries_total",
+			Help:      "Total number of instant queries analyzed by the split middleware",
+		}, []string{"splittable"})
+
+		queriesTotal.WithLabelValues("true")
+		queriesTotal.WithLabelValues("false")
+
+		return instantQuerySplitter{
+			next:          next,
+			limits:        limits,
+			queryAnalyzer: queryAnalyzer,
+			interval:      interval,
+			queriesTotal:  queriesTotal,
+			now:           time.Now,
+		}
+	})
+}
+
+type instantQuerySplitter struct {
+	next   queryrange.Handler
+	limits queryrange.Limits
+
+	queryAnalyzer querysharding.Analyzer
+	interval      time.Duration
+	now           func() time.Time
+
+	// Metrics
+	queriesTotal *prometheus.CounterVec
+}
+
+func (s instantQuerySplitter) Do(ctx context.Context, r queryrange.Request) (queryrange.Response, error) {
+	queries, fn, rng := s.splitQuery(r.GetQuery())
+	if len(queries) == 0 {
+		s.queriesTotal.WithLabelValues("false").Inc()
+		return s.next.Do(ctx, r)
+	}
+
+	s.queriesTotal.WithLabelValues("true").Inc()
+	// Queries without a time are evaluated at the current time, which has to be the same for all sub-windows.
+	if ir, ok := r.(*ThanosQueryInstantRequest); ok && ir.Time == 0 {
+		timed := *ir
+		timed.Time = s.now().UnixMilli()
+		r = &timed
+	}
+	reqs := make([]queryrange.Request, 0, len(queries))
+	for _, q := range queries {
+		reqs = append(reqs, r.WithQuery(q))
+	}
+
+	reqResps, err := queryrange.DoRequests(ctx, s.next, reqs, s.limits)
+	if err != nil {
+		return nil, err
+	}
+
+	resps := make([]queryrange.Response, 0, len(reqResps))
+	for _, reqResp := range reqResps {
+		resps = append(resps, reqResp.Response)
+	}
+	resp, ok := combineSplitResponses(resps, fn, rng)
+	if !ok {
+		// Native histograms or results of unexpected types are not combined, the query is executed as is.
+		return s.next.Do(ctx, r)
+	}
+	return resp, nil
+}
+
+// splitQuery returns the queries of the sub-windows of the query, the function they evaluate and the range they
+// split. It returns no queries if the query is not splittable.
+func (s instantQuerySplitter) splitQuery(query string) ([]string, splitRangeFunction, time.Duration) {
+	// Queries the sharding analyzer fails to analyze are passed on unchanged.
+	if _, err := s.queryAnalyzer.Analyze(query); err != nil {
+		return nil, splitRangeFunction{}, 0
+	}
+	expr, err := extpromql.ParseExpr(query)
+	if err != nil {
+		return nil, splitRangeFunction{}, 0
+	}
+
+	call, aggr := splittableCall(expr)
+	if call == nil {
+		return nil, splitRangeFunction{}, 0
+	}
+	fn, ok := splitRangeFunctions[call.Func.Name]
+	if !ok || (aggr != nil && aggr.Op != fn.combine) {
+		return nil, splitRangeFunction{}, 0
+	}
+	ms, ok := unwrapParens(call.Args[0]).(*parser.MatrixSelector)
+	if !ok || ms.RangeExpr != nil || ms.Range <= s.interval {
+		return nil, splitRangeFunction{}, 0
+	}
+	vs, ok := ms.VectorSelector.(*parser.VectorSelector)
+	if !ok || vs.Timestamp != nil || vs.StartOrEnd != 0 || vs.OriginalOffsetExpr != nil || vs.Anchored || vs.Smoothed {
+		return nil, splitRangeFunction{}, 0
+	}
+
+	// Sub-windows are rewritten in place, the original range and offset are kept to compute them.
+	rng, offset := ms.Range, vs.OriginalOffset
+	call.Func = parser.Functions[fn.subFunction]
+
+	var queries []string
+	for start := time.Duration(0); start < rng; start += s.interval {
+		ms.Range = min(s.interval, rng-start)
+		vs.OriginalOffset = offset + start
+		queries = append(queries, expr.String())
+	}
+	return queries, fn, rng
+}
+
+// splittableCall returns the function call of expressions which are either a call or an aggregation without
+// parameter of a call, and the aggregation if any.
+func splittableCall(expr parser.Expr) (*parser.Call, *parser.AggregateExpr) {
+	expr = unwrapParens(expr)
+	aggr, ok := expr.(*parser.AggregateExpr)
+	if ok {
+		if aggr.Param != nil {
+			return nil, nil
+		}
+		expr = unwrapParens(aggr.Expr)
+	}
+	call, ok := expr.(*parser.Call)
+	if !ok || call.Func == nil || len(call.Args) != 1 {
+		return nil, nil
+	}
+	return call, aggr
+}
+
+func unwrapParens(expr parser.Expr) parser.Expr {
+	for {
+		p, ok := expr.(*parser.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = p.Expr
+	}
+}
+
+// combineSplitResponses combines the vector responses of sub-window queries by series. It returns false if any
+// response is not a vector of float samples.
+func combineSplitResponses(resps []queryrange.Response, fn splitRangeFunction, rng time.Duration) (queryrange.Response, bool) {
+	var (
+		series   = map[string]*queryrange.Sample{}
+		analyzes []*queryrange.Analysis
+		warnings []string
+	)
+	for _, res := range resps {
+		resp, ok := res.(*queryrange.PrometheusInstantQueryResponse)
+		if !ok || resp.Data.ResultType != model.ValVector.String() {
+			return nil, false
+		}
+		for _, sample := range resp.Data.Result.GetVector().GetSamples() {
+			if sample == nil {
+				continue
+			}
+			if sample.Histogram != nil {
+				return nil, false
+			}
+			key := cortexpb.FromLabelAdaptersToLabels(sample.Labels).String()
+			existing, ok := series[key]
+			if !ok {
+				series[key] = &queryrange.Sample{Labels: sample.Labels, SampleValue: sample.SampleValue, Timestamp: sample.Timestamp}
+				continue
+			}
+			switch fn.combine {
+			case parser.SUM:
+				existing.SampleValue += sample.SampleValue
+			// Like max_over_time and min_over_time, NaN is only the result if all values are NaN.
+			case parser.MAX:
+				if sample.SampleValue > existing.SampleValue || math.IsNaN(existing.SampleValue) {
+					existing.SampleValue = sample.SampleValue
+				}
+			case parser.MIN:
+				if sample.SampleValue < existing.SampleValue || math.IsNaN(existing.SampleValue) {
+					existing.SampleValue = sample.SampleValue
+				}
+			}
+		}
+		if resp.Data.Analysis != nil {
+			analyzes = append(analyzes, resp.Data.Analysis)
+		}
+		warnings = append(warnings, resp.Warnings...)
+	}
+
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	vector := &queryrange.Vector{Samples: make([]*queryrange.Sample, 0, len(keys))}
+	for _, key := range keys {
+		sample := series[key]
+		if fn.perSecond {
+			sample.SampleValue /= rng.Seconds()
+		}
+		vector.Samples = append(vector.Samples, sample)
+	}
+
+	return &queryrange.PrometheusInstantQueryResponse{
+		Status: queryrange.StatusSuccess,
+		Data: queryrange.PrometheusInstantQueryData{
+			ResultType: model.ValVector.String(),
+			Result: queryrange.PrometheusInstantQueryResult{
+				Result: &queryrange.PrometheusInstantQueryResult_Vector{Vector: vector},
+			},
+			Analysis: queryrange.AnalyzesMerge(analyzes...),
+			Stats:    queryrange.StatsMerge(resps),
+		},
+		Warnings: warnings,
+	}, true
+}
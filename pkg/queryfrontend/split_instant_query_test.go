@@ -0,0 +1,231 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/weaveworks/common/user"
+
+	"github.com/thanos-io/thanos/internal/cortex/frontend/transport"
+	"github.com/thanos-io/thanos/internal/cortex/querier/queryrange"
+	cortexvalidation "github.com/thanos-io/thanos/internal/cortex/util/validation"
+	"github.com/thanos-io/thanos/pkg/querysharding"
+)
+
+func TestSplitInstantQuery(t *testing.T) {
+	splitter := SplitInstantQueryMiddleware(querysharding.NewQueryAnalyzer(), day, nil, prometheus.NewRegistry()).
+		Wrap(nil).(instantQuerySplitter)
+
+	for _, tc := range []struct {
+		query    string
+		expected []string
+	}{
+		{
+			query:    `sum(increase(foo[3d]))`,
+			expected: []string{`sum(increase(foo[1d]))`, `sum(increase(foo[1d] offset 1d))`, `sum(increase(foo[1d] offset 2d))`},
+		},
+		{
+			query:    `max by (a) (max_over_time(foo{b="c"}[36h] offset 1h))`,
+			expected: []string{`max by (a) (max_over_time(foo{b="c"}[1d] offset 1h))`, `max by (a) (max_over_time(foo{b="c"}[12h] offset 1d1h))`},
+		},
+		{
+			query:    `(count_over_time(foo[2d]))`,
+			expected: []string{`(count_over_time(foo[1d]))`, `(count_over_time(foo[1d] offset 1d))`},
+		},
+		// Rates are computed from the increase over sub-windows.
+		{
+			query:    `sum without (a) (rate(foo[2d]))`,
+			expected: []string{`sum without (a) (increase(foo[1d]))`, `sum without (a) (increase(foo[1d] offset 1d))`},
+		},
+		// Ranges not longer than the interval.
+		{query: `increase(foo[1d])`},
+		// Aggregations which don't match how sub-windows are combined.
+		{query: `sum(max_over_time(foo[3d]))`},
+		{query: `count(count_over_time(foo[3d]))`},
+		{query: `topk(1, sum_over_time(foo[3d]))`},
+		// Functions which can't be computed from sub-windows.
+		{query: `avg_over_time(foo[3d])`},
+		{query: `quantile_over_time(0.9, foo[3d])`},
+		{query: `absent_over_time(foo[3d])`},
+		// Expressions around the function.
+		{query: `sum(increase(foo[3d])) / 2`},
+		{query: `sum(sum(increase(foo[3d])))`},
+		// Modifiers and subqueries.
+		{query: `increase(foo[3d] @ 100)`},
+		{query: `increase(foo[3d] @ end())`},
+		{query: `sum_over_time(rate(foo[5m])[3d:1h])`},
+		{query: `sum(increase(foo[3d]`},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			queries, _, _ := splitter.splitQuery(tc.query)
+			testutil.Equals(t, tc.expected, queries)
+		})
+	}
+}
+
+func TestSplitInstantQueryWithoutTime(t *testing.T) {
+	var (
+		times []int64
+		lock  sync.Mutex
+	)
+	next := queryrange.HandlerFunc(func(_ context.Context, r queryrange.Request) (queryrange.Response, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		times = append(times, r.(*ThanosQueryInstantRequest).Time)
+		return &queryrange.PrometheusInstantQueryResponse{
+			Status: queryrange.StatusSuccess,
+			Data: queryrange.PrometheusInstantQueryData{
+				ResultType: model.ValVector.String(),
+				Result:     queryrange.PrometheusInstantQueryResult{Result: &queryrange.PrometheusInstantQueryResult_Vector{Vector: &queryrange.Vector{}}},
+			},
+		}, nil
+	})
+	limits, err := cortexvalidation.NewOverrides(*defaultLimits, nil)
+	testutil.Ok(t, err)
+	splitter := SplitInstantQueryMiddleware(querysharding.NewQueryAnalyzer(), day, limits, prometheus.NewRegistry()).
+		Wrap(next).(instantQuerySplitter)
+	now := time.Unix(1000, 0)
+	splitter.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	_, err = splitter.Do(user.InjectOrgID(context.Background(), "1"), &ThanosQueryInstantRequest{Path: "/api/v1/query", Query: `sum(increase(foo[3d]))`})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []int64{1001000, 1001000, 1001000}, times)
+}
+
+// splitInstantResults returns a handler answering instant queries with series {a="1"} and {a="2"} for every
+// query of the latest sub-window, which has no offset, and only {a="1"} for others. Values are the index of the
+// sub-window plus one.
+func splitInstantResults() (*[]string, http.Handler) {
+	var (
+		queries []string
+		lock    sync.Mutex
+	)
+	return &queries, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		queries = append(queries, r.FormValue("query"))
+		lock.Unlock()
+
+		var days int
+		query := r.FormValue("query")
+		if i := strings.Index(query, " offset "); i >= 0 {
+			if _, err := fmt.Sscanf(query[i:], " offset %dd", &days); err != nil {
+				panic(err)
+			}
+		}
+		result := fmt.Sprintf(`{"metric":{"a":"1"},"value":[1,"%d"]}`, days+1)
+		if days == 0 {
+			result += `,{"metric":{"a":"2"},"value":[1,"1"]}`
+		}
+		if _, err := fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[%s]}}`, result); err != nil {
+			panic(err)
+		}
+	})
+}
+
+func TestRoundTripSplitInstantQuery(t *testing.T) {
+	tpw, err := NewTripperware(
+		Config{
+			CortexHandlerConfig: &transport.HandlerConfig{},
+			// Instant queries are split with the parallelism of range queries.
+			QueryRangeConfig: QueryRangeConfig{Limits: defaultLimits},
+			QueryInstantConfig: QueryInstantConfig{
+				Limits:        defaultLimits,
+				SplitInterval: day,
+			},
+		}, nil, log.NewNopLogger(),
+	)
+	testutil.Ok(t, err)
+
+	rt, err := newFakeRoundTripper()
+	testutil.Ok(t, err)
+	defer rt.Close()
+
+	for _, tc := range []struct {
+		query           string
+		expectedQueries int
+		// expected are the values of series by their label a.
+		expected map[string]float64
+	}{
+		{
+			query:           `sum by (a) (increase(foo[3d]))`,
+			expectedQueries: 3,
+			expected:        map[string]float64{"1": 6, "2": 1},
+		},
+		{
+			query:           `max_over_time(foo[3d])`,
+			expectedQueries: 3,
+			expected:        map[string]float64{"1": 3, "2": 1},
+		},
+		{
+			query:           `min_over_time(foo[2d])`,
+			expectedQueries: 2,
+			expected:        map[string]float64{"1": 1, "2": 1},
+		},
+		{
+			query:           `rate(foo[2d])`,
+			expectedQueries: 2,
+			expected:        map[string]float64{"1": 3 / (2 * day).Seconds(), "2": 1 / (2 * day).Seconds()},
+		},
+		{
+			query:           `avg_over_time(foo[3d])`,
+			expectedQueries: 1,
+			expected:        map[string]float64{"1": 1, "2": 1},
+		},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			queries, handler := splitInstantResults()
+			rt.setHandler(handler)
+
+			ctx := user.InjectOrgID(context.Background(), "1")
+			httpReq, err := NewThanosQueryInstantCodec(true).EncodeRequest(ctx, &ThanosQueryInstantRequest{
+				Path:  "/api/v1/query",
+				Time:  3 * int64(day/time.Millisecond),
+				Query: tc.query,
+			})
+			testutil.Ok(t, err)
+
+			resp, err := tpw(rt).RoundTrip(httpReq)
+			testutil.Ok(t, err)
+			testutil.Equals(t, http.StatusOK, resp.StatusCode)
+			testutil.Equals(t, tc.expectedQueries, len(*queries))
+
+			res, err := NewThanosQueryInstantCodec(true).DecodeResponse(ctx, resp, nil)
+			testutil.Ok(t, err)
+			actual := map[string]float64{}
+			for _, s := range res.(*queryrange.PrometheusInstantQueryResponse).Data.Result.GetVector().Samples {
+				actual[s.Labels[0].Value] = s.SampleValue
+			}
+			testutil.Equals(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestCombineSplitResponsesHistograms(t *testing.T) {
+	resp, err := NewThanosQueryInstantCodec(true).DecodeResponse(context.Background(), &http.Response{
+		StatusCode: http.StatusOK,
+		Body: io.NopCloser(strings.NewReader(
+			`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"histogram":[1,{"count":"1","sum":"1"}]}]}}`,
+		)),
+	}, nil)
+	testutil.Ok(t, err)
+
+	// Native histograms are not combined, the query is executed without splitting instead.
+	_, ok := combineSplitResponses([]queryrange.Response{resp}, splitRangeFunctions["increase"], 2*day)
+	testutil.Assert(t, !ok)
+}
@@ -0,0 +1,39 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/thanos-io/thanos/internal/cortex/util/validation"
+)
+
+// TenantLimitsConfig holds per-tenant overrides of the query range limits.
+type TenantLimitsConfig struct {
+	Overrides map[string]*validation.Limits `yaml:"overrides"`
+}
+
+type tenantLimits map[string]*validation.Limits
+
+// NewTenantLimits parses per-tenant overrides of limits. Limits which are not overridden for a tenant
+// default to the given limits.
+func NewTenantLimits(confContentYaml []byte, defaults validation.Limits) (validation.TenantLimits, error) {
+	validation.SetDefaultLimitsForYAMLUnmarshalling(defaults)
+
+	var conf TenantLimitsConfig
+	if err := yaml.UnmarshalStrict(confContentYaml, &conf); err != nil {
+		return nil, errors.Wrap(err, "parsing tenant limits YAML")
+	}
+	for tenant, l := range conf.Overrides {
+		if l == nil {
+			return nil, errors.Errorf("no limits overridden for tenant %s", tenant)
+		}
+	}
+	return tenantLimits(conf.Overrides), nil
+}
+
+func (t tenantLimits) ByUserID(userID string) *validation.Limits { return t[userID] }
+
+func (t tenantLimits) AllByUserID() map[string]*validation.Limits { return t }
@@ -275,19 +275,7 @@ func (l *localClient) TimeRange() (mint int64, maxt int64) {
 }
 
 func (l *localClient) TSDBInfos() []infopb.TSDBInfo {
-	labelsets := l.store.LabelSet()
-	if len(labelsets) == 0 {
-		return []infopb.TSDBInfo{}
-	}
-
-	mint, maxt := l.store.TimeRange()
-	return []infopb.TSDBInfo{
-		{
-			Labels:  labelsets[0],
-			MinTime: mint,
-			MaxTime: maxt,
-		},
-	}
+	return l.store.TSDBInfos()
 }
 
 func (l *localClient) String() string {
@@ -0,0 +1,200 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package replicate
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/minio/sha256-simd"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+
+	"github.com/thanos-io/objstore"
+
+	thanosblock "github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/compact"
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
+	"github.com/thanos-io/thanos/pkg/logutil"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+// MigrateConfig configures how blocks are changed while being replicated to the target bucket.
+// The zero value replicates blocks as-is.
+type MigrateConfig struct {
+	// RelabelConfigs are applied to external labels of replicated blocks, e.g. to rename a label
+	// or to merge blocks of multiple sources into a single one. Blocks whose labels are dropped
+	// are not replicated.
+	RelabelConfigs []*relabel.Config
+	// Verify checks sizes and hashes of replicated files in the target bucket against the
+	// meta.json of the block before the block is considered replicated.
+	Verify bool
+	// VerticalCompaction compacts overlapping blocks of the replicated groups in the target bucket
+	// after every replication run.
+	VerticalCompaction bool
+	// CompactDir is the working directory of vertical compaction.
+	CompactDir string
+	// CompactionLevels are the block ranges used when compacting the target bucket.
+	CompactionLevels []int64
+}
+
+// rewriteLabels applies the relabel configs to external labels of the block.
+// It returns false if the block should not be replicated.
+func (c MigrateConfig) rewriteLabels(m *metadata.Meta) (labels.Labels, bool) {
+	lset := labels.FromMap(m.Thanos.Labels)
+	if len(c.RelabelConfigs) == 0 {
+		return lset, true
+	}
+	lset, keep := relabel.Process(lset, c.RelabelConfigs...)
+	return lset, keep && !lset.IsEmpty()
+}
+
+// rewriteMeta returns the content of the meta.json to upload to the target bucket.
+func (c MigrateConfig) rewriteMeta(content []byte) ([]byte, *metadata.Meta, error) {
+	m, err := metadata.Read(io.NopCloser(bytes.NewReader(content)))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "parse meta file")
+	}
+	if len(c.RelabelConfigs) == 0 {
+		return content, m, nil
+	}
+
+	lset, keep := c.rewriteLabels(m)
+	if !keep {
+		return nil, nil, errors.Errorf("external labels %v of block %v dropped by relabeling", m.Thanos.Labels, m.ULID)
+	}
+	m.Thanos.Labels = lset.Map()
+
+	var buf bytes.Buffer
+	if err := m.Write(&buf); err != nil {
+		return nil, nil, errors.Wrap(err, "encode meta file")
+	}
+	return buf.Bytes(), m, nil
+}
+
+// verifyReplicatedBlock checks that files of the block in the target bucket match the sizes and hashes recorded
+// in the block's meta.json. For blocks without recorded files, sizes are compared with the origin bucket.
+func verifyReplicatedBlock(ctx context.Context, from objstore.BucketReader, to objstore.BucketReader, m *metadata.Meta, objects []string) error {
+	files := make(map[string]metadata.File, len(m.Thanos.Files))
+	for _, f := range m.Thanos.Files {
+		files[path.Join(m.ULID.String(), f.RelPath)] = f
+	}
+
+	for _, name := range objects {
+		attrs, err := to.Attributes(ctx, name)
+		if err != nil {
+			return errors.Wrapf(err, "get attributes of %v in target bucket", name)
+		}
+
+		f, ok := files[name]
+		if !ok {
+			originAttrs, err := from.Attributes(ctx, name)
+			if err != nil {
+				return errors.Wrapf(err, "get attributes of %v in origin bucket", name)
+			}
+			f.SizeBytes = originAttrs.Size
+		}
+		if attrs.Size != f.SizeBytes {
+			return errors.Errorf("size of %v in target bucket is %d, expected %d", name, attrs.Size, f.SizeBytes)
+		}
+
+		if f.Hash == nil || f.Hash.Func != metadata.SHA256Func {
+			continue
+		}
+		h, err := hashObject(ctx, to, name)
+		if err != nil {
+			return err
+		}
+		if h != f.Hash.Value {
+			return errors.Errorf("hash of %v in target bucket is %s, expected %s", name, h, f.Hash.Value)
+		}
+	}
+	return nil
+}
+
+func hashObject(ctx context.Context, bkt objstore.BucketReader, name string) (_ string, err error) {
+	r, err := bkt.Get(ctx, name)
+	if err != nil {
+		return "", errors.Wrapf(err, "get %v", name)
+	}
+	defer runutil.CloseWithErrCapture(&err, r, "close %v", name)
+
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", errors.Wrapf(err, "hash %v", name)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// maxBlockIndexSize is the default maximum index size of compacted blocks used by the compactor.
+const maxBlockIndexSize = 64 << 30
+
+// groupsFilter keeps only blocks with one of the given external label sets.
+type groupsFilter map[string]struct{}
+
+func (f groupsFilter) Filter(_ context.Context, metas map[ulid.ULID]*metadata.Meta, _ thanosblock.GaugeVec, _ thanosblock.GaugeVec) error {
+	for id, m := range metas {
+		if _, ok := f[labels.FromMap(m.Thanos.Labels).String()]; !ok {
+			delete(metas, id)
+		}
+	}
+	return nil
+}
+
+// compactTarget runs vertical compaction in the target bucket for blocks with the given external labels
+// until there is nothing left to compact.
+func compactTarget(ctx context.Context, logger log.Logger, bkt objstore.InstrumentedBucket, conf MigrateConfig, groups groupsFilter) error {
+	if err := os.MkdirAll(conf.CompactDir, os.ModePerm); err != nil {
+		return errors.Wrap(err, "create compact dir")
+	}
+
+	ignoreDeletionMarkFilter := thanosblock.NewIgnoreDeletionMarkFilter(logger, bkt, 0, thanosblock.FetcherConcurrency)
+	duplicateBlocksFilter := thanosblock.NewDeduplicateFilter(thanosblock.FetcherConcurrency)
+	noCompactMarkerFilter := compact.NewGatherNoCompactionMarkFilter(logger, bkt, thanosblock.FetcherConcurrency)
+	fetcher, err := thanosblock.NewMetaFetcher(logger, thanosblock.FetcherConcurrency, bkt, thanosblock.NewConcurrentLister(logger, bkt), "", nil, []thanosblock.MetadataFilter{
+		groups,
+		ignoreDeletionMarkFilter,
+		duplicateBlocksFilter,
+		noCompactMarkerFilter,
+	})
+	if err != nil {
+		return errors.Wrap(err, "create meta fetcher")
+	}
+
+	// Metrics are not registered, as compaction is repeated after every replication run.
+	stubCounter := promauto.With(nil).NewCounter(prometheus.CounterOpts{})
+	sy, err := compact.NewMetaSyncer(logger, nil, bkt, fetcher, duplicateBlocksFilter, ignoreDeletionMarkFilter, stubCounter, stubCounter, 0)
+	if err != nil {
+		return errors.Wrap(err, "create syncer")
+	}
+	comp, err := tsdb.NewLeveledCompactor(ctx, nil, logutil.GoKitLogToSlog(logger), conf.CompactionLevels, downsample.NewPool(), storage.NewCompactingChunkSeriesMerger(storage.ChainedSeriesMerge))
+	if err != nil {
+		return errors.Wrap(err, "create compactor")
+	}
+	grouper := compact.NewDefaultGrouper(logger, bkt, false, true, nil, stubCounter, stubCounter, stubCounter, metadata.NoneFunc, 1, 1)
+	planner := compact.WithVerticalCompactionDownsampleFilter(
+		compact.WithLargeTotalIndexSizeFilter(compact.NewPlanner(logger, conf.CompactionLevels, noCompactMarkerFilter), bkt, maxBlockIndexSize, stubCounter),
+		bkt, stubCounter,
+	)
+
+	bComp, err := compact.NewBucketCompactor(logger, sy, grouper, planner, comp, conf.CompactDir, bkt, 1, false, nil)
+	if err != nil {
+		return errors.Wrap(err, "create bucket compactor")
+	}
+	level.Info(logger).Log("msg", "compacting replicated blocks in target bucket", "groups", len(groups))
+	return bComp.Compact(ctx)
+}
@@ -77,6 +77,7 @@ func RunReplicate(
 	minTime, maxTime *thanosmodel.TimeOrDurationValue,
 	blockIDs []ulid.ULID,
 	ignoreMarkedForDeletion bool,
+	migrate MigrateConfig,
 ) error {
 	logger = log.With(logger, "component", "replicate")
 
@@ -189,7 +190,7 @@ func RunReplicate(
 		logger := log.With(logger, "replication-run-id", runID.String())
 		level.Info(logger).Log("msg", "running replication attempt")
 
-		if err := newReplicationScheme(logger, metrics, blockFilter, fetcher, fromBkt, toBkt, reg).execute(ctx); err != nil {
+		if err := newReplicationScheme(logger, metrics, blockFilter, fetcher, fromBkt, toBkt, migrate, reg).execute(ctx); err != nil {
 			return errors.Wrap(err, "replication execute")
 		}
 
@@ -110,6 +110,7 @@ type replicationScheme struct {
 
 	blockFilter blockFilterFunc
 	fetcher     thanosblock.MetadataFetcher
+	migrate     MigrateConfig
 
 	logger  log.Logger
 	metrics *replicationMetrics
@@ -148,6 +149,7 @@ func newReplicationScheme(
 	fetcher thanosblock.MetadataFetcher,
 	from objstore.InstrumentedBucketReader,
 	to objstore.Bucket,
+	migrate MigrateConfig,
 	reg prometheus.Registerer,
 ) *replicationScheme {
 	if logger == nil {
@@ -158,6 +160,7 @@ func newReplicationScheme(
 		logger:      logger,
 		blockFilter: blockFilter,
 		fetcher:     fetcher,
+		migrate:     migrate,
 		fromBkt:     from,
 		toBkt:       to,
 		metrics:     metrics,
@@ -177,11 +180,19 @@ func (rs *replicationScheme) execute(ctx context.Context) error {
 		level.Info(rs.logger).Log("msg", "block meta not uploaded yet. Skipping.", "block_uuid", id.String())
 	}
 
+	groups := groupsFilter{}
 	for id, meta := range metas {
-		if rs.blockFilter(meta) {
-			level.Info(rs.logger).Log("msg", "adding block to be replicated", "block_uuid", id.String())
-			availableBlocks = append(availableBlocks, meta)
+		if !rs.blockFilter(meta) {
+			continue
 		}
+		lset, keep := rs.migrate.rewriteLabels(meta)
+		if !keep {
+			level.Info(rs.logger).Log("msg", "skipping block dropped by relabeling", "block_uuid", id.String())
+			continue
+		}
+		level.Info(rs.logger).Log("msg", "adding block to be replicated", "block_uuid", id.String())
+		availableBlocks = append(availableBlocks, meta)
+		groups[lset.String()] = struct{}{}
 	}
 
 	// In order to prevent races in compactions by the target environment, we
@@ -196,6 +207,12 @@ func (rs *replicationScheme) execute(ctx context.Context) error {
 		}
 	}
 
+	if rs.migrate.VerticalCompaction && len(groups) > 0 {
+		if err := compactTarget(ctx, rs.logger, objstore.WithNoopInstr(rs.toBkt), rs.migrate, groups); err != nil {
+			return errors.Wrap(err, "compact target bucket")
+		}
+	}
+
 	return nil
 }
 
@@ -226,11 +243,14 @@ func (rs *replicationScheme) ensureBlockIsReplicated(ctx context.Context, id uli
 		return errors.Wrap(err, "get meta file from target bucket")
 	}
 
-	// TODO(bwplotka): Allow injecting custom labels as shipper does.
 	originMetaFileContent, err := io.ReadAll(originMetaFile)
 	if err != nil {
 		return errors.Wrap(err, "read origin meta file")
 	}
+	originMetaFileContent, meta, err := rs.migrate.rewriteMeta(originMetaFileContent)
+	if err != nil {
+		return err
+	}
 
 	if targetMetaFile != nil && !rs.toBkt.IsObjNotFoundErr(err) {
 		targetMetaFileContent, err := io.ReadAll(targetMetaFile)
@@ -249,11 +269,13 @@ func (rs *replicationScheme) ensureBlockIsReplicated(ctx context.Context, id uli
 		}
 	}
 
+	var objects []string
 	if err := rs.fromBkt.Iter(ctx, chunksDir, func(objectName string) error {
 		err := rs.ensureObjectReplicated(ctx, objectName)
 		if err != nil {
 			return errors.Wrapf(err, "replicate object %v", objectName)
 		}
+		objects = append(objects, objectName)
 
 		return nil
 	}); err != nil {
@@ -263,6 +285,14 @@ func (rs *replicationScheme) ensureBlockIsReplicated(ctx context.Context, id uli
 	if err := rs.ensureObjectReplicated(ctx, indexFile); err != nil {
 		return errors.Wrap(err, "replicate index file")
 	}
+	objects = append(objects, indexFile)
+
+	if rs.migrate.Verify {
+		if err := verifyReplicatedBlock(ctx, rs.fromBkt, rs.toBkt, meta, objects); err != nil {
+			return errors.Wrap(err, "verify replicated block")
+		}
+		level.Debug(rs.logger).Log("msg", "verified replicated block", "block_uuid", blockID)
+	}
 
 	level.Debug(rs.logger).Log("msg", "replicating meta file", "object", metaFile)
 
@@ -11,6 +11,8 @@ import (
 	"math/rand"
 	"os"
 	"path"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -23,9 +25,11 @@ import (
 	"github.com/thanos-io/objstore"
 
 	"github.com/efficientgo/core/testutil"
+	thanosblock "github.com/thanos-io/thanos/pkg/block"
 	"github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/compact"
 	"github.com/thanos-io/thanos/pkg/model"
+	"github.com/thanos-io/thanos/pkg/testutil/e2eutil"
 )
 
 var (
@@ -393,7 +397,7 @@ func TestReplicationSchemeAll(t *testing.T) {
 		)
 		testutil.Ok(t, err)
 
-		r := newReplicationScheme(logger, newReplicationMetrics(nil), filter, fetcher, objstore.WithNoopInstr(originBucket), targetBucket, nil)
+		r := newReplicationScheme(logger, newReplicationMetrics(nil), filter, fetcher, objstore.WithNoopInstr(originBucket), targetBucket, MigrateConfig{}, nil)
 
 		err = r.execute(ctx)
 		testutil.Ok(t, err)
@@ -401,3 +405,106 @@ func TestReplicationSchemeAll(t *testing.T) {
 		c.assert(ctx, t, originBucket, targetBucket)
 	}
 }
+
+func TestReplicationSchemeMigrate(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+
+	relabelConfig, err := thanosblock.ParseRelabelConfig([]byte(`
+- source_labels: [cluster]
+  target_label: region
+- action: labeldrop
+  regex: cluster
+- target_label: region
+  replacement: eu
+`), nil)
+	testutil.Ok(t, err)
+
+	prepare := func(t *testing.T) (*objstore.InMemBucket, []ulid.ULID) {
+		originBucket := objstore.NewInMemBucket()
+		dir := t.TempDir()
+		series := []labels.Labels{labels.FromStrings("a", "1"), labels.FromStrings("a", "2")}
+
+		var ids []ulid.ULID
+		for _, cluster := range []string{"a", "b"} {
+			id, err := e2eutil.CreateBlock(ctx, dir, series, 100, 0, 1000, labels.FromStrings("cluster", cluster), 0, metadata.SHA256Func, nil)
+			testutil.Ok(t, err)
+			testutil.Ok(t, thanosblock.Upload(ctx, logger, originBucket, path.Join(dir, id.String()), metadata.SHA256Func))
+			ids = append(ids, id)
+		}
+		return originBucket, ids
+	}
+	execute := func(t *testing.T, originBucket, targetBucket *objstore.InMemBucket, conf MigrateConfig) error {
+		selector := labels.Selector{labels.MustNewMatcher(labels.MatchRegexp, "cluster", "a|b")}
+		filter := NewBlockFilter(logger, selector, []compact.ResolutionLevel{compact.ResolutionLevelRaw}, []int{1}, nil).Filter
+		fetcher, err := newMetaFetcher(logger, objstore.WithNoopInstr(originBucket), nil, minTimeDuration, maxTimeDuration, 32, false)
+		testutil.Ok(t, err)
+		return newReplicationScheme(logger, newReplicationMetrics(nil), filter, fetcher, objstore.WithNoopInstr(originBucket), targetBucket, conf, nil).execute(ctx)
+	}
+
+	t.Run("rewrite labels and compact", func(t *testing.T) {
+		originBucket, ids := prepare(t)
+		targetBucket := objstore.NewInMemBucket()
+
+		testutil.Ok(t, execute(t, originBucket, targetBucket, MigrateConfig{
+			RelabelConfigs:     relabelConfig,
+			Verify:             true,
+			VerticalCompaction: true,
+			CompactDir:         t.TempDir(),
+			CompactionLevels:   []int64{1000, 3000},
+		}))
+
+		for _, id := range ids {
+			meta, err := thanosblock.DownloadMeta(ctx, logger, targetBucket, id)
+			testutil.Ok(t, err)
+			testutil.Equals(t, map[string]string{"region": "eu"}, meta.Thanos.Labels)
+
+			// Both blocks overlap after merging their labels, so they are compacted vertically.
+			exists, err := targetBucket.Exists(ctx, path.Join(id.String(), metadata.DeletionMarkFilename))
+			testutil.Ok(t, err)
+			testutil.Assert(t, exists, "expected replicated block %v to be marked for deletion after compaction", id)
+		}
+
+		var compacted []*metadata.Meta
+		testutil.Ok(t, targetBucket.Iter(ctx, "", func(name string) error {
+			id, ok := thanosblock.IsBlockDir(name)
+			if !ok || slices.Contains(ids, id) {
+				return nil
+			}
+			meta, err := thanosblock.DownloadMeta(ctx, logger, targetBucket, id)
+			if err != nil {
+				return err
+			}
+			compacted = append(compacted, &meta)
+			return nil
+		}))
+		testutil.Equals(t, 1, len(compacted))
+		testutil.Equals(t, map[string]string{"region": "eu"}, compacted[0].Thanos.Labels)
+		testutil.Equals(t, uint64(200), compacted[0].Stats.NumSamples)
+		testutil.Equals(t, len(ids), len(compacted[0].Compaction.Sources))
+
+		// Origin blocks are left untouched.
+		for _, id := range ids {
+			meta, err := thanosblock.DownloadMeta(ctx, logger, originBucket, id)
+			testutil.Ok(t, err)
+			_, ok := meta.Thanos.Labels["cluster"]
+			testutil.Assert(t, ok && len(meta.Thanos.Labels) == 1, "unexpected origin labels %v", meta.Thanos.Labels)
+		}
+	})
+
+	t.Run("verification failure", func(t *testing.T) {
+		originBucket, ids := prepare(t)
+		targetBucket := objstore.NewInMemBucket()
+
+		// Existing objects are not replicated again, so a broken copy is only detected by verification.
+		testutil.Ok(t, targetBucket.Upload(ctx, path.Join(ids[0].String(), thanosblock.IndexFilename), bytes.NewReader([]byte("broken"))))
+
+		err := execute(t, originBucket, targetBucket, MigrateConfig{RelabelConfigs: relabelConfig, Verify: true})
+		testutil.NotOk(t, err)
+		testutil.Assert(t, strings.Contains(err.Error(), "verify replicated block"), "unexpected error: %v", err)
+
+		exists, err := targetBucket.Exists(ctx, path.Join(ids[0].String(), metadata.MetaFilename))
+		testutil.Ok(t, err)
+		testutil.Assert(t, !exists, "meta.json of a block failing verification should not be replicated")
+	})
+}
@@ -982,12 +982,14 @@ func (s *BucketStore) TSDBInfos() []infopb.TSDBInfo {
 			Labels: labelpb.ZLabelSet{
 				Labels: labelpb.ZLabelsFromPromLabels(lbls),
 			},
-			MinTime: b.meta.MinTime,
-			MaxTime: b.meta.MaxTime,
+			MinTime:   b.meta.MinTime,
+			MaxTime:   b.meta.MaxTime,
+			NumSeries: b.meta.Stats.NumSeries,
 		})
 	}
 
-	// join adjacent blocks so we emit less TSDBInfos
+	// join adjacent blocks so we emit less TSDBInfos, series of joined blocks mostly overlap
+	// so the largest number of series of them is kept
 	res := make([]infopb.TSDBInfo, 0, len(s.blocks))
 	for _, infos := range infoMap {
 		sort.Slice(infos, func(i, j int) bool { return infos[i].MinTime < infos[j].MinTime })
@@ -1000,6 +1002,7 @@ func (s *BucketStore) TSDBInfos() []infopb.TSDBInfo {
 				continue
 			}
 			cur.MaxTime = info.MaxTime
+			cur.NumSeries = max(cur.NumSeries, info.NumSeries)
 		}
 		res = append(res, cur)
 	}
@@ -1482,6 +1485,20 @@ func populateChunk(out *storepb.AggrChunk, in chunkenc.Chunk, aggrs []storepb.Ag
 				return err
 			}
 			out.Counter = &storepb.Chunk{Type: chunkToStoreEncoding(x.Encoding()), Data: b, Hash: hashChunk(hasher, b, calculateChecksum)}
+		case storepb.Aggr_LAST:
+			x, err := ac.Get(downsample.AggrLast)
+			if err == downsample.ErrAggrNotExist {
+				// Only native histogram aggregates have the last samples.
+				continue
+			}
+			if err != nil {
+				return errors.Wrapf(err, "get aggregate %s", downsample.AggrLast)
+			}
+			b, err := save(x.Bytes())
+			if err != nil {
+				return err
+			}
+			out.Last = &storepb.Chunk{Type: chunkToStoreEncoding(x.Encoding()), Data: b, Hash: hashChunk(hasher, b, calculateChecksum)}
 		}
 	}
 	return nil
@@ -1817,7 +1834,8 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, seriesSrv storepb.Store
 		return err
 	}
 
-	if s.enableSeriesResponseHints {
+	// Callers asking for query stats handle hints, even if they are not enabled for backward compatibility.
+	if s.enableSeriesResponseHints || queryStatsEnabled {
 		var anyHints *types.Any
 
 		if queryStatsEnabled {
@@ -2296,8 +2314,8 @@ type bucketBlockSet struct {
 	blocks      [][]*bucketBlock // Ordered buckets for the existing resolutions.
 }
 
-// newBucketBlockSet initializes a new set with the known downsampling windows hard-configured.
-// The set currently does not support arbitrary ranges.
+// newBucketBlockSet initializes a new set with the default downsampling windows. Blocks of other
+// resolutions, e.g. produced by a custom downsampling ladder, add their resolution on demand.
 func newBucketBlockSet(lset labels.Labels) *bucketBlockSet {
 	return &bucketBlockSet{
 		labels:      lset,
@@ -2313,9 +2331,16 @@ func (s *bucketBlockSet) add(b *bucketBlock) error {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
-	i := int64index(s.resolutions, b.meta.Thanos.Downsample.Resolution)
+	res := b.meta.Thanos.Downsample.Resolution
+	if res < 0 {
+		return errors.Errorf("unsupported downsampling resolution %d", res)
+	}
+	i := int64index(s.resolutions, res)
 	if i < 0 {
-		return errors.Errorf("unsupported downsampling resolution %d", b.meta.Thanos.Downsample.Resolution)
+		// Keep resolutions ordered from high to low.
+		i = sort.Search(len(s.resolutions), func(j int) bool { return s.resolutions[j] < res })
+		s.resolutions = slices.Insert(s.resolutions, i, res)
+		s.blocks = slices.Insert(s.blocks, i, nil)
 	}
 	bs := append(s.blocks[i], b)
 	s.blocks[i] = bs
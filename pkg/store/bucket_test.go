@@ -504,6 +504,32 @@ func TestBucketBlockSet_remove(t *testing.T) {
 	testutil.Equals(t, input[2].id, res[1].meta.ULID)
 }
 
+func TestBucketBlockSet_customResolution(t *testing.T) {
+	t.Parallel()
+
+	set := newBucketBlockSet(labels.Labels{})
+
+	for i, res := range []int64{0, 15 * 60 * 1000, downsample.ResLevel2} {
+		var m metadata.Meta
+		m.ULID = ulid.MustNew(uint64(i+1), nil)
+		m.MinTime = int64(i) * 100
+		m.MaxTime = int64(i+1) * 100
+		m.Thanos.Downsample.Resolution = res
+		testutil.Ok(t, set.add(&bucketBlock{meta: &m}))
+	}
+	testutil.Equals(t, []int64{downsample.ResLevel2, 15 * 60 * 1000, downsample.ResLevel1, downsample.ResLevel0}, set.resolutions)
+
+	// Blocks of resolutions higher than the max one are not returned.
+	res := set.getFor(0, 300, 30*60*1000, nil)
+	testutil.Equals(t, 2, len(res))
+	testutil.Equals(t, int64(0), res[0].meta.Thanos.Downsample.Resolution)
+	testutil.Equals(t, int64(15*60*1000), res[1].meta.Thanos.Downsample.Resolution)
+
+	var m metadata.Meta
+	m.Thanos.Downsample.Resolution = -1
+	testutil.NotOk(t, set.add(&bucketBlock{meta: &m}))
+}
+
 func TestBucketBlockSet_labelMatchers(t *testing.T) {
 	t.Parallel()
 
@@ -728,31 +754,37 @@ func TestBucketStore_TSDBInfo(t *testing.T) {
 	slices.SortFunc(infos, func(a, b infopb.TSDBInfo) int {
 		return strings.Compare(a.Labels.String(), b.Labels.String())
 	})
+	// Every block has a single series.
 	testutil.Equals(t, infos, []infopb.TSDBInfo{
 		{
-			Labels:  labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "a", Value: "b"}}},
-			MinTime: 0,
-			MaxTime: 2000,
+			Labels:    labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "a", Value: "b"}}},
+			MinTime:   0,
+			MaxTime:   2000,
+			NumSeries: 1,
 		},
 		{
-			Labels:  labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "a", Value: "b"}}},
-			MinTime: 3000,
-			MaxTime: 5000,
+			Labels:    labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "a", Value: "b"}}},
+			MinTime:   3000,
+			MaxTime:   5000,
+			NumSeries: 1,
 		},
 		{
-			Labels:  labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "a", Value: "c"}}},
-			MinTime: 0,
-			MaxTime: 2000,
+			Labels:    labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "a", Value: "c"}}},
+			MinTime:   0,
+			MaxTime:   2000,
+			NumSeries: 1,
 		},
 		{
-			Labels:  labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "a", Value: "d"}}},
-			MinTime: 0,
-			MaxTime: 1000,
+			Labels:    labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "a", Value: "d"}}},
+			MinTime:   0,
+			MaxTime:   1000,
+			NumSeries: 1,
 		},
 		{
-			Labels:  labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "a", Value: "d"}}},
-			MinTime: 2000,
-			MaxTime: 3000,
+			Labels:    labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "a", Value: "d"}}},
+			MinTime:   2000,
+			MaxTime:   3000,
+			NumSeries: 1,
 		},
 	})
 }
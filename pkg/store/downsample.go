@@ -0,0 +1,169 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package store
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+)
+
+var _ storepb.StoreServer = &downsamplingStoreServer{}
+
+// downsamplingStoreServer is a storepb.StoreServer which downsamples raw chunks at query time.
+type downsamplingStoreServer struct {
+	storepb.StoreServer
+
+	resolutions       []int64
+	downsampledChunks prometheus.Counter
+}
+
+// NewDownsamplingStoreServer returns a StoreServer which aggregates raw chunks of Series responses on the fly,
+// the same way the compactor downsamples raw blocks, when the request allows one of the given downsampling
+// resolutions, or the default ones if none are given. Raw chunks are only returned for such requests when no
+// downsampled data is available yet, e.g. for recent data or when downsampling is disabled, so this reduces
+// response sizes of long range queries.
+func NewDownsamplingStoreServer(store storepb.StoreServer, resolutions []int64, reg prometheus.Registerer) storepb.StoreServer {
+	if len(resolutions) == 0 {
+		resolutions = []int64{downsample.ResLevel1, downsample.ResLevel2}
+	}
+	return &downsamplingStoreServer{
+		StoreServer: store,
+		resolutions: resolutions,
+		downsampledChunks: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_store_query_time_downsampled_chunks_total",
+			Help: "Number of raw chunks downsampled at query time.",
+		}),
+	}
+}
+
+func (s *downsamplingStoreServer) Series(req *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+	resolution := downsampleResolution(s.resolutions, req.MaxResolutionWindow)
+	if resolution == 0 || req.SkipChunks || len(req.Aggregates) == 0 {
+		return s.StoreServer.Series(req, srv)
+	}
+	return s.StoreServer.Series(req, &downsamplingServer{
+		Store_SeriesServer: srv,
+		resolution:         resolution,
+		aggrs:              req.Aggregates,
+		downsampledChunks:  s.downsampledChunks,
+	})
+}
+
+// downsampleResolution returns the highest of the given downsampling resolutions allowed by the given
+// maximum resolution window, or zero if only raw data is allowed.
+func downsampleResolution(resolutions []int64, maxResolutionWindow int64) int64 {
+	var res int64
+	for _, r := range resolutions {
+		if r <= maxResolutionWindow && r > res {
+			res = r
+		}
+	}
+	return res
+}
+
+type downsamplingServer struct {
+	storepb.Store_SeriesServer
+
+	resolution        int64
+	aggrs             []storepb.Aggr
+	downsampledChunks prometheus.Counter
+}
+
+func (s *downsamplingServer) Send(resp *storepb.SeriesResponse) error {
+	if series := resp.GetSeries(); series != nil {
+		if err := s.downsample(series); err != nil {
+			return err
+		}
+	} else if batch := resp.GetBatch(); batch != nil {
+		for _, series := range batch.Series {
+			if series == nil {
+				continue
+			}
+			if err := s.downsample(series); err != nil {
+				return err
+			}
+		}
+	}
+	return s.Store_SeriesServer.Send(resp)
+}
+
+// downsample replaces raw chunks of the series with aggregated ones. Chunks which already hold
+// aggregates, e.g. from downsampled blocks, are kept.
+//
+// Merged responses of replicas hold overlapping raw chunks. They are split into runs of non-overlapping
+// chunks, like NewOverlapSplit does, and each run is downsampled on its own, so samples of replicas are
+// not aggregated together. The overlapping aggregated chunks are deduplicated by the querier the same
+// way as chunks of downsampled blocks of replicas.
+func (s *downsamplingServer) downsample(series *storepb.Series) error {
+	var (
+		out  = make([]storepb.AggrChunk, 0, len(series.Chunks))
+		runs [][]chunks.Meta
+	)
+
+chunksLoop:
+	for _, c := range series.Chunks {
+		if c.Raw == nil {
+			out = append(out, c)
+			continue
+		}
+		chk, err := chunkenc.FromData(storeToChunkEncoding(c.Raw.Type), c.Raw.Data)
+		if err != nil {
+			return errors.Wrapf(err, "decode chunk of series %s", labelpb.ZLabelsToPromLabels(series.Labels))
+		}
+		meta := chunks.Meta{MinTime: c.MinTime, MaxTime: c.MaxTime, Chunk: chk}
+		for i, run := range runs {
+			if run[len(run)-1].MaxTime < c.MinTime {
+				runs[i] = append(run, meta)
+				continue chunksLoop
+			}
+		}
+		runs = append(runs, []chunks.Meta{meta})
+	}
+	if len(runs) == 0 {
+		return nil
+	}
+
+	var raw int
+	for _, run := range runs {
+		res, err := downsample.DownsampleRawChunks(run, s.resolution)
+		if err != nil {
+			return errors.Wrapf(err, "downsample series %s", labelpb.ZLabelsToPromLabels(series.Labels))
+		}
+		for _, c := range res {
+			chk := storepb.AggrChunk{MinTime: c.MinTime, MaxTime: c.MaxTime}
+			if err := populateChunk(&chk, c.Chunk, s.aggrs, func(b []byte) ([]byte, error) { return b, nil }, false); err != nil {
+				return errors.Wrapf(err, "encode downsampled chunk of series %s", labelpb.ZLabelsToPromLabels(series.Labels))
+			}
+			out = append(out, chk)
+		}
+		raw += len(run)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].MinTime < out[j].MinTime
+	})
+	series.Chunks = out
+	s.downsampledChunks.Add(float64(raw))
+	return nil
+}
+
+func storeToChunkEncoding(in storepb.Chunk_Encoding) chunkenc.Encoding {
+	switch in {
+	case storepb.Chunk_XOR:
+		return chunkenc.EncXOR
+	case storepb.Chunk_HISTOGRAM:
+		return chunkenc.EncHistogram
+	case storepb.Chunk_FLOAT_HISTOGRAM:
+		return chunkenc.EncFloatHistogram
+	}
+	return chunkenc.EncNone
+}
@@ -0,0 +1,220 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+)
+
+type staticSeriesStoreServer struct {
+	storepb.UnimplementedStoreServer
+
+	resps []*storepb.SeriesResponse
+}
+
+func (s *staticSeriesStoreServer) Series(_ *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+	for _, r := range s.resps {
+		if err := srv.Send(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestDownsamplingStoreServer_Series(t *testing.T) {
+	t.Parallel()
+
+	// Two hours of samples scraped every 15 seconds, cut into chunks of 120 samples.
+	var smpls []sample
+	for ts := int64(0); ts < 2*60*60*1000; ts += 15 * 1000 {
+		smpls = append(smpls, sample{t: ts, v: float64(ts / 1000)})
+	}
+	rawSeries := func() *storepb.SeriesResponse {
+		var chks [][]sample
+		for i := 0; i < len(smpls); i += 120 {
+			chks = append(chks, smpls[i:min(i+120, len(smpls))])
+		}
+		return storeSeriesResponse(t, labels.FromStrings("a", "b"), chks...)
+	}
+	rawSamples := len(smpls)
+
+	for _, tc := range []struct {
+		title               string
+		maxResolutionWindow int64
+		aggrs               []storepb.Aggr
+
+		expectDownsampled bool
+	}{
+		{
+			title:               "raw resolution requested",
+			maxResolutionWindow: 0,
+			aggrs:               []storepb.Aggr{storepb.Aggr_COUNT, storepb.Aggr_SUM},
+		},
+		{
+			title:               "no aggregates requested",
+			maxResolutionWindow: downsample.ResLevel1,
+		},
+		{
+			title:               "5m resolution allowed",
+			maxResolutionWindow: downsample.ResLevel1,
+			aggrs:               []storepb.Aggr{storepb.Aggr_COUNT, storepb.Aggr_SUM},
+			expectDownsampled:   true,
+		},
+		{
+			title:               "counter aggregate",
+			maxResolutionWindow: downsample.ResLevel1,
+			aggrs:               []storepb.Aggr{storepb.Aggr_COUNTER},
+			expectDownsampled:   true,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			s := NewDownsamplingStoreServer(&staticSeriesStoreServer{resps: []*storepb.SeriesResponse{rawSeries()}}, nil, prometheus.NewRegistry())
+
+			srv := newStoreSeriesServer(context.Background())
+			testutil.Ok(t, s.Series(&storepb.SeriesRequest{
+				MinTime:             0,
+				MaxTime:             2 * 60 * 60 * 1000,
+				MaxResolutionWindow: tc.maxResolutionWindow,
+				Aggregates:          tc.aggrs,
+			}, srv))
+			testutil.Equals(t, 1, len(srv.SeriesSet))
+
+			chks := srv.SeriesSet[0].Chunks
+			if !tc.expectDownsampled {
+				testutil.Equals(t, rawSeries().GetSeries().Chunks, chks)
+				return
+			}
+
+			var count, samples int
+			for _, c := range chks {
+				testutil.Assert(t, c.Raw == nil, "expected no raw chunks")
+				for _, aggr := range tc.aggrs {
+					x := aggrChunkFor(c, aggr)
+					testutil.Assert(t, x != nil, "expected %s aggregate", aggr)
+					chk, err := chunkenc.FromData(chunkenc.EncXOR, x.Data)
+					testutil.Ok(t, err)
+					samples += chk.NumSamples()
+
+					if aggr != storepb.Aggr_COUNT {
+						continue
+					}
+					it := chk.Iterator(nil)
+					for it.Next() != chunkenc.ValNone {
+						_, v := it.At()
+						count += int(v)
+					}
+					testutil.Ok(t, it.Err())
+				}
+			}
+			// Each 5m window holds 20 raw samples.
+			testutil.Assert(t, samples/len(tc.aggrs) < rawSamples/10, "expected far fewer samples after downsampling, got %d", samples)
+			if count > 0 {
+				testutil.Equals(t, rawSamples, count)
+			}
+		})
+	}
+}
+
+func aggrChunkFor(c storepb.AggrChunk, aggr storepb.Aggr) *storepb.Chunk {
+	switch aggr {
+	case storepb.Aggr_COUNT:
+		return c.Count
+	case storepb.Aggr_SUM:
+		return c.Sum
+	case storepb.Aggr_MIN:
+		return c.Min
+	case storepb.Aggr_MAX:
+		return c.Max
+	case storepb.Aggr_COUNTER:
+		return c.Counter
+	}
+	return nil
+}
+
+func TestDownsamplingStoreServer_Series_OverlappingChunks(t *testing.T) {
+	t.Parallel()
+
+	// Two replicas scraping the same series every 15 seconds with different offsets, merged into one series.
+	replica := func(offset int64) [][]sample {
+		var chks [][]sample
+		for start := int64(0); start < 60*60*1000; start += 30 * 60 * 1000 {
+			var chk []sample
+			for ts := start + offset; ts < start+30*60*1000; ts += 15 * 1000 {
+				chk = append(chk, sample{t: ts, v: 1})
+			}
+			chks = append(chks, chk)
+		}
+		return chks
+	}
+	a, b := replica(0), replica(5*1000)
+	merged := storeSeriesResponse(t, labels.FromStrings("a", "b"), a[0], b[0], a[1], b[1])
+
+	s := NewDownsamplingStoreServer(&staticSeriesStoreServer{resps: []*storepb.SeriesResponse{merged}}, nil, prometheus.NewRegistry())
+	srv := newStoreSeriesServer(context.Background())
+	testutil.Ok(t, s.Series(&storepb.SeriesRequest{
+		MinTime:             0,
+		MaxTime:             60 * 60 * 1000,
+		MaxResolutionWindow: downsample.ResLevel1,
+		Aggregates:          []storepb.Aggr{storepb.Aggr_COUNT},
+	}, srv))
+	testutil.Equals(t, 1, len(srv.SeriesSet))
+
+	// Each replica is downsampled on its own, so no 5m window counts samples of both replicas.
+	var prevMint int64
+	for _, c := range srv.SeriesSet[0].Chunks {
+		testutil.Assert(t, c.MinTime >= prevMint, "expected chunks ordered by min time")
+		prevMint = c.MinTime
+
+		chk, err := chunkenc.FromData(chunkenc.EncXOR, c.Count.Data)
+		testutil.Ok(t, err)
+		it := chk.Iterator(nil)
+		for it.Next() != chunkenc.ValNone {
+			_, v := it.At()
+			testutil.Assert(t, v <= 20, "expected at most 20 samples per 5m window, got %v", v)
+		}
+		testutil.Ok(t, it.Err())
+	}
+}
+
+func TestDownsamplingStoreServer_Series_InvalidChunk(t *testing.T) {
+	t.Parallel()
+
+	resp := storepb.NewSeriesResponse(&storepb.Series{
+		Labels: labelpb.ZLabelsFromPromLabels(labels.FromStrings("a", "b")),
+		Chunks: []storepb.AggrChunk{{MinTime: 0, MaxTime: 10, Raw: &storepb.Chunk{Type: storepb.Chunk_Encoding(42), Data: []byte{0, 0}}}},
+	})
+	s := NewDownsamplingStoreServer(&staticSeriesStoreServer{resps: []*storepb.SeriesResponse{resp}}, nil, prometheus.NewRegistry())
+	srv := newStoreSeriesServer(context.Background())
+	testutil.NotOk(t, s.Series(&storepb.SeriesRequest{
+		MaxTime:             10,
+		MaxResolutionWindow: downsample.ResLevel1,
+		Aggregates:          []storepb.Aggr{storepb.Aggr_COUNT},
+	}, srv))
+}
+
+func TestDownsampleResolution(t *testing.T) {
+	t.Parallel()
+
+	defaults := []int64{downsample.ResLevel1, downsample.ResLevel2}
+	testutil.Equals(t, int64(0), downsampleResolution(defaults, 0))
+	testutil.Equals(t, int64(0), downsampleResolution(defaults, downsample.ResLevel1-1))
+	testutil.Equals(t, downsample.ResLevel1, downsampleResolution(defaults, downsample.ResLevel1))
+	testutil.Equals(t, downsample.ResLevel1, downsampleResolution(defaults, downsample.ResLevel2-1))
+	testutil.Equals(t, downsample.ResLevel2, downsampleResolution(defaults, downsample.ResLevel2))
+
+	// Resolutions of a custom downsampling ladder.
+	custom := []int64{60 * 1000, 15 * 60 * 1000}
+	testutil.Equals(t, int64(60*1000), downsampleResolution(custom, downsample.ResLevel1))
+	testutil.Equals(t, int64(15*60*1000), downsampleResolution(custom, downsample.ResLevel2))
+}
@@ -50,4 +50,6 @@ func (m *QueryStats) Merge(other *QueryStats) {
 
 	m.GetAllDuration += other.GetAllDuration
 	m.MergeDuration += other.MergeDuration
+
+	m.StoreStats = append(m.StoreStats, other.StoreStats...)
 }
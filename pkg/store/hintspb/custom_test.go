@@ -10,24 +10,25 @@ import (
 	"github.com/efficientgo/core/testutil"
 )
 
-func TestQueryStatsMerge(t *testing.T) {
-	s := &QueryStats{}
+// setIntFields sets all integer fields of the query stats to the given value.
+func setIntFields(s *QueryStats, v int64) {
 	ps := reflect.Indirect(reflect.ValueOf(s))
 	for i := 0; i < ps.NumField(); i++ {
-		ps.FieldByIndex([]int{i}).SetInt(int64(1))
-	}
-	o := &QueryStats{}
-	po := reflect.Indirect(reflect.ValueOf(o))
-	for i := 0; i < po.NumField(); i++ {
-		po.FieldByIndex([]int{i}).SetInt(int64(100))
+		if f := ps.FieldByIndex([]int{i}); f.CanInt() {
+			f.SetInt(v)
+		}
 	}
+}
+
+func TestQueryStatsMerge(t *testing.T) {
+	s := &QueryStats{StoreStats: []StoreStats{{Store: "a", Series: 1}}}
+	setIntFields(s, 1)
+	o := &QueryStats{StoreStats: []StoreStats{{Store: "b", Series: 100}}}
+	setIntFields(o, 100)
 	s.Merge(o)
 
 	// Expected stats.
-	e := &QueryStats{}
-	pe := reflect.Indirect(reflect.ValueOf(e))
-	for i := 0; i < pe.NumField(); i++ {
-		pe.FieldByIndex([]int{i}).SetInt(int64(101))
-	}
+	e := &QueryStats{StoreStats: []StoreStats{{Store: "a", Series: 1}, {Store: "b", Series: 100}}}
+	setIntFields(e, 101)
 	testutil.Equals(t, e, s)
 }
@@ -328,6 +328,8 @@ type QueryStats struct {
 	DataDownloadedSizeSum  int64         `protobuf:"varint,20,opt,name=data_downloaded_size_sum,json=dataDownloadedSizeSum,proto3" json:"data_downloaded_size_sum,omitempty"`
 	GetAllDuration         time.Duration `protobuf:"bytes,21,opt,name=get_all_duration,json=getAllDuration,proto3,stdduration" json:"get_all_duration"`
 	MergeDuration          time.Duration `protobuf:"bytes,22,opt,name=merge_duration,json=mergeDuration,proto3,stdduration" json:"merge_duration"`
+	/// store_stats contains statistics of every StoreAPI queried through the proxy store.
+	StoreStats []StoreStats `protobuf:"bytes,23,rep,name=store_stats,json=storeStats,proto3" json:"store_stats"`
 }
 
 func (m *QueryStats) Reset()         { *m = QueryStats{} }
@@ -363,6 +365,49 @@ func (m *QueryStats) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_QueryStats proto.InternalMessageInfo
 
+// / StoreStats contains statistics of streaming the series of a single StoreAPI.
+type StoreStats struct {
+	Store          string        `protobuf:"bytes,1,opt,name=store,proto3" json:"store,omitempty"`
+	Duration       time.Duration `protobuf:"bytes,2,opt,name=duration,proto3,stdduration" json:"duration"`
+	BytesProcessed int64         `protobuf:"varint,3,opt,name=bytes_processed,json=bytesProcessed,proto3" json:"bytes_processed,omitempty"`
+	Series         int64         `protobuf:"varint,4,opt,name=series,proto3" json:"series,omitempty"`
+	Chunks         int64         `protobuf:"varint,5,opt,name=chunks,proto3" json:"chunks,omitempty"`
+	Samples        int64         `protobuf:"varint,6,opt,name=samples,proto3" json:"samples,omitempty"`
+}
+
+func (m *StoreStats) Reset()         { *m = StoreStats{} }
+func (m *StoreStats) String() string { return proto.CompactTextString(m) }
+func (*StoreStats) ProtoMessage()    {}
+func (*StoreStats) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b82aa23c4c11e83f, []int{8}
+}
+func (m *StoreStats) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *StoreStats) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_StoreStats.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *StoreStats) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StoreStats.Merge(m, src)
+}
+func (m *StoreStats) XXX_Size() int {
+	return m.Size()
+}
+func (m *StoreStats) XXX_DiscardUnknown() {
+	xxx_messageInfo_StoreStats.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StoreStats proto.InternalMessageInfo
+
 func init() {
 	proto.RegisterType((*SeriesRequestHints)(nil), "hintspb.SeriesRequestHints")
 	proto.RegisterType((*SeriesResponseHints)(nil), "hintspb.SeriesResponseHints")
@@ -372,58 +417,66 @@ func init() {
 	proto.RegisterType((*LabelValuesRequestHints)(nil), "hintspb.LabelValuesRequestHints")
 	proto.RegisterType((*LabelValuesResponseHints)(nil), "hintspb.LabelValuesResponseHints")
 	proto.RegisterType((*QueryStats)(nil), "hintspb.QueryStats")
+	proto.RegisterType((*StoreStats)(nil), "hintspb.StoreStats")
 }
 
 func init() { proto.RegisterFile("store/hintspb/hints.proto", fileDescriptor_b82aa23c4c11e83f) }
 
 var fileDescriptor_b82aa23c4c11e83f = []byte{
-	// 731 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x55, 0xcd, 0x4f, 0xdb, 0x30,
-	0x1c, 0x6d, 0x28, 0x1f, 0xc5, 0x1d, 0xa1, 0xb8, 0x05, 0x02, 0x87, 0x80, 0x2a, 0x21, 0xb1, 0x09,
-	0xa5, 0x13, 0xd3, 0x34, 0x6d, 0x3b, 0xf1, 0x21, 0x34, 0x4d, 0x63, 0x12, 0xe9, 0xc4, 0xa4, 0x6d,
-	0x92, 0x95, 0x34, 0x26, 0x8d, 0x48, 0xe3, 0x12, 0x3b, 0x9a, 0xe0, 0xbe, 0xeb, 0xb4, 0xe3, 0xfe,
-	0x24, 0x8e, 0x1c, 0x77, 0xda, 0x07, 0x68, 0xff, 0xc7, 0x14, 0x7f, 0x34, 0x4e, 0xb9, 0xec, 0xd0,
-	0x0b, 0xb4, 0xef, 0xf7, 0xde, 0xf3, 0x7b, 0x76, 0x1a, 0x83, 0x35, 0xca, 0x48, 0x8a, 0x3b, 0xfd,
-	0x28, 0x61, 0x74, 0xe8, 0x8b, 0xff, 0xce, 0x30, 0x25, 0x8c, 0xc0, 0x39, 0x09, 0xae, 0xb7, 0x42,
-	0x12, 0x12, 0x8e, 0x75, 0xf2, 0x4f, 0x62, 0xbc, 0x6e, 0x87, 0x84, 0x84, 0x31, 0xee, 0xf0, 0x6f,
-	0x7e, 0x76, 0xd6, 0x09, 0xb2, 0xd4, 0x63, 0x11, 0x49, 0xe4, 0x5c, 0x3a, 0xf3, 0xbf, 0x43, 0xbf,
-	0xc3, 0x2e, 0x87, 0x58, 0x3a, 0xb7, 0xbf, 0x18, 0x00, 0x76, 0x71, 0x1a, 0x61, 0xea, 0xe2, 0x8b,
-	0x0c, 0x53, 0xf6, 0x2a, 0x5f, 0x09, 0xee, 0x01, 0xd3, 0x8f, 0x49, 0xef, 0x1c, 0x0d, 0x3c, 0xd6,
-	0xeb, 0xe3, 0x94, 0x5a, 0xc6, 0x66, 0x75, 0xbb, 0xbe, 0xdb, 0x72, 0x58, 0xdf, 0x4b, 0x08, 0x75,
-	0xde, 0x78, 0x3e, 0x8e, 0x8f, 0xc5, 0x70, 0x7f, 0xfa, 0xfa, 0xe7, 0x46, 0xc5, 0x5d, 0xe0, 0x0a,
-	0x89, 0x51, 0xb8, 0x03, 0x20, 0x4e, 0x3c, 0x3f, 0xc6, 0xe8, 0x22, 0xc3, 0xe9, 0x25, 0xa2, 0xcc,
-	0x63, 0xd4, 0x9a, 0xda, 0x34, 0xb6, 0x6b, 0x6e, 0x43, 0x4c, 0x4e, 0xf2, 0x41, 0x37, 0xc7, 0xdb,
-	0x5f, 0x0d, 0xd0, 0x54, 0x39, 0xe8, 0x90, 0x24, 0x14, 0x8b, 0x20, 0x2f, 0x81, 0x99, 0xcb, 0x23,
-	0x1c, 0x20, 0x6e, 0xaf, 0x82, 0x98, 0x8e, 0xdc, 0x12, 0x67, 0x3f, 0x87, 0x55, 0x04, 0xc9, 0xe5,
-	0x18, 0x85, 0x2f, 0x40, 0x7d, 0x7c, 0xed, 0xfa, 0x6e, 0x73, 0xa4, 0x2c, 0x96, 0xe7, 0x72, 0xc3,
-	0x05, 0x17, 0x45, 0xa0, 0x55, 0x30, 0xc3, 0x5d, 0xa0, 0x09, 0xa6, 0xa2, 0xc0, 0x32, 0x36, 0x8d,
-	0xed, 0x79, 0x77, 0x2a, 0x0a, 0xda, 0x1f, 0xc1, 0x0a, 0x2f, 0xff, 0xd6, 0x1b, 0x4c, 0x7c, 0xd3,
-	0xda, 0xa7, 0x60, 0x55, 0x37, 0x9f, 0xd4, 0x4e, 0xb4, 0x3f, 0x49, 0xdf, 0x53, 0x2f, 0xce, 0x26,
-	0x9f, 0xfa, 0x3d, 0xb0, 0x4a, 0xee, 0x13, 0x8b, 0xfd, 0xb7, 0x06, 0x40, 0x71, 0x4a, 0x70, 0x4b,
-	0x46, 0xa5, 0x48, 0xd2, 0xf8, 0xb1, 0x54, 0x65, 0x1c, 0x7a, 0x22, 0x40, 0xe8, 0x80, 0xe6, 0x00,
-	0xa7, 0x21, 0x0e, 0x10, 0xe5, 0x4f, 0x14, 0xea, 0x91, 0x2c, 0x61, 0xfc, 0xf8, 0xab, 0xee, 0x92,
-	0x18, 0x89, 0x67, 0xed, 0x20, 0x1f, 0x68, 0xfc, 0x5e, 0x3f, 0x4b, 0xce, 0x15, 0xbf, 0xaa, 0xf3,
-	0x0f, 0xf8, 0x44, 0xf0, 0x1f, 0x82, 0xc6, 0x90, 0x50, 0x16, 0x25, 0x21, 0x45, 0x8c, 0x64, 0xbd,
-	0x3e, 0x0e, 0xac, 0x69, 0x4e, 0x5e, 0x54, 0xf8, 0x3b, 0x01, 0xc3, 0xe7, 0x60, 0x6d, 0x9c, 0x8a,
-	0x68, 0x74, 0x85, 0x11, 0xcd, 0x06, 0xd6, 0x0c, 0xd7, 0xac, 0x8c, 0x69, 0xba, 0xd1, 0x15, 0xee,
-	0x66, 0x03, 0xf8, 0x08, 0x2c, 0x69, 0x52, 0x74, 0x86, 0x59, 0xaf, 0x6f, 0xcd, 0x8e, 0x2f, 0x73,
-	0x94, 0xc3, 0xa5, 0x44, 0x9c, 0x88, 0x03, 0x6b, 0xae, 0x4c, 0x3d, 0x12, 0x70, 0x29, 0x91, 0xa4,
-	0x16, 0x89, 0x6a, 0xe5, 0x44, 0x52, 0xa3, 0x12, 0x3d, 0x06, 0xad, 0xb2, 0x54, 0x6e, 0xd4, 0x3c,
-	0x57, 0xc1, 0x92, 0x4a, 0xec, 0xd4, 0x16, 0x30, 0xe5, 0x11, 0xa8, 0x7d, 0x02, 0xe2, 0xc0, 0x04,
-	0xaa, 0x76, 0xe9, 0x29, 0x58, 0x2d, 0xd3, 0x8a, 0x44, 0x75, 0xce, 0x6f, 0x95, 0xf8, 0x2a, 0x4f,
-	0xe1, 0xae, 0x3a, 0x3f, 0xd0, 0xdd, 0x55, 0xe3, 0xc2, 0xfd, 0x5e, 0xdf, 0x05, 0xdd, 0x7d, 0xac,
-	0xed, 0x0e, 0x80, 0xba, 0x4c, 0x76, 0x35, 0xb9, 0xa2, 0xa1, 0x29, 0x46, 0x4d, 0xe5, 0xc3, 0xa3,
-	0x9a, 0x2e, 0x8a, 0x2c, 0x02, 0xd5, 0x9a, 0x96, 0x69, 0x45, 0x96, 0x86, 0xc8, 0x52, 0xe2, 0x6b,
-	0x4d, 0xa5, 0x4c, 0x35, 0x5d, 0xd2, 0xdd, 0xb5, 0xa6, 0x65, 0x5a, 0xe1, 0x0e, 0x75, 0xf7, 0xfb,
-	0x4d, 0x75, 0x99, 0x6c, 0xda, 0x14, 0x4d, 0x35, 0x85, 0x68, 0xfa, 0x0c, 0x58, 0x81, 0xc7, 0x3c,
-	0x14, 0x90, 0xcf, 0x49, 0x4c, 0xbc, 0x40, 0x5f, 0xa5, 0xc5, 0x35, 0xcb, 0xf9, 0xfc, 0x70, 0x34,
-	0x56, 0xcb, 0x1c, 0x83, 0x46, 0x88, 0x19, 0xf2, 0xe2, 0x18, 0xa9, 0xfb, 0xc9, 0x5a, 0xe6, 0xaf,
-	0xe4, 0x35, 0x47, 0x5c, 0x60, 0x8e, 0xba, 0xc0, 0x9c, 0x43, 0x49, 0xd8, 0xaf, 0xe5, 0xaf, 0x85,
-	0xef, 0xbf, 0x36, 0x0c, 0xd7, 0x0c, 0x31, 0xdb, 0x8b, 0x63, 0x35, 0x81, 0xaf, 0x81, 0xc9, 0x7f,
-	0x9a, 0x85, 0xd9, 0xca, 0xff, 0x9b, 0x2d, 0x70, 0xe9, 0x68, 0xb0, 0x75, 0xfd, 0xc7, 0xae, 0x5c,
-	0xdf, 0xda, 0xc6, 0xcd, 0xad, 0x6d, 0xfc, 0xbe, 0xb5, 0x8d, 0x6f, 0x77, 0x76, 0xe5, 0xe6, 0xce,
-	0xae, 0xfc, 0xb8, 0xb3, 0x2b, 0x1f, 0xd4, 0xed, 0xeb, 0xcf, 0x72, 0xcb, 0x27, 0xff, 0x02, 0x00,
-	0x00, 0xff, 0xff, 0x49, 0xb6, 0x83, 0x90, 0xaa, 0x07, 0x00, 0x00,
+	// 835 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0xcf, 0x6f, 0xe3, 0x44,
+	0x18, 0x8d, 0x93, 0x6d, 0x9b, 0x7e, 0xa1, 0x6e, 0x3a, 0xc9, 0x26, 0xee, 0x1e, 0xbc, 0x51, 0xa4,
+	0x8a, 0x82, 0x56, 0x0e, 0x5a, 0x84, 0x10, 0xcb, 0x01, 0x6d, 0x76, 0xb5, 0x42, 0x88, 0x22, 0xea,
+	0xa0, 0x22, 0x01, 0x92, 0x65, 0xc7, 0x53, 0xc7, 0xaa, 0xe3, 0x71, 0x3d, 0x63, 0xa1, 0xf6, 0xce,
+	0x15, 0x71, 0xe4, 0xca, 0x7f, 0xd3, 0x63, 0x4f, 0x88, 0x13, 0x3f, 0xda, 0x7f, 0x04, 0x79, 0x7e,
+	0xc4, 0xe3, 0xf4, 0xd2, 0x43, 0x2e, 0xbb, 0x9d, 0xef, 0x7b, 0xef, 0xcd, 0x7b, 0x33, 0xee, 0x7c,
+	0x85, 0x43, 0xca, 0x48, 0x8e, 0x27, 0x8b, 0x38, 0x65, 0x34, 0x0b, 0xc4, 0xff, 0x4e, 0x96, 0x13,
+	0x46, 0xd0, 0x8e, 0x2c, 0x3e, 0xeb, 0x47, 0x24, 0x22, 0xbc, 0x36, 0x29, 0x7f, 0x12, 0xed, 0x67,
+	0x76, 0x44, 0x48, 0x94, 0xe0, 0x09, 0x5f, 0x05, 0xc5, 0xf9, 0x24, 0x2c, 0x72, 0x9f, 0xc5, 0x24,
+	0x95, 0x7d, 0xa9, 0xcc, 0xff, 0xcd, 0x82, 0x09, 0xbb, 0xca, 0xb0, 0x54, 0x1e, 0xff, 0x62, 0x00,
+	0x9a, 0xe1, 0x3c, 0xc6, 0xd4, 0xc5, 0x97, 0x05, 0xa6, 0xec, 0xcb, 0x72, 0x27, 0xf4, 0x1a, 0xcc,
+	0x20, 0x21, 0xf3, 0x0b, 0x6f, 0xe9, 0xb3, 0xf9, 0x02, 0xe7, 0xd4, 0x32, 0x46, 0xad, 0xe3, 0xce,
+	0xcb, 0xbe, 0xc3, 0x16, 0x7e, 0x4a, 0xa8, 0xf3, 0xb5, 0x1f, 0xe0, 0xe4, 0x44, 0x34, 0xa7, 0x4f,
+	0x6e, 0xfe, 0x7e, 0xde, 0x70, 0xf7, 0x38, 0x43, 0xd6, 0x28, 0x7a, 0x01, 0x08, 0xa7, 0x7e, 0x90,
+	0x60, 0xef, 0xb2, 0xc0, 0xf9, 0x95, 0x47, 0x99, 0xcf, 0xa8, 0xd5, 0x1c, 0x19, 0xc7, 0x6d, 0xb7,
+	0x2b, 0x3a, 0xa7, 0x65, 0x63, 0x56, 0xd6, 0xc7, 0xbf, 0x1a, 0xd0, 0x53, 0x3e, 0x68, 0x46, 0x52,
+	0x8a, 0x85, 0x91, 0xcf, 0xc1, 0x2c, 0xe9, 0x31, 0x0e, 0x3d, 0x2e, 0xaf, 0x8c, 0x98, 0x8e, 0x3c,
+	0x12, 0x67, 0x5a, 0x96, 0x95, 0x05, 0x89, 0xe5, 0x35, 0x8a, 0x5e, 0x41, 0x67, 0x7d, 0xef, 0xce,
+	0xcb, 0xde, 0x8a, 0x59, 0x6d, 0xcf, 0xe9, 0x86, 0x0b, 0x97, 0x95, 0xa1, 0x21, 0x6c, 0x71, 0x15,
+	0x64, 0x42, 0x33, 0x0e, 0x2d, 0x63, 0x64, 0x1c, 0xef, 0xba, 0xcd, 0x38, 0x1c, 0xff, 0x08, 0x03,
+	0x1e, 0xfe, 0x1b, 0x7f, 0xb9, 0xf1, 0x43, 0x1b, 0x9f, 0xc1, 0x50, 0x17, 0xdf, 0xd4, 0x49, 0x8c,
+	0x7f, 0x92, 0xba, 0x67, 0x7e, 0x52, 0x6c, 0xde, 0xf5, 0xf7, 0x60, 0xd5, 0xd4, 0x37, 0x66, 0xfb,
+	0x8f, 0x5d, 0x80, 0xea, 0x96, 0xd0, 0x91, 0xb4, 0x4a, 0x3d, 0x09, 0xe3, 0xd7, 0xd2, 0x92, 0x76,
+	0xe8, 0xa9, 0x28, 0x22, 0x07, 0x7a, 0x4b, 0x9c, 0x47, 0x38, 0xf4, 0x68, 0x59, 0xa0, 0xde, 0x9c,
+	0x14, 0x29, 0xe3, 0xd7, 0xdf, 0x72, 0x0f, 0x44, 0x4b, 0x7c, 0x6b, 0x6f, 0xca, 0x86, 0x86, 0x9f,
+	0x2f, 0x8a, 0xf4, 0x42, 0xe1, 0x5b, 0x3a, 0xfe, 0x0d, 0xef, 0x08, 0xfc, 0x07, 0xd0, 0xcd, 0x08,
+	0x65, 0x71, 0x1a, 0x51, 0x8f, 0x91, 0x62, 0xbe, 0xc0, 0xa1, 0xf5, 0x84, 0x83, 0xf7, 0x55, 0xfd,
+	0x3b, 0x51, 0x46, 0x9f, 0xc1, 0xe1, 0x3a, 0xd4, 0xa3, 0xf1, 0x35, 0xf6, 0x68, 0xb1, 0xb4, 0xb6,
+	0x38, 0x67, 0xb0, 0xc6, 0x99, 0xc5, 0xd7, 0x78, 0x56, 0x2c, 0xd1, 0x87, 0x70, 0xa0, 0x51, 0xbd,
+	0x73, 0xcc, 0xe6, 0x0b, 0x6b, 0x7b, 0x7d, 0x9b, 0x77, 0x65, 0xb9, 0xe6, 0x88, 0x03, 0x71, 0x68,
+	0xed, 0xd4, 0xa1, 0xef, 0x30, 0x7b, 0xe0, 0x48, 0x42, 0x2b, 0x47, 0xed, 0xba, 0x23, 0xc9, 0x51,
+	0x8e, 0x3e, 0x82, 0x7e, 0x9d, 0x2a, 0x0f, 0x6a, 0x97, 0xb3, 0x50, 0x8d, 0x25, 0x4e, 0xea, 0x08,
+	0x4c, 0x79, 0x05, 0xea, 0x9c, 0x40, 0x5c, 0x98, 0xa8, 0xaa, 0x53, 0xfa, 0x04, 0x86, 0x75, 0x58,
+	0xe5, 0xa8, 0xc3, 0xf1, 0xfd, 0x1a, 0x5e, 0xf9, 0xa9, 0xd4, 0x55, 0xe6, 0xf7, 0x74, 0x75, 0x95,
+	0xb8, 0x52, 0x7f, 0x90, 0x77, 0x4f, 0x57, 0x5f, 0x4b, 0xfb, 0x02, 0x90, 0x4e, 0x93, 0x59, 0x4d,
+	0xce, 0xe8, 0x6a, 0x8c, 0x55, 0x52, 0xf9, 0xf1, 0xa8, 0xa4, 0xfb, 0xc2, 0x8b, 0xa8, 0x6a, 0x49,
+	0xeb, 0xb0, 0xca, 0x4b, 0x57, 0x78, 0xa9, 0xe1, 0xb5, 0xa4, 0x92, 0xa6, 0x92, 0x1e, 0xe8, 0xea,
+	0x5a, 0xd2, 0x3a, 0xac, 0x52, 0x47, 0xba, 0xfa, 0xc3, 0xa4, 0x3a, 0x4d, 0x26, 0xed, 0x89, 0xa4,
+	0x1a, 0x43, 0x24, 0xfd, 0x14, 0xac, 0xd0, 0x67, 0xbe, 0x17, 0x92, 0x9f, 0xd3, 0x84, 0xf8, 0xa1,
+	0xbe, 0x4b, 0x9f, 0x73, 0x9e, 0x96, 0xfd, 0xb7, 0xab, 0xb6, 0xda, 0xe6, 0x04, 0xba, 0x11, 0x66,
+	0x9e, 0x9f, 0x24, 0x9e, 0x9a, 0x4f, 0xd6, 0x53, 0xfe, 0x24, 0x1f, 0x3a, 0x62, 0x80, 0x39, 0x6a,
+	0x80, 0x39, 0x6f, 0x25, 0x60, 0xda, 0x2e, 0x9f, 0x85, 0xdf, 0xff, 0x79, 0x6e, 0xb8, 0x66, 0x84,
+	0xd9, 0xeb, 0x24, 0x51, 0x1d, 0xf4, 0x15, 0x98, 0xfc, 0x57, 0xb3, 0x12, 0x1b, 0x3c, 0x5e, 0x6c,
+	0x8f, 0x53, 0x57, 0x5a, 0xaf, 0xa0, 0xc3, 0x87, 0xa3, 0x1c, 0x14, 0xc3, 0x51, 0xab, 0x36, 0x28,
+	0x66, 0x65, 0xaf, 0x1a, 0x14, 0x0d, 0x17, 0xe8, 0xaa, 0x32, 0xfe, 0xd3, 0x00, 0xa8, 0x00, 0xa8,
+	0x0f, 0x5b, 0xbc, 0x29, 0x27, 0x86, 0x58, 0xa0, 0x2f, 0xa0, 0xbd, 0xb2, 0xd9, 0x7c, 0xbc, 0xcd,
+	0x15, 0x09, 0xbd, 0x0f, 0xfb, 0xc1, 0x15, 0xc3, 0xd4, 0xcb, 0x72, 0x32, 0xc7, 0x94, 0xe2, 0x50,
+	0xbe, 0x4f, 0x26, 0x2f, 0x7f, 0xab, 0xaa, 0x68, 0x00, 0xdb, 0xe2, 0xe3, 0x94, 0x4f, 0x92, 0x5c,
+	0x95, 0x75, 0x71, 0x95, 0xf2, 0xd9, 0x91, 0x2b, 0x64, 0xc1, 0x0e, 0xf5, 0x97, 0x59, 0x82, 0xa9,
+	0x7c, 0x5c, 0xd4, 0x72, 0x7a, 0x74, 0xf3, 0x9f, 0xdd, 0xb8, 0xb9, 0xb3, 0x8d, 0xdb, 0x3b, 0xdb,
+	0xf8, 0xf7, 0xce, 0x36, 0x7e, 0xbb, 0xb7, 0x1b, 0xb7, 0xf7, 0x76, 0xe3, 0xaf, 0x7b, 0xbb, 0xf1,
+	0x83, 0xfa, 0x93, 0x24, 0xd8, 0xe6, 0x01, 0x3e, 0xfe, 0x7f, 0x00, 0xa5, 0x7b, 0xdd, 0x8f, 0xbf,
+	0x08, 0x00, 0x00,
 }
 
 func (m *SeriesRequestHints) Marshal() (dAtA []byte, err error) {
@@ -720,6 +773,22 @@ func (m *QueryStats) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.StoreStats) > 0 {
+		for iNdEx := len(m.StoreStats) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.StoreStats[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintHints(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x1
+			i--
+			dAtA[i] = 0xba
+		}
+	}
 	n2, err2 := github_com_gogo_protobuf_types.StdDurationMarshalTo(m.MergeDuration, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdDuration(m.MergeDuration):])
 	if err2 != nil {
 		return 0, err2
@@ -853,6 +922,64 @@ func (m *QueryStats) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *StoreStats) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *StoreStats) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *StoreStats) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Samples != 0 {
+		i = encodeVarintHints(dAtA, i, uint64(m.Samples))
+		i--
+		dAtA[i] = 0x30
+	}
+	if m.Chunks != 0 {
+		i = encodeVarintHints(dAtA, i, uint64(m.Chunks))
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.Series != 0 {
+		i = encodeVarintHints(dAtA, i, uint64(m.Series))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.BytesProcessed != 0 {
+		i = encodeVarintHints(dAtA, i, uint64(m.BytesProcessed))
+		i--
+		dAtA[i] = 0x18
+	}
+	n4, err4 := github_com_gogo_protobuf_types.StdDurationMarshalTo(m.Duration, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdDuration(m.Duration):])
+	if err4 != nil {
+		return 0, err4
+	}
+	i -= n4
+	i = encodeVarintHints(dAtA, i, uint64(n4))
+	i--
+	dAtA[i] = 0x12
+	if len(m.Store) > 0 {
+		i -= len(m.Store)
+		copy(dAtA[i:], m.Store)
+		i = encodeVarintHints(dAtA, i, uint64(len(m.Store)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
 func encodeVarintHints(dAtA []byte, offset int, v uint64) int {
 	offset -= sovHints(v)
 	base := offset
@@ -1044,6 +1171,39 @@ func (m *QueryStats) Size() (n int) {
 	n += 2 + l + sovHints(uint64(l))
 	l = github_com_gogo_protobuf_types.SizeOfStdDuration(m.MergeDuration)
 	n += 2 + l + sovHints(uint64(l))
+	if len(m.StoreStats) > 0 {
+		for _, e := range m.StoreStats {
+			l = e.Size()
+			n += 2 + l + sovHints(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *StoreStats) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Store)
+	if l > 0 {
+		n += 1 + l + sovHints(uint64(l))
+	}
+	l = github_com_gogo_protobuf_types.SizeOfStdDuration(m.Duration)
+	n += 1 + l + sovHints(uint64(l))
+	if m.BytesProcessed != 0 {
+		n += 1 + sovHints(uint64(m.BytesProcessed))
+	}
+	if m.Series != 0 {
+		n += 1 + sovHints(uint64(m.Series))
+	}
+	if m.Chunks != 0 {
+		n += 1 + sovHints(uint64(m.Chunks))
+	}
+	if m.Samples != 0 {
+		n += 1 + sovHints(uint64(m.Samples))
+	}
 	return n
 }
 
@@ -2170,6 +2330,231 @@ func (m *QueryStats) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 23:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StoreStats", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowHints
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthHints
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthHints
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.StoreStats = append(m.StoreStats, StoreStats{})
+			if err := m.StoreStats[len(m.StoreStats)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipHints(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthHints
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *StoreStats) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowHints
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: StoreStats: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: StoreStats: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Store", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowHints
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthHints
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthHints
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Store = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Duration", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowHints
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthHints
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthHints
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := github_com_gogo_protobuf_types.StdDurationUnmarshal(&m.Duration, dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BytesProcessed", wireType)
+			}
+			m.BytesProcessed = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowHints
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.BytesProcessed |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Series", wireType)
+			}
+			m.Series = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowHints
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Series |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Chunks", wireType)
+			}
+			m.Chunks = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowHints
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Chunks |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Samples", wireType)
+			}
+			m.Samples = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowHints
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Samples |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipHints(dAtA[iNdEx:])
@@ -7,12 +7,14 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/gogo/protobuf/types"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -29,6 +31,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/info/infopb"
 	"github.com/thanos-io/thanos/pkg/query/fanout"
 	storecache "github.com/thanos-io/thanos/pkg/store/cache"
+	"github.com/thanos-io/thanos/pkg/store/hintspb"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
 	"github.com/thanos-io/thanos/pkg/strutil"
@@ -101,10 +104,16 @@ type ProxyStore struct {
 	enableDedup       bool
 
 	lazyRetrievalMaxBufferedResponses int
+
+	hedging               HedgingConfig
+	replicaAwareSelection bool
+	latencies             *storeLatencies
 }
 
 type proxyStoreMetrics struct {
 	emptyStreamResponses prometheus.Counter
+	hedgedRequests       prometheus.Counter
+	replicaFallbacks     prometheus.Counter
 }
 
 func newProxyStoreMetrics(reg prometheus.Registerer) *proxyStoreMetrics {
@@ -114,6 +123,14 @@ func newProxyStoreMetrics(reg prometheus.Registerer) *proxyStoreMetrics {
 		Name: "thanos_proxy_store_empty_stream_responses_total",
 		Help: "Total number of empty responses received.",
 	})
+	m.hedgedRequests = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "thanos_proxy_store_hedged_requests_total",
+		Help: "Total number of hedged Series requests sent to replicas of slow stores.",
+	})
+	m.replicaFallbacks = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "thanos_proxy_store_replica_fallbacks_total",
+		Help: "Total number of Series requests sent to replicas of failed stores or stores returning partial data.",
+	})
 
 	return &m
 }
@@ -193,6 +210,7 @@ func NewProxyStore(
 		tsdbSelector:      DefaultSelector,
 		enableDedup:       true,
 		matcherCache:      storecache.NoopMatchersCache,
+		latencies:         newStoreLatencies(),
 	}
 
 	for _, option := range options {
@@ -326,6 +344,17 @@ func (s *ProxyStore) Series(originalRequest *storepb.SeriesRequest, seriesSrv st
 		return nil
 	}
 
+	var queryStats *seriesQueryStats
+	if originalRequest.Hints != nil {
+		reqHints := &hintspb.SeriesRequestHints{}
+		if err := types.UnmarshalAny(originalRequest.Hints, reqHints); err != nil {
+			return status.Error(codes.InvalidArgument, errors.Wrap(err, "unmarshal series request hints").Error())
+		}
+		if reqHints.EnableQueryStats {
+			queryStats = &seriesQueryStats{}
+		}
+	}
+
 	storeMatchers, _ := storepb.PromMatchersToMatchers(matchers...) // Error would be returned by matchesExternalLabels, so skip check.
 	r := &storepb.SeriesRequest{
 		MinTime:                 originalRequest.MinTime,
@@ -341,17 +370,17 @@ func (s *ProxyStore) Series(originalRequest *storepb.SeriesRequest, seriesSrv st
 		ShardInfo:               originalRequest.ShardInfo,
 		WithoutReplicaLabels:    originalRequest.WithoutReplicaLabels,
 		ResponseBatchSize:       originalRequest.ResponseBatchSize,
+		Hints:                   originalRequest.Hints,
 	}
 
 	tracker := fanout.FromContext(ctx)
 	opID, opIDOK := enginemodel.OperatorIDFromContext(ctx)
 
-	storeResponses := make([]respSet, 0, len(stores))
-	for _, st := range stores {
-		var reporter statsReporter
+	open := func(ctx context.Context, st Client) (respSet, error) {
+		_, storeAddr := storeInfo(st)
 
+		var reporter statsReporter
 		if tracker != nil && opIDOK {
-			_, storeAddr := storeInfo(st)
 			reporter = func(rs RespSetStats) {
 				tracker.AddStore(opID, fanout.StoreFanout{
 					EndpointAddr:   storeAddr,
@@ -364,8 +393,74 @@ func (s *ProxyStore) Series(originalRequest *storepb.SeriesRequest, seriesSrv st
 				})
 			}
 		}
+		if queryStats != nil {
+			next := reporter
+			reporter = func(rs RespSetStats) {
+				queryStats.addStore(storeAddr, rs)
+				reportStats(next, rs)
+			}
+		}
+		if !s.hedging.hedgingEnabled() && !s.replicaAwareSelection {
+			return newAsyncRespSet(ctx, st, r, s.responseTimeout, s.retrievalStrategy, &s.buffers, r.ShardInfo, reqLogger, s.metrics.emptyStreamResponses, s.lazyRetrievalMaxBufferedResponses, reporter)
+		}
 
-		respSet, err := newAsyncRespSet(ctx, st, r, s.responseTimeout, s.retrievalStrategy, &s.buffers, r.ShardInfo, reqLogger, s.metrics.emptyStreamResponses, s.lazyRetrievalMaxBufferedResponses, reporter)
+		// Track latencies of stores for hedging, adaptive timeouts and ordering replicas.
+		trackingReporter := func(rs RespSetStats) {
+			s.latencies.observe(storeAddr, rs.Duration)
+			reportStats(reporter, rs)
+		}
+		timeout := s.timeout(st)
+		if timeout == 0 {
+			return newAsyncRespSet(ctx, st, r, s.responseTimeout, s.retrievalStrategy, &s.buffers, r.ShardInfo, reqLogger, s.metrics.emptyStreamResponses, s.lazyRetrievalMaxBufferedResponses, trackingReporter)
+		}
+		tctx, cancel := context.WithTimeout(ctx, timeout)
+		rs, err := newAsyncRespSet(tctx, st, r, s.responseTimeout, s.retrievalStrategy, &s.buffers, r.ShardInfo, reqLogger, s.metrics.emptyStreamResponses, s.lazyRetrievalMaxBufferedResponses, trackingReporter)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		return &timeoutRespSet{respSet: rs, cancel: cancel}, nil
+	}
+
+	// Picking a replica needs the whole response of a store, so it is only done with eager retrieval.
+	// Replicas differing by replica labels are only grouped if the caller deduplicates them anyway.
+	var groups [][]Client
+	switch {
+	case s.retrievalStrategy != EagerRetrieval:
+	case s.replicaAwareSelection && len(r.WithoutReplicaLabels) > 0:
+		groups = s.replicaGroups(stores, r.WithoutReplicaLabels, r.MinTime, r.MaxTime)
+	case s.hedging.hedgingEnabled():
+		groups = s.replicaGroups(stores, nil, r.MinTime, r.MaxTime)
+	}
+	if groups == nil {
+		groups = make([][]Client, 0, len(stores))
+		for _, st := range stores {
+			groups = append(groups, []Client{st})
+		}
+	}
+
+	storeResponses := make([]respSet, 0, len(groups))
+	closed := false
+	closeResponses := func() {
+		if closed {
+			return
+		}
+		closed = true
+		for _, rs := range storeResponses {
+			rs.Close()
+		}
+	}
+	defer closeResponses()
+	for _, group := range groups {
+		var (
+			respSet respSet
+			err     error
+		)
+		if len(group) > 1 {
+			respSet, err = s.newHedgedRespSet(ctx, group, open)
+		} else {
+			respSet, err = open(ctx, group[0])
+		}
 		if err != nil {
 			level.Error(reqLogger).Log("err", err)
 
@@ -380,7 +475,6 @@ func (s *ProxyStore) Series(originalRequest *storepb.SeriesRequest, seriesSrv st
 		}
 
 		storeResponses = append(storeResponses, respSet)
-		defer respSet.Close()
 	}
 	level.Debug(reqLogger).Log("msg", "Series: started fanout streams", "status", strings.Join(storeDebugMsgs, ";"))
 
@@ -391,11 +485,19 @@ func (s *ProxyStore) Series(originalRequest *storepb.SeriesRequest, seriesSrv st
 
 	i := 0
 	for respHeap.Next() {
+		resp := respHeap.At()
+		if queryStats != nil && resp.GetHints() != nil {
+			// Stats of all stores are sent to the caller at once.
+			if err := queryStats.merge(resp.GetHints()); err != nil {
+				level.Warn(reqLogger).Log("msg", "failed to merge query stats of series response hints", "err", err)
+			}
+			continue
+		}
+
 		i++
 		if r.Limit > 0 && i > int(r.Limit) {
 			break
 		}
-		resp := respHeap.At()
 
 		if resp.GetWarning() != "" && (r.PartialResponseDisabled || r.PartialResponseStrategy == storepb.PartialResponseStrategy_ABORT) {
 			return status.Error(codes.Aborted, resp.GetWarning())
@@ -407,6 +509,21 @@ func (s *ProxyStore) Series(originalRequest *storepb.SeriesRequest, seriesSrv st
 		}
 	}
 
+	if queryStats != nil {
+		// Stats of stores are reported once their streams are closed.
+		closeResponses()
+		sort.Slice(queryStats.stats.StoreStats, func(i, j int) bool {
+			return queryStats.stats.StoreStats[i].Store < queryStats.stats.StoreStats[j].Store
+		})
+		anyHints, err := types.MarshalAny(&hintspb.SeriesResponseHints{QueryStats: &queryStats.stats})
+		if err != nil {
+			return status.Error(codes.Unknown, errors.Wrap(err, "marshal series response hints").Error())
+		}
+		if err := srv.Send(storepb.NewHintsSeriesResponse(anyHints)); err != nil {
+			return status.Error(codes.Unknown, errors.Wrap(err, "send series response hints").Error())
+		}
+	}
+
 	// Flush any remaining buffered series from the batchable server.
 	if f, ok := srv.(flushableServer); ok {
 		return f.Flush()
@@ -415,6 +532,42 @@ func (s *ProxyStore) Series(originalRequest *storepb.SeriesRequest, seriesSrv st
 	return nil
 }
 
+// seriesQueryStats aggregates the query stats of all StoreAPIs queried by a Series request.
+type seriesQueryStats struct {
+	mtx   sync.Mutex
+	stats hintspb.QueryStats
+}
+
+func (s *seriesQueryStats) addStore(store string, rs RespSetStats) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.stats.StoreStats = append(s.stats.StoreStats, hintspb.StoreStats{
+		Store:          store,
+		Duration:       rs.Duration,
+		BytesProcessed: rs.BytesProcessed,
+		Series:         rs.Series,
+		Chunks:         rs.Chunks,
+		Samples:        rs.Samples,
+	})
+}
+
+func (s *seriesQueryStats) merge(anyHints *types.Any) error {
+	hints := &hintspb.SeriesResponseHints{}
+	if err := types.UnmarshalAny(anyHints, hints); err != nil {
+		return err
+	}
+	if hints.QueryStats == nil {
+		return nil
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.stats.Merge(hints.QueryStats)
+	return nil
+}
+
 // LabelNames returns all known label names.
 func (s *ProxyStore) LabelNames(ctx context.Context, originalRequest *storepb.LabelNamesRequest) (*storepb.LabelNamesResponse, error) {
 	// TODO(bwplotka): This should be part of request logger, otherwise it does not make much sense. Also, could be
@@ -0,0 +1,389 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caio/go-tdigest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+)
+
+// minLatencyObservations is the number of Series calls a store has to serve before
+// its latency quantiles are used for hedging and adaptive timeouts.
+const minLatencyObservations = 10
+
+// HedgingConfig configures hedged Series requests and adaptive per-store timeouts in the ProxyStore.
+// Both only apply to the eager retrieval strategy, where every response is buffered before merging.
+type HedgingConfig struct {
+	// Quantile of the observed Series latency of a store after which the same request is sent
+	// to another store exposing identical label sets. Zero disables hedging.
+	Quantile float64
+	// TimeoutMultiplier scales the latency quantile of a store into its Series timeout. When the
+	// timeout is exceeded the store is treated as failed, as for any other error. Zero disables
+	// adaptive timeouts.
+	TimeoutMultiplier float64
+	// MinTimeout is the lower bound of adaptive timeouts.
+	MinTimeout time.Duration
+}
+
+// WithHedging enables hedged Series requests and adaptive per-store timeouts.
+func WithHedging(cfg HedgingConfig) ProxyStoreOption {
+	return func(s *ProxyStore) {
+		s.hedging = cfg
+	}
+}
+
+func (cfg HedgingConfig) hedgingEnabled() bool { return cfg.Quantile > 0 }
+
+func (cfg HedgingConfig) adaptiveTimeoutsEnabled() bool {
+	return cfg.Quantile > 0 && cfg.TimeoutMultiplier > 0
+}
+
+// storeLatencies tracks the latency distribution of Series calls per store address.
+type storeLatencies struct {
+	mtx     sync.Mutex
+	digests map[string]*tdigest.TDigest
+}
+
+func newStoreLatencies() *storeLatencies {
+	return &storeLatencies{digests: make(map[string]*tdigest.TDigest)}
+}
+
+func (l *storeLatencies) observe(addr string, d time.Duration) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	td, ok := l.digests[addr]
+	if !ok {
+		var err error
+		td, err = tdigest.New()
+		if err != nil {
+			panic(fmt.Sprintf("BUG: Failed to initialize T-Digest: %v", err))
+		}
+		l.digests[addr] = td
+	}
+	_ = td.Add(float64(d.Milliseconds()))
+}
+
+// quantile returns the q-quantile of Series latencies of addr, if enough calls were observed.
+func (l *storeLatencies) quantile(addr string, q float64) (time.Duration, bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	td, ok := l.digests[addr]
+	if !ok || td.Count() < minLatencyObservations {
+		return 0, false
+	}
+	return time.Duration(td.Quantile(q)) * time.Millisecond, true
+}
+
+// timeout returns the adaptive Series timeout of the given store, or zero if it is unknown.
+func (s *ProxyStore) timeout(st Client) time.Duration {
+	if !s.hedging.adaptiveTimeoutsEnabled() {
+		return 0
+	}
+	_, addr := storeInfo(st)
+	q, ok := s.latencies.quantile(addr, s.hedging.Quantile)
+	if !ok {
+		return 0
+	}
+	return max(time.Duration(float64(q)*s.hedging.TimeoutMultiplier), s.hedging.MinTimeout)
+}
+
+// WithReplicaAwareSelection makes the ProxyStore send Series requests asking for deduplication to
+// only one store of each replica group, i.e. stores whose label sets are identical once the replica
+// labels are removed and whose TSDBs each cover the whole queried time range. Other replicas are only
+// queried when the selected one fails. This only applies to the eager retrieval strategy.
+//
+// Stores can't report whether they shard series, so this must not be enabled when stores sharing
+// label sets hold different series of the same time range, e.g. Receivers of a hashring whose
+// only distinct label is the replica label.
+func WithReplicaAwareSelection() ProxyStoreOption {
+	return func(s *ProxyStore) {
+		s.replicaAwareSelection = true
+	}
+}
+
+// replicaGroups groups stores which are expected to hold the same data for [mint, maxt], e.g.
+// replicas of a store gateway or HA pairs of sidecars: they expose identical label sets once the
+// given replica labels are removed, and every one of them has TSDBs covering the whole time range
+// for each of its label sets. Other stores, e.g. time partitioned or hashmod sharded store
+// gateways, or a sidecar and the store gateway holding its older blocks, are always queried.
+// Stores within a group are ordered by their median latency, stores with unknown latency last.
+func (s *ProxyStore) replicaGroups(stores []Client, replicaLabels []string, mint, maxt int64) [][]Client {
+	var (
+		groups  = make([][]Client, 0, len(stores))
+		indexes = make(map[string]int, len(stores))
+	)
+	for _, st := range stores {
+		key, ok := replicaGroupKey(st, replicaLabels, mint, maxt)
+		if !ok {
+			groups = append(groups, []Client{st})
+			continue
+		}
+		if i, ok := indexes[key]; ok {
+			groups[i] = append(groups[i], st)
+			continue
+		}
+		indexes[key] = len(groups)
+		groups = append(groups, []Client{st})
+	}
+
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		type replica struct {
+			st     Client
+			median time.Duration
+			known  bool
+		}
+		replicas := make([]replica, 0, len(group))
+		for _, st := range group {
+			_, addr := storeInfo(st)
+			median, ok := s.latencies.quantile(addr, 0.5)
+			replicas = append(replicas, replica{st: st, median: median, known: ok})
+		}
+		sort.SliceStable(replicas, func(i, j int) bool {
+			if !replicas[i].known || !replicas[j].known {
+				return replicas[i].known && !replicas[j].known
+			}
+			return replicas[i].median < replicas[j].median
+		})
+		for i, r := range replicas {
+			group[i] = r.st
+		}
+	}
+	return groups
+}
+
+// replicaGroupKey returns the label sets of the store without the replica labels, if the TSDBs of
+// every label set of the store cover [mint, maxt] without gaps. Stores which don't report their
+// TSDBs are assumed to hold a single TSDB per label set covering their whole time range.
+func replicaGroupKey(st Client, replicaLabels []string, mint, maxt int64) (string, bool) {
+	ranges := make(map[string][][2]int64)
+	for _, info := range st.TSDBInfos() {
+		lset := labels.NewBuilder(info.Labels.PromLabels()).Del(replicaLabels...).Labels().String()
+		ranges[lset] = append(ranges[lset], [2]int64{info.MinTime, info.MaxTime})
+	}
+	if len(ranges) == 0 {
+		storeMint, storeMaxt := st.TimeRange()
+		for _, lset := range st.LabelSets() {
+			lset := labels.NewBuilder(lset).Del(replicaLabels...).Labels().String()
+			ranges[lset] = append(ranges[lset], [2]int64{storeMint, storeMaxt})
+		}
+	}
+	if len(ranges) == 0 {
+		return "", false
+	}
+
+	lsets := make([]string, 0, len(ranges))
+	for lset, rs := range ranges {
+		if lset == "{}" || !coversRange(rs, mint, maxt) {
+			return "", false
+		}
+		lsets = append(lsets, lset)
+	}
+	sort.Strings(lsets)
+	return strings.Join(lsets, ";"), true
+}
+
+// coversRange reports whether the union of the given time ranges covers [mint, maxt].
+func coversRange(ranges [][2]int64, mint, maxt int64) bool {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+	covered := mint
+	for _, r := range ranges {
+		if r[0] > covered {
+			return false
+		}
+		if r[1] >= maxt {
+			return true
+		}
+		covered = max(covered, r[1])
+	}
+	return false
+}
+
+// openRespSetFunc opens a Series stream against a single store.
+type openRespSetFunc func(ctx context.Context, st Client) (respSet, error)
+
+// hedgedRespSet is a respSet which sends the request to the first store of a replica group and,
+// once the store fails or, with hedging enabled, takes longer than its latency quantile, to the
+// next replica. The first stream that completes without errors is used, the others are canceled.
+type hedgedRespSet struct {
+	respSet
+
+	done   chan struct{}
+	cancel context.CancelFunc
+	opened []respSet
+}
+
+func (s *ProxyStore) newHedgedRespSet(ctx context.Context, group []Client, open openRespSetFunc) (respSet, error) {
+	// The primary store is opened synchronously so that errors are handled as for any other
+	// store once no replica can be opened.
+	var (
+		primary respSet
+		err     error
+	)
+	for len(group) > 0 {
+		primary, err = open(ctx, group[0])
+		if err == nil {
+			break
+		}
+		group = group[1:]
+	}
+	if primary == nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	h := &hedgedRespSet{
+		done:   make(chan struct{}),
+		cancel: cancel,
+		opened: []respSet{primary},
+	}
+	go h.run(ctx, s, group, primary, open)
+	return h, nil
+}
+
+// run waits for the primary store of the group and hedges to the next replicas.
+func (h *hedgedRespSet) run(ctx context.Context, s *ProxyStore, group []Client, primary respSet, open openRespSetFunc) {
+	defer close(h.done)
+	defer func() {
+		for _, rs := range h.opened {
+			if rs != h.respSet {
+				go rs.Close()
+			}
+		}
+	}()
+
+	finished := make(chan respSet, len(group))
+	wait := func(rs respSet) {
+		go func() {
+			// Empty blocks until an eager respSet buffered the whole response.
+			rs.Empty()
+			finished <- rs
+		}()
+	}
+	wait(primary)
+
+	var (
+		pending = 1
+		next    = 1
+		last    respSet
+	)
+	hedge := func(requests prometheus.Counter) {
+		for next < len(group) {
+			st := group[next]
+			next++
+
+			rs, err := open(ctx, st)
+			if err != nil {
+				continue
+			}
+			requests.Inc()
+			h.opened = append(h.opened, rs)
+			pending++
+			wait(rs)
+			return
+		}
+	}
+
+	var timer <-chan time.Time
+	if s.hedging.hedgingEnabled() {
+		_, addr := storeInfo(group[0])
+		if delay, ok := s.latencies.quantile(addr, s.hedging.Quantile); ok {
+			t := time.NewTimer(delay)
+			defer t.Stop()
+			timer = t.C
+		}
+	}
+
+	for pending > 0 {
+		select {
+		case rs := <-finished:
+			pending--
+			last = rs
+			if !respSetFailed(rs) {
+				h.respSet = rs
+				return
+			}
+			// The store failed, try the next replica right away.
+			hedge(s.metrics.replicaFallbacks)
+		case <-timer:
+			timer = nil
+			hedge(s.metrics.hedgedRequests)
+		}
+	}
+	// All replicas failed, return the warnings of the last one.
+	h.respSet = last
+}
+
+// respSetFailed reports whether the Series stream of the store ended with an error, e.g. when it
+// was aborted by a timeout. Warnings sent by the store itself are not failures.
+func respSetFailed(rs respSet) bool {
+	switch rs := rs.(type) {
+	case *eagerRespSet:
+		return rs.failed
+	case *timeoutRespSet:
+		return respSetFailed(rs.respSet)
+	}
+	return false
+}
+
+func (h *hedgedRespSet) Close() {
+	h.cancel()
+	<-h.done
+	h.respSet.Close()
+}
+
+func (h *hedgedRespSet) At() *storepb.SeriesResponse {
+	<-h.done
+	return h.respSet.At()
+}
+
+func (h *hedgedRespSet) Next() bool {
+	<-h.done
+	return h.respSet.Next()
+}
+
+func (h *hedgedRespSet) Empty() bool {
+	<-h.done
+	return h.respSet.Empty()
+}
+
+func (h *hedgedRespSet) StoreID() string {
+	<-h.done
+	return h.respSet.StoreID()
+}
+
+func (h *hedgedRespSet) Labelset() string {
+	<-h.done
+	return h.respSet.Labelset()
+}
+
+func (h *hedgedRespSet) StoreLabels() map[string]struct{} {
+	<-h.done
+	return h.respSet.StoreLabels()
+}
+
+// timeoutRespSet releases the context of an adaptive timeout once the respSet is closed.
+type timeoutRespSet struct {
+	respSet
+	cancel context.CancelFunc
+}
+
+func (t *timeoutRespSet) Close() {
+	t.respSet.Close()
+	t.cancel()
+}
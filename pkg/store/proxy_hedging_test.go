@@ -0,0 +1,387 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/thanos-io/thanos/pkg/component"
+	"github.com/thanos-io/thanos/pkg/info/infopb"
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+	storetestutil "github.com/thanos-io/thanos/pkg/store/storepb/testutil"
+)
+
+func TestReplicaGroups(t *testing.T) {
+	t.Parallel()
+
+	store := func(name string, mint, maxt int64, lsets ...labels.Labels) *storetestutil.TestClient {
+		return &storetestutil.TestClient{Name: name, ExtLset: lsets, MinTime: mint, MaxTime: maxt}
+	}
+	var (
+		a1 = store("a1", 0, 100, labels.FromStrings("ext", "a", "replica", "1"))
+		a2 = store("a2", 0, 100, labels.FromStrings("ext", "a", "replica", "2"))
+		a3 = store("a3", 0, 100, labels.FromStrings("ext", "a", "replica", "1"))
+		b  = store("b", 0, 100, labels.FromStrings("ext", "b", "replica", "1"))
+		n1 = store("n1", 0, 100)
+		n2 = store("n2", 0, 100)
+	)
+	s := NewProxyStore(nil, nil, func() []Client { return nil }, component.Query, labels.EmptyLabels(), 0, EagerRetrieval)
+
+	t.Run("identical label sets", func(t *testing.T) {
+		groups := s.replicaGroups([]Client{a1, b, n1, a2, a3, n2}, nil, 0, 100)
+		testutil.Equals(t, [][]Client{{a1, a3}, {b}, {n1}, {a2}, {n2}}, groups)
+	})
+	t.Run("without replica labels", func(t *testing.T) {
+		groups := s.replicaGroups([]Client{a1, b, n1, a2, a3, n2}, []string{"replica"}, 0, 100)
+		testutil.Equals(t, [][]Client{{a1, a2, a3}, {b}, {n1}, {n2}}, groups)
+	})
+	t.Run("time partitioned stores", func(t *testing.T) {
+		// Store gateways sharded by time expose the same labels, but each holds part of the time range.
+		old := store("old", 0, 50, labels.FromStrings("ext", "a"))
+		recent := store("recent", 50, 100, labels.FromStrings("ext", "a"))
+		groups := s.replicaGroups([]Client{old, recent}, nil, 0, 100)
+		testutil.Equals(t, [][]Client{{old}, {recent}}, groups)
+
+		// Both hold the whole range of queries within their common time range.
+		groups = s.replicaGroups([]Client{old, recent}, nil, 50, 50)
+		testutil.Equals(t, [][]Client{{old, recent}}, groups)
+	})
+	t.Run("sidecar and store gateway", func(t *testing.T) {
+		// The store gateway holds the blocks uploaded by the sidecar, which only holds recent data.
+		sidecar := store("sidecar", 80, 100, labels.FromStrings("ext", "a", "replica", "1"))
+		gateway := &storetestutil.TestClient{
+			Name:    "gateway",
+			ExtLset: []labels.Labels{labels.FromStrings("ext", "a", "replica", "1")},
+			MinTime: 0,
+			MaxTime: 80,
+			StoreTSDBInfos: []infopb.TSDBInfo{
+				infopb.NewTSDBInfo(0, 80, labelpb.ZLabelsFromPromLabels(labels.FromStrings("ext", "a", "replica", "1"))),
+			},
+		}
+		groups := s.replicaGroups([]Client{sidecar, gateway}, []string{"replica"}, 0, 100)
+		testutil.Equals(t, [][]Client{{sidecar}, {gateway}}, groups)
+	})
+	t.Run("blocks with gaps", func(t *testing.T) {
+		// Store gateways sharded by block hold blocks of the same label set with gaps between them.
+		sharded := func(name string, infos ...infopb.TSDBInfo) Client {
+			return &storetestutil.TestClient{Name: name, ExtLset: []labels.Labels{labels.FromStrings("ext", "a")}, StoreTSDBInfos: infos}
+		}
+		lset := labelpb.ZLabelsFromPromLabels(labels.FromStrings("ext", "a"))
+		s1 := sharded("s1", infopb.NewTSDBInfo(0, 30, lset), infopb.NewTSDBInfo(60, 100, lset))
+		s2 := sharded("s2", infopb.NewTSDBInfo(30, 60, lset))
+		groups := s.replicaGroups([]Client{s1, s2}, nil, 0, 100)
+		testutil.Equals(t, [][]Client{{s1}, {s2}}, groups)
+	})
+	t.Run("ordered by latency", func(t *testing.T) {
+		s := NewProxyStore(nil, nil, func() []Client { return nil }, component.Query, labels.EmptyLabels(), 0, EagerRetrieval)
+		for i := 0; i < minLatencyObservations; i++ {
+			s.latencies.observe("a1", 200*time.Millisecond)
+			s.latencies.observe("a2", 100*time.Millisecond)
+		}
+		groups := s.replicaGroups([]Client{a1, a2, a3}, []string{"replica"}, 0, 100)
+		testutil.Equals(t, [][]Client{{a2, a1, a3}}, groups)
+	})
+}
+
+func TestStoreLatencies(t *testing.T) {
+	t.Parallel()
+
+	l := newStoreLatencies()
+	for i := 0; i < minLatencyObservations-1; i++ {
+		l.observe("a", 100*time.Millisecond)
+	}
+	_, ok := l.quantile("a", 0.9)
+	testutil.Assert(t, !ok, "expected no quantile before enough observations")
+
+	l.observe("a", 100*time.Millisecond)
+	q, ok := l.quantile("a", 0.9)
+	testutil.Assert(t, ok, "expected quantile after enough observations")
+	testutil.Equals(t, 100*time.Millisecond, q)
+}
+
+func TestProxyStore_Series_Hedging(t *testing.T) {
+	t.Parallel()
+
+	series := []*storepb.SeriesResponse{
+		storeSeriesResponse(t, labels.FromStrings("a", "b"), []sample{{1, 1}, {2, 2}, {3, 3}}),
+	}
+	expectedSeries := []rawSeries{
+		{
+			lset:   labels.FromStrings("a", "b"),
+			chunks: [][]sample{{{1, 1}, {2, 2}, {3, 3}}},
+		},
+	}
+	req := &storepb.SeriesRequest{
+		MinTime:  1,
+		MaxTime:  300,
+		Matchers: []storepb.LabelMatcher{{Name: "ext", Value: "1", Type: storepb.LabelMatcher_EQ}},
+	}
+	replica := func(name string, m *mockedStoreAPI) Client {
+		return &storetestutil.TestClient{
+			Name:        name,
+			StoreClient: m,
+			ExtLset:     []labels.Labels{labels.FromStrings("ext", "1")},
+			MinTime:     1,
+			MaxTime:     300,
+		}
+	}
+
+	for _, tc := range []struct {
+		title             string
+		primary           *mockedStoreAPI
+		expectedHedged    float64
+		expectedFallbacks float64
+	}{
+		{
+			title:   "primary is fast",
+			primary: &mockedStoreAPI{RespSeries: series},
+		},
+		{
+			title:          "primary is slower than its latency quantile",
+			primary:        &mockedStoreAPI{RespSeries: series, RespDuration: 10 * time.Second},
+			expectedHedged: 1,
+		},
+		{
+			title:             "primary fails",
+			primary:           &mockedStoreAPI{RespSeries: series, injectedError: errors.New("test")},
+			expectedFallbacks: 1,
+		},
+		{
+			title:   "primary cannot be opened",
+			primary: &mockedStoreAPI{RespError: errors.New("test")},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			stores := []Client{
+				replica("primary", tc.primary),
+				replica("secondary", &mockedStoreAPI{RespSeries: series}),
+			}
+			q := NewProxyStore(nil,
+				prometheus.NewRegistry(),
+				func() []Client { return stores },
+				component.Query,
+				labels.EmptyLabels(),
+				0, EagerRetrieval,
+				WithHedging(HedgingConfig{Quantile: 0.9}),
+			)
+			for i := 0; i < minLatencyObservations; i++ {
+				q.latencies.observe("primary", 10*time.Millisecond)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			s := newStoreSeriesServer(ctx)
+
+			testutil.Ok(t, q.Series(req, s))
+			seriesEquals(t, expectedSeries, s.SeriesSet)
+			testutil.Equals(t, 0, len(s.Warnings), "got %v", s.Warnings)
+			testutil.Equals(t, tc.expectedHedged, promtest.ToFloat64(q.metrics.hedgedRequests))
+			testutil.Equals(t, tc.expectedFallbacks, promtest.ToFloat64(q.metrics.replicaFallbacks))
+		})
+	}
+}
+
+func TestProxyStore_Series_AdaptiveTimeout(t *testing.T) {
+	t.Parallel()
+
+	stores := []Client{
+		&storetestutil.TestClient{
+			Name: "slow",
+			StoreClient: &mockedStoreAPI{
+				RespSeries: []*storepb.SeriesResponse{
+					storeSeriesResponse(t, labels.FromStrings("a", "b"), []sample{{1, 1}, {2, 2}, {3, 3}}),
+				},
+				RespDuration: 10 * time.Second,
+			},
+			ExtLset: []labels.Labels{labels.FromStrings("ext", "1")},
+			MinTime: 1,
+			MaxTime: 300,
+		},
+	}
+	q := NewProxyStore(nil,
+		prometheus.NewRegistry(),
+		func() []Client { return stores },
+		component.Query,
+		labels.EmptyLabels(),
+		0, EagerRetrieval,
+		WithHedging(HedgingConfig{Quantile: 0.9, TimeoutMultiplier: 2, MinTimeout: 100 * time.Millisecond}),
+	)
+	for i := 0; i < minLatencyObservations; i++ {
+		q.latencies.observe("slow", 10*time.Millisecond)
+	}
+	testutil.Equals(t, 100*time.Millisecond, q.timeout(stores[0]))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s := newStoreSeriesServer(ctx)
+
+	req := &storepb.SeriesRequest{
+		MinTime:  1,
+		MaxTime:  300,
+		Matchers: []storepb.LabelMatcher{{Name: "ext", Value: "1", Type: storepb.LabelMatcher_EQ}},
+	}
+	testutil.Ok(t, q.Series(req, s))
+	testutil.Equals(t, 0, len(s.SeriesSet))
+	testutil.Equals(t, 1, len(s.Warnings), "got %v", s.Warnings)
+}
+
+func TestProxyStore_Series_ReplicaAwareSelection(t *testing.T) {
+	t.Parallel()
+
+	replica := func(name, replicaLabel string, m *mockedStoreAPI) Client {
+		return &storetestutil.TestClient{
+			Name:        name,
+			StoreClient: m,
+			ExtLset:     []labels.Labels{labels.FromStrings("ext", "1", "replica", replicaLabel)},
+			MinTime:     1,
+			MaxTime:     300,
+		}
+	}
+	// Replica labels are removed from series when deduplicating, so replicas are told apart by values.
+	seriesFor := func(replicaLabel string, v float64) []*storepb.SeriesResponse {
+		return []*storepb.SeriesResponse{
+			storeSeriesResponse(t, labels.FromStrings("a", "b", "replica", replicaLabel), []sample{{1, v}, {2, v}}),
+		}
+	}
+
+	for _, tc := range []struct {
+		title                string
+		replicaA             *mockedStoreAPI
+		withoutReplicaLabels []string
+
+		expectedSeries    []rawSeries
+		expectedWarnings  int
+		expectedFallbacks float64
+	}{
+		{
+			title:                "one replica queried",
+			replicaA:             &mockedStoreAPI{RespSeries: seriesFor("a", 1)},
+			withoutReplicaLabels: []string{"replica"},
+			expectedSeries: []rawSeries{
+				{lset: labels.FromStrings("a", "b"), chunks: [][]sample{{{1, 1}, {2, 1}}}},
+			},
+		},
+		{
+			title:                "falls back to other replica on error",
+			replicaA:             &mockedStoreAPI{RespSeries: seriesFor("a", 1), injectedError: errors.New("test")},
+			withoutReplicaLabels: []string{"replica"},
+			expectedSeries: []rawSeries{
+				{lset: labels.FromStrings("a", "b"), chunks: [][]sample{{{1, 2}, {2, 2}}}},
+			},
+			expectedFallbacks: 1,
+		},
+		{
+			title:                "no fall back on warnings",
+			replicaA:             &mockedStoreAPI{RespSeries: append(seriesFor("a", 1), storepb.NewWarnSeriesResponse(errors.New("partial")))},
+			withoutReplicaLabels: []string{"replica"},
+			expectedSeries: []rawSeries{
+				{lset: labels.FromStrings("a", "b"), chunks: [][]sample{{{1, 1}, {2, 1}}}},
+			},
+			expectedWarnings: 1,
+		},
+		{
+			title:    "all replicas queried without deduplication",
+			replicaA: &mockedStoreAPI{RespSeries: seriesFor("a", 1)},
+			expectedSeries: []rawSeries{
+				{lset: labels.FromStrings("a", "b", "replica", "a"), chunks: [][]sample{{{1, 1}, {2, 1}}}},
+				{lset: labels.FromStrings("a", "b", "replica", "b"), chunks: [][]sample{{{1, 2}, {2, 2}}}},
+			},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			replicaB := &mockedStoreAPI{RespSeries: seriesFor("b", 2)}
+			stores := []Client{
+				replica("a", "a", tc.replicaA),
+				replica("b", "b", replicaB),
+			}
+			q := NewProxyStore(nil,
+				prometheus.NewRegistry(),
+				func() []Client { return stores },
+				component.Query,
+				labels.EmptyLabels(),
+				0, EagerRetrieval,
+				WithReplicaAwareSelection(),
+			)
+
+			s := newStoreSeriesServer(context.Background())
+			req := &storepb.SeriesRequest{
+				MinTime:              1,
+				MaxTime:              300,
+				Matchers:             []storepb.LabelMatcher{{Name: "ext", Value: "1", Type: storepb.LabelMatcher_EQ}},
+				WithoutReplicaLabels: tc.withoutReplicaLabels,
+			}
+			testutil.Ok(t, q.Series(req, s))
+			seriesEquals(t, tc.expectedSeries, s.SeriesSet)
+			testutil.Equals(t, tc.expectedWarnings, len(s.Warnings), "got %v", s.Warnings)
+			testutil.Equals(t, tc.expectedFallbacks, promtest.ToFloat64(q.metrics.replicaFallbacks))
+		})
+	}
+}
+
+func TestProxyStore_Series_ReplicaAwareSelection_TimeRanges(t *testing.T) {
+	t.Parallel()
+
+	store := func(name string, mint, maxt int64, v float64) Client {
+		return &storetestutil.TestClient{
+			Name: name,
+			StoreClient: &mockedStoreAPI{RespSeries: []*storepb.SeriesResponse{
+				storeSeriesResponse(t, labels.FromStrings("a", "b", "replica", "1"), []sample{{mint, v}}),
+			}},
+			ExtLset: []labels.Labels{labels.FromStrings("ext", "1", "replica", "1")},
+			MinTime: mint,
+			MaxTime: maxt,
+		}
+	}
+
+	for _, tc := range []struct {
+		title  string
+		stores []Client
+	}{
+		{
+			title:  "time partitioned store gateways",
+			stores: []Client{store("old", 1, 150, 1), store("recent", 150, 300, 2)},
+		},
+		{
+			title:  "sidecar and store gateway",
+			stores: []Client{store("sidecar", 200, 300, 2), store("gateway", 1, 200, 1)},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			q := NewProxyStore(nil,
+				prometheus.NewRegistry(),
+				func() []Client { return tc.stores },
+				component.Query,
+				labels.EmptyLabels(),
+				0, EagerRetrieval,
+				WithReplicaAwareSelection(),
+			)
+
+			s := newStoreSeriesServer(context.Background())
+			req := &storepb.SeriesRequest{
+				MinTime:              1,
+				MaxTime:              300,
+				Matchers:             []storepb.LabelMatcher{{Name: "ext", Value: "1", Type: storepb.LabelMatcher_EQ}},
+				WithoutReplicaLabels: []string{"replica"},
+			}
+			testutil.Ok(t, q.Series(req, s))
+			testutil.Equals(t, 0, len(s.Warnings), "got %v", s.Warnings)
+
+			// Data of both stores is returned.
+			var samples int
+			for _, series := range s.SeriesSet {
+				samples += len(series.Chunks)
+			}
+			testutil.Equals(t, 2, samples)
+		})
+	}
+}
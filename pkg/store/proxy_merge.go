@@ -148,7 +148,7 @@ func (d *responseDeduplicator) chainSeriesAndRemIdenticalChunks(series []*storep
 	for _, s := range series {
 		for _, chk := range s.GetSeries().Chunks {
 			for _, field := range []*storepb.Chunk{
-				chk.Raw, chk.Count, chk.Max, chk.Min, chk.Sum, chk.Counter,
+				chk.Raw, chk.Count, chk.Max, chk.Min, chk.Sum, chk.Counter, chk.Last,
 			} {
 				if field == nil {
 					continue
@@ -673,6 +673,8 @@ type eagerRespSet struct {
 	bufferedResponses []*storepb.SeriesResponse
 	wg                *sync.WaitGroup
 	i                 int
+	// failed is set when the stream ended with an error instead of io.EOF.
+	failed bool
 }
 
 func newEagerRespSet(
@@ -758,6 +760,7 @@ func newEagerRespSet(
 				}
 
 				l.bufferedResponses = append(l.bufferedResponses, storepb.NewWarnSeriesResponse(rerr))
+				l.failed = true
 				return false
 			}
 
@@ -33,6 +33,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/component"
 	"github.com/thanos-io/thanos/pkg/info/infopb"
 	storecache "github.com/thanos-io/thanos/pkg/store/cache"
+	"github.com/thanos-io/thanos/pkg/store/hintspb"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
 	storetestutil "github.com/thanos-io/thanos/pkg/store/storepb/testutil"
@@ -1413,6 +1414,75 @@ func TestProxyStore_Series_RequestParamsProxied(t *testing.T) {
 	testutil.Assert(t, proto.Equal(req, m.LastSeriesReq), "request was not proxied properly to underlying storeAPI: %s vs %s", req, m.LastSeriesReq)
 }
 
+func TestProxyStore_Series_QueryStats(t *testing.T) {
+	t.Parallel()
+
+	storeHints := func(t *testing.T, stats hintspb.QueryStats) *storepb.SeriesResponse {
+		anyHints, err := types.MarshalAny(&hintspb.SeriesResponseHints{QueryStats: &stats})
+		testutil.Ok(t, err)
+		return storepb.NewHintsSeriesResponse(anyHints)
+	}
+	m1 := &mockedStoreAPI{
+		RespSeries: []*storepb.SeriesResponse{
+			storeSeriesResponse(t, labels.FromStrings("a", "1"), []sample{{1, 1}}),
+			storeHints(t, hintspb.QueryStats{BlocksQueried: 2, PostingsTouched: 3, DataDownloadedSizeSum: 100}),
+		},
+	}
+	m2 := &mockedStoreAPI{
+		RespSeries: []*storepb.SeriesResponse{
+			storeSeriesResponse(t, labels.FromStrings("a", "2"), []sample{{1, 1}}),
+			storeHints(t, hintspb.QueryStats{BlocksQueried: 1, DataDownloadedSizeSum: 50}),
+		},
+	}
+	cls := []Client{
+		&storetestutil.TestClient{Name: "store-2", StoreClient: m2, MinTime: 1, MaxTime: 300},
+		&storetestutil.TestClient{Name: "store-1", StoreClient: m1, MinTime: 1, MaxTime: 300},
+	}
+	q := NewProxyStore(nil,
+		nil,
+		func() []Client { return cls },
+		component.Query,
+		labels.EmptyLabels(),
+		1*time.Second, EagerRetrieval,
+	)
+
+	reqHints, err := types.MarshalAny(&hintspb.SeriesRequestHints{EnableQueryStats: true})
+	testutil.Ok(t, err)
+	s := newStoreSeriesServer(context.Background())
+	testutil.Ok(t, q.Series(&storepb.SeriesRequest{
+		MinTime:  1,
+		MaxTime:  300,
+		Matchers: []storepb.LabelMatcher{{Name: "a", Value: ".+", Type: storepb.LabelMatcher_RE}},
+		Hints:    reqHints,
+	}, s))
+
+	testutil.Equals(t, 2, len(s.SeriesSet))
+	// Request hints are proxied to stores.
+	testutil.Equals(t, reqHints, m1.LastSeriesReq.Hints)
+
+	// Stats of all stores are merged into a single response.
+	testutil.Equals(t, 1, len(s.HintsSet))
+	respHints := &hintspb.SeriesResponseHints{}
+	testutil.Ok(t, types.UnmarshalAny(s.HintsSet[0], respHints))
+	testutil.Equals(t, int64(3), respHints.QueryStats.BlocksQueried)
+	testutil.Equals(t, int64(3), respHints.QueryStats.PostingsTouched)
+	testutil.Equals(t, int64(150), respHints.QueryStats.DataDownloadedSizeSum)
+	testutil.Equals(t, 2, len(respHints.QueryStats.StoreStats))
+	for i, store := range []string{"store-1", "store-2"} {
+		testutil.Equals(t, store, respHints.QueryStats.StoreStats[i].Store)
+		testutil.Equals(t, int64(1), respHints.QueryStats.StoreStats[i].Series)
+	}
+
+	// Without query stats requested, hints of stores are passed through.
+	s = newStoreSeriesServer(context.Background())
+	testutil.Ok(t, q.Series(&storepb.SeriesRequest{
+		MinTime:  1,
+		MaxTime:  300,
+		Matchers: []storepb.LabelMatcher{{Name: "a", Value: ".+", Type: storepb.LabelMatcher_RE}},
+	}, s))
+	testutil.Equals(t, 2, len(s.HintsSet))
+}
+
 func TestProxyStore_Series_RegressionFillResponseChannel(t *testing.T) {
 	t.Parallel()
 
@@ -305,6 +305,7 @@ func (m AggrChunk) Compare(b AggrChunk) int {
 		func() int { return m.Min.Compare(b.Min) },
 		func() int { return m.Max.Compare(b.Max) },
 		func() int { return m.Counter.Compare(b.Counter) },
+		func() int { return m.Last.Compare(b.Last) },
 	} {
 		if c := cmp(); c == 0 {
 			continue
@@ -557,6 +558,11 @@ func (c *SeriesStatsCounter) Count(series *Series) {
 			c.Chunks++
 			c.Samples += chk.Sum.XORNumSamples()
 		}
+
+		if chk.Last != nil {
+			c.Chunks++
+			c.Samples += chk.Last.XORNumSamples()
+		}
 	}
 }
 
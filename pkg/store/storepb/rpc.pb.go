@@ -39,6 +39,7 @@ const (
 	Aggr_MIN     Aggr = 3
 	Aggr_MAX     Aggr = 4
 	Aggr_COUNTER Aggr = 5
+	Aggr_LAST    Aggr = 6
 )
 
 var Aggr_name = map[int32]string{
@@ -48,6 +49,7 @@ var Aggr_name = map[int32]string{
 	3: "MIN",
 	4: "MAX",
 	5: "COUNTER",
+	6: "LAST",
 }
 
 var Aggr_value = map[string]int32{
@@ -57,6 +59,7 @@ var Aggr_value = map[string]int32{
 	"MIN":     3,
 	"MAX":     4,
 	"COUNTER": 5,
+	"LAST":    6,
 }
 
 func (x Aggr) String() string {
@@ -796,89 +799,89 @@ func init() {
 func init() { proto.RegisterFile("store/storepb/rpc.proto", fileDescriptor_a938d55a388af629) }
 
 var fileDescriptor_a938d55a388af629 = []byte{
-	// 1301 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x57, 0xdf, 0x6e, 0x1b, 0xc5,
-	0x17, 0xf6, 0x7a, 0xbd, 0xfe, 0x73, 0x9c, 0xb8, 0xce, 0xc4, 0x49, 0x37, 0xae, 0x7e, 0x8e, 0x7f,
-	0x46, 0x48, 0x56, 0x5b, 0x92, 0xca, 0x45, 0x48, 0x20, 0x6e, 0x92, 0x96, 0x92, 0x4a, 0x24, 0xc0,
-	0x26, 0x25, 0x08, 0x2e, 0x56, 0x63, 0x7b, 0x62, 0x2f, 0x5d, 0xef, 0x6e, 0x77, 0x66, 0x49, 0xdc,
-	0x6b, 0x1e, 0x80, 0x7b, 0x1e, 0x83, 0x4b, 0x5e, 0xa0, 0x77, 0x54, 0x5c, 0x71, 0x81, 0x10, 0xb4,
-	0x2f, 0x82, 0xe6, 0xcc, 0xec, 0xda, 0x2e, 0xee, 0x3f, 0xb5, 0xdc, 0x58, 0x73, 0xce, 0xf7, 0xcd,
-	0x99, 0x99, 0x73, 0xbe, 0x39, 0xe3, 0x85, 0xcb, 0x5c, 0x84, 0x31, 0xdb, 0xc5, 0xdf, 0xa8, 0xbf,
-	0x1b, 0x47, 0x83, 0x9d, 0x28, 0x0e, 0x45, 0x48, 0x8a, 0x62, 0x4c, 0x83, 0x90, 0x37, 0xb7, 0x16,
-	0x09, 0x62, 0x1a, 0x31, 0xae, 0x28, 0xcd, 0xc6, 0x28, 0x1c, 0x85, 0x38, 0xdc, 0x95, 0x23, 0xed,
-	0x6d, 0x2f, 0x4e, 0x88, 0xe2, 0x70, 0xf2, 0xcc, 0xbc, 0xad, 0x51, 0x18, 0x8e, 0x7c, 0xb6, 0x8b,
-	0x56, 0x3f, 0x39, 0xdb, 0xa5, 0xc1, 0x54, 0x41, 0x9d, 0x4b, 0xb0, 0x7a, 0x1a, 0x7b, 0x82, 0x39,
-	0x8c, 0x47, 0x61, 0xc0, 0x59, 0xe7, 0x0f, 0x03, 0x56, 0xb4, 0xe7, 0x41, 0xc2, 0xb8, 0x20, 0x7b,
-	0x00, 0xc2, 0x9b, 0x30, 0xce, 0x62, 0x8f, 0x71, 0xdb, 0x68, 0x9b, 0xdd, 0x6a, 0xef, 0x8a, 0x9c,
-	0x3d, 0x61, 0x62, 0xcc, 0x12, 0xee, 0x0e, 0xc2, 0x68, 0xba, 0x73, 0xe2, 0x4d, 0xd8, 0x31, 0x52,
-	0xf6, 0x0b, 0x8f, 0xfe, 0xdc, 0xce, 0x39, 0x73, 0x93, 0xc8, 0x26, 0x14, 0x05, 0x0b, 0x68, 0x20,
-	0xec, 0x7c, 0xdb, 0xe8, 0x56, 0x1c, 0x6d, 0x11, 0x1b, 0x4a, 0x31, 0x8b, 0x7c, 0x6f, 0x40, 0x6d,
-	0xb3, 0x6d, 0x74, 0x4d, 0x27, 0x35, 0xc9, 0x29, 0x34, 0x66, 0xf3, 0x4f, 0x90, 0x7d, 0x9b, 0x0a,
-	0x6a, 0x17, 0x70, 0xf9, 0xff, 0xed, 0xa8, 0x5c, 0xcd, 0xad, 0xaa, 0x38, 0x27, 0x49, 0xe4, 0x33,
-	0xbd, 0x81, 0xa5, 0x01, 0x3a, 0xbf, 0x59, 0xb0, 0xaa, 0x66, 0xa4, 0xe7, 0xdb, 0x82, 0xf2, 0xc4,
-	0x0b, 0x5c, 0xc9, 0xb6, 0x0d, 0xb5, 0x8b, 0x89, 0x17, 0xc8, 0xc8, 0x08, 0xd1, 0x0b, 0x05, 0xe5,
-	0x35, 0x44, 0x2f, 0x10, 0xfa, 0x40, 0x42, 0x62, 0x30, 0x66, 0x31, 0xb7, 0x4d, 0xdc, 0x54, 0x23,
-	0xdd, 0xd4, 0x67, 0xb4, 0xcf, 0xfc, 0x43, 0x05, 0xea, 0xbd, 0x64, 0x5c, 0xd2, 0x83, 0x0d, 0x19,
-	0x32, 0x66, 0x3c, 0xf4, 0x13, 0xe1, 0x85, 0x81, 0x7b, 0xee, 0x05, 0xc3, 0xf0, 0xdc, 0x2e, 0x60,
-	0xfc, 0xf5, 0x09, 0xbd, 0x70, 0x32, 0xec, 0x14, 0x21, 0x72, 0x1d, 0x80, 0x8e, 0x46, 0x31, 0x1b,
-	0x51, 0xc1, 0xb8, 0x6d, 0xb5, 0xcd, 0x6e, 0xad, 0xb7, 0x92, 0xae, 0xb6, 0x37, 0x1a, 0xc5, 0xce,
-	0x1c, 0x4e, 0x3e, 0x82, 0xad, 0x88, 0xc6, 0xc2, 0xa3, 0xbe, 0x5c, 0x05, 0x8b, 0xea, 0x0e, 0x3d,
-	0x4e, 0xfb, 0x3e, 0x1b, 0xda, 0xc5, 0xb6, 0xd1, 0x2d, 0x3b, 0x97, 0x35, 0x21, 0x2d, 0xfa, 0x6d,
-	0x0d, 0x93, 0x6f, 0x97, 0xcc, 0xe5, 0x22, 0xa6, 0x82, 0x8d, 0xa6, 0x76, 0xa9, 0x6d, 0x74, 0x6b,
-	0xbd, 0xed, 0x74, 0xe1, 0x2f, 0x16, 0x63, 0x1c, 0x6b, 0xda, 0xbf, 0x82, 0xa7, 0x00, 0xd9, 0x86,
-	0x2a, 0xbf, 0xef, 0x45, 0xee, 0x60, 0x9c, 0x04, 0xf7, 0xb9, 0x5d, 0xc6, 0xad, 0x80, 0x74, 0xdd,
-	0x42, 0x0f, 0xb9, 0x0a, 0xd6, 0xd8, 0x0b, 0x04, 0xb7, 0x2b, 0x6d, 0x03, 0x13, 0xaa, 0x64, 0xbb,
-	0x93, 0xca, 0x76, 0x67, 0x2f, 0x98, 0x3a, 0x8a, 0x42, 0x08, 0x14, 0xb8, 0x60, 0x91, 0x0d, 0x98,
-	0x36, 0x1c, 0x93, 0x06, 0x58, 0x31, 0x0d, 0x46, 0xcc, 0xae, 0xa2, 0x53, 0x19, 0xe4, 0x26, 0x54,
-	0x1f, 0x24, 0x2c, 0x9e, 0xba, 0x2a, 0xf6, 0x0a, 0xc6, 0x26, 0xe9, 0x29, 0xbe, 0x94, 0xd0, 0x81,
-	0x44, 0x1c, 0x78, 0x90, 0x8d, 0xc9, 0x0d, 0x00, 0x3e, 0xa6, 0xf1, 0xd0, 0xf5, 0x82, 0xb3, 0xd0,
-	0x5e, 0xc5, 0x39, 0x6b, 0xe9, 0x9c, 0x63, 0x89, 0xdc, 0x0d, 0xce, 0x42, 0xa7, 0xc2, 0xd3, 0x21,
-	0x79, 0x1f, 0x36, 0xcf, 0x3d, 0x31, 0x0e, 0x13, 0xe1, 0x6a, 0x11, 0xbb, 0xbe, 0x14, 0x02, 0xb7,
-	0x6b, 0x6d, 0xb3, 0x5b, 0x71, 0x1a, 0x1a, 0x75, 0x14, 0x88, 0x22, 0xe1, 0x72, 0xcb, 0xbe, 0x37,
-	0xf1, 0x84, 0x7d, 0x49, 0x6d, 0x19, 0x0d, 0xb2, 0x03, 0xeb, 0x59, 0xfa, 0xfb, 0x52, 0x39, 0x2e,
-	0xf7, 0x1e, 0x32, 0xbb, 0x8e, 0x9c, 0xb5, 0x14, 0xda, 0x97, 0xc8, 0xb1, 0xf7, 0x90, 0x75, 0x7e,
-	0xce, 0x03, 0xcc, 0x0e, 0x82, 0x89, 0x16, 0x2c, 0x72, 0x27, 0x9e, 0xef, 0x7b, 0x5c, 0x8b, 0x1a,
-	0xa4, 0xeb, 0x10, 0x3d, 0xa4, 0x0d, 0x85, 0xb3, 0x24, 0x18, 0xa0, 0xa6, 0xab, 0x33, 0x29, 0xdd,
-	0x49, 0x82, 0x81, 0x83, 0x08, 0xb9, 0x0e, 0xe5, 0x51, 0x1c, 0x26, 0x91, 0x17, 0x8c, 0x50, 0x99,
-	0xd5, 0x5e, 0x3d, 0x65, 0x7d, 0xaa, 0xfd, 0x4e, 0xc6, 0x20, 0xef, 0xa4, 0x89, 0xb7, 0x90, 0xba,
-	0x9a, 0x52, 0x1d, 0xe9, 0x4c, 0xeb, 0x70, 0x0d, 0xd6, 0xa2, 0x38, 0xfc, 0x8e, 0x0d, 0x50, 0xf5,
-	0x3a, 0x37, 0x45, 0xcc, 0x4d, 0x7d, 0x06, 0xe8, 0xbc, 0xbc, 0x07, 0x64, 0x8e, 0xec, 0x05, 0x03,
-	0x3f, 0x19, 0x32, 0x54, 0x60, 0xd9, 0x99, 0x0b, 0x73, 0x57, 0x01, 0xe4, 0x26, 0x6c, 0xaa, 0x9b,
-	0xee, 0x8e, 0x29, 0x1f, 0xab, 0xe0, 0x6e, 0x40, 0x27, 0x0c, 0x55, 0x56, 0x71, 0xd6, 0x15, 0x7a,
-	0x40, 0xf9, 0x18, 0x17, 0x38, 0xa2, 0x13, 0xd6, 0x39, 0x87, 0x4a, 0x56, 0x49, 0xcc, 0x99, 0x2e,
-	0xf8, 0x90, 0x5d, 0x64, 0x39, 0x53, 0xf8, 0x90, 0x5d, 0x90, 0xff, 0xc3, 0x8a, 0x08, 0x05, 0xf5,
-	0x5d, 0xf4, 0x71, 0xdd, 0x0f, 0xaa, 0xe8, 0xc3, 0x30, 0x9c, 0xd4, 0x20, 0xdf, 0x9f, 0x62, 0x27,
-	0x2b, 0x3b, 0xf9, 0xfe, 0x54, 0xb6, 0x3d, 0x7d, 0xcc, 0x02, 0x1e, 0x53, 0x5b, 0x9d, 0x26, 0x14,
-	0x64, 0xaa, 0xa5, 0x86, 0x71, 0x8f, 0x06, 0xee, 0x11, 0xc7, 0x9d, 0x1e, 0x94, 0xd3, 0x04, 0xeb,
-	0x78, 0xc6, 0x92, 0x78, 0xe6, 0x42, 0xbc, 0x6d, 0xb0, 0x30, 0xd3, 0x92, 0xb0, 0x50, 0x73, 0x6d,
-	0x75, 0x62, 0xd8, 0x58, 0xda, 0x29, 0xff, 0xc3, 0xde, 0xde, 0xf9, 0xc5, 0x80, 0x5a, 0xda, 0x68,
-	0x95, 0x5e, 0x49, 0x17, 0x8a, 0xd9, 0x4a, 0x52, 0x27, 0xb5, 0xec, 0x42, 0xa9, 0xea, 0xe4, 0x1c,
-	0x8d, 0x93, 0x26, 0x94, 0xce, 0x69, 0x1c, 0x48, 0xf5, 0x61, 0xd4, 0x83, 0x9c, 0x93, 0x3a, 0xc8,
-	0xf5, 0xb4, 0x4b, 0x98, 0xcf, 0xef, 0x12, 0x07, 0xb9, 0xb4, 0x4f, 0x5c, 0x03, 0x0b, 0x6f, 0x90,
-	0x56, 0xf1, 0xfa, 0xe2, 0x92, 0x78, 0x85, 0x24, 0x19, 0x39, 0xfb, 0x65, 0x28, 0xc6, 0x8c, 0x27,
-	0xbe, 0xe8, 0xfc, 0x60, 0xc2, 0x5a, 0xa6, 0x94, 0xec, 0xa9, 0x78, 0x61, 0x6b, 0x35, 0xde, 0xa0,
-	0xb5, 0xe6, 0xdf, 0xb0, 0xb5, 0x36, 0xc0, 0xe2, 0x82, 0xc6, 0x42, 0x3f, 0xa3, 0xca, 0x20, 0x75,
-	0x30, 0x59, 0x30, 0xd4, 0x2f, 0x8b, 0x1c, 0xce, 0x3a, 0xac, 0xf5, 0xf2, 0x0e, 0x3b, 0xff, 0xc2,
-	0x15, 0x5f, 0xe3, 0x85, 0x7b, 0x7e, 0x23, 0x2c, 0xbd, 0x4a, 0x23, 0x2c, 0xcf, 0x35, 0xc2, 0x4e,
-	0x0c, 0x64, 0xbe, 0x0a, 0x5a, 0x47, 0x0d, 0xb0, 0xe4, 0x5d, 0x51, 0x82, 0xad, 0x38, 0xca, 0x20,
-	0x4d, 0x28, 0x6b, 0x89, 0xc8, 0xcb, 0x29, 0x81, 0xcc, 0x9e, 0x9d, 0xdb, 0x7c, 0xe9, 0xb9, 0x3b,
-	0x3f, 0x99, 0x7a, 0xd1, 0xaf, 0xa8, 0x9f, 0xcc, 0x6a, 0x2f, 0x37, 0x28, 0xbd, 0xfa, 0xb6, 0x2a,
-	0xe3, 0xc5, 0x8a, 0xc8, 0xbf, 0x81, 0x22, 0xcc, 0xb7, 0xa5, 0x88, 0xc2, 0x12, 0x45, 0x58, 0x4b,
-	0x14, 0x51, 0x7c, 0x3d, 0x45, 0x94, 0xde, 0x8a, 0x22, 0xca, 0xaf, 0xa2, 0x88, 0xca, 0xbc, 0x22,
-	0x12, 0x58, 0x5f, 0x28, 0x8e, 0x96, 0xc4, 0x26, 0x14, 0xbf, 0x47, 0x8f, 0xd6, 0x84, 0xb6, 0xde,
-	0x96, 0x28, 0xae, 0xee, 0x43, 0x41, 0xfe, 0xd1, 0x22, 0x25, 0x30, 0x9d, 0xbd, 0xd3, 0x7a, 0x8e,
-	0x54, 0xc0, 0xba, 0xf5, 0xf9, 0xbd, 0xa3, 0x93, 0xba, 0x21, 0x7d, 0xc7, 0xf7, 0x0e, 0xeb, 0x79,
-	0x39, 0x38, 0xbc, 0x7b, 0x54, 0x37, 0x71, 0xb0, 0xf7, 0x75, 0xbd, 0x40, 0xaa, 0x50, 0x42, 0xd6,
-	0x27, 0x4e, 0xdd, 0xea, 0xfd, 0x6a, 0x80, 0x75, 0x2c, 0xff, 0xa4, 0x93, 0x0f, 0xa1, 0xa8, 0xfa,
-	0x0f, 0xd9, 0x58, 0xec, 0x47, 0x5a, 0x6c, 0xcd, 0xcd, 0x67, 0xdd, 0xea, 0x98, 0x37, 0x0c, 0x72,
-	0x0b, 0x60, 0x76, 0x23, 0xc8, 0xd6, 0x42, 0xfe, 0xe7, 0x7b, 0x55, 0xb3, 0xb9, 0x0c, 0xd2, 0xd9,
-	0xba, 0x03, 0xd5, 0xb9, 0x24, 0x92, 0x45, 0xea, 0x82, 0xec, 0x9b, 0x57, 0x96, 0x62, 0x2a, 0x4e,
-	0xef, 0x08, 0x6a, 0xf8, 0xa9, 0x20, 0xf5, 0xac, 0x4e, 0xf6, 0x31, 0x54, 0x1d, 0x36, 0x09, 0x05,
-	0x43, 0x3f, 0xc9, 0xf4, 0x31, 0xff, 0x45, 0xd1, 0xdc, 0x78, 0xc6, 0xab, 0xbf, 0x3c, 0x72, 0xfb,
-	0xef, 0x3e, 0xfa, 0xbb, 0x95, 0x7b, 0xf4, 0xa4, 0x65, 0x3c, 0x7e, 0xd2, 0x32, 0xfe, 0x7a, 0xd2,
-	0x32, 0x7e, 0x7c, 0xda, 0xca, 0x3d, 0x7e, 0xda, 0xca, 0xfd, 0xfe, 0xb4, 0x95, 0xfb, 0xa6, 0xa4,
-	0xbf, 0x70, 0xfa, 0x45, 0xac, 0xd0, 0xcd, 0x7f, 0x02, 0x00, 0x00, 0xff, 0xff, 0x8f, 0x56, 0x75,
-	0xa5, 0x4b, 0x0d, 0x00, 0x00,
+	// 1305 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x57, 0xdd, 0x6e, 0x1b, 0x45,
+	0x14, 0xf6, 0x7a, 0xbd, 0xfe, 0x39, 0x4e, 0x5c, 0x67, 0xe2, 0xa4, 0x1b, 0x57, 0x38, 0xc6, 0x08,
+	0xc9, 0x6a, 0x4b, 0x52, 0xb9, 0x08, 0x09, 0xc4, 0x4d, 0xd2, 0x52, 0x52, 0xa9, 0x09, 0xb0, 0x4e,
+	0x09, 0x82, 0x8b, 0xd5, 0xd8, 0x9e, 0xd8, 0x4b, 0xd7, 0xbb, 0xdb, 0x9d, 0x59, 0x12, 0xf7, 0x9a,
+	0x07, 0xe0, 0x9e, 0xc7, 0xe0, 0x92, 0x17, 0xe8, 0x1d, 0x15, 0x57, 0x5c, 0x20, 0x04, 0xed, 0x8b,
+	0xa0, 0x39, 0x33, 0xeb, 0x9f, 0xe2, 0xfe, 0xa9, 0xe1, 0xc6, 0x9a, 0x73, 0xbe, 0x6f, 0xce, 0xcc,
+	0x9c, 0xf3, 0xcd, 0x19, 0x2f, 0x5c, 0xe6, 0x22, 0x8c, 0xd9, 0x2e, 0xfe, 0x46, 0xbd, 0xdd, 0x38,
+	0xea, 0xef, 0x44, 0x71, 0x28, 0x42, 0x92, 0x17, 0x23, 0x1a, 0x84, 0xbc, 0xbe, 0xb5, 0x48, 0x10,
+	0x93, 0x88, 0x71, 0x45, 0xa9, 0xd7, 0x86, 0xe1, 0x30, 0xc4, 0xe1, 0xae, 0x1c, 0x69, 0x6f, 0x73,
+	0x71, 0x42, 0x14, 0x87, 0xe3, 0xe7, 0xe6, 0x6d, 0x0d, 0xc3, 0x70, 0xe8, 0xb3, 0x5d, 0xb4, 0x7a,
+	0xc9, 0xe9, 0x2e, 0x0d, 0x26, 0x0a, 0x6a, 0x5d, 0x82, 0xd5, 0x93, 0xd8, 0x13, 0xcc, 0x61, 0x3c,
+	0x0a, 0x03, 0xce, 0x5a, 0x7f, 0x1a, 0xb0, 0xa2, 0x3d, 0x0f, 0x13, 0xc6, 0x05, 0xd9, 0x03, 0x10,
+	0xde, 0x98, 0x71, 0x16, 0x7b, 0x8c, 0xdb, 0x46, 0xd3, 0x6c, 0x97, 0x3b, 0x57, 0xe4, 0xec, 0x31,
+	0x13, 0x23, 0x96, 0x70, 0xb7, 0x1f, 0x46, 0x93, 0x9d, 0x63, 0x6f, 0xcc, 0xba, 0x48, 0xd9, 0xcf,
+	0x3d, 0xfe, 0x6b, 0x3b, 0xe3, 0xcc, 0x4d, 0x22, 0x9b, 0x90, 0x17, 0x2c, 0xa0, 0x81, 0xb0, 0xb3,
+	0x4d, 0xa3, 0x5d, 0x72, 0xb4, 0x45, 0x6c, 0x28, 0xc4, 0x2c, 0xf2, 0xbd, 0x3e, 0xb5, 0xcd, 0xa6,
+	0xd1, 0x36, 0x9d, 0xd4, 0x24, 0x27, 0x50, 0x9b, 0xcd, 0x3f, 0x46, 0xf6, 0x6d, 0x2a, 0xa8, 0x9d,
+	0xc3, 0xe5, 0xdf, 0xd9, 0x51, 0xb9, 0x9a, 0x5b, 0x55, 0x71, 0x8e, 0x93, 0xc8, 0x67, 0x7a, 0x03,
+	0x4b, 0x03, 0xb4, 0x7e, 0xb7, 0x60, 0x55, 0xcd, 0x48, 0xcf, 0xb7, 0x05, 0xc5, 0xb1, 0x17, 0xb8,
+	0x92, 0x6d, 0x1b, 0x6a, 0x17, 0x63, 0x2f, 0x90, 0x91, 0x11, 0xa2, 0xe7, 0x0a, 0xca, 0x6a, 0x88,
+	0x9e, 0x23, 0xf4, 0x91, 0x84, 0x44, 0x7f, 0xc4, 0x62, 0x6e, 0x9b, 0xb8, 0xa9, 0x5a, 0xba, 0xa9,
+	0x7b, 0xb4, 0xc7, 0xfc, 0x43, 0x05, 0xea, 0xbd, 0x4c, 0xb9, 0xa4, 0x03, 0x1b, 0x32, 0x64, 0xcc,
+	0x78, 0xe8, 0x27, 0xc2, 0x0b, 0x03, 0xf7, 0xcc, 0x0b, 0x06, 0xe1, 0x99, 0x9d, 0xc3, 0xf8, 0xeb,
+	0x63, 0x7a, 0xee, 0x4c, 0xb1, 0x13, 0x84, 0xc8, 0x75, 0x00, 0x3a, 0x1c, 0xc6, 0x6c, 0x48, 0x05,
+	0xe3, 0xb6, 0xd5, 0x34, 0xdb, 0x95, 0xce, 0x4a, 0xba, 0xda, 0xde, 0x70, 0x18, 0x3b, 0x73, 0x38,
+	0xf9, 0x04, 0xb6, 0x22, 0x1a, 0x0b, 0x8f, 0xfa, 0x6e, 0xac, 0x8b, 0xea, 0x0e, 0x3c, 0x4e, 0x7b,
+	0x3e, 0x1b, 0xd8, 0xf9, 0xa6, 0xd1, 0x2e, 0x3a, 0x97, 0x35, 0x21, 0x2d, 0xfa, 0x6d, 0x0d, 0x93,
+	0xef, 0x96, 0xcc, 0xe5, 0x22, 0xa6, 0x82, 0x0d, 0x27, 0x76, 0xa1, 0x69, 0xb4, 0x2b, 0x9d, 0xed,
+	0x74, 0xe1, 0x2f, 0x17, 0x63, 0x74, 0x35, 0xed, 0x3f, 0xc1, 0x53, 0x80, 0x6c, 0x43, 0x99, 0x3f,
+	0xf0, 0x22, 0xb7, 0x3f, 0x4a, 0x82, 0x07, 0xdc, 0x2e, 0xe2, 0x56, 0x40, 0xba, 0x6e, 0xa1, 0x87,
+	0x5c, 0x05, 0x6b, 0xe4, 0x05, 0x82, 0xdb, 0xa5, 0xa6, 0x81, 0x09, 0x55, 0xb2, 0xdd, 0x49, 0x65,
+	0xbb, 0xb3, 0x17, 0x4c, 0x1c, 0x45, 0x21, 0x04, 0x72, 0x5c, 0xb0, 0xc8, 0x06, 0x4c, 0x1b, 0x8e,
+	0x49, 0x0d, 0xac, 0x98, 0x06, 0x43, 0x66, 0x97, 0xd1, 0xa9, 0x0c, 0x72, 0x13, 0xca, 0x0f, 0x13,
+	0x16, 0x4f, 0x5c, 0x15, 0x7b, 0x05, 0x63, 0x93, 0xf4, 0x14, 0x5f, 0x49, 0xe8, 0x40, 0x22, 0x0e,
+	0x3c, 0x9c, 0x8e, 0xc9, 0x0d, 0x00, 0x3e, 0xa2, 0xf1, 0xc0, 0xf5, 0x82, 0xd3, 0xd0, 0x5e, 0xc5,
+	0x39, 0x6b, 0xe9, 0x9c, 0xae, 0x44, 0xee, 0x06, 0xa7, 0xa1, 0x53, 0xe2, 0xe9, 0x90, 0x7c, 0x08,
+	0x9b, 0x67, 0x9e, 0x18, 0x85, 0x89, 0x70, 0xb5, 0x88, 0x5d, 0x5f, 0x0a, 0x81, 0xdb, 0x95, 0xa6,
+	0xd9, 0x2e, 0x39, 0x35, 0x8d, 0x3a, 0x0a, 0x44, 0x91, 0x70, 0xb9, 0x65, 0xdf, 0x1b, 0x7b, 0xc2,
+	0xbe, 0xa4, 0xb6, 0x8c, 0x06, 0xd9, 0x81, 0xf5, 0x69, 0xfa, 0x7b, 0x52, 0x39, 0x2e, 0xf7, 0x1e,
+	0x31, 0xbb, 0x8a, 0x9c, 0xb5, 0x14, 0xda, 0x97, 0x48, 0xd7, 0x7b, 0xc4, 0x5a, 0xbf, 0x64, 0x01,
+	0x66, 0x07, 0xc1, 0x44, 0x0b, 0x16, 0xb9, 0x63, 0xcf, 0xf7, 0x3d, 0xae, 0x45, 0x0d, 0xd2, 0x75,
+	0x88, 0x1e, 0xd2, 0x84, 0xdc, 0x69, 0x12, 0xf4, 0x51, 0xd3, 0xe5, 0x99, 0x94, 0xee, 0x24, 0x41,
+	0xdf, 0x41, 0x84, 0x5c, 0x87, 0xe2, 0x30, 0x0e, 0x93, 0xc8, 0x0b, 0x86, 0xa8, 0xcc, 0x72, 0xa7,
+	0x9a, 0xb2, 0x3e, 0xd7, 0x7e, 0x67, 0xca, 0x20, 0xef, 0xa5, 0x89, 0xb7, 0x90, 0xba, 0x9a, 0x52,
+	0x1d, 0xe9, 0x4c, 0xeb, 0x70, 0x0d, 0xd6, 0xa2, 0x38, 0xfc, 0x9e, 0xf5, 0x51, 0xf5, 0x3a, 0x37,
+	0x79, 0xcc, 0x4d, 0x75, 0x06, 0xe8, 0xbc, 0x7c, 0x00, 0x64, 0x8e, 0xec, 0x05, 0x7d, 0x3f, 0x19,
+	0x30, 0x54, 0x60, 0xd1, 0x99, 0x0b, 0x73, 0x57, 0x01, 0xe4, 0x26, 0x6c, 0xaa, 0x9b, 0xee, 0x8e,
+	0x28, 0x1f, 0xa9, 0xe0, 0x6e, 0x40, 0xc7, 0x0c, 0x55, 0x56, 0x72, 0xd6, 0x15, 0x7a, 0x40, 0xf9,
+	0x08, 0x17, 0x38, 0xa2, 0x63, 0xd6, 0x3a, 0x83, 0xd2, 0xb4, 0x92, 0x98, 0x33, 0x5d, 0xf0, 0x01,
+	0x3b, 0x9f, 0xe6, 0x4c, 0xe1, 0x03, 0x76, 0x4e, 0xde, 0x85, 0x15, 0x11, 0x0a, 0xea, 0xbb, 0xe8,
+	0xe3, 0xba, 0x1f, 0x94, 0xd1, 0x87, 0x61, 0x38, 0xa9, 0x40, 0xb6, 0x37, 0xc1, 0x4e, 0x56, 0x74,
+	0xb2, 0xbd, 0x89, 0x6c, 0x7b, 0xfa, 0x98, 0x39, 0x3c, 0xa6, 0xb6, 0x5a, 0x75, 0xc8, 0xc9, 0x54,
+	0x4b, 0x0d, 0xe3, 0x1e, 0x0d, 0xdc, 0x23, 0x8e, 0x5b, 0x1d, 0x28, 0xa6, 0x09, 0xd6, 0xf1, 0x8c,
+	0x25, 0xf1, 0xcc, 0x85, 0x78, 0xdb, 0x60, 0x61, 0xa6, 0x25, 0x61, 0xa1, 0xe6, 0xda, 0x6a, 0xc5,
+	0xb0, 0xb1, 0xb4, 0x53, 0xfe, 0x8f, 0xbd, 0xbd, 0xf5, 0xab, 0x01, 0x95, 0xb4, 0xd1, 0x2a, 0xbd,
+	0x92, 0x36, 0xe4, 0xa7, 0x2b, 0x49, 0x9d, 0x54, 0xa6, 0x17, 0x4a, 0x55, 0x27, 0xe3, 0x68, 0x9c,
+	0xd4, 0xa1, 0x70, 0x46, 0xe3, 0x40, 0xaa, 0x0f, 0xa3, 0x1e, 0x64, 0x9c, 0xd4, 0x41, 0xae, 0xa7,
+	0x5d, 0xc2, 0x7c, 0x71, 0x97, 0x38, 0xc8, 0xa4, 0x7d, 0xe2, 0x1a, 0x58, 0x78, 0x83, 0xb4, 0x8a,
+	0xd7, 0x17, 0x97, 0xc4, 0x2b, 0x24, 0xc9, 0xc8, 0xd9, 0x2f, 0x42, 0x3e, 0x66, 0x3c, 0xf1, 0x45,
+	0xeb, 0x47, 0x13, 0xd6, 0xa6, 0x4a, 0x99, 0x3e, 0x15, 0x2f, 0x6d, 0xad, 0xc6, 0x5b, 0xb4, 0xd6,
+	0xec, 0x5b, 0xb6, 0xd6, 0x1a, 0x58, 0x5c, 0xd0, 0x58, 0xe8, 0x67, 0x54, 0x19, 0xa4, 0x0a, 0x26,
+	0x0b, 0x06, 0xfa, 0x65, 0x91, 0xc3, 0x59, 0x87, 0xb5, 0x5e, 0xdd, 0x61, 0xe7, 0x5f, 0xb8, 0xfc,
+	0x1b, 0xbc, 0x70, 0x2f, 0x6e, 0x84, 0x85, 0xd7, 0x69, 0x84, 0xc5, 0xb9, 0x46, 0xd8, 0x8a, 0x81,
+	0xcc, 0x57, 0x41, 0xeb, 0xa8, 0x06, 0x96, 0xbc, 0x2b, 0x4a, 0xb0, 0x25, 0x47, 0x19, 0xa4, 0x0e,
+	0x45, 0x2d, 0x11, 0x79, 0x39, 0x25, 0x30, 0xb5, 0x67, 0xe7, 0x36, 0x5f, 0x79, 0xee, 0xd6, 0xcf,
+	0xa6, 0x5e, 0xf4, 0x6b, 0xea, 0x27, 0xb3, 0xda, 0xcb, 0x0d, 0x4a, 0xaf, 0xbe, 0xad, 0xca, 0x78,
+	0xb9, 0x22, 0xb2, 0x6f, 0xa1, 0x08, 0xf3, 0xa2, 0x14, 0x91, 0x5b, 0xa2, 0x08, 0x6b, 0x89, 0x22,
+	0xf2, 0x6f, 0xa6, 0x88, 0xc2, 0x85, 0x28, 0xa2, 0xf8, 0x3a, 0x8a, 0x28, 0xcd, 0x2b, 0x22, 0x81,
+	0xf5, 0x85, 0xe2, 0x68, 0x49, 0x6c, 0x42, 0xfe, 0x07, 0xf4, 0x68, 0x4d, 0x68, 0xeb, 0xa2, 0x44,
+	0x71, 0xf5, 0x1e, 0xe4, 0xe4, 0x1f, 0x2d, 0x52, 0x00, 0xd3, 0xd9, 0x3b, 0xa9, 0x66, 0x48, 0x09,
+	0xac, 0x5b, 0x5f, 0xdc, 0x3f, 0x3a, 0xae, 0x1a, 0xd2, 0xd7, 0xbd, 0x7f, 0x58, 0xcd, 0xca, 0xc1,
+	0xe1, 0xdd, 0xa3, 0xaa, 0x89, 0x83, 0xbd, 0x6f, 0xaa, 0x39, 0x52, 0x86, 0x02, 0xb2, 0x3e, 0x73,
+	0xaa, 0x16, 0x29, 0x42, 0xee, 0xde, 0x5e, 0xf7, 0xb8, 0x9a, 0xef, 0xfc, 0x66, 0x80, 0xd5, 0x95,
+	0x7f, 0xd7, 0xc9, 0xc7, 0x90, 0x57, 0x9d, 0x88, 0x6c, 0x2c, 0x76, 0x26, 0x2d, 0xbb, 0xfa, 0xe6,
+	0xf3, 0x6e, 0x75, 0xe0, 0x1b, 0x06, 0xb9, 0x05, 0x30, 0xbb, 0x1b, 0x64, 0x6b, 0xa1, 0x12, 0xf3,
+	0x5d, 0xab, 0x5e, 0x5f, 0x06, 0xe9, 0xbc, 0xdd, 0x81, 0xf2, 0x5c, 0x3a, 0xc9, 0x22, 0x75, 0xe1,
+	0x02, 0xd4, 0xaf, 0x2c, 0xc5, 0x54, 0x9c, 0xce, 0x11, 0x54, 0xf0, 0xa3, 0x41, 0x2a, 0x5b, 0x9d,
+	0xec, 0x53, 0x28, 0x3b, 0x6c, 0x1c, 0x0a, 0x86, 0x7e, 0x32, 0x55, 0xca, 0xfc, 0xb7, 0x45, 0x7d,
+	0xe3, 0x39, 0xaf, 0xfe, 0x06, 0xc9, 0xec, 0xbf, 0xff, 0xf8, 0x9f, 0x46, 0xe6, 0xf1, 0xd3, 0x86,
+	0xf1, 0xe4, 0x69, 0xc3, 0xf8, 0xfb, 0x69, 0xc3, 0xf8, 0xe9, 0x59, 0x23, 0xf3, 0xe4, 0x59, 0x23,
+	0xf3, 0xc7, 0xb3, 0x46, 0xe6, 0xdb, 0x82, 0xfe, 0xd6, 0xe9, 0xe5, 0xb1, 0x56, 0x37, 0xff, 0x1d,
+	0x00, 0xa6, 0xbe, 0x1a, 0x60, 0x55, 0x0d, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -242,6 +242,8 @@ type AggrChunk struct {
 	Min     *Chunk `protobuf:"bytes,6,opt,name=min,proto3" json:"min,omitempty"`
 	Max     *Chunk `protobuf:"bytes,7,opt,name=max,proto3" json:"max,omitempty"`
 	Counter *Chunk `protobuf:"bytes,8,opt,name=counter,proto3" json:"counter,omitempty"`
+	// last is the last raw sample of each window, only set for native histograms.
+	Last *Chunk `protobuf:"bytes,9,opt,name=last,proto3" json:"last,omitempty"`
 }
 
 func (m *AggrChunk) Reset()         { *m = AggrChunk{} }
@@ -331,44 +333,45 @@ func init() {
 func init() { proto.RegisterFile("store/storepb/types.proto", fileDescriptor_121fba57de02d8e0) }
 
 var fileDescriptor_121fba57de02d8e0 = []byte{
-	// 588 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x53, 0x4d, 0x6f, 0xd3, 0x30,
-	0x18, 0x8e, 0xd3, 0x34, 0x6d, 0xdf, 0x7d, 0x10, 0xcc, 0x04, 0xd9, 0x0e, 0x69, 0x15, 0x04, 0x54,
-	0x93, 0x96, 0x48, 0x03, 0xc4, 0x85, 0x4b, 0x8b, 0xca, 0x87, 0xb4, 0xad, 0xcc, 0xab, 0x04, 0xda,
-	0x65, 0x72, 0x33, 0x2b, 0x89, 0xd6, 0x7c, 0x28, 0x76, 0xa0, 0xfb, 0x17, 0x20, 0x6e, 0x1c, 0xf8,
-	0x3d, 0x3d, 0xee, 0x88, 0x38, 0x4c, 0xb0, 0xfe, 0x11, 0x14, 0x27, 0x65, 0x54, 0xea, 0x25, 0x7a,
-	0xf3, 0x3e, 0x1f, 0x7e, 0xfd, 0xd8, 0x86, 0x6d, 0x2e, 0x92, 0x8c, 0xb9, 0xf2, 0x9b, 0x8e, 0x5d,
-	0x71, 0x99, 0x32, 0xee, 0xa4, 0x59, 0x22, 0x12, 0xac, 0x8b, 0x80, 0xc6, 0x09, 0xdf, 0xd9, 0xf2,
-	0x13, 0x3f, 0x91, 0x2d, 0xb7, 0xa8, 0x4a, 0x74, 0xa7, 0x12, 0x4e, 0xe8, 0x98, 0x4d, 0x96, 0x85,
-	0xf6, 0x77, 0x04, 0xf5, 0x57, 0x41, 0x1e, 0x5f, 0xe0, 0x5d, 0xd0, 0x0a, 0xc0, 0x44, 0x1d, 0xd4,
-	0xdd, 0xdc, 0xbf, 0xef, 0x94, 0x8e, 0x8e, 0x04, 0x9d, 0x41, 0xec, 0x25, 0xe7, 0x61, 0xec, 0x13,
-	0xc9, 0xc1, 0x18, 0xb4, 0x73, 0x2a, 0xa8, 0xa9, 0x76, 0x50, 0x77, 0x9d, 0xc8, 0x1a, 0x9b, 0xa0,
-	0x05, 0x94, 0x07, 0x66, 0xad, 0x83, 0xba, 0x5a, 0x5f, 0x9b, 0x5d, 0xb7, 0x11, 0x91, 0x1d, 0xfb,
-	0x05, 0x34, 0x17, 0x7a, 0xdc, 0x80, 0xda, 0xc7, 0x21, 0x31, 0x14, 0xbc, 0x01, 0xad, 0xb7, 0xef,
-	0x4e, 0x46, 0xc3, 0x37, 0xa4, 0x77, 0x68, 0x20, 0x7c, 0x0f, 0xee, 0xbc, 0x3e, 0x18, 0xf6, 0x46,
-	0x67, 0xb7, 0x4d, 0xd5, 0xfe, 0x81, 0x40, 0x3f, 0x61, 0x59, 0xc8, 0x38, 0xf6, 0x40, 0x97, 0xe3,
-	0x73, 0x13, 0x75, 0x6a, 0xdd, 0xb5, 0xfd, 0x8d, 0xc5, 0x7c, 0x07, 0x45, 0xb7, 0xff, 0x72, 0x76,
-	0xdd, 0x56, 0x7e, 0x5d, 0xb7, 0x9f, 0xf9, 0xa1, 0x08, 0xf2, 0xb1, 0xe3, 0x25, 0x91, 0x5b, 0x12,
-	0xf6, 0xc2, 0xa4, 0xaa, 0xdc, 0xf4, 0xc2, 0x77, 0x97, 0x92, 0x70, 0x4e, 0xa5, 0x9a, 0x54, 0xd6,
-	0xd8, 0x05, 0xdd, 0x2b, 0xb6, 0xcb, 0x4d, 0x55, 0x2e, 0x72, 0x77, 0xb1, 0x48, 0xcf, 0xf7, 0x33,
-	0x19, 0x84, 0xdc, 0x97, 0x42, 0x2a, 0x9a, 0xfd, 0x1c, 0xd6, 0xca, 0xf9, 0xfa, 0x54, 0x78, 0x01,
-	0x7e, 0x0c, 0x3a, 0x97, 0xbf, 0xd5, 0x90, 0x9b, 0x0b, 0x7d, 0x49, 0x22, 0x15, 0x6a, 0x7f, 0x53,
-	0xa1, 0xf5, 0xcf, 0x12, 0x6f, 0x43, 0x33, 0x0a, 0xe3, 0x33, 0x11, 0x46, 0x65, 0xf8, 0x35, 0xd2,
-	0x88, 0xc2, 0x78, 0x14, 0x46, 0x4c, 0x42, 0x74, 0x5a, 0x42, 0x6a, 0x05, 0xd1, 0xa9, 0x84, 0xda,
-	0x50, 0xcb, 0xe8, 0x67, 0x99, 0xf6, 0x7f, 0x69, 0x48, 0x47, 0x52, 0x20, 0xf8, 0x21, 0xd4, 0xbd,
-	0x24, 0x8f, 0x85, 0xa9, 0xad, 0xa2, 0x94, 0x58, 0xe1, 0xc2, 0xf3, 0xc8, 0xac, 0xaf, 0x74, 0xe1,
-	0x79, 0x54, 0x10, 0xa2, 0x30, 0x36, 0xf5, 0x95, 0x84, 0x28, 0x8c, 0x25, 0x81, 0x4e, 0xcd, 0xc6,
-	0x6a, 0x02, 0x9d, 0xe2, 0x27, 0xd0, 0x90, 0x6b, 0xb1, 0xcc, 0x6c, 0xae, 0x22, 0x2d, 0x50, 0xfb,
-	0x2b, 0x82, 0x75, 0x79, 0x1e, 0x87, 0x45, 0x98, 0x2c, 0xc3, 0x7b, 0x4b, 0x37, 0x72, 0x7b, 0xe9,
-	0xc4, 0x2b, 0x8e, 0x33, 0xba, 0x4c, 0xd9, 0xed, 0xa5, 0x8c, 0x69, 0x15, 0x54, 0x8b, 0xc8, 0x1a,
-	0x6f, 0x41, 0xfd, 0x13, 0x9d, 0xe4, 0x4c, 0xe6, 0xd4, 0x22, 0xe5, 0x8f, 0xdd, 0x05, 0xad, 0xd0,
-	0x61, 0x1d, 0xd4, 0xc1, 0xb1, 0xa1, 0x14, 0x97, 0xf2, 0x68, 0x70, 0x6c, 0xa0, 0xa2, 0x41, 0x06,
-	0x86, 0x2a, 0x1b, 0x64, 0x60, 0xd4, 0x76, 0x1d, 0x78, 0xf0, 0x9e, 0x66, 0x22, 0xa4, 0x13, 0xc2,
-	0x78, 0x9a, 0xc4, 0x9c, 0x9d, 0x88, 0x8c, 0x0a, 0xe6, 0x5f, 0xe2, 0x26, 0x68, 0x1f, 0x7a, 0xe4,
-	0xc8, 0x50, 0x70, 0x0b, 0xea, 0xbd, 0xfe, 0x90, 0x8c, 0x0c, 0xd4, 0x7f, 0x34, 0xfb, 0x63, 0x29,
-	0xb3, 0x1b, 0x0b, 0x5d, 0xdd, 0x58, 0xe8, 0xf7, 0x8d, 0x85, 0xbe, 0xcc, 0x2d, 0xe5, 0x6a, 0x6e,
-	0x29, 0x3f, 0xe7, 0x96, 0x72, 0xda, 0xa8, 0x9e, 0xee, 0x58, 0x97, 0x8f, 0xef, 0xe9, 0xdf, 0x00,
-	0x00, 0x00, 0xff, 0xff, 0xa9, 0xae, 0xe0, 0xc2, 0xd2, 0x03, 0x00, 0x00,
+	// 599 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x53, 0xcf, 0x6e, 0xd3, 0x4e,
+	0x10, 0xf6, 0x3a, 0x8e, 0x93, 0x4c, 0xff, 0xfc, 0xfc, 0x5b, 0x2a, 0x70, 0x7b, 0x70, 0x82, 0x11,
+	0x10, 0x55, 0xaa, 0x2d, 0x15, 0x10, 0x17, 0x2e, 0x09, 0x0a, 0x7f, 0xa4, 0xb6, 0xa1, 0xdb, 0x48,
+	0xa0, 0x5e, 0xaa, 0x4d, 0xba, 0x72, 0xac, 0xc6, 0x76, 0xe4, 0xdd, 0x40, 0xfa, 0x16, 0x70, 0xe5,
+	0xc0, 0x63, 0xf0, 0x0c, 0x39, 0xf6, 0x88, 0x38, 0x54, 0xd0, 0xbc, 0x08, 0xf2, 0xd8, 0xa1, 0x44,
+	0xca, 0xc5, 0x9a, 0x9d, 0xef, 0x9b, 0x6f, 0x66, 0x3f, 0xcf, 0xc2, 0xb6, 0x54, 0x49, 0x2a, 0x7c,
+	0xfc, 0x8e, 0xfb, 0xbe, 0xba, 0x1c, 0x0b, 0xe9, 0x8d, 0xd3, 0x44, 0x25, 0xd4, 0x54, 0x43, 0x1e,
+	0x27, 0x72, 0x67, 0x2b, 0x48, 0x82, 0x04, 0x53, 0x7e, 0x16, 0xe5, 0xe8, 0x4e, 0x51, 0x38, 0xe2,
+	0x7d, 0x31, 0x5a, 0x2e, 0x74, 0xbf, 0x12, 0x28, 0xbf, 0x1c, 0x4e, 0xe2, 0x0b, 0xba, 0x0b, 0x46,
+	0x06, 0xd8, 0xa4, 0x41, 0x9a, 0x9b, 0xfb, 0x77, 0xbd, 0x5c, 0xd1, 0x43, 0xd0, 0xeb, 0xc4, 0x83,
+	0xe4, 0x3c, 0x8c, 0x03, 0x86, 0x1c, 0x4a, 0xc1, 0x38, 0xe7, 0x8a, 0xdb, 0x7a, 0x83, 0x34, 0xd7,
+	0x19, 0xc6, 0xd4, 0x06, 0x63, 0xc8, 0xe5, 0xd0, 0x2e, 0x35, 0x48, 0xd3, 0x68, 0x1b, 0xb3, 0xeb,
+	0x3a, 0x61, 0x98, 0x71, 0x9f, 0x43, 0x75, 0x51, 0x4f, 0x2b, 0x50, 0xfa, 0xd0, 0x65, 0x96, 0x46,
+	0x37, 0xa0, 0xf6, 0xe6, 0xed, 0x49, 0xaf, 0xfb, 0x9a, 0xb5, 0x0e, 0x2d, 0x42, 0xef, 0xc0, 0x7f,
+	0xaf, 0x0e, 0xba, 0xad, 0xde, 0xd9, 0x6d, 0x52, 0x77, 0xbf, 0x11, 0x30, 0x4f, 0x44, 0x1a, 0x0a,
+	0x49, 0x07, 0x60, 0xe2, 0xf8, 0xd2, 0x26, 0x8d, 0x52, 0x73, 0x6d, 0x7f, 0x63, 0x31, 0xdf, 0x41,
+	0x96, 0x6d, 0xbf, 0x98, 0x5d, 0xd7, 0xb5, 0x9f, 0xd7, 0xf5, 0xa7, 0x41, 0xa8, 0x86, 0x93, 0xbe,
+	0x37, 0x48, 0x22, 0x3f, 0x27, 0xec, 0x85, 0x49, 0x11, 0xf9, 0xe3, 0x8b, 0xc0, 0x5f, 0x72, 0xc2,
+	0x3b, 0xc5, 0x6a, 0x56, 0x48, 0x53, 0x1f, 0xcc, 0x41, 0x76, 0x5d, 0x69, 0xeb, 0xd8, 0xe4, 0xff,
+	0x45, 0x93, 0x56, 0x10, 0xa4, 0x68, 0x04, 0xde, 0x4b, 0x63, 0x05, 0xcd, 0x7d, 0x06, 0x6b, 0xf9,
+	0x7c, 0x6d, 0xae, 0x06, 0x43, 0xfa, 0x08, 0x4c, 0x89, 0xc7, 0x62, 0xc8, 0xcd, 0x45, 0x7d, 0x4e,
+	0x62, 0x05, 0xea, 0x7e, 0xd7, 0xa1, 0xf6, 0x57, 0x92, 0x6e, 0x43, 0x35, 0x0a, 0xe3, 0x33, 0x15,
+	0x46, 0xb9, 0xf9, 0x25, 0x56, 0x89, 0xc2, 0xb8, 0x17, 0x46, 0x02, 0x21, 0x3e, 0xcd, 0x21, 0xbd,
+	0x80, 0xf8, 0x14, 0xa1, 0x3a, 0x94, 0x52, 0xfe, 0x09, 0xdd, 0xfe, 0xc7, 0x0d, 0x54, 0x64, 0x19,
+	0x42, 0x1f, 0x40, 0x79, 0x90, 0x4c, 0x62, 0x65, 0x1b, 0xab, 0x28, 0x39, 0x96, 0xa9, 0xc8, 0x49,
+	0x64, 0x97, 0x57, 0xaa, 0xc8, 0x49, 0x94, 0x11, 0xa2, 0x30, 0xb6, 0xcd, 0x95, 0x84, 0x28, 0x8c,
+	0x91, 0xc0, 0xa7, 0x76, 0x65, 0x35, 0x81, 0x4f, 0xe9, 0x63, 0xa8, 0x60, 0x2f, 0x91, 0xda, 0xd5,
+	0x55, 0xa4, 0x05, 0x4a, 0xef, 0x83, 0x31, 0xe2, 0x52, 0xd9, 0xb5, 0x55, 0x2c, 0x84, 0xdc, 0x2f,
+	0x04, 0xd6, 0xf1, 0x97, 0x1d, 0x66, 0x7e, 0x8b, 0x94, 0xee, 0x2d, 0x2d, 0xed, 0xf6, 0xd2, 0x52,
+	0x14, 0x1c, 0xaf, 0x77, 0x39, 0x16, 0xb7, 0x7b, 0x1b, 0xf3, 0xc2, 0xcb, 0x1a, 0xc3, 0x98, 0x6e,
+	0x41, 0xf9, 0x23, 0x1f, 0x4d, 0x04, 0x5a, 0x59, 0x63, 0xf9, 0xc1, 0x6d, 0x82, 0x91, 0xd5, 0x51,
+	0x13, 0xf4, 0xce, 0xb1, 0xa5, 0x65, 0x7b, 0x7b, 0xd4, 0x39, 0xb6, 0x48, 0x96, 0x60, 0x1d, 0x4b,
+	0xc7, 0x04, 0xeb, 0x58, 0xa5, 0x5d, 0x0f, 0xee, 0xbd, 0xe3, 0xa9, 0x0a, 0xf9, 0x88, 0x09, 0x39,
+	0x4e, 0x62, 0x29, 0x4e, 0x54, 0xca, 0x95, 0x08, 0x2e, 0x69, 0x15, 0x8c, 0xf7, 0x2d, 0x76, 0x64,
+	0x69, 0xb4, 0x06, 0xe5, 0x56, 0xbb, 0xcb, 0x7a, 0x16, 0x69, 0x3f, 0x9c, 0xfd, 0x76, 0xb4, 0xd9,
+	0x8d, 0x43, 0xae, 0x6e, 0x1c, 0xf2, 0xeb, 0xc6, 0x21, 0x9f, 0xe7, 0x8e, 0x76, 0x35, 0x77, 0xb4,
+	0x1f, 0x73, 0x47, 0x3b, 0xad, 0x14, 0xaf, 0xbb, 0x6f, 0xe2, 0xfb, 0x7c, 0xf2, 0x67, 0x00, 0x2c,
+	0x12, 0x9b, 0x5d, 0xf5, 0x03, 0x00, 0x00,
 }
 
 func (m *Chunk) Marshal() (dAtA []byte, err error) {
@@ -519,6 +522,18 @@ func (m *AggrChunk) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.Last != nil {
+		{
+			size, err := m.Last.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTypes(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x4a
+	}
 	if m.Counter != nil {
 		{
 			size, err := m.Counter.MarshalToSizedBuffer(dAtA[:i])
@@ -748,6 +763,10 @@ func (m *AggrChunk) Size() (n int) {
 		l = m.Counter.Size()
 		n += 1 + l + sovTypes(uint64(l))
 	}
+	if m.Last != nil {
+		l = m.Last.Size()
+		n += 1 + l + sovTypes(uint64(l))
+	}
 	return n
 }
 
@@ -1262,6 +1281,42 @@ func (m *AggrChunk) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Last", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTypes
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTypes
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Last == nil {
+				m.Last = &Chunk{}
+			}
+			if err := m.Last.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTypes(dAtA[iNdEx:])
@@ -20,6 +20,7 @@ import (
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -208,12 +209,21 @@ func (s *TSDBStore) TSDBInfos() []infopb.TSDBInfo {
 			Labels: labelpb.ZLabelSet{
 				Labels: labels[0].Labels,
 			},
-			MinTime: mint,
-			MaxTime: maxt,
+			MinTime:   mint,
+			MaxTime:   maxt,
+			NumSeries: s.numSeries(),
 		},
 	}
 }
 
+// numSeries returns the number of series in the head of the TSDB, 0 if the TSDB doesn't expose its head.
+func (s *TSDBStore) numSeries() uint64 {
+	if db, ok := s.db.(interface{ Head() *tsdb.Head }); ok {
+		return db.Head().NumSeries()
+	}
+	return 0
+}
+
 func (s *TSDBStore) TimeRange() (int64, int64) {
 	var minTime int64 = math.MinInt64
 	startTime, err := s.db.StartTime()
@@ -8,7 +8,7 @@ import { Alerts, Config, Flags, Rules, ServiceDiscovery, Status, Targets, TSDBSt
 import PathPrefixProps from './types/PathPrefixProps';
 import ThanosComponentProps from './thanos/types/ThanosComponentProps';
 import Navigation from './thanos/Navbar';
-import { Stores, ErrorBoundary, Blocks } from './thanos/pages';
+import { Stores, ErrorBoundary, Blocks, Jobs } from './thanos/pages';
 import { ThemeContext, themeName, themeSetting } from './contexts/ThemeContext';
 import { Theme, themeLocalStorageKey } from './Theme';
 import { useLocalStorage } from './hooks/useLocalStorage';
@@ -61,6 +61,7 @@ const App: FC<PathPrefixProps & ThanosComponentProps> = ({ pathPrefix, thanosCom
               <Stores path="/stores" pathPrefix={pathPrefix} />
               <Blocks path="/blocks" pathPrefix={pathPrefix} />
               <Blocks path="/loaded" pathPrefix={pathPrefix} view="loaded" />
+              <Jobs path="/compactor" pathPrefix={pathPrefix} />
               <NotFound pathPrefix={pathPrefix} default defaultRoute={defaultRouteConfig[thanosComponent]} />
             </Router>
           </QueryParamProvider>
@@ -65,6 +65,7 @@ const navConfig: { [component: string]: (NavConfig | NavDropDown)[] } = {
   compact: [
     { name: 'Global Blocks', uri: '/blocks' },
     { name: 'Loaded Blocks', uri: '/loaded' },
+    { name: 'Jobs', uri: '/compactor' },
     {
       name: 'Status',
       children: [
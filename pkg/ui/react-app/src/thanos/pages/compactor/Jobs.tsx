@@ -0,0 +1,146 @@
+import React, { FC, useEffect, useState } from 'react';
+import { RouteComponentProps } from '@reach/router';
+import { Alert, Badge, Button, ButtonGroup, Table, UncontrolledAlert } from 'reactstrap';
+import moment from 'moment';
+import { withStatusIndicator } from '../../../components/withStatusIndicator';
+import { useFetch } from '../../../hooks/useFetch';
+import PathPrefixProps from '../../../types/PathPrefixProps';
+import { FlagMap } from '../../../pages/flags/Flags';
+import { Job, JobsStatus } from './job';
+
+export const columns = ['Type', 'Group', 'Labels', 'Resolution', 'Blocks', 'State', 'Priority', 'Started', 'Actions'];
+
+const stateColor: Record<Job['state'], string> = {
+  queued: 'secondary',
+  running: 'primary',
+  done: 'success',
+  failed: 'danger',
+  skipped: 'warning',
+};
+
+export interface JobsContentProps {
+  data: JobsStatus;
+  disableAdminOperations: boolean;
+  submit: (action: string, params?: Record<string, string>) => Promise<void>;
+}
+
+export const JobsContent: FC<JobsContentProps> = ({ data, disableAdminOperations, submit }) => {
+  const skipped = new Set(data.skippedGroups);
+  return (
+    <>
+      <div className="d-flex align-items-center mb-2">
+        {data.paused ? (
+          <Alert color="warning" className="mb-0 mr-2">
+            Compactor is paused. Running jobs are finished, no new jobs are started.
+          </Alert>
+        ) : null}
+        {!disableAdminOperations && (
+          <Button color={data.paused ? 'primary' : 'warning'} onClick={() => submit(data.paused ? 'resume' : 'pause')}>
+            {data.paused ? 'Resume' : 'Pause'}
+          </Button>
+        )}
+      </div>
+      {data.jobs.length > 0 ? (
+        <Table size="sm" bordered hover>
+          <thead>
+            <tr key="header">
+              {columns.map((column) => (
+                <th key={column}>{column}</th>
+              ))}
+            </tr>
+          </thead>
+          <tbody>
+            {data.jobs.map((job: Job) => (
+              <tr key={`${job.type}-${job.group}-${job.blocks.join(',')}`}>
+                <td>{job.type}</td>
+                <td>{job.group}</td>
+                <td>
+                  {Object.entries(job.labels || {}).map(([name, value]) => (
+                    <Badge key={name} color="primary" className="mr-1">
+                      {`${name}="${value}"`}
+                    </Badge>
+                  ))}
+                </td>
+                <td>{job.resolution}</td>
+                <td>{job.blocks.length}</td>
+                <td>
+                  <Badge color={stateColor[job.state]} title={job.error}>
+                    {job.state}
+                  </Badge>
+                </td>
+                <td>{job.priority}</td>
+                <td>{job.startedAt ? moment(job.startedAt).fromNow() : '-'}</td>
+                <td>
+                  {!disableAdminOperations && (
+                    <ButtonGroup size="sm">
+                      <Button
+                        onClick={() => submit('groups/skip', { group: job.group, skip: String(!skipped.has(job.group)) })}
+                      >
+                        {skipped.has(job.group) ? 'Unskip' : 'Skip'}
+                      </Button>
+                      <Button onClick={() => submit('groups/priority', { group: job.group, priority: String(job.priority + 1) })}>
+                        Bump priority
+                      </Button>
+                    </ButtonGroup>
+                  )}
+                </td>
+              </tr>
+            ))}
+          </tbody>
+        </Table>
+      ) : (
+        <UncontrolledAlert color="info">No compaction or downsampling jobs in the current iteration.</UncontrolledAlert>
+      )}
+    </>
+  );
+};
+
+const JobsWithStatusIndicator = withStatusIndicator(JobsContent);
+
+export const Jobs: FC<RouteComponentProps & PathPrefixProps> = ({ pathPrefix = '' }) => {
+  const { response, error, isLoading } = useFetch<JobsStatus>(`${pathPrefix}/api/v1/compactor/jobs`);
+  const { response: flagsRes } = useFetch<FlagMap>(`${pathPrefix}/api/v1/status/flags`);
+  const disableAdminOperations = flagsRes?.data?.['disable-admin-operations'] === 'true' || false;
+  const [status, setStatus] = useState<JobsStatus>();
+  const [submitError, setSubmitError] = useState<Error>();
+
+  useEffect(() => {
+    setStatus(response.data);
+  }, [response.data]);
+
+  const submit = async (action: string, params?: Record<string, string>) => {
+    try {
+      const res = await fetch(`${pathPrefix}/api/v1/compactor/${action}`, {
+        method: 'POST',
+        body: new URLSearchParams(params),
+      });
+      if (!res.ok) {
+        throw new Error(res.statusText);
+      }
+      const json = await res.json();
+      setStatus(json.data);
+      setSubmitError(undefined);
+    } catch (err) {
+      setSubmitError(err as Error);
+    }
+  };
+
+  const { status: responseStatus } = response;
+  const badResponse = responseStatus !== 'success' && responseStatus !== 'start fetching';
+
+  return (
+    <>
+      {submitError && <UncontrolledAlert color="danger">Error: {submitError.message}</UncontrolledAlert>}
+      <JobsWithStatusIndicator
+        data={status || response.data}
+        disableAdminOperations={disableAdminOperations}
+        submit={submit}
+        error={badResponse ? new Error(responseStatus) : error}
+        isLoading={isLoading}
+        componentTitle="compactor jobs"
+      />
+    </>
+  );
+};
+
+export default Jobs;
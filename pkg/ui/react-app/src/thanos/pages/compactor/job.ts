@@ -0,0 +1,20 @@
+export interface Job {
+  type: 'compaction' | 'downsample';
+  group: string;
+  labels: Record<string, string>;
+  resolution: number;
+  blocks: string[];
+  state: 'queued' | 'running' | 'done' | 'failed' | 'skipped';
+  priority: number;
+  queuedAt: string;
+  startedAt?: string;
+  finishedAt?: string;
+  error?: string;
+}
+
+export interface JobsStatus {
+  paused: boolean;
+  jobs: Job[];
+  skippedGroups: string[];
+  priorities: Record<string, number>;
+}
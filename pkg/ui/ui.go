@@ -27,6 +27,7 @@ var reactRouterPaths = []string{
 	"/",
 	"/alerts",
 	"/blocks",
+	"/compactor",
 	"/config",
 	"/flags",
 	"/global",
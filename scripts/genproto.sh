@@ -63,6 +63,7 @@ for dir in ${CORTEX_DIRS}; do
   sed -i.bak -E 's/import _ \"gogoproto\"//g' *.pb.go
   sed -i.bak -E 's/_ \"google\/protobuf\"//g' *.pb.go
   sed -i.bak -E 's/\"cortex\/cortexpb\"/\"github.com\/thanos-io\/thanos\/internal\/cortex\/cortexpb\"/g' *.pb.go
+  sed -i.bak -E 's/\"store\/hintspb\"/\"github.com\/thanos-io\/thanos\/pkg\/store\/hintspb\"/g' *.pb.go
   rm -f *.bak
   ${GOIMPORTS_BIN} -w *.pb.go
   popd